@@ -25,6 +25,7 @@ func init() {
 		initCmd,
 		validateCmd,
 		runCmd,
+		watchTxCmd,
 		stateCmd,
 		exportCmd,
 	)
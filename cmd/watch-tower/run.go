@@ -2,20 +2,33 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/devblac/watch-tower/internal/api"
 	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/devgen"
 	"github.com/devblac/watch-tower/internal/engine"
 	"github.com/devblac/watch-tower/internal/health"
 	"github.com/devblac/watch-tower/internal/logging"
 	"github.com/devblac/watch-tower/internal/metrics"
-	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/price"
 	"github.com/devblac/watch-tower/internal/source/algorand"
+	"github.com/devblac/watch-tower/internal/source/blocktime"
 	"github.com/devblac/watch-tower/internal/source/evm"
+	"github.com/devblac/watch-tower/internal/source/ingest"
 	"github.com/devblac/watch-tower/internal/storage"
+	"github.com/devblac/watch-tower/internal/stream"
+	"github.com/devblac/watch-tower/internal/watchlist"
+	"github.com/devblac/watch-tower/pkg/watchtower"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/spf13/cobra"
 )
 
@@ -26,6 +39,20 @@ var (
 	flagTo      uint64
 	flagHealth  string
 	flagMetrics string
+	flagGRPC    string
+	flagAPI     string
+	flagWorkers int
+	flagBuffer  int
+	flagBudget  int
+	flagSince   time.Duration
+
+	flagBackfill       bool
+	flagBackfillWindow uint64
+
+	flagDevgen       bool
+	flagDevgenBlock  time.Duration
+	flagDevgenEvents int
+	flagDevgenSeed   uint64
 )
 
 func init() {
@@ -33,8 +60,20 @@ func init() {
 	runCmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "Do not send to sinks")
 	runCmd.Flags().Uint64Var(&flagFrom, "from", 0, "Start from height/round override")
 	runCmd.Flags().Uint64Var(&flagTo, "to", 0, "Stop at height/round (inclusive)")
+	runCmd.Flags().DurationVar(&flagSince, "since", 0, "Start from the first block/round this long ago (e.g. 6h)")
+	runCmd.Flags().BoolVar(&flagBackfill, "backfill", false, "Scan the --from/--to range of each EVM source for log rules, without moving cursors, then exit")
+	runCmd.Flags().Uint64Var(&flagBackfillWindow, "backfill-window", evm.DefaultBackfillWindow, "Most blocks per eth_getLogs call during --backfill; shrunk when the node refuses")
+	runCmd.Flags().BoolVar(&flagDevgen, "devgen", false, "Generate synthetic blocks matching the rules instead of calling RPC (for testing)")
+	runCmd.Flags().DurationVar(&flagDevgenBlock, "devgen-block-time", devgen.DefaultBlockTime, "Interval between generated blocks")
+	runCmd.Flags().IntVar(&flagDevgenEvents, "devgen-events", devgen.DefaultEventsPerBlock, "Matching events per generated block")
+	runCmd.Flags().Uint64Var(&flagDevgenSeed, "devgen-seed", 1, "Seed for generated data")
 	runCmd.Flags().StringVar(&flagHealth, "health", "", "Health check HTTP address (e.g., :8080)")
 	runCmd.Flags().StringVar(&flagMetrics, "metrics", "", "Metrics HTTP address (e.g., :9090)")
+	runCmd.Flags().StringVar(&flagGRPC, "grpc", "", "gRPC control API address (e.g., :9091); set WATCH_TOWER_API_TOKEN to require auth")
+	runCmd.Flags().IntVar(&flagWorkers, "sink-workers", 0, "Queue alerts and deliver them with this many concurrent workers; 0 sends inline during scanning")
+	runCmd.Flags().IntVar(&flagBudget, "max-failures", engine.DefaultFailureBudget, "Consecutive failed ticks a source may have before run exits; failing sources back off in the meantime (0 retries forever)")
+	runCmd.Flags().IntVar(&flagBuffer, "event-buffer", engine.DefaultEventBuffer, "Matched events queued per source before scanning waits for alert handling")
+	runCmd.Flags().StringVar(&flagAPI, "api", "", "HTTP API address (e.g., :8081); set WATCH_TOWER_API_TOKEN to require auth")
 }
 
 var runCmd = &cobra.Command{
@@ -59,72 +98,137 @@ var runCmd = &cobra.Command{
 		}
 		defer store.Close()
 
+		if err := engine.ApplyStoredRules(ctx, store, cfg); err != nil {
+			return fmt.Errorf("load stored rules: %w", err)
+		}
+
+		if flagSince > 0 && flagFrom > 0 {
+			return errors.New("--since and --from are mutually exclusive")
+		}
+		if flagBackfill {
+			if flagFrom == 0 || flagTo < flagFrom {
+				return errors.New("--backfill needs --from and --to")
+			}
+			// A backfill is one pass over its range.
+			flagOnce = true
+		}
+		var since string
+		if flagSince > 0 {
+			since = blocktime.Prefix + time.Now().Add(-flagSince).UTC().Format(time.RFC3339)
+		}
+
+		devgenOpts := devgen.Options{BlockTime: flagDevgenBlock, EventsPerBlock: flagDevgenEvents, Seed: flagDevgenSeed}
+		if flagDevgen {
+			log.Warn("devgen enabled: sources produce synthetic blocks instead of calling RPC")
+		}
+
 		evmClients := map[string]evm.BlockClient{}
-		algoClients := map[string]algorand.AlgodClient{}
+		pings := map[string]func(context.Context) error{}
 		evmScanners := map[string]*evm.Scanner{}
-		algoScanners := map[string]*algorand.Scanner{}
+		scanners := map[string]engine.Scanner{}
+		ingestSources := map[string]*ingest.Source{}
 
 		for _, src := range cfg.Sources {
 			switch src.Type {
 			case "evm":
 				if flagFrom > 0 {
 					src.StartBlock = fmt.Sprintf("%d", flagFrom)
+				} else if since != "" {
+					src.StartBlock = since
+				}
+				confirmations := cfg.Global.Confirmations["evm"]
+				if flagDevgen {
+					abis, _ := evm.LoadABIs(src.ABIPaths())
+					chain, err := devgen.NewEVMChain(devgenOpts, src.ID, cfg.Rules, abis)
+					if err != nil {
+						return err
+					}
+					evmClients[src.ID] = chain
+					sc, err := evm.NewScanner(chain, store, src, confirmations.Depth, abis, cfg.Rules)
+					if err != nil {
+						return err
+					}
+					sc.SetFinality(confirmations.Tag)
+					evmScanners[src.ID] = sc
+					continue
 				}
-				cli, err := evm.NewRPCClient(src.RPCURL)
+				rpcCli, err := evm.Dial(src.RPCURL, src.RPCHeaders, src.RPCBasicAuth)
 				if err != nil {
 					return err
 				}
+				cli := evm.NewLimitedClient(rpcCli, src.MaxRPS)
 				evmClients[src.ID] = cli
-				abis, _ := evm.LoadABIs(src.ABIDirs)
-				confirmations := cfg.Global.Confirmations["evm"]
-				sc, err := evm.NewScanner(cli, store, src, confirmations, abis, cfg.Rules)
+				if src.ABIFetch != nil {
+					fetcher := evm.NewABIFetcher(*src.ABIFetch)
+					fetcher.SetStateReader(cli)
+					if err := fetcher.Fetch(ctx, evm.RuleContracts(src.ID, cfg.Rules)); err != nil {
+						log.Warn("abi fetch incomplete", "source", src.ID, "error", err)
+					}
+				}
+				abis, _ := evm.LoadABIs(src.ABIPaths())
+				sc, err := evm.NewScanner(cli, store, src, confirmations.Depth, abis, cfg.Rules)
 				if err != nil {
 					return err
 				}
-				evmScanners[src.ID] = sc
-			case "algorand":
-				if flagFrom > 0 {
-					src.StartRound = fmt.Sprintf("%d", flagFrom)
+				sc.SetFinality(confirmations.Tag)
+				sc.SetTokenResolver(evm.NewTokenResolver(cli, store, src.ID))
+				if src.ENS {
+					ens := evm.NewENSResolver(cli, store, src.ID)
+					if src.ENSRegistry != "" {
+						ens.SetRegistry(common.HexToAddress(src.ENSRegistry))
+					}
+					sc.SetENSResolver(ens)
 				}
-				cli, err := algorand.NewAlgodClient(src.AlgodURL)
+				evmScanners[src.ID] = sc
+			case "ingest":
+				s, err := ingest.NewSource(store, src, cfg.Rules)
 				if err != nil {
 					return err
 				}
-				algoClients[src.ID] = cli
-				confirmations := cfg.Global.Confirmations["algorand"]
-				sc, err := algorand.NewScanner(cli, store, src, confirmations, cfg.Rules)
-				if err != nil {
-					return err
+				ingestSources[src.ID] = s
+			default:
+				chain, ok := engine.Chains[src.Type]
+				if !ok {
+					continue
 				}
-				algoScanners[src.ID] = sc
-			}
-		}
-
-		sinks := map[string]sink.Sender{}
-		for _, s := range cfg.Sinks {
-			switch s.Type {
-			case "slack":
-				sender, err := sink.NewSlackSender(s.WebhookURL, s.Template)
-				if err != nil {
-					return err
+				if flagFrom > 0 {
+					chain.SetStart(&src, fmt.Sprintf("%d", flagFrom))
+				} else if since != "" {
+					chain.SetStart(&src, since)
 				}
-				sinks[s.ID] = sender
-			case "teams":
-				sender, err := sink.NewTeamsSender(s.WebhookURL, s.Template)
-				if err != nil {
-					return err
+				var cli any
+				switch {
+				case flagDevgen && src.Type == algorand.Chain:
+					cli = devgen.NewAlgoChain(devgenOpts, src.ID, cfg.Rules)
+				case flagDevgen:
+					return fmt.Errorf("source %s: --devgen does not generate %s blocks", src.ID, src.Type)
+				default:
+					if cli, err = chain.Dial(src); err != nil {
+						return err
+					}
 				}
-				sinks[s.ID] = sender
-			case "webhook":
-				sender, err := sink.NewWebhookSender(s.URL, s.Method, s.Template, nil)
+				pings[src.ID] = func(ctx context.Context) error { return chain.Ping(ctx, cli) }
+				sc, err := chain.NewScanner(cli, store, src, cfg.Global.Confirmations[src.Type], cfg.Rules)
 				if err != nil {
 					return err
 				}
-				sinks[s.ID] = sender
-			default:
-				continue
+				scanners[src.ID] = sc
 			}
 		}
+		// sequencer_lag rules read the rollup's contract on its L1 source.
+		for _, src := range cfg.Sources {
+			if sc, ok := evmScanners[src.ID]; ok && src.L2 != nil {
+				sc.SetL1Client(evmClients[src.L2.L1Source])
+			}
+		}
+		for id, sc := range evmScanners {
+			scanners[id] = engine.NewEVMScanner(sc)
+		}
+
+		sinks, err := watchtower.NewSinks(cfg.Sinks, store)
+		if err != nil {
+			return err
+		}
 
 		var mtr *metrics.Metrics
 		if flagMetrics != "" {
@@ -133,7 +237,7 @@ var runCmd = &cobra.Command{
 		}
 
 		if flagHealth != "" {
-			rpcChecker := health.NewRPCChecker(evmClients, algoClients)
+			rpcChecker := health.NewRPCChecker(evmClients, pings)
 			healthSrv := health.Serve(flagHealth, health.Checker{
 				DBPing:  store.Ping,
 				RPCPing: rpcChecker.Ping,
@@ -157,13 +261,158 @@ var runCmd = &cobra.Command{
 			}()
 		}
 
-		runner, err := engine.NewRunner(store, cfg, evmScanners, algoScanners, sinks, flagDryRun, flagFrom, flagTo)
+		runner, err := engine.NewRunner(store, cfg, scanners, sinks, flagDryRun, flagFrom, flagTo)
 		if err != nil {
 			return err
 		}
+		if err := runner.LoadDisabledRules(ctx); err != nil {
+			return fmt.Errorf("load stored rules: %w", err)
+		}
+		runner.SetEventBuffer(flagBuffer)
+		for id, s := range ingestSources {
+			runner.SetIngest(id, s)
+		}
+		if flagOnce {
+			// A single tick has no later tick to retry on.
+			runner.SetFailureBudget(1)
+		} else {
+			runner.SetFailureBudget(flagBudget)
+		}
+		runner.SetLogger(log)
+		runner.SetMetrics(mtr)
+		if a := cfg.Global.Audit; a != nil && a.Table {
+			retention, _ := time.ParseDuration(a.Retention)
+			runner.SetAuditTable(retention)
+		}
+		if !flagOnce {
+			for id, cli := range evmClients {
+				if mc, ok := cli.(evm.MempoolClient); ok && evm.HasPendingRules(id, cfg.Rules) {
+					runner.SetMempool(id, evm.NewMempoolWatcher(mc, id, cfg.Rules))
+				}
+			}
+		}
+		if flagOnce {
+			if _, err := runner.SweepDedupe(ctx); err != nil {
+				log.Warn("dedupe sweep failed", "error", err)
+			}
+			if _, err := runner.SweepAudit(ctx); err != nil {
+				log.Warn("audit sweep failed", "error", err)
+			}
+		} else {
+			go runner.RunJanitor(ctx, engine.DefaultDedupeSweep, func(err error) {
+				log.Warn("janitor sweep failed", "error", err)
+			})
+			if every, _ := time.ParseDuration(cfg.Global.SuppressionSummary); every > 0 {
+				go runner.RunSuppressionSummaries(ctx, every, func(err error) {
+					log.Warn("suppression summary failed", "error", err)
+				})
+			}
+			for _, s := range cfg.Sinks {
+				if every, _ := time.ParseDuration(s.Heartbeat); every > 0 && !flagDryRun {
+					go runner.RunHeartbeat(ctx, s.ID, every, nil)
+				}
+			}
+			if runner.HasQuietHours() && !flagDryRun {
+				go runner.RunQuietHours(ctx, engine.DefaultQuietCheck, func(err error) {
+					log.Warn("quiet hours digest failed", "error", err)
+				})
+			}
+			go watchABIs(ctx, cfg.Sources, runner, log)
+			if runner.HasMempools() {
+				go runner.RunMempools(ctx, func(err error) {
+					log.Warn("mempool watcher failed", "error", err)
+				})
+			}
+		}
+		if lists := watchlist.NewReloader(cfg.Rules, runner.UpdateWatchlist, mtr, log); lists.Len() > 0 {
+			if err := lists.Refresh(ctx); err != nil {
+				return err
+			}
+			if !flagOnce {
+				go lists.Run(ctx)
+			}
+		}
+		if cfg.Prices != nil {
+			prices, err := price.New(cfg.Prices)
+			if err != nil {
+				return err
+			}
+			runner.SetAnnotator(prices)
+		}
 
+		var dispatcher *engine.Dispatcher
+		if flagWorkers > 0 && !flagDryRun {
+			dispatcher = engine.NewDispatcher(store, sinks, flagWorkers)
+			dispatcher.SetLogger(log)
+			runner.SetDispatcher(dispatcher)
+			if !flagOnce {
+				dispatchCtx, stopDispatch := context.WithCancel(ctx)
+				dispatchDone := make(chan struct{})
+				go func() {
+					defer close(dispatchDone)
+					dispatcher.Run(dispatchCtx)
+				}()
+				defer func() {
+					stopDispatch()
+					<-dispatchDone
+				}()
+			}
+		}
+
+		token := os.Getenv("WATCH_TOWER_API_TOKEN")
+		var hub *stream.Hub
+		if flagGRPC != "" || flagAPI != "" {
+			hub = stream.NewHub()
+			runner.SetPublisher(hub)
+		}
+
+		if flagGRPC != "" {
+			grpcSrv, err := api.ServeGRPC(flagGRPC, token, api.NewServer(store, hub, runner))
+			if err != nil {
+				return err
+			}
+			log.Info("grpc api enabled", "addr", flagGRPC, "auth", token != "")
+			defer grpcSrv.GracefulStop()
+		}
+
+		if flagAPI != "" {
+			apiSrv := api.ServeHTTP(flagAPI, api.NewHTTPServer(store, hub, runner, token))
+			log.Info("http api enabled", "addr", flagAPI, "auth", token != "")
+			defer func() {
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = api.Shutdown(shutdownCtx, apiSrv)
+			}()
+		}
+
+		if len(ingestSources) > 0 && !flagOnce {
+			srvs, err := ingest.ServeAll(cfg.Sources, ingestSources)
+			if err != nil {
+				return err
+			}
+			for addr := range srvs {
+				log.Info("ingest listener enabled", "addr", addr)
+			}
+			defer func() {
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				for _, srv := range srvs {
+					_ = ingest.Shutdown(shutdownCtx, srv)
+				}
+			}()
+		}
+
+		tick := runner.RunOnce
+		if flagBackfill {
+			if len(scanners) > len(evmScanners) {
+				log.Warn("backfill covers evm sources only")
+			}
+			tick = func(ctx context.Context) error {
+				return runner.Backfill(ctx, flagFrom, flagTo, flagBackfillWindow)
+			}
+		}
 		for {
-			if err := runner.RunOnce(ctx); err != nil {
+			if err := tick(ctx); err != nil {
 				if mtr != nil {
 					mtr.Errors()
 				}
@@ -175,10 +424,72 @@ var runCmd = &cobra.Command{
 			}
 			log.Info("tick complete", "dry_run", flagDryRun)
 			if flagOnce {
+				if runner.HasQuietHours() && !flagDryRun {
+					if err := runner.FlushQuietHours(ctx); err != nil {
+						log.Warn("quiet hours digest failed", "error", err)
+					}
+				}
+				if dispatcher != nil {
+					if err := dispatcher.Drain(ctx); err != nil {
+						return fmt.Errorf("deliver alerts: %w", err)
+					}
+				}
 				break
 			}
-			time.Sleep(1 * time.Second)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-runner.Wake():
+			case <-time.After(1 * time.Second):
+			}
 		}
 		return nil
 	},
 }
+
+// watchABIs reloads each EVM source's abi_dirs when they change on disk, or
+// all of them on SIGHUP, until ctx is cancelled.
+func watchABIs(ctx context.Context, sources []config.Source, runner *engine.Runner, log *slog.Logger) {
+	watchers := map[string]*evm.ABIWatcher{}
+	for _, src := range sources {
+		if src.Type != "evm" || len(src.ABIPaths()) == 0 {
+			continue
+		}
+		id := src.ID
+		watchers[id] = evm.NewABIWatcher(src.ABIPaths(), func(abis map[string]*abi.ABI) error {
+			return runner.ReloadABIs(id, abis)
+		})
+	}
+	if len(watchers) == 0 {
+		return
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(evm.DefaultABIPoll)
+	defer ticker.Stop()
+	for {
+		forced := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-hup:
+			forced = true
+		}
+		for id, w := range watchers {
+			var reloaded bool
+			var err error
+			if forced {
+				reloaded, err = true, w.Reload()
+			} else {
+				reloaded, err = w.Check()
+			}
+			if err != nil {
+				log.Warn("abi reload failed", "source", id, "error", err)
+			} else if reloaded {
+				log.Info("abis reloaded", "source", id)
+			}
+		}
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,18 +34,29 @@ var validateCmd = &cobra.Command{
 		failures := 0
 
 		for _, src := range cfg.Sources {
+			header := config.HTTPHeaders(src.RPCHeaders, src.RPCBasicAuth)
 			switch strings.ToLower(src.Type) {
 			case "evm":
-				chainID, err := pingEVM(cmd.Context(), client, src.RPCURL)
-				if err != nil {
+				failed := false
+				for i, url := range src.RPCURL {
+					label := src.ID
+					if len(src.RPCURL) > 1 {
+						label = fmt.Sprintf("%s[%d]", src.ID, i)
+					}
+					chainID, err := pingEVM(cmd.Context(), client, url, header)
+					if err != nil {
+						failed = true
+						fmt.Fprintf(out, "- source %s (evm): ERROR %v\n", label, err)
+						continue
+					}
+					fmt.Fprintf(out, "- source %s (evm): chainId %s OK\n", label, chainID)
+				}
+				if failed {
 					failures++
-					fmt.Fprintf(out, "- source %s (evm): ERROR %v\n", src.ID, err)
-					continue
 				}
-				fmt.Fprintf(out, "- source %s (evm): chainId %s OK\n", src.ID, chainID)
 			case "algorand":
-				algodVer, algodErr := pingAlgod(cmd.Context(), client, src.AlgodURL)
-				indexerVer, indexerErr := pingAlgod(cmd.Context(), client, src.IndexerURL)
+				algodVer, algodErr := pingAlgod(cmd.Context(), client, src.AlgodURL, src.AlgodHeaders())
+				indexerVer, indexerErr := pingAlgod(cmd.Context(), client, src.IndexerURL, src.IndexerHeaders())
 
 				if algodErr != nil || indexerErr != nil {
 					failures++
@@ -50,6 +64,128 @@ var validateCmd = &cobra.Command{
 					continue
 				}
 				fmt.Fprintf(out, "- source %s (algorand): algod %s, indexer %s OK\n", src.ID, algodVer, indexerVer)
+			case "solana":
+				failed := false
+				for i, url := range src.RPCURL {
+					label := src.ID
+					if len(src.RPCURL) > 1 {
+						label = fmt.Sprintf("%s[%d]", src.ID, i)
+					}
+					version, err := pingSolana(cmd.Context(), client, url, header)
+					if err != nil {
+						failed = true
+						fmt.Fprintf(out, "- source %s (solana): ERROR %v\n", label, err)
+						continue
+					}
+					fmt.Fprintf(out, "- source %s (solana): solana-core %s OK\n", label, version)
+				}
+				if failed {
+					failures++
+				}
+			case "bitcoin":
+				urls, ping := src.RPCURL, pingBitcoind
+				if len(src.EsploraURL) > 0 {
+					urls, ping = src.EsploraURL, pingEsplora
+				}
+				failed := false
+				for i, url := range urls {
+					label := src.ID
+					if len(urls) > 1 {
+						label = fmt.Sprintf("%s[%d]", src.ID, i)
+					}
+					status, err := ping(cmd.Context(), client, url, header)
+					if err != nil {
+						failed = true
+						fmt.Fprintf(out, "- source %s (bitcoin): ERROR %v\n", label, err)
+						continue
+					}
+					fmt.Fprintf(out, "- source %s (bitcoin): %s OK\n", label, status)
+				}
+				if failed {
+					failures++
+				}
+			case "cosmos":
+				failed := false
+				for i, url := range src.RPCURL {
+					label := src.ID
+					if len(src.RPCURL) > 1 {
+						label = fmt.Sprintf("%s[%d]", src.ID, i)
+					}
+					status, err := pingCometBFT(cmd.Context(), client, url, header)
+					if err != nil {
+						failed = true
+						fmt.Fprintf(out, "- source %s (cosmos): ERROR %v\n", label, err)
+						continue
+					}
+					fmt.Fprintf(out, "- source %s (cosmos): %s OK\n", label, status)
+				}
+				if failed {
+					failures++
+				}
+			case "substrate":
+				failed := false
+				for i, url := range src.RPCURL {
+					label := src.ID
+					if len(src.RPCURL) > 1 {
+						label = fmt.Sprintf("%s[%d]", src.ID, i)
+					}
+					status, err := pingSubstrate(cmd.Context(), client, url, header)
+					if err != nil {
+						failed = true
+						fmt.Fprintf(out, "- source %s (substrate): ERROR %v\n", label, err)
+						continue
+					}
+					fmt.Fprintf(out, "- source %s (substrate): %s OK\n", label, status)
+				}
+				if failed {
+					failures++
+				}
+			case "near":
+				failed := false
+				for i, url := range src.RPCURL {
+					label := src.ID
+					if len(src.RPCURL) > 1 {
+						label = fmt.Sprintf("%s[%d]", src.ID, i)
+					}
+					status, err := pingNear(cmd.Context(), client, url, header)
+					if err != nil {
+						failed = true
+						fmt.Fprintf(out, "- source %s (near): ERROR %v\n", label, err)
+						continue
+					}
+					fmt.Fprintf(out, "- source %s (near): %s OK\n", label, status)
+				}
+				if failed {
+					failures++
+				}
+			case "tron":
+				failed := false
+				for i, url := range src.RPCURL {
+					label := src.ID
+					if len(src.RPCURL) > 1 {
+						label = fmt.Sprintf("%s[%d]", src.ID, i)
+					}
+					status, err := pingTron(cmd.Context(), client, url, header)
+					if err != nil {
+						failed = true
+						fmt.Fprintf(out, "- source %s (tron): ERROR %v\n", label, err)
+						continue
+					}
+					fmt.Fprintf(out, "- source %s (tron): %s OK\n", label, status)
+				}
+				if failed {
+					failures++
+				}
+			case "ingest":
+				// Nothing to ping; check the address can be listened on.
+				lis, err := net.Listen("tcp", src.Listen)
+				if err != nil {
+					failures++
+					fmt.Fprintf(out, "- source %s (ingest): ERROR %v\n", src.ID, err)
+					continue
+				}
+				_ = lis.Close()
+				fmt.Fprintf(out, "- source %s (ingest): listen %s OK\n", src.ID, src.Listen)
 			default:
 				failures++
 				fmt.Fprintf(out, "- source %s: unsupported type %s\n", src.ID, src.Type)
@@ -65,7 +201,7 @@ var validateCmd = &cobra.Command{
 	},
 }
 
-func pingEVM(ctx context.Context, client *http.Client, url string) (string, error) {
+func pingEVM(ctx context.Context, client *http.Client, url string, header http.Header) (string, error) {
 	payload := map[string]any{
 		"jsonrpc": "2.0",
 		"id":      1,
@@ -81,6 +217,7 @@ func pingEVM(ctx context.Context, client *http.Client, url string) (string, erro
 	if err != nil {
 		return "", fmt.Errorf("build request: %w", err)
 	}
+	req.Header = header.Clone()
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := client.Do(req)
@@ -113,12 +250,223 @@ func pingEVM(ctx context.Context, client *http.Client, url string) (string, erro
 	return rpcResp.Result, nil
 }
 
-func pingAlgod(ctx context.Context, client *http.Client, baseURL string) (string, error) {
+func pingSolana(ctx context.Context, client *http.Client, url string, header http.Header) (string, error) {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "getVersion"})
+	if err != nil {
+		return "", fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("call getVersion: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("rpc status %d", resp.StatusCode)
+	}
+
+	var rpcResp struct {
+		Result struct {
+			Core string `json:"solana-core"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return "", fmt.Errorf("decode rpc response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return "", fmt.Errorf("rpc error: %s", rpcResp.Error.Message)
+	}
+	if rpcResp.Result.Core == "" {
+		return "unknown", nil
+	}
+	return rpcResp.Result.Core, nil
+}
+
+func pingBitcoind(ctx context.Context, client *http.Client, url string, header http.Header) (string, error) {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "1.0", "id": "watch-tower", "method": "getblockchaininfo", "params": []any{}})
+	if err != nil {
+		return "", fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("call getblockchaininfo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// bitcoind answers RPC errors with a 500 and the error in the body.
+	var rpcResp struct {
+		Result struct {
+			Chain  string `json:"chain"`
+			Blocks uint64 `json:"blocks"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		if resp.StatusCode >= 400 {
+			return "", fmt.Errorf("rpc status %d", resp.StatusCode)
+		}
+		return "", fmt.Errorf("decode rpc response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return "", fmt.Errorf("rpc error: %s", rpcResp.Error.Message)
+	}
+	return fmt.Sprintf("bitcoind chain %s, height %d", rpcResp.Result.Chain, rpcResp.Result.Blocks), nil
+}
+
+func pingCometBFT(ctx context.Context, client *http.Client, url string, header http.Header) (string, error) {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "status", "params": map[string]any{}})
+	if err != nil {
+		return "", fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("call status: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("rpc status %d", resp.StatusCode)
+	}
+
+	var rpcResp struct {
+		Result struct {
+			NodeInfo struct {
+				Network string `json:"network"`
+				Version string `json:"version"`
+			} `json:"node_info"`
+			SyncInfo struct {
+				LatestBlockHeight string `json:"latest_block_height"`
+			} `json:"sync_info"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return "", fmt.Errorf("decode rpc response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return "", fmt.Errorf("rpc error: %s", rpcResp.Error.Message)
+	}
+	r := rpcResp.Result
+	return fmt.Sprintf("cometbft %s, chain %s, height %s", r.NodeInfo.Version, r.NodeInfo.Network, r.SyncInfo.LatestBlockHeight), nil
+}
+
+func pingSubstrate(ctx context.Context, client *http.Client, url string, header http.Header) (string, error) {
+	call := func(method string, out any) error {
+		body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": []any{}})
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("build request: %w", err)
+		}
+		req.Header = header.Clone()
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("call %s: %w", method, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("rpc status %d", resp.StatusCode)
+		}
+
+		var rpcResp struct {
+			Result json.RawMessage `json:"result"`
+			Error  *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+			return fmt.Errorf("decode rpc response: %w", err)
+		}
+		if rpcResp.Error != nil {
+			return fmt.Errorf("rpc error: %s", rpcResp.Error.Message)
+		}
+		return json.Unmarshal(rpcResp.Result, out)
+	}
+
+	var version struct {
+		SpecName    string `json:"specName"`
+		SpecVersion uint32 `json:"specVersion"`
+	}
+	if err := call("state_getRuntimeVersion", &version); err != nil {
+		return "", err
+	}
+	var head struct {
+		Number string `json:"number"`
+	}
+	if err := call("chain_getHeader", &head); err != nil {
+		return "", err
+	}
+	height, err := strconv.ParseUint(strings.TrimPrefix(head.Number, "0x"), 16, 64)
+	if err != nil {
+		return "", fmt.Errorf("block number: %w", err)
+	}
+	return fmt.Sprintf("runtime %s/%d, height %d", version.SpecName, version.SpecVersion, height), nil
+}
+
+func pingEsplora(ctx context.Context, client *http.Client, baseURL string, header http.Header) (string, error) {
+	url := strings.TrimRight(baseURL, "/") + "/blocks/tip/height"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header = header.Clone()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("call %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("esplora status %d", resp.StatusCode)
+	}
+	var height uint64
+	if err := json.NewDecoder(resp.Body).Decode(&height); err != nil {
+		return "", fmt.Errorf("decode tip height: %w", err)
+	}
+	return fmt.Sprintf("esplora height %d", height), nil
+}
+
+func pingAlgod(ctx context.Context, client *http.Client, baseURL string, header http.Header) (string, error) {
 	url := strings.TrimRight(baseURL, "/") + "/versions"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return "", fmt.Errorf("build request: %w", err)
 	}
+	req.Header = header.Clone()
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -141,3 +489,94 @@ func pingAlgod(ctx context.Context, client *http.Client, baseURL string) (string
 	}
 	return body.Versions[0], nil
 }
+
+func pingNear(ctx context.Context, client *http.Client, url string, header http.Header) (string, error) {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "status", "params": []any{}})
+	if err != nil {
+		return "", fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("call status: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("rpc status %d", resp.StatusCode)
+	}
+
+	var rpcResp struct {
+		Result struct {
+			ChainID string `json:"chain_id"`
+			Version struct {
+				Version string `json:"version"`
+			} `json:"version"`
+			SyncInfo struct {
+				LatestBlockHeight uint64 `json:"latest_block_height"`
+				Syncing           bool   `json:"syncing"`
+			} `json:"sync_info"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return "", fmt.Errorf("decode rpc response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return "", fmt.Errorf("rpc error: %s", rpcResp.Error.Message)
+	}
+	r := rpcResp.Result
+	if r.SyncInfo.Syncing {
+		return "", fmt.Errorf("node is syncing, at height %d", r.SyncInfo.LatestBlockHeight)
+	}
+	return fmt.Sprintf("nearcore %s, chain %s, height %d", r.Version.Version, r.ChainID, r.SyncInfo.LatestBlockHeight), nil
+}
+
+func pingTron(ctx context.Context, client *http.Client, url string, header http.Header) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(url, "/")+"/wallet/getnowblock", strings.NewReader("{}"))
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("call getnowblock: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("api status %d", resp.StatusCode)
+	}
+
+	var block struct {
+		BlockID     string `json:"blockID"`
+		BlockHeader struct {
+			RawData struct {
+				Number    uint64 `json:"number"`
+				Timestamp int64  `json:"timestamp"`
+			} `json:"raw_data"`
+		} `json:"block_header"`
+		Error string `json:"Error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&block); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	if block.Error != "" {
+		return "", fmt.Errorf("api error: %s", block.Error)
+	}
+	if block.BlockID == "" {
+		return "", errors.New("no block in response")
+	}
+	h := block.BlockHeader.RawData
+	return fmt.Sprintf("height %d, block time %s", h.Number, time.UnixMilli(h.Timestamp).UTC().Format(time.RFC3339)), nil
+}
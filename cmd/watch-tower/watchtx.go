@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/source/evm"
+	"github.com/devblac/watch-tower/pkg/watchtower"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+)
+
+// watchTxRuleID is the rule id of watch-tx completion alerts.
+const watchTxRuleID = "watch-tx"
+
+var (
+	flagTxSource        string
+	flagTxHash          string
+	flagTxSinks         []string
+	flagTxConfirmations int
+	flagTxInterval      time.Duration
+	flagTxDropAfter     time.Duration
+	flagTxDryRun        bool
+)
+
+func init() {
+	watchTxCmd.Flags().StringVar(&flagTxSource, "source", "", "EVM source to watch the transaction on")
+	watchTxCmd.Flags().StringVar(&flagTxHash, "tx", "", "Transaction hash")
+	watchTxCmd.Flags().StringSliceVar(&flagTxSinks, "sink", nil, "Sink to send the completion alert to (repeatable; default all sinks)")
+	watchTxCmd.Flags().IntVar(&flagTxConfirmations, "confirmations", -1, "Confirmations to wait for (default global.confirmations.evm)")
+	watchTxCmd.Flags().DurationVar(&flagTxInterval, "interval", 5*time.Second, "How often to poll the node")
+	watchTxCmd.Flags().DurationVar(&flagTxDropAfter, "drop-after", evm.DefaultDropAfter, "Report the transaction dropped once the node has not known it this long")
+	watchTxCmd.Flags().BoolVar(&flagTxDryRun, "dry-run", false, "Do not send the completion alert")
+	_ = watchTxCmd.MarkFlagRequired("source")
+	_ = watchTxCmd.MarkFlagRequired("tx")
+}
+
+var watchTxCmd = &cobra.Command{
+	Use:   "watch-tx",
+	Short: "Wait for a transaction to confirm, drop, or be reorged, then alert",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		out := cmd.OutOrStdout()
+
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		var src *config.Source
+		for i := range cfg.Sources {
+			if cfg.Sources[i].ID == flagTxSource {
+				src = &cfg.Sources[i]
+			}
+		}
+		if src == nil {
+			return fmt.Errorf("unknown source %q", flagTxSource)
+		}
+		if src.Type != "evm" {
+			return fmt.Errorf("source %s: watch-tx supports evm sources only", src.ID)
+		}
+		hash := common.HexToHash(flagTxHash)
+		if len(common.FromHex(flagTxHash)) != common.HashLength {
+			return fmt.Errorf("invalid transaction hash %q", flagTxHash)
+		}
+
+		all, err := watchtower.NewSinks(cfg.Sinks, nil)
+		if err != nil {
+			return err
+		}
+		sinks := all
+		if len(flagTxSinks) > 0 {
+			sinks = map[string]sink.Sender{}
+			for _, id := range flagTxSinks {
+				s, ok := all[id]
+				if !ok {
+					return fmt.Errorf("unknown sink %q", id)
+				}
+				sinks[id] = s
+			}
+		}
+
+		conf := cfg.Global.Confirmations["evm"]
+		confirmations := conf.Depth
+		switch {
+		case flagTxConfirmations >= 0:
+			confirmations = uint64(flagTxConfirmations)
+		case conf.Tag != "":
+			return fmt.Errorf("global.confirmations.evm is %q; pass --confirmations to set a depth", conf.Tag)
+		}
+		rpcCli, err := evm.Dial(src.RPCURL, src.RPCHeaders, src.RPCBasicAuth)
+		if err != nil {
+			return err
+		}
+		w := evm.NewTxWatcher(evm.NewLimitedClient(rpcCli, src.MaxRPS), hash, confirmations)
+		w.SetDropAfter(flagTxDropAfter)
+		fmt.Fprintf(out, "watching %s on %s for %d confirmations\n", hash.Hex(), src.ID, confirmations)
+		st, err := w.Wait(ctx, flagTxInterval)
+		if err != nil {
+			return err
+		}
+
+		payload := txPayload(*src, hash, st)
+		fmt.Fprintf(out, "%s: %s (%s) at height %d\n", hash.Hex(), st.Outcome, payload.Args["status"], st.Height)
+		if !flagTxDryRun {
+			for id, s := range sinks {
+				if err := s.Send(ctx, payload); err != nil {
+					return fmt.Errorf("send to %s: %w", id, err)
+				}
+			}
+		}
+		if st.Outcome != evm.TxConfirmed || st.Reverted {
+			return fmt.Errorf("transaction %s %s", st.Outcome, payload.Args["status"])
+		}
+		return nil
+	},
+}
+
+// txPayload builds the completion alert. Templates can branch on
+// {{.Args.outcome}} and, for mined transactions, {{.Args.status}}.
+func txPayload(src config.Source, hash common.Hash, st evm.TxStatus) sink.EventPayload {
+	status := "not_mined"
+	switch {
+	case st.Height == 0:
+	case st.Reverted:
+		status = "reverted"
+	default:
+		status = "success"
+	}
+	p := sink.EventPayload{
+		RuleID:    watchTxRuleID,
+		Chain:     "evm",
+		SourceID:  src.ID,
+		Height:    st.Height,
+		TxHash:    hash.Hex(),
+		Timestamp: time.Now().UTC(),
+		Args: map[string]any{
+			"outcome":       st.Outcome,
+			"status":        status,
+			"confirmations": st.Confirmations,
+			"gas_used":      st.GasUsed,
+		},
+		Explorer: src.ExplorerURL,
+	}
+	if st.Height > 0 {
+		p.Hash = st.BlockHash.Hex()
+	}
+	return p
+}
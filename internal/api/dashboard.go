@@ -0,0 +1,134 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+//go:embed dashboard
+var dashboardFS embed.FS
+
+// dashboardWindow bounds the rule hit and sink delivery counters.
+const dashboardWindow = 24 * time.Hour
+
+// DashboardSource is a source row on the dashboard.
+type DashboardSource struct {
+	ID        string    `json:"id"`
+	Chain     string    `json:"chain,omitempty"`
+	Paused    bool      `json:"paused"`
+	Height    uint64    `json:"height"`
+	Tip       uint64    `json:"tip"`
+	Lag       uint64    `json:"lag"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DashboardAlert is a recent alert row on the dashboard.
+type DashboardAlert struct {
+	ID        string    `json:"id"`
+	RuleID    string    `json:"rule_id"`
+	TxHash    string    `json:"tx_hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DashboardRule is a rule hit counter on the dashboard.
+type DashboardRule struct {
+	RuleID string    `json:"rule_id"`
+	Hits   int       `json:"hits"`
+	LastAt time.Time `json:"last_at"`
+}
+
+// DashboardSink is a sink delivery summary on the dashboard.
+type DashboardSink struct {
+	SinkID     string    `json:"sink_id"`
+	Sent       int       `json:"sent"`
+	Failed     int       `json:"failed"`
+	LastStatus string    `json:"last_status"`
+	LastAt     time.Time `json:"last_at"`
+}
+
+// DashboardSummary is the payload behind the embedded dashboard.
+type DashboardSummary struct {
+	Sources []DashboardSource `json:"sources"`
+	Alerts  []DashboardAlert  `json:"alerts"`
+	Rules   []DashboardRule   `json:"rules"`
+	Sinks   []DashboardSink   `json:"sinks"`
+}
+
+func dashboardHandler() http.Handler {
+	sub, _ := fs.Sub(dashboardFS, "dashboard")
+	return http.StripPrefix("/dashboard/", http.FileServer(http.FS(sub)))
+}
+
+func (s *HTTPServer) handleDashboardSummary(w http.ResponseWriter, r *http.Request) {
+	sum, err := s.dashboardSummary(r)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, sum)
+}
+
+func (s *HTTPServer) dashboardSummary(r *http.Request) (DashboardSummary, error) {
+	ctx := r.Context()
+	sum := DashboardSummary{
+		Sources: []DashboardSource{},
+		Alerts:  []DashboardAlert{},
+		Rules:   []DashboardRule{},
+		Sinks:   []DashboardSink{},
+	}
+
+	cursors, err := s.store.ListCursors(ctx)
+	if err != nil {
+		return sum, err
+	}
+	byID := make(map[string]storage.Cursor, len(cursors))
+	for _, c := range cursors {
+		byID[c.SourceID] = c
+	}
+	if s.ctl != nil {
+		for _, st := range s.ctl.Sources() {
+			src := DashboardSource{ID: st.ID, Chain: st.Chain, Paused: st.Paused, Tip: st.Tip}
+			if c, ok := byID[st.ID]; ok {
+				src.Height, src.UpdatedAt = c.Height, c.UpdatedAt
+			}
+			if src.Tip > src.Height {
+				src.Lag = src.Tip - src.Height
+			}
+			sum.Sources = append(sum.Sources, src)
+		}
+	} else {
+		for _, c := range cursors {
+			sum.Sources = append(sum.Sources, DashboardSource{ID: c.SourceID, Height: c.Height, UpdatedAt: c.UpdatedAt})
+		}
+	}
+
+	alerts, err := s.store.ListAlerts(ctx, storage.AlertFilter{Limit: 25})
+	if err != nil {
+		return sum, err
+	}
+	for _, a := range alerts {
+		sum.Alerts = append(sum.Alerts, DashboardAlert{ID: a.ID, RuleID: a.RuleID, TxHash: a.TxHash, CreatedAt: a.CreatedAt})
+	}
+
+	since := time.Now().Add(-dashboardWindow)
+	hits, err := s.store.ListRuleHits(ctx, since)
+	if err != nil {
+		return sum, err
+	}
+	for _, h := range hits {
+		sum.Rules = append(sum.Rules, DashboardRule{RuleID: h.RuleID, Hits: h.Count, LastAt: h.LastAt})
+	}
+
+	sinks, err := s.store.ListSinkStats(ctx, since)
+	if err != nil {
+		return sum, err
+	}
+	for _, st := range sinks {
+		sum.Sinks = append(sum.Sinks, DashboardSink{SinkID: st.SinkID, Sent: st.Sent, Failed: st.Failed, LastStatus: st.LastStatus, LastAt: st.LastAt})
+	}
+	return sum, nil
+}
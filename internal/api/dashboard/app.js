@@ -0,0 +1,75 @@
+// Polls the dashboard summary and renders it. A token in ?access_token= is
+// forwarded so the page works when WATCH_TOWER_API_TOKEN is set.
+(function () {
+  const token = new URLSearchParams(location.search).get("access_token");
+  const summaryURL = "/api/v1/dashboard" + (token ? "?access_token=" + encodeURIComponent(token) : "");
+  const lagWarn = 10;
+
+  function ago(ts) {
+    if (!ts || ts.startsWith("0001-")) return "—";
+    const s = Math.round((Date.now() - new Date(ts).getTime()) / 1000);
+    if (s < 60) return s + "s ago";
+    if (s < 3600) return Math.round(s / 60) + "m ago";
+    if (s < 86400) return Math.round(s / 3600) + "h ago";
+    return Math.round(s / 86400) + "d ago";
+  }
+
+  function cell(text, cls) {
+    const td = document.createElement("td");
+    td.textContent = text;
+    if (cls) td.className = cls;
+    return td;
+  }
+
+  function fill(id, rows, cols, render) {
+    const body = document.querySelector("#" + id + " tbody");
+    body.replaceChildren();
+    if (!rows.length) {
+      const tr = document.createElement("tr");
+      const td = cell("nothing yet", "empty");
+      td.colSpan = cols;
+      tr.appendChild(td);
+      body.appendChild(tr);
+      return;
+    }
+    for (const row of rows) {
+      const tr = document.createElement("tr");
+      for (const td of render(row)) tr.appendChild(td);
+      body.appendChild(tr);
+    }
+  }
+
+  async function refresh() {
+    let sum;
+    try {
+      const resp = await fetch(summaryURL);
+      if (!resp.ok) throw new Error("HTTP " + resp.status);
+      sum = await resp.json();
+    } catch (err) {
+      document.getElementById("updated").textContent = "error: " + err.message;
+      return;
+    }
+    fill("sources", sum.sources, 7, (s) => [
+      cell(s.id),
+      cell(s.chain || "—"),
+      cell(s.height, "num"),
+      cell(s.tip || "—", "num"),
+      cell(s.tip ? s.lag : "—", "num " + (s.lag > lagWarn ? "warn" : "")),
+      cell(ago(s.updated_at)),
+      cell(s.paused ? "paused" : "running", s.paused ? "warn" : "ok"),
+    ]);
+    fill("rules", sum.rules, 3, (r) => [cell(r.rule_id), cell(r.hits, "num"), cell(ago(r.last_at))]);
+    fill("sinks", sum.sinks, 5, (s) => [
+      cell(s.sink_id),
+      cell(s.sent, "num"),
+      cell(s.failed, "num " + (s.failed ? "bad" : "")),
+      cell(s.last_status, s.last_status === "sent" ? "ok" : "bad"),
+      cell(ago(s.last_at)),
+    ]);
+    fill("alerts", sum.alerts, 3, (a) => [cell(ago(a.created_at)), cell(a.rule_id), cell(a.tx_hash || "—", "mono")]);
+    document.getElementById("updated").textContent = "updated " + new Date().toLocaleTimeString();
+  }
+
+  refresh();
+  setInterval(refresh, 5000);
+})();
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+func TestDashboard(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	if err := store.UpsertCursor(ctx, "evm_main", 90, "0xabc"); err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+	if err := store.InsertAlert(ctx, storage.Alert{ID: "a1", RuleID: "big_transfer", TxHash: "0xtx"}); err != nil {
+		t.Fatalf("alert: %v", err)
+	}
+	if err := store.InsertSend(ctx, storage.Send{AlertID: "a1", SinkID: "slack", Status: "failed"}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	ctl := &fakeController{paused: map[string]bool{}, rules: map[string]config.Rule{}, tip: 100}
+	srv := httptest.NewServer(NewHTTPServer(store, nil, ctl, "").Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("get index: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "<title>watch-tower</title>") {
+		t.Fatalf("unexpected index: %d %s", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/v1/dashboard")
+	if err != nil {
+		t.Fatalf("get summary: %v", err)
+	}
+	defer resp.Body.Close()
+	var sum DashboardSummary
+	if err := json.NewDecoder(resp.Body).Decode(&sum); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(sum.Sources) != 1 || sum.Sources[0].Height != 90 || sum.Sources[0].Lag != 10 {
+		t.Fatalf("unexpected sources: %+v", sum.Sources)
+	}
+	if len(sum.Alerts) != 1 || len(sum.Rules) != 1 || sum.Rules[0].Hits != 1 {
+		t.Fatalf("unexpected alerts/rules: %+v %+v", sum.Alerts, sum.Rules)
+	}
+	if len(sum.Sinks) != 1 || sum.Sinks[0].Failed != 1 || sum.Sinks[0].LastStatus != "failed" {
+		t.Fatalf("unexpected sinks: %+v", sum.Sinks)
+	}
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/api/pb"
+	"github.com/devblac/watch-tower/internal/engine"
+	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/storage"
+	"github.com/devblac/watch-tower/internal/stream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements the WatchTower gRPC service on top of the store and event hub.
+type Server struct {
+	pb.UnimplementedWatchTowerServer
+
+	store   *storage.Store
+	hub     *stream.Hub
+	ctl     Controller
+	nowFunc func() time.Time
+}
+
+// NewServer builds the service. hub may be nil, in which case StreamEvents is
+// unavailable, and ctl may be nil, in which case SetCursor is.
+func NewServer(store *storage.Store, hub *stream.Hub, ctl Controller) *Server {
+	return &Server{store: store, hub: hub, ctl: ctl, nowFunc: time.Now}
+}
+
+// ServeGRPC listens on addr and serves the WatchTower service in the background.
+// When token is non-empty every call must carry "authorization: Bearer <token>";
+// when it is empty, calls that change state are refused.
+func ServeGRPC(addr, token string, srv *Server) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen grpc: %w", err)
+	}
+	gs := grpc.NewServer(authOptions(token)...)
+	pb.RegisterWatchTowerServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	return gs, nil
+}
+
+// adminMethods change runtime state; like the HTTP admin endpoints they
+// always require a token.
+var adminMethods = map[string]bool{
+	pb.WatchTower_SetCursor_FullMethodName:     true,
+	pb.WatchTower_CreateSilence_FullMethodName: true,
+	pb.WatchTower_DeleteSilence_FullMethodName: true,
+}
+
+// authOptions installs the interceptors that check every call against token.
+func authOptions(token string) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+			if err := authorize(ctx, info.FullMethod, token); err != nil {
+				return nil, err
+			}
+			return h(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(s any, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+			if err := authorize(ss.Context(), info.FullMethod, token); err != nil {
+				return err
+			}
+			return h(s, ss)
+		}),
+	}
+}
+
+// authorize checks a call to method. Without a configured token, read-only
+// calls are open and admin ones are denied.
+func authorize(ctx context.Context, method, token string) error {
+	if token == "" {
+		if adminMethods[method] {
+			return status.Error(codes.PermissionDenied, "admin calls require WATCH_TOWER_API_TOKEN")
+		}
+		return nil
+	}
+	return checkToken(ctx, token)
+}
+
+// checkToken accepts a call whose authorization metadata is "Bearer <token>".
+// A token without the scheme is rejected.
+func checkToken(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		got, ok := bearer(v)
+		if ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing token")
+}
+
+// bearer returns the credentials of a "Bearer" authorization value. The
+// scheme is matched without regard to case, as RFC 7235 has it.
+func bearer(v string) (string, bool) {
+	const scheme = "Bearer "
+	if len(v) <= len(scheme) || !strings.EqualFold(v[:len(scheme)], scheme) {
+		return "", false
+	}
+	return v[len(scheme):], true
+}
+
+// ListAlerts returns recorded alerts, newest first.
+func (s *Server) ListAlerts(ctx context.Context, req *pb.ListAlertsRequest) (*pb.ListAlertsResponse, error) {
+	f := storage.AlertFilter{RuleID: req.GetRuleId(), Limit: int(req.GetLimit())}
+	if req.GetSince() != nil {
+		f.Since = req.GetSince().AsTime()
+	}
+	alerts, err := s.store.ListAlerts(ctx, f)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &pb.ListAlertsResponse{}
+	for _, a := range alerts {
+		resp.Alerts = append(resp.Alerts, &pb.Alert{
+			Id:          a.ID,
+			RuleId:      a.RuleID,
+			Fingerprint: a.Fingerprint,
+			TxHash:      a.TxHash,
+			PayloadJson: a.PayloadJSON,
+			CreatedAt:   timestamppb.New(a.CreatedAt),
+		})
+	}
+	return resp, nil
+}
+
+// ListCursors returns every stored source cursor.
+func (s *Server) ListCursors(ctx context.Context, _ *pb.ListCursorsRequest) (*pb.ListCursorsResponse, error) {
+	cursors, err := s.store.ListCursors(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &pb.ListCursorsResponse{}
+	for _, c := range cursors {
+		resp.Cursors = append(resp.Cursors, toPBCursor(c))
+	}
+	return resp, nil
+}
+
+// SetCursor overwrites a source cursor between ticks of the runner.
+func (s *Server) SetCursor(ctx context.Context, req *pb.SetCursorRequest) (*pb.Cursor, error) {
+	if s.ctl == nil {
+		return nil, status.Error(codes.Unavailable, "cursor control not enabled")
+	}
+	if req.GetSourceId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "source_id required")
+	}
+	if err := s.ctl.SetCursor(ctx, req.GetSourceId(), req.GetHeight(), req.GetHash()); err != nil {
+		if errors.Is(err, engine.ErrUnknownSource) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.Cursor{
+		SourceId:  req.GetSourceId(),
+		Height:    req.GetHeight(),
+		Hash:      req.GetHash(),
+		UpdatedAt: timestamppb.New(s.nowFunc()),
+	}, nil
+}
+
+// CreateSilence mutes a rule for the requested duration.
+func (s *Server) CreateSilence(ctx context.Context, req *pb.CreateSilenceRequest) (*pb.Silence, error) {
+	if req.GetRuleId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "rule_id required")
+	}
+	d, err := time.ParseDuration(req.GetDuration())
+	if err != nil || d <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid duration %q", req.GetDuration())
+	}
+	now := s.nowFunc()
+	sil := storage.Silence{
+		ID:        newID(),
+		RuleID:    req.GetRuleId(),
+		Reason:    req.GetReason(),
+		ExpiresAt: now.Add(d),
+		CreatedAt: now,
+	}
+	if err := s.store.InsertSilence(ctx, sil); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return toPBSilence(sil), nil
+}
+
+// ListSilences returns active silences.
+func (s *Server) ListSilences(ctx context.Context, _ *pb.ListSilencesRequest) (*pb.ListSilencesResponse, error) {
+	silences, err := s.store.ListSilences(ctx, s.nowFunc())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &pb.ListSilencesResponse{}
+	for _, sil := range silences {
+		resp.Silences = append(resp.Silences, toPBSilence(sil))
+	}
+	return resp, nil
+}
+
+// DeleteSilence removes a silence by id.
+func (s *Server) DeleteSilence(ctx context.Context, req *pb.DeleteSilenceRequest) (*pb.DeleteSilenceResponse, error) {
+	ok, err := s.store.DeleteSilence(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "silence %s not found", req.GetId())
+	}
+	return &pb.DeleteSilenceResponse{}, nil
+}
+
+// StreamEvents forwards matched events until the client disconnects.
+func (s *Server) StreamEvents(req *pb.StreamEventsRequest, out pb.WatchTower_StreamEventsServer) error {
+	if s.hub == nil {
+		return status.Error(codes.Unavailable, "event streaming not enabled")
+	}
+	events, cancel := s.hub.Subscribe(256)
+	defer cancel()
+
+	rules := map[string]struct{}{}
+	for _, id := range req.GetRuleIds() {
+		rules[id] = struct{}{}
+	}
+	for {
+		select {
+		case <-out.Context().Done():
+			return nil
+		case p, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if len(rules) > 0 {
+				if _, hit := rules[p.RuleID]; !hit {
+					continue
+				}
+			}
+			if req.GetChain() != "" && req.GetChain() != p.Chain {
+				continue
+			}
+			ev, err := sink.ProtoEvent(p)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err := out.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toPBCursor(c storage.Cursor) *pb.Cursor {
+	return &pb.Cursor{
+		SourceId:  c.SourceID,
+		Height:    c.Height,
+		Hash:      c.Hash,
+		UpdatedAt: timestamppb.New(c.UpdatedAt),
+	}
+}
+
+func toPBSilence(sil storage.Silence) *pb.Silence {
+	return &pb.Silence{
+		Id:        sil.ID,
+		RuleId:    sil.RuleID,
+		Reason:    sil.Reason,
+		ExpiresAt: timestamppb.New(sil.ExpiresAt),
+		CreatedAt: timestamppb.New(sil.CreatedAt),
+	}
+}
+
+func newID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/api/pb"
+	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/storage"
+	"github.com/devblac/watch-tower/internal/stream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T, srv *Server, opts ...grpc.ServerOption) pb.WatchTowerClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer(opts...)
+	pb.RegisterWatchTowerServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewWatchTowerClient(conn)
+}
+
+func newTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.Open(t.TempDir() + "/db.sqlite")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestGRPCCursorsAndSilences(t *testing.T) {
+	store := newTestStore(t)
+	client := newTestClient(t, NewServer(store, nil, &fakeController{store: store}))
+	ctx := context.Background()
+
+	if _, err := client.SetCursor(ctx, &pb.SetCursorRequest{SourceId: "evm_main", Height: 42, Hash: "0xabc"}); err != nil {
+		t.Fatalf("set cursor: %v", err)
+	}
+	cursors, err := client.ListCursors(ctx, &pb.ListCursorsRequest{})
+	if err != nil {
+		t.Fatalf("list cursors: %v", err)
+	}
+	if len(cursors.Cursors) != 1 || cursors.Cursors[0].Height != 42 {
+		t.Fatalf("unexpected cursors: %v", cursors.Cursors)
+	}
+	if _, err := client.SetCursor(ctx, &pb.SetCursorRequest{SourceId: "nope", Height: 1}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	sil, err := client.CreateSilence(ctx, &pb.CreateSilenceRequest{RuleId: "r1", Duration: "1h", Reason: "maintenance"})
+	if err != nil {
+		t.Fatalf("create silence: %v", err)
+	}
+	if silenced, _ := store.IsSilenced(ctx, "r1", time.Now()); !silenced {
+		t.Fatalf("expected r1 silenced")
+	}
+	if _, err := client.CreateSilence(ctx, &pb.CreateSilenceRequest{RuleId: "r1", Duration: "soon"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected invalid argument, got %v", err)
+	}
+	if _, err := client.DeleteSilence(ctx, &pb.DeleteSilenceRequest{Id: sil.Id}); err != nil {
+		t.Fatalf("delete silence: %v", err)
+	}
+	if _, err := client.DeleteSilence(ctx, &pb.DeleteSilenceRequest{Id: sil.Id}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestGRPCStreamEventsFiltersRules(t *testing.T) {
+	hub := stream.NewHub()
+	client := newTestClient(t, NewServer(newTestStore(t), hub, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	st, err := client.StreamEvents(ctx, &pb.StreamEventsRequest{RuleIds: []string{"wanted"}})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Subscribers() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	idx := uint(7)
+	hub.Publish(sink.EventPayload{RuleID: "other", Chain: "evm"})
+	hub.Publish(sink.EventPayload{RuleID: "wanted", Chain: "evm", LogIndex: &idx, Args: map[string]any{"value": 10}})
+
+	ev, err := st.Recv()
+	if err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	if ev.RuleId != "wanted" || ev.GetLogIndex() != 7 || ev.Args.Fields["value"].GetNumberValue() != 10 {
+		t.Fatalf("unexpected event: %v", ev)
+	}
+}
+
+func TestCheckToken(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer s3cret"))
+	if err := checkToken(ctx, "s3cret"); err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+	if err := checkToken(context.Background(), "s3cret"); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected unauthenticated, got %v", err)
+	}
+	for _, v := range []string{"s3cret", "Basic s3cret", "Bearer", "Bearer wrong"} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", v))
+		if err := checkToken(ctx, "s3cret"); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("authorization %q: expected unauthenticated, got %v", v, err)
+		}
+	}
+}
+
+func TestGRPCAdminCallsRequireToken(t *testing.T) {
+	store := newTestStore(t)
+	ctl := &fakeController{store: store}
+	client := newTestClient(t, NewServer(store, nil, ctl), authOptions("")...)
+	ctx := context.Background()
+
+	if _, err := client.SetCursor(ctx, &pb.SetCursorRequest{SourceId: "evm_main", Height: 42}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	if _, err := client.ListCursors(ctx, &pb.ListCursorsRequest{}); err != nil {
+		t.Fatalf("list cursors without token: %v", err)
+	}
+
+	authed := newTestClient(t, NewServer(store, nil, ctl), authOptions("s3cret")...)
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer s3cret")
+	if _, err := authed.SetCursor(ctx, &pb.SetCursorRequest{SourceId: "evm_main", Height: 42}); err != nil {
+		t.Fatalf("set cursor with token: %v", err)
+	}
+}
@@ -0,0 +1,354 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/engine"
+	"github.com/devblac/watch-tower/internal/storage"
+	"github.com/devblac/watch-tower/internal/stream"
+)
+
+// sseKeepAlive is how often an idle event stream sends a comment line so
+// proxies and load balancers keep the connection open.
+const sseKeepAlive = 15 * time.Second
+
+// Controller is the subset of the runner driven by admin endpoints and calls.
+type Controller interface {
+	Sources() []engine.SourceStatus
+	Pause(sourceID string) error
+	Resume(sourceID string) error
+	Skip(ctx context.Context, sourceID string) (uint64, error)
+	SetCursor(ctx context.Context, sourceID string, height uint64, hash string) error
+	Trigger()
+	Rules() []engine.RuleStatus
+	ApplyRule(ctx context.Context, rule config.Rule) error
+	DisableRule(ctx context.Context, ruleID string) error
+	EnableRule(ctx context.Context, ruleID string) error
+}
+
+// HTTPServer serves the HTTP API.
+type HTTPServer struct {
+	store *storage.Store
+	hub   *stream.Hub
+	ctl   Controller
+	token string
+	mux   *http.ServeMux
+}
+
+// NewHTTPServer builds the HTTP API. When token is non-empty, requests must
+// carry "Authorization: Bearer <token>" (or ?access_token= for EventSource clients).
+// Admin endpoints are only registered when ctl is set and a token is configured.
+// The dashboard is served at /dashboard/ when store is set.
+func NewHTTPServer(store *storage.Store, hub *stream.Hub, ctl Controller, token string) *HTTPServer {
+	s := &HTTPServer{store: store, hub: hub, ctl: ctl, token: token, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/api/v1/events/stream", s.authorized(s.handleEventStream))
+	if store != nil {
+		s.mux.Handle("GET /dashboard/", dashboardHandler())
+		s.mux.Handle("GET /{$}", http.RedirectHandler("/dashboard/", http.StatusFound))
+		s.mux.HandleFunc("GET /api/v1/dashboard", s.authorized(s.handleDashboardSummary))
+		s.mux.HandleFunc("GET /api/v1/audit", s.authorized(s.handleAudit))
+	}
+	if ctl != nil {
+		s.mux.HandleFunc("GET /api/v1/sources", s.authorized(s.handleSources))
+		s.mux.HandleFunc("POST /api/v1/admin/sources/{id}/pause", s.admin(s.handlePause))
+		s.mux.HandleFunc("POST /api/v1/admin/sources/{id}/resume", s.admin(s.handleResume))
+		s.mux.HandleFunc("POST /api/v1/admin/sources/{id}/skip", s.admin(s.handleSkip))
+		s.mux.HandleFunc("POST /api/v1/admin/tick", s.admin(s.handleTick))
+		s.mux.HandleFunc("GET /api/v1/rules", s.authorized(s.handleRules))
+		s.mux.HandleFunc("PUT /api/v1/admin/rules/{id}", s.admin(s.handlePutRule))
+		s.mux.HandleFunc("POST /api/v1/admin/rules/{id}/disable", s.admin(s.handleDisableRule))
+		s.mux.HandleFunc("POST /api/v1/admin/rules/{id}/enable", s.admin(s.handleEnableRule))
+	}
+	return s
+}
+
+// Handler returns the root handler.
+func (s *HTTPServer) Handler() http.Handler {
+	return s.mux
+}
+
+// ServeHTTP starts the HTTP API on addr in the background.
+func ServeHTTP(addr string, s *HTTPServer) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 3 * time.Second,
+	}
+	go func() { _ = srv.ListenAndServe() }()
+	return srv
+}
+
+// Shutdown gracefully stops the HTTP API.
+func Shutdown(ctx context.Context, srv *http.Server) error {
+	return srv.Shutdown(ctx)
+}
+
+func (s *HTTPServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" && !validToken(r, s.token) {
+			writeError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// admin wraps handlers that change runtime state; they always require a token.
+func (s *HTTPServer) admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token == "" {
+			writeError(w, http.StatusForbidden, "admin endpoints require WATCH_TOWER_API_TOKEN")
+			return
+		}
+		s.authorized(next)(w, r)
+	}
+}
+
+// validToken checks "Authorization: Bearer <token>", or ?access_token= when
+// there is no Authorization header. A header without the Bearer scheme is
+// rejected rather than compared as a bare token.
+func validToken(r *http.Request, token string) bool {
+	got := r.URL.Query().Get("access_token")
+	if h := r.Header.Get("Authorization"); h != "" {
+		var ok bool
+		if got, ok = bearer(h); !ok {
+			return false
+		}
+	}
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// handleEventStream streams matched events as server-sent events.
+// Optional filters: ?rule=<id> (repeatable) and ?chain=<chain>.
+func (s *HTTPServer) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.hub == nil {
+		writeError(w, http.StatusServiceUnavailable, "event streaming not enabled")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	rules := map[string]struct{}{}
+	for _, id := range r.URL.Query()["rule"] {
+		rules[id] = struct{}{}
+	}
+	chain := r.URL.Query().Get("chain")
+
+	events, cancel := s.hub.Subscribe(256)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case p, ok := <-events:
+			if !ok {
+				return
+			}
+			if len(rules) > 0 {
+				if _, hit := rules[p.RuleID]; !hit {
+					continue
+				}
+			}
+			if chain != "" && chain != p.Chain {
+				continue
+			}
+			data, err := json.Marshal(p)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: match\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *HTTPServer) handleSources(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"sources": s.ctl.Sources()})
+}
+
+func (s *HTTPServer) handlePause(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.ctl.Pause(id); err != nil {
+		writeControlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"source": id, "paused": true})
+}
+
+func (s *HTTPServer) handleResume(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.ctl.Resume(id); err != nil {
+		writeControlError(w, err)
+		return
+	}
+	s.ctl.Trigger()
+	writeJSON(w, http.StatusOK, map[string]any{"source": id, "paused": false})
+}
+
+func (s *HTTPServer) handleSkip(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	height, err := s.ctl.Skip(r.Context(), id)
+	if err != nil {
+		writeControlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"source": id, "skipped": height})
+}
+
+func (s *HTTPServer) handleTick(w http.ResponseWriter, _ *http.Request) {
+	s.ctl.Trigger()
+	writeJSON(w, http.StatusAccepted, map[string]any{"triggered": true})
+}
+
+func (s *HTTPServer) handleRules(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"rules": s.ctl.Rules()})
+}
+
+// handlePutRule creates or replaces a rule. The body is a rule in the same
+// shape as the config file (JSON field names match the YAML keys).
+func (s *HTTPServer) handlePutRule(w http.ResponseWriter, r *http.Request) {
+	var rule config.Rule
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rule); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("decode rule: %v", err))
+		return
+	}
+	id := r.PathValue("id")
+	if rule.ID == "" {
+		rule.ID = id
+	}
+	if rule.ID != id {
+		writeError(w, http.StatusBadRequest, "rule id does not match path")
+		return
+	}
+	if err := s.ctl.ApplyRule(r.Context(), rule); err != nil {
+		writeControlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rule": rule})
+}
+
+func (s *HTTPServer) handleDisableRule(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.ctl.DisableRule(r.Context(), id); err != nil {
+		writeControlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rule": id, "disabled": true})
+}
+
+func (s *HTTPServer) handleEnableRule(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.ctl.EnableRule(r.Context(), id); err != nil {
+		writeControlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rule": id, "disabled": false})
+}
+
+// AuditEntry is one audit record as returned by GET /api/v1/audit.
+type AuditEntry struct {
+	RuleID    string    `json:"rule_id"`
+	SourceID  string    `json:"source_id,omitempty"`
+	Height    uint64    `json:"height,omitempty"`
+	TxHash    string    `json:"tx_hash,omitempty"`
+	AlertID   string    `json:"alert_id,omitempty"`
+	SinkID    string    `json:"sink_id,omitempty"`
+	Decision  string    `json:"decision"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handleAudit lists stored audit records newest first. Optional filters:
+// ?rule=<id>, ?tx=<hash>, ?since=<RFC 3339 time>, and ?limit=<n>.
+func (s *HTTPServer) handleAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := storage.AuditFilter{RuleID: q.Get("rule"), TxHash: q.Get("tx")}
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since must be an RFC 3339 time")
+			return
+		}
+		f.Since = since
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		f.Limit = n
+	}
+	recs, err := s.store.ListAudit(r.Context(), f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	out := make([]AuditEntry, 0, len(recs))
+	for _, a := range recs {
+		out = append(out, AuditEntry{
+			RuleID:    a.RuleID,
+			SourceID:  a.SourceID,
+			Height:    a.Height,
+			TxHash:    a.TxHash,
+			AlertID:   a.AlertID,
+			SinkID:    a.SinkID,
+			Decision:  a.Decision,
+			Detail:    a.Detail,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"audit": out})
+}
+
+func writeControlError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, engine.ErrUnknownSource), errors.Is(err, engine.ErrUnknownRule):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, engine.ErrInvalidRule):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/engine"
+	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/storage"
+	"github.com/devblac/watch-tower/internal/stream"
+)
+
+func TestEventStreamSSE(t *testing.T) {
+	hub := stream.NewHub()
+	srv := httptest.NewServer(NewHTTPServer(nil, hub, nil, "").Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/events/stream?rule=wanted")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Subscribers() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	hub.Publish(sink.EventPayload{RuleID: "other"})
+	hub.Publish(sink.EventPayload{RuleID: "wanted", Chain: "evm", TxHash: "0xabc"})
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			if !strings.Contains(line, `"RuleID":"wanted"`) || !strings.Contains(line, `"TxHash":"0xabc"`) {
+				t.Fatalf("unexpected event: %s", line)
+			}
+			return
+		}
+	}
+}
+
+func TestHTTPRequiresToken(t *testing.T) {
+	srv := httptest.NewServer(NewHTTPServer(nil, stream.NewHub(), nil, "s3cret").Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/events/stream")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+type fakeController struct {
+	paused    map[string]bool
+	triggered int
+	rules     map[string]config.Rule
+	tip       uint64
+	store     *storage.Store
+}
+
+func (f *fakeController) Sources() []engine.SourceStatus {
+	return []engine.SourceStatus{{ID: "evm_main", Chain: "evm", Paused: f.paused["evm_main"], Tip: f.tip}}
+}
+
+func (f *fakeController) Pause(id string) error {
+	if id != "evm_main" {
+		return engine.ErrUnknownSource
+	}
+	f.paused[id] = true
+	return nil
+}
+
+func (f *fakeController) Resume(id string) error {
+	if id != "evm_main" {
+		return engine.ErrUnknownSource
+	}
+	f.paused[id] = false
+	return nil
+}
+
+func (f *fakeController) Skip(_ context.Context, id string) (uint64, error) {
+	if id != "evm_main" {
+		return 0, engine.ErrUnknownSource
+	}
+	return 101, nil
+}
+
+func (f *fakeController) SetCursor(ctx context.Context, id string, height uint64, hash string) error {
+	if id != "evm_main" {
+		return engine.ErrUnknownSource
+	}
+	return f.store.UpsertCursor(ctx, id, height, hash)
+}
+
+func (f *fakeController) Trigger() { f.triggered++ }
+
+func (f *fakeController) Rules() []engine.RuleStatus {
+	var out []engine.RuleStatus
+	for _, r := range f.rules {
+		out = append(out, engine.RuleStatus{Rule: r})
+	}
+	return out
+}
+
+func (f *fakeController) ApplyRule(_ context.Context, rule config.Rule) error {
+	if rule.Source != "evm_main" {
+		return fmt.Errorf("%w: unknown source: %s", engine.ErrInvalidRule, rule.Source)
+	}
+	f.rules[rule.ID] = rule
+	return nil
+}
+
+func (f *fakeController) DisableRule(_ context.Context, id string) error {
+	if _, ok := f.rules[id]; !ok {
+		return engine.ErrUnknownRule
+	}
+	return nil
+}
+
+func (f *fakeController) EnableRule(_ context.Context, id string) error {
+	return f.DisableRule(context.Background(), id)
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	ctl := &fakeController{paused: map[string]bool{}, rules: map[string]config.Rule{}}
+	srv := httptest.NewServer(NewHTTPServer(nil, nil, ctl, "s3cret").Handler())
+	defer srv.Close()
+
+	post := func(path, token string) int {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("/api/v1/admin/sources/evm_main/pause", ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", code)
+	}
+	for _, auth := range []string{"s3cret", "Basic s3cret", "Bearer wrong"} {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/admin/sources/evm_main/pause", nil)
+		req.Header.Set("Authorization", auth)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || ctl.paused["evm_main"] {
+			t.Fatalf("Authorization %q: expected 401, got %d", auth, resp.StatusCode)
+		}
+	}
+	if code := post("/api/v1/admin/sources/evm_main/pause", "s3cret"); code != http.StatusOK || !ctl.paused["evm_main"] {
+		t.Fatalf("pause failed: code=%d paused=%v", code, ctl.paused["evm_main"])
+	}
+	if code := post("/api/v1/admin/sources/evm_main/resume", "s3cret"); code != http.StatusOK || ctl.paused["evm_main"] {
+		t.Fatalf("resume failed: code=%d", code)
+	}
+	if code := post("/api/v1/admin/sources/nope/skip", "s3cret"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown source, got %d", code)
+	}
+	if code := post("/api/v1/admin/tick", "s3cret"); code != http.StatusAccepted || ctl.triggered != 2 {
+		t.Fatalf("tick failed: code=%d triggered=%d", code, ctl.triggered)
+	}
+}
+
+func TestAdminDisabledWithoutToken(t *testing.T) {
+	srv := httptest.NewServer(NewHTTPServer(nil, nil, &fakeController{paused: map[string]bool{}, rules: map[string]config.Rule{}}, "").Handler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/api/v1/admin/tick", "application/json", nil)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminRuleEndpoints(t *testing.T) {
+	ctl := &fakeController{paused: map[string]bool{}, rules: map[string]config.Rule{}}
+	srv := httptest.NewServer(NewHTTPServer(nil, nil, ctl, "s3cret").Handler())
+	defer srv.Close()
+
+	do := func(method, path, body string) int {
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	rule := `{"source":"evm_main","match":{"type":"log","contract":"0xabc","event":"Transfer(address,address,uint256)"},"sinks":["slack"]}`
+	if code := do(http.MethodPut, "/api/v1/admin/rules/big_transfer", rule); code != http.StatusOK {
+		t.Fatalf("put rule: %d", code)
+	}
+	if got := ctl.rules["big_transfer"]; got.ID != "big_transfer" || got.Match.Contract != "0xabc" {
+		t.Fatalf("unexpected rule: %+v", got)
+	}
+	// A single topic value may be given without a list, as in YAML.
+	topic := `{"source":"evm_main","match":{"type":"log","contract":"0xabc","event":"Transfer(address indexed from,address indexed to,uint256 value)","topics":{"to":"0xdef"}},"sinks":["slack"]}`
+	if code := do(http.MethodPut, "/api/v1/admin/rules/to_treasury", topic); code != http.StatusOK {
+		t.Fatalf("put rule with scalar topic: %d", code)
+	}
+	if got := ctl.rules["to_treasury"].Match.Topics["to"]; len(got) != 1 || got[0] != "0xdef" {
+		t.Fatalf("unexpected topics: %v", got)
+	}
+	if code := do(http.MethodPut, "/api/v1/admin/rules/other", `{"id":"mismatch"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for id mismatch, got %d", code)
+	}
+	if code := do(http.MethodPut, "/api/v1/admin/rules/bad", `{"source":"nope"}`); code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for invalid rule, got %d", code)
+	}
+	if code := do(http.MethodPost, "/api/v1/admin/rules/big_transfer/disable", ""); code != http.StatusOK {
+		t.Fatalf("disable: %d", code)
+	}
+	if code := do(http.MethodPost, "/api/v1/admin/rules/missing/enable", ""); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown rule, got %d", code)
+	}
+	if code := do(http.MethodGet, "/api/v1/rules", ""); code != http.StatusOK {
+		t.Fatalf("list rules: %d", code)
+	}
+}
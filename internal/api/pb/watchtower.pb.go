@@ -0,0 +1,1106 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: watchtower/v1/watchtower.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Alert struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	RuleId        string                 `protobuf:"bytes,2,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	Fingerprint   string                 `protobuf:"bytes,3,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	TxHash        string                 `protobuf:"bytes,4,opt,name=tx_hash,json=txHash,proto3" json:"tx_hash,omitempty"`
+	PayloadJson   string                 `protobuf:"bytes,5,opt,name=payload_json,json=payloadJson,proto3" json:"payload_json,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Alert) Reset() {
+	*x = Alert{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Alert) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Alert) ProtoMessage() {}
+
+func (x *Alert) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Alert.ProtoReflect.Descriptor instead.
+func (*Alert) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Alert) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Alert) GetRuleId() string {
+	if x != nil {
+		return x.RuleId
+	}
+	return ""
+}
+
+func (x *Alert) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *Alert) GetTxHash() string {
+	if x != nil {
+		return x.TxHash
+	}
+	return ""
+}
+
+func (x *Alert) GetPayloadJson() string {
+	if x != nil {
+		return x.PayloadJson
+	}
+	return ""
+}
+
+func (x *Alert) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type ListAlertsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RuleId        string                 `protobuf:"bytes,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	Since         *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=since,proto3" json:"since,omitempty"`
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAlertsRequest) Reset() {
+	*x = ListAlertsRequest{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAlertsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAlertsRequest) ProtoMessage() {}
+
+func (x *ListAlertsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAlertsRequest.ProtoReflect.Descriptor instead.
+func (*ListAlertsRequest) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListAlertsRequest) GetRuleId() string {
+	if x != nil {
+		return x.RuleId
+	}
+	return ""
+}
+
+func (x *ListAlertsRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *ListAlertsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListAlertsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alerts        []*Alert               `protobuf:"bytes,1,rep,name=alerts,proto3" json:"alerts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAlertsResponse) Reset() {
+	*x = ListAlertsResponse{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAlertsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAlertsResponse) ProtoMessage() {}
+
+func (x *ListAlertsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAlertsResponse.ProtoReflect.Descriptor instead.
+func (*ListAlertsResponse) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListAlertsResponse) GetAlerts() []*Alert {
+	if x != nil {
+		return x.Alerts
+	}
+	return nil
+}
+
+type Cursor struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SourceId      string                 `protobuf:"bytes,1,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	Height        uint64                 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Hash          string                 `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Cursor) Reset() {
+	*x = Cursor{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cursor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cursor) ProtoMessage() {}
+
+func (x *Cursor) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cursor.ProtoReflect.Descriptor instead.
+func (*Cursor) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Cursor) GetSourceId() string {
+	if x != nil {
+		return x.SourceId
+	}
+	return ""
+}
+
+func (x *Cursor) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Cursor) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Cursor) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListCursorsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCursorsRequest) Reset() {
+	*x = ListCursorsRequest{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCursorsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCursorsRequest) ProtoMessage() {}
+
+func (x *ListCursorsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCursorsRequest.ProtoReflect.Descriptor instead.
+func (*ListCursorsRequest) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{4}
+}
+
+type ListCursorsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cursors       []*Cursor              `protobuf:"bytes,1,rep,name=cursors,proto3" json:"cursors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCursorsResponse) Reset() {
+	*x = ListCursorsResponse{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCursorsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCursorsResponse) ProtoMessage() {}
+
+func (x *ListCursorsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCursorsResponse.ProtoReflect.Descriptor instead.
+func (*ListCursorsResponse) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListCursorsResponse) GetCursors() []*Cursor {
+	if x != nil {
+		return x.Cursors
+	}
+	return nil
+}
+
+type SetCursorRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SourceId      string                 `protobuf:"bytes,1,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	Height        uint64                 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Hash          string                 `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetCursorRequest) Reset() {
+	*x = SetCursorRequest{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetCursorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetCursorRequest) ProtoMessage() {}
+
+func (x *SetCursorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetCursorRequest.ProtoReflect.Descriptor instead.
+func (*SetCursorRequest) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SetCursorRequest) GetSourceId() string {
+	if x != nil {
+		return x.SourceId
+	}
+	return ""
+}
+
+func (x *SetCursorRequest) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *SetCursorRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+type Silence struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	RuleId        string                 `protobuf:"bytes,2,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Silence) Reset() {
+	*x = Silence{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Silence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Silence) ProtoMessage() {}
+
+func (x *Silence) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Silence.ProtoReflect.Descriptor instead.
+func (*Silence) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Silence) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Silence) GetRuleId() string {
+	if x != nil {
+		return x.RuleId
+	}
+	return ""
+}
+
+func (x *Silence) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Silence) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *Silence) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type CreateSilenceRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	RuleId string                 `protobuf:"bytes,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	// Go duration string, e.g. "30m" or "2h".
+	Duration      string `protobuf:"bytes,2,opt,name=duration,proto3" json:"duration,omitempty"`
+	Reason        string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSilenceRequest) Reset() {
+	*x = CreateSilenceRequest{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSilenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSilenceRequest) ProtoMessage() {}
+
+func (x *CreateSilenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSilenceRequest.ProtoReflect.Descriptor instead.
+func (*CreateSilenceRequest) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CreateSilenceRequest) GetRuleId() string {
+	if x != nil {
+		return x.RuleId
+	}
+	return ""
+}
+
+func (x *CreateSilenceRequest) GetDuration() string {
+	if x != nil {
+		return x.Duration
+	}
+	return ""
+}
+
+func (x *CreateSilenceRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type ListSilencesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSilencesRequest) Reset() {
+	*x = ListSilencesRequest{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSilencesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSilencesRequest) ProtoMessage() {}
+
+func (x *ListSilencesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSilencesRequest.ProtoReflect.Descriptor instead.
+func (*ListSilencesRequest) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{9}
+}
+
+type ListSilencesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Silences      []*Silence             `protobuf:"bytes,1,rep,name=silences,proto3" json:"silences,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSilencesResponse) Reset() {
+	*x = ListSilencesResponse{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSilencesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSilencesResponse) ProtoMessage() {}
+
+func (x *ListSilencesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSilencesResponse.ProtoReflect.Descriptor instead.
+func (*ListSilencesResponse) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListSilencesResponse) GetSilences() []*Silence {
+	if x != nil {
+		return x.Silences
+	}
+	return nil
+}
+
+type DeleteSilenceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSilenceRequest) Reset() {
+	*x = DeleteSilenceRequest{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSilenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSilenceRequest) ProtoMessage() {}
+
+func (x *DeleteSilenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSilenceRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSilenceRequest) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeleteSilenceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DeleteSilenceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSilenceResponse) Reset() {
+	*x = DeleteSilenceResponse{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSilenceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSilenceResponse) ProtoMessage() {}
+
+func (x *DeleteSilenceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSilenceResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSilenceResponse) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{12}
+}
+
+type StreamEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only stream events for these rules; empty means all rules.
+	RuleIds []string `protobuf:"bytes,1,rep,name=rule_ids,json=ruleIds,proto3" json:"rule_ids,omitempty"`
+	// Only stream events for this chain ("evm", "algorand"); empty means all.
+	Chain         string `protobuf:"bytes,2,opt,name=chain,proto3" json:"chain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *StreamEventsRequest) GetRuleIds() []string {
+	if x != nil {
+		return x.RuleIds
+	}
+	return nil
+}
+
+func (x *StreamEventsRequest) GetChain() string {
+	if x != nil {
+		return x.Chain
+	}
+	return ""
+}
+
+// Event is a matched event. Sinks with encoding: protobuf send it as the body.
+type Event struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	RuleId   string                 `protobuf:"bytes,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	Chain    string                 `protobuf:"bytes,2,opt,name=chain,proto3" json:"chain,omitempty"`
+	SourceId string                 `protobuf:"bytes,3,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	Height   uint64                 `protobuf:"varint,4,opt,name=height,proto3" json:"height,omitempty"`
+	Hash     string                 `protobuf:"bytes,5,opt,name=hash,proto3" json:"hash,omitempty"`
+	TxHash   string                 `protobuf:"bytes,6,opt,name=tx_hash,json=txHash,proto3" json:"tx_hash,omitempty"`
+	AppId    uint64                 `protobuf:"varint,7,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	LogIndex *uint32                `protobuf:"varint,8,opt,name=log_index,json=logIndex,proto3,oneof" json:"log_index,omitempty"`
+	Args     *structpb.Struct       `protobuf:"bytes,9,opt,name=args,proto3" json:"args,omitempty"`
+	// Block or round time of the event.
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Alert id, also sent to HTTP sinks as X-Correlation-ID.
+	AlertId string `protobuf:"bytes,11,opt,name=alert_id,json=alertId,proto3" json:"alert_id,omitempty"`
+	// Id of an earlier alert this one withdraws because its block was reorged out.
+	Retracts string `protobuf:"bytes,12,opt,name=retracts,proto3" json:"retracts,omitempty"`
+	// Block explorer base URL of the source, if configured.
+	Explorer string `protobuf:"bytes,13,opt,name=explorer,proto3" json:"explorer,omitempty"`
+	// Shared by related alerts, such as those with the same dedupe key.
+	Group string `protobuf:"bytes,14,opt,name=group,proto3" json:"group,omitempty"`
+	// Contract or application address that emitted the event, if any.
+	Contract      string `protobuf:"bytes,15,opt,name=contract,proto3" json:"contract,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_watchtower_v1_watchtower_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_watchtower_v1_watchtower_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Event) GetRuleId() string {
+	if x != nil {
+		return x.RuleId
+	}
+	return ""
+}
+
+func (x *Event) GetChain() string {
+	if x != nil {
+		return x.Chain
+	}
+	return ""
+}
+
+func (x *Event) GetSourceId() string {
+	if x != nil {
+		return x.SourceId
+	}
+	return ""
+}
+
+func (x *Event) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Event) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Event) GetTxHash() string {
+	if x != nil {
+		return x.TxHash
+	}
+	return ""
+}
+
+func (x *Event) GetAppId() uint64 {
+	if x != nil {
+		return x.AppId
+	}
+	return 0
+}
+
+func (x *Event) GetLogIndex() uint32 {
+	if x != nil && x.LogIndex != nil {
+		return *x.LogIndex
+	}
+	return 0
+}
+
+func (x *Event) GetArgs() *structpb.Struct {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *Event) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Event) GetAlertId() string {
+	if x != nil {
+		return x.AlertId
+	}
+	return ""
+}
+
+func (x *Event) GetRetracts() string {
+	if x != nil {
+		return x.Retracts
+	}
+	return ""
+}
+
+func (x *Event) GetExplorer() string {
+	if x != nil {
+		return x.Explorer
+	}
+	return ""
+}
+
+func (x *Event) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *Event) GetContract() string {
+	if x != nil {
+		return x.Contract
+	}
+	return ""
+}
+
+var File_watchtower_v1_watchtower_proto protoreflect.FileDescriptor
+
+const file_watchtower_v1_watchtower_proto_rawDesc = "" +
+	"\n" +
+	"\x1ewatchtower/v1/watchtower.proto\x12\rwatchtower.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xc9\x01\n" +
+	"\x05Alert\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\arule_id\x18\x02 \x01(\tR\x06ruleId\x12 \n" +
+	"\vfingerprint\x18\x03 \x01(\tR\vfingerprint\x12\x17\n" +
+	"\atx_hash\x18\x04 \x01(\tR\x06txHash\x12!\n" +
+	"\fpayload_json\x18\x05 \x01(\tR\vpayloadJson\x129\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"t\n" +
+	"\x11ListAlertsRequest\x12\x17\n" +
+	"\arule_id\x18\x01 \x01(\tR\x06ruleId\x120\n" +
+	"\x05since\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"B\n" +
+	"\x12ListAlertsResponse\x12,\n" +
+	"\x06alerts\x18\x01 \x03(\v2\x14.watchtower.v1.AlertR\x06alerts\"\x8c\x01\n" +
+	"\x06Cursor\x12\x1b\n" +
+	"\tsource_id\x18\x01 \x01(\tR\bsourceId\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x04R\x06height\x12\x12\n" +
+	"\x04hash\x18\x03 \x01(\tR\x04hash\x129\n" +
+	"\n" +
+	"updated_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\x14\n" +
+	"\x12ListCursorsRequest\"F\n" +
+	"\x13ListCursorsResponse\x12/\n" +
+	"\acursors\x18\x01 \x03(\v2\x15.watchtower.v1.CursorR\acursors\"[\n" +
+	"\x10SetCursorRequest\x12\x1b\n" +
+	"\tsource_id\x18\x01 \x01(\tR\bsourceId\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x04R\x06height\x12\x12\n" +
+	"\x04hash\x18\x03 \x01(\tR\x04hash\"\xc0\x01\n" +
+	"\aSilence\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\arule_id\x18\x02 \x01(\tR\x06ruleId\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x129\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"c\n" +
+	"\x14CreateSilenceRequest\x12\x17\n" +
+	"\arule_id\x18\x01 \x01(\tR\x06ruleId\x12\x1a\n" +
+	"\bduration\x18\x02 \x01(\tR\bduration\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"\x15\n" +
+	"\x13ListSilencesRequest\"J\n" +
+	"\x14ListSilencesResponse\x122\n" +
+	"\bsilences\x18\x01 \x03(\v2\x16.watchtower.v1.SilenceR\bsilences\"&\n" +
+	"\x14DeleteSilenceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x17\n" +
+	"\x15DeleteSilenceResponse\"F\n" +
+	"\x13StreamEventsRequest\x12\x19\n" +
+	"\brule_ids\x18\x01 \x03(\tR\aruleIds\x12\x14\n" +
+	"\x05chain\x18\x02 \x01(\tR\x05chain\"\xcb\x03\n" +
+	"\x05Event\x12\x17\n" +
+	"\arule_id\x18\x01 \x01(\tR\x06ruleId\x12\x14\n" +
+	"\x05chain\x18\x02 \x01(\tR\x05chain\x12\x1b\n" +
+	"\tsource_id\x18\x03 \x01(\tR\bsourceId\x12\x16\n" +
+	"\x06height\x18\x04 \x01(\x04R\x06height\x12\x12\n" +
+	"\x04hash\x18\x05 \x01(\tR\x04hash\x12\x17\n" +
+	"\atx_hash\x18\x06 \x01(\tR\x06txHash\x12\x15\n" +
+	"\x06app_id\x18\a \x01(\x04R\x05appId\x12 \n" +
+	"\tlog_index\x18\b \x01(\rH\x00R\blogIndex\x88\x01\x01\x12+\n" +
+	"\x04args\x18\t \x01(\v2\x17.google.protobuf.StructR\x04args\x128\n" +
+	"\ttimestamp\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x19\n" +
+	"\balert_id\x18\v \x01(\tR\aalertId\x12\x1a\n" +
+	"\bretracts\x18\f \x01(\tR\bretracts\x12\x1a\n" +
+	"\bexplorer\x18\r \x01(\tR\bexplorer\x12\x14\n" +
+	"\x05group\x18\x0e \x01(\tR\x05group\x12\x1a\n" +
+	"\bcontract\x18\x0f \x01(\tR\bcontractB\f\n" +
+	"\n" +
+	"_log_index2\xc9\x04\n" +
+	"\n" +
+	"WatchTower\x12Q\n" +
+	"\n" +
+	"ListAlerts\x12 .watchtower.v1.ListAlertsRequest\x1a!.watchtower.v1.ListAlertsResponse\x12T\n" +
+	"\vListCursors\x12!.watchtower.v1.ListCursorsRequest\x1a\".watchtower.v1.ListCursorsResponse\x12C\n" +
+	"\tSetCursor\x12\x1f.watchtower.v1.SetCursorRequest\x1a\x15.watchtower.v1.Cursor\x12L\n" +
+	"\rCreateSilence\x12#.watchtower.v1.CreateSilenceRequest\x1a\x16.watchtower.v1.Silence\x12W\n" +
+	"\fListSilences\x12\".watchtower.v1.ListSilencesRequest\x1a#.watchtower.v1.ListSilencesResponse\x12Z\n" +
+	"\rDeleteSilence\x12#.watchtower.v1.DeleteSilenceRequest\x1a$.watchtower.v1.DeleteSilenceResponse\x12J\n" +
+	"\fStreamEvents\x12\".watchtower.v1.StreamEventsRequest\x1a\x14.watchtower.v1.Event0\x01B3Z1github.com/devblac/watch-tower/internal/api/pb;pbb\x06proto3"
+
+var (
+	file_watchtower_v1_watchtower_proto_rawDescOnce sync.Once
+	file_watchtower_v1_watchtower_proto_rawDescData []byte
+)
+
+func file_watchtower_v1_watchtower_proto_rawDescGZIP() []byte {
+	file_watchtower_v1_watchtower_proto_rawDescOnce.Do(func() {
+		file_watchtower_v1_watchtower_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_watchtower_v1_watchtower_proto_rawDesc), len(file_watchtower_v1_watchtower_proto_rawDesc)))
+	})
+	return file_watchtower_v1_watchtower_proto_rawDescData
+}
+
+var file_watchtower_v1_watchtower_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_watchtower_v1_watchtower_proto_goTypes = []any{
+	(*Alert)(nil),                 // 0: watchtower.v1.Alert
+	(*ListAlertsRequest)(nil),     // 1: watchtower.v1.ListAlertsRequest
+	(*ListAlertsResponse)(nil),    // 2: watchtower.v1.ListAlertsResponse
+	(*Cursor)(nil),                // 3: watchtower.v1.Cursor
+	(*ListCursorsRequest)(nil),    // 4: watchtower.v1.ListCursorsRequest
+	(*ListCursorsResponse)(nil),   // 5: watchtower.v1.ListCursorsResponse
+	(*SetCursorRequest)(nil),      // 6: watchtower.v1.SetCursorRequest
+	(*Silence)(nil),               // 7: watchtower.v1.Silence
+	(*CreateSilenceRequest)(nil),  // 8: watchtower.v1.CreateSilenceRequest
+	(*ListSilencesRequest)(nil),   // 9: watchtower.v1.ListSilencesRequest
+	(*ListSilencesResponse)(nil),  // 10: watchtower.v1.ListSilencesResponse
+	(*DeleteSilenceRequest)(nil),  // 11: watchtower.v1.DeleteSilenceRequest
+	(*DeleteSilenceResponse)(nil), // 12: watchtower.v1.DeleteSilenceResponse
+	(*StreamEventsRequest)(nil),   // 13: watchtower.v1.StreamEventsRequest
+	(*Event)(nil),                 // 14: watchtower.v1.Event
+	(*timestamppb.Timestamp)(nil), // 15: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),       // 16: google.protobuf.Struct
+}
+var file_watchtower_v1_watchtower_proto_depIdxs = []int32{
+	15, // 0: watchtower.v1.Alert.created_at:type_name -> google.protobuf.Timestamp
+	15, // 1: watchtower.v1.ListAlertsRequest.since:type_name -> google.protobuf.Timestamp
+	0,  // 2: watchtower.v1.ListAlertsResponse.alerts:type_name -> watchtower.v1.Alert
+	15, // 3: watchtower.v1.Cursor.updated_at:type_name -> google.protobuf.Timestamp
+	3,  // 4: watchtower.v1.ListCursorsResponse.cursors:type_name -> watchtower.v1.Cursor
+	15, // 5: watchtower.v1.Silence.expires_at:type_name -> google.protobuf.Timestamp
+	15, // 6: watchtower.v1.Silence.created_at:type_name -> google.protobuf.Timestamp
+	7,  // 7: watchtower.v1.ListSilencesResponse.silences:type_name -> watchtower.v1.Silence
+	16, // 8: watchtower.v1.Event.args:type_name -> google.protobuf.Struct
+	15, // 9: watchtower.v1.Event.timestamp:type_name -> google.protobuf.Timestamp
+	1,  // 10: watchtower.v1.WatchTower.ListAlerts:input_type -> watchtower.v1.ListAlertsRequest
+	4,  // 11: watchtower.v1.WatchTower.ListCursors:input_type -> watchtower.v1.ListCursorsRequest
+	6,  // 12: watchtower.v1.WatchTower.SetCursor:input_type -> watchtower.v1.SetCursorRequest
+	8,  // 13: watchtower.v1.WatchTower.CreateSilence:input_type -> watchtower.v1.CreateSilenceRequest
+	9,  // 14: watchtower.v1.WatchTower.ListSilences:input_type -> watchtower.v1.ListSilencesRequest
+	11, // 15: watchtower.v1.WatchTower.DeleteSilence:input_type -> watchtower.v1.DeleteSilenceRequest
+	13, // 16: watchtower.v1.WatchTower.StreamEvents:input_type -> watchtower.v1.StreamEventsRequest
+	2,  // 17: watchtower.v1.WatchTower.ListAlerts:output_type -> watchtower.v1.ListAlertsResponse
+	5,  // 18: watchtower.v1.WatchTower.ListCursors:output_type -> watchtower.v1.ListCursorsResponse
+	3,  // 19: watchtower.v1.WatchTower.SetCursor:output_type -> watchtower.v1.Cursor
+	7,  // 20: watchtower.v1.WatchTower.CreateSilence:output_type -> watchtower.v1.Silence
+	10, // 21: watchtower.v1.WatchTower.ListSilences:output_type -> watchtower.v1.ListSilencesResponse
+	12, // 22: watchtower.v1.WatchTower.DeleteSilence:output_type -> watchtower.v1.DeleteSilenceResponse
+	14, // 23: watchtower.v1.WatchTower.StreamEvents:output_type -> watchtower.v1.Event
+	17, // [17:24] is the sub-list for method output_type
+	10, // [10:17] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_watchtower_v1_watchtower_proto_init() }
+func file_watchtower_v1_watchtower_proto_init() {
+	if File_watchtower_v1_watchtower_proto != nil {
+		return
+	}
+	file_watchtower_v1_watchtower_proto_msgTypes[14].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_watchtower_v1_watchtower_proto_rawDesc), len(file_watchtower_v1_watchtower_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_watchtower_v1_watchtower_proto_goTypes,
+		DependencyIndexes: file_watchtower_v1_watchtower_proto_depIdxs,
+		MessageInfos:      file_watchtower_v1_watchtower_proto_msgTypes,
+	}.Build()
+	File_watchtower_v1_watchtower_proto = out.File
+	file_watchtower_v1_watchtower_proto_goTypes = nil
+	file_watchtower_v1_watchtower_proto_depIdxs = nil
+}
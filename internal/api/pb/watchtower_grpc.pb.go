@@ -0,0 +1,371 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: watchtower/v1/watchtower.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	WatchTower_ListAlerts_FullMethodName    = "/watchtower.v1.WatchTower/ListAlerts"
+	WatchTower_ListCursors_FullMethodName   = "/watchtower.v1.WatchTower/ListCursors"
+	WatchTower_SetCursor_FullMethodName     = "/watchtower.v1.WatchTower/SetCursor"
+	WatchTower_CreateSilence_FullMethodName = "/watchtower.v1.WatchTower/CreateSilence"
+	WatchTower_ListSilences_FullMethodName  = "/watchtower.v1.WatchTower/ListSilences"
+	WatchTower_DeleteSilence_FullMethodName = "/watchtower.v1.WatchTower/DeleteSilence"
+	WatchTower_StreamEvents_FullMethodName  = "/watchtower.v1.WatchTower/StreamEvents"
+)
+
+// WatchTowerClient is the client API for WatchTower service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// WatchTower exposes programmatic control over a running watch-tower process.
+type WatchTowerClient interface {
+	// ListAlerts returns recorded alerts, newest first.
+	ListAlerts(ctx context.Context, in *ListAlertsRequest, opts ...grpc.CallOption) (*ListAlertsResponse, error)
+	// ListCursors returns the processing position of every source.
+	ListCursors(ctx context.Context, in *ListCursorsRequest, opts ...grpc.CallOption) (*ListCursorsResponse, error)
+	// SetCursor moves a source cursor; the next tick resumes at height + 1.
+	SetCursor(ctx context.Context, in *SetCursorRequest, opts ...grpc.CallOption) (*Cursor, error)
+	// CreateSilence mutes alerts for a rule ("*" for all rules) for a duration.
+	CreateSilence(ctx context.Context, in *CreateSilenceRequest, opts ...grpc.CallOption) (*Silence, error)
+	// ListSilences returns silences that are still active.
+	ListSilences(ctx context.Context, in *ListSilencesRequest, opts ...grpc.CallOption) (*ListSilencesResponse, error)
+	// DeleteSilence lifts a silence before it expires.
+	DeleteSilence(ctx context.Context, in *DeleteSilenceRequest, opts ...grpc.CallOption) (*DeleteSilenceResponse, error)
+	// StreamEvents pushes matched events as they are processed.
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type watchTowerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWatchTowerClient(cc grpc.ClientConnInterface) WatchTowerClient {
+	return &watchTowerClient{cc}
+}
+
+func (c *watchTowerClient) ListAlerts(ctx context.Context, in *ListAlertsRequest, opts ...grpc.CallOption) (*ListAlertsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAlertsResponse)
+	err := c.cc.Invoke(ctx, WatchTower_ListAlerts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *watchTowerClient) ListCursors(ctx context.Context, in *ListCursorsRequest, opts ...grpc.CallOption) (*ListCursorsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCursorsResponse)
+	err := c.cc.Invoke(ctx, WatchTower_ListCursors_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *watchTowerClient) SetCursor(ctx context.Context, in *SetCursorRequest, opts ...grpc.CallOption) (*Cursor, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Cursor)
+	err := c.cc.Invoke(ctx, WatchTower_SetCursor_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *watchTowerClient) CreateSilence(ctx context.Context, in *CreateSilenceRequest, opts ...grpc.CallOption) (*Silence, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Silence)
+	err := c.cc.Invoke(ctx, WatchTower_CreateSilence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *watchTowerClient) ListSilences(ctx context.Context, in *ListSilencesRequest, opts ...grpc.CallOption) (*ListSilencesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSilencesResponse)
+	err := c.cc.Invoke(ctx, WatchTower_ListSilences_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *watchTowerClient) DeleteSilence(ctx context.Context, in *DeleteSilenceRequest, opts ...grpc.CallOption) (*DeleteSilenceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSilenceResponse)
+	err := c.cc.Invoke(ctx, WatchTower_DeleteSilence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *watchTowerClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &WatchTower_ServiceDesc.Streams[0], WatchTower_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WatchTower_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+// WatchTowerServer is the server API for WatchTower service.
+// All implementations must embed UnimplementedWatchTowerServer
+// for forward compatibility.
+//
+// WatchTower exposes programmatic control over a running watch-tower process.
+type WatchTowerServer interface {
+	// ListAlerts returns recorded alerts, newest first.
+	ListAlerts(context.Context, *ListAlertsRequest) (*ListAlertsResponse, error)
+	// ListCursors returns the processing position of every source.
+	ListCursors(context.Context, *ListCursorsRequest) (*ListCursorsResponse, error)
+	// SetCursor moves a source cursor; the next tick resumes at height + 1.
+	SetCursor(context.Context, *SetCursorRequest) (*Cursor, error)
+	// CreateSilence mutes alerts for a rule ("*" for all rules) for a duration.
+	CreateSilence(context.Context, *CreateSilenceRequest) (*Silence, error)
+	// ListSilences returns silences that are still active.
+	ListSilences(context.Context, *ListSilencesRequest) (*ListSilencesResponse, error)
+	// DeleteSilence lifts a silence before it expires.
+	DeleteSilence(context.Context, *DeleteSilenceRequest) (*DeleteSilenceResponse, error)
+	// StreamEvents pushes matched events as they are processed.
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedWatchTowerServer()
+}
+
+// UnimplementedWatchTowerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedWatchTowerServer struct{}
+
+func (UnimplementedWatchTowerServer) ListAlerts(context.Context, *ListAlertsRequest) (*ListAlertsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAlerts not implemented")
+}
+func (UnimplementedWatchTowerServer) ListCursors(context.Context, *ListCursorsRequest) (*ListCursorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCursors not implemented")
+}
+func (UnimplementedWatchTowerServer) SetCursor(context.Context, *SetCursorRequest) (*Cursor, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCursor not implemented")
+}
+func (UnimplementedWatchTowerServer) CreateSilence(context.Context, *CreateSilenceRequest) (*Silence, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSilence not implemented")
+}
+func (UnimplementedWatchTowerServer) ListSilences(context.Context, *ListSilencesRequest) (*ListSilencesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSilences not implemented")
+}
+func (UnimplementedWatchTowerServer) DeleteSilence(context.Context, *DeleteSilenceRequest) (*DeleteSilenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSilence not implemented")
+}
+func (UnimplementedWatchTowerServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedWatchTowerServer) mustEmbedUnimplementedWatchTowerServer() {}
+func (UnimplementedWatchTowerServer) testEmbeddedByValue()                    {}
+
+// UnsafeWatchTowerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WatchTowerServer will
+// result in compilation errors.
+type UnsafeWatchTowerServer interface {
+	mustEmbedUnimplementedWatchTowerServer()
+}
+
+func RegisterWatchTowerServer(s grpc.ServiceRegistrar, srv WatchTowerServer) {
+	// If the following call pancis, it indicates UnimplementedWatchTowerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&WatchTower_ServiceDesc, srv)
+}
+
+func _WatchTower_ListAlerts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAlertsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchTowerServer).ListAlerts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WatchTower_ListAlerts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchTowerServer).ListAlerts(ctx, req.(*ListAlertsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WatchTower_ListCursors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCursorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchTowerServer).ListCursors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WatchTower_ListCursors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchTowerServer).ListCursors(ctx, req.(*ListCursorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WatchTower_SetCursor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCursorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchTowerServer).SetCursor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WatchTower_SetCursor_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchTowerServer).SetCursor(ctx, req.(*SetCursorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WatchTower_CreateSilence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSilenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchTowerServer).CreateSilence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WatchTower_CreateSilence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchTowerServer).CreateSilence(ctx, req.(*CreateSilenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WatchTower_ListSilences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSilencesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchTowerServer).ListSilences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WatchTower_ListSilences_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchTowerServer).ListSilences(ctx, req.(*ListSilencesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WatchTower_DeleteSilence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSilenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchTowerServer).DeleteSilence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WatchTower_DeleteSilence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchTowerServer).DeleteSilence(ctx, req.(*DeleteSilenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WatchTower_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WatchTowerServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WatchTower_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+// WatchTower_ServiceDesc is the grpc.ServiceDesc for WatchTower service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WatchTower_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "watchtower.v1.WatchTower",
+	HandlerType: (*WatchTowerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListAlerts",
+			Handler:    _WatchTower_ListAlerts_Handler,
+		},
+		{
+			MethodName: "ListCursors",
+			Handler:    _WatchTower_ListCursors_Handler,
+		},
+		{
+			MethodName: "SetCursor",
+			Handler:    _WatchTower_SetCursor_Handler,
+		},
+		{
+			MethodName: "CreateSilence",
+			Handler:    _WatchTower_CreateSilence_Handler,
+		},
+		{
+			MethodName: "ListSilences",
+			Handler:    _WatchTower_ListSilences_Handler,
+		},
+		{
+			MethodName: "DeleteSilence",
+			Handler:    _WatchTower_DeleteSilence_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _WatchTower_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "watchtower/v1/watchtower.proto",
+}
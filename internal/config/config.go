@@ -1,17 +1,76 @@
 package config
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/devblac/watch-tower/internal/source/blocktime"
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
 )
 
+// Block tags an EVM confirmation setting can name instead of a depth.
+const (
+	ConfirmFinalized = "finalized"
+	ConfirmSafe      = "safe"
+)
+
+// Confirmation is how far behind the chain head a source scans: a number of
+// blocks, or on EVM chains the "finalized" or "safe" block the node reports.
+// Solana sources take "finalized" too, and otherwise scan confirmed slots.
+// Substrate sources take "finalized" for the GRANDPA-finalized head, NEAR
+// sources for the final block and Tron sources for the solidified block.
+// Bitcoin and Cosmos sources take a depth only.
+type Confirmation struct {
+	Depth uint64
+	Tag   string
+}
+
+// UnmarshalYAML accepts a block count or a block tag.
+func (c *Confirmation) UnmarshalYAML(value *yaml.Node) error {
+	switch strings.ToLower(value.Value) {
+	case ConfirmFinalized, ConfirmSafe:
+		*c = Confirmation{Tag: strings.ToLower(value.Value)}
+		return nil
+	}
+	var depth uint64
+	if err := value.Decode(&depth); err != nil {
+		return fmt.Errorf("confirmations must be a block count, %q or %q, got %q", ConfirmFinalized, ConfirmSafe, value.Value)
+	}
+	*c = Confirmation{Depth: depth}
+	return nil
+}
+
+// URLs is one URL or a list of them. A list names fallbacks, tried in order.
+type URLs []string
+
+// UnmarshalYAML accepts a single URL as well as a list.
+func (u *URLs) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*u = nil
+		if value.Value != "" {
+			*u = URLs{value.Value}
+		}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return errors.New("expected a URL or a list of URLs")
+	}
+	*u = list
+	return nil
+}
+
 // Config holds the YAML configuration.
 type Config struct {
 	Version int          `yaml:"version"`
@@ -19,52 +78,457 @@ type Config struct {
 	Sources []Source     `yaml:"sources"`
 	Rules   []Rule       `yaml:"rules"`
 	Sinks   []Sink       `yaml:"sinks"`
+	Prices  *Prices      `yaml:"prices,omitempty"`
 }
 
 type GlobalConfig struct {
-	DBPath        string            `yaml:"db_path"`
-	Confirmations map[string]uint64 `yaml:"confirmations"`
+	DBPath        string                  `yaml:"db_path"`
+	Confirmations map[string]Confirmation `yaml:"confirmations"`
+	// ReorgSinks receive a "reorg" alert whenever a source rewinds.
+	ReorgSinks []string `yaml:"reorg_sinks"`
+	// SuppressionSummary is how often each rule's sinks get a count of the
+	// alerts dedupe and rate limiting dropped (Go duration, off when empty).
+	SuppressionSummary string `yaml:"suppression_summary"`
+	// Audit configures the decision audit trail. Records are always logged
+	// at debug level; Audit.Table also keeps them in SQLite.
+	Audit *Audit `yaml:"audit"`
+}
+
+// Audit configures where audit records are kept.
+type Audit struct {
+	// Table stores every record in the audit table.
+	Table bool `yaml:"table"`
+	// Retention is how long stored records are kept (Go duration, default 168h).
+	Retention string `yaml:"retention"`
 }
 
 type Source struct {
 	ID         string   `yaml:"id"`
 	Type       string   `yaml:"type"`
-	RPCURL     string   `yaml:"rpc_url"`
+	RPCURL     URLs     `yaml:"rpc_url"` // one endpoint, or several to fail over between
 	StartBlock string   `yaml:"start_block"`
 	ABIDirs    []string `yaml:"abi_dirs"`
+	// TipTTL is how long a fetched chain tip is reused once the source has
+	// caught up (Go duration, default 2s). While behind, the tip is never refetched.
+	TipTTL string `yaml:"tip_ttl"`
+	// RPCHeaders are sent with every RPC request, for providers that take
+	// API keys in headers rather than the URL. On Algorand sources they go
+	// to algod and the indexer.
+	RPCHeaders map[string]string `yaml:"rpc_headers"`
+	// RPCBasicAuth authenticates RPC requests with HTTP basic auth.
+	RPCBasicAuth *BasicAuth `yaml:"rpc_basic_auth"`
+	// MaxRPS caps RPC requests per second to this source (0 = no cap).
+	// Throttled (429 / -32005) responses are retried with backoff either way.
+	MaxRPS float64 `yaml:"max_rps"`
+	// ExplorerURL is the block explorer base (e.g. https://etherscan.io) used
+	// by the explorer_* template funcs.
+	ExplorerURL string `yaml:"explorer_url"`
+	// MulticallAddress overrides the Multicall3 contract used to batch call
+	// rule reads, for chains where it is not at the canonical address.
+	MulticallAddress string `yaml:"multicall_address"`
+	// MaxBlocksPerTick lets a source that is behind scan up to this many
+	// blocks or rounds per tick (default 1). EVM sources fetch their logs
+	// with one eth_getLogs call.
+	MaxBlocksPerTick int `yaml:"max_blocks_per_tick"`
+	// MaxReorgDepth bounds how far back an EVM or Bitcoin source looks for
+	// the common ancestor after a reorg (default DefaultMaxReorgDepth).
+	MaxReorgDepth int `yaml:"max_reorg_depth"`
+	// ABIFetch downloads the verified ABIs of the source's rule contracts.
+	ABIFetch *ABIFetch `yaml:"abi_fetch"`
+	// ENS adds the primary ENS names of an event's from, to and contract
+	// addresses to its args as from_ens, to_ens and contract_ens.
+	ENS bool `yaml:"ens"`
+	// ENSRegistry overrides the ENS registry, for chains where it is not at
+	// the canonical address.
+	ENSRegistry string `yaml:"ens_registry"`
+	// L2 marks an EVM source as an optimistic rollup and says where it
+	// posts to L1, for sequencer_lag rules.
+	L2 *L2 `yaml:"l2"`
 
 	AlgodURL   string `yaml:"algod_url"`
 	IndexerURL string `yaml:"indexer_url"`
 	StartRound string `yaml:"start_round"`
+	// AlgodToken and IndexerToken are the API tokens algod and the indexer
+	// require, sent in their token headers.
+	AlgodToken   string `yaml:"algod_token"`
+	IndexerToken string `yaml:"indexer_token"`
+
+	// StartSlot is where a solana source without a cursor starts, like
+	// start_block. Solana sources take their endpoint from rpc_url.
+	StartSlot string `yaml:"start_slot"`
+
+	// EsploraURL points a bitcoin source at an Esplora API (such as
+	// https://blockstream.info/api) instead of a bitcoind rpc_url.
+	EsploraURL URLs `yaml:"esplora_url"`
+
+	// Listen is the address an ingest source accepts events on, such as
+	// ":8090". Ingest sources may share an address.
+	Listen string `yaml:"listen"`
+	// Secret is the key ingest requests are signed with (HMAC-SHA256).
+	Secret string `yaml:"secret"`
+	// SignatureHeader is the header carrying an ingest request's signature,
+	// for providers that sign with their own (default
+	// X-Watchtower-Signature).
+	SignatureHeader string `yaml:"signature_header"`
+	// TimestampHeader is the header carrying the unix time an ingest
+	// request was signed at (default X-Watchtower-Timestamp).
+	TimestampHeader string `yaml:"timestamp_header"`
+	// BodyOnlySignature accepts ingest requests signed over the body alone,
+	// without a timestamp, for providers that sign that way. Replays of
+	// them are then only caught by event id.
+	BodyOnlySignature bool `yaml:"body_only_signature"`
+	// Chain names the chain of ingested events that name none.
+	Chain string `yaml:"chain"`
+}
+
+// Rollup stacks an L2 source can be.
+const (
+	L2Optimism = "optimism"
+	L2Arbitrum = "arbitrum"
+)
+
+// L2 describes how a rollup source posts to its L1.
+type L2 struct {
+	Stack    string `yaml:"stack"`     // optimism or arbitrum
+	L1Source string `yaml:"l1_source"` // id of the EVM source for the L1
+	// Contract is the L1 contract posts land in: the L2OutputOracle for
+	// optimism, the SequencerInbox for arbitrum.
+	Contract string `yaml:"contract"`
+}
+
+// ABI providers an EVM source can fetch contract ABIs from.
+const (
+	ABIFetchEtherscan = "etherscan"
+	ABIFetchSourcify  = "sourcify"
+)
+
+// DefaultABICacheDir is where fetched ABIs are kept when abi_fetch sets no
+// cache_dir.
+const DefaultABICacheDir = ".watch-tower/abis"
+
+// ABIFetch configures where a source downloads verified contract ABIs from.
+// Each is fetched once and cached on disk as <cache_dir>/<chain_id>/<address>.json.
+type ABIFetch struct {
+	Provider string `yaml:"provider"` // etherscan or sourcify
+	APIKey   string `yaml:"api_key"`  // etherscan: required
+	ChainID  uint64 `yaml:"chain_id"` // default 1
+	CacheDir string `yaml:"cache_dir"`
+	URL      string `yaml:"url"` // overrides the provider's API base URL
+}
+
+// UnmarshalYAML accepts a provider name on its own, as in
+// "abi_fetch: sourcify", as well as the full mapping.
+func (f *ABIFetch) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*f = ABIFetch{Provider: value.Value}
+		return nil
+	}
+	type plain ABIFetch
+	return value.Decode((*plain)(f))
+}
+
+// Dir returns the directory fetched ABIs for the chain are cached in.
+func (f *ABIFetch) Dir() string {
+	dir := f.CacheDir
+	if dir == "" {
+		dir = DefaultABICacheDir
+	}
+	chain := f.ChainID
+	if chain == 0 {
+		chain = 1
+	}
+	return filepath.Join(dir, fmt.Sprint(chain))
+}
+
+// TopicValues are the values a match.topics argument may take.
+type TopicValues []string
+
+// UnmarshalYAML accepts a single value as well as a list.
+func (t *TopicValues) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = TopicValues{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return errors.New("expected a value or a list of values")
+	}
+	*t = list
+	return nil
+}
+
+// UnmarshalJSON accepts a single string as well as a list, as rules sent to
+// the admin API may use either form.
+func (t *TopicValues) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = TopicValues{one}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("expected a value or a list of values")
+	}
+	*t = list
+	return nil
+}
+
+// BasicAuth holds HTTP basic auth credentials.
+type BasicAuth struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// HTTPHeaders returns the HTTP headers for a source's RPC requests: its
+// rpc_headers, plus an Authorization header when auth is set. Both may be
+// nil.
+func HTTPHeaders(headers map[string]string, auth *BasicAuth) http.Header {
+	h := http.Header{}
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	if a := auth; a != nil {
+		cred := base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
+		h.Set("Authorization", "Basic "+cred)
+	}
+	return h
+}
+
+// Token headers of algod and the Algorand indexer.
+const (
+	AlgodTokenHeader   = "X-Algo-API-Token"
+	IndexerTokenHeader = "X-Indexer-API-Token"
+)
+
+// AlgodHeaders returns the HTTP headers for an Algorand source's algod
+// requests: its rpc_headers and basic auth, plus algod_token when set.
+func (s *Source) AlgodHeaders() http.Header {
+	h := HTTPHeaders(s.RPCHeaders, s.RPCBasicAuth)
+	if s.AlgodToken != "" {
+		h.Set(AlgodTokenHeader, s.AlgodToken)
+	}
+	return h
+}
+
+// IndexerHeaders returns the HTTP headers for an Algorand source's indexer
+// requests: its rpc_headers and basic auth, plus indexer_token when set.
+func (s *Source) IndexerHeaders() http.Header {
+	h := HTTPHeaders(s.RPCHeaders, s.RPCBasicAuth)
+	if s.IndexerToken != "" {
+		h.Set(IndexerTokenHeader, s.IndexerToken)
+	}
+	return h
 }
 
 type MatchSpec struct {
-	Type     string   `yaml:"type"`
-	Contract string   `yaml:"contract"`
-	Event    string   `yaml:"event"`
-	AppID    uint64   `yaml:"app_id"`
-	Where    []string `yaml:"where"`
+	Type          string   `yaml:"type" json:"type"`
+	Contract      string   `yaml:"contract" json:"contract,omitempty"`   // EVM contract; trc20_transfer: Tron token contract
+	Contracts     []string `yaml:"contracts" json:"contracts,omitempty"` // log, erc721/1155_transfer: more contracts emitting the same events
+	Event         string   `yaml:"event" json:"event,omitempty"`         // log: event signature; app_call: ARC-28 event to decode from the app's logs; abci_event: event type; pallet_event, ingest: event name
+	Events        []string `yaml:"events" json:"events,omitempty"`       // several signatures for one log rule
+	ABI           string   `yaml:"abi" json:"abi,omitempty"`             // log, function_call: ABI file in abi_dirs that decodes the events or calldata
+	Standard      string   `yaml:"standard" json:"standard,omitempty"`   // log: erc20, erc721 or erc1155 built-in events instead of an ABI
+	Topic0        string   `yaml:"topic0" json:"topic0,omitempty"`       // log: event topic hash to match, instead of or besides a signature
+	AppID         uint64   `yaml:"app_id" json:"app_id,omitempty"`
+	Methods       []string `yaml:"methods" json:"methods,omitempty"`               // app_call: ARC-4 method signatures that decode application args; receipt: NEAR method names to match
+	Program       string   `yaml:"program" json:"program,omitempty"`               // program_log: Solana program id whose logs to match
+	Mint          string   `yaml:"mint" json:"mint,omitempty"`                     // spl_transfer: only transfers of this token mint
+	Pallet        string   `yaml:"pallet" json:"pallet,omitempty"`                 // pallet_event: Substrate pallet emitting the event, such as Balances
+	NotePrefix    string   `yaml:"note_prefix" json:"note_prefix,omitempty"`       // algorand: only transactions whose note starts with this text
+	Addresses     []string `yaml:"addresses" json:"addresses,omitempty"`           // watch_address: EVM, Algorand, Solana, Bitcoin, Cosmos, Substrate and Tron addresses, and NEAR accounts; large_tx: Bitcoin addresses on either side; trc20_transfer, trx_transfer: Tron addresses on either side; key_reg, rekey: Algorand accounts; spl_transfer: token accounts or owners on either side
+	AddressesFrom string   `yaml:"addresses_from" json:"addresses_from,omitempty"` // file path or http(s) URL of extra addresses
+	Refresh       string   `yaml:"refresh" json:"refresh,omitempty"`               // how often addresses_from is reloaded
+	Slot          string   `yaml:"slot" json:"slot,omitempty"`                     // storage: slot number or 32-byte hex key
+	Function      string   `yaml:"function" json:"function,omitempty"`             // call: no-argument view, e.g. "owner()"; function_call, view: signature
+	EveryBlocks   uint64   `yaml:"every_blocks" json:"every_blocks,omitempty"`     // storage/call: read every N blocks (default 1)
+	Account       string   `yaml:"account" json:"account,omitempty"`               // balance: account to poll; receipt: NEAR account the receipts go to
+	AssetID       uint64   `yaml:"asset_id" json:"asset_id,omitempty"`             // balance: ASA id, 0 for ALGO; asset_transfer, asset_config, asset_freeze, asset_clawback: only this ASA
+	Below         uint64   `yaml:"below" json:"below,omitempty"`                   // balance, base_fee: alert when it drops below, in base units or wei
+	Above         uint64   `yaml:"above" json:"above,omitempty"`                   // balance, base_fee: alert when it rises above, in base units or wei
+	Hysteresis    uint64   `yaml:"hysteresis" json:"hysteresis,omitempty"`         // balance, base_fee: how far back past the threshold before re-arming
+	ChangePct     float64  `yaml:"change_pct" json:"change_pct,omitempty"`         // base_fee: alert when it moves this many percent within within_blocks
+	WithinBlocks  uint64   `yaml:"within_blocks" json:"within_blocks,omitempty"`   // base_fee: how many blocks change_pct is measured over (default 1)
+	Interval      string   `yaml:"interval" json:"interval,omitempty"`             // balance, view, sequencer_lag: how often to poll (default 1m)
+	Inputs        []string `yaml:"inputs" json:"inputs,omitempty"`                 // view: call arguments, one per function parameter
+	Returns       string   `yaml:"returns" json:"returns,omitempty"`               // view: return types, e.g. "uint256", when no ABI defines the function
+	MinValue      string   `yaml:"min_value" json:"min_value,omitempty"`           // transfer: smallest value to alert on, in wei or with an ether/gwei suffix; large_tx: in sats or with a btc suffix; trx_transfer: in sun or with a trx suffix
+	MinAmount     uint64   `yaml:"min_amount" json:"min_amount,omitempty"`         // asset_transfer, asset_clawback, spl_transfer, trc20_transfer: smallest amount of asset_id, mint or contract to alert on, in base units
+	MinBlobs      uint64   `yaml:"min_blobs" json:"min_blobs,omitempty"`           // blob_tx: fewest blobs to alert on
+	MinBlobFee    string   `yaml:"min_blob_fee" json:"min_blob_fee,omitempty"`     // blob_tx: smallest blob fee paid to alert on, like min_value
+	MaxLag        string   `yaml:"max_lag" json:"max_lag,omitempty"`               // sequencer_lag: how far L1 posts may trail the L2 head
+	Where         []string `yaml:"where" json:"where,omitempty"`
+
+	// Topics filters log rules on indexed arguments, by name, at the node:
+	// a log matches when each named argument has one of the listed values.
+	Topics map[string]TopicValues `yaml:"topics" json:"topics,omitempty"`
+
+	// Group lists the transactions an Algorand group rule looks for. The
+	// rule matches an atomic group when each member matches a different
+	// transaction in it.
+	Group []GroupMember `yaml:"group" json:"group,omitempty"`
+
+	// Attributes filters abci_event rules: an event matches when it has
+	// each named attribute with the given value.
+	Attributes map[string]string `yaml:"attributes" json:"attributes,omitempty"`
+}
+
+// GroupMember is one transaction of a group rule: a match on its own,
+// whose args appear in the group's under Name and a dot.
+type GroupMember struct {
+	Name      string `yaml:"name" json:"name,omitempty"` // default tx0, tx1, ... by position
+	MatchSpec `yaml:",inline" json:",inline"`
+}
+
+// GroupMemberTypes are the match types a group rule's members can have.
+var GroupMemberTypes = []string{"app_call", "asset_transfer", "payment", MatchAssetConfig, MatchAssetFreeze, MatchAssetClawback, MatchKeyReg, MatchRekey}
+
+// Token standards whose events are built in, for match.standard.
+const (
+	StandardERC20   = "erc20"
+	StandardERC721  = "erc721"
+	StandardERC1155 = "erc1155"
+)
+
+// Standards lists the built-in token standards in order of precedence.
+var Standards = []string{StandardERC20, StandardERC721, StandardERC1155}
+
+// ParseSlot reads a storage match slot, given in decimal or 0x hex.
+func ParseSlot(slot string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(slot, 0)
+	if !ok || n.Sign() < 0 || n.BitLen() > 256 {
+		return nil, fmt.Errorf("invalid match.slot %q", slot)
+	}
+	return n, nil
+}
+
+// ParseWei reads a transfer match min_value: a decimal amount of wei, or an
+// amount followed by "ether" or "gwei", such as "2.5 ether".
+func ParseWei(v string) (*big.Int, error) {
+	s := strings.TrimSpace(strings.ToLower(v))
+	exp := 0
+	// "gwei" before "wei", which it ends with.
+	for _, u := range []struct {
+		name string
+		exp  int
+	}{{"ether", 18}, {"gwei", 9}, {"wei", 0}} {
+		if strings.HasSuffix(s, u.name) {
+			s, exp = strings.TrimSpace(strings.TrimSuffix(s, u.name)), u.exp
+			break
+		}
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() < 0 {
+		return nil, fmt.Errorf("invalid match.min_value %q", v)
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil)))
+	if !r.IsInt() {
+		return nil, fmt.Errorf("invalid match.min_value %q: smaller than 1 wei", v)
+	}
+	return r.Num(), nil
+}
+
+// ParseSats parses a large_tx min_value: satoshis, or BTC with a "btc"
+// suffix, e.g. "250000" or "10 btc".
+func ParseSats(v string) (uint64, error) {
+	s := strings.TrimSpace(strings.ToLower(v))
+	exp := int64(0)
+	if strings.HasSuffix(s, "btc") {
+		s, exp = strings.TrimSpace(strings.TrimSuffix(s, "btc")), 8
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() < 0 {
+		return 0, fmt.Errorf("invalid match.min_value %q", v)
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil)))
+	if !r.IsInt() || !r.Num().IsUint64() {
+		return 0, fmt.Errorf("invalid match.min_value %q: not a whole number of satoshis", v)
+	}
+	return r.Num().Uint64(), nil
+}
+
+// ParseSun parses a trx_transfer min_value: sun, or TRX with a "trx"
+// suffix, e.g. "5000000" or "100000 trx".
+func ParseSun(v string) (uint64, error) {
+	s := strings.TrimSpace(strings.ToLower(v))
+	exp := int64(0)
+	if strings.HasSuffix(s, "trx") {
+		s, exp = strings.TrimSpace(strings.TrimSuffix(s, "trx")), 6
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() < 0 {
+		return 0, fmt.Errorf("invalid match.min_value %q", v)
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil)))
+	if !r.IsInt() || !r.Num().IsUint64() {
+		return 0, fmt.Errorf("invalid match.min_value %q: not a whole number of sun", v)
+	}
+	return r.Num().Uint64(), nil
+}
+
+// DefaultBalanceInterval is used when a balance rule sets no interval.
+const DefaultBalanceInterval = time.Minute
+
+// PollInterval returns the parsed interval, falling back to DefaultBalanceInterval.
+func (m *MatchSpec) PollInterval() time.Duration {
+	if d, err := time.ParseDuration(m.Interval); err == nil && d > 0 {
+		return d
+	}
+	return DefaultBalanceInterval
+}
+
+// DefaultWatchlistRefresh is used when a rule with addresses_from sets no refresh.
+const DefaultWatchlistRefresh = 15 * time.Minute
+
+// RefreshInterval returns the parsed refresh, falling back to DefaultWatchlistRefresh.
+func (m *MatchSpec) RefreshInterval() time.Duration {
+	if d, err := time.ParseDuration(m.Refresh); err == nil && d > 0 {
+		return d
+	}
+	return DefaultWatchlistRefresh
 }
 
 type Dedupe struct {
-	Key string `yaml:"key"`
-	TTL string `yaml:"ttl"`
+	Key string `yaml:"key" json:"key"`
+	TTL string `yaml:"ttl" json:"ttl"`
 }
 
 type RateLimit struct {
-	Capacity float64 `yaml:"capacity"` // max tokens
-	Rate     float64 `yaml:"rate"`     // tokens per second
+	Capacity float64 `yaml:"capacity" json:"capacity"` // max tokens
+	Rate     float64 `yaml:"rate" json:"rate"`         // tokens per second
 }
 
 type Rule struct {
-	ID        string     `yaml:"id"`
-	Source    string     `yaml:"source"`
-	Match     MatchSpec  `yaml:"match"`
-	Sinks     []string   `yaml:"sinks"`
-	Dedupe    *Dedupe    `yaml:"dedupe,omitempty"`
-	RateLimit *RateLimit `yaml:"rate_limit,omitempty"`
+	ID        string     `yaml:"id" json:"id"`
+	Source    string     `yaml:"source" json:"source"`
+	Match     MatchSpec  `yaml:"match" json:"match"`
+	Sinks     []string   `yaml:"sinks" json:"sinks"`
+	Dedupe    *Dedupe    `yaml:"dedupe,omitempty" json:"dedupe,omitempty"`
+	RateLimit *RateLimit `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"`
+	// OnEvalError is what happens when a where predicate cannot be
+	// evaluated: EvalErrorDrop (default), EvalErrorAlert, or EvalErrorFail.
+	OnEvalError string `yaml:"on_eval_error,omitempty" json:"on_eval_error,omitempty"`
+	// Severity is SeverityInfo, SeverityWarning, or SeverityCritical. It is
+	// passed to templates and lets alerts through a sink's quiet hours.
+	Severity string `yaml:"severity,omitempty" json:"severity,omitempty"`
+	// Preset names a built-in match (see PresetNames) filled in from Params.
+	Preset string            `yaml:"preset,omitempty" json:"preset,omitempty"`
+	Params map[string]string `yaml:"params,omitempty" json:"params,omitempty"`
 }
 
+// Policies for predicate evaluation errors (Rule.OnEvalError).
+const (
+	EvalErrorDrop  = "drop"  // skip the event, as a non-match
+	EvalErrorAlert = "alert" // raise the alert with an eval_error arg
+	EvalErrorFail  = "fail"  // stop the source so the block is retried
+)
+
+// Rule severities (Rule.Severity).
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
 type Sink struct {
 	ID         string `yaml:"id"`
 	Type       string `yaml:"type"`
@@ -72,8 +536,133 @@ type Sink struct {
 	Template   string `yaml:"template"`
 	URL        string `yaml:"url"`
 	Method     string `yaml:"method"`
+	Compress   string `yaml:"compress"` // webhook: "gzip" to compress request bodies
+	// Token and Channel post to Slack through the Web API instead of a
+	// webhook, which lets related alerts be threaded.
+	Token     string `yaml:"token"`
+	Channel   string `yaml:"channel"`
+	ThreadTTL string `yaml:"thread_ttl"` // how long a thread takes replies (default 24h)
+	// Heartbeat is how often the sink gets a liveness message with the
+	// alert count and source lag (Go duration, off when empty).
+	Heartbeat string `yaml:"heartbeat"`
+	// Encoding sends the payload itself instead of the rendered template:
+	// "json", "protobuf", or "avro" (webhook only; default "text").
+	Encoding string `yaml:"encoding"`
+	// SchemaRegistry registers the Avro schema and frames each body with its
+	// id in the Confluent wire format (encoding: avro only).
+	SchemaRegistry *SchemaRegistry `yaml:"schema_registry"`
+	// QuietHours holds alerts during a daily window and sends them as one
+	// digest when it ends.
+	QuietHours *QuietHours `yaml:"quiet_hours"`
+	// SkipBackfill keeps alerts from backfills of past blocks off the sink.
+	SkipBackfill bool `yaml:"skip_backfill"`
+}
+
+// QuietHours is a daily window, such as 23:00 to 07:00 in Europe/Madrid.
+type QuietHours struct {
+	Start    string `yaml:"start"`    // "HH:MM"
+	End      string `yaml:"end"`      // "HH:MM"; before Start, the window spans midnight
+	Timezone string `yaml:"timezone"` // IANA name (default UTC)
+	// Except lists rule severities delivered during the window anyway.
+	Except []string `yaml:"except"`
+}
+
+// Parse returns the window's start and end as offsets from midnight, and
+// its location.
+func (q *QuietHours) Parse() (start, end time.Duration, loc *time.Location, err error) {
+	if start, err = parseClock(q.Start); err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid start: %w", err)
+	}
+	if end, err = parseClock(q.End); err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid end: %w", err)
+	}
+	if start == end {
+		return 0, 0, nil, errors.New("start and end must differ")
+	}
+	loc = time.UTC
+	if q.Timezone != "" {
+		if loc, err = time.LoadLocation(q.Timezone); err != nil {
+			return 0, 0, nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+	return start, end, loc, nil
+}
+
+// parseClock reads an "HH:MM" time of day.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// SchemaRegistry is a Confluent-compatible schema registry.
+type SchemaRegistry struct {
+	URL      string `yaml:"url"`
+	Subject  string `yaml:"subject"` // default "<sink id>-value"
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Prices configures USD enrichment: events from a listed token get a
+// value_usd arg computed from its amount and a cached price.
+type Prices struct {
+	// CoinGeckoURL overrides the CoinGecko API base (default public API).
+	CoinGeckoURL string `yaml:"coingecko_url"`
+	APIKey       string `yaml:"api_key"`
+	// RPCURL is the EVM endpoint used to read Chainlink feeds.
+	RPCURL string `yaml:"rpc_url"`
+	// TTL is how long a fetched price is reused (Go duration, default 60s).
+	TTL    string       `yaml:"ttl"`
+	Tokens []PriceToken `yaml:"tokens"`
+}
+
+// PriceToken maps a token to its price source. Contract identifies EVM
+// tokens, AssetID Algorand ASAs; exactly one of CoinGeckoID and
+// ChainlinkFeed is set.
+type PriceToken struct {
+	Contract      string `yaml:"contract"`
+	AssetID       uint64 `yaml:"asset_id"`
+	Decimals      int    `yaml:"decimals"`
+	CoinGeckoID   string `yaml:"coingecko_id"`
+	ChainlinkFeed string `yaml:"chainlink_feed"`
+}
+
+// DefaultPriceTTL is used when prices.ttl is not set.
+const DefaultPriceTTL = time.Minute
+
+// CacheTTL returns the parsed ttl, falling back to DefaultPriceTTL.
+func (p *Prices) CacheTTL() time.Duration {
+	if d, err := time.ParseDuration(p.TTL); err == nil && d > 0 {
+		return d
+	}
+	return DefaultPriceTTL
 }
 
+// DefaultTipTTL is used when a source does not set tip_ttl.
+const DefaultTipTTL = 2 * time.Second
+
+// DefaultMaxReorgDepth is used when a source does not set max_reorg_depth.
+const DefaultMaxReorgDepth = 64
+
+var hexAddress = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+var hexHash = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+
+// solanaAddress matches a base58 public key, such as a program id or mint.
+var solanaAddress = regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{32,44}$`)
+
+// bitcoinAddress matches a base58 or bech32 Bitcoin address.
+var bitcoinAddress = regexp.MustCompile(`^([13mn2][1-9A-HJ-NP-Za-km-z]{25,34}|(?i:(bc|tb|bcrt)1[02-9ac-hj-np-z]{8,87}))$`)
+
+// tronAddress matches a base58 Tron address.
+var tronAddress = regexp.MustCompile(`^T[1-9A-HJ-NP-Za-km-z]{33}$`)
+
+// nearAccount matches a NEAR account id, named (alice.near) or implicit
+// (64 hex characters). Ids are 2 to 64 characters long.
+var nearAccount = regexp.MustCompile(`^(([a-z\d]+[-_])*[a-z\d]+\.)*([a-z\d]+[-_])*[a-z\d]+$`)
+
 var envPattern = regexp.MustCompile(`\${([A-Za-z_][A-Za-z0-9_]*)}`)
 
 // Load reads, interpolates env vars, parses YAML, and validates.
@@ -90,7 +679,12 @@ func Load(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read config: %w", err)
 	}
+	return Parse(raw)
+}
 
+// Parse interpolates env vars in a YAML config, parses it, and validates it.
+// Unlike Load it does not read a .env file.
+func Parse(raw []byte) (*Config, error) {
 	interpolated, err := interpolateEnv(string(raw))
 	if err != nil {
 		return nil, err
@@ -151,15 +745,26 @@ func (c *Config) Validate() error {
 	}
 
 	sourceIDs := map[string]struct{}{}
-	for _, s := range c.Sources {
+	sources := map[string]*Source{}
+	for i := range c.Sources {
+		s := &c.Sources[i]
 		if _, exists := sourceIDs[s.ID]; exists {
 			return fmt.Errorf("duplicate source id: %s", s.ID)
 		}
 		sourceIDs[s.ID] = struct{}{}
+		sources[s.ID] = s
 		if err := s.Validate(); err != nil {
 			return fmt.Errorf("source %s: %w", s.ID, err)
 		}
 	}
+	for _, s := range c.Sources {
+		if s.L2 == nil {
+			continue
+		}
+		if l1, ok := sources[s.L2.L1Source]; !ok || strings.ToLower(l1.Type) != "evm" {
+			return fmt.Errorf("source %s: l2.l1_source %s is not an evm source", s.ID, s.L2.L1Source)
+		}
+	}
 
 	sinkIDs := map[string]*Sink{}
 	for i := range c.Sinks {
@@ -173,10 +778,47 @@ func (c *Config) Validate() error {
 		}
 	}
 
-	for _, r := range c.Rules {
+	for chain, conf := range c.Global.Confirmations {
+		if (chain == "solana" || chain == "substrate" || chain == "near" || chain == "tron") && conf.Tag == ConfirmFinalized {
+			continue
+		}
+		if conf.Tag != "" && chain != "evm" {
+			return fmt.Errorf("global.confirmations.%s: block tags are only supported for evm", chain)
+		}
+	}
+
+	for _, id := range c.Global.ReorgSinks {
+		if _, ok := sinkIDs[id]; !ok {
+			return fmt.Errorf("global.reorg_sinks: unknown sink: %s", id)
+		}
+	}
+
+	if c.Global.SuppressionSummary != "" {
+		if d, err := time.ParseDuration(c.Global.SuppressionSummary); err != nil || d <= 0 {
+			return fmt.Errorf("global.suppression_summary: invalid duration %q", c.Global.SuppressionSummary)
+		}
+	}
+
+	if a := c.Global.Audit; a != nil && a.Retention != "" {
+		if d, err := time.ParseDuration(a.Retention); err != nil || d <= 0 {
+			return fmt.Errorf("global.audit.retention: invalid duration %q", a.Retention)
+		}
+	}
+
+	if c.Prices != nil {
+		if err := c.Prices.Validate(); err != nil {
+			return fmt.Errorf("prices: %w", err)
+		}
+	}
+
+	for i := range c.Rules {
+		r := &c.Rules[i]
 		if err := r.Validate(sourceIDs, sinkIDs); err != nil {
 			return fmt.Errorf("rule %s: %w", r.ID, err)
 		}
+		if r.Match.Type == MatchSequencerLag && sources[r.Source].L2 == nil {
+			return fmt.Errorf("rule %s: sequencer_lag needs source %s to set l2", r.ID, r.Source)
+		}
 	}
 
 	return nil
@@ -188,28 +830,311 @@ func (s *Source) Validate() error {
 	}
 	switch strings.ToLower(s.Type) {
 	case "evm":
-		if s.RPCURL == "" {
+		if len(s.RPCURL) == 0 {
 			return errors.New("rpc_url is required for evm sources")
 		}
+		for _, u := range s.RPCURL {
+			if u == "" {
+				return errors.New("rpc_url entries must not be empty")
+			}
+		}
 	case "algorand":
 		if s.AlgodURL == "" || s.IndexerURL == "" {
 			return errors.New("algod_url and indexer_url are required for algorand sources")
 		}
+	case "solana":
+		if len(s.RPCURL) == 0 {
+			return errors.New("rpc_url is required for solana sources")
+		}
+		for _, u := range s.RPCURL {
+			if u == "" {
+				return errors.New("rpc_url entries must not be empty")
+			}
+		}
+	case "cosmos":
+		if len(s.RPCURL) == 0 {
+			return errors.New("rpc_url is required for cosmos sources")
+		}
+		for _, u := range s.RPCURL {
+			if u == "" {
+				return errors.New("rpc_url entries must not be empty")
+			}
+		}
+	case "substrate":
+		if len(s.RPCURL) == 0 {
+			return errors.New("rpc_url is required for substrate sources")
+		}
+		for _, u := range s.RPCURL {
+			if u == "" {
+				return errors.New("rpc_url entries must not be empty")
+			}
+		}
+	case "near":
+		if len(s.RPCURL) == 0 {
+			return errors.New("rpc_url is required for near sources")
+		}
+		for _, u := range s.RPCURL {
+			if u == "" {
+				return errors.New("rpc_url entries must not be empty")
+			}
+		}
+	case "tron":
+		if len(s.RPCURL) == 0 {
+			return errors.New("rpc_url is required for tron sources")
+		}
+		for _, u := range s.RPCURL {
+			if u == "" {
+				return errors.New("rpc_url entries must not be empty")
+			}
+		}
+	case "ingest":
+		if s.Listen == "" || s.Secret == "" {
+			return errors.New("listen and secret are required for ingest sources")
+		}
+		if len(s.RPCURL) > 0 {
+			return errors.New("rpc_url does not apply to ingest sources")
+		}
+	case "bitcoin":
+		if (len(s.RPCURL) == 0) == (len(s.EsploraURL) == 0) {
+			return errors.New("exactly one of rpc_url and esplora_url is required for bitcoin sources")
+		}
+		for _, u := range append(append(URLs{}, s.RPCURL...), s.EsploraURL...) {
+			if u == "" {
+				return errors.New("rpc_url and esplora_url entries must not be empty")
+			}
+		}
 	default:
 		return fmt.Errorf("unsupported source type: %s", s.Type)
 	}
+	if s.RPCBasicAuth != nil && s.RPCBasicAuth.Username == "" {
+		return errors.New("rpc_basic_auth.username is required")
+	}
+	if s.MaxRPS < 0 {
+		return errors.New("max_rps must not be negative")
+	}
+	if s.MaxBlocksPerTick < 0 {
+		return errors.New("max_blocks_per_tick must not be negative")
+	}
+	if s.MaxReorgDepth < 0 {
+		return errors.New("max_reorg_depth must not be negative")
+	}
+	if t := strings.ToLower(s.Type); s.MaxReorgDepth > 0 && t != "evm" && t != "bitcoin" && t != "substrate" && t != "tron" {
+		return errors.New("max_reorg_depth applies to evm, bitcoin, substrate and tron sources only")
+	}
+	if (s.Listen != "" || s.Secret != "" || s.SignatureHeader != "" || s.TimestampHeader != "" || s.BodyOnlySignature || s.Chain != "") && strings.ToLower(s.Type) != "ingest" {
+		return errors.New("listen, secret, signature_header, timestamp_header, body_only_signature and chain apply to ingest sources only")
+	}
+	if s.TimestampHeader != "" && s.BodyOnlySignature {
+		return errors.New("timestamp_header does not apply with body_only_signature")
+	}
+	if len(s.EsploraURL) > 0 && strings.ToLower(s.Type) != "bitcoin" {
+		return errors.New("esplora_url applies to bitcoin sources only")
+	}
+	if (s.AlgodToken != "" || s.IndexerToken != "") && strings.ToLower(s.Type) != "algorand" {
+		return errors.New("algod_token and indexer_token apply to algorand sources only")
+	}
+	if s.MulticallAddress != "" && !hexAddress.MatchString(s.MulticallAddress) {
+		return fmt.Errorf("invalid multicall_address: %s", s.MulticallAddress)
+	}
+	if (s.ENS || s.ENSRegistry != "") && strings.ToLower(s.Type) != "evm" {
+		return errors.New("ens applies to evm sources only")
+	}
+	if s.ENSRegistry != "" && !hexAddress.MatchString(s.ENSRegistry) {
+		return fmt.Errorf("invalid ens_registry: %s", s.ENSRegistry)
+	}
+	if s.L2 != nil {
+		if strings.ToLower(s.Type) != "evm" {
+			return errors.New("l2 applies to evm sources only")
+		}
+		if s.L2.Stack != L2Optimism && s.L2.Stack != L2Arbitrum {
+			return fmt.Errorf("l2.stack must be %q or %q, got %q", L2Optimism, L2Arbitrum, s.L2.Stack)
+		}
+		if s.L2.L1Source == "" || s.L2.L1Source == s.ID {
+			return errors.New("l2.l1_source must name another evm source")
+		}
+		if !hexAddress.MatchString(s.L2.Contract) {
+			return fmt.Errorf("invalid l2.contract: %s", s.L2.Contract)
+		}
+	}
+	if s.ABIFetch != nil {
+		if strings.ToLower(s.Type) != "evm" {
+			return errors.New("abi_fetch applies to evm sources only")
+		}
+		switch strings.ToLower(s.ABIFetch.Provider) {
+		case ABIFetchEtherscan:
+			if s.ABIFetch.APIKey == "" {
+				return errors.New("abi_fetch.api_key is required for etherscan")
+			}
+		case ABIFetchSourcify:
+		default:
+			return fmt.Errorf("abi_fetch.provider must be %q or %q, got %q", ABIFetchEtherscan, ABIFetchSourcify, s.ABIFetch.Provider)
+		}
+	}
+	if s.TipTTL != "" {
+		if d, err := time.ParseDuration(s.TipTTL); err != nil || d < 0 {
+			return fmt.Errorf("invalid tip_ttl: %s", s.TipTTL)
+		}
+	}
+	if s.StartSlot != "" && strings.ToLower(s.Type) != "solana" {
+		return errors.New("start_slot applies to solana sources only")
+	}
+	for _, start := range []string{s.StartBlock, s.StartRound, s.StartSlot} {
+		if _, _, err := blocktime.Parse(start); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// ABIPaths returns the directories the source's ABIs are loaded from:
+// abi_dirs, then the abi_fetch cache.
+func (s *Source) ABIPaths() []string {
+	if s.ABIFetch == nil {
+		return s.ABIDirs
+	}
+	return append(append([]string{}, s.ABIDirs...), s.ABIFetch.Dir())
+}
+
+// TipCacheTTL returns the parsed tip_ttl, defaulting to DefaultTipTTL.
+func (s *Source) TipCacheTTL() time.Duration {
+	if d, err := time.ParseDuration(s.TipTTL); err == nil && d >= 0 {
+		return d
+	}
+	return DefaultTipTTL
+}
+
+// ReorgDepth returns max_reorg_depth, defaulting to DefaultMaxReorgDepth.
+func (s *Source) ReorgDepth() uint64 {
+	if s.MaxReorgDepth > 0 {
+		return uint64(s.MaxReorgDepth)
+	}
+	return DefaultMaxReorgDepth
+}
+
+const (
+	// MatchWatchAddress rules match any activity involving a list of addresses.
+	MatchWatchAddress = "watch_address"
+	// MatchBalance rules poll an account balance and alert on threshold crossings.
+	MatchBalance = "balance"
+	// MatchPendingTx rules match EVM transactions still in the mempool.
+	MatchPendingTx = "pending_tx"
+	// MatchFunctionCall rules match EVM transactions calling one function on a contract.
+	MatchFunctionCall = "function_call"
+	// MatchContractCreation rules match EVM contract deployments, optionally by a list of deployers.
+	MatchContractCreation = "contract_creation"
+	// MatchInternalCall rules match calls between contracts, read from block traces.
+	MatchInternalCall = "internal_call"
+	// MatchView rules call a view function on an interval and match its return values.
+	MatchView = "view"
+	// MatchTransfer rules match native EVM value sent to or from a list of addresses.
+	MatchTransfer = "transfer"
+	// MatchReorg rules match the source rewinding after a chain reorg.
+	MatchReorg = "reorg"
+	// MatchBaseFee rules match the EIP-1559 base fee of EVM blocks crossing
+	// a threshold or moving sharply.
+	MatchBaseFee = "base_fee"
+	// MatchBlobTx rules match EIP-4844 blob transactions, optionally by
+	// sender, blob count or blob fee.
+	MatchBlobTx = "blob_tx"
+	// MatchFailedTx rules match reverted EVM transactions sent to a
+	// contract or from a list of addresses.
+	MatchFailedTx = "failed_tx"
+	// MatchSequencerLag rules match an L2 source's posts to L1 falling
+	// behind its head.
+	MatchSequencerLag = "sequencer_lag"
+	// MatchAssetConfig rules match Algorand ASAs being created,
+	// reconfigured or destroyed.
+	MatchAssetConfig = "asset_config"
+	// MatchAssetFreeze rules match Algorand accounts being frozen or
+	// unfrozen in an ASA.
+	MatchAssetFreeze = "asset_freeze"
+	// MatchAssetClawback rules match Algorand ASA transfers the clawback
+	// account forces out of another account.
+	MatchAssetClawback = "asset_clawback"
+	// MatchKeyReg rules match Algorand accounts registering participation
+	// keys online or going offline.
+	MatchKeyReg = "key_reg"
+	// MatchRekey rules match Algorand accounts being rekeyed to a new
+	// authorizing address.
+	MatchRekey = "rekey"
+	// MatchGroup rules match Algorand atomic groups containing a set of
+	// transactions.
+	MatchGroup = "group"
+	// MatchProgramLog rules match the log lines a Solana program writes.
+	MatchProgramLog = "program_log"
+	// MatchSPLTransfer rules match SPL token transfers on Solana,
+	// optionally of one mint or by a list of accounts.
+	MatchSPLTransfer = "spl_transfer"
+	// MatchLargeTx rules match Bitcoin transactions moving at least
+	// min_value, optionally only those touching a list of addresses.
+	MatchLargeTx = "large_tx"
+	// MatchABCIEvent rules match the ABCI events of Cosmos SDK chains by
+	// type and attributes.
+	MatchABCIEvent = "abci_event"
+	// MatchPalletEvent rules match the events a Substrate pallet deposits,
+	// decoded with the chain's metadata.
+	MatchPalletEvent = "pallet_event"
+	// MatchReceipt rules match the actions of NEAR receipts sent to an
+	// account, optionally only calls of some methods.
+	MatchReceipt = "receipt"
+	// MatchTRC20Transfer rules match TRC-20 Transfer events on Tron,
+	// optionally of one token contract.
+	MatchTRC20Transfer = "trc20_transfer"
+	// MatchTRXTransfer rules match native TRX transfers of at least
+	// min_value.
+	MatchTRXTransfer = "trx_transfer"
+	// MatchIngest rules match the events posted to an ingest source,
+	// optionally only those with one name.
+	MatchIngest = "ingest"
+	// AllSources as a watch_address or reorg rule's source applies it to
+	// every source.
+	AllSources = "*"
+)
+
+// ContractList returns contract followed by contracts, without duplicates.
+func (m *MatchSpec) ContractList() []string {
+	var out []string
+	seen := map[string]struct{}{}
+	for _, c := range append([]string{m.Contract}, m.Contracts...) {
+		key := strings.ToLower(c)
+		if _, dup := seen[key]; c == "" || dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, c)
+	}
+	return out
+}
+
+// AppliesTo reports whether the rule should be matched on the given source.
+func (r *Rule) AppliesTo(sourceID string) bool {
+	return r.Source == sourceID || r.Source == AllSources
+}
+
+// Validate expands the rule's preset, if any, and checks the result.
 func (r *Rule) Validate(sourceIDs map[string]struct{}, sinkIDs map[string]*Sink) error {
 	if r.ID == "" {
 		return errors.New("id is required")
 	}
-	if r.Source == "" {
-		return errors.New("source is required")
+	if err := r.ExpandPreset(); err != nil {
+		return err
 	}
-	if _, ok := sourceIDs[r.Source]; !ok {
-		return fmt.Errorf("unknown source: %s", r.Source)
+	watch := strings.EqualFold(r.Match.Type, MatchWatchAddress)
+	reorg := strings.EqualFold(r.Match.Type, MatchReorg)
+	if (watch || reorg) && r.Source == "" {
+		r.Source = AllSources
+	}
+	switch {
+	case r.Source == "":
+		return errors.New("source is required")
+	case r.Source == AllSources:
+		if !watch && !reorg {
+			return errors.New("source \"*\" is only supported for watch_address and reorg rules")
+		}
+	default:
+		if _, ok := sourceIDs[r.Source]; !ok {
+			return fmt.Errorf("unknown source: %s", r.Source)
+		}
 	}
 
 	if len(r.Sinks) == 0 {
@@ -224,20 +1149,331 @@ func (r *Rule) Validate(sourceIDs map[string]struct{}, sinkIDs map[string]*Sink)
 	if r.Match.Type == "" {
 		return errors.New("match.type is required")
 	}
+	if t := strings.ToLower(r.Match.Type); r.Match.ABI != "" && t != "log" && t != MatchFunctionCall && t != MatchView && t != MatchInternalCall {
+		return errors.New("match.abi applies to log, function_call, view and internal_call matches only")
+	}
+	if t := strings.ToLower(r.Match.Type); len(r.Match.Contracts) > 0 && t != "log" && t != "erc721_transfer" && t != "erc1155_transfer" {
+		return errors.New("match.contracts applies to log, erc721_transfer and erc1155_transfer matches only")
+	}
+	for _, c := range r.Match.Contracts {
+		if !hexAddress.MatchString(c) {
+			return fmt.Errorf("invalid address in match.contracts: %s", c)
+		}
+	}
+	if len(r.Match.Topics) > 0 && !strings.EqualFold(r.Match.Type, "log") {
+		return errors.New("match.topics applies to log matches only")
+	}
+	if len(r.Match.Attributes) > 0 && !strings.EqualFold(r.Match.Type, MatchABCIEvent) {
+		return errors.New("match.attributes applies to abci_event matches only")
+	}
+	if r.Match.Pallet != "" && !strings.EqualFold(r.Match.Type, MatchPalletEvent) {
+		return errors.New("match.pallet applies to pallet_event matches only")
+	}
+	for name, values := range r.Match.Topics {
+		if len(values) == 0 {
+			return fmt.Errorf("match.topics.%s needs at least one value", name)
+		}
+	}
+	if r.Match.Topic0 != "" {
+		if !strings.EqualFold(r.Match.Type, "log") {
+			return errors.New("match.topic0 applies to log matches only")
+		}
+		if !hexHash.MatchString(r.Match.Topic0) {
+			return fmt.Errorf("match.topic0 must be a 32-byte hex hash, got %q", r.Match.Topic0)
+		}
+	}
 	switch strings.ToLower(r.Match.Type) {
 	case "log":
+		if len(r.Match.ContractList()) == 0 {
+			return errors.New("match.contract or match.contracts is required for log match")
+		}
+		if r.Match.Standard != "" {
+			if !slices.Contains(Standards, r.Match.Standard) {
+				return fmt.Errorf("match.standard must be one of %s, got %q", strings.Join(Standards, ", "), r.Match.Standard)
+			}
+			if r.Match.ABI != "" {
+				return errors.New("match.abi and match.standard are mutually exclusive")
+			}
+			if r.Match.Topic0 != "" {
+				return errors.New("match.topic0 and match.standard are mutually exclusive")
+			}
+		} else if r.Match.Event == "" && len(r.Match.Events) == 0 && r.Match.Topic0 == "" {
+			return errors.New("match.event, match.events or match.topic0 is required for log match")
+		}
+	case "erc721_transfer", "erc1155_transfer":
+		if len(r.Match.ContractList()) == 0 {
+			return fmt.Errorf("match.contract or match.contracts is required for %s match", r.Match.Type)
+		}
+	case "storage":
 		if r.Match.Contract == "" {
-			return errors.New("match.contract is required for log match")
+			return errors.New("match.contract is required for storage match")
 		}
-		if r.Match.Event == "" {
-			return errors.New("match.event is required for log match")
+		if _, err := ParseSlot(r.Match.Slot); err != nil {
+			return err
+		}
+	case "call":
+		if r.Match.Contract == "" {
+			return errors.New("match.contract is required for call match")
+		}
+		if len(r.Match.Function) < 3 || !strings.HasSuffix(r.Match.Function, "()") {
+			return fmt.Errorf("match.function must be a no-argument signature like \"owner()\", got %q", r.Match.Function)
+		}
+	case MatchBalance:
+		if r.Match.Account == "" {
+			return errors.New("match.account is required for balance match")
+		}
+		if r.Match.Below == 0 && r.Match.Above == 0 {
+			return errors.New("match.below or match.above is required for balance match")
+		}
+		if r.Match.Above != 0 && r.Match.Below >= r.Match.Above {
+			return errors.New("match.below must be less than match.above")
+		}
+		if r.Match.Interval != "" {
+			if d, err := time.ParseDuration(r.Match.Interval); err != nil || d <= 0 {
+				return fmt.Errorf("invalid match.interval %q", r.Match.Interval)
+			}
+		}
+	case MatchPendingTx:
+		if r.Match.Contract == "" && len(r.Match.Addresses) == 0 {
+			return errors.New("match.contract or match.addresses is required for pending_tx match")
+		}
+		for _, a := range r.Match.Addresses {
+			if !hexAddress.MatchString(a) {
+				return fmt.Errorf("invalid address in match.addresses: %s", a)
+			}
+		}
+	case MatchFunctionCall, MatchView:
+		if r.Match.Contract == "" {
+			return fmt.Errorf("match.contract is required for %s match", r.Match.Type)
+		}
+		if l, rp := strings.Index(r.Match.Function, "("), strings.LastIndex(r.Match.Function, ")"); l <= 0 || rp != len(r.Match.Function)-1 {
+			return fmt.Errorf("match.function must be a signature like \"balanceOf(address)\", got %q", r.Match.Function)
+		}
+		if r.Match.Interval != "" {
+			if d, err := time.ParseDuration(r.Match.Interval); err != nil || d <= 0 {
+				return fmt.Errorf("invalid match.interval %q", r.Match.Interval)
+			}
+		}
+	case MatchInternalCall:
+		if r.Match.Contract == "" && len(r.Match.Addresses) == 0 {
+			return errors.New("match.contract or match.addresses is required for internal_call match")
+		}
+		for _, a := range r.Match.Addresses {
+			if !hexAddress.MatchString(a) {
+				return fmt.Errorf("invalid address in match.addresses: %s", a)
+			}
+		}
+		if f := r.Match.Function; f != "" && (strings.Index(f, "(") <= 0 || !strings.HasSuffix(f, ")")) {
+			return fmt.Errorf("match.function must be a signature like \"transfer(address,uint256)\", got %q", f)
+		}
+		if r.Match.MinValue != "" {
+			if _, err := ParseWei(r.Match.MinValue); err != nil {
+				return err
+			}
+		}
+	case MatchContractCreation:
+		for _, a := range r.Match.Addresses {
+			if !hexAddress.MatchString(a) {
+				return fmt.Errorf("invalid address in match.addresses: %s", a)
+			}
+		}
+	case MatchTransfer:
+		if len(r.Match.Addresses) == 0 {
+			return errors.New("match.addresses is required for transfer match")
+		}
+		for _, a := range r.Match.Addresses {
+			if !hexAddress.MatchString(a) {
+				return fmt.Errorf("invalid address in match.addresses: %s", a)
+			}
+		}
+		if r.Match.MinValue != "" {
+			if _, err := ParseWei(r.Match.MinValue); err != nil {
+				return err
+			}
+		}
+	case MatchFailedTx:
+		if r.Match.Contract == "" && len(r.Match.Addresses) == 0 {
+			return errors.New("match.contract or match.addresses is required for failed_tx match")
+		}
+		if r.Match.Contract != "" && !hexAddress.MatchString(r.Match.Contract) {
+			return fmt.Errorf("invalid match.contract: %s", r.Match.Contract)
+		}
+		for _, a := range r.Match.Addresses {
+			if !hexAddress.MatchString(a) {
+				return fmt.Errorf("invalid address in match.addresses: %s", a)
+			}
+		}
+	case MatchBlobTx:
+		for _, a := range r.Match.Addresses {
+			if !hexAddress.MatchString(a) {
+				return fmt.Errorf("invalid address in match.addresses: %s", a)
+			}
+		}
+		if r.Match.MinBlobFee != "" {
+			if _, err := ParseWei(r.Match.MinBlobFee); err != nil {
+				return fmt.Errorf("invalid match.min_blob_fee %q", r.Match.MinBlobFee)
+			}
+		}
+	case MatchSequencerLag:
+		if d, err := time.ParseDuration(r.Match.MaxLag); err != nil || d <= 0 {
+			return fmt.Errorf("invalid match.max_lag %q", r.Match.MaxLag)
+		}
+		if r.Match.Interval != "" {
+			if d, err := time.ParseDuration(r.Match.Interval); err != nil || d <= 0 {
+				return fmt.Errorf("invalid match.interval %q", r.Match.Interval)
+			}
 		}
 	case "app_call":
 		if r.Match.AppID == 0 {
 			return errors.New("match.app_id is required for app_call match")
 		}
-	case "asset_transfer":
-		// No additional required fields for asset transfers.
+		if e := r.Match.Event; e != "" && (strings.Index(e, "(") <= 0 || !strings.HasSuffix(e, ")")) {
+			return fmt.Errorf("match.event must be an ARC-28 signature like \"Swapped(address,uint64)\", got %q", e)
+		}
+		for _, m := range r.Match.Methods {
+			if strings.Index(m, "(") <= 0 || !strings.Contains(m, ")") {
+				return fmt.Errorf("match.methods must be ARC-4 signatures like \"withdraw(uint64,account)void\", got %q", m)
+			}
+		}
+	case "asset_transfer", MatchAssetClawback:
+		// ASAs differ in decimals, so an amount only means something for one.
+		if r.Match.MinAmount > 0 && r.Match.AssetID == 0 {
+			return fmt.Errorf("match.min_amount requires match.asset_id for %s match", r.Match.Type)
+		}
+	case MatchProgramLog:
+		if !solanaAddress.MatchString(r.Match.Program) {
+			return fmt.Errorf("match.program must be a solana program id, got %q", r.Match.Program)
+		}
+	case MatchSPLTransfer:
+		if r.Match.Mint != "" && !solanaAddress.MatchString(r.Match.Mint) {
+			return fmt.Errorf("invalid match.mint: %s", r.Match.Mint)
+		}
+		// Mints differ in decimals, so an amount only means something for one.
+		if r.Match.MinAmount > 0 && r.Match.Mint == "" {
+			return errors.New("match.min_amount requires match.mint for spl_transfer match")
+		}
+		for _, a := range r.Match.Addresses {
+			if !solanaAddress.MatchString(a) {
+				return fmt.Errorf("invalid solana address in match.addresses: %s", a)
+			}
+		}
+	case MatchPalletEvent:
+		if r.Match.Pallet == "" {
+			return errors.New("match.pallet is required for pallet_event match")
+		}
+	case MatchReceipt:
+		if a := r.Match.Account; len(a) < 2 || len(a) > 64 || !nearAccount.MatchString(a) {
+			return fmt.Errorf("match.account must be a near account id, got %q", a)
+		}
+		for _, m := range r.Match.Methods {
+			if m == "" {
+				return errors.New("match.methods entries must not be empty")
+			}
+		}
+	case MatchTRC20Transfer:
+		if r.Match.Contract != "" && !tronAddress.MatchString(r.Match.Contract) {
+			return fmt.Errorf("match.contract must be a tron address, got %q", r.Match.Contract)
+		}
+		// Tokens differ in decimals, so an amount only means something for one.
+		if r.Match.MinAmount > 0 && r.Match.Contract == "" {
+			return errors.New("match.min_amount requires match.contract for trc20_transfer match")
+		}
+		for _, a := range r.Match.Addresses {
+			if !tronAddress.MatchString(a) {
+				return fmt.Errorf("invalid tron address in match.addresses: %s", a)
+			}
+		}
+	case MatchTRXTransfer:
+		if r.Match.MinValue == "" {
+			return errors.New("match.min_value is required for trx_transfer match")
+		}
+		if _, err := ParseSun(r.Match.MinValue); err != nil {
+			return err
+		}
+		for _, a := range r.Match.Addresses {
+			if !tronAddress.MatchString(a) {
+				return fmt.Errorf("invalid tron address in match.addresses: %s", a)
+			}
+		}
+	case MatchIngest:
+		// Any event posted to the source matches, or with match.event only
+		// those with that name.
+	case MatchABCIEvent:
+		if r.Match.Event == "" {
+			return errors.New("match.event is required for abci_event match")
+		}
+		for k := range r.Match.Attributes {
+			if k == "" {
+				return errors.New("match.attributes keys must not be empty")
+			}
+		}
+	case MatchLargeTx:
+		if r.Match.MinValue == "" {
+			return errors.New("match.min_value is required for large_tx match")
+		}
+		if _, err := ParseSats(r.Match.MinValue); err != nil {
+			return err
+		}
+		for _, a := range r.Match.Addresses {
+			if !bitcoinAddress.MatchString(a) {
+				return fmt.Errorf("invalid bitcoin address in match.addresses: %s", a)
+			}
+		}
+	case "payment", MatchAssetConfig, MatchAssetFreeze:
+		// No additional required fields for payments and asset operations.
+	case MatchReorg:
+		// Matches every rewind; where clauses can filter on depth.
+	case MatchBaseFee:
+		if r.Match.Below == 0 && r.Match.Above == 0 && r.Match.ChangePct == 0 {
+			return errors.New("match.below, match.above or match.change_pct is required for base_fee match")
+		}
+		if r.Match.Above != 0 && r.Match.Below >= r.Match.Above {
+			return errors.New("match.below must be less than match.above")
+		}
+		if r.Match.ChangePct < 0 {
+			return errors.New("match.change_pct must not be negative")
+		}
+		if r.Match.WithinBlocks != 0 && r.Match.ChangePct == 0 {
+			return errors.New("match.within_blocks needs match.change_pct")
+		}
+	case MatchGroup:
+		if len(r.Match.Group) == 0 {
+			return errors.New("match.group needs at least one member for group match")
+		}
+		names := map[string]struct{}{}
+		for i, m := range r.Match.Group {
+			if !slices.Contains(GroupMemberTypes, strings.ToLower(m.Type)) {
+				return fmt.Errorf("match.group[%d]: type must be one of %s, got %q", i, strings.Join(GroupMemberTypes, ", "), m.Type)
+			}
+			if len(m.Where) > 0 {
+				return fmt.Errorf("match.group[%d]: where applies to the whole group; use the member's args in the rule's where", i)
+			}
+			name := m.Name
+			if name == "" {
+				name = fmt.Sprintf("tx%d", i)
+			}
+			if _, dup := names[name]; dup {
+				return fmt.Errorf("match.group[%d]: duplicate name %q", i, name)
+			}
+			names[name] = struct{}{}
+			member := Rule{ID: r.ID, Source: r.Source, Sinks: r.Sinks, Match: m.MatchSpec}
+			if err := member.Validate(sourceIDs, sinkIDs); err != nil {
+				return fmt.Errorf("match.group[%d]: %w", i, err)
+			}
+		}
+	case MatchKeyReg, MatchRekey:
+		if len(r.Match.Addresses) == 0 {
+			return fmt.Errorf("match.addresses is required for %s match", r.Match.Type)
+		}
+	case MatchWatchAddress:
+		if len(r.Match.Addresses) == 0 && r.Match.AddressesFrom == "" {
+			return errors.New("match.addresses or match.addresses_from is required for watch_address match")
+		}
+		if r.Match.Refresh != "" {
+			if d, err := time.ParseDuration(r.Match.Refresh); err != nil || d <= 0 {
+				return fmt.Errorf("invalid match.refresh %q", r.Match.Refresh)
+			}
+		}
 	default:
 		return fmt.Errorf("unsupported match.type: %s", r.Match.Type)
 	}
@@ -248,6 +1484,38 @@ func (r *Rule) Validate(sourceIDs map[string]struct{}, sinkIDs map[string]*Sink)
 		}
 	}
 
+	switch r.OnEvalError {
+	case "", EvalErrorDrop, EvalErrorAlert, EvalErrorFail:
+	default:
+		return fmt.Errorf("unsupported on_eval_error: %s (drop, alert, or fail)", r.OnEvalError)
+	}
+	if r.Severity != "" && !validSeverity(r.Severity) {
+		return fmt.Errorf("unsupported severity: %s (info, warning, or critical)", r.Severity)
+	}
+
+	return nil
+}
+
+func (p *Prices) Validate() error {
+	if p.TTL != "" {
+		if d, err := time.ParseDuration(p.TTL); err != nil || d <= 0 {
+			return fmt.Errorf("invalid ttl: %q", p.TTL)
+		}
+	}
+	for i, t := range p.Tokens {
+		if (t.Contract == "") == (t.AssetID == 0) {
+			return fmt.Errorf("token %d: exactly one of contract and asset_id is required", i)
+		}
+		if (t.CoinGeckoID == "") == (t.ChainlinkFeed == "") {
+			return fmt.Errorf("token %d: exactly one of coingecko_id and chainlink_feed is required", i)
+		}
+		if t.ChainlinkFeed != "" && p.RPCURL == "" {
+			return fmt.Errorf("token %d: rpc_url is required for chainlink feeds", i)
+		}
+		if t.Decimals < 0 {
+			return fmt.Errorf("token %d: decimals must not be negative", i)
+		}
+	}
 	return nil
 }
 
@@ -259,10 +1527,45 @@ func (s *Sink) Validate() error {
 		return errors.New("type is required")
 	}
 
+	if s.Heartbeat != "" {
+		if d, err := time.ParseDuration(s.Heartbeat); err != nil || d <= 0 {
+			return fmt.Errorf("invalid heartbeat %q", s.Heartbeat)
+		}
+	}
+	if q := s.QuietHours; q != nil {
+		if _, _, _, err := q.Parse(); err != nil {
+			return fmt.Errorf("quiet_hours: %w", err)
+		}
+		for _, sev := range q.Except {
+			if !validSeverity(sev) {
+				return fmt.Errorf("quiet_hours: unsupported severity: %s (info, warning, or critical)", sev)
+			}
+		}
+	}
+	if (s.Encoding != "" || s.SchemaRegistry != nil) && strings.ToLower(s.Type) != "webhook" {
+		return errors.New("encoding and schema_registry are only supported on webhook sinks")
+	}
+
 	switch strings.ToLower(s.Type) {
-	case "slack", "teams":
+	case "slack":
+		if s.Token != "" || s.Channel != "" {
+			if s.Token == "" || s.Channel == "" {
+				return errors.New("token and channel must be set together for slack sinks")
+			}
+			if s.WebhookURL != "" {
+				return errors.New("set either webhook_url or token/channel for slack sinks")
+			}
+		} else if s.WebhookURL == "" {
+			return errors.New("webhook_url or token/channel is required for slack sinks")
+		}
+		if s.ThreadTTL != "" {
+			if _, err := time.ParseDuration(s.ThreadTTL); err != nil {
+				return fmt.Errorf("invalid thread_ttl: %w", err)
+			}
+		}
+	case "teams":
 		if s.WebhookURL == "" {
-			return errors.New("webhook_url is required for slack/teams sinks")
+			return errors.New("webhook_url is required for teams sinks")
 		}
 	case "webhook":
 		if s.URL == "" {
@@ -271,12 +1574,34 @@ func (s *Sink) Validate() error {
 		if s.Method == "" {
 			s.Method = "POST"
 		}
+		if s.Compress != "" && s.Compress != "gzip" {
+			return fmt.Errorf("unsupported compress: %s (only gzip)", s.Compress)
+		}
+		switch s.Encoding {
+		case "", "text", "json", "protobuf", "avro":
+		default:
+			return fmt.Errorf("unsupported encoding: %s (text, json, protobuf, or avro)", s.Encoding)
+		}
+		if s.SchemaRegistry != nil {
+			if s.Encoding != "avro" {
+				return errors.New("schema_registry requires encoding: avro")
+			}
+			if s.SchemaRegistry.URL == "" {
+				return errors.New("schema_registry.url is required")
+			}
+		}
+	case "external":
+		// Supplied by a program embedding watch-tower (watchtower.WithSink).
 	default:
 		return fmt.Errorf("unsupported sink type: %s", s.Type)
 	}
 	return nil
 }
 
+func validSeverity(s string) bool {
+	return s == SeverityInfo || s == SeverityWarning || s == SeverityCritical
+}
+
 func dedup(values []string) []string {
 	seen := map[string]struct{}{}
 	out := make([]string, 0, len(values))
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -41,7 +42,7 @@ sinks:
 		t.Fatalf("expected load to succeed: %v", err)
 	}
 
-	if got := cfg.Sources[0].RPCURL; got != "http://example-rpc" {
+	if got := cfg.Sources[0].RPCURL; len(got) != 1 || got[0] != "http://example-rpc" {
 		t.Fatalf("rpc_url not interpolated, got %q", got)
 	}
 }
@@ -77,3 +78,530 @@ sinks:
 		t.Fatalf("expected missing env to fail")
 	}
 }
+
+func TestRulePresetsExpand(t *testing.T) {
+	sources := map[string]struct{}{"evm_main": {}}
+	sinks := map[string]*Sink{"s1": {ID: "s1"}}
+
+	r := Rule{
+		ID:     "whale",
+		Source: "evm_main",
+		Sinks:  []string{"s1"},
+		Preset: "erc20_large_transfer",
+		Params: map[string]string{"contract": "0xA0b8", "min_value": "1e12"},
+		Match:  MatchSpec{Where: []string{"to != 0x0"}},
+	}
+	if err := r.Validate(sources, sinks); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if r.Match.Type != "log" || r.Match.Event != "Transfer(address,address,uint256)" || r.Match.Contract != "0xA0b8" {
+		t.Fatalf("unexpected match: %+v", r.Match)
+	}
+	if len(r.Match.Where) != 2 || r.Match.Where[0] != "value >= 1e12" || r.Match.Where[1] != "to != 0x0" {
+		t.Fatalf("unexpected where: %v", r.Match.Where)
+	}
+	if r.Preset != "" {
+		t.Fatalf("expected preset to be consumed")
+	}
+
+	pause := Rule{ID: "p", Source: "evm_main", Sinks: []string{"s1"}, Preset: "pause_unpause", Params: map[string]string{"contract": "0x1"}}
+	if err := pause.Validate(sources, sinks); err != nil {
+		t.Fatalf("validate pause: %v", err)
+	}
+	if len(pause.Match.Events) != 2 {
+		t.Fatalf("expected paused and unpaused events, got %v", pause.Match.Events)
+	}
+
+	for name, bad := range map[string]Rule{
+		"unknown preset": {Preset: "nope"},
+		"missing param":  {Preset: "erc20_large_transfer", Params: map[string]string{"contract": "0x1"}},
+		"unknown param":  {Preset: "ownership_transferred", Params: map[string]string{"contract": "0x1", "owner": "x"}},
+		"match override": {Preset: "ownership_transferred", Params: map[string]string{"contract": "0x1"}, Match: MatchSpec{Type: "log"}},
+	} {
+		bad.ID, bad.Source, bad.Sinks = "bad", "evm_main", []string{"s1"}
+		if err := bad.Validate(sources, sinks); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestConfirmationsAcceptBlockTags(t *testing.T) {
+	base := `
+version: 1
+global:
+  confirmations:
+%s
+sources:
+  - id: evm_main
+    type: evm
+    rpc_url: http://example-rpc
+rules:
+  - id: r1
+    source: evm_main
+    match:
+      type: log
+      contract: "0x0"
+      event: "E()"
+    sinks: ["sink1"]
+sinks:
+  - id: sink1
+    type: slack
+    webhook_url: https://hooks.slack.test
+`
+	cfg, err := Parse([]byte(fmt.Sprintf(base, "    evm: finalized\n    algorand: 4")))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := cfg.Global.Confirmations["evm"]; got.Tag != ConfirmFinalized || got.Depth != 0 {
+		t.Fatalf("unexpected evm confirmations: %+v", got)
+	}
+	if got := cfg.Global.Confirmations["algorand"]; got.Depth != 4 || got.Tag != "" {
+		t.Fatalf("unexpected algorand confirmations: %+v", got)
+	}
+	if _, err := Parse([]byte(fmt.Sprintf(base, "    algorand: safe"))); err == nil {
+		t.Fatalf("expected a block tag on algorand to fail")
+	}
+	if _, err := Parse([]byte(fmt.Sprintf(base, "    evm: latest"))); err == nil {
+		t.Fatalf("expected an unknown tag to fail")
+	}
+}
+
+func TestRPCURLAcceptsList(t *testing.T) {
+	base := `
+version: 1
+sources:
+  - id: evm_main
+    type: evm
+    rpc_url: %s
+rules:
+  - id: r1
+    source: evm_main
+    match:
+      type: log
+      contract: "0x0"
+      event: "E()"
+    sinks: ["sink1"]
+sinks:
+  - id: sink1
+    type: slack
+    webhook_url: https://hooks.slack.test
+`
+	cfg, err := Parse([]byte(fmt.Sprintf(base, `["https://primary", "https://backup"]`)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := cfg.Sources[0].RPCURL; len(got) != 2 || got[0] != "https://primary" || got[1] != "https://backup" {
+		t.Fatalf("unexpected rpc_url: %q", got)
+	}
+	if _, err := Parse([]byte(fmt.Sprintf(base, `["https://primary", ""]`))); err == nil {
+		t.Fatalf("expected an empty endpoint to fail")
+	}
+	if _, err := Parse([]byte(fmt.Sprintf(base, `[]`))); err == nil {
+		t.Fatalf("expected an empty list to fail")
+	}
+}
+
+func TestABIFetchConfig(t *testing.T) {
+	base := `
+version: 1
+sources:
+  - id: evm_main
+    type: evm
+    rpc_url: http://example-rpc
+    abi_fetch: %s
+rules:
+  - id: r1
+    source: evm_main
+    match:
+      type: log
+      contract: "0x0"
+      event: "E()"
+    sinks: ["sink1"]
+sinks:
+  - id: sink1
+    type: slack
+    webhook_url: https://hooks.slack.test
+`
+	cfg, err := Parse([]byte(fmt.Sprintf(base, "sourcify")))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	src := cfg.Sources[0]
+	if src.ABIFetch == nil || src.ABIFetch.Provider != ABIFetchSourcify {
+		t.Fatalf("unexpected abi_fetch: %+v", src.ABIFetch)
+	}
+	if paths := src.ABIPaths(); len(paths) != 1 || paths[0] != filepath.Join(DefaultABICacheDir, "1") {
+		t.Fatalf("unexpected abi paths: %v", paths)
+	}
+	cfg, err = Parse([]byte(fmt.Sprintf(base, "{provider: etherscan, api_key: k1, chain_id: 8453, cache_dir: ./abis}")))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := cfg.Sources[0].ABIFetch.Dir(); got != filepath.Join("abis", "8453") {
+		t.Fatalf("unexpected cache dir: %s", got)
+	}
+	if _, err := Parse([]byte(fmt.Sprintf(base, "etherscan"))); err == nil {
+		t.Fatalf("expected etherscan without api_key to fail")
+	}
+	if _, err := Parse([]byte(fmt.Sprintf(base, "blockscout"))); err == nil {
+		t.Fatalf("expected an unknown provider to fail")
+	}
+}
+
+func TestL2SourceConfig(t *testing.T) {
+	base := `
+version: 1
+sources:
+  - id: mainnet
+    type: evm
+    rpc_url: http://example-l1
+  - id: base
+    type: evm
+    rpc_url: http://example-l2
+    %s
+rules:
+  - id: r1
+    source: base
+    match:
+      type: sequencer_lag
+      max_lag: 30m
+    sinks: ["sink1"]
+sinks:
+  - id: sink1
+    type: slack
+    webhook_url: https://hooks.slack.test
+`
+	cfg, err := Parse([]byte(fmt.Sprintf(base, `l2: {stack: optimism, l1_source: mainnet, contract: "0x56315b90c40730925ec5485cf004d835058518A0"}`)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if l2 := cfg.Sources[1].L2; l2 == nil || l2.Stack != L2Optimism || l2.L1Source != "mainnet" {
+		t.Fatalf("unexpected l2: %+v", l2)
+	}
+	for name, l2 := range map[string]string{
+		"no l2":          "",
+		"unknown stack":  `l2: {stack: zksync, l1_source: mainnet, contract: "0x56315b90c40730925ec5485cf004d835058518A0"}`,
+		"unknown source": `l2: {stack: arbitrum, l1_source: sepolia, contract: "0x56315b90c40730925ec5485cf004d835058518A0"}`,
+		"own source":     `l2: {stack: arbitrum, l1_source: base, contract: "0x56315b90c40730925ec5485cf004d835058518A0"}`,
+	} {
+		if _, err := Parse([]byte(fmt.Sprintf(base, l2))); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSolanaSourceConfig(t *testing.T) {
+	base := `
+version: 1
+global:
+  confirmations:
+    solana: finalized
+sources:
+  - id: sol
+    type: solana
+    rpc_url: https://api.mainnet-beta.solana.com
+    start_slot: latest-100
+rules:
+  - id: r1
+    source: sol
+    match:
+      %s
+    sinks: ["sink1"]
+sinks:
+  - id: sink1
+    type: slack
+    webhook_url: https://hooks.slack.test
+`
+	cfg, err := Parse([]byte(fmt.Sprintf(base, `{type: spl_transfer, mint: EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v, min_amount: 1000000}`)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := cfg.Global.Confirmations["solana"]; got.Tag != ConfirmFinalized {
+		t.Fatalf("unexpected solana confirmations: %+v", got)
+	}
+	if _, err := Parse([]byte(fmt.Sprintf(base, `{type: program_log, program: JUP6LkbZbjS1jKKwapdHNy74zcZ3tdTJ4Zdq8U7gKpeR}`))); err != nil {
+		t.Fatalf("parse program_log: %v", err)
+	}
+	for name, match := range map[string]string{
+		"no program":           `{type: program_log}`,
+		"hex program":          `{type: program_log, program: "0x56315b90c40730925ec5485cf004d835058518A0"}`,
+		"min amount no mint":   `{type: spl_transfer, min_amount: 5}`,
+		"bad transfer address": `{type: spl_transfer, addresses: ["0x56315b90c40730925ec5485cf004d835058518A0"]}`,
+	} {
+		if _, err := Parse([]byte(fmt.Sprintf(base, match))); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestBitcoinSourceConfig(t *testing.T) {
+	base := `
+version: 1
+global:
+  confirmations:
+    bitcoin: 3
+sources:
+  - id: btc
+    type: bitcoin
+    %s
+rules:
+  - id: r1
+    source: btc
+    match:
+      %s
+    sinks: ["sink1"]
+sinks:
+  - id: sink1
+    type: slack
+    webhook_url: https://hooks.slack.test
+`
+	esplora := `esplora_url: https://blockstream.info/api`
+	cfg, err := Parse([]byte(fmt.Sprintf(base, esplora, `{type: large_tx, min_value: "2.5 btc", addresses: [bc1qgdjqv0av3q56jvd82tkdjpy7gdp9ut8tlqmgrpmv24sq90ecnvqqjwvw97]}`)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := cfg.Global.Confirmations["bitcoin"]; got.Depth != 3 {
+		t.Fatalf("unexpected bitcoin confirmations: %+v", got)
+	}
+	if sats, err := ParseSats(cfg.Rules[0].Match.MinValue); err != nil || sats != 250000000 {
+		t.Fatalf("expected 250000000 sats, got %d (%v)", sats, err)
+	}
+	if _, err := Parse([]byte(fmt.Sprintf(base, "rpc_url: http://127.0.0.1:8332\n    max_reorg_depth: 12", `{type: large_tx, min_value: "100000"}`))); err != nil {
+		t.Fatalf("parse bitcoind source: %v", err)
+	}
+	for name, c := range map[string][2]string{
+		"no endpoint":       {"", `{type: large_tx, min_value: "1 btc"}`},
+		"both endpoints":    {esplora + "\n    rpc_url: http://127.0.0.1:8332", `{type: large_tx, min_value: "1 btc"}`},
+		"no min value":      {esplora, `{type: large_tx}`},
+		"sub-satoshi value": {esplora, `{type: large_tx, min_value: "0.000000001 btc"}`},
+		"evm address":       {esplora, `{type: large_tx, min_value: "1 btc", addresses: ["0x56315b90c40730925ec5485cf004d835058518A0"]}`},
+		"start slot":        {esplora + "\n    start_slot: latest", `{type: large_tx, min_value: "1 btc"}`},
+	} {
+		if _, err := Parse([]byte(fmt.Sprintf(base, c[0], c[1]))); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCosmosSourceConfig(t *testing.T) {
+	base := `
+version: 1
+global:
+  confirmations:
+    cosmos: 0
+sources:
+  - id: hub
+    type: cosmos
+    %s
+rules:
+  - id: r1
+    source: hub
+    match:
+      %s
+    sinks: ["sink1"]
+sinks:
+  - id: sink1
+    type: slack
+    webhook_url: https://hooks.slack.test
+`
+	rpc := `rpc_url: https://rpc.cosmos.test`
+	cfg, err := Parse([]byte(fmt.Sprintf(base, rpc, `{type: abci_event, event: wasm, attributes: {action: transfer}}`)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := cfg.Rules[0].Match.Attributes["action"]; got != "transfer" {
+		t.Fatalf("unexpected attributes: %+v", cfg.Rules[0].Match.Attributes)
+	}
+	for name, c := range map[string][2]string{
+		"no endpoint":        {"", `{type: abci_event, event: transfer}`},
+		"no event":           {rpc, `{type: abci_event}`},
+		"empty attribute":    {rpc, `{type: abci_event, event: transfer, attributes: {"": x}}`},
+		"attributes on logs": {rpc, `{type: log, contract: "0x56315b90c40730925ec5485cf004d835058518A0", event: "Transfer(address,address,uint256)", attributes: {a: b}}`},
+		"esplora url":        {rpc + "\n    esplora_url: https://blockstream.info/api", `{type: abci_event, event: transfer}`},
+	} {
+		if _, err := Parse([]byte(fmt.Sprintf(base, c[0], c[1]))); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSubstrateSourceConfig(t *testing.T) {
+	base := `
+version: 1
+global:
+  confirmations:
+    substrate: %s
+sources:
+  - id: dot
+    type: substrate
+    %s
+rules:
+  - id: r1
+    source: dot
+    match:
+      %s
+    sinks: ["sink1"]
+sinks:
+  - id: sink1
+    type: slack
+    webhook_url: https://hooks.slack.test
+`
+	rpc := "rpc_url: https://rpc.polkadot.test\n    max_reorg_depth: 20"
+	cfg, err := Parse([]byte(fmt.Sprintf(base, "finalized", rpc, `{type: pallet_event, pallet: Balances, event: Transfer}`)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := cfg.Global.Confirmations["substrate"]; got.Tag != ConfirmFinalized {
+		t.Fatalf("expected the finalized tag, got %+v", got)
+	}
+	if _, err := Parse([]byte(fmt.Sprintf(base, "2", rpc, `{type: pallet_event, pallet: Balances}`))); err != nil {
+		t.Fatalf("a pallet_event rule without event should parse: %v", err)
+	}
+	for name, c := range [][3]string{
+		{"0", "", `{type: pallet_event, pallet: Balances}`},
+		{"0", rpc, `{type: pallet_event, event: Transfer}`},
+		{"0", rpc, `{type: abci_event, event: transfer, pallet: Balances}`},
+		{"safe", rpc, `{type: pallet_event, pallet: Balances}`},
+	} {
+		if _, err := Parse([]byte(fmt.Sprintf(base, c[0], c[1], c[2]))); err == nil {
+			t.Fatalf("case %d: expected an error", name)
+		}
+	}
+}
+
+func TestNearSourceConfig(t *testing.T) {
+	base := `
+version: 1
+global:
+  confirmations:
+    near: finalized
+sources:
+  - id: near
+    type: near
+    %s
+rules:
+  - id: r1
+    source: near
+    match:
+      %s
+    sinks: ["sink1"]
+sinks:
+  - id: sink1
+    type: slack
+    webhook_url: https://hooks.slack.test
+`
+	rpc := `rpc_url: https://rpc.mainnet.near.org`
+	cfg, err := Parse([]byte(fmt.Sprintf(base, rpc, `{type: receipt, account: usdt.tether-token.near, methods: [ft_transfer, ft_transfer_call]}`)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := cfg.Global.Confirmations["near"]; got.Tag != ConfirmFinalized {
+		t.Fatalf("expected the finalized tag, got %+v", got)
+	}
+	if _, err := Parse([]byte(fmt.Sprintf(base, rpc, `{type: receipt, account: 8dbd2a6de4cbd8e4c2a2e7bfcdbe5b28c4e9b0e7c9bb3f2e3f6a8a8e2a1b1c0d}`))); err != nil {
+		t.Fatalf("an implicit account should parse: %v", err)
+	}
+	for name, c := range map[string][2]string{
+		"no endpoint":     {"", `{type: receipt, account: token.near}`},
+		"no account":      {rpc, `{type: receipt}`},
+		"bad account":     {rpc, `{type: receipt, account: Token.Near}`},
+		"empty method":    {rpc, `{type: receipt, account: token.near, methods: [""]}`},
+		"max_reorg_depth": {rpc + "\n    max_reorg_depth: 10", `{type: receipt, account: token.near}`},
+	} {
+		if _, err := Parse([]byte(fmt.Sprintf(base, c[0], c[1]))); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestTronSourceConfig(t *testing.T) {
+	base := `
+version: 1
+global:
+  confirmations:
+    tron: finalized
+sources:
+  - id: tron
+    type: tron
+    %s
+rules:
+  - id: r1
+    source: tron
+    match:
+      %s
+    sinks: ["sink1"]
+sinks:
+  - id: sink1
+    type: slack
+    webhook_url: https://hooks.slack.test
+`
+	rpc := `rpc_url: https://api.trongrid.io`
+	cfg, err := Parse([]byte(fmt.Sprintf(base, rpc+"\n    max_reorg_depth: 20", `{type: trc20_transfer, contract: TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t, min_amount: 1000000000}`)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := cfg.Global.Confirmations["tron"]; got.Tag != ConfirmFinalized {
+		t.Fatalf("expected the finalized tag, got %+v", got)
+	}
+	if _, err := Parse([]byte(fmt.Sprintf(base, rpc, `{type: trx_transfer, min_value: "100000 trx", addresses: [TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t]}`))); err != nil {
+		t.Fatalf("trx_transfer: %v", err)
+	}
+	if v, err := ParseSun("1.5 trx"); err != nil || v != 1_500_000 {
+		t.Fatalf("ParseSun: %d, %v", v, err)
+	}
+	for name, c := range map[string][2]string{
+		"no endpoint":          {"", `{type: trx_transfer, min_value: "1 trx"}`},
+		"evm contract":         {rpc, `{type: trc20_transfer, contract: "0xdAC17F958D2ee523a2206206994597C13D831ec7"}`},
+		"amount without token": {rpc, `{type: trc20_transfer, min_amount: 5}`},
+		"bad address":          {rpc, `{type: trc20_transfer, addresses: [bob]}`},
+		"no min_value":         {rpc, `{type: trx_transfer}`},
+		"fractional sun":       {rpc, `{type: trx_transfer, min_value: "0.0000001 trx"}`},
+	} {
+		if _, err := Parse([]byte(fmt.Sprintf(base, c[0], c[1]))); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestIngestSourceConfig(t *testing.T) {
+	base := `
+version: 1
+sources:
+  - id: hooks
+    type: ingest
+    %s
+rules:
+  - id: r1
+    source: hooks
+    match:
+      type: ingest
+      event: Swap
+    sinks: ["sink1"]
+sinks:
+  - id: sink1
+    type: slack
+    webhook_url: https://hooks.slack.test
+`
+	ok := "listen: \":8090\"\n    secret: s3cret"
+	cfg, err := Parse([]byte(fmt.Sprintf(base, ok+"\n    signature_header: X-Alchemy-Signature\n    chain: arbitrum")))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if src := cfg.Sources[0]; src.SignatureHeader != "X-Alchemy-Signature" || src.Chain != "arbitrum" {
+		t.Fatalf("unexpected source %+v", src)
+	}
+	for name, c := range map[string]string{
+		"no listen": "secret: s3cret",
+		"no secret": `listen: ":8090"`,
+		"rpc_url":   ok + "\n    rpc_url: https://rpc.example",
+		"timestamp": ok + "\n    timestamp_header: X-Time\n    body_only_signature: true",
+	} {
+		if _, err := Parse([]byte(fmt.Sprintf(base, c))); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+	evm := Source{ID: "evm", Type: "evm", RPCURL: URLs{"https://rpc.example"}, Secret: "s3cret"}
+	if err := evm.Validate(); err == nil {
+		t.Fatal("expected secret on an evm source to fail")
+	}
+}
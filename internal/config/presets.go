@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// preset is a named rule shorthand. Required params must be set; optional
+// ones fall back to their default.
+type preset struct {
+	required []string
+	optional map[string]string
+	expand   func(p map[string]string) MatchSpec
+}
+
+var presets = map[string]preset{
+	"erc20_large_transfer": {
+		required: []string{"contract", "min_value"},
+		expand: func(p map[string]string) MatchSpec {
+			return MatchSpec{
+				Type:     "log",
+				Contract: p["contract"],
+				Event:    "Transfer(address,address,uint256)",
+				Where:    []string{"value >= " + p["min_value"]},
+			}
+		},
+	},
+	"ownership_transferred": {
+		required: []string{"contract"},
+		expand: func(p map[string]string) MatchSpec {
+			return MatchSpec{
+				Type:     "log",
+				Contract: p["contract"],
+				Event:    "OwnershipTransferred(address,address)",
+			}
+		},
+	},
+	"pause_unpause": {
+		required: []string{"contract"},
+		expand: func(p map[string]string) MatchSpec {
+			return MatchSpec{
+				Type:     "log",
+				Contract: p["contract"],
+				Events:   []string{"Paused(address)", "Unpaused(address)"},
+			}
+		},
+	},
+	"algorand_governance_commit": {
+		optional: map[string]string{"min_amount": "0", "governor": ""},
+		expand: func(p map[string]string) MatchSpec {
+			m := MatchSpec{
+				Type:  "payment",
+				Where: []string{"governance_commit >= " + p["min_amount"]},
+			}
+			if p["governor"] != "" {
+				m.Where = append(m.Where, "receiver == "+p["governor"])
+			}
+			return m
+		},
+	},
+}
+
+// PresetNames lists the built-in rule presets.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandPreset replaces a preset rule's shorthand with the full match spec it
+// stands for. Any match.where of the rule is kept after the preset's own
+// predicates. Rules without a preset are left unchanged, so it is safe to
+// call more than once.
+func (r *Rule) ExpandPreset() error {
+	if r.Preset == "" {
+		return nil
+	}
+	p, ok := presets[strings.ToLower(r.Preset)]
+	if !ok {
+		return fmt.Errorf("unknown preset %q (available: %s)", r.Preset, strings.Join(PresetNames(), ", "))
+	}
+	if r.Match.Type != "" || r.Match.Contract != "" || len(r.Match.Contracts) > 0 || r.Match.Event != "" || len(r.Match.Events) > 0 || r.Match.Standard != "" || r.Match.Topic0 != "" || len(r.Match.Topics) > 0 || r.Match.AppID != 0 || len(r.Match.Addresses) > 0 || r.Match.AddressesFrom != "" || r.Match.Slot != "" || r.Match.Function != "" || r.Match.Account != "" {
+		return fmt.Errorf("preset %s sets the match itself; only match.where may be added", r.Preset)
+	}
+
+	params := map[string]string{}
+	for k, v := range p.optional {
+		params[k] = v
+	}
+	for k, v := range r.Params {
+		if !slices.Contains(p.required, k) {
+			if _, opt := p.optional[k]; !opt {
+				return fmt.Errorf("preset %s: unknown param %q", r.Preset, k)
+			}
+		}
+		params[k] = strings.TrimSpace(v)
+	}
+	for _, k := range p.required {
+		if params[k] == "" {
+			return fmt.Errorf("preset %s: param %q is required", r.Preset, k)
+		}
+	}
+
+	m := p.expand(params)
+	m.Where = append(m.Where, r.Match.Where...)
+	r.Match = m
+	r.Preset = ""
+	r.Params = nil
+	return nil
+}
@@ -0,0 +1,188 @@
+package devgen
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common"
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/algorand/go-codec/codec"
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source/algorand"
+)
+
+// AlgoChain is an algorand.AlgodClient whose rounds hold app calls, asset
+// transfers, and payments for the source's rules.
+type AlgoChain struct {
+	opts   Options
+	clock  clock
+	kinds  []txnKind
+	appIDs []uint64
+	addrs  []sdk.Address // from watch_address rules
+}
+
+type txnKind int
+
+const (
+	kindAppCall txnKind = iota
+	kindAssetTransfer
+	kindPayment
+	kindWatched
+)
+
+// NewAlgoChain builds a generated chain for the rules that apply to sourceID.
+func NewAlgoChain(opts Options, sourceID string, rules []config.Rule) *AlgoChain {
+	opts = opts.withDefaults()
+	c := &AlgoChain{opts: opts, clock: newClock(opts.BlockTime)}
+	seen := map[txnKind]bool{}
+	add := func(k txnKind) {
+		if !seen[k] {
+			seen[k] = true
+			c.kinds = append(c.kinds, k)
+		}
+	}
+	for _, r := range rules {
+		if !r.AppliesTo(sourceID) {
+			continue
+		}
+		switch strings.ToLower(r.Match.Type) {
+		case "app_call":
+			c.appIDs = append(c.appIDs, r.Match.AppID)
+			add(kindAppCall)
+		case "asset_transfer":
+			add(kindAssetTransfer)
+		case "payment":
+			add(kindPayment)
+		case config.MatchWatchAddress:
+			for _, a := range r.Match.Addresses {
+				if addr, err := sdk.DecodeAddress(a); err == nil {
+					c.addrs = append(c.addrs, addr)
+				}
+			}
+			if len(c.addrs) > 0 {
+				add(kindWatched)
+			}
+		}
+	}
+	return c
+}
+
+// Status implements algorand.AlgodClient.
+func (c *AlgoChain) Status() algorand.StatusGetter {
+	return algoStatus{round: c.clock.tip()}
+}
+
+// BlockRaw implements algorand.AlgodClient.
+func (c *AlgoChain) BlockRaw(round uint64) algorand.BlockGetter {
+	return algoBlock{c: c, round: round}
+}
+
+// GetBlockHash implements algorand.AlgodClient.
+func (c *AlgoChain) GetBlockHash(round uint64) algorand.BlockHashGetter {
+	return algoBlockHash{hash: c.blockHash(round)}
+}
+
+// blockHash is a made-up digest of the round; the scanner only compares it
+// with the next block's Branch.
+func (c *AlgoChain) blockHash(round uint64) sdk.BlockHash {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], c.opts.Seed)
+	binary.BigEndian.PutUint64(b[8:], round)
+	return sdk.BlockHash(sha512.Sum512_256(b[:]))
+}
+
+func (c *AlgoChain) block(round uint64) ([]byte, error) {
+	b := sdk.Block{BlockHeader: sdk.BlockHeader{
+		Round:     sdk.Round(round),
+		TimeStamp: c.clock.timeAt(round).Unix(),
+	}}
+	if round > 0 {
+		b.Branch = c.blockHash(round - 1)
+	}
+	if len(c.kinds) > 0 {
+		r := blockRand(c.opts.Seed, round)
+		for i := 0; i < c.opts.EventsPerBlock; i++ {
+			tx := c.txn(r, c.kinds[r.IntN(len(c.kinds))])
+			tx.FirstValid = sdk.Round(round)
+			tx.LastValid = sdk.Round(round + 1000)
+			b.Payset = append(b.Payset, sdk.SignedTxnInBlock{
+				SignedTxnWithAD: sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: tx}},
+			})
+		}
+	}
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, &codec.MsgpackHandle{}).Encode(b); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *AlgoChain) txn(r *rand.Rand, k txnKind) sdk.Transaction {
+	tx := sdk.Transaction{Header: sdk.Header{Sender: randAlgoAddress(r), Note: []byte("devgen")}}
+	switch k {
+	case kindAppCall:
+		tx.Type = sdk.ApplicationCallTx
+		tx.ApplicationID = sdk.AppIndex(c.appIDs[r.IntN(len(c.appIDs))])
+		tx.OnCompletion = sdk.NoOpOC
+		tx.ApplicationArgs = [][]byte{[]byte("devgen"), randBytes(r, 8)}
+	case kindAssetTransfer:
+		tx.Type = sdk.AssetTransferTx
+		tx.XferAsset = sdk.AssetIndex(1 + r.Uint64N(1_000_000))
+		tx.AssetAmount = randMicros(r)
+		tx.AssetReceiver = randAlgoAddress(r)
+	case kindPayment:
+		tx.Type = sdk.PaymentTx
+		tx.Amount = sdk.MicroAlgos(randMicros(r))
+		tx.Receiver = randAlgoAddress(r)
+	case kindWatched:
+		tx.Type = sdk.PaymentTx
+		tx.Amount = sdk.MicroAlgos(randMicros(r))
+		tx.Receiver = c.addrs[r.IntN(len(c.addrs))]
+	}
+	return tx
+}
+
+func randAlgoAddress(r *rand.Rand) sdk.Address {
+	var a sdk.Address
+	copy(a[:], randBytes(r, len(a)))
+	return a
+}
+
+// randMicros spans 1 microAlgo to about a million Algos.
+func randMicros(r *rand.Rand) uint64 {
+	n := uint64(1 + r.IntN(9))
+	for e := r.IntN(13); e > 0; e-- {
+		n *= 10
+	}
+	return n
+}
+
+type algoStatus struct{ round uint64 }
+
+func (s algoStatus) Do(context.Context, ...*common.Header) (models.NodeStatus, error) {
+	return models.NodeStatus{LastRound: s.round}, nil
+}
+
+type algoBlock struct {
+	c     *AlgoChain
+	round uint64
+}
+
+func (b algoBlock) Do(context.Context, ...*common.Header) ([]byte, error) {
+	if b.round > b.c.clock.tip() {
+		return nil, fmt.Errorf("round %d not available yet", b.round)
+	}
+	return b.c.block(b.round)
+}
+
+type algoBlockHash struct{ hash sdk.BlockHash }
+
+func (h algoBlockHash) Do(context.Context, ...*common.Header) (models.BlockHashResponse, error) {
+	return models.BlockHashResponse{Blockhash: base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(h.hash[:])}, nil
+}
@@ -0,0 +1,79 @@
+// Package devgen fabricates chain data for load and end-to-end testing. Its
+// clients stand in for an EVM node or algod: blocks appear at a fixed rate
+// and carry logs and transactions shaped to match the configured rules, so
+// scanners, predicates, dedupe, and sinks all run as they would against a
+// live chain.
+package devgen
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	// DefaultBlockTime is how often a new block is produced.
+	DefaultBlockTime = time.Second
+	// DefaultEventsPerBlock is how many matching logs or transactions each block carries.
+	DefaultEventsPerBlock = 5
+)
+
+// Options set the pace of a generated chain.
+type Options struct {
+	BlockTime      time.Duration
+	EventsPerBlock int
+	// Seed makes runs reproducible: the same seed yields the same blocks.
+	Seed uint64
+}
+
+func (o Options) withDefaults() Options {
+	if o.BlockTime <= 0 {
+		o.BlockTime = DefaultBlockTime
+	}
+	if o.EventsPerBlock < 0 {
+		o.EventsPerBlock = 0
+	}
+	return o
+}
+
+// clock maps wall time to block heights. Height 0 is produced at start.
+type clock struct {
+	start     time.Time
+	blockTime time.Duration
+	nowFunc   func() time.Time
+}
+
+func newClock(blockTime time.Duration) clock {
+	return clock{start: time.Now(), blockTime: blockTime, nowFunc: time.Now}
+}
+
+// tip is the latest height produced so far.
+func (c clock) tip() uint64 {
+	elapsed := c.nowFunc().Sub(c.start)
+	if elapsed < 0 {
+		return 0
+	}
+	return uint64(elapsed / c.blockTime)
+}
+
+// timeAt is when height was produced.
+func (c clock) timeAt(height uint64) time.Time {
+	return c.start.Add(time.Duration(height) * c.blockTime)
+}
+
+// blockRand returns the random source for one block, so a block reads the
+// same however often it is fetched.
+func blockRand(seed, height uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, height))
+}
+
+// randBytes fills n bytes from r.
+func randBytes(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	for i := 0; i < n; i += 8 {
+		var w [8]byte
+		binary.LittleEndian.PutUint64(w[:], r.Uint64())
+		copy(b[i:], w[:])
+	}
+	return b
+}
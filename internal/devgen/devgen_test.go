@@ -0,0 +1,106 @@
+package devgen
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source/algorand"
+	"github.com/devblac/watch-tower/internal/source/evm"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+func newTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+// advance moves the chain's clock n blocks past its start.
+func advance(c *clock, n int) {
+	at := c.start.Add(time.Duration(n) * c.blockTime)
+	c.nowFunc = func() time.Time { return at }
+}
+
+func TestEVMChainFeedsScanner(t *testing.T) {
+	ctx := context.Background()
+	rules := []config.Rule{
+		{ID: "transfers", Source: "dev", Match: config.MatchSpec{Type: "log", Contract: "0x00000000000000000000000000000000000000aa", Event: "Transfer(address,address,uint256)"}},
+		{ID: "nfts", Source: "dev", Match: config.MatchSpec{Type: evm.MatchERC721Transfer, Contract: "0x00000000000000000000000000000000000000bb"}},
+	}
+	chain, err := NewEVMChain(Options{EventsPerBlock: 4, Seed: 1}, "dev", rules, nil)
+	if err != nil {
+		t.Fatalf("new chain: %v", err)
+	}
+	advance(&chain.clock, 10)
+
+	sc, err := evm.NewScanner(chain, newTestStore(t), config.Source{ID: "dev", Type: "evm"}, 2, nil, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	byRule := map[string]int{}
+	for i := 0; i < 9; i++ {
+		evs, err := sc.ProcessNext(ctx)
+		if err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+		for _, ev := range evs {
+			byRule[ev.RuleID]++
+			if ev.RuleID == "nfts" && ev.Args["token_id"] == nil {
+				t.Fatalf("nft transfer not decoded: %+v", ev.Args)
+			}
+		}
+	}
+	// Blocks 0..8 are confirmed two below the tip at 10.
+	if byRule["transfers"]+byRule["nfts"] != 9*4 || byRule["transfers"] == 0 || byRule["nfts"] == 0 {
+		t.Fatalf("unexpected events per rule: %v", byRule)
+	}
+	if evs, err := sc.ProcessNext(ctx); err != nil || len(evs) != 0 {
+		t.Fatalf("expected scanner to wait for confirmations, got %d events, %v", len(evs), err)
+	}
+
+	// Blocks are stable across fetches.
+	a, _ := chain.HeaderByNumber(ctx, big.NewInt(5))
+	b, _ := chain.HeaderByNumber(ctx, big.NewInt(5))
+	if a.Hash() != b.Hash() {
+		t.Fatalf("header 5 changed between fetches")
+	}
+}
+
+func TestAlgoChainFeedsScanner(t *testing.T) {
+	ctx := context.Background()
+	rules := []config.Rule{
+		{ID: "app", Source: "dev", Match: config.MatchSpec{Type: "app_call", AppID: 42}},
+		{ID: "pay", Source: "dev", Match: config.MatchSpec{Type: "payment"}},
+	}
+	chain := NewAlgoChain(Options{EventsPerBlock: 3, Seed: 1}, "dev", rules)
+	advance(&chain.clock, 5)
+
+	sc, err := algorand.NewScanner(chain, newTestStore(t), config.Source{ID: "dev", Type: "algorand"}, 0, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	total := 0
+	for i := 0; i <= 5; i++ {
+		evs, err := sc.ProcessNext(ctx)
+		if err != nil {
+			t.Fatalf("round %d: %v", i, err)
+		}
+		for _, ev := range evs {
+			if ev.RuleID == "app" && ev.AppID != 42 {
+				t.Fatalf("unexpected app id %d", ev.AppID)
+			}
+		}
+		total += len(evs)
+	}
+	if total != 6*3 {
+		t.Fatalf("expected %d events, got %d", 6*3, total)
+	}
+}
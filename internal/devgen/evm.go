@@ -0,0 +1,298 @@
+package devgen
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source/evm"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// hashWindow is how many recent header hashes are kept to chain parents.
+const hashWindow = 4096
+
+var erc20Transfer = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// logTemplate is one kind of log a generated block can carry.
+type logTemplate struct {
+	contract common.Address
+	event    abi.Event
+	to       *common.Address // fixed recipient, for watch_address rules
+}
+
+// EVMChain is an evm.BlockClient whose blocks hold logs for the source's log,
+// NFT transfer, and watch_address rules.
+type EVMChain struct {
+	opts      Options
+	clock     clock
+	templates []logTemplate
+
+	mu     sync.Mutex
+	hashes map[uint64]common.Hash
+}
+
+// NewEVMChain builds a generated chain for the rules that apply to sourceID.
+// abis are used as the scanner uses them, so generated logs decode the same.
+func NewEVMChain(opts Options, sourceID string, rules []config.Rule, abis map[string]*abi.ABI) (*EVMChain, error) {
+	opts = opts.withDefaults()
+	c := &EVMChain{opts: opts, clock: newClock(opts.BlockTime), hashes: map[uint64]common.Hash{}}
+	for _, r := range rules {
+		if !r.AppliesTo(sourceID) {
+			continue
+		}
+		if r.Match.Type == config.MatchWatchAddress {
+			for _, a := range r.Match.Addresses {
+				if !common.IsHexAddress(a) {
+					continue
+				}
+				to := common.HexToAddress(a)
+				ev := abi.Event{Name: "Transfer", ID: erc20Transfer}
+				c.templates = append(c.templates, logTemplate{event: ev, to: &to})
+			}
+			continue
+		}
+		if !evm.IsMatchType(r.Match.Type) {
+			continue
+		}
+		events, err := evm.RuleEvents(r, abis)
+		if err != nil {
+			return nil, err
+		}
+		for _, contract := range r.Match.ContractList() {
+			for _, ev := range events {
+				c.templates = append(c.templates, logTemplate{contract: common.HexToAddress(contract), event: ev})
+			}
+		}
+	}
+	return c, nil
+}
+
+// HeaderByNumber implements evm.BlockClient. A nil number is the tip.
+func (c *EVMChain) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	tip := c.clock.tip()
+	n := tip
+	// Generated blocks never reorg, so finalized and safe (negative tags)
+	// are the tip too.
+	if number != nil && number.Sign() >= 0 {
+		n = number.Uint64()
+	}
+	if n > tip {
+		return nil, ethereum.NotFound
+	}
+	return c.header(n), nil
+}
+
+// FilterLogs implements evm.BlockClient.
+func (c *EVMChain) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	from, to := q.FromBlock.Uint64(), q.ToBlock.Uint64()
+	if tip := c.clock.tip(); to > tip {
+		to = tip
+	}
+	want := map[common.Address]bool{}
+	for _, a := range q.Addresses {
+		want[a] = true
+	}
+	var out []types.Log
+	for n := from; n <= to; n++ {
+		logs, err := c.logs(n)
+		if err != nil {
+			return nil, err
+		}
+		for _, lg := range logs {
+			if len(want) == 0 || want[lg.Address] {
+				out = append(out, lg)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (c *EVMChain) header(n uint64) *types.Header {
+	h := &types.Header{
+		Number: new(big.Int).SetUint64(n),
+		Time:   uint64(c.clock.timeAt(n).Unix()),
+	}
+	if n > 0 {
+		h.ParentHash = c.hashOf(n - 1)
+	}
+	return h
+}
+
+// hashOf returns the hash of header n, building any missing ancestors first
+// so that each header's ParentHash matches the one before it.
+func (c *EVMChain) hashOf(n uint64) common.Hash {
+	c.mu.Lock()
+	if h, ok := c.hashes[n]; ok {
+		c.mu.Unlock()
+		return h
+	}
+	start := n
+	for start > 0 {
+		if _, ok := c.hashes[start-1]; ok {
+			break
+		}
+		start--
+	}
+	c.mu.Unlock()
+
+	var h common.Hash
+	for i := start; i <= n; i++ {
+		h = c.header(i).Hash()
+		c.mu.Lock()
+		c.hashes[i] = h
+		if i >= hashWindow {
+			delete(c.hashes, i-hashWindow)
+		}
+		c.mu.Unlock()
+	}
+	return h
+}
+
+func (c *EVMChain) logs(n uint64) ([]types.Log, error) {
+	if len(c.templates) == 0 {
+		return nil, nil
+	}
+	r := blockRand(c.opts.Seed, n)
+	blockHash := c.hashOf(n)
+	out := make([]types.Log, 0, c.opts.EventsPerBlock)
+	for i := 0; i < c.opts.EventsPerBlock; i++ {
+		t := c.templates[r.IntN(len(c.templates))]
+		lg, err := t.build(r)
+		if err != nil {
+			return nil, fmt.Errorf("devgen %s: %w", t.event.Name, err)
+		}
+		lg.BlockNumber = n
+		lg.BlockHash = blockHash
+		lg.TxHash = common.BytesToHash(randBytes(r, 32))
+		lg.Index = uint(i)
+		out = append(out, lg)
+	}
+	return out, nil
+}
+
+func (t logTemplate) build(r *rand.Rand) (types.Log, error) {
+	if t.to != nil {
+		// An ERC-20 transfer to the watched address from a random token.
+		return types.Log{
+			Address: randAddress(r),
+			Topics:  []common.Hash{erc20Transfer, addressWord(randAddress(r)), addressWord(*t.to)},
+			Data:    common.LeftPadBytes(randAmount(r).Bytes(), 32),
+		}, nil
+	}
+	lg := types.Log{Address: t.contract}
+	if !t.event.Anonymous {
+		lg.Topics = []common.Hash{t.event.ID}
+	}
+	var data []any
+	var nonIndexed abi.Arguments
+	for _, in := range t.event.Inputs {
+		v, err := randValue(r, in.Type)
+		if err != nil {
+			return lg, err
+		}
+		if in.Indexed {
+			topic, err := topicWord(in.Type, v)
+			if err != nil {
+				return lg, err
+			}
+			lg.Topics = append(lg.Topics, topic)
+			continue
+		}
+		nonIndexed = append(nonIndexed, in)
+		data = append(data, v)
+	}
+	packed, err := nonIndexed.Pack(data...)
+	if err != nil {
+		return lg, err
+	}
+	lg.Data = packed
+	return lg, nil
+}
+
+// randValue returns a random Go value of the type abi packs for t.
+func randValue(r *rand.Rand, t abi.Type) (any, error) {
+	switch t.T {
+	case abi.AddressTy:
+		return randAddress(r), nil
+	case abi.BoolTy:
+		return r.IntN(2) == 1, nil
+	case abi.StringTy:
+		return fmt.Sprintf("devgen-%d", r.IntN(1000)), nil
+	case abi.BytesTy:
+		return randBytes(r, 1+r.IntN(64)), nil
+	case abi.UintTy, abi.IntTy:
+		if t.Size > 64 {
+			return randAmount(r), nil
+		}
+		v := reflect.New(t.GetType()).Elem()
+		bits := min(t.Size, 16)
+		if t.T == abi.UintTy {
+			v.SetUint(r.Uint64N(1 << bits))
+		} else {
+			v.SetInt(r.Int64N(1 << (bits - 1)))
+		}
+		return v.Interface(), nil
+	case abi.FixedBytesTy:
+		v := reflect.New(t.GetType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(randBytes(r, t.Size)))
+		return v.Interface(), nil
+	case abi.SliceTy, abi.ArrayTy:
+		n := 2
+		if t.T == abi.ArrayTy {
+			n = t.Size
+		}
+		v := reflect.New(t.GetType()).Elem()
+		if t.T == abi.SliceTy {
+			v = reflect.MakeSlice(t.GetType(), n, n)
+		}
+		for i := 0; i < n; i++ {
+			e, err := randValue(r, *t.Elem)
+			if err != nil {
+				return nil, err
+			}
+			v.Index(i).Set(reflect.ValueOf(e))
+		}
+		return v.Interface(), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t.String())
+}
+
+// topicWord encodes an indexed argument. Dynamic types are hashed, as the
+// EVM does.
+func topicWord(t abi.Type, v any) (common.Hash, error) {
+	packed, err := abi.Arguments{{Type: t}}.Pack(v)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	switch t.T {
+	case abi.AddressTy, abi.BoolTy, abi.UintTy, abi.IntTy, abi.FixedBytesTy:
+		return common.BytesToHash(packed), nil
+	default:
+		return crypto.Keccak256Hash(packed), nil
+	}
+}
+
+func randAddress(r *rand.Rand) common.Address {
+	return common.BytesToAddress(randBytes(r, common.AddressLength))
+}
+
+// randAmount spans dust to about a million whole 18-decimal tokens, so
+// threshold predicates see both sides.
+func randAmount(r *rand.Rand) *big.Int {
+	exp := big.NewInt(int64(r.IntN(25)))
+	amt := new(big.Int).Exp(big.NewInt(10), exp, nil)
+	return amt.Mul(amt, big.NewInt(int64(1+r.IntN(9))))
+}
+
+func addressWord(a common.Address) common.Hash {
+	return common.BytesToHash(common.LeftPadBytes(a.Bytes(), 32))
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// DefaultAuditRetention is how long stored audit records are kept.
+const DefaultAuditRetention = 7 * 24 * time.Hour
+
+// Audit decisions, one per step of an event's journey through a rule.
+const (
+	AuditPredicateFailed = "predicate_failed"
+	AuditEvalError       = "eval_error"
+	AuditMatched         = "matched"
+	AuditSilenced        = "silenced"
+	AuditRateLimited     = "rate_limited"
+	AuditDeduped         = "deduped"
+	AuditHeld            = "held" // for a sink's quiet hours
+	AuditSent            = "sent"
+	AuditFailed          = "failed"
+)
+
+// SetAuditTable makes the runner, and its dispatcher, store audit records
+// and delete those older than retention (DefaultAuditRetention when zero).
+func (r *Runner) SetAuditTable(retention time.Duration) {
+	if retention <= 0 {
+		retention = DefaultAuditRetention
+	}
+	r.auditRetention = retention
+}
+
+// audit logs one decision and stores it when the audit table is enabled.
+// A failed insert is only logged: auditing never holds up an alert.
+func (r *Runner) audit(ctx context.Context, rec storage.AuditRecord) {
+	r.log.Debug("audit", "decision", rec.Decision, "rule", rec.RuleID, "source", rec.SourceID,
+		"height", rec.Height, "tx", rec.TxHash, "alert_id", rec.AlertID, "sink", rec.SinkID, "detail", rec.Detail)
+	if r.auditRetention == 0 {
+		return
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = r.nowFunc()
+	}
+	if err := r.store.InsertAudit(context.WithoutCancel(ctx), rec); err != nil {
+		r.log.Warn("audit insert failed", "decision", rec.Decision, "rule", rec.RuleID, "error", err)
+	}
+}
+
+// auditEvent starts an audit record for ev under rule ruleID.
+func auditEvent(ev Event, ruleID, decision string) storage.AuditRecord {
+	return storage.AuditRecord{
+		RuleID:   ruleID,
+		SourceID: ev.SourceID,
+		Height:   ev.Height,
+		TxHash:   ev.TxHash,
+		Decision: decision,
+	}
+}
+
+// SweepAudit deletes audit records past their retention and returns how
+// many were removed. It does nothing unless the audit table is enabled.
+func (r *Runner) SweepAudit(ctx context.Context) (int64, error) {
+	if r.auditRetention == 0 {
+		return 0, nil
+	}
+	return r.store.PruneAudit(ctx, r.nowFunc().Add(-r.auditRetention))
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// Backfill runs the EVM sources' log rules over blocks from..to, in
+// eth_getLogs windows of up to window blocks (0 for the default), without
+// moving their cursors. Alerts go through the usual predicates, dedupe and
+// rate limits, are marked as backfill, and skip sinks set to skip_backfill.
+// Algorand sources are not backfilled.
+func (r *Runner) Backfill(ctx context.Context, from, to, window uint64) error {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+
+	var ids []string
+	for id, sc := range r.scanners {
+		if _, ok := evmScanner(sc); ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		sc, _ := evmScanner(r.scanners[id])
+		r.log.Info("backfill started", "source", id, "from", from, "to", to)
+		err := r.pipeEvents(func(emit func(Event) error) error {
+			return sc.Backfill(ctx, from, to, window, func(e source.Event) error {
+				ev := event(e)
+				ev.Backfill = true
+				return emit(ev)
+			}, func(height uint64) {
+				r.log.Debug("backfill progress", "source", id, "height", height)
+			})
+		}, func(ev Event) error {
+			return r.handleEvent(ctx, ev)
+		})
+		if err != nil {
+			return fmt.Errorf("backfill evm source %s: %w", id, err)
+		}
+		r.log.Info("backfill complete", "source", id, "from", from, "to", to)
+	}
+	return nil
+}
+
+// backfillSinks drops the sinks set to skip_backfill from sinks.
+func (r *Runner) backfillSinks(sinks []string) []string {
+	out := make([]string, 0, len(sinks))
+	for _, id := range sinks {
+		if s := r.sinkIDs[id]; s != nil && s.SkipBackfill {
+			continue
+		}
+		out = append(out, id)
+	}
+	return out
+}
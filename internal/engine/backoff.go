@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// DefaultFailureBudget is how many consecutive failed ticks a source may have
+// before RunOnce gives up and returns the error.
+const DefaultFailureBudget = 10
+
+// sourceBackoff tracks consecutive failures of one source.
+type sourceBackoff struct {
+	failures int
+	until    time.Time
+}
+
+// SetFailureBudget sets how many consecutive failures a source may have
+// before RunOnce returns its error. Zero or less retries forever.
+func (r *Runner) SetFailureBudget(n int) {
+	r.failureBudget = n
+}
+
+// backingOff reports whether the source is waiting out a backoff.
+func (r *Runner) backingOff(sourceID string) bool {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	b := r.backoffs[sourceID]
+	return b != nil && r.nowFunc().Before(b.until)
+}
+
+// sourceDone records the outcome of a source's tick. A failure puts the source
+// in a jittered exponential backoff so the other sources keep running; the
+// error is only returned once the failure budget is spent or ctx is done.
+func (r *Runner) sourceDone(ctx context.Context, sourceID string, err error) error {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	if err == nil {
+		delete(r.backoffs, sourceID)
+		return nil
+	}
+	if ctx.Err() != nil {
+		return err
+	}
+	b := r.backoffs[sourceID]
+	if b == nil {
+		b = &sourceBackoff{}
+		r.backoffs[sourceID] = b
+	}
+	b.failures++
+	if r.failureBudget > 0 && b.failures >= r.failureBudget {
+		return err
+	}
+	wait := jitter(backoff(b.failures))
+	b.until = r.nowFunc().Add(wait)
+	r.log.Warn("source failed, backing off", "source", sourceID, "failures", b.failures, "retry_in", wait.Round(time.Millisecond), "error", err)
+	return nil
+}
+
+// jitter spreads d over [d/2, d) so sources that fail together do not retry
+// in lockstep.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source/algorand"
+	"github.com/devblac/watch-tower/internal/source/bitcoin"
+	"github.com/devblac/watch-tower/internal/source/cosmos"
+	"github.com/devblac/watch-tower/internal/source/near"
+	"github.com/devblac/watch-tower/internal/source/solana"
+	"github.com/devblac/watch-tower/internal/source/substrate"
+	"github.com/devblac/watch-tower/internal/source/tron"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// ChainSource builds the scanners of one source type.
+type ChainSource struct {
+	// SetStart makes a source without a cursor start at height, a number
+	// or a blocktime reference.
+	SetStart func(src *config.Source, height string)
+	// Dial connects to the source's node, limited to its max_rps.
+	Dial func(src config.Source) (any, error)
+	// Ping checks that cli, as returned by Dial, answers.
+	Ping func(ctx context.Context, cli any) error
+	// NewScanner scans src through cli, a client Dial returned or any other
+	// implementing the chain's client interface, waiting for conf.
+	NewScanner func(cli any, store *storage.Store, src config.Source, conf config.Confirmation, rules []config.Rule) (Scanner, error)
+}
+
+// Chains lists the source types built from a client and their chain's
+// confirmation setting alone, by type. EVM sources, which also load ABIs
+// and resolvers, are built by the caller and wrapped with NewEVMScanner.
+var Chains = map[string]ChainSource{
+	algorand.Chain: {
+		SetStart: func(src *config.Source, height string) { src.StartRound = height },
+		Dial: func(src config.Source) (any, error) {
+			cli, err := algorand.NewAlgodClient(src.AlgodURL, src.AlgodHeaders())
+			if err != nil {
+				return nil, err
+			}
+			return algorand.NewLimitedClient(cli, src.MaxRPS), nil
+		},
+		Ping: func(ctx context.Context, cli any) error {
+			_, err := cli.(algorand.AlgodClient).Status().Do(ctx)
+			return err
+		},
+		NewScanner: func(cli any, store *storage.Store, src config.Source, conf config.Confirmation, rules []config.Rule) (Scanner, error) {
+			c, ok := cli.(algorand.AlgodClient)
+			if !ok {
+				return nil, clientError(src, cli)
+			}
+			sc, err := algorand.NewScanner(c, store, src, conf.Depth, rules)
+			if err != nil {
+				return nil, err
+			}
+			return chainScanner{sc, algorand.Chain}, nil
+		},
+	},
+	solana.Chain: {
+		SetStart: func(src *config.Source, height string) { src.StartSlot = height },
+		Dial: func(src config.Source) (any, error) {
+			cli, err := solana.NewRPCClient(src.RPCURL, config.HTTPHeaders(src.RPCHeaders, src.RPCBasicAuth), src.MaxRPS)
+			if err != nil {
+				return nil, err
+			}
+			return cli, nil
+		},
+		Ping: func(ctx context.Context, cli any) error {
+			_, err := cli.(solana.Client).GetSlot(ctx, solana.CommitmentConfirmed)
+			return err
+		},
+		NewScanner: func(cli any, store *storage.Store, src config.Source, conf config.Confirmation, rules []config.Rule) (Scanner, error) {
+			c, ok := cli.(solana.Client)
+			if !ok {
+				return nil, clientError(src, cli)
+			}
+			sc, err := solana.NewScanner(c, store, src, conf.Depth, rules)
+			if err != nil {
+				return nil, err
+			}
+			sc.SetFinality(conf.Tag)
+			return chainScanner{sc, solana.Chain}, nil
+		},
+	},
+	bitcoin.Chain: {
+		SetStart: func(src *config.Source, height string) { src.StartBlock = height },
+		Dial:     func(src config.Source) (any, error) { return bitcoin.NewSourceClient(src) },
+		Ping: func(ctx context.Context, cli any) error {
+			_, err := cli.(bitcoin.Client).TipHeight(ctx)
+			return err
+		},
+		NewScanner: func(cli any, store *storage.Store, src config.Source, conf config.Confirmation, rules []config.Rule) (Scanner, error) {
+			c, ok := cli.(bitcoin.Client)
+			if !ok {
+				return nil, clientError(src, cli)
+			}
+			sc, err := bitcoin.NewScanner(c, store, src, conf.Depth, rules)
+			if err != nil {
+				return nil, err
+			}
+			return chainScanner{sc, bitcoin.Chain}, nil
+		},
+	},
+	cosmos.Chain: {
+		SetStart: func(src *config.Source, height string) { src.StartBlock = height },
+		Dial: func(src config.Source) (any, error) {
+			cli, err := cosmos.NewRPCClient(src.RPCURL, config.HTTPHeaders(src.RPCHeaders, src.RPCBasicAuth), src.MaxRPS)
+			if err != nil {
+				return nil, err
+			}
+			return cli, nil
+		},
+		Ping: func(ctx context.Context, cli any) error {
+			_, err := cli.(cosmos.Client).LatestHeight(ctx)
+			return err
+		},
+		NewScanner: func(cli any, store *storage.Store, src config.Source, conf config.Confirmation, rules []config.Rule) (Scanner, error) {
+			c, ok := cli.(cosmos.Client)
+			if !ok {
+				return nil, clientError(src, cli)
+			}
+			sc, err := cosmos.NewScanner(c, store, src, conf.Depth, rules)
+			if err != nil {
+				return nil, err
+			}
+			return chainScanner{sc, cosmos.Chain}, nil
+		},
+	},
+	substrate.Chain: {
+		SetStart: func(src *config.Source, height string) { src.StartBlock = height },
+		Dial: func(src config.Source) (any, error) {
+			cli, err := substrate.NewRPCClient(src.RPCURL, config.HTTPHeaders(src.RPCHeaders, src.RPCBasicAuth), src.MaxRPS)
+			if err != nil {
+				return nil, err
+			}
+			return cli, nil
+		},
+		Ping: func(ctx context.Context, cli any) error {
+			_, err := cli.(substrate.Client).LatestHeight(ctx)
+			return err
+		},
+		NewScanner: func(cli any, store *storage.Store, src config.Source, conf config.Confirmation, rules []config.Rule) (Scanner, error) {
+			c, ok := cli.(substrate.Client)
+			if !ok {
+				return nil, clientError(src, cli)
+			}
+			sc, err := substrate.NewScanner(c, store, src, conf.Depth, rules)
+			if err != nil {
+				return nil, err
+			}
+			sc.SetFinality(conf.Tag)
+			return chainScanner{sc, substrate.Chain}, nil
+		},
+	},
+	near.Chain: {
+		SetStart: func(src *config.Source, height string) { src.StartBlock = height },
+		Dial: func(src config.Source) (any, error) {
+			cli, err := near.NewRPCClient(src.RPCURL, config.HTTPHeaders(src.RPCHeaders, src.RPCBasicAuth), src.MaxRPS)
+			if err != nil {
+				return nil, err
+			}
+			return cli, nil
+		},
+		Ping: func(ctx context.Context, cli any) error {
+			_, err := cli.(near.Client).LatestHeight(ctx)
+			return err
+		},
+		NewScanner: func(cli any, store *storage.Store, src config.Source, conf config.Confirmation, rules []config.Rule) (Scanner, error) {
+			c, ok := cli.(near.Client)
+			if !ok {
+				return nil, clientError(src, cli)
+			}
+			sc, err := near.NewScanner(c, store, src, conf.Depth, rules)
+			if err != nil {
+				return nil, err
+			}
+			sc.SetFinality(conf.Tag)
+			return chainScanner{sc, near.Chain}, nil
+		},
+	},
+	tron.Chain: {
+		SetStart: func(src *config.Source, height string) { src.StartBlock = height },
+		Dial: func(src config.Source) (any, error) {
+			cli, err := tron.NewRPCClient(src.RPCURL, config.HTTPHeaders(src.RPCHeaders, src.RPCBasicAuth), src.MaxRPS)
+			if err != nil {
+				return nil, err
+			}
+			return cli, nil
+		},
+		Ping: func(ctx context.Context, cli any) error {
+			_, err := cli.(tron.Client).LatestHeight(ctx)
+			return err
+		},
+		NewScanner: func(cli any, store *storage.Store, src config.Source, conf config.Confirmation, rules []config.Rule) (Scanner, error) {
+			c, ok := cli.(tron.Client)
+			if !ok {
+				return nil, clientError(src, cli)
+			}
+			sc, err := tron.NewScanner(c, store, src, conf.Depth, rules)
+			if err != nil {
+				return nil, err
+			}
+			sc.SetFinality(conf.Tag)
+			return chainScanner{sc, tron.Chain}, nil
+		},
+	},
+}
+
+func clientError(src config.Source, cli any) error {
+	return fmt.Errorf("source %s: %T is not a %s client", src.ID, cli, src.Type)
+}
@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+const (
+	// defaultMaxAttempts bounds retries before a delivery is recorded as failed.
+	defaultMaxAttempts = 5
+	// dispatchPoll is how often the queue is checked for retries without a Notify.
+	dispatchPoll = time.Second
+	maxBackoff   = 5 * time.Minute
+)
+
+// Dispatcher delivers queued alerts to sinks with a bounded worker pool, so
+// slow sinks do not hold up scanning. The queue lives in the store, so
+// pending deliveries survive restarts.
+type Dispatcher struct {
+	store       *storage.Store
+	sinks       map[string]sink.Sender
+	workers     int
+	maxAttempts int
+	nowFunc     func() time.Time
+	log         *slog.Logger
+	audit       func(context.Context, storage.AuditRecord)
+
+	notify   chan struct{}
+	mu       sync.Mutex
+	inflight map[string]struct{}
+}
+
+// NewDispatcher builds a dispatcher with the given number of workers (minimum 1).
+func NewDispatcher(store *storage.Store, sinks map[string]sink.Sender, workers int) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Dispatcher{
+		store:       store,
+		sinks:       sinks,
+		workers:     workers,
+		maxAttempts: defaultMaxAttempts,
+		nowFunc:     time.Now,
+		log:         discardLogger,
+		audit:       func(context.Context, storage.AuditRecord) {},
+		notify:      make(chan struct{}, 1),
+		inflight:    map[string]struct{}{},
+	}
+}
+
+// SetLogger makes the dispatcher log delivery outcomes, keyed by alert_id.
+func (d *Dispatcher) SetLogger(l *slog.Logger) {
+	d.log = l
+}
+
+// Enqueue queues a send of an already recorded alert.
+func (d *Dispatcher) Enqueue(ctx context.Context, alertID, sinkID string) error {
+	return d.store.EnqueueDelivery(ctx, storage.Delivery{AlertID: alertID, SinkID: sinkID, NextAttemptAt: d.nowFunc()})
+}
+
+// Notify wakes the dispatcher after new deliveries were queued.
+func (d *Dispatcher) Notify() {
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run feeds due deliveries to the workers until ctx is cancelled, then waits
+// for in-flight sends to finish.
+func (d *Dispatcher) Run(ctx context.Context) {
+	jobs := make(chan storage.Delivery, d.workers)
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				// An unrecorded outcome leaves the job queued; it is
+				// picked up again on a later pass.
+				if err := d.deliver(ctx, job); err != nil {
+					d.log.Error("alert delivery not recorded", "alert_id", job.AlertID, "sink", job.SinkID, "error", err)
+				}
+				d.done(job)
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(dispatchPoll)
+	defer ticker.Stop()
+	for {
+		if err := d.feed(ctx, jobs); err != nil && ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.notify:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain delivers everything currently due, inline. It is used by --once runs
+// that exit before a background dispatcher would get to the queue. It stops at
+// the first outcome it cannot record, which would otherwise be fetched and
+// sent again on every pass.
+func (d *Dispatcher) Drain(ctx context.Context) error {
+	for {
+		due, err := d.store.DueDeliveries(ctx, d.nowFunc(), 100)
+		if err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+		for _, job := range due {
+			if err := d.deliver(ctx, job); err != nil {
+				return err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *Dispatcher) feed(ctx context.Context, jobs chan<- storage.Delivery) error {
+	due, err := d.store.DueDeliveries(ctx, d.nowFunc(), d.workers*4)
+	if err != nil {
+		return err
+	}
+	for _, job := range due {
+		if !d.claim(job) {
+			continue
+		}
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			d.done(job)
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) claim(job storage.Delivery) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := job.AlertID + "/" + job.SinkID
+	if _, busy := d.inflight[key]; busy {
+		return false
+	}
+	d.inflight[key] = struct{}{}
+	return true
+}
+
+func (d *Dispatcher) done(job storage.Delivery) {
+	d.mu.Lock()
+	delete(d.inflight, job.AlertID+"/"+job.SinkID)
+	d.mu.Unlock()
+}
+
+// deliver attempts one send and records the outcome, returning an error only
+// when the outcome could not be stored. Bookkeeping uses a context detached
+// from cancellation so shutdown does not lose the result.
+func (d *Dispatcher) deliver(ctx context.Context, job storage.Delivery) error {
+	bg := context.WithoutCancel(ctx)
+	log := d.log.With("alert_id", job.AlertID, "sink", job.SinkID)
+	s := d.sinks[job.SinkID]
+	if s == nil {
+		log.Warn("alert delivery failed", "error", "unknown sink")
+		d.audit(bg, storage.AuditRecord{AlertID: job.AlertID, SinkID: job.SinkID, Decision: AuditFailed, Detail: "unknown sink"})
+		return d.complete(bg, job, "failed")
+	}
+
+	sendErr := errNoPayload
+	payload, err := decodePayload(job.PayloadJSON)
+	if err == nil {
+		payload.AlertID = job.AlertID // payloads stored before alert_id was added lack it
+		sendErr = s.Send(ctx, payload)
+	}
+	rec := storage.AuditRecord{RuleID: payload.RuleID, SourceID: payload.SourceID, Height: payload.Height, TxHash: payload.TxHash, AlertID: job.AlertID, SinkID: job.SinkID, Decision: AuditSent}
+	if sendErr == nil {
+		log.Info("alert sent", "attempt", job.Attempts+1)
+		d.audit(bg, rec)
+		return d.complete(bg, job, "sent")
+	}
+
+	job.Attempts++
+	if job.Attempts >= d.maxAttempts || sendErr == errNoPayload {
+		log.Warn("alert delivery failed", "attempts", job.Attempts, "error", sendErr)
+		rec.Decision, rec.Detail = AuditFailed, sendErr.Error()
+		d.audit(bg, rec)
+		return d.complete(bg, job, "failed")
+	}
+	job.NextAttemptAt = d.nowFunc().Add(backoff(job.Attempts))
+	job.LastError = sendErr.Error()
+	log.Info("alert delivery retry", "attempt", job.Attempts, "next_attempt", job.NextAttemptAt, "error", sendErr)
+	if err := d.store.RetryDelivery(bg, job); err != nil {
+		return fmt.Errorf("reschedule delivery %s to %s: %w", job.AlertID, job.SinkID, err)
+	}
+	return nil
+}
+
+// complete records the final status of a delivery and removes it from the queue.
+func (d *Dispatcher) complete(ctx context.Context, job storage.Delivery, status string) error {
+	err := d.store.CompleteDelivery(ctx, storage.Send{AlertID: job.AlertID, SinkID: job.SinkID, Status: status, CreatedAt: d.nowFunc()})
+	if err != nil {
+		return fmt.Errorf("record delivery %s to %s: %w", job.AlertID, job.SinkID, err)
+	}
+	return nil
+}
+
+var errNoPayload = errors.New("alert payload missing")
+
+// backoff doubles from one second per attempt, capped at maxBackoff.
+func backoff(attempt int) time.Duration {
+	d := time.Second << (attempt - 1)
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+// decodePayload restores a stored payload. Numbers are kept as json.Number so
+// large integers (token amounts) render exactly in templates.
+func decodePayload(raw string) (sink.EventPayload, error) {
+	var p sink.EventPayload
+	if raw == "" {
+		return p, errNoPayload
+	}
+	dec := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	dec.UseNumber()
+	if err := dec.Decode(&p); err != nil {
+		return p, fmt.Errorf("decode payload: %w", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+type flakySink struct {
+	failures int
+	got      []sink.EventPayload
+}
+
+func (f *flakySink) Send(_ context.Context, payload sink.EventPayload) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("sink down")
+	}
+	f.got = append(f.got, payload)
+	return nil
+}
+
+func TestDispatcherQueuesAndRetries(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	s := &flakySink{failures: 1}
+	sinks := map[string]sink.Sender{"s1": s}
+	cfg := &config.Config{Rules: []config.Rule{{ID: "r1", Sinks: []string{"s1"}}}}
+	runner, err := NewRunner(store, cfg, nil, sinks, false, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	now := time.Now()
+	d := NewDispatcher(store, sinks, 2)
+	d.nowFunc = func() time.Time { return now }
+	runner.SetDispatcher(d)
+
+	evs := []Event{{RuleID: "r1", TxHash: "0x1", Args: map[string]any{"value": "123456789012345678901234"}}}
+	if err := runner.handleEvents(ctx, evs); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if len(s.got) != 0 {
+		t.Fatalf("expected send to be queued, not inline")
+	}
+
+	// First attempt fails and is rescheduled with backoff.
+	if err := d.Drain(ctx); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	due, err := store.DueDeliveries(ctx, now.Add(time.Second), 10)
+	if err != nil {
+		t.Fatalf("due: %v", err)
+	}
+	if len(due) != 1 || due[0].Attempts != 1 || due[0].LastError != "sink down" {
+		t.Fatalf("unexpected queue: %+v", due)
+	}
+
+	now = now.Add(time.Second)
+	if err := d.Drain(ctx); err != nil {
+		t.Fatalf("drain retry: %v", err)
+	}
+	if len(s.got) != 1 || s.got[0].Args["value"] != "123456789012345678901234" {
+		t.Fatalf("unexpected deliveries: %+v", s.got)
+	}
+	if s.got[0].AlertID != due[0].AlertID {
+		t.Fatalf("expected payload to carry alert id %s, got %q", due[0].AlertID, s.got[0].AlertID)
+	}
+	stats, err := store.ListSinkStats(ctx, time.Time{})
+	if err != nil || len(stats) != 1 || stats[0].Sent != 1 {
+		t.Fatalf("expected recorded send, got %+v err=%v", stats, err)
+	}
+}
+
+func TestDispatcherGivesUpAfterMaxAttempts(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	s := &flakySink{failures: 100}
+	d := NewDispatcher(store, map[string]sink.Sender{"s1": s}, 1)
+	now := time.Now()
+	d.nowFunc = func() time.Time { return now }
+
+	if err := store.InsertAlert(ctx, storageAlert("a1")); err != nil {
+		t.Fatalf("alert: %v", err)
+	}
+	if err := d.Enqueue(ctx, "a1", "s1"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	for i := 0; i < defaultMaxAttempts; i++ {
+		if err := d.Drain(ctx); err != nil {
+			t.Fatalf("drain: %v", err)
+		}
+		now = now.Add(maxBackoff)
+	}
+	due, _ := store.DueDeliveries(ctx, now, 10)
+	if len(due) != 0 {
+		t.Fatalf("expected queue empty, got %+v", due)
+	}
+	stats, _ := store.ListSinkStats(ctx, time.Time{})
+	if len(stats) != 1 || stats[0].Failed != 1 {
+		t.Fatalf("expected one failed send, got %+v", stats)
+	}
+}
+
+func TestDispatcherDrainStopsWhenOutcomeNotRecorded(t *testing.T) {
+	path := t.TempDir() + "/db.sqlite"
+	store, err := storage.Open(path)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+	s := &flakySink{}
+	d := NewDispatcher(store, map[string]sink.Sender{"s1": s}, 1)
+
+	if err := store.InsertAlert(ctx, storageAlert("a1")); err != nil {
+		t.Fatalf("alert: %v", err)
+	}
+	if err := d.Enqueue(ctx, "a1", "s1"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	// Make recording a send fail while the queue stays readable.
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TRIGGER no_sends BEFORE INSERT ON sends BEGIN SELECT RAISE(ABORT, 'disk full'); END;`); err != nil {
+		t.Fatalf("trigger: %v", err)
+	}
+
+	if err := d.Drain(ctx); err == nil {
+		t.Fatalf("expected drain to fail when the send cannot be recorded")
+	}
+	if len(s.got) != 1 {
+		t.Fatalf("expected one send, got %d", len(s.got))
+	}
+}
+
+func TestDispatcherRun(t *testing.T) {
+	store := newTestStore(t)
+	s := &flakySink{}
+	d := NewDispatcher(store, map[string]sink.Sender{"s1": s}, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := store.InsertAlert(ctx, storageAlert("a1")); err != nil {
+		t.Fatalf("alert: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+	if err := d.Enqueue(ctx, "a1", "s1"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	d.Notify()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if due, _ := store.DueDeliveries(ctx, time.Now(), 10); len(due) == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if len(s.got) != 1 {
+		t.Fatalf("expected delivery from worker, got %d", len(s.got))
+	}
+}
+
+func storageAlert(id string) storage.Alert {
+	return storage.Alert{ID: id, RuleID: "r1", PayloadJSON: `{"RuleID":"r1","Chain":"evm"}`}
+}
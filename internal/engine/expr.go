@@ -0,0 +1,1139 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ExprError is a where expression that does not compile, with the offset in
+// the expression where the problem was found.
+type ExprError struct {
+	Expr string
+	Pos  int
+	Msg  string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("%s: %s at column %d", e.Expr, e.Msg, e.Pos+1)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+type token struct {
+	kind     tokenKind
+	text     string
+	pos, end int
+	num      *big.Rat
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// keyword reports whether t is the word kw, ignoring case.
+func (t token) keyword(kw string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, kw)
+}
+
+func (t token) isKeyword() bool {
+	for _, kw := range []string{"and", "or", "not", "in", "contains", "iequals", "not_in", "not_contains"} {
+		if t.keyword(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+var punctuation = map[byte]tokenKind{'(': tokLParen, ')': tokRParen, '[': tokLBracket, ']': tokRBracket, ',': tokComma}
+
+// wordBreaks are the characters that end a bare word. Everything else,
+// including '-', '/', '.' and ':', can appear in one, so addresses, account
+// names and denoms need no quotes.
+const wordBreaks = "()[],\"'=!<>*&|"
+
+func tokenize(expr string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case punctuation[c] != tokEOF:
+			toks = append(toks, token{kind: punctuation[c], text: string(c), pos: i, end: i + 1})
+			i++
+			continue
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(expr) && expr[j] != c; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				sb.WriteByte(expr[j])
+			}
+			if j == len(expr) {
+				return nil, &ExprError{Expr: expr, Pos: i, Msg: "unterminated string"}
+			}
+			toks = append(toks, token{kind: tokString, text: sb.String(), pos: i, end: j + 1})
+			i = j + 1
+			continue
+		case strings.IndexByte(wordBreaks, c) >= 0:
+			op := string(c)
+			if i+1 < len(expr) {
+				switch two := expr[i : i+2]; two {
+				case "==", "!=", ">=", "<=", "&&", "||":
+					op = two
+				}
+			}
+			switch op {
+			case "=":
+				return nil, &ExprError{Expr: expr, Pos: i, Msg: `unexpected "=", use "==" to compare`}
+			case "&", "|":
+				return nil, &ExprError{Expr: expr, Pos: i, Msg: fmt.Sprintf("unexpected %q", op)}
+			}
+			toks = append(toks, token{kind: tokOp, text: op, pos: i, end: i + len(op)})
+			i += len(op)
+			continue
+		}
+		j := i
+		for j < len(expr) && !strings.ContainsRune(" \t\n\r"+wordBreaks, rune(expr[j])) {
+			j++
+		}
+		if valuePosition(toks) {
+			j = base64Padding(expr, j)
+		}
+		tok := token{kind: tokWord, text: expr[i:j], pos: i, end: j}
+		if n, ok := numberLiteral(tok.text); ok {
+			tok.kind, tok.num = tokNumber, n
+		}
+		toks = append(toks, tok)
+		i = j
+	}
+	return append(toks, token{kind: tokEOF, pos: len(expr), end: len(expr)}), nil
+}
+
+// valuePosition reports whether a word after toks is a value rather than a
+// field: it follows a comparison, or is in a list.
+func valuePosition(toks []token) bool {
+	if len(toks) == 0 {
+		return false
+	}
+	switch prev := toks[len(toks)-1]; prev.kind {
+	case tokComma, tokLBracket:
+		return true
+	case tokOp:
+		return prev.text == "==" || prev.text == "!="
+	case tokWord:
+		return prev.isKeyword() && !prev.keyword("and") && !prev.keyword("or") && !prev.keyword("not")
+	}
+	return false
+}
+
+// base64Padding extends a word ending at j over the '=' padding of a base64
+// value, as in "data == eA==", so such values need no quotes.
+func base64Padding(expr string, j int) int {
+	k := j
+	for k < len(expr) && k-j < 2 && expr[k] == '=' {
+		k++
+	}
+	if k > j && (k == len(expr) || strings.IndexByte(" \t\n\r),]", expr[k]) >= 0) {
+		return k
+	}
+	return j
+}
+
+// numberLiteral parses a number as written in an expression: digits with
+// optional '_' separators, a fraction and an exponent, such as 1_000 or 1e6.
+// The value is exact, however many digits it has. Hex is left alone so 0x
+// addresses stay strings.
+func numberLiteral(s string) (*big.Rat, bool) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "_", "")
+	digits := strings.TrimLeft(s, "+-")
+	if digits == "" || !(digits[0] >= '0' && digits[0] <= '9' || digits[0] == '.') {
+		return nil, false
+	}
+	if len(digits) > 1 && digits[0] == '0' && (digits[1] == 'x' || digits[1] == 'X') {
+		return nil, false
+	}
+	// ParseFloat settles the syntax; big.Rat would also take fractions like
+	// 1/2, which are denoms and paths here.
+	if _, err := strconv.ParseFloat(s, 64); err != nil && !errors.Is(err, strconv.ErrRange) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// valueType is what a node is known to produce when the expression compiles.
+// Args are only known when an event arrives, so fields are typeAny.
+type valueType int
+
+const (
+	typeAny valueType = iota
+	typeBool
+	typeNumber
+	typeString
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeBool:
+		return "condition"
+	case typeNumber:
+		return "number"
+	case typeString:
+		return "string"
+	}
+	return "value"
+}
+
+// node is a compiled expression. eval reports ok=false when a field it reads
+// is missing from args; conditions turn that into a non-match.
+type node interface {
+	typ() valueType
+	eval(args map[string]any) (v any, ok bool, err error)
+}
+
+type fieldNode struct{ name string }
+
+func (n fieldNode) typ() valueType { return typeAny }
+func (n fieldNode) eval(args map[string]any) (any, bool, error) {
+	v, ok := lookup(args, n.name)
+	return v, ok, nil
+}
+
+// lookup finds a dotted path in v. A key that contains the whole path, like
+// a group rule's tx0.amount, wins; otherwise the path walks into nested maps,
+// so details.to.address reads details, then to, then address.
+func lookup(v any, path string) (any, bool) {
+	if got, ok := member(v, path); ok {
+		return got, true
+	}
+	for i := strings.LastIndexByte(path, '.'); i > 0; i = strings.LastIndexByte(path[:i], '.') {
+		if inner, ok := member(v, path[:i]); ok {
+			if got, ok := lookup(inner, path[i+1:]); ok {
+				return got, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// member returns the value under key in a map with string keys.
+func member(v any, key string) (any, bool) {
+	if m, ok := v.(map[string]any); ok {
+		got, ok := m[key]
+		return got, ok
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	got := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+	if !got.IsValid() {
+		return nil, false
+	}
+	return got.Interface(), true
+}
+
+// indexNode reads x[i] from a list, counting from the end when i is
+// negative, or x["key"] from a map. An index past the end, like a missing
+// key, is a missing field.
+type indexNode struct {
+	x     node
+	index int
+	key   *string
+	path  string // a dotted path read after the index, as in x[0].to
+	text  string
+}
+
+func (n indexNode) typ() valueType { return typeAny }
+func (n indexNode) eval(args map[string]any) (any, bool, error) {
+	v, ok, err := n.x.eval(args)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	if n.key != nil {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map {
+			return nil, false, fmt.Errorf("%s: %v (%T) is not a map", n.text, v, v)
+		}
+		v, ok = member(v, *n.key)
+	} else {
+		rv := reflect.ValueOf(v)
+		if k := rv.Kind(); k != reflect.Slice && k != reflect.Array {
+			return nil, false, fmt.Errorf("%s: %v (%T) is not a list", n.text, v, v)
+		}
+		i := n.index
+		if i < 0 {
+			i += rv.Len()
+		}
+		if i < 0 || i >= rv.Len() {
+			return nil, false, nil
+		}
+		v, ok = rv.Index(i).Interface(), true
+	}
+	if !ok || n.path == "" {
+		return v, ok, nil
+	}
+	v, ok = lookup(v, n.path)
+	return v, ok, nil
+}
+
+type literalNode struct {
+	v any // *big.Rat or string
+}
+
+func (n literalNode) typ() valueType {
+	if _, ok := n.v.(*big.Rat); ok {
+		return typeNumber
+	}
+	return typeString
+}
+func (n literalNode) eval(map[string]any) (any, bool, error) { return n.v, true, nil }
+
+type function struct {
+	args int
+	call func(args []*big.Rat) (*big.Rat, error)
+}
+
+// functions are the helpers available in expressions. They take and return
+// numbers. Unit helpers convert to the base unit args are in, so
+// value >= ether(5) compares against 5e18 wei.
+var functions = map[string]function{
+	// wei and microAlgos are already base units; they only document intent.
+	"wei":        {args: 1, call: scale(0)},
+	"microAlgos": {args: 1, call: scale(0)},
+	"gwei":       {args: 1, call: scale(9)},
+	"ether":      {args: 1, call: scale(18)},
+	"algos":      {args: 1, call: scale(6)},
+	// token(amount, decimals) scales by a token's own decimals, as in
+	// token(100, 6) for 100 USDC.
+	"token": {args: 2, call: func(a []*big.Rat) (*big.Rat, error) {
+		d := a[1]
+		if !d.IsInt() || d.Sign() < 0 || d.Num().Cmp(big.NewInt(77)) > 0 {
+			return nil, fmt.Errorf("decimals must be a whole number from 0 to 77, got %s", d.RatString())
+		}
+		return scale(int(d.Num().Int64()))(a[:1])
+	}},
+}
+
+// scale returns a helper that multiplies its argument by 10^decimals.
+func scale(decimals int) func([]*big.Rat) (*big.Rat, error) {
+	unit := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	return func(a []*big.Rat) (*big.Rat, error) {
+		return new(big.Rat).Mul(a[0], unit), nil
+	}
+}
+
+type callNode struct {
+	fn   function
+	args []node
+	text string
+}
+
+func (n callNode) typ() valueType { return typeNumber }
+func (n callNode) eval(args map[string]any) (any, bool, error) {
+	vals := make([]*big.Rat, len(n.args))
+	for i, a := range n.args {
+		v, ok, err := a.eval(args)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		f, ok := toNumber(v)
+		if !ok {
+			return nil, false, fmt.Errorf("%s: argument is %v (%T), not a number", n.text, v, v)
+		}
+		vals[i] = f
+	}
+	v, err := n.fn.call(vals)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", n.text, err)
+	}
+	return v, true, nil
+}
+
+// lenNode is len(x): the number of elements of a list or map arg, or the
+// number of characters of text.
+type lenNode struct {
+	x    node
+	text string
+}
+
+func (n lenNode) typ() valueType { return typeNumber }
+func (n lenNode) eval(args map[string]any) (any, bool, error) {
+	v, ok, err := n.x.eval(args)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	if s, ok := v.(string); ok {
+		return big.NewRat(int64(utf8.RuneCountInString(s)), 1), true, nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return big.NewRat(int64(rv.Len()), 1), true, nil
+	}
+	return nil, false, fmt.Errorf("%s: %v (%T) has no length", n.text, v, v)
+}
+
+// quantifierNode is any(list, cond) or all(list, cond). cond sees the args
+// with x set to each element in turn.
+type quantifierNode struct {
+	all        bool
+	list, cond node
+	text       string
+}
+
+func (n quantifierNode) typ() valueType { return typeBool }
+func (n quantifierNode) eval(args map[string]any) (any, bool, error) {
+	v, ok, err := n.list.eval(args)
+	if err != nil || !ok {
+		return false, true, err
+	}
+	rv := reflect.ValueOf(v)
+	if k := rv.Kind(); k != reflect.Slice && k != reflect.Array {
+		return false, true, fmt.Errorf("%s: %v (%T) is not a list", n.text, v, v)
+	}
+	scope := make(map[string]any, len(args)+1)
+	for k, v := range args {
+		scope[k] = v
+	}
+	for i := 0; i < rv.Len(); i++ {
+		scope["x"] = rv.Index(i).Interface()
+		hit, _, err := n.cond.eval(scope)
+		if err != nil {
+			return false, true, err
+		}
+		if hit.(bool) != n.all {
+			return !n.all, true, nil
+		}
+	}
+	return n.all, true, nil
+}
+
+type mulNode struct {
+	l, r node
+	text string
+}
+
+func (n mulNode) typ() valueType { return typeNumber }
+func (n mulNode) eval(args map[string]any) (any, bool, error) {
+	out := big.NewRat(1, 1)
+	for _, side := range []node{n.l, n.r} {
+		v, ok, err := side.eval(args)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		f, ok := toNumber(v)
+		if !ok {
+			return nil, false, fmt.Errorf("%s: %v (%T) is not a number", n.text, v, v)
+		}
+		out.Mul(out, f)
+	}
+	return out, true, nil
+}
+
+// compareNode compares two values. If either side is a number the comparison
+// is numeric; otherwise == and != compare text, as args print, with hex such
+// as addresses compared regardless of case.
+type compareNode struct {
+	op   string
+	l, r node
+	lsrc string
+	rsrc string
+	text string
+}
+
+func (n compareNode) typ() valueType { return typeBool }
+func (n compareNode) eval(args map[string]any) (any, bool, error) {
+	lv, ok, err := n.l.eval(args)
+	if err != nil || !ok {
+		return false, true, err
+	}
+	rv, ok, err := n.r.eval(args)
+	if err != nil || !ok {
+		return false, true, err
+	}
+	if n.l.typ() == typeNumber || n.r.typ() == typeNumber {
+		lf, ok := toNumber(lv)
+		if !ok {
+			return false, true, fmt.Errorf("%s: %s is %v (%T), not a number", n.text, n.lsrc, lv, lv)
+		}
+		rf, ok := toNumber(rv)
+		if !ok {
+			return false, true, fmt.Errorf("%s: %s is %v (%T), not a number", n.text, n.rsrc, rv, rv)
+		}
+		c := lf.Cmp(rf)
+		switch n.op {
+		case "==":
+			return c == 0, true, nil
+		case "!=":
+			return c != 0, true, nil
+		case ">":
+			return c > 0, true, nil
+		case "<":
+			return c < 0, true, nil
+		case ">=":
+			return c >= 0, true, nil
+		default:
+			return c <= 0, true, nil
+		}
+	}
+	switch n.op {
+	case "==":
+		return textOf(lv) == textOf(rv), true, nil
+	case "!=":
+		return textOf(lv) != textOf(rv), true, nil
+	default:
+		return false, true, fmt.Errorf("%s: %s is not a number", n.text, n.rsrc)
+	}
+}
+
+type listItem struct {
+	text string
+	num  *big.Rat // nil unless text is a number
+}
+
+// inNode matches a value against a fixed list, by its text as == compares it
+// or, for numbers, by value.
+type inNode struct {
+	l      node
+	items  []listItem
+	negate bool
+}
+
+func (n inNode) typ() valueType { return typeBool }
+func (n inNode) eval(args map[string]any) (any, bool, error) {
+	v, ok, err := n.l.eval(args)
+	if err != nil || !ok {
+		return false, true, err
+	}
+	s := textOf(v)
+	f, isNum := toNumber(v)
+	for _, it := range n.items {
+		if it.text == s || isNum && it.num != nil && it.num.Cmp(f) == 0 {
+			return !n.negate, true, nil
+		}
+	}
+	return n.negate, true, nil
+}
+
+// textNode applies contains or iequals to the printed values. Like not_in,
+// not_contains needs the field to be present to match.
+type textNode struct {
+	op     string
+	l, r   node
+	negate bool
+}
+
+func (n textNode) typ() valueType { return typeBool }
+func (n textNode) eval(args map[string]any) (any, bool, error) {
+	lv, ok, err := n.l.eval(args)
+	if err != nil || !ok {
+		return false, true, err
+	}
+	rv, ok, err := n.r.eval(args)
+	if err != nil || !ok {
+		return false, true, err
+	}
+	if n.op == "iequals" {
+		return strings.EqualFold(fmt.Sprint(lv), fmt.Sprint(rv)), true, nil
+	}
+	return strings.Contains(fmt.Sprint(lv), fmt.Sprint(rv)) != n.negate, true, nil
+}
+
+// textOf prints a value for comparison. 0x hex is lowercased, so a
+// checksummed address from a decoder equals the same address typed in
+// lowercase, and the other way around.
+func textOf(v any) string {
+	s := fmt.Sprint(v)
+	if isHex(s) {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+func isHex(s string) bool {
+	if len(s) < 3 || s[0] != '0' || s[1] != 'x' && s[1] != 'X' {
+		return false
+	}
+	for _, c := range s[2:] {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// truthNode is a field used on its own as a condition, such as "flagged".
+type truthNode struct {
+	x    node
+	text string
+}
+
+func (n truthNode) typ() valueType { return typeBool }
+func (n truthNode) eval(args map[string]any) (any, bool, error) {
+	v, ok, err := n.x.eval(args)
+	if err != nil || !ok {
+		return false, true, err
+	}
+	switch b := v.(type) {
+	case bool:
+		return b, true, nil
+	case string:
+		if parsed, err := strconv.ParseBool(b); err == nil {
+			return parsed, true, nil
+		}
+	}
+	return false, true, fmt.Errorf("%s is %v (%T), not a boolean", n.text, v, v)
+}
+
+type notNode struct{ x node }
+
+func (n notNode) typ() valueType { return typeBool }
+func (n notNode) eval(args map[string]any) (any, bool, error) {
+	v, _, err := n.x.eval(args)
+	if err != nil {
+		return false, true, err
+	}
+	return !v.(bool), true, nil
+}
+
+// logicNode is and or or over conditions, evaluated left to right and
+// stopping once the result is known.
+type logicNode struct {
+	or   bool
+	l, r node
+}
+
+func (n logicNode) typ() valueType { return typeBool }
+func (n logicNode) eval(args map[string]any) (any, bool, error) {
+	v, _, err := n.l.eval(args)
+	if err != nil {
+		return false, true, err
+	}
+	if v.(bool) == n.or {
+		return n.or, true, nil
+	}
+	v, _, err = n.r.eval(args)
+	if err != nil {
+		return false, true, err
+	}
+	return v.(bool), true, nil
+}
+
+// parser is a recursive descent parser over the grammar, loosest first:
+//
+//	or      = and { ("or" | "||") and }
+//	and     = not { ("and" | "&&") not }
+//	not     = ("not" | "!") not | compare
+//	compare = product [ op rhs | ["not"] "in" list | ["not"] "contains" rhs | "iequals" rhs ]
+//	product = operand { "*" operand }
+//	operand = number | string | field | func "(" args ")" | "(" or ")"
+//
+// A bare word is a field on the left of a comparison and a value on the right,
+// so "status == ok" compares the status arg to the text ok.
+type parser struct {
+	expr string
+	toks []token
+	i    int
+}
+
+func parseExpr(expr string) (node, error) {
+	toks, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{expr: expr, toks: toks}
+	n, err := p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &ExprError{Expr: p.expr, Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCondition parses an expression that must produce a condition.
+func (p *parser) parseCondition() (node, error) {
+	start := p.peek()
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	return p.condition(n, start)
+}
+
+// condition checks that n is a condition, turning a bare field into a test
+// of its truth.
+func (p *parser) condition(n node, at token) (node, error) {
+	if n.typ() == typeAny {
+		return truthNode{x: n, text: p.span(at)}, nil
+	}
+	if n.typ() != typeBool {
+		return nil, p.errorf(at, "expected a condition, got a %s", n.typ())
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogic(true)
+}
+
+func (p *parser) parseLogic(or bool) (node, error) {
+	kw, op, sub := "or", "||", func() (node, error) { return p.parseLogic(false) }
+	if !or {
+		kw, op, sub = "and", "&&", p.parseNot
+	}
+	start := p.peek()
+	n, err := sub()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword(kw) || p.isOp(op) {
+		if n, err = p.condition(n, start); err != nil {
+			return nil, err
+		}
+		p.next()
+		start = p.peek()
+		r, err := sub()
+		if err != nil {
+			return nil, err
+		}
+		if r, err = p.condition(r, start); err != nil {
+			return nil, err
+		}
+		n = logicNode{or: or, l: n, r: r}
+	}
+	return n, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if t := p.peek(); t.keyword("not") || p.isOp("!") {
+		p.next()
+		start := p.peek()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if x, err = p.condition(x, start); err != nil {
+			return nil, err
+		}
+		return notNode{x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	start := p.peek()
+	l, err := p.parseProduct(false)
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case p.isOp("==", "!=", ">", "<", ">=", "<="):
+		lsrc := p.span(start)
+		p.next()
+		if err := p.comparable(l, start); err != nil {
+			return nil, err
+		}
+		rstart := p.peek()
+		r, err := p.parseProduct(true)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.comparable(r, rstart); err != nil {
+			return nil, err
+		}
+		if t.text != "==" && t.text != "!=" && (l.typ() == typeNumber && r.typ() == typeString || l.typ() == typeString && r.typ() == typeNumber) {
+			return nil, p.errorf(t, "cannot order a %s against a %s", l.typ(), r.typ())
+		}
+		return compareNode{op: t.text, l: l, r: r, lsrc: lsrc, rsrc: p.span(rstart), text: p.span(start)}, nil
+	}
+	// not_in and not_contains, also written "not in" and "not contains", are
+	// not the same as not around in: a missing field does not match either.
+	if t.kind != tokWord {
+		return l, nil
+	}
+	op, negate := strings.ToLower(t.text), false
+	switch next := p.toks[p.i+1]; {
+	case op == "not_in" || op == "not_contains":
+		op, negate = strings.TrimPrefix(op, "not_"), true
+	case op == "not" && (next.keyword("in") || next.keyword("contains")):
+		p.next()
+		op, negate = strings.ToLower(next.text), true
+	}
+	switch op {
+	case "in":
+		p.next()
+		if err := p.comparable(l, start); err != nil {
+			return nil, err
+		}
+		items, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			items[i].text = textOf(items[i].text)
+		}
+		return inNode{l: l, items: items, negate: negate}, nil
+	case "contains", "iequals":
+		p.next()
+		if err := p.comparable(l, start); err != nil {
+			return nil, err
+		}
+		rstart := p.peek()
+		r, err := p.parseProduct(true)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.comparable(r, rstart); err != nil {
+			return nil, err
+		}
+		if lit, ok := r.(literalNode); ok && lit.typ() == typeNumber {
+			// Search for the digits as written, not the parsed value.
+			r = literalNode{v: p.span(rstart)}
+		}
+		return textNode{op: op, l: l, r: r, negate: negate}, nil
+	}
+	return l, nil
+}
+
+func (p *parser) comparable(n node, at token) error {
+	if n.typ() == typeBool {
+		return p.errorf(at, "cannot compare a condition")
+	}
+	return nil
+}
+
+// span returns the expression text from the start of from to the end of the
+// last token read.
+func (p *parser) span(from token) string {
+	return p.expr[from.pos:p.toks[p.i-1].end]
+}
+
+func (p *parser) parseProduct(rhs bool) (node, error) {
+	start := p.peek()
+	n, err := p.parseOperand(rhs)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") {
+		p.next()
+		if err := p.numeric(n, start); err != nil {
+			return nil, err
+		}
+		rstart := p.peek()
+		r, err := p.parseOperand(rhs)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.numeric(r, rstart); err != nil {
+			return nil, err
+		}
+		n = mulNode{l: n, r: r, text: p.span(start)}
+	}
+	return n, nil
+}
+
+// numeric checks that n can be a number.
+func (p *parser) numeric(n node, at token) error {
+	if t := n.typ(); t != typeNumber && t != typeAny {
+		return p.errorf(at, "expected a number, got a %s", t)
+	}
+	return nil
+}
+
+func (p *parser) parseOperand(rhs bool) (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokLParen:
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tokRParen {
+			return nil, p.errorf(c, "expected \")\", got %s", c)
+		}
+		return n, nil
+	case tokString:
+		p.next()
+		return literalNode{v: t.text}, nil
+	case tokNumber, tokWord:
+		if t.isKeyword() {
+			break
+		}
+		if p.toks[p.i+1].kind == tokLParen {
+			return p.parseCall()
+		}
+		if !rhs {
+			p.next()
+			if t.kind == tokNumber {
+				return literalNode{v: t.num}, nil
+			}
+			return p.parseIndexes(fieldNode{name: t.text}, t)
+		}
+		text, num := p.parseWords()
+		if num != nil {
+			return literalNode{v: num}, nil
+		}
+		return literalNode{v: text}, nil
+	}
+	return nil, p.errorf(t, "expected a value, got %s", t)
+}
+
+// parseIndexes reads any [index] or ["key"] after a field, each optionally
+// followed by a dotted path, as in transfers[0].to.address.
+func (p *parser) parseIndexes(n node, start token) (node, error) {
+	for p.peek().kind == tokLBracket && p.peek().pos == p.toks[p.i-1].end {
+		p.next()
+		idx := indexNode{x: n}
+		switch t := p.next(); {
+		case t.kind == tokString:
+			key := t.text
+			idx.key = &key
+		case t.kind == tokNumber && t.num.IsInt() && t.num.Num().IsInt64():
+			idx.index = int(t.num.Num().Int64())
+		default:
+			return nil, p.errorf(t, "expected a list index or quoted key, got %s", t)
+		}
+		if _, err := p.expect(tokRBracket, `"]"`); err != nil {
+			return nil, err
+		}
+		if t := p.peek(); t.kind == tokWord && strings.HasPrefix(t.text, ".") && t.pos == p.toks[p.i-1].end {
+			p.next()
+			idx.path = t.text[1:]
+		}
+		idx.text = p.span(start)
+		n = idx
+	}
+	return n, nil
+}
+
+// parseWords reads a run of bare words as one value, so unquoted text with
+// spaces, as in "memo contains big alert", keeps working. It stops at
+// keywords and symbols.
+func (p *parser) parseWords() (string, *big.Rat) {
+	first := p.next()
+	last := first
+	for t := p.peek(); (t.kind == tokWord || t.kind == tokNumber) && !t.isKeyword(); t = p.peek() {
+		last = p.next()
+	}
+	if first == last && first.kind == tokNumber {
+		return first.text, first.num
+	}
+	return p.expr[first.pos:last.end], nil
+}
+
+func (p *parser) parseCall() (node, error) {
+	name := p.next()
+	p.next() // (
+	switch name.text {
+	case "env":
+		v, err := p.parseEnv(name)
+		if err != nil {
+			return nil, err
+		}
+		return literalNode{v: v}, nil
+	case "len":
+		start := p.peek()
+		x, err := p.parseProduct(false)
+		if err != nil {
+			return nil, err
+		}
+		if x.typ() != typeAny && x.typ() != typeString {
+			return nil, p.errorf(start, "len takes a list or text, got a %s", x.typ())
+		}
+		end, err := p.expect(tokRParen, `")"`)
+		if err != nil {
+			return nil, err
+		}
+		return lenNode{x: x, text: p.expr[name.pos:end.end]}, nil
+	case "any", "all":
+		start := p.peek()
+		list, err := p.parseProduct(false)
+		if err != nil {
+			return nil, err
+		}
+		if list.typ() != typeAny {
+			return nil, p.errorf(start, "%s takes a list, got a %s", name.text, list.typ())
+		}
+		if _, err := p.expect(tokComma, `","`); err != nil {
+			return nil, err
+		}
+		cond, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		end, err := p.expect(tokRParen, `")"`)
+		if err != nil {
+			return nil, err
+		}
+		return quantifierNode{all: name.text == "all", list: list, cond: cond, text: p.expr[name.pos:end.end]}, nil
+	}
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %s", name.text)
+	}
+	var args []node
+	for p.peek().kind != tokRParen {
+		if len(args) > 0 {
+			if c := p.next(); c.kind != tokComma {
+				return nil, p.errorf(c, "expected \",\" or \")\", got %s", c)
+			}
+		}
+		start := p.peek()
+		a, err := p.parseProduct(false)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.numeric(a, start); err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	end := p.next()
+	if len(args) != fn.args {
+		return nil, p.errorf(name, "%s takes %d argument(s), got %d", name.text, fn.args, len(args))
+	}
+	call := callNode{fn: fn, args: args, text: p.expr[name.pos:end.end]}
+	for _, a := range args {
+		if _, ok := a.(literalNode); !ok {
+			return call, nil
+		}
+	}
+	// Every argument is known, so work the value out now and report a bad
+	// argument when the config loads.
+	v, _, err := call.eval(nil)
+	if err != nil {
+		return nil, p.errorf(name, "%v", errors.Unwrap(err))
+	}
+	return literalNode{v: v}, nil
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.errorf(t, "expected %s, got %s", what, t)
+	}
+	return t, nil
+}
+
+// parseEnv reads env(NAME), which is replaced by the environment variable
+// when the expression compiles.
+func (p *parser) parseEnv(name token) (string, error) {
+	v := p.next()
+	if v.kind != tokWord && v.kind != tokString {
+		return "", p.errorf(v, "env takes a variable name, got %s", v)
+	}
+	if c := p.next(); c.kind != tokRParen {
+		return "", p.errorf(c, "expected \")\", got %s", c)
+	}
+	return os.Getenv(v.text), nil
+}
+
+// parseList reads the values after in: a bracketed list or, as before
+// brackets were supported, bare comma-separated values. env(NAME) expands
+// to the comma-separated values of the variable.
+func (p *parser) parseList() ([]listItem, error) {
+	bracketed := p.peek().kind == tokLBracket
+	if bracketed {
+		p.next()
+	}
+	var items []listItem
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokComma:
+			p.next()
+			continue
+		case bracketed && t.kind == tokRBracket:
+			p.next()
+			return items, nil
+		case t.kind == tokString:
+			p.next()
+			items = append(items, listItem{text: t.text})
+		case t.kind == tokWord && t.text == "env" && p.toks[p.i+1].kind == tokLParen:
+			p.next()
+			p.next()
+			v, err := p.parseEnv(t)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					items = append(items, listItem{text: s})
+				}
+			}
+		case (t.kind == tokWord || t.kind == tokNumber) && !t.isKeyword():
+			text, num := p.parseWords()
+			items = append(items, listItem{text: text, num: num})
+		default:
+			if bracketed {
+				return nil, p.errorf(t, "expected a value or \"]\", got %s", t)
+			}
+			if len(items) == 0 {
+				return nil, p.errorf(t, "expected a value, got %s", t)
+			}
+			return items, nil
+		}
+		if c := p.peek(); c.kind != tokComma && !(bracketed && c.kind == tokRBracket) {
+			if bracketed {
+				return nil, p.errorf(c, "expected \",\" or \"]\", got %s", c)
+			}
+			return items, nil
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/source/algorand"
+)
+
+// HeartbeatRuleID is the rule id carried by heartbeat messages.
+const HeartbeatRuleID = "heartbeat"
+
+// Heartbeat sends sinkID a liveness message: how many alerts were recorded
+// since the given time and how far each source is behind its chain tip.
+// Heartbeats are sent directly rather than recorded as alerts, so a failed
+// one is not retried and does not show up in alert history.
+func (r *Runner) Heartbeat(ctx context.Context, sinkID string, since time.Time) error {
+	s := r.sinks[sinkID]
+	if s == nil {
+		return fmt.Errorf("unknown sink %q", sinkID)
+	}
+	hits, err := r.store.ListRuleHits(ctx, since)
+	if err != nil {
+		return err
+	}
+	alerts := 0
+	for _, h := range hits {
+		alerts += h.Count
+	}
+
+	lag := map[string]uint64{}
+	var parts []string
+	for _, src := range r.Sources() {
+		// The tip is unknown until a source's first poll.
+		if src.Tip == 0 {
+			continue
+		}
+		height, _, ok, err := r.store.GetCursor(ctx, src.ID)
+		if err != nil {
+			return err
+		}
+		behind := src.Tip
+		if ok {
+			behind = 0
+			if src.Tip > height {
+				behind = src.Tip - height
+			}
+		}
+		lag[src.ID] = behind
+		unit := "block"
+		if src.Chain == algorand.Chain {
+			unit = "round"
+		}
+		if behind != 1 {
+			unit += "s"
+		}
+		parts = append(parts, fmt.Sprintf("%s %d %s", src.ID, behind, unit))
+	}
+	summary := fmt.Sprintf("watch-tower alive, %d alerts", alerts)
+	if len(parts) > 0 {
+		summary += ", lag: " + strings.Join(parts, " / ")
+	}
+
+	alertID := newAlertID()
+	payload := sink.EventPayload{
+		RuleID:    HeartbeatRuleID,
+		Timestamp: r.nowFunc(),
+		AlertID:   alertID,
+		Args: map[string]any{
+			"alerts":  alerts,
+			"lag":     lag,
+			"since":   since,
+			"summary": summary,
+		},
+	}
+	log := r.log.With("alert_id", alertID, "rule", HeartbeatRuleID, "sink", sinkID)
+	if err := s.Send(ctx, payload); err != nil {
+		log.Warn("heartbeat failed", "error", err)
+		return err
+	}
+	log.Debug("heartbeat sent", "alerts", alerts)
+	return nil
+}
+
+// RunHeartbeat sends sinkID a heartbeat every interval until ctx is
+// cancelled, each counting the alerts since the one before. Failures are
+// passed to onErr (which may be nil).
+func (r *Runner) RunHeartbeat(ctx context.Context, sinkID string, every time.Duration, onErr func(error)) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	since := r.nowFunc()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := r.nowFunc()
+		if err := r.Heartbeat(ctx, sinkID, since); err != nil && onErr != nil && ctx.Err() == nil {
+			onErr(err)
+		}
+		since = now
+	}
+}
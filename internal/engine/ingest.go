@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/source/ingest"
+)
+
+// SetIngest matches the rules of ingest source sourceID against the events
+// s receives. Queued events are handled on each tick, and receiving events
+// triggers one.
+func (r *Runner) SetIngest(sourceID string, s *ingest.Source) {
+	s.SetNotify(r.Trigger)
+	r.ingests[sourceID] = s
+}
+
+// runIngests handles the events queued by each ingest source. Paused
+// sources keep theirs queued.
+func (r *Runner) runIngests(ctx context.Context) error {
+	for id, s := range r.ingests {
+		if r.isPaused(id) || r.backingOff(id) {
+			continue
+		}
+		err := r.pipeEvents(func(emit func(Event) error) error {
+			err := s.ProcessNextFunc(ctx, func(e source.Event) error {
+				return emit(event(e))
+			})
+			if err != nil {
+				return fmt.Errorf("ingest source %s: %w", id, err)
+			}
+			return nil
+		}, func(ev Event) error {
+			return r.handleEvent(ctx, ev)
+		})
+		if err := r.sourceDone(ctx, id, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultDedupeSweep is how often RunJanitor deletes expired dedupe keys.
+const DefaultDedupeSweep = 10 * time.Minute
+
+// SweepDedupe deletes dedupe keys that have expired and returns how many
+// were removed.
+func (r *Runner) SweepDedupe(ctx context.Context) (int64, error) {
+	return r.store.PruneDedupe(ctx, r.nowFunc())
+}
+
+// RunJanitor sweeps expired dedupe keys and audit records immediately and
+// then every interval until ctx is cancelled. Failures are passed to onErr
+// (which may be nil) and retried on the next sweep.
+func (r *Runner) RunJanitor(ctx context.Context, every time.Duration, onErr func(error)) {
+	if every <= 0 {
+		every = DefaultDedupeSweep
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if _, err := r.SweepDedupe(ctx); err != nil && onErr != nil && ctx.Err() == nil {
+			onErr(err)
+		}
+		if _, err := r.SweepAudit(ctx); err != nil && onErr != nil && ctx.Err() == nil {
+			onErr(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/source/evm"
+)
+
+// mempoolRetry is how long a failed mempool watcher waits before restarting.
+const mempoolRetry = 5 * time.Second
+
+// SetMempool matches the pending_tx rules of EVM source sourceID against
+// the mempool w reads. Watchers only run under RunMempools.
+func (r *Runner) SetMempool(sourceID string, w *evm.MempoolWatcher) {
+	r.mempools[sourceID] = w
+}
+
+// HasMempools reports whether any source watches its mempool.
+func (r *Runner) HasMempools() bool {
+	return len(r.mempools) > 0
+}
+
+// RunMempools runs every mempool watcher until ctx is cancelled, handling
+// their matches like mined events. A watcher that fails is restarted after
+// a pause; failures are passed to onErr (which may be nil).
+func (r *Runner) RunMempools(ctx context.Context, onErr func(error)) {
+	var wg sync.WaitGroup
+	for id, w := range r.mempools {
+		wg.Add(1)
+		go func(id string, w *evm.MempoolWatcher) {
+			defer wg.Done()
+			for {
+				err := w.Run(ctx, func(e source.Event) error {
+					return r.handlePending(ctx, e)
+				})
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil && onErr != nil {
+					onErr(fmt.Errorf("evm source %s mempool: %w", id, err))
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(mempoolRetry):
+				}
+			}
+		}(id, w)
+	}
+	wg.Wait()
+}
+
+// handlePending runs a pending transaction match through its rule. It waits
+// for any tick in progress, as rules may be swapped between ticks.
+func (r *Runner) handlePending(ctx context.Context, e source.Event) error {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+	if r.isPaused(e.SourceID) {
+		return nil
+	}
+	return r.handleEvent(ctx, Event{
+		RuleID:    e.RuleID,
+		Chain:     e.Chain,
+		SourceID:  e.SourceID,
+		TxHash:    e.TxHash,
+		Contract:  e.Contract,
+		Timestamp: e.Timestamp,
+		Args:      e.Args,
+	})
+}
@@ -1,22 +1,35 @@
 package engine
 
 import (
-	"fmt"
-	"strconv"
+	"encoding/json"
+	"math/big"
 	"strings"
 	"time"
 )
 
-// Predicate evaluates whether an event args map satisfies a condition.
+// Predicate evaluates whether an event args map satisfies a condition. A
+// missing field is a non-match; an error means the field is present but the
+// condition cannot be evaluated on it, such as a numeric comparison with a
+// value that is not a number.
 type Predicate func(args map[string]any) (bool, error)
 
-// CompilePredicates parses simple expressions into executable predicates.
-// Supported operators: ==, !=, >, <, in, contains.
+// CompilePredicates compiles where expressions into executable predicates.
+// Comparisons use ==, !=, >, <, >=, <=, in, not_in, contains, not_contains
+// and iequals, and combine with and, or and not (also &&, || and !), grouped
+// with parentheses. not binds tightest and or loosest. Numbers may use _
+// separators, exponents, * and the unit helpers wei(), gwei(), ether(),
+// microAlgos(), algos() and token(amount, decimals). len(list) counts the
+// elements of an array arg, and any(list, cond) and all(list, cond) test
+// cond against each element, bound to x. A bare word is a field on the left
+// of a comparison and a value on the right; quote values containing spaces
+// next to keywords or symbols. Mistakes such as unbalanced parentheses,
+// unknown functions or multiplying text are reported with their column.
 // Examples:
 //
 //	"value > 10"
 //	"sender in a,b,c"
 //	"memo contains alert"
+//	"(value > 10 and sender == 0xabc) or flagged == true"
 func CompilePredicates(exprs []string) ([]Predicate, error) {
 	var preds []Predicate
 	for _, raw := range exprs {
@@ -24,191 +37,62 @@ func CompilePredicates(exprs []string) ([]Predicate, error) {
 		if raw == "" {
 			continue
 		}
-		p, err := compile(raw)
+		n, err := parseExpr(raw)
 		if err != nil {
 			return nil, err
 		}
-		preds = append(preds, p)
-	}
-	return preds, nil
-}
-
-func compile(expr string) (Predicate, error) {
-	if strings.Contains(expr, " in ") {
-		parts := strings.SplitN(expr, " in ", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid in expression: %s", expr)
-		}
-		field := strings.TrimSpace(parts[0])
-		rawList := strings.Split(parts[1], ",")
-		values := make(map[string]struct{}, len(rawList))
-		for _, v := range rawList {
-			v = strings.TrimSpace(v)
-			if v == "" {
-				continue
-			}
-			values[v] = struct{}{}
-		}
-		return func(args map[string]any) (bool, error) {
-			arg, ok := args[field]
-			if !ok {
-				return false, nil
-			}
-			s := fmt.Sprint(arg)
-			_, hit := values[s]
-			return hit, nil
-		}, nil
-	}
-
-	if strings.Contains(expr, " contains ") {
-		parts := strings.SplitN(expr, " contains ", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid contains expression: %s", expr)
-		}
-		field := strings.TrimSpace(parts[0])
-		needle := strings.TrimSpace(parts[1])
-		return func(args map[string]any) (bool, error) {
-			val, ok := args[field]
-			if !ok {
-				return false, nil
-			}
-			return strings.Contains(fmt.Sprint(val), needle), nil
-		}, nil
-	}
-
-	var op string
-	switch {
-	case strings.Contains(expr, "=="):
-		op = "=="
-	case strings.Contains(expr, "!="):
-		op = "!="
-	case strings.Contains(expr, ">="):
-		op = ">="
-	case strings.Contains(expr, "<="):
-		op = "<="
-	case strings.Contains(expr, ">"):
-		op = ">"
-	case strings.Contains(expr, "<"):
-		op = "<"
-	default:
-		return nil, fmt.Errorf("unsupported expression: %s", expr)
-	}
-
-	parts := strings.SplitN(expr, op, 2)
-	if len(parts) != 2 {
-		return nil, fmt.Errorf("invalid expression: %s", expr)
-	}
-	field := strings.TrimSpace(parts[0])
-	rhsRaw := strings.TrimSpace(parts[1])
-
-	numRHS, rhsIsNum := evaluateNumber(rhsRaw)
-
-	return func(args map[string]any) (bool, error) {
-		val, ok := args[field]
-		if !ok {
-			return false, nil
-		}
-
-		if rhsIsNum {
-			lhs, ok := toNumber(val)
-			if !ok {
-				return false, nil
-			}
-			switch op {
-			case "==":
-				return lhs == numRHS, nil
-			case "!=":
-				return lhs != numRHS, nil
-			case ">":
-				return lhs > numRHS, nil
-			case "<":
-				return lhs < numRHS, nil
-			case ">=":
-				return lhs >= numRHS, nil
-			case "<=":
-				return lhs <= numRHS, nil
+		preds = append(preds, func(args map[string]any) (bool, error) {
+			v, _, err := n.eval(args)
+			if err != nil {
+				return false, err
 			}
-		}
-
-		// String comparisons
-		lhs := fmt.Sprint(val)
-		switch op {
-		case "==":
-			return lhs == rhsRaw, nil
-		case "!=":
-			return lhs != rhsRaw, nil
-		default:
-			return false, nil
-		}
-	}, nil
-}
-
-// evaluateNumber evaluates a numeric expression, supporting:
-// - Simple numbers: "100", "1e6", "1_000_000"
-// - Helper functions: "wei(1e18)", "microAlgos(1e6)"
-// - Multiplication: "1_000_000 * 1e6"
-func evaluateNumber(s string) (float64, bool) {
-	s = strings.TrimSpace(s)
-	s = strings.ReplaceAll(s, "_", "")
-
-	// Handle multiplication
-	if strings.Contains(s, "*") {
-		parts := strings.Split(s, "*")
-		if len(parts) != 2 {
-			return 0, false
-		}
-		a, ok1 := evaluateNumber(strings.TrimSpace(parts[0]))
-		b, ok2 := evaluateNumber(strings.TrimSpace(parts[1]))
-		if !ok1 || !ok2 {
-			return 0, false
-		}
-		return a * b, true
+			return v.(bool), nil
+		})
 	}
-
-	// Check for helper functions: wei(value) or microAlgos(value)
-	if strings.HasPrefix(s, "wei(") && strings.HasSuffix(s, ")") {
-		inner := strings.TrimSpace(s[4 : len(s)-1])
-		v, ok := evaluateNumber(inner)
-		if !ok {
-			return 0, false
-		}
-		return v, true // wei is already the base unit, no conversion needed
-	}
-	if strings.HasPrefix(s, "microAlgos(") && strings.HasSuffix(s, ")") {
-		inner := strings.TrimSpace(s[11 : len(s)-1])
-		v, ok := evaluateNumber(inner)
-		if !ok {
-			return 0, false
-		}
-		return v, true // microAlgos is already the base unit, no conversion needed
-	}
-
-	// Parse as a simple number
-	v, err := strconv.ParseFloat(s, 64)
-	return v, err == nil
-}
-
-// parseNumber is a simple wrapper for backward compatibility.
-func parseNumber(s string) (float64, bool) {
-	return evaluateNumber(s)
+	return preds, nil
 }
 
-func toNumber(v any) (float64, bool) {
+// toNumber converts an arg to an exact number. Integers of any size, such as
+// uint256 amounts, keep every digit.
+func toNumber(v any) (*big.Rat, bool) {
 	switch n := v.(type) {
 	case int:
-		return float64(n), true
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int32:
+		return new(big.Rat).SetInt64(int64(n)), true
 	case int64:
-		return float64(n), true
+		return new(big.Rat).SetInt64(n), true
+	case uint:
+		return new(big.Rat).SetUint64(uint64(n)), true
+	case uint32:
+		return new(big.Rat).SetUint64(uint64(n)), true
 	case uint64:
-		return float64(n), true
+		return new(big.Rat).SetUint64(n), true
 	case float64:
-		return n, true
+		r := new(big.Rat).SetFloat64(n)
+		return r, r != nil
 	case float32:
-		return float64(n), true
+		r := new(big.Rat).SetFloat64(float64(n))
+		return r, r != nil
 	case string:
-		return parseNumber(n)
+		return numberLiteral(n)
+	case json.Number:
+		return numberLiteral(string(n))
+	case *big.Int:
+		if n == nil {
+			return nil, false
+		}
+		return new(big.Rat).SetInt(n), true
+	case *big.Rat:
+		return n, n != nil
+	case *big.Float:
+		if n == nil || n.IsInf() {
+			return nil, false
+		}
+		r, _ := n.Rat(nil)
+		return r, true
 	default:
-		return 0, false
+		return nil, false
 	}
 }
 
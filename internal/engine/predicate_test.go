@@ -1,6 +1,10 @@
 package engine
 
 import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
 	"testing"
 	"time"
 )
@@ -107,6 +111,7 @@ func TestCompilePredicates_TableDriven(t *testing.T) {
 		{"uint64_value", "value > 10", map[string]any{"value": uint64(15)}, true, false},
 		{"float64_value", "value > 10", map[string]any{"value": 15.5}, true, false},
 		{"string_number", "value > 10", map[string]any{"value": "15"}, true, false},
+		{"big_int_value", "value > 10", map[string]any{"value": big.NewInt(15)}, true, false},
 
 		// Missing fields
 		{"missing_field_numeric", "value > 10", map[string]any{"other": 15}, false, false},
@@ -143,6 +148,481 @@ func TestCompilePredicates_TableDriven(t *testing.T) {
 	}
 }
 
+func TestCompilePredicates_EvalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		args map[string]any
+	}{
+		{"non_numeric_field", "value > 10", map[string]any{"value": "lots"}},
+		{"bool_field", "value == 10", map[string]any{"value": true}},
+		{"ordering_on_string", "status > ok", map[string]any{"status": "ok"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preds, err := CompilePredicates([]string{tt.expr})
+			if err != nil {
+				t.Fatalf("unexpected compile error: %v", err)
+			}
+			ok, err := preds[0](tt.args)
+			if err == nil || ok {
+				t.Fatalf("expected eval error, got %v", ok)
+			}
+			if !strings.Contains(err.Error(), tt.expr) {
+				t.Fatalf("error %q does not name the expression", err)
+			}
+		})
+	}
+}
+
+func TestCompilePredicates_BooleanGrouping(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		args map[string]any
+		want bool
+	}{
+		{"and_pass", "value > 10 and sender == 0xabc", map[string]any{"value": 15, "sender": "0xabc"}, true},
+		{"and_fail", "value > 10 and sender == 0xabc", map[string]any{"value": 15, "sender": "0xdef"}, false},
+		{"or_left", "value > 10 or flagged == true", map[string]any{"value": 15, "flagged": false}, true},
+		{"or_right", "value > 10 or flagged == true", map[string]any{"value": 5, "flagged": true}, true},
+		{"or_neither", "value > 10 or flagged == true", map[string]any{"value": 5, "flagged": false}, false},
+		{"grouped_and", "(value > 10 and sender == 0xabc) or flagged == true", map[string]any{"value": 15, "sender": "0xabc"}, true},
+		{"grouped_or", "(value > 10 and sender == 0xabc) or flagged == true", map[string]any{"value": 1, "flagged": true}, true},
+		{"grouped_fail", "(value > 10 and sender == 0xabc) or flagged == true", map[string]any{"value": 15, "sender": "0xdef"}, false},
+		{"and_binds_tighter", "flagged == true or value > 10 and value < 20", map[string]any{"value": 25, "flagged": true}, true},
+		{"parens_override", "(flagged == true or value > 10) and value < 20", map[string]any{"value": 25, "flagged": true}, false},
+		{"not", "not status == ok", map[string]any{"status": "fail"}, true},
+		{"not_group", "not (status == ok or status == pending)", map[string]any{"status": "ok"}, false},
+		{"not_missing_field", "not flagged == true", map[string]any{}, true},
+		{"uppercase_keywords", "value > 10 AND NOT status == ok", map[string]any{"value": 15, "status": "fail"}, true},
+		{"helper_parens", "value >= wei(1000) or (memo contains alert)", map[string]any{"value": 10, "memo": "alert"}, true},
+		{"in_list", "sender in a,b,c or value > 10", map[string]any{"sender": "b"}, true},
+		{"field_named_note", "note == x and value > 1", map[string]any{"note": "x", "value": 2}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preds, err := CompilePredicates([]string{tt.expr})
+			if err != nil {
+				t.Fatalf("unexpected compile error: %v", err)
+			}
+			got, err := preds[0](tt.args)
+			if err != nil {
+				t.Fatalf("unexpected eval error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("predicate(%q) with args %v = %v, want %v", tt.expr, tt.args, got, tt.want)
+			}
+		})
+	}
+
+	for _, expr := range []string{
+		"(value > 10",
+		"value > 10)",
+		"value > 10 and",
+		"or value > 10",
+		"not",
+		"()",
+	} {
+		if _, err := CompilePredicates([]string{expr}); err == nil {
+			t.Errorf("expected compile error for %q", expr)
+		}
+	}
+
+	preds, err := CompilePredicates([]string{"flagged == true or value > 10"})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	if _, err := preds[0](map[string]any{"value": "lots"}); err == nil || !strings.Contains(err.Error(), "value > 10") {
+		t.Fatalf("expected eval error naming the comparison, got %v", err)
+	}
+}
+
+func TestCompilePredicates_Expressions(t *testing.T) {
+	t.Setenv("WT_TEST_SENDERS", "alice, bob")
+	tests := []struct {
+		name string
+		expr string
+		args map[string]any
+		want bool
+	}{
+		{"symbol_operators", "value > 10 && !(status == ok) || flagged == true", map[string]any{"value": 15, "status": "fail"}, true},
+		{"not_binds_tighter_than_and", "not status == ok and value > 10", map[string]any{"status": "fail", "value": 15}, true},
+		{"multiply_before_compare", "value >= 2 * 1e3", map[string]any{"value": 2000}, true},
+		{"multiply_field", "value * 2 > 10", map[string]any{"value": 6}, true},
+		{"helper_of_field", "wei(value) > 10", map[string]any{"value": 11}, true},
+		{"bare_field_condition", "flagged and value > 1", map[string]any{"flagged": true, "value": 2}, true},
+		{"bare_field_false", "flagged", map[string]any{"flagged": false}, false},
+		{"bare_field_missing", "not flagged", map[string]any{}, true},
+		{"quoted_value", `memo contains "and then"`, map[string]any{"memo": "this and then that"}, true},
+		{"single_quoted", "status == 'not ok'", map[string]any{"status": "not ok"}, true},
+		{"escaped_quote", `memo == "say \"hi\""`, map[string]any{"memo": `say "hi"`}, true},
+		{"unquoted_words", "memo contains critical alert", map[string]any{"memo": "a critical alert"}, true},
+		{"hex_stays_text", "to != 0x0000000000000000000000000000000000000000", map[string]any{"to": "0x00000000000000000000000000000000000000aa"}, true},
+		{"hyphenated_value", "receiver == aurora-0.near", map[string]any{"receiver": "aurora-0.near"}, true},
+		{"denom_with_slash", "denom == ibc/27394FB0", map[string]any{"denom": "ibc/27394FB0"}, true},
+		{"negative_number", "delta > -5", map[string]any{"delta": -3}, true},
+		{"no_spaces", "value>10", map[string]any{"value": 11}, true},
+		{"base64_padding", "data == eA== and value == 1", map[string]any{"data": "eA==", "value": 1}, true},
+		{"base64_in_list", "data in MTA=, eA==", map[string]any{"data": "eA=="}, true},
+		{"no_space_before_op", "value== 10", map[string]any{"value": 10}, true},
+		{"bracketed_list", `sender in ["a b", c]`, map[string]any{"sender": "a b"}, true},
+		{"spaced_list", "sender in a, b, c", map[string]any{"sender": "c"}, true},
+		{"numeric_list", "value in [1, 2_000]", map[string]any{"value": int64(2000)}, true},
+		{"env_list", "sender in env(WT_TEST_SENDERS)", map[string]any{"sender": "bob"}, true},
+		{"env_list_miss", "sender in env(WT_TEST_SENDERS)", map[string]any{"sender": "carol"}, false},
+		{"missing_in_product", "value * 2 > 10", map[string]any{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preds, err := CompilePredicates([]string{tt.expr})
+			if err != nil {
+				t.Fatalf("unexpected compile error: %v", err)
+			}
+			got, err := preds[0](tt.args)
+			if err != nil {
+				t.Fatalf("unexpected eval error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("predicate(%q) with args %v = %v, want %v", tt.expr, tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePredicates_ExactNumbers(t *testing.T) {
+	bigInt := func(s string) *big.Int {
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			t.Fatalf("bad big int %s", s)
+		}
+		return n
+	}
+	tests := []struct {
+		name string
+		expr string
+		args map[string]any
+		want bool
+	}{
+		// Both sides round to the same float64; only an exact comparison
+		// tells them apart.
+		{"big_int_below_threshold", "value >= 123456789012345678901", map[string]any{"value": bigInt("123456789012345678900")}, false},
+		{"big_int_at_threshold", "value >= 123456789012345678901", map[string]any{"value": bigInt("123456789012345678901")}, true},
+		{"big_int_equal", "value == 123456789012345678901", map[string]any{"value": bigInt("123456789012345678902")}, false},
+		{"uint64_above_2_53", "value > 9007199254740992", map[string]any{"value": uint64(9007199254740993)}, true},
+		{"string_digits", "value > 99999999999999999999", map[string]any{"value": "100000000000000000000"}, true},
+		{"json_number", "value == 10000000000000000000001", map[string]any{"value": json.Number("10000000000000000000001")}, true},
+		{"uint256_max", "value == 115792089237316195423570985008687907853269984665640564039457584007913129639935", map[string]any{"value": new(big.Int).Sub(new(big.Int).Lsh(bigInt("1"), 256), bigInt("1"))}, true},
+		{"exponent_exact", "value >= 1e18", map[string]any{"value": bigInt("999999999999999999")}, false},
+		{"product_exact", "value >= 1_000_000 * 1e18", map[string]any{"value": bigInt("999999999999999999999999")}, false},
+		{"decimal_literal", "value > 0.1", map[string]any{"value": 0.1}, true},
+		{"contains_digits", "memo contains 1e3", map[string]any{"memo": "paid 1e3"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preds, err := CompilePredicates([]string{tt.expr})
+			if err != nil {
+				t.Fatalf("unexpected compile error: %v", err)
+			}
+			got, err := preds[0](tt.args)
+			if err != nil {
+				t.Fatalf("unexpected eval error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("predicate(%q) with args %v = %v, want %v", tt.expr, tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePredicates_AddressCase(t *testing.T) {
+	checksummed := "0xA0b86991c6218b36c1d19d4a2e9eB0cE3606eB48"
+	tests := []struct {
+		name string
+		expr string
+		args map[string]any
+		want bool
+	}{
+		{"lowercase_rule", "sender == 0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", map[string]any{"sender": checksummed}, true},
+		{"checksummed_rule", "sender == " + checksummed, map[string]any{"sender": "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"}, true},
+		{"not_equal", "sender != 0xA0B86991C6218B36C1D19D4A2E9EB0CE3606EB48", map[string]any{"sender": checksummed}, false},
+		{"in_list", "sender in 0xdead, 0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", map[string]any{"sender": checksummed}, true},
+		{"tx_hash", "tx == 0xABCDEF", map[string]any{"tx": "0xabcdef"}, true},
+		{"other_text_keeps_case", "status == OK", map[string]any{"status": "ok"}, false},
+		{"base58_keeps_case", "owner == tr7nhqjekqxgtci8q8zy4pl8otszgjlj6t", map[string]any{"owner": "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"}, false},
+		{"iequals", "status iequals OK", map[string]any{"status": "ok"}, true},
+		{"iequals_quoted", `name IEQUALS "Alice Smith"`, map[string]any{"name": "alice smith"}, true},
+		{"iequals_miss", "status iequals OK", map[string]any{"status": "okay"}, false},
+		{"iequals_missing_field", "status iequals OK", map[string]any{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preds, err := CompilePredicates([]string{tt.expr})
+			if err != nil {
+				t.Fatalf("unexpected compile error: %v", err)
+			}
+			got, err := preds[0](tt.args)
+			if err != nil {
+				t.Fatalf("unexpected eval error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("predicate(%q) with args %v = %v, want %v", tt.expr, tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePredicates_NegatedOperators(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		args map[string]any
+		want bool
+	}{
+		{"not_in_outside", "from not_in 0xaa,0xbb", map[string]any{"from": "0xcc"}, true},
+		{"not_in_listed", "from not_in 0xaa,0xbb", map[string]any{"from": "0xBB"}, false},
+		{"not_in_spaced", "from not in [0xaa, 0xbb]", map[string]any{"from": "0xcc"}, true},
+		{"not_in_missing_field", "from not_in 0xaa,0xbb", map[string]any{}, false},
+		{"not_around_in_missing_field", "not from in 0xaa,0xbb", map[string]any{}, true},
+		{"not_in_numbers", "code not_in 1, 2", map[string]any{"code": int64(2)}, false},
+		{"not_contains", "memo not_contains test", map[string]any{"memo": "live alert"}, true},
+		{"not_contains_hit", "memo not_contains test", map[string]any{"memo": "a test alert"}, false},
+		{"not_contains_spaced", "memo NOT CONTAINS test", map[string]any{"memo": "live"}, true},
+		{"not_contains_missing_field", "memo not_contains test", map[string]any{}, false},
+		{"combined", "value > 10 and from not_in 0xaa,0xbb", map[string]any{"value": 11, "from": "0xcc"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preds, err := CompilePredicates([]string{tt.expr})
+			if err != nil {
+				t.Fatalf("unexpected compile error: %v", err)
+			}
+			got, err := preds[0](tt.args)
+			if err != nil {
+				t.Fatalf("unexpected eval error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("predicate(%q) with args %v = %v, want %v", tt.expr, tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePredicates_ArrayHelpers(t *testing.T) {
+	args := map[string]any{
+		"application_args": []string{"YnV5", "MTA=", "eA=="},
+		"accounts":         []string{"AAAA", "BBBB"},
+		"amounts":          []any{int64(5), big.NewInt(50)},
+		"empty":            []string{},
+		"memo":             "héllo",
+		"asset":            uint64(31566704),
+	}
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{"len_list", "len(application_args) > 2", true},
+		{"len_list_exact", "len(accounts) == 2", true},
+		{"len_empty", "len(empty) == 0", true},
+		{"len_text_counts_characters", "len(memo) == 5", true},
+		{"len_missing_field", "len(missing) == 0", false},
+		{"any_hit", `any(accounts, x == "BBBB")`, true},
+		{"any_miss", `any(accounts, x == "CCCC")`, false},
+		{"any_numbers", "any(amounts, x > 10)", true},
+		{"any_uses_other_args", "any(accounts, x == AAAA and asset == 31566704)", true},
+		{"all_numbers", "all(amounts, x > 1)", true},
+		{"all_fail", "all(amounts, x > 10)", false},
+		{"all_empty", "all(empty, x == a)", true},
+		{"any_empty", "any(empty, x == a)", false},
+		{"any_missing_field", "any(missing, x == a)", false},
+		{"not_any", `not any(accounts, x == "CCCC")`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preds, err := CompilePredicates([]string{tt.expr})
+			if err != nil {
+				t.Fatalf("unexpected compile error: %v", err)
+			}
+			got, err := preds[0](args)
+			if err != nil {
+				t.Fatalf("unexpected eval error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("predicate(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+
+	preds, err := CompilePredicates([]string{"len(asset) > 1", "any(asset, x == 1)"})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	for _, p := range preds {
+		if _, err := p(args); err == nil {
+			t.Fatalf("expected eval error on a non-list")
+		}
+	}
+}
+
+func TestCompilePredicates_NestedFields(t *testing.T) {
+	args := map[string]any{
+		"application_args": []string{"YnV5", "MTA="},
+		"details": map[string]any{
+			"to":    map[string]any{"address": "0xabc"},
+			"memo":  "hi",
+			"flags": map[string]bool{"frozen": true},
+		},
+		"transfers": []any{
+			map[string]any{"to": map[string]any{"address": "0xdef"}, "amount": int64(5)},
+			map[string]any{"to": map[string]any{"address": "0x123"}, "amount": int64(50)},
+		},
+		"tx0.amount": int64(7),
+		"tx0":        map[string]any{"amount": int64(1)},
+	}
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{"index", "application_args[0] == YnV5", true},
+		{"negative_index", "application_args[-1] == MTA=", true},
+		{"index_past_end", "application_args[2] == YnV5", false},
+		{"not_index_past_end", "not application_args[2] == YnV5", true},
+		{"dotted_path", "details.to.address == 0xabc", true},
+		{"dotted_missing", "details.from.address == 0xabc", false},
+		{"typed_map", "details.flags.frozen", true},
+		{"quoted_key", `details["memo"] == hi`, true},
+		{"index_then_path", "transfers[1].to.address == 0x123", true},
+		{"index_then_field", "transfers[0].amount < 10", true},
+		{"chained_index", `transfers[0]["to"].address == 0xdef`, true},
+		{"flat_key_wins", "tx0.amount == 7", true},
+		{"any_nested", "any(transfers, x.amount > 10 and x.to.address == 0x123)", true},
+		{"len_nested", "len(details.to) == 1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preds, err := CompilePredicates([]string{tt.expr})
+			if err != nil {
+				t.Fatalf("unexpected compile error: %v", err)
+			}
+			got, err := preds[0](args)
+			if err != nil {
+				t.Fatalf("unexpected eval error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("predicate(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+
+	preds, err := CompilePredicates([]string{"details[0] == a", `application_args["a"] == b`})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	for _, p := range preds {
+		if _, err := p(args); err == nil {
+			t.Fatalf("expected eval error indexing the wrong kind of value")
+		}
+	}
+}
+
+func TestCompilePredicates_UnitHelpers(t *testing.T) {
+	bigInt := func(s string) *big.Int {
+		n, _ := new(big.Int).SetString(s, 10)
+		return n
+	}
+	tests := []struct {
+		name string
+		expr string
+		args map[string]any
+		want bool
+	}{
+		{"ether", "value == ether(5)", map[string]any{"value": bigInt("5000000000000000000")}, true},
+		{"ether_fraction", "value == ether(0.5)", map[string]any{"value": bigInt("500000000000000000")}, true},
+		{"ether_below", "value >= ether(5)", map[string]any{"value": bigInt("4999999999999999999")}, false},
+		{"gwei", "gas_price > gwei(30)", map[string]any{"gas_price": int64(30_000_000_001)}, true},
+		{"wei_unchanged", "value == wei(1e18)", map[string]any{"value": bigInt("1000000000000000000")}, true},
+		{"algos", "amount == algos(10)", map[string]any{"amount": uint64(10_000_000)}, true},
+		{"microAlgos_unchanged", "amount == microAlgos(10)", map[string]any{"amount": uint64(10)}, true},
+		{"token_decimals", "value >= token(100, 6)", map[string]any{"value": bigInt("100000000")}, true},
+		{"token_decimals_below", "value >= token(100, 6)", map[string]any{"value": bigInt("99999999")}, false},
+		{"token_zero_decimals", "value == token(7, 0)", map[string]any{"value": 7}, true},
+		{"helper_of_field", "value == ether(eth_amount)", map[string]any{"eth_amount": "2", "value": bigInt("2000000000000000000")}, true},
+		{"token_field_decimals", "value >= token(100, decimals)", map[string]any{"value": bigInt("100000000"), "decimals": 6}, true},
+		{"times_helper", "value >= 2 * ether(1)", map[string]any{"value": bigInt("2000000000000000000")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preds, err := CompilePredicates([]string{tt.expr})
+			if err != nil {
+				t.Fatalf("unexpected compile error: %v", err)
+			}
+			got, err := preds[0](tt.args)
+			if err != nil {
+				t.Fatalf("unexpected eval error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("predicate(%q) with args %v = %v, want %v", tt.expr, tt.args, got, tt.want)
+			}
+		})
+	}
+
+	preds, err := CompilePredicates([]string{"value >= token(100, decimals)"})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	if _, err := preds[0](map[string]any{"value": 1, "decimals": 2.5}); err == nil || !strings.Contains(err.Error(), "token(100, decimals)") {
+		t.Fatalf("expected eval error naming the call, got %v", err)
+	}
+}
+
+func TestCompilePredicates_CompileErrors(t *testing.T) {
+	tests := []struct {
+		expr   string
+		column int
+		msg    string
+	}{
+		{"value ** 2", 8, `expected a value, got "*"`},
+		{"(value > 10", 12, `expected ")"`},
+		{"value > 10)", 11, `unexpected ")"`},
+		{"value = 10", 7, `use "=="`},
+		{"memo contains \"alert", 15, "unterminated string"},
+		{"value > 10 and 5", 16, "expected a condition, got a number"},
+		{"value > eth(1)", 9, "unknown function eth"},
+		{"value > token(1, 1.5)", 9, "decimals must be a whole number from 0 to 77, got 3/2"},
+		{"value > token(1, -1)", 9, "decimals must be a whole number"},
+		{"value > wei(1, 2)", 9, "wei takes 1 argument(s), got 2"},
+		{"value > 2 * \"x\"", 13, "expected a number, got a string"},
+		{"(value > 1) == true", 1, "cannot compare a condition"},
+		{"value * 2 > \"x\"", 11, "cannot order a number against a string"},
+		{"sender in", 10, "expected a value, got end of expression"},
+		{"len(5) > 1", 5, "len takes a list or text, got a number"},
+		{"any(accounts)", 13, `expected ",", got ")"`},
+		{"any(accounts, x))", 17, `unexpected ")"`},
+		{"all(accounts, 5)", 15, "expected a condition, got a number"},
+		{"items[x] == 1", 7, "expected a list index or quoted key"},
+		{"items[1.5] == 1", 7, "expected a list index or quoted key"},
+		{"items[0 == 1", 9, `expected "]"`},
+		{"value > 10 and", 15, "expected a value, got end of expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := CompilePredicates([]string{tt.expr})
+			var exprErr *ExprError
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("expected ExprError, got %v", err)
+			}
+			if exprErr.Pos+1 != tt.column || !strings.Contains(exprErr.Msg, tt.msg) {
+				t.Fatalf("got %q at column %d, want %q at column %d", exprErr.Msg, exprErr.Pos+1, tt.msg, tt.column)
+			}
+			if !strings.Contains(err.Error(), tt.expr) {
+				t.Fatalf("error %q does not name the expression", err)
+			}
+		})
+	}
+}
+
 func TestCompilePredicates_MultiplePredicates(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// DigestRuleID is the rule id carried by quiet hours digests.
+const DigestRuleID = "digest"
+
+// DefaultQuietCheck is how often ended quiet hours are checked for held
+// alerts to send.
+const DefaultQuietCheck = time.Minute
+
+// quietHours is a sink's compiled config.QuietHours.
+type quietHours struct {
+	start, end time.Duration // offsets from local midnight
+	loc        *time.Location
+	except     map[string]bool // severities delivered anyway
+}
+
+func compileQuietHours(sinks []config.Sink) (map[string]*quietHours, error) {
+	out := map[string]*quietHours{}
+	for _, s := range sinks {
+		if s.QuietHours == nil {
+			continue
+		}
+		start, end, loc, err := s.QuietHours.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("sink %s quiet_hours: %w", s.ID, err)
+		}
+		q := &quietHours{start: start, end: end, loc: loc, except: map[string]bool{}}
+		for _, sev := range s.QuietHours.Except {
+			q.except[sev] = true
+		}
+		out[s.ID] = q
+	}
+	return out, nil
+}
+
+// active reports whether t falls in the window, read on the wall clock of
+// its timezone so it follows daylight saving changes.
+func (q *quietHours) active(t time.Time) bool {
+	h, m, sec := t.In(q.loc).Clock()
+	at := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second
+	if q.start < q.end {
+		return at >= q.start && at < q.end
+	}
+	return at >= q.start || at < q.end
+}
+
+// holdQuiet holds alert for each of sinks in quiet hours, unless its
+// severity is exempt, and returns the sinks to deliver to now.
+func (r *Runner) holdQuiet(ctx context.Context, log *slog.Logger, alert storage.Alert, severity string, sinks []string) ([]string, error) {
+	if len(r.quiet) == 0 || alert.RuleID == DigestRuleID {
+		return sinks, nil
+	}
+	now := r.nowFunc()
+	var deliver []string
+	for _, sinkID := range sinks {
+		q := r.quiet[sinkID]
+		if q == nil || q.except[severity] || !q.active(now) {
+			deliver = append(deliver, sinkID)
+			continue
+		}
+		if err := r.store.HoldAlert(ctx, alert.ID, sinkID, now); err != nil {
+			return nil, err
+		}
+		log.Info("alert held for quiet hours", "sink", sinkID)
+		r.audit(ctx, storage.AuditRecord{RuleID: alert.RuleID, SourceID: alert.SourceID, Height: alert.Height, TxHash: alert.TxHash, AlertID: alert.ID, SinkID: sinkID, Decision: AuditHeld})
+	}
+	return deliver, nil
+}
+
+// FlushQuietHours sends each sink whose quiet hours are over one digest of
+// the alerts held for it, then releases them.
+func (r *Runner) FlushQuietHours(ctx context.Context) error {
+	sinkIDs := make([]string, 0, len(r.quiet))
+	for id := range r.quiet {
+		sinkIDs = append(sinkIDs, id)
+	}
+	sort.Strings(sinkIDs)
+	for _, sinkID := range sinkIDs {
+		now := r.nowFunc()
+		if r.quiet[sinkID].active(now) {
+			continue
+		}
+		held, err := r.store.HeldAlerts(ctx, sinkID)
+		if err != nil {
+			return err
+		}
+		if len(held) == 0 {
+			continue
+		}
+
+		ids := make([]string, len(held))
+		byRule := map[string]int{}
+		for i, a := range held {
+			ids[i] = a.ID
+			byRule[a.RuleID]++
+		}
+		ruleIDs := make([]string, 0, len(byRule))
+		for id := range byRule {
+			ruleIDs = append(ruleIDs, id)
+		}
+		sort.Strings(ruleIDs)
+		parts := make([]string, len(ruleIDs))
+		for i, id := range ruleIDs {
+			parts[i] = fmt.Sprintf("%s %d", id, byRule[id])
+		}
+
+		alertID := newAlertID()
+		payload := sink.EventPayload{
+			RuleID:    DigestRuleID,
+			Timestamp: now,
+			AlertID:   alertID,
+			Group:     DigestRuleID + "|" + sinkID,
+			Args: map[string]any{
+				"count":     len(held),
+				"rules":     byRule,
+				"alert_ids": ids,
+				"since":     held[0].CreatedAt,
+				"summary":   fmt.Sprintf("%d alerts held during quiet hours: %s", len(held), strings.Join(parts, ", ")),
+			},
+		}
+		log := r.log.With("alert_id", alertID, "rule", DigestRuleID)
+		if err := r.record(ctx, log, storage.Alert{
+			ID:          alertID,
+			RuleID:      DigestRuleID,
+			Fingerprint: alertID,
+			PayloadJSON: payloadJSON(payload),
+			CreatedAt:   now,
+		}, payload, []string{sinkID}); err != nil {
+			return err
+		}
+		if err := r.store.ReleaseHeld(ctx, sinkID, ids, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunQuietHours checks every interval for quiet hours that have ended and
+// sends their digests, until ctx is cancelled. Failures are passed to onErr
+// (which may be nil); held alerts stay held until a later check succeeds.
+func (r *Runner) RunQuietHours(ctx context.Context, every time.Duration, onErr func(error)) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.FlushQuietHours(ctx); err != nil && onErr != nil && ctx.Err() == nil {
+			onErr(err)
+		}
+	}
+}
+
+// HasQuietHours reports whether any sink has quiet hours configured.
+func (r *Runner) HasQuietHours() bool {
+	return len(r.quiet) > 0
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// ReorgRuleID is the rule id carried by reorg alerts sent to global.reorg_sinks.
+const ReorgRuleID = "reorg"
+
+// reorg is a rewind reported by any scanner.
+type reorg struct {
+	height, from, to uint64
+	oldHash, newHash string
+	exceeded         bool // no common ancestor within max_reorg_depth
+}
+
+// asReorg extracts a scanner's ReorgError from err.
+func asReorg(err error) (reorg, bool) {
+	var e *source.ReorgError
+	if !errors.As(err, &e) {
+		return reorg{}, false
+	}
+	return reorg{e.Height, e.From, e.To, e.OldHash, e.NewHash, e.Exceeded}, true
+}
+
+// notifyReorg logs a rewind, retracts alerts raised from the orphaned blocks,
+// matches the source's reorg rules and, when reorg sinks are configured,
+// sends them a "reorg" alert describing the rolled-back range.
+func (r *Runner) notifyReorg(ctx context.Context, chain, sourceID string, rg reorg) error {
+	depth := rg.to - rg.from + 1
+	alertID := newAlertID()
+	log := r.log.With("alert_id", alertID, "rule", ReorgRuleID)
+	if rg.exceeded {
+		log.Error("reorg deeper than max_reorg_depth, rewound to the bound", "source", sourceID, "height", rg.height, "depth", depth, "from", rg.from, "to", rg.to)
+	} else {
+		log.Warn("reorg detected", "source", sourceID, "height", rg.height, "depth", depth, "from", rg.from, "to", rg.to)
+	}
+	if !r.dryRun {
+		if err := r.retract(ctx, sourceID, rg); err != nil {
+			return err
+		}
+	}
+	if err := r.matchReorg(ctx, chain, sourceID, rg); err != nil {
+		return err
+	}
+	if len(r.reorgSinks) == 0 {
+		return nil
+	}
+	now := r.nowFunc()
+	payload := sink.EventPayload{
+		RuleID:    ReorgRuleID,
+		Chain:     chain,
+		SourceID:  sourceID,
+		Height:    rg.height,
+		Hash:      rg.newHash,
+		Timestamp: now,
+		AlertID:   alertID,
+		Explorer:  r.explorers[sourceID],
+		Args:      rg.args(),
+	}
+	if r.publisher != nil {
+		r.publisher.Publish(payload)
+	}
+	if r.dryRun {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%s", ReorgRuleID, sourceID, rg.height, rg.newHash)))
+	return r.record(ctx, log, storage.Alert{
+		ID:          alertID,
+		RuleID:      ReorgRuleID,
+		Fingerprint: hex.EncodeToString(sum[:16]),
+		PayloadJSON: payloadJSON(payload),
+		CreatedAt:   now,
+	}, payload, r.reorgSinks)
+}
+
+// args describes the rolled-back range.
+func (rg reorg) args() map[string]any {
+	return map[string]any{
+		"depth":       rg.to - rg.from + 1,
+		"from_height": rg.from,
+		"to_height":   rg.to,
+		"old_hash":    rg.oldHash,
+		"new_hash":    rg.newHash,
+	}
+}
+
+// matchReorg hands a rewind to the source's reorg rules as an event, so it
+// goes through their where clauses, dedupe and sinks like any other match.
+func (r *Runner) matchReorg(ctx context.Context, chain, sourceID string, rg reorg) error {
+	var ids []string
+	for id, exec := range r.rules {
+		if strings.EqualFold(exec.rule.Match.Type, config.MatchReorg) && exec.rule.AppliesTo(sourceID) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		err := r.handleEvent(ctx, Event{
+			RuleID:    id,
+			Chain:     chain,
+			SourceID:  sourceID,
+			Height:    rg.height,
+			Hash:      rg.newHash,
+			Timestamp: r.nowFunc(),
+			Args:      rg.args(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retract follows up every alert raised from the orphaned blocks with a copy
+// marked as retracted, sent to the sinks that received the original. Sends of
+// the original that are still queued are cancelled instead.
+func (r *Runner) retract(ctx context.Context, sourceID string, rg reorg) error {
+	alerts, err := r.store.ReorgedAlerts(ctx, sourceID, rg.from, rg.to)
+	if err != nil {
+		return err
+	}
+	for _, orig := range alerts {
+		// A reorg alert reports a rewind, not the contents of a block.
+		if exec, ok := r.rules[orig.RuleID]; ok && strings.EqualFold(exec.rule.Match.Type, config.MatchReorg) {
+			continue
+		}
+		now := r.nowFunc()
+		if err := r.store.CancelDeliveries(ctx, orig.ID, now); err != nil {
+			return err
+		}
+		sinks, err := r.store.SentSinks(ctx, orig.ID)
+		if err != nil {
+			return err
+		}
+		payload, err := decodePayload(orig.PayloadJSON)
+		if len(sinks) == 0 || err != nil {
+			continue
+		}
+		alertID := newAlertID()
+		payload.AlertID = alertID
+		payload.Retracts = orig.ID
+		log := r.log.With("alert_id", alertID, "rule", orig.RuleID, "retracts", orig.ID)
+		if r.publisher != nil {
+			r.publisher.Publish(payload)
+		}
+		if err := r.record(ctx, log, storage.Alert{
+			ID:          alertID,
+			RuleID:      orig.RuleID,
+			Fingerprint: orig.Fingerprint,
+			TxHash:      orig.TxHash,
+			PayloadJSON: payloadJSON(payload),
+			CreatedAt:   now,
+			Retracts:    orig.ID,
+		}, payload, sinks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
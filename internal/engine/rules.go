@@ -0,0 +1,342 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/storage"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// RuleStatus is a rule as seen by the management API.
+type RuleStatus struct {
+	Rule     config.Rule `json:"rule"`
+	Disabled bool        `json:"disabled"`
+}
+
+// ApplyStoredRules overlays runtime-managed rules persisted in the store onto
+// cfg.Rules: stored rules replace config rules with the same id, new ones are
+// appended, and disabled ones are removed. Call it before building scanners.
+func ApplyStoredRules(ctx context.Context, store *storage.Store, cfg *config.Config) error {
+	recs, err := store.ListRules(ctx)
+	if err != nil {
+		return err
+	}
+	sourceIDs, sinkIDs := ruleRefs(cfg)
+	for _, rec := range recs {
+		var rule config.Rule
+		if err := json.Unmarshal([]byte(rec.SpecJSON), &rule); err != nil {
+			return fmt.Errorf("stored rule %s: %w", rec.ID, err)
+		}
+		if rec.Disabled {
+			cfg.Rules = removeRule(cfg.Rules, rec.ID)
+			continue
+		}
+		if err := rule.Validate(sourceIDs, sinkIDs); err != nil {
+			return fmt.Errorf("stored rule %s: %w", rec.ID, err)
+		}
+		cfg.Rules = upsertRule(cfg.Rules, rule)
+	}
+	return nil
+}
+
+// ruleRefs indexes the source and sink ids a rule may reference.
+func ruleRefs(cfg *config.Config) (map[string]struct{}, map[string]*config.Sink) {
+	sourceIDs := make(map[string]struct{}, len(cfg.Sources))
+	for _, s := range cfg.Sources {
+		sourceIDs[s.ID] = struct{}{}
+	}
+	sinkIDs := make(map[string]*config.Sink, len(cfg.Sinks))
+	for i := range cfg.Sinks {
+		sinkIDs[cfg.Sinks[i].ID] = &cfg.Sinks[i]
+	}
+	return sourceIDs, sinkIDs
+}
+
+// LoadDisabledRules makes rules disabled in a previous run visible to Rules
+// and EnableRule. ApplyStoredRules has already dropped them from the config.
+func (r *Runner) LoadDisabledRules(ctx context.Context) error {
+	recs, err := r.store.ListRules(ctx)
+	if err != nil {
+		return err
+	}
+	r.rulesMu.Lock()
+	defer r.rulesMu.Unlock()
+	for _, rec := range recs {
+		if !rec.Disabled {
+			continue
+		}
+		var rule config.Rule
+		if err := json.Unmarshal([]byte(rec.SpecJSON), &rule); err != nil {
+			return fmt.Errorf("stored rule %s: %w", rec.ID, err)
+		}
+		r.disabled[rec.ID] = rule
+	}
+	return nil
+}
+
+// Rules lists active rules followed by disabled ones.
+func (r *Runner) Rules() []RuleStatus {
+	r.rulesMu.RLock()
+	defer r.rulesMu.RUnlock()
+	out := make([]RuleStatus, 0, len(r.ruleSpecs)+len(r.disabled))
+	for _, rule := range r.ruleSpecs {
+		out = append(out, RuleStatus{Rule: rule})
+	}
+	ids := make([]string, 0, len(r.disabled))
+	for id := range r.disabled {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		out = append(out, RuleStatus{Rule: r.disabled[id], Disabled: true})
+	}
+	return out
+}
+
+// ApplyRule validates a rule, persists it, and atomically swaps it into the
+// running engine (creating it or replacing the rule with the same id).
+func (r *Runner) ApplyRule(ctx context.Context, rule config.Rule) error {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+	r.rulesMu.Lock()
+	defer r.rulesMu.Unlock()
+
+	if err := rule.ExpandPreset(); err != nil {
+		return fmt.Errorf("%w: rule %s: %v", ErrInvalidRule, rule.ID, err)
+	}
+	return r.swapRules(ctx, upsertRule(r.ruleSpecs, rule), rule, false)
+}
+
+// DisableRule removes a rule from the running engine and remembers it as disabled.
+func (r *Runner) DisableRule(ctx context.Context, id string) error {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+	r.rulesMu.Lock()
+	defer r.rulesMu.Unlock()
+
+	rule, ok := findRule(r.ruleSpecs, id)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRule, id)
+	}
+	return r.swapRules(ctx, removeRule(r.ruleSpecs, id), rule, true)
+}
+
+// EnableRule restores a previously disabled rule.
+func (r *Runner) EnableRule(ctx context.Context, id string) error {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+	r.rulesMu.Lock()
+	defer r.rulesMu.Unlock()
+
+	rule, ok := r.disabled[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRule, id)
+	}
+	return r.swapRules(ctx, upsertRule(r.ruleSpecs, rule), rule, false)
+}
+
+// swapRules compiles next, prepares every scanner, persists changed, and only
+// then commits. Callers hold tickMu and rulesMu.
+func (r *Runner) swapRules(ctx context.Context, next []config.Rule, changed config.Rule, disabled bool) error {
+	if !disabled {
+		if err := changed.Validate(r.sourceIDs, r.sinkIDs); err != nil {
+			return fmt.Errorf("%w: rule %s: %v", ErrInvalidRule, changed.ID, err)
+		}
+	}
+	execs, err := compileRules(next, r.rules)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	commits, err := r.prepareScanners(next)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+
+	spec, err := json.Marshal(changed)
+	if err != nil {
+		return fmt.Errorf("marshal rule: %w", err)
+	}
+	if err := r.store.UpsertRule(ctx, storage.RuleRecord{ID: changed.ID, SpecJSON: string(spec), Disabled: disabled}); err != nil {
+		return err
+	}
+
+	for _, commit := range commits {
+		commit()
+	}
+	r.rules = execs
+	r.ruleSpecs = next
+	if disabled {
+		r.disabled[changed.ID] = changed
+	} else {
+		delete(r.disabled, changed.ID)
+	}
+	return nil
+}
+
+// UpdateWatchlist swaps in the addresses loaded from a watch_address rule's
+// addresses_from. They are added to the rule's own addresses for matching but
+// never persisted, since the list is reloaded from its origin on start.
+func (r *Runner) UpdateWatchlist(ruleID string, addrs []string) error {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+	r.rulesMu.Lock()
+	defer r.rulesMu.Unlock()
+
+	prev, had := r.watchlists[ruleID]
+	r.watchlists[ruleID] = addrs
+	commits, err := r.prepareScanners(r.ruleSpecs)
+	if err != nil {
+		if had {
+			r.watchlists[ruleID] = prev
+		} else {
+			delete(r.watchlists, ruleID)
+		}
+		return err
+	}
+	for _, commit := range commits {
+		commit()
+	}
+	return nil
+}
+
+// ReloadABIs rebuilds an EVM source's matchers from reloaded abi_dirs. If a
+// rule no longer resolves against them, the current matchers are kept.
+func (r *Runner) ReloadABIs(sourceID string, abis map[string]*abi.ABI) error {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+	r.rulesMu.Lock()
+	defer r.rulesMu.Unlock()
+
+	sc, ok := evmScanner(r.scanners[sourceID])
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSource, sourceID)
+	}
+	commit, err := sc.PrepareABIs(abis, r.withWatchlists(r.ruleSpecs))
+	if err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+// withWatchlists returns rules with loaded watchlist addresses merged in.
+func (r *Runner) withWatchlists(rules []config.Rule) []config.Rule {
+	if len(r.watchlists) == 0 {
+		return rules
+	}
+	merged := make([]config.Rule, len(rules))
+	for i, rule := range rules {
+		if extra := r.watchlists[rule.ID]; len(extra) > 0 {
+			rule.Match.Addresses = append(slices.Clip(rule.Match.Addresses), extra...)
+		}
+		merged[i] = rule
+	}
+	return merged
+}
+
+// prepareScanners prepares every scanner for rules, with loaded watchlist
+// addresses merged in. Callers hold tickMu and rulesMu.
+func (r *Runner) prepareScanners(rules []config.Rule) ([]func(), error) {
+	rules = r.withWatchlists(rules)
+	var commits []func()
+	for _, sc := range r.scanners {
+		commit, err := sc.PrepareRules(rules)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, commit)
+	}
+	for _, s := range r.ingests {
+		commit, err := s.PrepareRules(rules)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, commit)
+	}
+	for _, w := range r.mempools {
+		commit, err := w.PrepareRules(rules)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// compileRules builds executable rules. Rate-limit buckets of rules whose
+// limit is unchanged are carried over from prev so a swap does not refill them.
+func compileRules(rules []config.Rule, prev map[string]ruleExec) (map[string]ruleExec, error) {
+	out := make(map[string]ruleExec, len(rules))
+	for _, r := range rules {
+		preds, err := CompilePredicates(r.Match.Where)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s predicates: %w", r.ID, err)
+		}
+		var ttl time.Duration
+		if r.Dedupe != nil && r.Dedupe.TTL != "" {
+			if d, err := time.ParseDuration(r.Dedupe.TTL); err == nil {
+				ttl = d
+			}
+		}
+		var rateLimit *TokenBucket
+		if r.RateLimit != nil {
+			if old, ok := prev[r.ID]; ok && old.rateLimit != nil && reflect.DeepEqual(old.rule.RateLimit, r.RateLimit) {
+				rateLimit = old.rateLimit
+			} else {
+				rateLimit = NewTokenBucket(r.RateLimit.Capacity, r.RateLimit.Rate)
+			}
+		}
+		var where []string
+		for _, expr := range r.Match.Where {
+			if expr = strings.TrimSpace(expr); expr != "" {
+				where = append(where, expr)
+			}
+		}
+		out[r.ID] = ruleExec{rule: r, preds: preds, where: where, ttl: ttl, rateLimit: rateLimit}
+	}
+	return out, nil
+}
+
+func findRule(rules []config.Rule, id string) (config.Rule, bool) {
+	for _, r := range rules {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return config.Rule{}, false
+}
+
+func upsertRule(rules []config.Rule, rule config.Rule) []config.Rule {
+	out := make([]config.Rule, 0, len(rules)+1)
+	replaced := false
+	for _, r := range rules {
+		if r.ID == rule.ID {
+			out = append(out, rule)
+			replaced = true
+			continue
+		}
+		out = append(out, r)
+	}
+	if !replaced {
+		out = append(out, rule)
+	}
+	return out
+}
+
+func removeRule(rules []config.Rule, id string) []config.Rule {
+	out := make([]config.Rule, 0, len(rules))
+	for _, r := range rules {
+		if r.ID != id {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source/evm"
+)
+
+func TestRunnerRuleLifecycle(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	cfg := &config.Config{
+		Sources: []config.Source{{ID: "evm_main", Type: "evm"}},
+		Sinks:   []config.Sink{{ID: "s1", Type: "webhook"}},
+	}
+	sc, err := evm.NewScanner(failingClient{}, store, cfg.Sources[0], 0, nil, nil)
+	if err != nil {
+		t.Fatalf("scanner: %v", err)
+	}
+	runner, err := NewRunner(store, cfg, map[string]Scanner{"evm_main": NewEVMScanner(sc)}, nil, true, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+
+	rule := config.Rule{
+		ID:     "big_transfer",
+		Source: "evm_main",
+		Match:  config.MatchSpec{Type: "log", Contract: "0xabc", Event: "Transfer(address,address,uint256)", Where: []string{"value > 10"}},
+		Sinks:  []string{"s1"},
+	}
+	if err := runner.ApplyRule(ctx, rule); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if _, ok := runner.rules["big_transfer"]; !ok {
+		t.Fatalf("rule not swapped in")
+	}
+
+	bad := rule
+	bad.Match.Where = []string{"value ~ 10"}
+	if err := runner.ApplyRule(ctx, bad); !errors.Is(err, ErrInvalidRule) {
+		t.Fatalf("expected invalid rule, got %v", err)
+	}
+	bad = rule
+	bad.Sinks = []string{"missing"}
+	if err := runner.ApplyRule(ctx, bad); !errors.Is(err, ErrInvalidRule) {
+		t.Fatalf("expected invalid rule for unknown sink, got %v", err)
+	}
+	if got := runner.rules["big_transfer"].rule.Match.Where; len(got) != 1 || got[0] != "value > 10" {
+		t.Fatalf("failed update must leave the running rule untouched, got %v", got)
+	}
+
+	if err := runner.DisableRule(ctx, "big_transfer"); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if st := runner.Rules(); len(st) != 1 || !st[0].Disabled {
+		t.Fatalf("unexpected rules: %+v", st)
+	}
+	if err := runner.DisableRule(ctx, "big_transfer"); !errors.Is(err, ErrUnknownRule) {
+		t.Fatalf("expected unknown rule, got %v", err)
+	}
+
+	// A fresh config picks up the persisted state on the next start.
+	restart := &config.Config{Sources: cfg.Sources, Sinks: cfg.Sinks, Rules: []config.Rule{rule}}
+	if err := ApplyStoredRules(ctx, store, restart); err != nil {
+		t.Fatalf("apply stored: %v", err)
+	}
+	if len(restart.Rules) != 0 {
+		t.Fatalf("disabled rule should be dropped, got %+v", restart.Rules)
+	}
+
+	if err := runner.EnableRule(ctx, "big_transfer"); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	restart.Rules = nil
+	if err := ApplyStoredRules(ctx, store, restart); err != nil {
+		t.Fatalf("apply stored: %v", err)
+	}
+	if len(restart.Rules) != 1 || restart.Rules[0].Match.Contract != "0xabc" {
+		t.Fatalf("expected stored rule restored, got %+v", restart.Rules)
+	}
+}
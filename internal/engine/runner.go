@@ -2,224 +2,578 @@ package engine
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/metrics"
 	"github.com/devblac/watch-tower/internal/sink"
-	"github.com/devblac/watch-tower/internal/source/algorand"
 	"github.com/devblac/watch-tower/internal/source/evm"
+	"github.com/devblac/watch-tower/internal/source/ingest"
 	"github.com/devblac/watch-tower/internal/storage"
 )
 
+// ErrUnknownSource is returned by admin operations for an unconfigured source id.
+var ErrUnknownSource = errors.New("unknown source")
+
+// ErrUnknownRule is returned by rule management for an id that is not loaded.
+var ErrUnknownRule = errors.New("unknown rule")
+
+// ErrInvalidRule wraps validation and compilation failures of a submitted rule.
+var ErrInvalidRule = errors.New("invalid rule")
+
+// discardLogger is used until SetLogger is called.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// DefaultEventBuffer is how many matched events may wait for handling before
+// a scanner blocks.
+const DefaultEventBuffer = 256
+
 // Runner wires sources, predicates, dedupe, and sinks for a single pass.
 type Runner struct {
 	store      *storage.Store
 	sinks      map[string]sink.Sender
 	rules      map[string]ruleExec
-	evmScan    map[string]*evm.Scanner
-	algoScan   map[string]*algorand.Scanner
+	ruleSpecs  []config.Rule
+	disabled   map[string]config.Rule
+	watchlists map[string][]string // rule id -> addresses loaded from addresses_from
+	sourceIDs  map[string]struct{}
+	explorers  map[string]string
+	reorgSinks []string
+	sinkIDs    map[string]*config.Sink
+	quiet      map[string]*quietHours // sink id -> quiet hours
+	scanners   map[string]Scanner
+	mempools   map[string]*evm.MempoolWatcher
+	ingests    map[string]*ingest.Source
 	dryRun     bool
 	nowFunc    func() time.Time
 	targetFrom uint64
 	targetTo   uint64
+	publisher  Publisher
+	dispatcher *Dispatcher
+	annotator  Annotator
+	metrics    *metrics.Metrics
+	log        *slog.Logger
+	// auditRetention is how long stored audit records are kept; zero
+	// means audit records are only logged.
+	auditRetention time.Duration
+	// eventBuffer bounds how many decoded events wait between a scanner and
+	// the handler; a full buffer blocks the scanner.
+	eventBuffer int
+	// failureBudget is how many consecutive failures a source may have
+	// before RunOnce returns the error; see SetFailureBudget.
+	failureBudget int
+
+	// tickMu serializes scanning with admin operations that move cursors.
+	tickMu  sync.Mutex
+	stateMu sync.Mutex
+	paused  map[string]bool
+	// backoffs holds sources that failed recently; see sourceDone.
+	backoffs map[string]*sourceBackoff
+	wake     chan struct{}
+	// rulesMu guards ruleSpecs and disabled for readers outside a tick.
+	rulesMu sync.RWMutex
+	// suppressed counts dropped alerts by rule and reason until the next
+	// suppression summary; see FlushSuppressed.
+	suppressMu sync.Mutex
+	suppressed map[string]map[string]int
+}
+
+// Publisher receives every event that passes a rule's predicates.
+type Publisher interface {
+	Publish(payload sink.EventPayload)
+}
+
+// Annotator adds derived args (such as value_usd) to an event before its
+// rule's predicates run.
+type Annotator interface {
+	Annotate(ctx context.Context, contract string, args map[string]any)
 }
 
 type Event struct {
-	RuleID   string
-	Chain    string
-	SourceID string
-	Height   uint64
-	Hash     string
-	TxHash   string
-	LogIndex *uint
-	AppID    uint64
-	Args     map[string]any
+	RuleID    string
+	Chain     string
+	SourceID  string
+	Height    uint64
+	Hash      string
+	TxHash    string
+	LogIndex  *uint
+	AppID     uint64
+	Contract  string
+	Timestamp time.Time // block or round time
+	Args      map[string]any
+	Backfill  bool // found by a backfill rather than live scanning
 }
 
 type ruleExec struct {
 	rule      config.Rule
 	preds     []Predicate
+	where     []string // the expression behind each of preds
 	ttl       time.Duration
 	rateLimit *TokenBucket
 }
 
-// NewRunner builds a runner for the provided config and scanners.
-func NewRunner(store *storage.Store, cfg *config.Config, evmScanners map[string]*evm.Scanner, algoScanners map[string]*algorand.Scanner, sinks map[string]sink.Sender, dryRun bool, from, to uint64) (*Runner, error) {
-	rules := make(map[string]ruleExec, len(cfg.Rules))
-	for _, r := range cfg.Rules {
-		preds, err := CompilePredicates(r.Match.Where)
-		if err != nil {
-			return nil, fmt.Errorf("rule %s predicates: %w", r.ID, err)
-		}
-		var ttl time.Duration
-		if r.Dedupe != nil && r.Dedupe.TTL != "" {
-			if d, err := time.ParseDuration(r.Dedupe.TTL); err == nil {
-				ttl = d
-			}
+// NewRunner builds a runner for the provided config and scanners, keyed by
+// source id.
+func NewRunner(store *storage.Store, cfg *config.Config, scanners map[string]Scanner, sinks map[string]sink.Sender, dryRun bool, from, to uint64) (*Runner, error) {
+	rules, err := compileRules(cfg.Rules, nil)
+	if err != nil {
+		return nil, err
+	}
+	sourceIDs, sinkIDs := ruleRefs(cfg)
+	quiet, err := compileQuietHours(cfg.Sinks)
+	if err != nil {
+		return nil, err
+	}
+	if to > 0 {
+		for _, sc := range scanners {
+			sc.SetStopHeight(to)
 		}
-		var rateLimit *TokenBucket
-		if r.RateLimit != nil {
-			rateLimit = NewTokenBucket(r.RateLimit.Capacity, r.RateLimit.Rate)
+	}
+	explorers := map[string]string{}
+	for _, src := range cfg.Sources {
+		if src.ExplorerURL != "" {
+			explorers[src.ID] = src.ExplorerURL
 		}
-		rules[r.ID] = ruleExec{rule: r, preds: preds, ttl: ttl, rateLimit: rateLimit}
 	}
 
 	return &Runner{
 		store:      store,
 		sinks:      sinks,
 		rules:      rules,
-		evmScan:    evmScanners,
-		algoScan:   algoScanners,
+		ruleSpecs:  append([]config.Rule(nil), cfg.Rules...),
+		disabled:   map[string]config.Rule{},
+		watchlists: map[string][]string{},
+		sourceIDs:  sourceIDs,
+		explorers:  explorers,
+		reorgSinks: cfg.Global.ReorgSinks,
+		sinkIDs:    sinkIDs,
+		quiet:      quiet,
+		scanners:   scanners,
+		mempools:   map[string]*evm.MempoolWatcher{},
+		ingests:    map[string]*ingest.Source{},
 		dryRun:     dryRun,
 		nowFunc:    time.Now,
 		targetFrom: from,
 		targetTo:   to,
+		paused:     map[string]bool{},
+		backoffs:   map[string]*sourceBackoff{},
+		wake:       make(chan struct{}, 1),
+		log:        discardLogger,
+
+		eventBuffer:   DefaultEventBuffer,
+		failureBudget: DefaultFailureBudget,
 	}, nil
 }
 
-// RunOnce processes one eligible block/round per source.
+// SetLogger makes the runner log each alert's progress, keyed by alert_id.
+func (r *Runner) SetLogger(l *slog.Logger) {
+	r.log = l
+}
+
+// SetMetrics makes the runner count predicate evaluation errors.
+func (r *Runner) SetMetrics(m *metrics.Metrics) {
+	r.metrics = m
+}
+
+// SetPublisher attaches a live event publisher (e.g. a stream.Hub).
+func (r *Runner) SetPublisher(p Publisher) {
+	r.publisher = p
+}
+
+// SetAnnotator enriches event args before predicates are evaluated.
+func (r *Runner) SetAnnotator(a Annotator) {
+	r.annotator = a
+}
+
+// SetDispatcher queues sink sends on d instead of sending them inline.
+// Its delivery outcomes join the runner's audit trail.
+func (r *Runner) SetDispatcher(d *Dispatcher) {
+	r.dispatcher = d
+	d.audit = r.audit
+}
+
+// SetEventBuffer sets how many matched events may be queued between a
+// scanner and alert handling before the scanner is made to wait.
+func (r *Runner) SetEventBuffer(n int) {
+	if n < 0 {
+		n = 0
+	}
+	r.eventBuffer = n
+}
+
+// SourceStatus describes a configured source for the admin API. Tip is the
+// latest chain height seen, or 0 before the first poll.
+type SourceStatus struct {
+	ID     string `json:"id"`
+	Chain  string `json:"chain"`
+	Paused bool   `json:"paused"`
+	Tip    uint64 `json:"tip"`
+}
+
+// Sources lists configured sources and whether they are paused.
+func (r *Runner) Sources() []SourceStatus {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	out := make([]SourceStatus, 0, len(r.scanners)+len(r.ingests))
+	for id, sc := range r.scanners {
+		out = append(out, SourceStatus{ID: id, Chain: sc.Chain(), Paused: r.paused[id], Tip: sc.Tip()})
+	}
+	for id, s := range r.ingests {
+		out = append(out, SourceStatus{ID: id, Chain: s.Chain(), Paused: r.paused[id], Tip: s.Tip()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Pause stops scanning a source until Resume is called.
+func (r *Runner) Pause(sourceID string) error {
+	return r.setPaused(sourceID, true)
+}
+
+// Resume restarts scanning a paused source.
+func (r *Runner) Resume(sourceID string) error {
+	return r.setPaused(sourceID, false)
+}
+
+func (r *Runner) setPaused(sourceID string, paused bool) error {
+	if !r.hasSource(sourceID) {
+		return fmt.Errorf("%w: %s", ErrUnknownSource, sourceID)
+	}
+	r.stateMu.Lock()
+	r.paused[sourceID] = paused
+	r.stateMu.Unlock()
+	return nil
+}
+
+func (r *Runner) isPaused(sourceID string) bool {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	return r.paused[sourceID]
+}
+
+func (r *Runner) hasSource(sourceID string) bool {
+	_, isScanner := r.scanners[sourceID]
+	_, isIngest := r.ingests[sourceID]
+	return isScanner || isIngest
+}
+
+// Skip advances a source past its next block/round without matching it and
+// returns the skipped height.
+func (r *Runner) Skip(ctx context.Context, sourceID string) (uint64, error) {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+	if sc, ok := r.scanners[sourceID]; ok {
+		return sc.SkipNext(ctx)
+	}
+	if s, ok := r.ingests[sourceID]; ok {
+		return s.SkipNext(ctx)
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownSource, sourceID)
+}
+
+// SetCursor moves a source's cursor to height and hash. It waits for the
+// current tick, so the scanner picks the cursor up on its next one.
+func (r *Runner) SetCursor(ctx context.Context, sourceID string, height uint64, hash string) error {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+	if !r.hasSource(sourceID) {
+		return fmt.Errorf("%w: %s", ErrUnknownSource, sourceID)
+	}
+	return r.store.UpsertCursor(ctx, sourceID, height, hash)
+}
+
+// Trigger requests an immediate tick; see Wake.
+func (r *Runner) Trigger() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Wake fires when Trigger has been called since the last receive.
+func (r *Runner) Wake() <-chan struct{} {
+	return r.wake
+}
+
+// RunOnce processes one eligible block/round per source, or a batch of them
+// for sources with max_blocks_per_tick, and the events ingest sources have
+// queued.
 func (r *Runner) RunOnce(ctx context.Context) error {
-	for id, sc := range r.evmScan {
-		if r.targetTo > 0 {
-			// stop if beyond target
-			h, _, ok, err := r.store.GetCursor(ctx, id)
-			if err != nil {
-				return err
-			}
-			if ok && h >= r.targetTo {
-				continue
-			}
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+
+	for id, sc := range r.scanners {
+		if err := r.scan(ctx, id, sc); err != nil {
+			return err
 		}
-		events, err := sc.ProcessNext(ctx)
+	}
+
+	return r.runIngests(ctx)
+}
+
+// scan processes the next block or batch of source id, unless it is paused,
+// backing off or already at the target height.
+func (r *Runner) scan(ctx context.Context, id string, sc Scanner) error {
+	if r.isPaused(id) || r.backingOff(id) {
+		return nil
+	}
+	if r.targetTo > 0 {
+		// stop if beyond target
+		h, _, ok, err := r.store.GetCursor(ctx, id)
 		if err != nil {
-			if err == evm.ErrReorgDetected {
-				continue
-			}
-			return fmt.Errorf("evm source %s: %w", id, err)
-		}
-		evs := make([]Event, 0, len(events))
-		for _, e := range events {
-			evs = append(evs, Event{
-				RuleID:   e.RuleID,
-				Chain:    e.Chain,
-				SourceID: e.SourceID,
-				Height:   e.Height,
-				Hash:     e.Hash,
-				TxHash:   e.TxHash,
-				LogIndex: e.LogIndex,
-				AppID:    0,
-				Args:     e.Args,
-			})
-		}
-		if err := r.handleEvents(ctx, evs); err != nil {
 			return err
 		}
+		if ok && h >= r.targetTo {
+			return nil
+		}
 	}
-
-	for id, sc := range r.algoScan {
-		if r.targetTo > 0 {
-			h, _, ok, err := r.store.GetCursor(ctx, id)
-			if err != nil {
-				return err
-			}
-			if ok && h >= r.targetTo {
-				continue
-			}
+	var rg reorg
+	rewound := false
+	err := r.pipeEvents(func(emit func(Event) error) error {
+		err := sc.ProcessNextFunc(ctx, emit)
+		if rg, rewound = asReorg(err); rewound {
+			return nil
 		}
-		events, err := sc.ProcessNext(ctx)
 		if err != nil {
-			if err == algorand.ErrReorgDetected {
-				continue
-			}
-			return fmt.Errorf("algorand source %s: %w", id, err)
-		}
-		evs := make([]Event, 0, len(events))
-		for _, e := range events {
-			evs = append(evs, Event{
-				RuleID:   e.RuleID,
-				Chain:    e.Chain,
-				SourceID: e.SourceID,
-				Height:   e.Height,
-				Hash:     e.Hash,
-				TxHash:   e.TxHash,
-				AppID:    e.AppID,
-				Args:     e.Args,
-			})
-		}
-		if err := r.handleEvents(ctx, evs); err != nil {
-			return err
+			return fmt.Errorf("%s source %s: %w", sc.Chain(), id, err)
 		}
+		return nil
+	}, func(ev Event) error {
+		return r.handleEvent(ctx, ev)
+	})
+	if err == nil && rewound {
+		err = r.notifyReorg(ctx, sc.Chain(), id, rg)
 	}
-
-	return nil
+	return r.sourceDone(ctx, id, err)
 }
 
 func (r *Runner) handleEvents(ctx context.Context, events []Event) error {
 	for _, ev := range events {
-		exec, ok := r.rules[ev.RuleID]
-		if !ok {
-			continue
+		if err := r.handleEvent(ctx, ev); err != nil {
+			return err
 		}
-		pass, err := allPredicates(exec.preds, ev.Args)
-		if err != nil || !pass {
+	}
+	return nil
+}
+
+// pipeEvents runs produce in its own goroutine and hands each event it emits
+// to handle through a channel of eventBuffer slots, so a block with thousands
+// of matches never sits in memory at once. After a handler error the rest of
+// the block is drained unhandled, as the scanner has already committed to it.
+func (r *Runner) pipeEvents(produce func(emit func(Event) error) error, handle func(Event) error) error {
+	ch := make(chan Event, r.eventBuffer)
+	produced := make(chan error, 1)
+	go func() {
+		defer close(ch)
+		produced <- produce(func(ev Event) error {
+			ch <- ev
+			return nil
+		})
+	}()
+
+	var handleErr error
+	for ev := range ch {
+		if handleErr != nil {
 			continue
 		}
-		if r.dryRun {
-			// No side effects in dry-run: skip dedupe and sends.
-			continue
+		handleErr = handle(ev)
+	}
+	if err := <-produced; err != nil {
+		return err
+	}
+	return handleErr
+}
+
+func (r *Runner) handleEvent(ctx context.Context, ev Event) error {
+	exec, ok := r.rules[ev.RuleID]
+	if !ok {
+		return nil
+	}
+	if r.annotator != nil {
+		r.annotator.Annotate(ctx, ev.Contract, ev.Args)
+	}
+	failed, err := allPredicates(exec.preds, ev.Args)
+	pass := failed < 0
+	if err != nil {
+		policy := exec.rule.OnEvalError
+		if policy == "" {
+			policy = config.EvalErrorDrop
+		}
+		r.metrics.PredicateError(exec.rule.ID)
+		r.log.Warn("predicate eval failed", "rule", exec.rule.ID, "source", ev.SourceID, "height", ev.Height, "tx", ev.TxHash, "policy", policy, "error", err)
+		rec := auditEvent(ev, exec.rule.ID, AuditEvalError)
+		rec.Detail = err.Error()
+		r.audit(ctx, rec)
+		switch policy {
+		case config.EvalErrorFail:
+			return fmt.Errorf("rule %s: %w", exec.rule.ID, err)
+		case config.EvalErrorAlert:
+			ev.Args["eval_error"] = err.Error()
+			pass = true
+		}
+	}
+	if !pass {
+		if err == nil {
+			rec := auditEvent(ev, exec.rule.ID, AuditPredicateFailed)
+			rec.Detail = exec.where[failed]
+			r.audit(ctx, rec)
 		}
-		now := r.nowFunc()
+		return nil
+	}
+	// The alert id doubles as the correlation id, so it is assigned before
+	// any suppression and every log line about this event can carry it.
+	alertID := newAlertID()
+	log := r.log.With("alert_id", alertID, "rule", exec.rule.ID)
+	payload := toSinkPayload(ev, exec.rule.ID)
+	payload.AlertID = alertID
+	payload.Explorer = r.explorers[ev.SourceID]
+	payload.Group = alertGroup(exec.rule, ev)
+	payload.Severity = exec.rule.Severity
+	auditAlert := func(decision string) {
+		rec := auditEvent(ev, exec.rule.ID, decision)
+		rec.AlertID = alertID
+		r.audit(ctx, rec)
+	}
+	auditAlert(AuditMatched)
+	if r.publisher != nil {
+		r.publisher.Publish(payload)
+	}
+	if r.dryRun {
+		// No side effects in dry-run: skip dedupe and sends.
+		return nil
+	}
+	now := r.nowFunc()
 
-		// Check rate limit if configured
-		if exec.rateLimit != nil {
-			if !exec.rateLimit.Allow(now) {
-				continue // Rate limited, skip this alert
-			}
+	silenced, err := r.store.IsSilenced(ctx, exec.rule.ID, now)
+	if err != nil {
+		return err
+	}
+	if silenced {
+		auditAlert(AuditSilenced)
+		return nil
+	}
+
+	// Check rate limit if configured
+	if exec.rateLimit != nil {
+		if !exec.rateLimit.Allow(now) {
+			auditAlert(AuditRateLimited)
+			r.countSuppressed(exec.rule.ID, reasonRateLimited)
+			return nil // Rate limited, skip this alert
 		}
+	}
 
-		if exec.rule.Dedupe != nil {
-			key := buildDedupeKey(exec.rule.Dedupe.Key, ev)
-			isDup, err := r.store.IsDuplicate(ctx, key, now)
-			if err != nil {
-				return err
-			}
-			if isDup {
-				continue
-			}
-			exp := now.Add(exec.ttl)
-			if exec.ttl == 0 {
-				exp = now.Add(24 * time.Hour)
-			}
-			if err := r.store.MarkDedupe(ctx, key, exp); err != nil {
-				return err
-			}
+	if exec.rule.Dedupe != nil {
+		key := buildDedupeKey(exec.rule.Dedupe.Key, ev)
+		isDup, err := r.store.IsDuplicate(ctx, key, now)
+		if err != nil {
+			return err
 		}
-		for _, sinkID := range exec.rule.Sinks {
-			s := r.sinks[sinkID]
-			if s == nil {
-				continue
-			}
-			if err := s.Send(ctx, toSinkPayload(ev, exec.rule.ID)); err != nil {
+		if isDup {
+			auditAlert(AuditDeduped)
+			r.countSuppressed(exec.rule.ID, reasonDuplicate)
+			return nil
+		}
+		exp := now.Add(exec.ttl)
+		if exec.ttl == 0 {
+			exp = now.Add(24 * time.Hour)
+		}
+		if err := r.store.MarkDedupe(ctx, key, exp); err != nil {
+			return err
+		}
+	}
+
+	sinks := exec.rule.Sinks
+	if ev.Backfill {
+		sinks = r.backfillSinks(sinks)
+	}
+	return r.record(ctx, log, storage.Alert{
+		ID:          alertID,
+		RuleID:      exec.rule.ID,
+		Fingerprint: fingerprint(ev),
+		TxHash:      ev.TxHash,
+		PayloadJSON: payloadJSON(payload),
+		CreatedAt:   now,
+		SourceID:    ev.SourceID,
+		Height:      ev.Height,
+	}, payload, sinks)
+}
+
+// record stores an alert and delivers it to sinks, through the dispatcher
+// when one is set and inline otherwise.
+func (r *Runner) record(ctx context.Context, log *slog.Logger, alert storage.Alert, payload sink.EventPayload, sinks []string) error {
+	if err := r.store.InsertAlert(ctx, alert); err != nil {
+		return err
+	}
+	alertID, now := alert.ID, alert.CreatedAt
+	log.Info("alert recorded", "sinks", sinks)
+	sinks, err := r.holdQuiet(ctx, log, alert, payload.Severity, sinks)
+	if err != nil {
+		return err
+	}
+	if r.dispatcher != nil {
+		for _, sinkID := range sinks {
+			if err := r.dispatcher.Enqueue(ctx, alertID, sinkID); err != nil {
 				return err
 			}
 		}
+		r.dispatcher.Notify()
+		return nil
+	}
+	for _, sinkID := range sinks {
+		s := r.sinks[sinkID]
+		if s == nil {
+			continue
+		}
+		sendErr := s.Send(ctx, payload)
+		status := "sent"
+		rec := storage.AuditRecord{RuleID: alert.RuleID, SourceID: alert.SourceID, Height: alert.Height, TxHash: alert.TxHash, AlertID: alertID, SinkID: sinkID, Decision: AuditSent}
+		if sendErr != nil {
+			status = "failed"
+			rec.Decision, rec.Detail = AuditFailed, sendErr.Error()
+			log.Warn("alert send failed", "sink", sinkID, "error", sendErr)
+		} else {
+			log.Info("alert sent", "sink", sinkID)
+		}
+		r.audit(ctx, rec)
+		if err := r.store.InsertSend(ctx, storage.Send{
+			AlertID:   alertID,
+			SinkID:    sinkID,
+			Status:    status,
+			CreatedAt: now,
+		}); err != nil {
+			return err
+		}
+		if sendErr != nil {
+			return sendErr
+		}
 	}
 	return nil
 }
 
-func allPredicates(preds []Predicate, args map[string]any) (bool, error) {
-	for _, p := range preds {
+// allPredicates returns the index of the first predicate args fail, or -1
+// when they pass them all.
+func allPredicates(preds []Predicate, args map[string]any) (int, error) {
+	for i, p := range preds {
 		ok, err := p(args)
 		if err != nil {
-			return false, err
+			return i, err
 		}
 		if !ok {
-			return false, nil
+			return i, nil
 		}
 	}
-	return true, nil
+	return -1, nil
 }
 
 func buildDedupeKey(pattern string, ev Event) string {
@@ -236,16 +590,61 @@ func buildDedupeKey(pattern string, ev Event) string {
 	return key
 }
 
+// alertGroup relates alerts for threading: a rule's alerts with the same
+// dedupe key share a group, and otherwise only repeats of one occurrence do.
+func alertGroup(rule config.Rule, ev Event) string {
+	if rule.Dedupe != nil {
+		return rule.ID + "|" + buildDedupeKey(rule.Dedupe.Key, ev)
+	}
+	return fingerprint(ev)
+}
+
+// fingerprint identifies the on-chain occurrence behind an alert, stable across replays.
+func fingerprint(ev Event) string {
+	logIndex := ""
+	if ev.LogIndex != nil {
+		logIndex = fmt.Sprintf("%d", *ev.LogIndex)
+	}
+	parts := []string{ev.RuleID, ev.SourceID, ev.TxHash, logIndex, fmt.Sprintf("%d", ev.AppID)}
+	// Items expanded from one batch log share its log index.
+	if i, ok := ev.Args["batch_index"]; ok {
+		parts = append(parts, fmt.Sprint(i))
+	}
+	// Algorand inner transactions share their top-level txid.
+	if p, ok := ev.Args["inner_path"]; ok {
+		parts = append(parts, fmt.Sprint(p))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:16])
+}
+
+func newAlertID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func payloadJSON(p sink.EventPayload) string {
+	out, err := json.Marshal(p)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
 func toSinkPayload(ev Event, ruleID string) sink.EventPayload {
 	return sink.EventPayload{
-		RuleID:   ruleID,
-		Chain:    ev.Chain,
-		SourceID: ev.SourceID,
-		Height:   ev.Height,
-		Hash:     ev.Hash,
-		TxHash:   ev.TxHash,
-		LogIndex: ev.LogIndex,
-		AppID:    ev.AppID,
-		Args:     ev.Args,
+		RuleID:    ruleID,
+		Chain:     ev.Chain,
+		SourceID:  ev.SourceID,
+		Height:    ev.Height,
+		Hash:      ev.Hash,
+		TxHash:    ev.TxHash,
+		LogIndex:  ev.LogIndex,
+		AppID:     ev.AppID,
+		Contract:  ev.Contract,
+		Timestamp: ev.Timestamp,
+		Args:      ev.Args,
+		Backfill:  ev.Backfill,
 	}
 }
@@ -2,12 +2,25 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/devblac/watch-tower/internal/config"
 	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/source/evm"
+	"github.com/devblac/watch-tower/internal/source/ingest"
 	"github.com/devblac/watch-tower/internal/storage"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 type fakeSink struct {
@@ -33,7 +46,7 @@ func TestRunnerPredicatesAndDryRun(t *testing.T) {
 	}
 	cfg := &config.Config{Rules: []config.Rule{rule}}
 	s := &fakeSink{}
-	runner, err := NewRunner(store, cfg, nil, nil, map[string]sink.Sender{"s1": s}, true, 0, 0)
+	runner, err := NewRunner(store, cfg, nil, map[string]sink.Sender{"s1": s}, true, 0, 0)
 	if err != nil {
 		t.Fatalf("runner: %v", err)
 	}
@@ -80,7 +93,7 @@ func TestRunnerRateLimit(t *testing.T) {
 	}
 	cfg := &config.Config{Rules: []config.Rule{rule}}
 	s := &fakeSink{}
-	runner, err := NewRunner(store, cfg, nil, nil, map[string]sink.Sender{"s1": s}, false, 0, 0)
+	runner, err := NewRunner(store, cfg, nil, map[string]sink.Sender{"s1": s}, false, 0, 0)
 	if err != nil {
 		t.Fatalf("runner: %v", err)
 	}
@@ -127,6 +140,237 @@ func TestRunnerRateLimit(t *testing.T) {
 	}
 }
 
+func TestRunnerSuppressionSummary(t *testing.T) {
+	store := newTestStore(t)
+	rule := config.Rule{
+		ID:        "usdc_whale",
+		Sinks:     []string{"s1"},
+		Dedupe:    &config.Dedupe{Key: "txhash", TTL: "1h"},
+		RateLimit: &config.RateLimit{Capacity: 3, Rate: 0.001},
+	}
+	cfg := &config.Config{Rules: []config.Rule{rule}}
+	s := &flakySink{}
+	runner, err := NewRunner(store, cfg, nil, map[string]sink.Sender{"s1": s}, false, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := runner.FlushSuppressed(ctx, time.Hour); err != nil || len(s.got) != 0 {
+		t.Fatalf("expected no summary without suppressions, got %d (%v)", len(s.got), err)
+	}
+	// Rate limiting runs first, so the deduped 0x1 spends a token too: 0x1 and
+	// 0x2 are sent, then 0x3 and 0x4 are rate limited.
+	for _, tx := range []string{"0x1", "0x1", "0x2", "0x3", "0x4"} {
+		if err := runner.handleEvents(ctx, []Event{{RuleID: "usdc_whale", TxHash: tx}}); err != nil {
+			t.Fatalf("handle %s: %v", tx, err)
+		}
+	}
+	if len(s.got) != 2 {
+		t.Fatalf("expected 2 alerts sent, got %d", len(s.got))
+	}
+
+	if err := runner.FlushSuppressed(ctx, time.Hour); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(s.got) != 3 {
+		t.Fatalf("expected a summary, got %d sends", len(s.got))
+	}
+	sum := s.got[2]
+	if sum.RuleID != SuppressedRuleID || sum.Args["rule"] != "usdc_whale" || sum.Args["count"] != 3 ||
+		sum.Args["duplicate"] != 1 || sum.Args["rate_limited"] != 2 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+	if want := "3 similar alerts for rule usdc_whale suppressed in the last 1h0m0s"; sum.Args["summary"] != want {
+		t.Fatalf("summary %q, want %q", sum.Args["summary"], want)
+	}
+
+	if err := runner.FlushSuppressed(ctx, time.Hour); err != nil || len(s.got) != 3 {
+		t.Fatalf("expected counts to reset after a flush, got %d sends (%v)", len(s.got), err)
+	}
+}
+
+func TestRunnerOnEvalError(t *testing.T) {
+	ctx := context.Background()
+	ev := func() []Event {
+		return []Event{{RuleID: "whale", TxHash: "0x1", Args: map[string]any{"value": "n/a"}}}
+	}
+	for _, tt := range []struct {
+		policy   string
+		wantErr  bool
+		wantSent int
+	}{
+		{"", false, 0},
+		{config.EvalErrorDrop, false, 0},
+		{config.EvalErrorAlert, false, 1},
+		{config.EvalErrorFail, true, 0},
+	} {
+		rule := config.Rule{ID: "whale", Sinks: []string{"s1"}, Match: config.MatchSpec{Where: []string{"value > 10"}}, OnEvalError: tt.policy}
+		s := &flakySink{}
+		runner, err := NewRunner(newTestStore(t), &config.Config{Rules: []config.Rule{rule}}, nil, map[string]sink.Sender{"s1": s}, false, 0, 0)
+		if err != nil {
+			t.Fatalf("runner: %v", err)
+		}
+		err = runner.handleEvents(ctx, ev())
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("policy %q: err = %v, want error %v", tt.policy, err, tt.wantErr)
+		}
+		if len(s.got) != tt.wantSent {
+			t.Fatalf("policy %q: %d alerts sent, want %d", tt.policy, len(s.got), tt.wantSent)
+		}
+		if tt.wantSent > 0 && s.got[0].Args["eval_error"] == nil {
+			t.Fatalf("policy %q: alert missing eval_error: %+v", tt.policy, s.got[0].Args)
+		}
+	}
+}
+
+func TestRunnerAudit(t *testing.T) {
+	store := newTestStore(t)
+	rule := config.Rule{
+		ID:     "whale",
+		Sinks:  []string{"s1"},
+		Match:  config.MatchSpec{Where: []string{"value > 10", " ", "to != 0x0"}},
+		Dedupe: &config.Dedupe{Key: "txhash", TTL: "1h"},
+	}
+	s := &flakySink{failures: 1}
+	runner, err := NewRunner(store, &config.Config{Rules: []config.Rule{rule}}, nil, map[string]sink.Sender{"s1": s}, false, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	runner.SetAuditTable(0)
+	ctx := context.Background()
+
+	events := []Event{
+		{RuleID: "whale", TxHash: "0x1", Args: map[string]any{"value": 50, "to": "0x0"}},
+		{RuleID: "whale", TxHash: "0x2", Args: map[string]any{"value": 50, "to": "0xb"}},
+		{RuleID: "whale", TxHash: "0x2", Args: map[string]any{"value": 50, "to": "0xb"}},
+		{RuleID: "whale", TxHash: "0x3", Args: map[string]any{"value": 50, "to": "0xb"}},
+	}
+	for _, ev := range events {
+		// The first send fails by design; its error is checked via the trail.
+		_ = runner.handleEvents(ctx, []Event{ev})
+	}
+
+	recs, err := store.ListAudit(ctx, storage.AuditFilter{})
+	if err != nil {
+		t.Fatalf("list audit: %v", err)
+	}
+	var got []string
+	for i := len(recs) - 1; i >= 0; i-- {
+		got = append(got, recs[i].TxHash+" "+recs[i].Decision+" "+recs[i].Detail)
+	}
+	want := []string{
+		"0x1 predicate_failed to != 0x0",
+		"0x2 matched ",
+		"0x2 failed sink down",
+		"0x2 matched ",
+		"0x2 deduped ",
+		"0x3 matched ",
+		"0x3 sent ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("audit trail:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if recs[0].SinkID != "s1" || recs[0].AlertID == "" {
+		t.Fatalf("send record missing sink or alert id: %+v", recs[0])
+	}
+}
+
+func TestRunnerQuietHours(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	cfg := &config.Config{
+		Rules: []config.Rule{
+			{ID: "whale", Sinks: []string{"slack", "pager"}, Severity: config.SeverityWarning},
+			{ID: "exploit", Sinks: []string{"slack"}, Severity: config.SeverityCritical},
+		},
+		Sinks: []config.Sink{{ID: "slack", QuietHours: &config.QuietHours{
+			Start: "23:00", End: "07:00", Timezone: "Europe/Madrid", Except: []string{config.SeverityCritical},
+		}}},
+	}
+	slack, pager := &flakySink{}, &flakySink{}
+	runner, err := NewRunner(store, cfg, nil, map[string]sink.Sender{"slack": slack, "pager": pager}, false, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	madrid, _ := time.LoadLocation("Europe/Madrid")
+	now := time.Date(2026, 1, 15, 23, 30, 0, 0, madrid)
+	runner.nowFunc = func() time.Time { return now }
+
+	for _, ev := range []Event{{RuleID: "whale", TxHash: "0x1"}, {RuleID: "exploit", TxHash: "0x2"}, {RuleID: "whale", TxHash: "0x3"}} {
+		if err := runner.handleEvents(ctx, []Event{ev}); err != nil {
+			t.Fatalf("handle %s: %v", ev.TxHash, err)
+		}
+	}
+	if len(pager.got) != 2 {
+		t.Fatalf("sinks without quiet hours should get every alert, got %d", len(pager.got))
+	}
+	if len(slack.got) != 1 || slack.got[0].RuleID != "exploit" || slack.got[0].Severity != config.SeverityCritical {
+		t.Fatalf("expected only the critical alert during quiet hours, got %+v", slack.got)
+	}
+
+	now = now.Add(7 * time.Hour) // 06:30, still quiet
+	if err := runner.FlushQuietHours(ctx); err != nil || len(slack.got) != 1 {
+		t.Fatalf("expected no digest before the window ends, got %d sends (%v)", len(slack.got), err)
+	}
+	now = now.Add(time.Hour)
+	if err := runner.FlushQuietHours(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(slack.got) != 2 {
+		t.Fatalf("expected a digest, got %d sends", len(slack.got))
+	}
+	digest := slack.got[1]
+	if want := "2 alerts held during quiet hours: whale 2"; digest.RuleID != DigestRuleID || digest.Args["count"] != 2 || digest.Args["summary"] != want {
+		t.Fatalf("unexpected digest: %+v", digest)
+	}
+	if err := runner.FlushQuietHours(ctx); err != nil || len(slack.got) != 2 {
+		t.Fatalf("expected held alerts to be released, got %d sends (%v)", len(slack.got), err)
+	}
+}
+
+func TestRunnerHeartbeat(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	if err := store.UpsertCursor(ctx, "evm_main", 8, "0x08"); err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+	since := time.Now().Add(-time.Hour)
+	if err := store.InsertAlert(ctx, storage.Alert{ID: "a1", RuleID: "whale", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("insert alert: %v", err)
+	}
+	// With two confirmations on a tip of 10, block 8 is as far as it can go.
+	sc, err := evm.NewScanner(tipClient{tip: 10}, store, config.Source{ID: "evm_main", Type: "evm"}, 2, nil, nil)
+	if err != nil {
+		t.Fatalf("scanner: %v", err)
+	}
+	ops := &flakySink{}
+	runner, err := NewRunner(store, &config.Config{}, map[string]Scanner{"evm_main": NewEVMScanner(sc)}, map[string]sink.Sender{"ops": ops}, false, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	if err := runner.RunOnce(ctx); err != nil {
+		t.Fatalf("run once: %v", err)
+	}
+
+	if err := runner.Heartbeat(ctx, "ops", since); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	if len(ops.got) != 1 {
+		t.Fatalf("expected one heartbeat, got %d", len(ops.got))
+	}
+	got := ops.got[0]
+	if want := "watch-tower alive, 1 alerts, lag: evm_main 2 blocks"; got.RuleID != HeartbeatRuleID || got.Args["summary"] != want {
+		t.Fatalf("unexpected heartbeat: %+v", got)
+	}
+	if alerts, _ := store.ListAlerts(ctx, storage.AlertFilter{}); len(alerts) != 1 {
+		t.Fatalf("heartbeat should not be recorded as an alert, got %d alerts", len(alerts))
+	}
+	if err := runner.Heartbeat(ctx, "missing", since); err == nil {
+		t.Fatalf("expected error for unknown sink")
+	}
+}
+
 func newTestStore(t *testing.T) *storage.Store {
 	t.Helper()
 	store, err := storage.Open(t.TempDir() + "/db.sqlite")
@@ -136,3 +380,386 @@ func newTestStore(t *testing.T) *storage.Store {
 	t.Cleanup(func() { _ = store.Close() })
 	return store
 }
+
+// fakeScanner emits one event per block and counts skips and rule swaps.
+type fakeScanner struct {
+	height   uint64
+	skipped  int
+	prepared int
+	rules    []config.Rule
+}
+
+func (f *fakeScanner) Chain() string { return "fake" }
+
+func (f *fakeScanner) ProcessNextFunc(_ context.Context, emit func(Event) error) error {
+	f.height++
+	return emit(Event{RuleID: "r1", Chain: "fake", SourceID: "fake_main", Height: f.height, TxHash: fmt.Sprintf("0x%d", f.height), Args: map[string]any{}})
+}
+
+func (f *fakeScanner) SkipNext(context.Context) (uint64, error) {
+	f.height++
+	f.skipped++
+	return f.height, nil
+}
+
+func (f *fakeScanner) Tip() uint64 { return f.height }
+
+func (f *fakeScanner) PrepareRules(rules []config.Rule) (func(), error) {
+	return func() {
+		f.prepared++
+		f.rules = rules
+	}, nil
+}
+
+func (f *fakeScanner) SetStopHeight(uint64) {}
+
+func TestRunnerScansAnyScanner(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	sc := &fakeScanner{}
+	s := &fakeSink{}
+	cfg := &config.Config{Rules: []config.Rule{{ID: "r1", Source: "fake_main", Sinks: []string{"s1"}}}}
+	runner, err := NewRunner(store, cfg, map[string]Scanner{"fake_main": sc}, map[string]sink.Sender{"s1": s}, false, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+
+	if err := runner.RunOnce(ctx); err != nil {
+		t.Fatalf("run once: %v", err)
+	}
+	if s.count != 1 {
+		t.Fatalf("expected one alert, got %d", s.count)
+	}
+	if _, err := runner.Skip(ctx, "fake_main"); err != nil || sc.skipped != 1 {
+		t.Fatalf("skip: %v (skipped %d)", err, sc.skipped)
+	}
+	if err := runner.SetCursor(ctx, "fake_main", 10, "0x0a"); err != nil {
+		t.Fatalf("set cursor: %v", err)
+	}
+	if h, _, _, _ := store.GetCursor(ctx, "fake_main"); h != 10 {
+		t.Fatalf("expected cursor at 10, got %d", h)
+	}
+	if err := runner.SetCursor(ctx, "nope", 1, ""); !errors.Is(err, ErrUnknownSource) {
+		t.Fatalf("expected unknown source, got %v", err)
+	}
+	if st := runner.Sources(); len(st) != 1 || st[0].Chain != "fake" || st[0].Tip != 2 {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if err := runner.DisableRule(ctx, "r1"); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if sc.prepared != 1 || len(sc.rules) != 0 {
+		t.Fatalf("expected rules swapped into the scanner, got %d swaps of %+v", sc.prepared, sc.rules)
+	}
+}
+
+func TestRunnerPauseSkipsSource(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	src := config.Source{ID: "evm_main", Type: "evm"}
+	sc, err := evm.NewScanner(failingClient{}, store, src, 0, nil, nil)
+	if err != nil {
+		t.Fatalf("scanner: %v", err)
+	}
+	runner, err := NewRunner(store, &config.Config{}, map[string]Scanner{"evm_main": NewEVMScanner(sc)}, nil, false, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+
+	if err := runner.Pause("missing"); !errors.Is(err, ErrUnknownSource) {
+		t.Fatalf("expected unknown source, got %v", err)
+	}
+	if err := runner.Pause("evm_main"); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	// A paused source is never polled, so the failing client is not reached.
+	if err := runner.RunOnce(ctx); err != nil {
+		t.Fatalf("run once while paused: %v", err)
+	}
+	if st := runner.Sources(); len(st) != 1 || !st[0].Paused {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if err := runner.Resume("evm_main"); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	runner.SetFailureBudget(1)
+	if err := runner.RunOnce(ctx); err == nil {
+		t.Fatalf("expected resumed source to be polled")
+	}
+}
+
+func TestRunnerBacksOffFailingSource(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &countingClient{}
+	sc, err := evm.NewScanner(client, store, config.Source{ID: "evm_main", Type: "evm"}, 0, nil, nil)
+	if err != nil {
+		t.Fatalf("scanner: %v", err)
+	}
+	runner, err := NewRunner(store, &config.Config{}, map[string]Scanner{"evm_main": NewEVMScanner(sc)}, nil, false, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	now := time.Now()
+	runner.nowFunc = func() time.Time { return now }
+	runner.SetFailureBudget(3)
+
+	if err := runner.RunOnce(ctx); err != nil {
+		t.Fatalf("first failure should back off, got %v", err)
+	}
+	if err := runner.RunOnce(ctx); err != nil || client.calls != 1 {
+		t.Fatalf("expected source skipped during backoff, calls=%d err=%v", client.calls, err)
+	}
+	now = now.Add(maxBackoff)
+	if err := runner.RunOnce(ctx); err != nil || client.calls != 2 {
+		t.Fatalf("expected retry after backoff, calls=%d err=%v", client.calls, err)
+	}
+	now = now.Add(maxBackoff)
+	if err := runner.RunOnce(ctx); err == nil {
+		t.Fatalf("expected error once the failure budget is spent")
+	}
+
+	// A success clears the streak.
+	client.healthy = true
+	runner.backoffs["evm_main"].until = time.Time{}
+	if err := runner.RunOnce(ctx); err != nil {
+		t.Fatalf("healthy tick: %v", err)
+	}
+	if _, ok := runner.backoffs["evm_main"]; ok {
+		t.Fatalf("expected backoff reset after success")
+	}
+}
+
+func TestRunnerNotifiesReorg(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	if err := store.UpsertCursor(ctx, "evm_main", 5, "0xold"); err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+	// An alert already delivered from the block that is about to be orphaned.
+	orig := sink.EventPayload{RuleID: "whale", SourceID: "evm_main", Height: 5, TxHash: "0xfeed"}
+	if err := store.InsertAlert(ctx, storage.Alert{ID: "a1", RuleID: "whale", TxHash: "0xfeed", PayloadJSON: payloadJSON(orig), SourceID: "evm_main", Height: 5}); err != nil {
+		t.Fatalf("insert alert: %v", err)
+	}
+	if err := store.InsertSend(ctx, storage.Send{AlertID: "a1", SinkID: "chat", Status: "sent"}); err != nil {
+		t.Fatalf("insert send: %v", err)
+	}
+	newParent := common.HexToHash("0x0a")
+	client := reorgClient{parent: newParent}
+	sc, err := evm.NewScanner(client, store, config.Source{ID: "evm_main", Type: "evm"}, 0, nil, nil)
+	if err != nil {
+		t.Fatalf("scanner: %v", err)
+	}
+	ops, chat, pager := &flakySink{}, &flakySink{}, &flakySink{}
+	cfg := &config.Config{
+		Global: config.GlobalConfig{ReorgSinks: []string{"ops"}},
+		Rules: []config.Rule{
+			{ID: "any_reorg", Source: config.AllSources, Match: config.MatchSpec{Type: config.MatchReorg}, Sinks: []string{"pager"}},
+			{ID: "deep_reorg", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchReorg, Where: []string{"depth >= 3"}}, Sinks: []string{"pager"}},
+		},
+	}
+	runner, err := NewRunner(store, cfg, map[string]Scanner{"evm_main": NewEVMScanner(sc)}, map[string]sink.Sender{"ops": ops, "chat": chat, "pager": pager}, false, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+
+	if err := runner.RunOnce(ctx); err != nil {
+		t.Fatalf("run once: %v", err)
+	}
+	if len(ops.got) != 1 {
+		t.Fatalf("expected one reorg alert, got %d", len(ops.got))
+	}
+	got := ops.got[0]
+	if got.RuleID != ReorgRuleID || got.SourceID != "evm_main" || got.Height != 6 || got.Args["depth"] != uint64(1) || got.Args["old_hash"] != "0xold" || got.Args["new_hash"] != newParent.Hex() {
+		t.Fatalf("unexpected reorg payload: %+v", got)
+	}
+	if len(chat.got) != 1 || chat.got[0].Retracts != "a1" || chat.got[0].TxHash != "0xfeed" {
+		t.Fatalf("expected retraction of a1 on its original sink, got %+v", chat.got)
+	}
+	if left, err := store.ReorgedAlerts(ctx, "evm_main", 5, 5); err != nil || len(left) != 0 {
+		t.Fatalf("expected a1 marked retracted, got %+v err=%v", left, err)
+	}
+	// Reorg rules see the rewind as an event; the depth filter holds back
+	// the second.
+	if len(pager.got) != 1 || pager.got[0].RuleID != "any_reorg" || pager.got[0].Height != 6 || pager.got[0].Args["from_height"] != uint64(5) {
+		t.Fatalf("expected one reorg rule alert, got %+v", pager.got)
+	}
+}
+
+func TestRunnerBackfillSkipsSinks(t *testing.T) {
+	store := newTestStore(t)
+	cfg := &config.Config{
+		Rules: []config.Rule{{ID: "whale", Sinks: []string{"pager", "archive"}}},
+		Sinks: []config.Sink{{ID: "pager", SkipBackfill: true}, {ID: "archive"}},
+	}
+	pager, archive := &flakySink{}, &flakySink{}
+	runner, err := NewRunner(store, cfg, nil, map[string]sink.Sender{"pager": pager, "archive": archive}, false, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	evs := []Event{
+		{RuleID: "whale", TxHash: "0x1", Args: map[string]any{}},
+		{RuleID: "whale", TxHash: "0x2", Args: map[string]any{}, Backfill: true},
+	}
+	if err := runner.handleEvents(context.Background(), evs); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if len(pager.got) != 1 || pager.got[0].Backfill {
+		t.Fatalf("expected only the live alert on the pager, got %+v", pager.got)
+	}
+	if len(archive.got) != 2 || archive.got[0].Backfill || !archive.got[1].Backfill {
+		t.Fatalf("expected both alerts on the archive, backfill marked, got %+v", archive.got)
+	}
+}
+
+func TestPipeEventsBoundsBuffer(t *testing.T) {
+	runner := &Runner{eventBuffer: 2}
+	emitted, handled := 0, 0
+	var mu sync.Mutex
+	err := runner.pipeEvents(func(emit func(Event) error) error {
+		for i := 0; i < 50; i++ {
+			if err := emit(Event{RuleID: "r1"}); err != nil {
+				return err
+			}
+			mu.Lock()
+			emitted++
+			mu.Unlock()
+		}
+		return nil
+	}, func(Event) error {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		// The event being handled plus a full buffer is the most in flight.
+		if ahead := emitted - handled; ahead > runner.eventBuffer+1 {
+			t.Errorf("producer ran %d events ahead of the handler", ahead)
+		}
+		handled++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	if handled != 50 {
+		t.Fatalf("expected 50 handled, got %d", handled)
+	}
+
+	// A handler error stops handling but lets the producer finish the block.
+	calls, done := 0, false
+	err = runner.pipeEvents(func(emit func(Event) error) error {
+		for i := 0; i < 10; i++ {
+			_ = emit(Event{})
+		}
+		done = true
+		return nil
+	}, func(Event) error {
+		calls++
+		return errors.New("sink down")
+	})
+	if err == nil || calls != 1 || !done {
+		t.Fatalf("expected one failed handle and a finished producer, got err=%v calls=%d done=%v", err, calls, done)
+	}
+}
+
+// reorgClient reports a chain whose block 6 no longer builds on the stored cursor.
+type reorgClient struct {
+	parent common.Hash
+}
+
+func (c reorgClient) HeaderByNumber(_ context.Context, n *big.Int) (*types.Header, error) {
+	if n == nil {
+		return &types.Header{Number: big.NewInt(10)}, nil
+	}
+	return &types.Header{Number: n, ParentHash: c.parent}, nil
+}
+
+func (reorgClient) FilterLogs(context.Context, ethereum.FilterQuery) ([]types.Log, error) {
+	return nil, nil
+}
+
+// tipClient serves empty headers up to tip.
+type tipClient struct{ tip uint64 }
+
+func (c tipClient) HeaderByNumber(_ context.Context, n *big.Int) (*types.Header, error) {
+	if n == nil {
+		n = new(big.Int).SetUint64(c.tip)
+	}
+	return &types.Header{Number: n}, nil
+}
+
+func (tipClient) FilterLogs(context.Context, ethereum.FilterQuery) ([]types.Log, error) {
+	return nil, nil
+}
+
+// countingClient fails until healthy is set, counting header requests.
+type countingClient struct {
+	calls   int
+	healthy bool
+}
+
+func (c *countingClient) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	c.calls++
+	if !c.healthy {
+		return nil, errors.New("rpc down")
+	}
+	return &types.Header{Number: big.NewInt(0)}, nil
+}
+
+func (c *countingClient) FilterLogs(context.Context, ethereum.FilterQuery) ([]types.Log, error) {
+	return nil, nil
+}
+
+// failingClient errors on every RPC call.
+type failingClient struct{}
+
+func (failingClient) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	return nil, errors.New("rpc down")
+}
+
+func (failingClient) FilterLogs(context.Context, ethereum.FilterQuery) ([]types.Log, error) {
+	return nil, errors.New("rpc down")
+}
+
+func TestRunnerHandlesIngestedEvents(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	src := config.Source{ID: "hooks", Type: "ingest", Listen: ":0", Secret: "k"}
+	rule := config.Rule{ID: "big", Source: "hooks", Match: config.MatchSpec{Type: config.MatchIngest, Where: []string{"amount > 10"}}, Sinks: []string{"s1"}}
+	cfg := &config.Config{Sources: []config.Source{src}, Rules: []config.Rule{rule}}
+	s, err := ingest.NewSource(store, src, cfg.Rules)
+	if err != nil {
+		t.Fatalf("ingest source: %v", err)
+	}
+	fs := &fakeSink{}
+	runner, err := NewRunner(store, cfg, nil, map[string]sink.Sender{"s1": fs}, false, 0, 0)
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	runner.SetIngest("hooks", s)
+
+	body := `[{"id":"a","args":{"amount":50}},{"id":"b","args":{"amount":5}}]`
+	req := httptest.NewRequest(http.MethodPost, ingest.Path("hooks"), strings.NewReader(body))
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(ingest.DefaultTimestampHeader, ts)
+	req.Header.Set(ingest.DefaultSignatureHeader, ingest.Sign([]byte("k"), ts, []byte(body)))
+	rec := httptest.NewRecorder()
+	ingest.Handler(map[string]*ingest.Source{"hooks": s}).ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("post: %d %s", rec.Code, rec.Body)
+	}
+	select {
+	case <-runner.Wake():
+	default:
+		t.Fatal("expected queued events to trigger a tick")
+	}
+
+	if err := runner.RunOnce(ctx); err != nil {
+		t.Fatalf("run once: %v", err)
+	}
+	if fs.count != 1 {
+		t.Fatalf("expected only the event passing the predicate sent, got %d", fs.count)
+	}
+	if st := runner.Sources(); len(st) != 1 || st[0].Chain != ingest.Chain {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+}
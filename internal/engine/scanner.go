@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/source/evm"
+)
+
+// Scanner is a chain source the runner scans, one block or batch of blocks
+// per tick. Each source package's scanner is wrapped to emit Events: EVM
+// ones with NewEVMScanner, the others by their entry in Chains.
+type Scanner interface {
+	// Chain is the source's chain, as set on its events.
+	Chain() string
+	// ProcessNextFunc scans the next confirmed block, or batch of them, and
+	// passes each matched event to emit. A rewind after a reorg is reported
+	// as a source.ReorgError.
+	ProcessNextFunc(ctx context.Context, emit func(Event) error) error
+	// SkipNext advances past the next block without matching it and returns
+	// its height.
+	SkipNext(ctx context.Context) (uint64, error)
+	// Tip is the latest chain height seen, or 0 before the first poll.
+	Tip() uint64
+	// PrepareRules builds matchers for rules; commit installs them.
+	PrepareRules(rules []config.Rule) (commit func(), err error)
+	// SetStopHeight makes the scanner stop after height.
+	SetStopHeight(height uint64)
+}
+
+// sourceScanner is the scanner of a source package, which emits
+// source.Events.
+type sourceScanner interface {
+	ProcessNextFunc(ctx context.Context, emit func(source.Event) error) error
+	SkipNext(ctx context.Context) (uint64, error)
+	Tip() uint64
+	PrepareRules(rules []config.Rule) (commit func(), err error)
+	SetStopHeight(height uint64)
+}
+
+// chainScanner adapts a source package's scanner on chain to Scanner.
+type chainScanner struct {
+	sourceScanner
+	chain string
+}
+
+// NewEVMScanner wraps sc for NewRunner.
+func NewEVMScanner(sc *evm.Scanner) Scanner {
+	return chainScanner{sc, evm.Chain}
+}
+
+// evmScanner returns the EVM scanner behind sc. Backfill and ReloadABIs
+// only apply to these.
+func evmScanner(sc Scanner) (*evm.Scanner, bool) {
+	c, ok := sc.(chainScanner)
+	if !ok {
+		return nil, false
+	}
+	e, ok := c.sourceScanner.(*evm.Scanner)
+	return e, ok
+}
+
+func (s chainScanner) Chain() string { return s.chain }
+
+func (s chainScanner) ProcessNextFunc(ctx context.Context, emit func(Event) error) error {
+	return s.sourceScanner.ProcessNextFunc(ctx, func(e source.Event) error {
+		return emit(event(e))
+	})
+}
+
+// event is e as the runner handles it.
+func event(e source.Event) Event {
+	return Event{
+		RuleID:    e.RuleID,
+		Chain:     e.Chain,
+		SourceID:  e.SourceID,
+		Height:    e.Height,
+		Hash:      e.Hash,
+		TxHash:    e.TxHash,
+		LogIndex:  e.LogIndex,
+		AppID:     e.AppID,
+		Contract:  e.Contract,
+		Timestamp: e.Timestamp,
+		Args:      e.Args,
+	}
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// SuppressedRuleID is the rule id carried by suppression summary alerts.
+const SuppressedRuleID = "suppressed"
+
+// Suppression reasons counted for summaries. Silenced alerts are not
+// counted: an operator muted them on purpose.
+const (
+	reasonDuplicate   = "duplicate"
+	reasonRateLimited = "rate_limited"
+)
+
+// countSuppressed notes an alert dropped by dedupe or rate limiting.
+func (r *Runner) countSuppressed(ruleID, reason string) {
+	r.suppressMu.Lock()
+	defer r.suppressMu.Unlock()
+	if r.suppressed == nil {
+		r.suppressed = map[string]map[string]int{}
+	}
+	if r.suppressed[ruleID] == nil {
+		r.suppressed[ruleID] = map[string]int{}
+	}
+	r.suppressed[ruleID][reason]++
+}
+
+// FlushSuppressed sends each rule's sinks one summary of the alerts dedupe
+// and rate limiting dropped since the previous flush, then resets the
+// counts. window is only used to word the summary.
+func (r *Runner) FlushSuppressed(ctx context.Context, window time.Duration) error {
+	r.suppressMu.Lock()
+	counts := r.suppressed
+	r.suppressed = nil
+	r.suppressMu.Unlock()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	r.rulesMu.RLock()
+	sinks := make(map[string][]string, len(r.ruleSpecs))
+	for _, rule := range r.ruleSpecs {
+		sinks[rule.ID] = rule.Sinks
+	}
+	r.rulesMu.RUnlock()
+
+	ruleIDs := make([]string, 0, len(counts))
+	for id := range counts {
+		ruleIDs = append(ruleIDs, id)
+	}
+	sort.Strings(ruleIDs)
+	for _, ruleID := range ruleIDs {
+		// A rule removed since its alerts were dropped has nowhere to report.
+		if len(sinks[ruleID]) == 0 {
+			continue
+		}
+		byReason := counts[ruleID]
+		total := byReason[reasonDuplicate] + byReason[reasonRateLimited]
+		alertID := newAlertID()
+		now := r.nowFunc()
+		payload := sink.EventPayload{
+			RuleID:    SuppressedRuleID,
+			Timestamp: now,
+			AlertID:   alertID,
+			Group:     SuppressedRuleID + "|" + ruleID,
+			Args: map[string]any{
+				"rule":         ruleID,
+				"count":        total,
+				"duplicate":    byReason[reasonDuplicate],
+				"rate_limited": byReason[reasonRateLimited],
+				"window":       window.String(),
+				"summary":      fmt.Sprintf("%d similar alerts for rule %s suppressed in the last %s", total, ruleID, window),
+			},
+		}
+		log := r.log.With("alert_id", alertID, "rule", SuppressedRuleID)
+		if r.publisher != nil {
+			r.publisher.Publish(payload)
+		}
+		if err := r.record(ctx, log, storage.Alert{
+			ID:          alertID,
+			RuleID:      SuppressedRuleID,
+			Fingerprint: alertID,
+			PayloadJSON: payloadJSON(payload),
+			CreatedAt:   now,
+		}, payload, sinks[ruleID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunSuppressionSummaries flushes suppression summaries every interval until
+// ctx is cancelled. Failures are passed to onErr (which may be nil); the
+// counts of a failed flush are dropped.
+func (r *Runner) RunSuppressionSummaries(ctx context.Context, every time.Duration, onErr func(error)) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.FlushSuppressed(ctx, every); err != nil && onErr != nil && ctx.Err() == nil {
+			onErr(err)
+		}
+	}
+}
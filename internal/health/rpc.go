@@ -5,21 +5,21 @@ import (
 	"fmt"
 	"math/big"
 
-	"github.com/devblac/watch-tower/internal/source/algorand"
 	"github.com/devblac/watch-tower/internal/source/evm"
 )
 
 // RPCChecker combines multiple RPC health checks.
 type RPCChecker struct {
-	evmClients      map[string]evm.BlockClient
-	algorandClients map[string]algorand.AlgodClient
+	evmClients map[string]evm.BlockClient
+	pings      map[string]func(context.Context) error
 }
 
-// NewRPCChecker creates a checker for multiple RPC sources.
-func NewRPCChecker(evmClients map[string]evm.BlockClient, algorandClients map[string]algorand.AlgodClient) *RPCChecker {
+// NewRPCChecker creates a checker for multiple RPC sources. Sources of other
+// chains are checked by calling their ping, keyed by source id.
+func NewRPCChecker(evmClients map[string]evm.BlockClient, pings map[string]func(context.Context) error) *RPCChecker {
 	return &RPCChecker{
-		evmClients:      evmClients,
-		algorandClients: algorandClients,
+		evmClients: evmClients,
+		pings:      pings,
 	}
 }
 
@@ -32,9 +32,9 @@ func (c *RPCChecker) Ping(ctx context.Context) error {
 			continue
 		}
 	}
-	for id, cli := range c.algorandClients {
-		if _, err := cli.Status().Do(ctx); err != nil {
-			lastErr = fmt.Errorf("algorand source %s: %w", id, err)
+	for id, ping := range c.pings {
+		if err := ping(ctx); err != nil {
+			lastErr = fmt.Errorf("source %s: %w", id, err)
 			continue
 		}
 	}
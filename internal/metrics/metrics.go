@@ -3,6 +3,7 @@ package metrics
 import (
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -14,6 +15,9 @@ type Metrics struct {
 	alertsSent      prometheus.Counter
 	alertsDropped   prometheus.Counter
 	errors          prometheus.Counter
+	predicateErrors *prometheus.CounterVec
+	watchlistSize   *prometheus.GaugeVec
+	watchlistLoaded *prometheus.GaugeVec
 }
 
 var (
@@ -41,12 +45,27 @@ func Init() *Metrics {
 				Name: "watch_tower_errors_total",
 				Help: "Total number of errors encountered",
 			}),
+			predicateErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: "watch_tower_predicate_errors_total",
+				Help: "Number of events whose where predicates could not be evaluated",
+			}, []string{"rule"}),
+			watchlistSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "watch_tower_watchlist_addresses",
+				Help: "Number of addresses loaded from a rule's addresses_from",
+			}, []string{"rule"}),
+			watchlistLoaded: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "watch_tower_watchlist_last_refresh_timestamp_seconds",
+				Help: "Unix time of a rule's last successful watchlist refresh",
+			}, []string{"rule"}),
 		}
 		prometheus.MustRegister(
 			metrics.blocksProcessed,
 			metrics.alertsSent,
 			metrics.alertsDropped,
 			metrics.errors,
+			metrics.predicateErrors,
+			metrics.watchlistSize,
+			metrics.watchlistLoaded,
 		)
 	})
 	return metrics
@@ -80,6 +99,21 @@ func (m *Metrics) Errors() {
 	}
 }
 
+// PredicateError counts an event whose predicates failed to evaluate.
+func (m *Metrics) PredicateError(ruleID string) {
+	if m != nil {
+		m.predicateErrors.WithLabelValues(ruleID).Inc()
+	}
+}
+
+// WatchlistRefreshed records a successful reload of a rule's watchlist.
+func (m *Metrics) WatchlistRefreshed(ruleID string, size int, at time.Time) {
+	if m != nil {
+		m.watchlistSize.WithLabelValues(ruleID).Set(float64(size))
+		m.watchlistLoaded.WithLabelValues(ruleID).Set(float64(at.Unix()))
+	}
+}
+
 // Handler returns an HTTP handler for /metrics endpoint.
 func Handler() http.Handler {
 	return promhttp.Handler()
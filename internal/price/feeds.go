@@ -0,0 +1,117 @@
+package price
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// DefaultCoinGeckoURL is the public CoinGecko API.
+const DefaultCoinGeckoURL = "https://api.coingecko.com/api/v3"
+
+// CoinGecko reads prices from the CoinGecko simple price endpoint.
+type CoinGecko struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewCoinGecko builds a CoinGecko feed; an empty baseURL uses the public API.
+func NewCoinGecko(baseURL, apiKey string) *CoinGecko {
+	if baseURL == "" {
+		baseURL = DefaultCoinGeckoURL
+	}
+	return &CoinGecko{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// USD implements Feed.
+func (c *CoinGecko) USD(ctx context.Context, token config.PriceToken) (float64, error) {
+	q := url.Values{"ids": {token.CoinGeckoID}, "vs_currencies": {"usd"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/simple/price?"+q.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.apiKey != "" {
+		header := "x-cg-demo-api-key"
+		if strings.Contains(c.baseURL, "pro-api") {
+			header = "x-cg-pro-api-key"
+		}
+		req.Header.Set(header, c.apiKey)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("coingecko: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("coingecko: status %d", resp.StatusCode)
+	}
+	var body map[string]map[string]float64
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("coingecko: decode: %w", err)
+	}
+	p, ok := body[token.CoinGeckoID]["usd"]
+	if !ok {
+		return 0, fmt.Errorf("coingecko: no usd price for %s", token.CoinGeckoID)
+	}
+	return p, nil
+}
+
+// Chainlink reads prices from Chainlink aggregator contracts.
+type Chainlink struct {
+	caller ethereum.ContractCaller
+}
+
+// NewChainlink dials the RPC endpoint used for feed calls.
+func NewChainlink(rpcURL string) (*Chainlink, error) {
+	c, err := ethclient.Dial(rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("dial price rpc: %w", err)
+	}
+	return &Chainlink{caller: c}, nil
+}
+
+var (
+	// latestRoundData() and decimals() selectors of AggregatorV3Interface.
+	selLatestRoundData = common.FromHex("0xfeaf968c")
+	selDecimals        = common.FromHex("0x313ce567")
+)
+
+// USD implements Feed.
+func (c *Chainlink) USD(ctx context.Context, token config.PriceToken) (float64, error) {
+	feed := common.HexToAddress(token.ChainlinkFeed)
+	dec, err := c.caller.CallContract(ctx, ethereum.CallMsg{To: &feed, Data: selDecimals}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("chainlink decimals: %w", err)
+	}
+	round, err := c.caller.CallContract(ctx, ethereum.CallMsg{To: &feed, Data: selLatestRoundData}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("chainlink latestRoundData: %w", err)
+	}
+	if len(dec) < 32 || len(round) < 64 {
+		return 0, fmt.Errorf("chainlink: short response from %s", token.ChainlinkFeed)
+	}
+	// answer is the second word of (roundId, answer, startedAt, updatedAt, answeredInRound).
+	answer := new(big.Int).SetBytes(round[32:64])
+	if round[32]&0x80 != 0 {
+		return 0, fmt.Errorf("chainlink: negative answer from %s", token.ChainlinkFeed)
+	}
+	decimals := new(big.Int).SetBytes(dec[:32]).Int64()
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(answer), big.NewFloat(math.Pow10(int(decimals)))).Float64()
+	return f, nil
+}
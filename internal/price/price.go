@@ -0,0 +1,157 @@
+// Package price annotates token transfer events with their USD value, using
+// cached prices from CoinGecko or Chainlink feeds.
+package price
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+)
+
+// Feed fetches the current USD price of a token.
+type Feed interface {
+	USD(ctx context.Context, token config.PriceToken) (float64, error)
+}
+
+type cached struct {
+	price float64
+	at    time.Time
+}
+
+// Enricher adds value_usd to the args of events from configured tokens.
+// It is safe for concurrent use.
+type Enricher struct {
+	tokens    map[string]config.PriceToken
+	coingecko Feed
+	chainlink Feed
+	ttl       time.Duration
+	nowFunc   func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+// New builds an enricher for cfg, dialing the Chainlink RPC when a token needs it.
+func New(cfg *config.Prices) (*Enricher, error) {
+	var chainlink Feed
+	for _, t := range cfg.Tokens {
+		if t.ChainlinkFeed != "" {
+			cl, err := NewChainlink(cfg.RPCURL)
+			if err != nil {
+				return nil, err
+			}
+			chainlink = cl
+			break
+		}
+	}
+	return newEnricher(cfg, NewCoinGecko(cfg.CoinGeckoURL, cfg.APIKey), chainlink), nil
+}
+
+func newEnricher(cfg *config.Prices, coingecko, chainlink Feed) *Enricher {
+	tokens := make(map[string]config.PriceToken, len(cfg.Tokens))
+	for _, t := range cfg.Tokens {
+		tokens[tokenKey(t.Contract, t.AssetID)] = t
+	}
+	return &Enricher{
+		tokens:    tokens,
+		coingecko: coingecko,
+		chainlink: chainlink,
+		ttl:       cfg.CacheTTL(),
+		nowFunc:   time.Now,
+		cache:     map[string]cached{},
+	}
+}
+
+// Annotate sets args["value_usd"] when the event comes from a configured token
+// and carries an amount (value for EVM logs, amount for ASA transfers). Events
+// are left untouched when no price is available.
+func (e *Enricher) Annotate(ctx context.Context, contract string, args map[string]any) {
+	var assetID uint64
+	if contract == "" {
+		assetID, _ = args["asset_id"].(uint64)
+	}
+	t, ok := e.tokens[tokenKey(contract, assetID)]
+	if !ok {
+		return
+	}
+	amount, ok := amountOf(args)
+	if !ok {
+		return
+	}
+	p, err := e.price(ctx, t)
+	if err != nil {
+		return
+	}
+	units := new(big.Float).Quo(amount, new(big.Float).SetFloat64(math.Pow10(t.Decimals)))
+	v, _ := units.Float64()
+	args["value_usd"] = math.Round(v*p*100) / 100
+}
+
+// price returns a cached price, refreshing it after the ttl. A failed refresh
+// falls back to the last known price.
+func (e *Enricher) price(ctx context.Context, t config.PriceToken) (float64, error) {
+	key := tokenKey(t.Contract, t.AssetID)
+	now := e.nowFunc()
+	e.mu.Lock()
+	c, ok := e.cache[key]
+	e.mu.Unlock()
+	if ok && now.Sub(c.at) < e.ttl {
+		return c.price, nil
+	}
+
+	feed := e.coingecko
+	if t.ChainlinkFeed != "" {
+		feed = e.chainlink
+	}
+	if feed == nil {
+		return 0, fmt.Errorf("no price feed for %s", key)
+	}
+	p, err := feed.USD(ctx, t)
+	if err != nil {
+		if ok {
+			return c.price, nil
+		}
+		return 0, err
+	}
+	e.mu.Lock()
+	e.cache[key] = cached{price: p, at: now}
+	e.mu.Unlock()
+	return p, nil
+}
+
+func tokenKey(contract string, assetID uint64) string {
+	if contract != "" {
+		return "evm:" + strings.ToLower(contract)
+	}
+	return "asa:" + strconv.FormatUint(assetID, 10)
+}
+
+func amountOf(args map[string]any) (*big.Float, bool) {
+	raw, ok := args["value"]
+	if !ok {
+		raw, ok = args["amount"]
+	}
+	if !ok {
+		return nil, false
+	}
+	switch v := raw.(type) {
+	case *big.Int:
+		return new(big.Float).SetInt(v), true
+	case uint64:
+		return new(big.Float).SetUint64(v), true
+	case int64:
+		return new(big.Float).SetInt64(v), true
+	case int:
+		return new(big.Float).SetInt64(int64(v)), true
+	case float64:
+		return big.NewFloat(v), true
+	}
+	return nil, false
+}
@@ -0,0 +1,93 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+)
+
+type stubFeed struct {
+	price float64
+	err   error
+	calls int
+}
+
+func (s *stubFeed) USD(context.Context, config.PriceToken) (float64, error) {
+	s.calls++
+	return s.price, s.err
+}
+
+func TestEnricherAnnotatesAndCaches(t *testing.T) {
+	feed := &stubFeed{price: 2.5}
+	cfg := &config.Prices{TTL: "1m", Tokens: []config.PriceToken{
+		{Contract: "0xA0b8", Decimals: 6, CoinGeckoID: "usd-coin"},
+		{AssetID: 31566704, Decimals: 6, CoinGeckoID: "usd-coin"},
+	}}
+	e := newEnricher(cfg, feed, nil)
+	now := time.Unix(1_700_000_000, 0)
+	e.nowFunc = func() time.Time { return now }
+	ctx := context.Background()
+
+	args := map[string]any{"value": big.NewInt(3_000_000)}
+	e.Annotate(ctx, "0xa0B8", args)
+	if args["value_usd"] != 7.5 {
+		t.Fatalf("expected 7.5 usd, got %v", args["value_usd"])
+	}
+	asa := map[string]any{"asset_id": uint64(31566704), "amount": uint64(1_000_000)}
+	e.Annotate(ctx, "", asa)
+	if asa["value_usd"] != 2.5 || feed.calls != 2 {
+		t.Fatalf("unexpected asa annotation %v after %d calls", asa["value_usd"], feed.calls)
+	}
+	e.Annotate(ctx, "0xA0b8", map[string]any{"value": big.NewInt(1)})
+	if feed.calls != 2 {
+		t.Fatalf("expected cached price, got %d feed calls", feed.calls)
+	}
+
+	// A failed refresh keeps the stale price rather than dropping value_usd.
+	now = now.Add(2 * time.Minute)
+	feed.err = errors.New("down")
+	stale := map[string]any{"value": big.NewInt(1_000_000)}
+	e.Annotate(ctx, "0xA0b8", stale)
+	if stale["value_usd"] != 2.5 {
+		t.Fatalf("expected stale price, got %v", stale["value_usd"])
+	}
+
+	other := map[string]any{"value": big.NewInt(1)}
+	e.Annotate(ctx, "0xdead", other)
+	if _, ok := other["value_usd"]; ok {
+		t.Fatalf("unlisted token should not be annotated")
+	}
+}
+
+func TestCoinGeckoUSD(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/simple/price" || r.URL.Query().Get("ids") != "ethereum" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("x-cg-demo-api-key") != "k" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ethereum":{"usd":3120.5}}`))
+	}))
+	defer srv.Close()
+
+	cg := NewCoinGecko(srv.URL, "k")
+	p, err := cg.USD(context.Background(), config.PriceToken{CoinGeckoID: "ethereum"})
+	if err != nil {
+		t.Fatalf("usd: %v", err)
+	}
+	if p != 3120.5 {
+		t.Fatalf("unexpected price %v", p)
+	}
+	if _, err := cg.USD(context.Background(), config.PriceToken{CoinGeckoID: "missing"}); err == nil {
+		t.Fatalf("expected error for unknown id")
+	}
+}
@@ -0,0 +1,143 @@
+// Package rpclimit keeps RPC traffic for a source within provider quotas: it
+// spaces requests to a configured rate and backs off when the provider
+// signals throttling or briefly fails.
+package rpclimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultRetries is how many times a throttled or failed call is retried before the error is returned.
+	DefaultRetries = 5
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+)
+
+// Limiter paces calls for one source. It is safe for concurrent use.
+type Limiter struct {
+	interval  time.Duration
+	retryable func(error) bool
+	retries   int
+
+	mu      sync.Mutex
+	next    time.Time
+	backoff time.Duration
+
+	nowFunc   func() time.Time
+	sleepFunc func(ctx context.Context, d time.Duration) error
+}
+
+// New builds a limiter allowing rps requests per second (0 means unlimited).
+// retryable reports whether an error is worth another try after a pause: the
+// provider asking us to slow down, or a failure that may pass, such as a 5xx
+// or a dropped connection.
+func New(rps float64, retryable func(error) bool) *Limiter {
+	var interval time.Duration
+	if rps > 0 {
+		interval = time.Duration(float64(time.Second) / rps)
+	}
+	return &Limiter{
+		interval:  interval,
+		retryable: retryable,
+		retries:   DefaultRetries,
+		nowFunc:   time.Now,
+		sleepFunc: sleep,
+	}
+}
+
+// Do waits for a slot, runs fn, and retries it with exponential backoff while
+// it fails with a retryable error. Other errors are returned immediately.
+func (l *Limiter) Do(ctx context.Context, fn func() error) error {
+	if l == nil {
+		return fn()
+	}
+	for attempt := 0; ; attempt++ {
+		if err := l.wait(ctx); err != nil {
+			return err
+		}
+		err := fn()
+		if err == nil {
+			l.recover()
+			return nil
+		}
+		if l.retryable == nil || !l.retryable(err) || attempt >= l.retries {
+			return err
+		}
+		l.penalize()
+	}
+}
+
+// wait reserves the next slot and sleeps until it.
+func (l *Limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := l.nowFunc()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	if d := at.Sub(now); d > 0 {
+		return l.sleepFunc(ctx, d)
+	}
+	return ctx.Err()
+}
+
+// penalize pushes the next slot out by a growing backoff.
+func (l *Limiter) penalize() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.backoff == 0 {
+		l.backoff = initialBackoff
+	} else {
+		l.backoff *= 2
+	}
+	if l.backoff > maxBackoff {
+		l.backoff = maxBackoff
+	}
+	next := l.nowFunc().Add(l.backoff)
+	if next.After(l.next) {
+		l.next = next
+	}
+}
+
+// recover resets the backoff after a successful call.
+func (l *Limiter) recover() {
+	l.mu.Lock()
+	l.backoff = 0
+	l.mu.Unlock()
+}
+
+// IsNetworkError reports whether err is a transport failure that may pass on
+// its own: a refused or reset connection, one closed mid-response, or a
+// timeout. Cancellation is not one.
+func IsNetworkError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,94 @@
+package rpclimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var errThrottled = errors.New("429 too many requests")
+
+func newTestLimiter(rps float64) (*Limiter, *[]time.Duration) {
+	l := New(rps, func(err error) bool { return errors.Is(err, errThrottled) })
+	now := time.Unix(1_700_000_000, 0)
+	var slept []time.Duration
+	l.nowFunc = func() time.Time { return now }
+	l.sleepFunc = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		now = now.Add(d)
+		return nil
+	}
+	return l, &slept
+}
+
+func TestLimiterSpacesRequests(t *testing.T) {
+	l, slept := newTestLimiter(4)
+	for i := 0; i < 3; i++ {
+		if err := l.Do(context.Background(), func() error { return nil }); err != nil {
+			t.Fatalf("do: %v", err)
+		}
+	}
+	if len(*slept) != 2 || (*slept)[0] != 250*time.Millisecond || (*slept)[1] != 250*time.Millisecond {
+		t.Fatalf("unexpected waits: %v", *slept)
+	}
+}
+
+func TestLimiterBacksOffOnThrottle(t *testing.T) {
+	l, slept := newTestLimiter(0)
+	calls := 0
+	err := l.Do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return errThrottled
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("expected success on third call, calls=%d err=%v", calls, err)
+	}
+	if len(*slept) != 2 || (*slept)[0] != initialBackoff || (*slept)[1] != 2*initialBackoff {
+		t.Fatalf("unexpected backoff: %v", *slept)
+	}
+	if l.backoff != 0 {
+		t.Fatalf("backoff should reset after success")
+	}
+}
+
+func TestLimiterGivesUpAndPassesOtherErrors(t *testing.T) {
+	l, _ := newTestLimiter(0)
+	calls := 0
+	if err := l.Do(context.Background(), func() error { calls++; return errThrottled }); !errors.Is(err, errThrottled) {
+		t.Fatalf("expected throttled error, got %v", err)
+	}
+	if calls != DefaultRetries+1 {
+		t.Fatalf("expected %d calls, got %d", DefaultRetries+1, calls)
+	}
+
+	boom := errors.New("boom")
+	calls = 0
+	if err := l.Do(context.Background(), func() error { calls++; return boom }); !errors.Is(err, boom) || calls != 1 {
+		t.Fatalf("non-throttle errors must not retry: calls=%d err=%v", calls, err)
+	}
+}
+
+func TestLimiterRetriesTransientErrors(t *testing.T) {
+	l, slept := newTestLimiter(0)
+	l.retryable = IsNetworkError
+	calls := 0
+	err := l.Do(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 || len(*slept) != 1 {
+		t.Fatalf("expected one retry after a reset, calls=%d err=%v waits=%v", calls, err, *slept)
+	}
+	if IsNetworkError(context.Canceled) || IsNetworkError(errors.New("execution reverted")) {
+		t.Fatalf("cancellation and node errors are not network errors")
+	}
+}
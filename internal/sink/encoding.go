@@ -0,0 +1,342 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/devblac/watch-tower/internal/api/pb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Payload encodings for sinks that send the event itself rather than a
+// rendered template.
+const (
+	EncodingText     = "text" // the rendered template (default)
+	EncodingJSON     = "json"
+	EncodingProtobuf = "protobuf" // watchtower.v1.Event
+	EncodingAvro     = "avro"     // AvroSchema
+)
+
+// AvroSchema is the published Avro schema of encoded payloads. Args are
+// strings: numbers in exact decimal, anything else but strings as JSON.
+//
+//go:embed schemas/event.avsc
+var AvroSchema string
+
+// Encoder serializes a payload for the wire.
+type Encoder interface {
+	Encode(ctx context.Context, payload EventPayload) ([]byte, error)
+	ContentType() string
+}
+
+// NewEncoder returns the encoder for format. registry is only used by avro,
+// and may be nil to send bare Avro binary.
+func NewEncoder(format string, registry *SchemaRegistry) (Encoder, error) {
+	switch format {
+	case EncodingJSON:
+		return jsonEncoder{}, nil
+	case EncodingProtobuf:
+		return protoEncoder{}, nil
+	case EncodingAvro:
+		return avroEncoder{registry: registry}, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding: %s", format)
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string { return "application/json" }
+
+func (jsonEncoder) Encode(_ context.Context, p EventPayload) ([]byte, error) {
+	out, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+	return out, nil
+}
+
+type protoEncoder struct{}
+
+func (protoEncoder) ContentType() string { return "application/x-protobuf" }
+
+func (protoEncoder) Encode(_ context.Context, p EventPayload) ([]byte, error) {
+	ev, err := ProtoEvent(p)
+	if err != nil {
+		return nil, err
+	}
+	out, err := proto.Marshal(ev)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+	return out, nil
+}
+
+// ProtoEvent converts a payload to its protobuf form.
+func ProtoEvent(p EventPayload) (*pb.Event, error) {
+	args, err := argsStruct(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	ev := &pb.Event{
+		RuleId:   p.RuleID,
+		Chain:    p.Chain,
+		SourceId: p.SourceID,
+		Height:   p.Height,
+		Hash:     p.Hash,
+		TxHash:   p.TxHash,
+		AppId:    p.AppID,
+		Args:     args,
+		AlertId:  p.AlertID,
+		Retracts: p.Retracts,
+		Explorer: p.Explorer,
+		Group:    p.Group,
+		Contract: p.Contract,
+	}
+	if p.LogIndex != nil {
+		idx := uint32(*p.LogIndex)
+		ev.LogIndex = &idx
+	}
+	if !p.Timestamp.IsZero() {
+		ev.Timestamp = timestamppb.New(p.Timestamp)
+	}
+	return ev, nil
+}
+
+// maxExactInt is the largest integer a double holds exactly.
+const maxExactInt = 1 << 53
+
+// argsStruct converts decoder output (big.Int, addresses, byte slices) to a
+// protobuf Struct by round-tripping through JSON. Integers too large for a
+// double, such as token amounts, become decimal strings so they stay exact.
+func argsStruct(args map[string]any) (*structpb.Struct, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("marshal args: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic map[string]any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("unmarshal args: %w", err)
+	}
+	out, err := structpb.NewStruct(exactNumbers(generic).(map[string]any))
+	if err != nil {
+		return nil, fmt.Errorf("convert args: %w", err)
+	}
+	return out, nil
+}
+
+func exactNumbers(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, e := range x {
+			x[k] = exactNumbers(e)
+		}
+		return x
+	case []any:
+		for i, e := range x {
+			x[i] = exactNumbers(e)
+		}
+		return x
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			if n > -maxExactInt && n < maxExactInt {
+				return float64(n)
+			}
+			return x.String()
+		}
+		if _, ok := new(big.Int).SetString(x.String(), 10); ok {
+			return x.String()
+		}
+		f, _ := x.Float64()
+		return f
+	}
+	return v
+}
+
+type avroEncoder struct {
+	registry *SchemaRegistry
+}
+
+func (e avroEncoder) ContentType() string {
+	if e.registry != nil {
+		return "application/vnd.confluent.avro"
+	}
+	return "avro/binary"
+}
+
+// Encode writes p as Avro binary in AvroSchema field order. With a registry
+// it is framed in the Confluent wire format: a zero byte, the schema id as a
+// big-endian uint32, then the record.
+func (e avroEncoder) Encode(ctx context.Context, p EventPayload) ([]byte, error) {
+	var buf bytes.Buffer
+	if e.registry != nil {
+		id, err := e.registry.SchemaID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(0)
+		_ = binary.Write(&buf, binary.BigEndian, uint32(id))
+	}
+	w := avroWriter{&buf}
+	w.string(p.RuleID)
+	w.string(p.Chain)
+	w.string(p.SourceID)
+	w.long(int64(p.Height))
+	w.string(p.Hash)
+	w.string(p.TxHash)
+	w.long(int64(p.AppID))
+	if p.LogIndex == nil {
+		w.long(0) // union branch 0: null
+	} else {
+		w.long(1)
+		w.long(int64(*p.LogIndex))
+	}
+	w.string(p.Contract)
+	var ts int64
+	if !p.Timestamp.IsZero() {
+		ts = p.Timestamp.UnixMilli()
+	}
+	w.long(ts)
+
+	keys := make([]string, 0, len(p.Args))
+	for k := range p.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		w.long(int64(len(keys)))
+		for _, k := range keys {
+			v, err := argString(p.Args[k])
+			if err != nil {
+				return nil, fmt.Errorf("arg %s: %w", k, err)
+			}
+			w.string(k)
+			w.string(v)
+		}
+	}
+	w.long(0) // end of map blocks
+
+	w.string(p.AlertID)
+	w.string(p.Retracts)
+	w.string(p.Explorer)
+	w.string(p.Group)
+	return buf.Bytes(), nil
+}
+
+// argString renders an arg value for the Avro args map.
+func argString(v any) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return x, nil
+	case *big.Int, json.Number, int, int64, uint64, uint, uint32, int32:
+		return fmt.Sprint(x), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case fmt.Stringer:
+		return x.String(), nil
+	}
+	out, err := json.Marshal(v)
+	return string(out), err
+}
+
+type avroWriter struct {
+	buf *bytes.Buffer
+}
+
+// long writes a zig-zag varint.
+func (w avroWriter) long(n int64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutVarint(b[:], n)])
+}
+
+func (w avroWriter) string(s string) {
+	w.long(int64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// SchemaRegistry registers AvroSchema with a Confluent-compatible schema
+// registry and remembers the id it was given.
+type SchemaRegistry struct {
+	url      string
+	subject  string
+	username string
+	password string
+	client   *http.Client
+
+	mu sync.Mutex
+	id int
+}
+
+// NewSchemaRegistry builds a registry client for subject (such as
+// "alerts-value"). username may be empty for registries without auth.
+func NewSchemaRegistry(baseURL, subject, username, password string) *SchemaRegistry {
+	return &SchemaRegistry{
+		url:      strings.TrimSuffix(baseURL, "/"),
+		subject:  subject,
+		username: username,
+		password: password,
+		client:   defaultClient(),
+	}
+}
+
+// SchemaID registers AvroSchema under the subject on first use and returns
+// its id. Registering an unchanged schema is idempotent, so restarts reuse
+// the same id.
+func (r *SchemaRegistry) SchemaID(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.id != 0 {
+		return r.id, nil
+	}
+	body, err := json.Marshal(map[string]string{"schema": AvroSchema})
+	if err != nil {
+		return 0, fmt.Errorf("marshal schema: %w", err)
+	}
+	endpoint := r.url + "/subjects/" + url.PathEscape(r.subject) + "/versions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("register schema: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("register schema: registry status %d", resp.StatusCode)
+	}
+	var out struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("decode registry response: %w", err)
+	}
+	if out.ID == 0 {
+		return 0, fmt.Errorf("register schema: registry returned no id")
+	}
+	r.id = out.ID
+	return r.id, nil
+}
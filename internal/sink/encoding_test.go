@@ -0,0 +1,203 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/api/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func testPayload() EventPayload {
+	amount, _ := new(big.Int).SetString("1000000000000000000000000", 10)
+	idx := uint(3)
+	return EventPayload{
+		RuleID:    "whale",
+		Chain:     "evm",
+		SourceID:  "evm_main",
+		Height:    19000000,
+		TxHash:    "0xabc",
+		LogIndex:  &idx,
+		Contract:  "0xA0b8",
+		Timestamp: time.UnixMilli(1700000000123).UTC(),
+		Args:      map[string]any{"value": amount, "count": 2, "to": "0xbob"},
+		AlertID:   "a1",
+		Group:     "whale|0xabc",
+	}
+}
+
+func TestProtobufEncoding(t *testing.T) {
+	enc, err := NewEncoder(EncodingProtobuf, nil)
+	if err != nil {
+		t.Fatalf("encoder: %v", err)
+	}
+	raw, err := enc.Encode(context.Background(), testPayload())
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var ev pb.Event
+	if err := proto.Unmarshal(raw, &ev); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if ev.RuleId != "whale" || ev.Height != 19000000 || ev.GetLogIndex() != 3 || ev.Contract != "0xA0b8" || ev.Group != "whale|0xabc" {
+		t.Fatalf("unexpected event: %v", &ev)
+	}
+	args := ev.Args.AsMap()
+	// Too large for a double, so it is kept exact as a string.
+	if args["value"] != "1000000000000000000000000" || args["count"] != float64(2) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+// avroReader decodes the primitives avroWriter produces.
+type avroReader struct {
+	t *testing.T
+	r *bytes.Reader
+}
+
+func (a avroReader) long() int64 {
+	n, err := binary.ReadVarint(a.r)
+	if err != nil {
+		a.t.Fatalf("read long: %v", err)
+	}
+	return n
+}
+
+func (a avroReader) string() string {
+	b := make([]byte, a.long())
+	if _, err := io.ReadFull(a.r, b); err != nil {
+		a.t.Fatalf("read string: %v", err)
+	}
+	return string(b)
+}
+
+func TestAvroEncodingWithRegistry(t *testing.T) {
+	registrations := 0
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registrations++
+		if r.URL.Path != "/subjects/alerts-value/versions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["schema"] != AvroSchema {
+			t.Errorf("schema not sent")
+		}
+		_, _ = w.Write([]byte(`{"id":7}`))
+	}))
+	defer registry.Close()
+
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/vnd.confluent.avro" {
+			t.Errorf("content type %q", ct)
+		}
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, b)
+	}))
+	defer receiver.Close()
+
+	enc, err := NewEncoder(EncodingAvro, NewSchemaRegistry(registry.URL+"/", "alerts-value", "", ""))
+	if err != nil {
+		t.Fatalf("encoder: %v", err)
+	}
+	sender, err := NewEncodedWebhookSender(receiver.URL, "", enc, false, nil)
+	if err != nil {
+		t.Fatalf("sender: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := sender.Send(context.Background(), testPayload()); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	if registrations != 1 || len(bodies) != 2 {
+		t.Fatalf("expected 1 registration and 2 bodies, got %d and %d", registrations, len(bodies))
+	}
+
+	body := bodies[0]
+	if body[0] != 0 || binary.BigEndian.Uint32(body[1:5]) != 7 {
+		t.Fatalf("missing wire format header: %x", body[:5])
+	}
+	a := avroReader{t, bytes.NewReader(body[5:])}
+	if a.string() != "whale" || a.string() != "evm" || a.string() != "evm_main" || a.long() != 19000000 {
+		t.Fatalf("unexpected leading fields")
+	}
+	a.string() // hash
+	if a.string() != "0xabc" || a.long() != 0 {
+		t.Fatalf("unexpected tx_hash or app_id")
+	}
+	if a.long() != 1 || a.long() != 3 {
+		t.Fatalf("expected log_index 3")
+	}
+	if a.string() != "0xA0b8" || a.long() != 1700000000123 {
+		t.Fatalf("unexpected contract or timestamp")
+	}
+	args := map[string]string{}
+	for n := a.long(); n != 0; n = a.long() {
+		for ; n > 0; n-- {
+			k := a.string()
+			args[k] = a.string()
+		}
+	}
+	if args["value"] != "1000000000000000000000000" || args["count"] != "2" || args["to"] != "0xbob" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+	if a.string() != "a1" || a.string() != "" || a.string() != "" || a.string() != "whale|0xabc" || a.r.Len() != 0 {
+		t.Fatalf("unexpected trailing fields")
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	var got map[string]any
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer receiver.Close()
+
+	enc, _ := NewEncoder(EncodingJSON, nil)
+	sender, err := NewEncodedWebhookSender(receiver.URL, "POST", enc, false, nil)
+	if err != nil {
+		t.Fatalf("sender: %v", err)
+	}
+	if err := sender.Send(context.Background(), testPayload()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if got["RuleID"] != "whale" || got["AlertID"] != "a1" || got["text"] != nil {
+		t.Fatalf("unexpected body: %v", got)
+	}
+}
+
+func TestJSONOmitsUnknownTimestamp(t *testing.T) {
+	enc, _ := NewEncoder(EncodingJSON, nil)
+	p := testPayload()
+	p.Timestamp = time.Time{}
+	out, err := enc.Encode(context.Background(), p)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if _, ok := got["Timestamp"]; ok || got["RuleID"] != "whale" {
+		t.Fatalf("expected no Timestamp for an unknown time, got %s", out)
+	}
+
+	p.Timestamp = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if out, _ = enc.Encode(context.Background(), p); !strings.Contains(string(out), `"Timestamp":"2024-05-01T12:00:00Z"`) {
+		t.Fatalf("expected the known time encoded, got %s", out)
+	}
+	var back EventPayload
+	if err := json.Unmarshal(out, &back); err != nil || !back.Timestamp.Equal(p.Timestamp) || back.RuleID != "whale" {
+		t.Fatalf("expected the payload to round-trip, got %+v, %v", back, err)
+	}
+}
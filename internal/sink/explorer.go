@@ -0,0 +1,30 @@
+package sink
+
+import (
+	"fmt"
+	"strings"
+)
+
+// explorerLink builds a block explorer URL for the payload's source. Without
+// a configured explorer the bare id is returned so templates still read well.
+func explorerLink(p EventPayload, kind, id string) string {
+	if p.Explorer == "" || id == "" {
+		return id
+	}
+	if kind == "address" && p.Chain == "algorand" {
+		kind = "account"
+	}
+	return fmt.Sprintf("%s/%s/%s", strings.TrimRight(p.Explorer, "/"), kind, id)
+}
+
+func explorerTx(p EventPayload) string {
+	return explorerLink(p, "tx", p.TxHash)
+}
+
+func explorerAddress(p EventPayload, addr any) string {
+	return explorerLink(p, "address", fmt.Sprint(addr))
+}
+
+func explorerBlock(p EventPayload) string {
+	return explorerLink(p, "block", fmt.Sprint(p.Height))
+}
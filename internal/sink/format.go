@@ -0,0 +1,143 @@
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTimeLayout is what format_time renders when no layout is given.
+const defaultTimeLayout = "2006-01-02 15:04:05 MST"
+
+// formatUnits scales an integer amount down by decimals, e.g. wei to ETH,
+// and renders it exactly with trailing zeros trimmed. An optional precision
+// rounds to that many decimal places instead.
+func formatUnits(v any, decimals int, precision ...int) (string, error) {
+	r, err := toRat(v)
+	if err != nil {
+		return "", err
+	}
+	if decimals > 0 {
+		r.Quo(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+	}
+	if len(precision) > 0 {
+		return r.FloatString(precision[0]), nil
+	}
+	return trimZeros(r.FloatString(decimals)), nil
+}
+
+// comma renders a number with thousands separators, rounded to at most two
+// decimal places: 1234567.891 becomes "1,234,567.89".
+func comma(v any) (string, error) {
+	r, err := toRat(v)
+	if err != nil {
+		return "", err
+	}
+	s := trimZeros(r.FloatString(2))
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	if hasFrac {
+		return sign + b.String() + "." + frac, nil
+	}
+	return sign + b.String(), nil
+}
+
+// formatTime renders a time.Time, unix seconds, or RFC 3339 string in UTC
+// using layout (Go reference time), defaulting to defaultTimeLayout.
+func formatTime(v any, layout ...string) (string, error) {
+	l := defaultTimeLayout
+	if len(layout) > 0 && layout[0] != "" {
+		l = layout[0]
+	}
+	t, err := toTime(v)
+	if err != nil {
+		return "", fmt.Errorf("format_time: %w", err)
+	}
+	return t.UTC().Format(l), nil
+}
+
+// toTime converts a time.Time, unix seconds, or RFC 3339 string.
+func toTime(v any) (time.Time, error) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, nil
+	case string:
+		if parsed, err := time.Parse(time.RFC3339Nano, x); err == nil {
+			return parsed, nil
+		}
+		sec, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unsupported time %q", x)
+		}
+		return time.Unix(sec, 0), nil
+	}
+	r, err := toRat(v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	sec, _ := r.Float64()
+	return time.Unix(int64(sec), 0), nil
+}
+
+// toRat converts the numeric shapes event args arrive in: big ints from ABI
+// decoding, json.Number from queued payloads, and plain Go numbers.
+func toRat(v any) (*big.Rat, error) {
+	switch x := v.(type) {
+	case *big.Int:
+		if x == nil {
+			return nil, errors.New("nil number")
+		}
+		return new(big.Rat).SetInt(x), nil
+	case big.Int:
+		return new(big.Rat).SetInt(&x), nil
+	case json.Number:
+		return parseRat(string(x))
+	case string:
+		return parseRat(x)
+	case int:
+		return new(big.Rat).SetInt64(int64(x)), nil
+	case int64:
+		return new(big.Rat).SetInt64(x), nil
+	case uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(x)), nil
+	case uint32:
+		return new(big.Rat).SetInt64(int64(x)), nil
+	case float64:
+		r := new(big.Rat)
+		if r.SetFloat64(x) == nil {
+			return nil, fmt.Errorf("not a finite number: %v", x)
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("not a number: %v (%T)", v, v)
+}
+
+func parseRat(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return nil, fmt.Errorf("not a number: %q", s)
+	}
+	return r, nil
+}
+
+func trimZeros(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,201 @@
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// helperFuncs is a curated subset of the sprig template library: names and
+// argument order match sprig, so the value being transformed comes last and
+// helpers chain in pipelines ({{.Args.name | trimPrefix "0x" | upper}}).
+// Math helpers take any number the event args carry, big ints included, and
+// return exact decimal strings that format_units and comma accept.
+func helperFuncs() template.FuncMap {
+	return template.FuncMap{
+		// strings
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"repeat":     func(n int, s string) string { return strings.Repeat(s, max(n, 0)) },
+		"trunc":      trunc,
+		"substr":     substr,
+		"splitList":  func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"quote":      func(v any) string { return fmt.Sprintf("%q", fmt.Sprint(v)) },
+		"default":    dflt,
+		"toString":   func(v any) string { return fmt.Sprint(v) },
+		"toJson": func(v any) (string, error) {
+			out, err := json.Marshal(v)
+			return string(out), err
+		},
+
+		// math
+		"add": func(a, b any) (string, error) { return ratOp(a, b, (*big.Rat).Add) },
+		"sub": func(a, b any) (string, error) { return ratOp(a, b, (*big.Rat).Sub) },
+		"mul": func(a, b any) (string, error) { return ratOp(a, b, (*big.Rat).Mul) },
+		"div": div,
+		"max": func(a, b any) (string, error) { return ratPick(a, b, 1) },
+		"min": func(a, b any) (string, error) { return ratPick(a, b, -1) },
+
+		// dates
+		"now":  time.Now,
+		"date": func(layout string, v any) (string, error) { return formatTime(v, layout) },
+		"ago":  ago,
+		"unixEpoch": func(v any) (int64, error) {
+			t, err := toTime(v)
+			return t.Unix(), err
+		},
+	}
+}
+
+func title(s string) string {
+	var b strings.Builder
+	prev := ' '
+	for _, r := range s {
+		if unicode.IsSpace(prev) {
+			r = unicode.ToTitle(r)
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+// trunc keeps the first n runes of s, or the last -n when n is negative.
+func trunc(n int, s string) string {
+	r := []rune(s)
+	switch {
+	case n >= 0 && n < len(r):
+		return string(r[:n])
+	case n < 0 && -n < len(r):
+		return string(r[len(r)+n:])
+	}
+	return s
+}
+
+// substr returns runes start through end-1 of s; a negative end means the
+// rest of the string.
+func substr(start, end int, s string) string {
+	r := []rune(s)
+	start = min(max(start, 0), len(r))
+	if end < 0 || end > len(r) {
+		end = len(r)
+	}
+	if end < start {
+		return ""
+	}
+	return string(r[start:end])
+}
+
+func join(sep string, v any) string {
+	switch x := v.(type) {
+	case []string:
+		return strings.Join(x, sep)
+	case []any:
+		parts := make([]string, len(x))
+		for i, e := range x {
+			parts[i] = fmt.Sprint(e)
+		}
+		return strings.Join(parts, sep)
+	}
+	return fmt.Sprint(v)
+}
+
+// dflt returns def when v is nil, zero, or empty.
+func dflt(def, v any) any {
+	switch x := v.(type) {
+	case nil:
+		return def
+	case string:
+		if x == "" {
+			return def
+		}
+	case bool:
+		if !x {
+			return def
+		}
+	case *big.Int:
+		if x == nil || x.Sign() == 0 {
+			return def
+		}
+	case int, int64, uint64, float64:
+		if r, err := toRat(x); err == nil && r.Sign() == 0 {
+			return def
+		}
+	}
+	return v
+}
+
+func ratOp(a, b any, op func(z, x, y *big.Rat) *big.Rat) (string, error) {
+	x, err := toRat(a)
+	if err != nil {
+		return "", err
+	}
+	y, err := toRat(b)
+	if err != nil {
+		return "", err
+	}
+	return ratString(op(new(big.Rat), x, y)), nil
+}
+
+func div(a, b any) (string, error) {
+	x, err := toRat(a)
+	if err != nil {
+		return "", err
+	}
+	y, err := toRat(b)
+	if err != nil {
+		return "", err
+	}
+	if y.Sign() == 0 {
+		return "", errors.New("division by zero")
+	}
+	return ratString(x.Quo(x, y)), nil
+}
+
+// ratPick returns a when it compares to b as want (1 larger, -1 smaller).
+func ratPick(a, b any, want int) (string, error) {
+	x, err := toRat(a)
+	if err != nil {
+		return "", err
+	}
+	y, err := toRat(b)
+	if err != nil {
+		return "", err
+	}
+	if x.Cmp(y) == want {
+		return ratString(x), nil
+	}
+	return ratString(y), nil
+}
+
+// ratString renders integers exactly and fractions to 18 places, the
+// precision of most token amounts.
+func ratString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	return trimZeros(r.FloatString(18))
+}
+
+// ago renders how long before now v was, to the second.
+func ago(v any) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+	return time.Since(t).Round(time.Second).String(), nil
+}
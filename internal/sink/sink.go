@@ -2,6 +2,7 @@ package sink
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,19 +12,54 @@ import (
 	"time"
 )
 
-// EventPayload is the data passed to sinks.
+// EventPayload is the data passed to sinks. It is encoded as JSON with its
+// field names as keys (RuleID, TxHash, ...), the names templates use, so
+// pretty_json and json-encoded webhook bodies keep their shape. Fields added
+// since are left out when empty.
 type EventPayload struct {
-	RuleID   string
-	Chain    string
-	SourceID string
-	Height   uint64
-	Hash     string
-	TxHash   string
-	AppID    uint64
-	LogIndex *uint
-	Args     map[string]any
+	RuleID    string
+	Chain     string
+	SourceID  string
+	Height    uint64
+	Hash      string
+	TxHash    string
+	AppID     uint64
+	LogIndex  *uint
+	Contract  string    `json:",omitempty"`
+	Timestamp time.Time // block or round time; left out of JSON when unknown
+	Args      map[string]any
+	AlertID   string `json:",omitempty"` // correlation id, sent as CorrelationHeader
+	Retracts  string `json:",omitempty"` // id of an earlier alert whose block was reorged out
+	// Explorer is the block explorer base URL of the source, if configured.
+	Explorer string `json:",omitempty"`
+	// Group is shared by related alerts: those with the same dedupe key, and
+	// an alert and its retraction. Threading sinks reply within a group.
+	Group string `json:",omitempty"`
+	// Severity is the rule's severity, if set.
+	Severity string `json:",omitempty"`
+	// Backfill marks alerts raised by a backfill of past blocks rather than
+	// by live scanning.
+	Backfill bool `json:",omitempty"`
 }
 
+// MarshalJSON leaves Timestamp out when the time is unknown, as it is for
+// events from sources that report none, rather than sending year 1.
+func (p EventPayload) MarshalJSON() ([]byte, error) {
+	type payload EventPayload
+	out := struct {
+		payload
+		Timestamp *time.Time `json:",omitempty"`
+	}{payload: payload(p)}
+	if !p.Timestamp.IsZero() {
+		out.Timestamp = &p.Timestamp
+	}
+	return json.Marshal(out)
+}
+
+// CorrelationHeader carries the alert id on HTTP sink requests so a delivery
+// can be traced back to the event that produced it.
+const CorrelationHeader = "X-Correlation-ID"
+
 type Sender interface {
 	Send(ctx context.Context, payload EventPayload) error
 }
@@ -34,6 +70,8 @@ type httpSender struct {
 	render  *template.Template
 	client  *http.Client
 	headers map[string]string
+	gzip    bool    // compress request bodies
+	encoder Encoder // when set, the body is the encoded payload instead of the template
 }
 
 // NewWebhookSender builds a generic HTTP sink.
@@ -57,6 +95,30 @@ func NewWebhookSender(url, method, tmpl string, headers map[string]string) (Send
 	}, nil
 }
 
+// NewGzipWebhookSender is NewWebhookSender with request bodies gzipped and
+// sent with Content-Encoding: gzip, for receivers that cap body size.
+func NewGzipWebhookSender(url, method, tmpl string, headers map[string]string) (Sender, error) {
+	s, err := NewWebhookSender(url, method, tmpl, headers)
+	if err != nil {
+		return nil, err
+	}
+	s.(*httpSender).gzip = true
+	return s, nil
+}
+
+// NewEncodedWebhookSender builds an HTTP sink whose request body is the
+// payload encoded by enc, such as for a queue's HTTP ingest endpoint. gzip
+// compresses bodies as NewGzipWebhookSender does.
+func NewEncodedWebhookSender(url, method string, enc Encoder, gzip bool, headers map[string]string) (Sender, error) {
+	s, err := NewWebhookSender(url, method, "", headers)
+	if err != nil {
+		return nil, err
+	}
+	s.(*httpSender).encoder = enc
+	s.(*httpSender).gzip = gzip
+	return s, nil
+}
+
 // NewSlackSender builds a Slack-compatible webhook sink.
 func NewSlackSender(url, tmpl string) (Sender, error) {
 	return NewWebhookSender(url, http.MethodPost, tmpl, map[string]string{
@@ -73,24 +135,33 @@ func NewTeamsSender(url, tmpl string) (Sender, error) {
 }
 
 func (s *httpSender) Send(ctx context.Context, payload EventPayload) error {
-	bodyStr, err := executeTemplate(s.render, payload)
+	reqBody, err := s.body(ctx, payload)
 	if err != nil {
 		return err
 	}
-	reqBody, err := json.Marshal(map[string]string{
-		"text": bodyStr,
-	})
-	if err != nil {
-		return fmt.Errorf("marshal body: %w", err)
+
+	if s.gzip {
+		if reqBody, err = gzipBody(reqBody); err != nil {
+			return err
+		}
 	}
 
 	req, err := http.NewRequestWithContext(ctx, s.method, s.url, bytes.NewReader(reqBody))
 	if err != nil {
 		return fmt.Errorf("new request: %w", err)
 	}
+	if s.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if s.encoder != nil {
+		req.Header.Set("Content-Type", s.encoder.ContentType())
+	}
 	for k, v := range s.headers {
 		req.Header.Set(k, v)
 	}
+	if payload.AlertID != "" {
+		req.Header.Set(CorrelationHeader, payload.AlertID)
+	}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
@@ -103,11 +174,43 @@ func (s *httpSender) Send(ctx context.Context, payload EventPayload) error {
 	return nil
 }
 
+// body is the encoded payload, or the rendered template wrapped as
+// {"text": ...} when the sink has no encoder.
+func (s *httpSender) body(ctx context.Context, payload EventPayload) ([]byte, error) {
+	if s.encoder != nil {
+		return s.encoder.Encode(ctx, payload)
+	}
+	bodyStr, err := executeTemplate(s.render, payload)
+	if err != nil {
+		return nil, err
+	}
+	reqBody, err := json.Marshal(map[string]string{
+		"text": bodyStr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal body: %w", err)
+	}
+	return reqBody, nil
+}
+
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, fmt.Errorf("gzip body: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("gzip body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
 func parseTemplate(tmpl string) (*template.Template, error) {
 	if tmpl == "" {
-		tmpl = "ALERT {{.RuleID}} {{.Chain}} {{.TxHash}}"
+		tmpl = "{{if .Retracts}}RETRACTED {{end}}ALERT {{.RuleID}} {{.Chain}} {{.TxHash}}"
 	}
-	funcs := template.FuncMap{
+	funcs := helperFuncs()
+	for name, fn := range (template.FuncMap{
 		"pretty_json": func(v any) string {
 			out, _ := json.MarshalIndent(v, "", "  ")
 			return string(out)
@@ -118,6 +221,14 @@ func parseTemplate(tmpl string) (*template.Template, error) {
 			}
 			return addr[:6] + "..." + addr[len(addr)-4:]
 		},
+		"explorer_tx":      explorerTx,
+		"explorer_address": explorerAddress,
+		"explorer_block":   explorerBlock,
+		"format_units":     formatUnits,
+		"comma":            comma,
+		"format_time":      formatTime,
+	}) {
+		funcs[name] = fn
 	}
 	return template.New("msg").Funcs(funcs).Parse(tmpl)
 }
@@ -1,7 +1,11 @@
 package sink
 
 import (
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -9,11 +13,12 @@ import (
 )
 
 func TestSlackSenderRendersTemplate(t *testing.T) {
-	var got string
+	var got, correlation string
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		buf := make([]byte, r.ContentLength)
 		_, _ = r.Body.Read(buf)
 		got = string(buf)
+		correlation = r.Header.Get(CorrelationHeader)
 		w.WriteHeader(http.StatusOK)
 	}))
 	defer server.Close()
@@ -24,7 +29,7 @@ func TestSlackSenderRendersTemplate(t *testing.T) {
 	}
 
 	err = sender.Send(context.Background(), EventPayload{
-		RuleID: "r1", Chain: "evm", TxHash: "0x1234567890abcdef",
+		RuleID: "r1", Chain: "evm", TxHash: "0x1234567890abcdef", AlertID: "a1",
 	})
 	if err != nil {
 		t.Fatalf("send: %v", err)
@@ -33,6 +38,9 @@ func TestSlackSenderRendersTemplate(t *testing.T) {
 	if got == "" || !contains(got, "ALERT r1 evm 0x1234") {
 		t.Fatalf("unexpected payload: %s", got)
 	}
+	if correlation != "a1" {
+		t.Fatalf("expected correlation header a1, got %q", correlation)
+	}
 }
 
 func TestWebhookStatusFailure(t *testing.T) {
@@ -51,5 +59,127 @@ func TestWebhookStatusFailure(t *testing.T) {
 	}
 }
 
+func TestWebhookGzip(t *testing.T) {
+	var encoding, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("gzip reader: %v", err)
+			return
+		}
+		raw, _ := io.ReadAll(zr)
+		body = string(raw)
+	}))
+	defer server.Close()
+
+	sender, err := NewGzipWebhookSender(server.URL, http.MethodPost, "{{pretty_json .Args}}", nil)
+	if err != nil {
+		t.Fatalf("sender: %v", err)
+	}
+	if err := sender.Send(context.Background(), EventPayload{RuleID: "r", Args: map[string]any{"big": strings.Repeat("x", 1<<16)}}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if encoding != "gzip" {
+		t.Fatalf("expected gzip content encoding, got %q", encoding)
+	}
+	if !strings.Contains(body, `"text"`) || !strings.Contains(body, strings.Repeat("x", 100)) {
+		t.Fatalf("unexpected decompressed body: %.80s", body)
+	}
+}
+
+func TestExplorerTemplateFuncs(t *testing.T) {
+	tmpl, err := parseTemplate(`{{explorer_tx .}} {{explorer_address . .Args.to}} {{explorer_block .}}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	evm := EventPayload{Chain: "evm", TxHash: "0xabc", Height: 42, Explorer: "https://etherscan.io/", Args: map[string]any{"to": "0xdef"}}
+	got, err := executeTemplate(tmpl, evm)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if want := "https://etherscan.io/tx/0xabc https://etherscan.io/address/0xdef https://etherscan.io/block/42"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	algo := EventPayload{Chain: "algorand", TxHash: "TX", Height: 7, Explorer: "https://allo.info", Args: map[string]any{"to": "ADDR"}}
+	if got, _ := executeTemplate(tmpl, algo); got != "https://allo.info/tx/TX https://allo.info/account/ADDR https://allo.info/block/7" {
+		t.Fatalf("unexpected algorand links: %q", got)
+	}
+
+	evm.Explorer = ""
+	if got, _ := executeTemplate(tmpl, evm); got != "0xabc 0xdef 42" {
+		t.Fatalf("expected bare ids without an explorer, got %q", got)
+	}
+}
+
+func TestFormatTemplateFuncs(t *testing.T) {
+	value, _ := new(big.Int).SetString("1234567890000000000000", 10)
+	cases := []struct {
+		tmpl string
+		args map[string]any
+		want string
+	}{
+		{`{{comma (format_units .Args.value 18)}}`, map[string]any{"value": value}, "1,234.57"},
+		{`{{format_units .Args.value 18}}`, map[string]any{"value": value}, "1234.56789"},
+		{`{{format_units .Args.value 6 2}}`, map[string]any{"value": json.Number("1500000")}, "1.50"},
+		{`{{comma .Args.n}}`, map[string]any{"n": uint64(1000000)}, "1,000,000"},
+		{`{{comma .Args.n}}`, map[string]any{"n": -1234.5}, "-1,234.5"},
+		{`{{format_time .Args.ts}}`, map[string]any{"ts": uint64(1700000000)}, "2023-11-14 22:13:20 UTC"},
+		{`{{format_time .Args.ts "2006-01-02"}}`, map[string]any{"ts": "2023-11-14T22:13:20Z"}, "2023-11-14"},
+	}
+	for _, c := range cases {
+		tmpl, err := parseTemplate(c.tmpl)
+		if err != nil {
+			t.Fatalf("parse %s: %v", c.tmpl, err)
+		}
+		got, err := executeTemplate(tmpl, EventPayload{Args: c.args})
+		if err != nil {
+			t.Fatalf("render %s: %v", c.tmpl, err)
+		}
+		if got != c.want {
+			t.Fatalf("%s: got %q, want %q", c.tmpl, got, c.want)
+		}
+	}
+}
+
+func TestHelperTemplateFuncs(t *testing.T) {
+	value, _ := new(big.Int).SetString("1000000000000000000", 10)
+	cases := []struct {
+		tmpl string
+		args map[string]any
+		want string
+	}{
+		{`{{.Args.s | trimPrefix "0x" | upper}}`, map[string]any{"s": "0xabc"}, "ABC"},
+		{`{{title "large transfer"}} {{trunc 4 "abcdef"}} {{trunc -2 "abcdef"}}`, nil, "Large Transfer abcd ef"},
+		{`{{substr 1 3 "abcdef"}} {{replace "-" "_" "a-b"}} {{join ", " (splitList "," "a,b")}}`, nil, "bc a_b a, b"},
+		{`{{default "none" .Args.missing}} {{default "none" .Args.s}}`, map[string]any{"s": "x"}, "none x"},
+		{`{{if contains "ab" .Args.s}}yes{{end}}`, map[string]any{"s": "cabd"}, "yes"},
+		{`{{add .Args.v 1}} {{sub 10 .Args.n}} {{mul 3 0.5}}`, map[string]any{"v": value, "n": uint64(4)}, "1000000000000000001 6 1.5"},
+		{`{{div 10 4}} {{max 2 .Args.n}} {{min 2 .Args.n}}`, map[string]any{"n": json.Number("3")}, "2.5 3 2"},
+		{`{{comma (div .Args.v 1000)}}`, map[string]any{"v": value}, "1,000,000,000,000,000"},
+		{`{{date "2006-01-02" .Args.ts}} {{unixEpoch "2023-11-14T22:13:20Z"}}`, map[string]any{"ts": uint64(1700000000)}, "2023-11-14 1700000000"},
+		{`{{toJson .Args}}`, map[string]any{"a": 1}, `{"a":1}`},
+	}
+	for _, c := range cases {
+		tmpl, err := parseTemplate(c.tmpl)
+		if err != nil {
+			t.Fatalf("parse %s: %v", c.tmpl, err)
+		}
+		got, err := executeTemplate(tmpl, EventPayload{Args: c.args})
+		if err != nil {
+			t.Fatalf("render %s: %v", c.tmpl, err)
+		}
+		if got != c.want {
+			t.Fatalf("%s: got %q, want %q", c.tmpl, got, c.want)
+		}
+	}
+
+	tmpl, _ := parseTemplate(`{{div 1 0}}`)
+	if _, err := executeTemplate(tmpl, EventPayload{}); err == nil {
+		t.Fatalf("expected division by zero error")
+	}
+}
+
 func contains(s, substr string) bool { return strings.Contains(s, substr) }
 
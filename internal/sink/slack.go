@@ -0,0 +1,135 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// SlackPostMessageURL is the Slack Web API method used by token sinks.
+const SlackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// DefaultThreadTTL is how long a Slack thread keeps collecting replies for
+// its alert group before the next related alert starts a new one.
+const DefaultThreadTTL = 24 * time.Hour
+
+// ThreadStore remembers the Slack message that started each alert group.
+type ThreadStore interface {
+	// GetThread returns the ts of the group's thread in channel if it was
+	// started after since, or "" when there is none.
+	GetThread(ctx context.Context, channel, group string, since time.Time) (string, error)
+	PutThread(ctx context.Context, channel, group, ts string, at time.Time) error
+}
+
+type slackAPISender struct {
+	url     string
+	token   string
+	channel string
+	render  *template.Template
+	client  *http.Client
+	threads ThreadStore
+	ttl     time.Duration
+	nowFunc func() time.Time
+}
+
+// NewSlackAPISender builds a Slack sink that posts with a bot token through
+// chat.postMessage. Unlike incoming webhooks this returns the message ts, so
+// alerts sharing a payload Group within ttl are posted as replies in the
+// first one's thread. threads may be nil, which disables threading.
+func NewSlackAPISender(token, channel, tmpl string, threads ThreadStore, ttl time.Duration) (Sender, error) {
+	if token == "" || channel == "" {
+		return nil, fmt.Errorf("slack token and channel required")
+	}
+	t, err := parseTemplate(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		ttl = DefaultThreadTTL
+	}
+	return &slackAPISender{
+		url:     SlackPostMessageURL,
+		token:   token,
+		channel: channel,
+		render:  t,
+		client:  defaultClient(),
+		threads: threads,
+		ttl:     ttl,
+		nowFunc: time.Now,
+	}, nil
+}
+
+type slackMessage struct {
+	Channel  string `json:"channel"`
+	Text     string `json:"text"`
+	ThreadTS string `json:"thread_ts,omitempty"`
+}
+
+type slackResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	TS    string `json:"ts"`
+}
+
+func (s *slackAPISender) Send(ctx context.Context, payload EventPayload) error {
+	text, err := executeTemplate(s.render, payload)
+	if err != nil {
+		return err
+	}
+	msg := slackMessage{Channel: s.channel, Text: text}
+	threaded := s.threads != nil && payload.Group != ""
+	now := s.nowFunc()
+	if threaded {
+		if msg.ThreadTS, err = s.threads.GetThread(ctx, s.channel, payload.Group, now.Add(-s.ttl)); err != nil {
+			return err
+		}
+	}
+
+	ts, err := s.post(ctx, msg, payload.AlertID)
+	if err != nil {
+		return err
+	}
+	if threaded && msg.ThreadTS == "" {
+		return s.threads.PutThread(ctx, s.channel, payload.Group, ts, now)
+	}
+	return nil
+}
+
+// post sends one message and returns its ts.
+func (s *slackAPISender) post(ctx context.Context, msg slackMessage, alertID string) (string, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("marshal body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	if alertID != "" {
+		req.Header.Set(CorrelationHeader, alertID)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("sink http status %d", resp.StatusCode)
+	}
+	// The Web API reports failures in the body with a 200 status.
+	var out slackResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decode slack response: %w", err)
+	}
+	if !out.OK {
+		return "", fmt.Errorf("slack: %s", out.Error)
+	}
+	return out.TS, nil
+}
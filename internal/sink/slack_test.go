@@ -0,0 +1,79 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type memThreads map[string]string
+
+func (m memThreads) GetThread(_ context.Context, channel, group string, _ time.Time) (string, error) {
+	return m[channel+"|"+group], nil
+}
+
+func (m memThreads) PutThread(_ context.Context, channel, group, ts string, _ time.Time) error {
+	m[channel+"|"+group] = ts
+	return nil
+}
+
+func TestSlackAPIThreadsGroups(t *testing.T) {
+	var got []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			t.Errorf("missing bearer token")
+		}
+		var msg slackMessage
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		got = append(got, msg)
+		_ = json.NewEncoder(w).Encode(slackResponse{OK: true, TS: fmt.Sprintf("100.%d", len(got))})
+	}))
+	defer server.Close()
+
+	threads := memThreads{}
+	sender, err := NewSlackAPISender("xoxb-test", "C1", "ALERT {{.RuleID}}", threads, 0)
+	if err != nil {
+		t.Fatalf("sender: %v", err)
+	}
+	sender.(*slackAPISender).url = server.URL
+
+	ctx := context.Background()
+	for _, group := range []string{"g1", "g1", "g2", ""} {
+		if err := sender.Send(ctx, EventPayload{RuleID: "r1", Group: group}); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	want := []string{"", "100.1", "", ""}
+	for i, msg := range got {
+		if msg.Channel != "C1" || msg.Text != "ALERT r1" {
+			t.Fatalf("message %d: unexpected %+v", i, msg)
+		}
+		if msg.ThreadTS != want[i] {
+			t.Fatalf("message %d: thread_ts %q, want %q", i, msg.ThreadTS, want[i])
+		}
+	}
+	if threads["C1|g2"] != "100.3" || len(threads) != 2 {
+		t.Fatalf("unexpected threads: %v", threads)
+	}
+}
+
+func TestSlackAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(slackResponse{Error: "channel_not_found"})
+	}))
+	defer server.Close()
+
+	sender, err := NewSlackAPISender("xoxb-test", "C1", "", nil, 0)
+	if err != nil {
+		t.Fatalf("sender: %v", err)
+	}
+	sender.(*slackAPISender).url = server.URL
+	if err := sender.Send(context.Background(), EventPayload{RuleID: "r"}); err == nil {
+		t.Fatalf("expected error for ok=false response")
+	}
+}
@@ -0,0 +1,58 @@
+package algorand
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"strings"
+)
+
+// arc28Event is an ARC-28 event an app_call rule decodes from the app's
+// logs. Each log line of the event starts with the first four bytes of the
+// SHA-512/256 hash of its signature, followed by its fields as an ARC-4
+// tuple.
+type arc28Event struct {
+	name     string
+	selector [4]byte
+	fields   []arc4Type
+	names    []string
+}
+
+// parseARC28Event reads an event signature such as
+// "Swapped(address,uint64,uint64)". Fields may be named after their type,
+// as in "Swapped(address trader,uint64 amount_in,uint64 amount_out)";
+// unnamed fields are called arg0, arg1, and so on.
+func parseARC28Event(sig string) (*arc28Event, error) {
+	name, types, names, rest, err := parseSignature(sig)
+	if err != nil || rest != "" {
+		return nil, fmt.Errorf("event must be a signature like \"Swapped(address,uint64)\", got %q", sig)
+	}
+	ev := &arc28Event{name: name, names: names}
+	for _, typ := range types {
+		t, err := parseARC4Type(typ)
+		if err != nil {
+			return nil, fmt.Errorf("event %s: %w", name, err)
+		}
+		ev.fields = append(ev.fields, t)
+	}
+	sum := sha512.Sum512_256([]byte(name + "(" + strings.Join(types, ",") + ")"))
+	copy(ev.selector[:], sum[:4])
+	return ev, nil
+}
+
+// decode returns the event's fields from one log line, and false when the
+// line is not this event. Apps log free-form bytes too, so a line that
+// starts with the selector but does not decode is taken as some other log.
+func (e *arc28Event) decode(line []byte) (map[string]any, bool) {
+	if len(line) < 4 || [4]byte(line[:4]) != e.selector {
+		return nil, false
+	}
+	vals, err := decodeTuple(e.fields, line[4:])
+	if err != nil {
+		return nil, false
+	}
+	args := make(map[string]any, len(vals))
+	for i, v := range vals {
+		args[e.names[i]] = v
+	}
+	return args, true
+}
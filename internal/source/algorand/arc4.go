@@ -0,0 +1,493 @@
+package algorand
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+// arc4Kind is the kind of an ARC-4 ABI type.
+type arc4Kind int
+
+const (
+	arc4Uint arc4Kind = iota
+	arc4Byte
+	arc4Bool
+	arc4Address
+	arc4String
+	arc4StaticArray
+	arc4DynamicArray
+	arc4Tuple
+)
+
+// arc4Type is an ARC-4 type, enough of one to decode event fields.
+type arc4Type struct {
+	kind  arc4Kind
+	bits  int        // uint and ufixed width
+	n     int        // static array length
+	elem  *arc4Type  // array element
+	elems []arc4Type // tuple elements
+}
+
+// parseARC4Type reads an ARC-4 type name: uintN, ufixedNxM, byte, bool,
+// address, string, T[N], T[] or a tuple (T1,T2,...).
+func parseARC4Type(s string) (arc4Type, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "]") {
+		open := strings.LastIndex(s, "[")
+		if open <= 0 {
+			return arc4Type{}, fmt.Errorf("invalid type %q", s)
+		}
+		elem, err := parseARC4Type(s[:open])
+		if err != nil {
+			return arc4Type{}, err
+		}
+		if n := s[open+1 : len(s)-1]; n != "" {
+			size, err := strconv.Atoi(n)
+			if err != nil || size < 0 {
+				return arc4Type{}, fmt.Errorf("invalid array length in %q", s)
+			}
+			return arc4Type{kind: arc4StaticArray, n: size, elem: &elem}, nil
+		}
+		return arc4Type{kind: arc4DynamicArray, elem: &elem}, nil
+	}
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		t := arc4Type{kind: arc4Tuple}
+		if inner := s[1 : len(s)-1]; inner != "" {
+			for _, part := range splitTopLevel(inner) {
+				e, err := parseARC4Type(part)
+				if err != nil {
+					return arc4Type{}, err
+				}
+				t.elems = append(t.elems, e)
+			}
+		}
+		return t, nil
+	}
+	switch s {
+	case "byte":
+		return arc4Type{kind: arc4Byte}, nil
+	case "bool":
+		return arc4Type{kind: arc4Bool}, nil
+	case "address":
+		return arc4Type{kind: arc4Address}, nil
+	case "string":
+		return arc4Type{kind: arc4String}, nil
+	}
+	width := ""
+	switch {
+	case strings.HasPrefix(s, "uint"):
+		width = s[len("uint"):]
+	case strings.HasPrefix(s, "ufixed"):
+		// The raw integer; the precision after the x does not change it.
+		width, _, _ = strings.Cut(s[len("ufixed"):], "x")
+	}
+	if bits, err := strconv.Atoi(width); err == nil && bits >= 8 && bits <= 512 && bits%8 == 0 {
+		return arc4Type{kind: arc4Uint, bits: bits}, nil
+	}
+	return arc4Type{}, fmt.Errorf("unsupported type %q", s)
+}
+
+// splitTopLevel splits s on the commas outside parentheses.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// parseSignature splits a signature such as "swap(uint64 amount,asset)void"
+// into its name, argument types and names, and whatever follows the
+// argument list. Unnamed arguments are called arg0, arg1, and so on.
+func parseSignature(sig string) (name string, types, names []string, rest string, err error) {
+	sig = strings.TrimSpace(sig)
+	open := strings.Index(sig, "(")
+	if open <= 0 {
+		return "", nil, nil, "", fmt.Errorf("invalid signature %q", sig)
+	}
+	depth, end := 0, -1
+	for i := open; i < len(sig) && end < 0; i++ {
+		switch sig[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return "", nil, nil, "", fmt.Errorf("invalid signature %q", sig)
+	}
+	name = strings.TrimSpace(sig[:open])
+	if inner := strings.TrimSpace(sig[open+1 : end]); inner != "" {
+		for i, part := range splitTopLevel(inner) {
+			typ, argName := part, fmt.Sprintf("arg%d", i)
+			if sp := strings.LastIndex(part, " "); sp > 0 {
+				typ, argName = strings.TrimSpace(part[:sp]), part[sp+1:]
+			}
+			types = append(types, typ)
+			names = append(names, argName)
+		}
+	}
+	return name, types, names, strings.TrimSpace(sig[end+1:]), nil
+}
+
+// dynamic reports whether t is encoded with a length, and so takes an
+// offset in the head of an enclosing tuple.
+func (t arc4Type) dynamic() bool {
+	switch t.kind {
+	case arc4String, arc4DynamicArray:
+		return true
+	case arc4StaticArray:
+		return t.elem.dynamic()
+	case arc4Tuple:
+		for _, e := range t.elems {
+			if e.dynamic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// size is the encoded length of a static type.
+func (t arc4Type) size() int {
+	switch t.kind {
+	case arc4Uint:
+		return t.bits / 8
+	case arc4Byte, arc4Bool:
+		return 1
+	case arc4Address:
+		return 32
+	case arc4StaticArray:
+		if t.elem.kind == arc4Bool {
+			return (t.n + 7) / 8
+		}
+		return t.n * t.elem.size()
+	case arc4Tuple:
+		n := 0
+		for i := 0; i < len(t.elems); i++ {
+			if t.elems[i].kind == arc4Bool {
+				run := boolRun(t.elems, i)
+				n += (run + 7) / 8
+				i += run - 1
+				continue
+			}
+			n += t.elems[i].size()
+		}
+		return n
+	}
+	return 0
+}
+
+// boolRun counts the bools from elems[i] on, which share bytes.
+func boolRun(elems []arc4Type, i int) int {
+	n := 0
+	for i+n < len(elems) && elems[i+n].kind == arc4Bool {
+		n++
+	}
+	return n
+}
+
+var errShort = errors.New("value shorter than its type")
+
+// decodeTuple decodes elements laid out as an ARC-4 tuple: static values
+// and the offsets of dynamic ones in the head, dynamic values after it.
+func decodeTuple(elems []arc4Type, data []byte) ([]any, error) {
+	out := make([]any, len(elems))
+	offsets := make([]int, len(elems))
+	pos := 0
+	for i := 0; i < len(elems); i++ {
+		t := elems[i]
+		switch {
+		case t.kind == arc4Bool:
+			run := boolRun(elems, i)
+			if pos+(run+7)/8 > len(data) {
+				return nil, errShort
+			}
+			for j := 0; j < run; j++ {
+				out[i+j] = data[pos+j/8]&(0x80>>(j%8)) != 0
+			}
+			pos += (run + 7) / 8
+			i += run - 1
+		case t.dynamic():
+			if pos+2 > len(data) {
+				return nil, errShort
+			}
+			offsets[i] = int(binary.BigEndian.Uint16(data[pos:]))
+			pos += 2
+		default:
+			n := t.size()
+			if pos+n > len(data) {
+				return nil, errShort
+			}
+			v, err := decodeValue(t, data[pos:pos+n])
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+			pos += n
+		}
+	}
+	// A dynamic value runs to the offset of the next one, or to the end.
+	for i, t := range elems {
+		if !t.dynamic() {
+			continue
+		}
+		end := len(data)
+		for j := i + 1; j < len(elems); j++ {
+			if elems[j].dynamic() {
+				end = offsets[j]
+				break
+			}
+		}
+		if offsets[i] > end || end > len(data) {
+			return nil, errShort
+		}
+		v, err := decodeValue(t, data[offsets[i]:end])
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// decodeValue decodes one value of type t from data, which holds exactly
+// its encoding. Integers up to 64 bits become uint64 and wider ones
+// *big.Int; byte arrays become base64 like application args.
+func decodeValue(t arc4Type, data []byte) (any, error) {
+	switch t.kind {
+	case arc4Uint:
+		if len(data) != t.bits/8 {
+			return nil, errShort
+		}
+		if t.bits <= 64 {
+			var buf [8]byte
+			copy(buf[8-len(data):], data)
+			return binary.BigEndian.Uint64(buf[:]), nil
+		}
+		return new(big.Int).SetBytes(data), nil
+	case arc4Byte:
+		if len(data) != 1 {
+			return nil, errShort
+		}
+		return uint64(data[0]), nil
+	case arc4Bool:
+		if len(data) != 1 {
+			return nil, errShort
+		}
+		return data[0]&0x80 != 0, nil
+	case arc4Address:
+		var addr sdk.Address
+		if len(data) != len(addr) {
+			return nil, errShort
+		}
+		copy(addr[:], data)
+		return addr.String(), nil
+	case arc4String:
+		if len(data) < 2 || int(binary.BigEndian.Uint16(data)) != len(data)-2 {
+			return nil, errShort
+		}
+		return string(data[2:]), nil
+	case arc4StaticArray:
+		if t.elem.kind == arc4Byte {
+			if len(data) != t.n {
+				return nil, errShort
+			}
+			return base64.StdEncoding.EncodeToString(data), nil
+		}
+		return decodeTuple(repeat(*t.elem, t.n), data)
+	case arc4DynamicArray:
+		if len(data) < 2 {
+			return nil, errShort
+		}
+		n := int(binary.BigEndian.Uint16(data))
+		if t.elem.kind == arc4Byte {
+			if n != len(data)-2 {
+				return nil, errShort
+			}
+			return base64.StdEncoding.EncodeToString(data[2:]), nil
+		}
+		return decodeTuple(repeat(*t.elem, n), data[2:])
+	case arc4Tuple:
+		return decodeTuple(t.elems, data)
+	}
+	return nil, fmt.Errorf("unsupported type kind %d", t.kind)
+}
+
+func repeat(t arc4Type, n int) []arc4Type {
+	out := make([]arc4Type, n)
+	for i := range out {
+		out[i] = t
+	}
+	return out
+}
+
+// arc4MethodMaxArgs is how many application args a method call spreads its
+// arguments over after the selector; further arguments are packed as a
+// tuple into the last of them.
+const arc4MethodMaxArgs = 15
+
+// Argument types of an ARC-4 method that are not values. Reference types
+// are a one-byte index into the call's accounts, assets or apps;
+// transaction types are other transactions of the group and take no
+// application arg.
+var (
+	arc4RefTypes = map[string]bool{"account": true, "asset": true, "application": true}
+	arc4TxnTypes = map[string]bool{"txn": true, "pay": true, "keyreg": true, "acfg": true, "axfer": true, "afrz": true, "appl": true}
+)
+
+// arc4Method is an ARC-4 method whose calls an app_call rule decodes. The
+// first application arg of a call is the first four bytes of the
+// SHA-512/256 hash of the method's signature.
+type arc4Method struct {
+	name      string
+	signature string
+	selector  [4]byte
+	args      []methodArg // without transaction arguments
+}
+
+// methodArg is one argument of an arc4Method.
+type methodArg struct {
+	name string
+	ref  string // account, asset or application; "" for a value
+	typ  arc4Type
+}
+
+// parseARC4Method reads a method signature such as
+// "withdraw(uint64,account)void". Arguments may be named after their type,
+// as in "withdraw(uint64 amount,account to)void"; unnamed ones are called
+// arg0, arg1, and so on. The return type may be left out for void.
+func parseARC4Method(sig string) (*arc4Method, error) {
+	name, types, names, ret, err := parseSignature(sig)
+	if err != nil {
+		return nil, fmt.Errorf("method must be a signature like \"withdraw(uint64,account)void\", got %q", sig)
+	}
+	if ret == "" {
+		ret = "void"
+	} else if ret != "void" {
+		if _, err := parseARC4Type(ret); err != nil {
+			return nil, fmt.Errorf("method %s: return %w", name, err)
+		}
+	}
+	m := &arc4Method{name: name, signature: name + "(" + strings.Join(types, ",") + ")" + ret}
+	for i, typ := range types {
+		switch {
+		case arc4TxnTypes[typ]:
+			continue
+		case arc4RefTypes[typ]:
+			m.args = append(m.args, methodArg{name: names[i], ref: typ, typ: arc4Type{kind: arc4Uint, bits: 8}})
+		default:
+			t, err := parseARC4Type(typ)
+			if err != nil {
+				return nil, fmt.Errorf("method %s: %w", name, err)
+			}
+			m.args = append(m.args, methodArg{name: names[i], typ: t})
+		}
+	}
+	sum := sha512.Sum512_256([]byte(m.signature))
+	copy(m.selector[:], sum[:4])
+	return m, nil
+}
+
+// matches reports whether tx calls the method.
+func (m *arc4Method) matches(tx sdk.Transaction) bool {
+	return len(tx.ApplicationArgs) > 0 && bytes.Equal(tx.ApplicationArgs[0], m.selector[:])
+}
+
+// decode returns the arguments of a call to the method by name. Reference
+// arguments are resolved to the address, asset id or app id they point at.
+func (m *arc4Method) decode(tx sdk.Transaction) (map[string]any, error) {
+	appArgs := tx.ApplicationArgs[1:]
+	vals := make([]any, len(m.args))
+	n := len(m.args)
+	if n > arc4MethodMaxArgs {
+		n = arc4MethodMaxArgs - 1
+	}
+	if len(appArgs) < n {
+		return nil, fmt.Errorf("%d application args for %d method args", len(appArgs), len(m.args))
+	}
+	for i := 0; i < n; i++ {
+		v, err := decodeValue(m.args[i].typ, appArgs[i])
+		if err != nil {
+			return nil, fmt.Errorf("arg %s: %w", m.args[i].name, err)
+		}
+		vals[i] = v
+	}
+	if n < len(m.args) {
+		if len(appArgs) <= n {
+			return nil, fmt.Errorf("missing packed args from %s on", m.args[n].name)
+		}
+		packed := make([]arc4Type, 0, len(m.args)-n)
+		for _, a := range m.args[n:] {
+			packed = append(packed, a.typ)
+		}
+		rest, err := decodeTuple(packed, appArgs[n])
+		if err != nil {
+			return nil, fmt.Errorf("packed args: %w", err)
+		}
+		copy(vals[n:], rest)
+	}
+	out := make(map[string]any, len(vals))
+	for i, a := range m.args {
+		v := vals[i]
+		if a.ref != "" {
+			ref, err := resolveRef(tx, a.ref, v.(uint64))
+			if err != nil {
+				return nil, fmt.Errorf("arg %s: %w", a.name, err)
+			}
+			v = ref
+		}
+		out[a.name] = v
+	}
+	return out, nil
+}
+
+// resolveRef returns what a reference argument's index points at. Account 0
+// is the sender and application 0 the called app; the others index the
+// call's accounts and foreign apps from 1, and assets from 0.
+func resolveRef(tx sdk.Transaction, ref string, i uint64) (any, error) {
+	switch ref {
+	case "account":
+		if i == 0 {
+			return tx.Sender.String(), nil
+		}
+		if i <= uint64(len(tx.Accounts)) {
+			return tx.Accounts[i-1].String(), nil
+		}
+	case "asset":
+		if i < uint64(len(tx.ForeignAssets)) {
+			return uint64(tx.ForeignAssets[i]), nil
+		}
+	case "application":
+		if i == 0 {
+			return uint64(tx.ApplicationID), nil
+		}
+		if i <= uint64(len(tx.ForeignApps)) {
+			return uint64(tx.ForeignApps[i-1]), nil
+		}
+	}
+	return nil, fmt.Errorf("%s index %d out of range", ref, i)
+}
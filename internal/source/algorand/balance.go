@@ -0,0 +1,137 @@
+package algorand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// Event names of balance rule matches.
+const (
+	BalanceBelowEvent = "balance_below"
+	BalanceAboveEvent = "balance_above"
+)
+
+// Threshold states recorded for a balance rule.
+const (
+	balanceOK    = "ok"
+	balanceBelow = "below"
+	balanceAbove = "above"
+)
+
+var errNoBalances = errors.New("client does not read account balances")
+
+// BalanceClient is implemented by clients that read account balances.
+type BalanceClient interface {
+	// Balance returns the account's ALGO balance in microAlgos when assetID
+	// is 0, otherwise its holding of the ASA in base units.
+	Balance(ctx context.Context, account string, assetID uint64) (uint64, error)
+}
+
+func (a *clientAdapter) Balance(ctx context.Context, account string, assetID uint64) (uint64, error) {
+	if assetID == 0 {
+		info, err := a.c.AccountInformation(account).Exclude("all").Do(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return info.Amount, nil
+	}
+	info, err := a.c.AccountAssetInformation(account, assetID).Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return info.AssetHolding.Amount, nil
+}
+
+// balanceWatcher polls one account balance. It fires once when the balance
+// crosses a threshold and re-arms only after the balance moves back past it
+// by the rule's hysteresis, so a balance hovering at the line does not flap.
+type balanceWatcher struct {
+	rule     config.Rule
+	every    time.Duration
+	lastRead time.Time
+}
+
+func newBalanceWatcher(rule config.Rule) *balanceWatcher {
+	return &balanceWatcher{rule: rule, every: rule.Match.PollInterval()}
+}
+
+// next returns the threshold state for bal given the previous state.
+func (w *balanceWatcher) next(prev string, bal uint64) string {
+	m := w.rule.Match
+	switch {
+	case m.Below != 0 && bal < m.Below:
+		return balanceBelow
+	case m.Above != 0 && bal > m.Above:
+		return balanceAbove
+	case prev == balanceBelow && bal-m.Below < m.Hysteresis:
+		return balanceBelow
+	case prev == balanceAbove && m.Above-bal < m.Hysteresis:
+		return balanceAbove
+	default:
+		return balanceOK
+	}
+}
+
+func (w *balanceWatcher) event(state string, bal uint64) source.Event {
+	m := w.rule.Match
+	name, threshold := BalanceBelowEvent, m.Below
+	if state == balanceAbove {
+		name, threshold = BalanceAboveEvent, m.Above
+	}
+	return source.Event{
+		RuleID: w.rule.ID,
+		Name:   name,
+		Args: map[string]any{
+			"account":    m.Account,
+			"asset_id":   m.AssetID,
+			"balance":    bal,
+			"threshold":  threshold,
+			"hysteresis": m.Hysteresis,
+		},
+	}
+}
+
+// checkBalances polls the balance rules whose interval has passed and emits
+// an event for each new threshold crossing.
+func (s *Scanner) checkBalances(ctx context.Context, round uint64, emit func(source.Event) error) error {
+	if len(s.balances) == 0 {
+		return nil
+	}
+	bc, ok := s.client.(BalanceClient)
+	if !ok {
+		return errNoBalances
+	}
+	now := s.nowFunc()
+	for _, w := range s.balances {
+		if now.Sub(w.lastRead) < w.every {
+			continue
+		}
+		bal, err := bc.Balance(ctx, w.rule.Match.Account, w.rule.Match.AssetID)
+		if err != nil {
+			return fmt.Errorf("rule %s: balance: %w", w.rule.ID, err)
+		}
+		prev, _, err := s.store.GetStateValue(ctx, s.source.ID, w.rule.ID)
+		if err != nil {
+			return err
+		}
+		state := w.next(prev.Value, bal)
+		if state != balanceOK && state != prev.Value {
+			if err := emit(w.event(state, bal)); err != nil {
+				return err
+			}
+		}
+		if state != prev.Value {
+			if err := s.store.UpsertStateValue(ctx, storage.StateValue{SourceID: s.source.ID, RuleID: w.rule.ID, Value: state, Height: round}); err != nil {
+				return err
+			}
+		}
+		w.lastRead = now
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package algorand
+
+import (
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/algorand/go-codec/codec"
+	"github.com/devblac/watch-tower/internal/config"
+)
+
+// leanBlock decodes only what the scanner needs from a block: the previous
+// block hash for reorg checks and the payset as undecoded msgpack.
+type leanBlock struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	Branch    sdk.BlockHash `codec:"prev"`
+	TimeStamp int64         `codec:"ts"`
+	Payset    []codec.Raw   `codec:"txns"`
+}
+
+// txnPeek reads the few transaction fields needed to decide whether any
+// matcher could accept it, without decoding the rest.
+type txnPeek struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	Txn struct {
+		_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+		Type          sdk.TxType     `codec:"type"`
+		ApplicationID sdk.AppIndex   `codec:"apid"`
+		RekeyTo       sdk.Address    `codec:"rekey"`
+		XferAsset     sdk.AssetIndex `codec:"xaid"`
+		Group         sdk.Digest     `codec:"grp"`
+	} `codec:"txn"`
+	EvalDelta struct {
+		_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+		InnerTxns codec.Raw `codec:"itx"`
+	} `codec:"dt"`
+}
+
+// txnFilter is the union of transactions a scanner's matchers can accept.
+// Any matcher may accept an inner transaction, so every transaction with
+// inner ones is wanted as long as there is a matcher.
+type txnFilter struct {
+	inner          bool
+	appIDs         map[uint64]struct{}
+	assetTransfers bool                // of any ASA
+	transferAssets map[uint64]struct{} // ASAs whose transfers are wanted
+	assetConfigs   bool
+	assetFreezes   bool
+	keyRegs        bool
+	rekeys         bool // any transaction type
+	payments       bool
+}
+
+func newTxnFilter(matchers []*RuleMatcher) txnFilter {
+	f := txnFilter{appIDs: map[uint64]struct{}{}, transferAssets: map[uint64]struct{}{}, inner: len(matchers) > 0}
+	for _, m := range matchers {
+		switch m.kind {
+		case "app_call":
+			f.appIDs[m.appID] = struct{}{}
+		case "asset_transfer", config.MatchAssetClawback:
+			if m.assetID == 0 {
+				f.assetTransfers = true
+			} else {
+				f.transferAssets[m.assetID] = struct{}{}
+			}
+		case config.MatchAssetConfig:
+			f.assetConfigs = true
+		case config.MatchAssetFreeze:
+			f.assetFreezes = true
+		case config.MatchKeyReg:
+			f.keyRegs = true
+		case config.MatchRekey:
+			f.rekeys = true
+		case "payment":
+			f.payments = true
+		case config.MatchWatchAddress:
+			f.payments = true
+			f.assetTransfers = true
+		}
+	}
+	return f
+}
+
+func (f txnFilter) wants(p txnPeek) bool {
+	if f.inner && len(p.EvalDelta.InnerTxns) > 0 {
+		return true
+	}
+	if f.rekeys && !p.Txn.RekeyTo.IsZero() {
+		return true
+	}
+	switch p.Txn.Type {
+	case sdk.ApplicationCallTx:
+		_, ok := f.appIDs[uint64(p.Txn.ApplicationID)]
+		return ok
+	case sdk.AssetTransferTx:
+		if f.assetTransfers {
+			return true
+		}
+		_, ok := f.transferAssets[uint64(p.Txn.XferAsset)]
+		return ok
+	case sdk.AssetConfigTx:
+		return f.assetConfigs
+	case sdk.AssetFreezeTx:
+		return f.assetFreezes
+	case sdk.KeyRegistrationTx:
+		return f.keyRegs
+	case sdk.PaymentTx:
+		return f.payments
+	default:
+		return false
+	}
+}
+
+// txnDecoder decodes payset entries, reusing one decoder across a block.
+type txnDecoder struct {
+	dec *codec.Decoder
+}
+
+func newTxnDecoder() *txnDecoder {
+	return &txnDecoder{dec: codec.NewDecoderBytes(nil, &codec.MsgpackHandle{})}
+}
+
+func (d *txnDecoder) peek(raw codec.Raw) (txnPeek, error) {
+	var p txnPeek
+	d.dec.ResetBytes(raw)
+	err := d.dec.Decode(&p)
+	return p, err
+}
+
+func (d *txnDecoder) full(raw codec.Raw) (sdk.SignedTxnInBlock, error) {
+	var stib sdk.SignedTxnInBlock
+	d.dec.ResetBytes(raw)
+	err := d.dec.Decode(&stib)
+	return stib, err
+}
+
+func decodeLeanBlock(raw []byte) (leanBlock, error) {
+	var b leanBlock
+	err := codec.NewDecoderBytes(raw, &codec.MsgpackHandle{}).Decode(&b)
+	return b, err
+}
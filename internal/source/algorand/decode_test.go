@@ -0,0 +1,73 @@
+package algorand
+
+import (
+	"testing"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/algorand/go-codec/codec"
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+func appCall(appID sdk.AppIndex) sdk.SignedTxnInBlock {
+	return sdk.SignedTxnInBlock{SignedTxnWithAD: sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{
+		Type:              sdk.ApplicationCallTx,
+		Header:            sdk.Header{Sender: mustAddress()},
+		ApplicationFields: sdk.ApplicationFields{ApplicationCallTxnFields: sdk.ApplicationCallTxnFields{ApplicationID: appID}},
+	}}}}
+}
+
+func TestLeanDecodeSkipsUnwatchedTxns(t *testing.T) {
+	pay := sdk.SignedTxnInBlock{SignedTxnWithAD: sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{
+		Type:   sdk.PaymentTx,
+		Header: sdk.Header{Sender: mustAddress()},
+	}}}}
+	block := sdk.Block{
+		BlockHeader: sdk.BlockHeader{Round: 7, Branch: sdk.BlockHash{1, 2, 3}},
+		Payset:      []sdk.SignedTxnInBlock{pay, appCall(999), appCall(123)},
+	}
+	var raw []byte
+	if err := codec.NewEncoderBytes(&raw, &codec.MsgpackHandle{}).Encode(block); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	lean, err := decodeLeanBlock(raw)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if lean.Branch != block.Branch || len(lean.Payset) != 3 {
+		t.Fatalf("unexpected lean block: branch=%v txns=%d", lean.Branch, len(lean.Payset))
+	}
+
+	rule := config.Rule{ID: "app", Source: "algo", Match: config.MatchSpec{Type: "app_call", AppID: 123}}
+	scanner, err := NewScanner(nil, nil, config.Source{ID: "algo"}, 0, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	dec := newTxnDecoder()
+	var wanted int
+	for _, txn := range lean.Payset {
+		p, err := dec.peek(txn)
+		if err != nil {
+			t.Fatalf("peek: %v", err)
+		}
+		if scanner.filter.wants(p) {
+			wanted++
+		}
+	}
+	if wanted != 1 {
+		t.Fatalf("expected only the watched app call to pass the filter, got %d", wanted)
+	}
+
+	var evs []source.Event
+	err = scanner.extractEvents(lean, func(ev source.Event) error {
+		evs = append(evs, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if len(evs) != 1 || evs[0].AppID != 123 {
+		t.Fatalf("unexpected events: %+v", evs)
+	}
+}
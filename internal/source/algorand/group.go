@@ -0,0 +1,117 @@
+package algorand
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	sdk "github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// groupTxn is one top-level transaction of an atomic group.
+type groupTxn struct {
+	tx    sdk.Transaction
+	apply sdk.ApplyData
+	txid  string
+}
+
+// groupMatcher matches a group rule: each member must match a different
+// transaction of the same atomic group, in any order.
+type groupMatcher struct {
+	rule    config.Rule
+	names   []string
+	members []*RuleMatcher
+}
+
+func newGroupMatcher(rule config.Rule) (*groupMatcher, error) {
+	if len(rule.Match.Group) == 0 {
+		return nil, fmt.Errorf("rule %s: match.group required for group", rule.ID)
+	}
+	g := &groupMatcher{rule: rule}
+	for i, member := range rule.Match.Group {
+		m, err := NewRuleMatcher(config.Rule{ID: rule.ID, Source: rule.Source, Match: member.MatchSpec})
+		if err != nil {
+			return nil, fmt.Errorf("match.group[%d]: %w", i, err)
+		}
+		name := member.Name
+		if name == "" {
+			name = fmt.Sprintf("tx%d", i)
+		}
+		g.names = append(g.names, name)
+		g.members = append(g.members, m)
+	}
+	return g, nil
+}
+
+// match returns the group's event, with each member's args under its name,
+// or false when no assignment of members to transactions works.
+func (g *groupMatcher) match(txns []groupTxn) (*source.Event, bool, error) {
+	if len(txns) < len(g.members) {
+		return nil, false, nil
+	}
+	// hits[i][j] is member i's match of transaction j, nil for none.
+	hits := make([][]*source.Event, len(g.members))
+	for i, m := range g.members {
+		hits[i] = make([]*source.Event, len(txns))
+		found := false
+		for j, t := range txns {
+			ev, ok, err := m.MatchTxn(t.tx, t.apply)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				hits[i][j] = ev
+				found = true
+			}
+		}
+		if !found {
+			return nil, false, nil
+		}
+	}
+	picks := make([]int, len(g.members))
+	used := make([]bool, len(txns))
+	if !assignMembers(hits, picks, used, 0) {
+		return nil, false, nil
+	}
+	first := txns[0].tx
+	args := map[string]any{
+		"group_id":   base64.StdEncoding.EncodeToString(first.Group[:]),
+		"group_size": len(txns),
+	}
+	for i, j := range picks {
+		name := g.names[i]
+		for k, v := range hits[i][j].Args {
+			args[name+"."+k] = v
+		}
+		args[name+".tx_id"] = txns[j].txid
+		args[name+".index"] = j
+	}
+	return &source.Event{
+		RuleID: g.rule.ID,
+		Name:   config.MatchGroup,
+		TxHash: txns[0].txid,
+		Args:   args,
+	}, true, nil
+}
+
+// assignMembers gives members i and on a distinct matching transaction
+// each, backtracking when a later member is left without one. Groups hold
+// at most 16 transactions, so the search stays small.
+func assignMembers(hits [][]*source.Event, picks []int, used []bool, i int) bool {
+	if i == len(hits) {
+		return true
+	}
+	for j, ev := range hits[i] {
+		if ev == nil || used[j] {
+			continue
+		}
+		used[j] = true
+		picks[i] = j
+		if assignMembers(hits, picks, used, i+1) {
+			return true
+		}
+		used[j] = false
+	}
+	return false
+}
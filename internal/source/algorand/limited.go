@@ -0,0 +1,108 @@
+package algorand
+
+import (
+	"context"
+	"strings"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common"
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/devblac/watch-tower/internal/rpclimit"
+)
+
+// NewLimitedClient wraps c so it makes at most rps requests per second
+// (0 for no cap) and backs off when algod or a provider such as Nodely
+// answers with HTTP 429 or 5xx, or the connection drops.
+func NewLimitedClient(c AlgodClient, rps float64) AlgodClient {
+	return &limitedClient{inner: c, limiter: rpclimit.New(rps, func(err error) bool {
+		return IsThrottled(err) || IsTransient(err)
+	})}
+}
+
+// IsThrottled reports whether err is an HTTP 429 from the algod client.
+func IsThrottled(err error) bool {
+	// The SDK formats non-mapped status codes as "HTTP <code>: <body>".
+	return strings.HasPrefix(err.Error(), "HTTP 429")
+}
+
+// IsTransient reports whether err is an HTTP 5xx from the algod client or a
+// network error, either of which may pass if the call is repeated.
+func IsTransient(err error) bool {
+	return strings.HasPrefix(err.Error(), "HTTP 5") || rpclimit.IsNetworkError(err)
+}
+
+type limitedClient struct {
+	inner   AlgodClient
+	limiter *rpclimit.Limiter
+}
+
+func (c *limitedClient) Status() StatusGetter {
+	return limitedStatus{inner: c.inner.Status(), limiter: c.limiter}
+}
+
+func (c *limitedClient) BlockRaw(round uint64) BlockGetter {
+	return limitedBlock{inner: c.inner.BlockRaw(round), limiter: c.limiter}
+}
+
+func (c *limitedClient) GetBlockHash(round uint64) BlockHashGetter {
+	return limitedBlockHash{inner: c.inner.GetBlockHash(round), limiter: c.limiter}
+}
+
+// Balance forwards balance reads when the inner client supports them.
+func (c *limitedClient) Balance(ctx context.Context, account string, assetID uint64) (uint64, error) {
+	bc, ok := c.inner.(BalanceClient)
+	if !ok {
+		return 0, errNoBalances
+	}
+	var out uint64
+	err := c.limiter.Do(ctx, func() error {
+		var err error
+		out, err = bc.Balance(ctx, account, assetID)
+		return err
+	})
+	return out, err
+}
+
+type limitedStatus struct {
+	inner   StatusGetter
+	limiter *rpclimit.Limiter
+}
+
+func (g limitedStatus) Do(ctx context.Context, headers ...*common.Header) (models.NodeStatus, error) {
+	var out models.NodeStatus
+	err := g.limiter.Do(ctx, func() error {
+		var err error
+		out, err = g.inner.Do(ctx, headers...)
+		return err
+	})
+	return out, err
+}
+
+type limitedBlock struct {
+	inner   BlockGetter
+	limiter *rpclimit.Limiter
+}
+
+func (g limitedBlock) Do(ctx context.Context, headers ...*common.Header) ([]byte, error) {
+	var out []byte
+	err := g.limiter.Do(ctx, func() error {
+		var err error
+		out, err = g.inner.Do(ctx, headers...)
+		return err
+	})
+	return out, err
+}
+
+type limitedBlockHash struct {
+	inner   BlockHashGetter
+	limiter *rpclimit.Limiter
+}
+
+func (g limitedBlockHash) Do(ctx context.Context, headers ...*common.Header) (models.BlockHashResponse, error) {
+	var out models.BlockHashResponse
+	err := g.limiter.Do(ctx, func() error {
+		var err error
+		out, err = g.inner.Do(ctx, headers...)
+		return err
+	})
+	return out, err
+}
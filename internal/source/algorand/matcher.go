@@ -1,21 +1,32 @@
 package algorand
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	sdk "github.com/algorand/go-algorand-sdk/v2/types"
 	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
 )
 
 // RuleMatcher filters Algorand transactions for a given rule.
 type RuleMatcher struct {
-	rule  config.Rule
-	appID uint64
-	kind  string
+	rule    config.Rule
+	appID   uint64
+	kind    string
+	assetID uint64                   // asset operations; 0 for any ASA
+	addrs   map[sdk.Address]struct{} // watch_address, key_reg and rekey only
+	event   *arc28Event              // app_call with match.event only
+	methods []*arc4Method            // app_call with match.methods only
 }
 
+// ActivityEvent is the event name of watch_address matches on every chain.
+const ActivityEvent = "address_activity"
+
 // NewRuleMatcher builds a matcher for Algorand rules.
 func NewRuleMatcher(rule config.Rule) (*RuleMatcher, error) {
 	mt := strings.ToLower(rule.Match.Type)
@@ -24,16 +35,90 @@ func NewRuleMatcher(rule config.Rule) (*RuleMatcher, error) {
 		if rule.Match.AppID == 0 {
 			return nil, fmt.Errorf("rule %s: match.app_id required for app_call", rule.ID)
 		}
-		return &RuleMatcher{rule: rule, appID: rule.Match.AppID, kind: "app_call"}, nil
+		m := &RuleMatcher{rule: rule, appID: rule.Match.AppID, kind: "app_call"}
+		if rule.Match.Event != "" {
+			ev, err := parseARC28Event(rule.Match.Event)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+			}
+			m.event = ev
+		}
+		for _, sig := range rule.Match.Methods {
+			method, err := parseARC4Method(sig)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+			}
+			m.methods = append(m.methods, method)
+		}
+		return m, nil
 	case "asset_transfer":
-		return &RuleMatcher{rule: rule, kind: "asset_transfer"}, nil
+		return &RuleMatcher{rule: rule, kind: "asset_transfer", assetID: rule.Match.AssetID}, nil
+	case "payment":
+		return &RuleMatcher{rule: rule, kind: "payment"}, nil
+	case config.MatchAssetConfig, config.MatchAssetFreeze, config.MatchAssetClawback:
+		return &RuleMatcher{rule: rule, kind: mt, assetID: rule.Match.AssetID}, nil
+	case config.MatchKeyReg, config.MatchRekey:
+		addrs := map[sdk.Address]struct{}{}
+		for _, a := range rule.Match.Addresses {
+			addr, err := sdk.DecodeAddress(a)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: invalid address %q: %w", rule.ID, a, err)
+			}
+			addrs[addr] = struct{}{}
+		}
+		return &RuleMatcher{rule: rule, kind: mt, addrs: addrs}, nil
+	case config.MatchWatchAddress:
+		// The list is shared with other chains, so non-Algorand entries are skipped.
+		addrs := map[sdk.Address]struct{}{}
+		for _, a := range rule.Match.Addresses {
+			if addr, err := sdk.DecodeAddress(a); err == nil {
+				addrs[addr] = struct{}{}
+			}
+		}
+		return &RuleMatcher{rule: rule, kind: config.MatchWatchAddress, addrs: addrs}, nil
 	default:
 		return nil, fmt.Errorf("rule %s: unsupported match.type %s for algorand", rule.ID, rule.Match.Type)
 	}
 }
 
-// MatchTxn inspects a transaction and returns a normalized event when matched.
-func (m *RuleMatcher) MatchTxn(tx sdk.Transaction, apply sdk.ApplyData) (*NormalizedEvent, bool, error) {
+// MatchEvents returns every event a transaction matches. An app_call rule
+// with an ARC-28 event matches once per log line of that event; other rules
+// match a transaction at most once.
+func (m *RuleMatcher) MatchEvents(tx sdk.Transaction, apply sdk.ApplyData) ([]source.Event, error) {
+	if !m.wantsNote(tx.Note) {
+		return nil, nil
+	}
+	if m.event != nil {
+		return m.matchLogs(tx, apply), nil
+	}
+	ev, ok, err := m.MatchTxn(tx, apply)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return []source.Event{*ev}, nil
+}
+
+// MatchTxn inspects a transaction and returns a normalized event when
+// matched. For an app_call rule with an ARC-28 event it returns the first
+// one logged; MatchEvents returns them all. Every event carries the
+// transaction's note.
+func (m *RuleMatcher) MatchTxn(tx sdk.Transaction, apply sdk.ApplyData) (*source.Event, bool, error) {
+	if !m.wantsNote(tx.Note) {
+		return nil, false, nil
+	}
+	ev, ok, err := m.matchTxn(tx, apply)
+	if ok {
+		ev.Args["note"] = noteString(tx.Note)
+	}
+	return ev, ok, err
+}
+
+// wantsNote reports whether a note starts with the rule's note_prefix.
+func (m *RuleMatcher) wantsNote(note []byte) bool {
+	return bytes.HasPrefix(note, []byte(m.rule.Match.NotePrefix))
+}
+
+func (m *RuleMatcher) matchTxn(tx sdk.Transaction, apply sdk.ApplyData) (*source.Event, bool, error) {
 	switch m.kind {
 	case "app_call":
 		if tx.Type != sdk.ApplicationCallTx {
@@ -42,19 +127,18 @@ func (m *RuleMatcher) MatchTxn(tx sdk.Transaction, apply sdk.ApplyData) (*Normal
 		if uint64(tx.ApplicationID) != m.appID {
 			return nil, false, nil
 		}
-		args := map[string]any{
-			"sender":           tx.Sender.String(),
-			"on_completion":    tx.OnCompletion,
-			"app_id":           uint64(tx.ApplicationID),
-			"foreign_apps":     toAppUint64s(tx.ForeignApps),
-			"foreign_assets":   toAssetUint64s(tx.ForeignAssets),
-			"accounts":         toStrings(tx.Accounts),
-			"application_args": encodeArgs(tx.ApplicationArgs),
+		if m.event != nil {
+			evs := m.matchLogs(tx, apply)
+			if len(evs) == 0 {
+				return nil, false, nil
+			}
+			return &evs[0], true, nil
 		}
+		args := m.appCallArgs(tx)
 		if apply.ApplicationID != 0 {
 			args["inner_app_id"] = apply.ApplicationID
 		}
-		return &NormalizedEvent{
+		return &source.Event{
 			RuleID: m.rule.ID,
 			Name:   "app_call",
 			AppID:  uint64(tx.ApplicationID),
@@ -62,7 +146,7 @@ func (m *RuleMatcher) MatchTxn(tx sdk.Transaction, apply sdk.ApplyData) (*Normal
 		}, true, nil
 
 	case "asset_transfer":
-		if tx.Type != sdk.AssetTransferTx {
+		if tx.Type != sdk.AssetTransferTx || !m.wantsAsset(uint64(tx.XferAsset)) || tx.AssetAmount < m.rule.Match.MinAmount {
 			return nil, false, nil
 		}
 		args := map[string]any{
@@ -75,16 +159,311 @@ func (m *RuleMatcher) MatchTxn(tx sdk.Transaction, apply sdk.ApplyData) (*Normal
 			"close_amount":   apply.AssetClosingAmount,
 			"closing_reward": apply.CloseRewards,
 		}
-		return &NormalizedEvent{
+		return &source.Event{
 			RuleID: m.rule.ID,
 			Name:   "asset_transfer",
 			Args:   args,
 		}, true, nil
+
+	case "payment":
+		if tx.Type != sdk.PaymentTx {
+			return nil, false, nil
+		}
+		args := map[string]any{
+			"amount":         uint64(tx.Amount),
+			"sender":         tx.Sender.String(),
+			"receiver":       tx.Receiver.String(),
+			"close_to":       tx.CloseRemainderTo.String(),
+			"close_amount":   uint64(apply.ClosingAmount),
+			"closing_reward": uint64(apply.CloseRewards),
+		}
+		if amount, ok := governanceCommit(tx.Note); ok {
+			args["governance_commit"] = amount
+		}
+		return &source.Event{
+			RuleID: m.rule.ID,
+			Name:   "payment",
+			Args:   args,
+		}, true, nil
+
+	case config.MatchAssetConfig:
+		if tx.Type != sdk.AssetConfigTx {
+			return nil, false, nil
+		}
+		// A create has no asset id until it is applied.
+		assetID, action := uint64(tx.ConfigAsset), "reconfigure"
+		switch {
+		case assetID == 0:
+			assetID, action = apply.ConfigAsset, "create"
+		case tx.AssetParams.IsZero():
+			action = "destroy"
+		}
+		if !m.wantsAsset(assetID) {
+			return nil, false, nil
+		}
+		params := tx.AssetParams
+		args := map[string]any{
+			"asset_id": assetID,
+			"action":   action,
+			"sender":   tx.Sender.String(),
+			"manager":  params.Manager.String(),
+			"reserve":  params.Reserve.String(),
+			"freeze":   params.Freeze.String(),
+			"clawback": params.Clawback.String(),
+		}
+		if action == "create" {
+			args["total"] = params.Total
+			args["decimals"] = uint64(params.Decimals)
+			args["default_frozen"] = params.DefaultFrozen
+			args["unit_name"] = params.UnitName
+			args["asset_name"] = params.AssetName
+			args["url"] = params.URL
+		}
+		return &source.Event{
+			RuleID: m.rule.ID,
+			Name:   config.MatchAssetConfig,
+			Args:   args,
+		}, true, nil
+
+	case config.MatchAssetFreeze:
+		if tx.Type != sdk.AssetFreezeTx || !m.wantsAsset(uint64(tx.FreezeAsset)) {
+			return nil, false, nil
+		}
+		args := map[string]any{
+			"asset_id": uint64(tx.FreezeAsset),
+			"sender":   tx.Sender.String(),
+			"account":  tx.FreezeAccount.String(),
+			"frozen":   tx.AssetFrozen,
+		}
+		return &source.Event{
+			RuleID: m.rule.ID,
+			Name:   config.MatchAssetFreeze,
+			Args:   args,
+		}, true, nil
+
+	case config.MatchAssetClawback:
+		// A transfer with an asset sender is the clawback account moving
+		// another account's holding.
+		if tx.Type != sdk.AssetTransferTx || tx.AssetSender.IsZero() || !m.wantsAsset(uint64(tx.XferAsset)) || tx.AssetAmount < m.rule.Match.MinAmount {
+			return nil, false, nil
+		}
+		args := map[string]any{
+			"asset_id": uint64(tx.XferAsset),
+			"amount":   tx.AssetAmount,
+			"sender":   tx.Sender.String(),
+			"from":     tx.AssetSender.String(),
+			"receiver": tx.AssetReceiver.String(),
+			"close_to": tx.AssetCloseTo.String(),
+		}
+		return &source.Event{
+			RuleID: m.rule.ID,
+			Name:   config.MatchAssetClawback,
+			Args:   args,
+		}, true, nil
+
+	case config.MatchKeyReg:
+		if tx.Type != sdk.KeyRegistrationTx {
+			return nil, false, nil
+		}
+		if _, ok := m.addrs[tx.Sender]; !ok {
+			return nil, false, nil
+		}
+		// Without a vote key the registration takes the account offline;
+		// nonparticipation also takes it out of rewards for good.
+		status := "online"
+		switch {
+		case tx.Nonparticipation:
+			status = "nonparticipating"
+		case tx.VotePK == (sdk.VotePK{}):
+			status = "offline"
+		}
+		args := map[string]any{
+			"account": tx.Sender.String(),
+			"status":  status,
+			"fee":     uint64(tx.Fee),
+		}
+		if status == "online" {
+			args["vote_first"] = uint64(tx.VoteFirst)
+			args["vote_last"] = uint64(tx.VoteLast)
+			args["vote_key_dilution"] = tx.VoteKeyDilution
+			args["vote_key"] = base64.StdEncoding.EncodeToString(tx.VotePK[:])
+			args["selection_key"] = base64.StdEncoding.EncodeToString(tx.SelectionPK[:])
+		}
+		return &source.Event{
+			RuleID: m.rule.ID,
+			Name:   config.MatchKeyReg,
+			Args:   args,
+		}, true, nil
+
+	case config.MatchRekey:
+		// Any transaction can rekey its sender. Rekeying to the account
+		// itself hands authority back to the account's own key.
+		if tx.RekeyTo.IsZero() {
+			return nil, false, nil
+		}
+		if _, ok := m.addrs[tx.Sender]; !ok {
+			return nil, false, nil
+		}
+		args := map[string]any{
+			"account":   tx.Sender.String(),
+			"auth_addr": tx.RekeyTo.String(),
+			"reset":     tx.RekeyTo == tx.Sender,
+			"tx_type":   string(tx.Type),
+		}
+		return &source.Event{
+			RuleID: m.rule.ID,
+			Name:   config.MatchRekey,
+			Args:   args,
+		}, true, nil
+
+	case config.MatchWatchAddress:
+		ev, ok := m.matchActivity(tx)
+		return ev, ok, nil
 	default:
 		return nil, false, nil
 	}
 }
 
+// wantsAsset reports whether an asset operation rule covers the ASA.
+func (m *RuleMatcher) wantsAsset(id uint64) bool {
+	return m.assetID == 0 || m.assetID == id
+}
+
+// matchLogs returns an event for each log line of an app call that decodes
+// as the rule's ARC-28 event. Its args are the call's, the event's fields by
+// name, and log_index, the line's position in the call's logs.
+func (m *RuleMatcher) matchLogs(tx sdk.Transaction, apply sdk.ApplyData) []source.Event {
+	if tx.Type != sdk.ApplicationCallTx || uint64(tx.ApplicationID) != m.appID {
+		return nil
+	}
+	var out []source.Event
+	for i, line := range apply.EvalDelta.Logs {
+		fields, ok := m.event.decode([]byte(line))
+		if !ok {
+			continue
+		}
+		args := m.appCallArgs(tx)
+		for k, v := range fields {
+			args[k] = v
+		}
+		args["log_index"] = uint64(i)
+		args["note"] = noteString(tx.Note)
+		out = append(out, source.Event{
+			RuleID: m.rule.ID,
+			Name:   m.event.name,
+			AppID:  uint64(tx.ApplicationID),
+			Args:   args,
+		})
+	}
+	return out
+}
+
+// appCallArgs returns the args every app_call match carries. A call to one
+// of the rule's ARC-4 methods also gets the method's name, signature and
+// arguments; calldata that does not decode, which anyone can send, is
+// reported with a decode_error arg.
+func (m *RuleMatcher) appCallArgs(tx sdk.Transaction) map[string]any {
+	args := map[string]any{
+		"sender":           tx.Sender.String(),
+		"on_completion":    tx.OnCompletion,
+		"app_id":           uint64(tx.ApplicationID),
+		"foreign_apps":     toAppUint64s(tx.ForeignApps),
+		"foreign_assets":   toAssetUint64s(tx.ForeignAssets),
+		"accounts":         toStrings(tx.Accounts),
+		"application_args": encodeArgs(tx.ApplicationArgs),
+	}
+	for _, method := range m.methods {
+		if !method.matches(tx) {
+			continue
+		}
+		args["method"] = method.name
+		args["method_signature"] = method.signature
+		decoded, err := method.decode(tx)
+		if err != nil {
+			args["decode_error"] = err.Error()
+			break
+		}
+		for k, v := range decoded {
+			args[k] = v
+		}
+		break
+	}
+	return args
+}
+
+// matchActivity returns an address_activity event when a watched account
+// sends, receives or is closed out by a payment or asset transfer.
+func (m *RuleMatcher) matchActivity(tx sdk.Transaction) (*source.Event, bool) {
+	var args map[string]any
+	var roles []string
+	var parties []sdk.Address
+	switch tx.Type {
+	case sdk.PaymentTx:
+		args = map[string]any{
+			"kind":   "payment",
+			"from":   tx.Sender.String(),
+			"to":     tx.Receiver.String(),
+			"amount": uint64(tx.Amount),
+		}
+		roles = []string{"sender", "receiver", "close_to"}
+		parties = []sdk.Address{tx.Sender, tx.Receiver, tx.CloseRemainderTo}
+	case sdk.AssetTransferTx:
+		from := tx.Sender
+		if !tx.AssetSender.IsZero() {
+			from = tx.AssetSender // clawback
+		}
+		args = map[string]any{
+			"kind":     "asset_transfer",
+			"from":     from.String(),
+			"to":       tx.AssetReceiver.String(),
+			"amount":   tx.AssetAmount,
+			"asset_id": uint64(tx.XferAsset),
+		}
+		roles = []string{"sender", "sender", "receiver", "close_to"}
+		parties = []sdk.Address{tx.Sender, tx.AssetSender, tx.AssetReceiver, tx.AssetCloseTo}
+	default:
+		return nil, false
+	}
+	for i, addr := range parties {
+		if addr.IsZero() {
+			continue
+		}
+		if _, ok := m.addrs[addr]; ok {
+			args["watched"] = addr.String()
+			args["role"] = roles[i]
+			return &source.Event{RuleID: m.rule.ID, Name: ActivityEvent, Args: args}, true
+		}
+	}
+	return nil, false
+}
+
+// governanceNotePrefix starts the note of every Algorand governance
+// transaction; a commitment carries {"com": microAlgos} after it.
+const governanceNotePrefix = "af/gov1:j"
+
+// governanceCommit returns the committed amount of a governance sign-up note.
+func governanceCommit(note []byte) (uint64, bool) {
+	body, ok := bytes.CutPrefix(note, []byte(governanceNotePrefix))
+	if !ok {
+		return 0, false
+	}
+	var msg struct {
+		Com *uint64 `json:"com"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil || msg.Com == nil {
+		return 0, false
+	}
+	return *msg.Com, true
+}
+
+// noteString renders a note as text when it is UTF-8 and as base64 otherwise.
+func noteString(note []byte) string {
+	if utf8.Valid(note) {
+		return string(note)
+	}
+	return base64.StdEncoding.EncodeToString(note)
+}
+
 func toAssetUint64s(in []sdk.AssetIndex) []uint64 {
 	out := make([]uint64, 0, len(in))
 	for _, v := range in {
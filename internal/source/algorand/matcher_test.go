@@ -1,7 +1,9 @@
 package algorand
 
 import (
+	"crypto/sha512"
 	"encoding/base64"
+	"strings"
 	"testing"
 
 	sdk "github.com/algorand/go-algorand-sdk/v2/types"
@@ -91,8 +93,347 @@ func TestMatcher_AssetTransfer(t *testing.T) {
 	}
 }
 
+func TestMatcher_PaymentGovernanceCommit(t *testing.T) {
+	m, err := NewRuleMatcher(config.Rule{ID: "gov", Source: "algo", Match: config.MatchSpec{Type: "payment"}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	tx := sdk.Transaction{
+		Type:   sdk.PaymentTx,
+		Header: sdk.Header{Sender: addr("SENDER0000000000000000000000000000000000000000000000000000"), Note: []byte(`af/gov1:j{"com":5000000}`)},
+	}
+	ev, ok, err := m.MatchTxn(tx, sdk.ApplyData{})
+	if err != nil || !ok {
+		t.Fatalf("expected match, got ok=%v err=%v", ok, err)
+	}
+	if ev.Args["governance_commit"] != uint64(5000000) {
+		t.Fatalf("unexpected commit: %v", ev.Args["governance_commit"])
+	}
+
+	// Votes share the prefix but carry no commitment.
+	tx.Note = []byte(`af/gov1:j[5:"a"]`)
+	ev, _, _ = m.MatchTxn(tx, sdk.ApplyData{})
+	if _, ok := ev.Args["governance_commit"]; ok {
+		t.Fatalf("vote note should not yield a commitment")
+	}
+	if ev.Args["note"] != `af/gov1:j[5:"a"]` {
+		t.Fatalf("unexpected note: %v", ev.Args["note"])
+	}
+
+	if _, ok, _ := m.MatchTxn(sdk.Transaction{Type: sdk.AssetTransferTx}, sdk.ApplyData{}); ok {
+		t.Fatalf("payment matcher accepted an asset transfer")
+	}
+}
+
+func TestMatcher_WatchAddress(t *testing.T) {
+	watched := addr("WATCHED000000000000000000000000000000000000000000000000000")
+	m, err := NewRuleMatcher(config.Rule{ID: "hot", Source: config.AllSources, Match: config.MatchSpec{
+		Type:      config.MatchWatchAddress,
+		Addresses: []string{watched.String(), "0x00000000000000000000000000000000000000aa"},
+	}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	other := addr("OTHER00000000000000000000000000000000000000000000000000000")
+
+	ev, ok, err := m.MatchTxn(sdk.Transaction{
+		Type:             sdk.PaymentTx,
+		Header:           sdk.Header{Sender: other},
+		PaymentTxnFields: sdk.PaymentTxnFields{Receiver: watched, Amount: 7},
+	}, sdk.ApplyData{})
+	if err != nil || !ok {
+		t.Fatalf("expected payment match, got ok=%v err=%v", ok, err)
+	}
+	if ev.Name != ActivityEvent || ev.Args["role"] != "receiver" || ev.Args["kind"] != "payment" || ev.Args["amount"] != uint64(7) {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+
+	ev, ok, _ = m.MatchTxn(sdk.Transaction{
+		Type:                   sdk.AssetTransferTx,
+		Header:                 sdk.Header{Sender: watched},
+		AssetTransferTxnFields: sdk.AssetTransferTxnFields{XferAsset: 31566704, AssetReceiver: other, AssetAmount: 9},
+	}, sdk.ApplyData{})
+	if !ok || ev.Args["role"] != "sender" || ev.Args["asset_id"] != uint64(31566704) {
+		t.Fatalf("unexpected asset transfer match: %v %+v", ok, ev)
+	}
+
+	if _, ok, _ := m.MatchTxn(sdk.Transaction{Type: sdk.PaymentTx, Header: sdk.Header{Sender: other}}, sdk.ApplyData{}); ok {
+		t.Fatalf("unexpected match for unwatched accounts")
+	}
+}
+
 func addr(bech string) sdk.Address {
 	var a sdk.Address
 	copy(a[:], []byte(bech)[:])
 	return a
 }
+
+func TestMatcher_AppCallARC28Event(t *testing.T) {
+	rule := config.Rule{
+		ID:     "swaps",
+		Source: "algo",
+		Match: config.MatchSpec{
+			Type:  "app_call",
+			AppID: 123,
+			Event: "Swapped(address trader,uint64 amount,bool exact,string memo,uint16[] hops)",
+		},
+	}
+	m, err := NewRuleMatcher(rule)
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+
+	trader := addr("TRADER0000000000000000000000000000000000000000000000000000")
+	sum := sha512.Sum512_256([]byte("Swapped(address,uint64,bool,string,uint16[])"))
+	swapped := func(amount byte) string {
+		line := append([]byte{}, sum[:4]...)
+		line = append(line, trader[:]...)
+		line = append(line, 0, 0, 0, 0, 0, 0, 0, amount)
+		line = append(line, 0x80)             // exact
+		line = append(line, 0, 45, 0, 49)     // offsets of memo and hops
+		line = append(line, 0, 2, 'h', 'i')   // memo
+		line = append(line, 0, 2, 0, 1, 0, 2) // hops
+		return string(line)
+	}
+	tx := sdk.Transaction{
+		Type:   sdk.ApplicationCallTx,
+		Header: sdk.Header{Sender: trader},
+		ApplicationFields: sdk.ApplicationFields{
+			ApplicationCallTxnFields: sdk.ApplicationCallTxnFields{ApplicationID: 123},
+		},
+	}
+	apply := sdk.ApplyData{EvalDelta: sdk.EvalDelta{Logs: []string{swapped(7), "plain log", swapped(9), string(sum[:4])}}}
+
+	evs, err := m.MatchEvents(tx, apply)
+	if err != nil {
+		t.Fatalf("match events: %v", err)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("expected 2 events, got %+v", evs)
+	}
+	ev := evs[1]
+	if ev.Name != "Swapped" || ev.Args["trader"] != trader.String() || ev.Args["amount"] != uint64(9) ||
+		ev.Args["exact"] != true || ev.Args["memo"] != "hi" || ev.Args["log_index"] != uint64(2) || ev.Args["app_id"] != uint64(123) {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if hops, ok := ev.Args["hops"].([]any); !ok || len(hops) != 2 || hops[1] != uint64(2) {
+		t.Fatalf("unexpected hops %v", ev.Args["hops"])
+	}
+
+	if _, ok, _ := m.MatchTxn(tx, sdk.ApplyData{}); ok {
+		t.Fatalf("expected no match without the event logged")
+	}
+	if _, err := NewRuleMatcher(config.Rule{ID: "bad", Match: config.MatchSpec{Type: "app_call", AppID: 1, Event: "Bad(uint7)"}}); err == nil {
+		t.Fatalf("expected error for unsupported type")
+	}
+}
+
+func TestMatcher_AssetOperations(t *testing.T) {
+	issuer := addr("ISSUER0000000000000000000000000000000000000000000000000000")
+	holder := addr("HOLDER0000000000000000000000000000000000000000000000000000")
+	newMatcher := func(typ string) *RuleMatcher {
+		m, err := NewRuleMatcher(config.Rule{ID: typ, Source: "algo", Match: config.MatchSpec{Type: typ, AssetID: 77}})
+		if err != nil {
+			t.Fatalf("new matcher: %v", err)
+		}
+		return m
+	}
+
+	cfg := newMatcher(config.MatchAssetConfig)
+	create := sdk.Transaction{
+		Type:   sdk.AssetConfigTx,
+		Header: sdk.Header{Sender: issuer},
+		AssetConfigTxnFields: sdk.AssetConfigTxnFields{
+			AssetParams: sdk.AssetParams{Total: 1000, UnitName: "TOK", Manager: issuer, Clawback: issuer},
+		},
+	}
+	ev, ok, _ := cfg.MatchTxn(create, sdk.ApplyData{ConfigAsset: 77})
+	if !ok || ev.Args["action"] != "create" || ev.Args["asset_id"] != uint64(77) || ev.Args["unit_name"] != "TOK" {
+		t.Fatalf("unexpected create match %v %+v", ok, ev)
+	}
+	destroy := sdk.Transaction{Type: sdk.AssetConfigTx, AssetConfigTxnFields: sdk.AssetConfigTxnFields{ConfigAsset: 77}}
+	if ev, ok, _ := cfg.MatchTxn(destroy, sdk.ApplyData{}); !ok || ev.Args["action"] != "destroy" {
+		t.Fatalf("unexpected destroy match %v %+v", ok, ev)
+	}
+	destroy.ConfigAsset = 78
+	if _, ok, _ := cfg.MatchTxn(destroy, sdk.ApplyData{}); ok {
+		t.Fatalf("expected no match for another asset")
+	}
+
+	freeze := newMatcher(config.MatchAssetFreeze)
+	ev, ok, _ = freeze.MatchTxn(sdk.Transaction{
+		Type:                 sdk.AssetFreezeTx,
+		Header:               sdk.Header{Sender: issuer},
+		AssetFreezeTxnFields: sdk.AssetFreezeTxnFields{FreezeAccount: holder, FreezeAsset: 77, AssetFrozen: true},
+	}, sdk.ApplyData{})
+	if !ok || ev.Args["account"] != holder.String() || ev.Args["frozen"] != true {
+		t.Fatalf("unexpected freeze match %v %+v", ok, ev)
+	}
+
+	clawback := newMatcher(config.MatchAssetClawback)
+	xfer := sdk.Transaction{
+		Type:   sdk.AssetTransferTx,
+		Header: sdk.Header{Sender: issuer},
+		AssetTransferTxnFields: sdk.AssetTransferTxnFields{
+			XferAsset: 77, AssetAmount: 5, AssetReceiver: issuer,
+		},
+	}
+	if _, ok, _ := clawback.MatchTxn(xfer, sdk.ApplyData{}); ok {
+		t.Fatalf("expected no match for an ordinary transfer")
+	}
+	xfer.AssetSender = holder
+	ev, ok, _ = clawback.MatchTxn(xfer, sdk.ApplyData{})
+	if !ok || ev.Name != config.MatchAssetClawback || ev.Args["from"] != holder.String() || ev.Args["amount"] != uint64(5) {
+		t.Fatalf("unexpected clawback match %v %+v", ok, ev)
+	}
+}
+
+func TestMatcher_KeyReg(t *testing.T) {
+	node := addr("NODE00000000000000000000000000000000000000000000000000000000")
+	m, err := NewRuleMatcher(config.Rule{ID: "keys", Source: "algo", Match: config.MatchSpec{
+		Type:      config.MatchKeyReg,
+		Addresses: []string{node.String()},
+	}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+
+	online := sdk.Transaction{
+		Type:   sdk.KeyRegistrationTx,
+		Header: sdk.Header{Sender: node, Fee: 2_000_000},
+		KeyregTxnFields: sdk.KeyregTxnFields{
+			VotePK:          sdk.VotePK{1},
+			SelectionPK:     sdk.VRFPK{2},
+			VoteFirst:       100,
+			VoteLast:        3_000_100,
+			VoteKeyDilution: 1733,
+		},
+	}
+	ev, ok, _ := m.MatchTxn(online, sdk.ApplyData{})
+	if !ok || ev.Args["status"] != "online" || ev.Args["vote_last"] != uint64(3_000_100) || ev.Args["fee"] != uint64(2_000_000) {
+		t.Fatalf("unexpected online match %v %+v", ok, ev)
+	}
+	offline := sdk.Transaction{Type: sdk.KeyRegistrationTx, Header: sdk.Header{Sender: node}}
+	if ev, ok, _ := m.MatchTxn(offline, sdk.ApplyData{}); !ok || ev.Args["status"] != "offline" {
+		t.Fatalf("unexpected offline match %v %+v", ok, ev)
+	}
+	offline.Sender = addr("OTHER000000000000000000000000000000000000000000000000000000")
+	if _, ok, _ := m.MatchTxn(offline, sdk.ApplyData{}); ok {
+		t.Fatalf("expected no match for an unwatched account")
+	}
+	if _, err := NewRuleMatcher(config.Rule{ID: "bad", Match: config.MatchSpec{Type: config.MatchKeyReg, Addresses: []string{"0xabc"}}}); err == nil {
+		t.Fatalf("expected error for a non-Algorand address")
+	}
+}
+
+func TestMatcher_AppCallARC4Method(t *testing.T) {
+	many := "many(" + strings.TrimSuffix(strings.Repeat("uint8,", 16), ",") + ")void"
+	m, err := NewRuleMatcher(config.Rule{ID: "vault", Source: "algo", Match: config.MatchSpec{
+		Type:    "app_call",
+		AppID:   123,
+		Methods: []string{"withdraw(uint64 amount,pay fee,account to,asset token,string memo)void", many},
+	}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	selector := func(sig string) []byte {
+		sum := sha512.Sum512_256([]byte(sig))
+		return sum[:4]
+	}
+
+	receiver := addr("RECEIVER00000000000000000000000000000000000000000000000000")
+	tx := sdk.Transaction{
+		Type:   sdk.ApplicationCallTx,
+		Header: sdk.Header{Sender: addr("SENDER0000000000000000000000000000000000000000000000000000")},
+		ApplicationFields: sdk.ApplicationFields{ApplicationCallTxnFields: sdk.ApplicationCallTxnFields{
+			ApplicationID: 123,
+			ApplicationArgs: [][]byte{
+				selector("withdraw(uint64,pay,account,asset,string)void"),
+				{0, 0, 0, 0, 0, 0, 0x03, 0xe8},
+				{1},
+				{0},
+				{0, 2, 'o', 'k'},
+			},
+			Accounts:      []sdk.Address{receiver},
+			ForeignAssets: []sdk.AssetIndex{31566704},
+		}},
+	}
+	ev, ok, _ := m.MatchTxn(tx, sdk.ApplyData{})
+	if !ok || ev.Args["method"] != "withdraw" || ev.Args["amount"] != uint64(1000) || ev.Args["to"] != receiver.String() ||
+		ev.Args["token"] != uint64(31566704) || ev.Args["memo"] != "ok" || ev.Args["decode_error"] != nil {
+		t.Fatalf("unexpected match %v %+v", ok, ev)
+	}
+
+	tx.ApplicationArgs[2] = []byte{5}
+	if ev, _, _ := m.MatchTxn(tx, sdk.ApplyData{}); ev.Args["decode_error"] == nil {
+		t.Fatalf("expected decode_error for an out of range account, got %+v", ev.Args)
+	}
+
+	// Args after the fourteenth are packed into the last application arg.
+	tx.ApplicationArgs = [][]byte{selector(many)}
+	for i := 0; i < 14; i++ {
+		tx.ApplicationArgs = append(tx.ApplicationArgs, []byte{byte(i)})
+	}
+	tx.ApplicationArgs = append(tx.ApplicationArgs, []byte{14, 15})
+	if ev, _, _ := m.MatchTxn(tx, sdk.ApplyData{}); ev.Args["method"] != "many" || ev.Args["arg13"] != uint64(13) || ev.Args["arg15"] != uint64(15) {
+		t.Fatalf("unexpected packed args %+v", ev.Args)
+	}
+
+	tx.ApplicationArgs = [][]byte{[]byte("noop")}
+	if ev, _, _ := m.MatchTxn(tx, sdk.ApplyData{}); ev.Args["method"] != nil {
+		t.Fatalf("expected no method for a bare call, got %+v", ev.Args)
+	}
+}
+
+func TestMatcher_NotePrefix(t *testing.T) {
+	m, err := NewRuleMatcher(config.Rule{ID: "tagged", Source: "algo", Match: config.MatchSpec{Type: "asset_transfer", NotePrefix: "order:"}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	tx := sdk.Transaction{Type: sdk.AssetTransferTx, Header: sdk.Header{Note: []byte("order:42")}}
+	ev, ok, _ := m.MatchTxn(tx, sdk.ApplyData{})
+	if !ok || ev.Args["note"] != "order:42" {
+		t.Fatalf("unexpected match %v %+v", ok, ev)
+	}
+	tx.Note = []byte("refund:42")
+	if _, ok, _ := m.MatchTxn(tx, sdk.ApplyData{}); ok {
+		t.Fatalf("expected no match for another prefix")
+	}
+	tx.Note = nil
+	if evs, _ := m.MatchEvents(tx, sdk.ApplyData{}); len(evs) != 0 {
+		t.Fatalf("expected no match without a note, got %+v", evs)
+	}
+}
+
+func TestMatcher_AssetTransferScoping(t *testing.T) {
+	m, err := NewRuleMatcher(config.Rule{ID: "usdc", Source: "algo", Match: config.MatchSpec{Type: "asset_transfer", AssetID: 31566704, MinAmount: 1_000_000}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	for _, c := range []struct {
+		asset  sdk.AssetIndex
+		amount uint64
+		want   bool
+	}{
+		{31566704, 1_000_000, true},
+		{31566704, 999_999, false},
+		{999, 5_000_000, false},
+	} {
+		tx := sdk.Transaction{Type: sdk.AssetTransferTx, AssetTransferTxnFields: sdk.AssetTransferTxnFields{XferAsset: c.asset, AssetAmount: c.amount}}
+		if _, ok, _ := m.MatchTxn(tx, sdk.ApplyData{}); ok != c.want {
+			t.Fatalf("asset %d amount %d: got match %v", c.asset, c.amount, ok)
+		}
+	}
+
+	f := newTxnFilter([]*RuleMatcher{m})
+	var p txnPeek
+	p.Txn.Type = sdk.AssetTransferTx
+	p.Txn.XferAsset = 999
+	if f.wants(p) {
+		t.Fatalf("expected the filter to skip transfers of other assets")
+	}
+	p.Txn.XferAsset = 31566704
+	if !f.wants(p) {
+		t.Fatalf("expected the filter to keep transfers of the asset")
+	}
+}
@@ -4,43 +4,61 @@ import (
 	"context"
 	"encoding/base32"
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/common"
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
 	"github.com/algorand/go-algorand-sdk/v2/crypto"
-	"github.com/algorand/go-codec/codec"
 	sdk "github.com/algorand/go-algorand-sdk/v2/types"
 	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/source/blocktime"
 	"github.com/devblac/watch-tower/internal/storage"
 )
 
-// statusGetter models the algod Status() fluent call.
-type statusGetter interface {
+// StatusGetter models the algod Status() fluent call.
+type StatusGetter interface {
 	Do(ctx context.Context, headers ...*common.Header) (models.NodeStatus, error)
 }
 
-// blockGetter models the algod BlockRaw() fluent call.
-type blockGetter interface {
+// BlockGetter models the algod BlockRaw() fluent call.
+type BlockGetter interface {
 	Do(ctx context.Context, headers ...*common.Header) ([]byte, error)
 }
 
-type blockHashGetter interface {
+// BlockHashGetter models the algod GetBlockHash() fluent call.
+type BlockHashGetter interface {
 	Do(ctx context.Context, headers ...*common.Header) (models.BlockHashResponse, error)
 }
 
 // AlgodClient is the minimal subset of the algod client we need.
 type AlgodClient interface {
-	Status() statusGetter
-	BlockRaw(round uint64) blockGetter
-	GetBlockHash(round uint64) blockHashGetter
+	Status() StatusGetter
+	BlockRaw(round uint64) BlockGetter
+	GetBlockHash(round uint64) BlockHashGetter
 }
 
-// NewAlgodClient constructs a real algod client.
-func NewAlgodClient(url string) (AlgodClient, error) {
-	cli, err := algod.MakeClient(url, "")
+// NewAlgodClient constructs a real algod client that sends header with
+// every request, as built by config.Source.AlgodHeaders. The API token goes
+// in header as X-Algo-API-Token.
+func NewAlgodClient(url string, header http.Header) (AlgodClient, error) {
+	h := header.Clone()
+	// The client always sends its token header, so a configured one is
+	// passed as the token rather than added twice.
+	token := h.Get(config.AlgodTokenHeader)
+	h.Del(config.AlgodTokenHeader)
+	var hs []*common.Header
+	for k, vs := range h {
+		for _, v := range vs {
+			hs = append(hs, &common.Header{Key: k, Value: v})
+		}
+	}
+	cli, err := algod.MakeClientWithHeaders(url, token, hs)
 	if err != nil {
 		return nil, err
 	}
@@ -51,11 +69,11 @@ type clientAdapter struct {
 	c *algod.Client
 }
 
-func (a *clientAdapter) Status() statusGetter { return a.c.Status() }
-func (a *clientAdapter) BlockRaw(round uint64) blockGetter {
+func (a *clientAdapter) Status() StatusGetter { return a.c.Status() }
+func (a *clientAdapter) BlockRaw(round uint64) BlockGetter {
 	return a.c.BlockRaw(round)
 }
-func (a *clientAdapter) GetBlockHash(round uint64) blockHashGetter {
+func (a *clientAdapter) GetBlockHash(round uint64) BlockHashGetter {
 	return a.c.GetBlockHash(round)
 }
 
@@ -66,13 +84,65 @@ type Scanner struct {
 	source        config.Source
 	confirmations uint64
 	matchers      []*RuleMatcher
+	groups        []*groupMatcher
+	filter        txnFilter
+	balances      []*balanceWatcher
+	tipTTL        time.Duration
+	nowFunc       func() time.Time
+	// maxRounds is how many rounds one call may scan (source
+	// max_blocks_per_tick); stopAt, when set, is the last round to scan.
+	maxRounds uint64
+	stopAt    uint64
+	// tip is the latest chain height seen, read by the dashboard.
+	tip   atomic.Uint64
+	tipAt time.Time
 }
 
 // NewScanner builds a scanner for an Algorand source and its rules.
-func NewScanner(client AlgodClient, store *storage.Store, source config.Source, confirmations uint64, rules []config.Rule) (*Scanner, error) {
+func NewScanner(client AlgodClient, store *storage.Store, src config.Source, confirmations uint64, rules []config.Rule) (*Scanner, error) {
+	s := &Scanner{
+		client:        client,
+		store:         store,
+		source:        src,
+		confirmations: confirmations,
+		tipTTL:        src.TipCacheTTL(),
+		nowFunc:       time.Now,
+		maxRounds:     1,
+	}
+	if src.MaxBlocksPerTick > 1 {
+		s.maxRounds = uint64(src.MaxBlocksPerTick)
+	}
+	commit, err := s.PrepareRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	commit()
+	return s, nil
+}
+
+// PrepareRules builds matchers for the source's rules without touching the
+// running scanner. Calling the returned commit func swaps them in.
+func (s *Scanner) PrepareRules(rules []config.Rule) (commit func(), err error) {
 	matchers := []*RuleMatcher{}
+	groups := []*groupMatcher{}
+	balances := []*balanceWatcher{}
 	for _, r := range rules {
-		if r.Source != source.ID {
+		if !r.AppliesTo(s.source.ID) {
+			continue
+		}
+		if strings.EqualFold(r.Match.Type, config.MatchBalance) {
+			balances = append(balances, newBalanceWatcher(r))
+			continue
+		}
+		if strings.EqualFold(r.Match.Type, config.MatchReorg) {
+			continue // raised by the engine when the scanner rewinds
+		}
+		if strings.EqualFold(r.Match.Type, config.MatchGroup) {
+			g, err := newGroupMatcher(r)
+			if err != nil {
+				return nil, err
+			}
+			groups = append(groups, g)
 			continue
 		}
 		m, err := NewRuleMatcher(r)
@@ -81,113 +151,304 @@ func NewScanner(client AlgodClient, store *storage.Store, source config.Source,
 		}
 		matchers = append(matchers, m)
 	}
-
-	return &Scanner{
-		client:        client,
-		store:         store,
-		source:        source,
-		confirmations: confirmations,
-		matchers:      matchers,
+	filter := newTxnFilter(matchers)
+	return func() {
+		s.matchers = matchers
+		s.groups = groups
+		s.filter = filter
+		s.balances = balances
 	}, nil
 }
 
+// SetStopHeight keeps a call from scanning past round, the end of a bounded
+// replay. Zero means no limit.
+func (s *Scanner) SetStopHeight(round uint64) {
+	s.stopAt = round
+}
+
+// Tip returns the latest chain height observed by ProcessNext, or 0 before the first poll.
+func (s *Scanner) Tip() uint64 {
+	return s.tip.Load()
+}
+
+// latestHeight returns the chain tip. The cached tip is reused while next is
+// still confirmed below it (catching up) or while it is younger than tipTTL.
+func (s *Scanner) latestHeight(ctx context.Context, next uint64) (uint64, error) {
+	if tip := s.tip.Load(); tip > 0 {
+		if next+s.confirmations <= tip || s.nowFunc().Sub(s.tipAt) < s.tipTTL {
+			return tip, nil
+		}
+	}
+	status, err := s.client.Status().Do(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("latest status: %w", err)
+	}
+	height := status.LastRound
+	if prev := s.tip.Load(); height < prev {
+		// The lower tip is not kept, so the next tick asks again.
+		return 0, &source.TipError{Tip: height, Previous: prev, Unit: "round"}
+	}
+	s.tip.Store(height)
+	s.tipAt = s.nowFunc()
+	return height, nil
+}
+
 // ProcessNext handles the next eligible round (respecting confirmations) and returns matched events.
-// On success advances the cursor. On reorg returns ErrReorgDetected after rewinding.
-func (s *Scanner) ProcessNext(ctx context.Context) ([]NormalizedEvent, error) {
-	curRound, curHash, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+// A source with max_blocks_per_tick that is behind handles up to that many rounds at once.
+// On success advances the cursor. On reorg returns source.ErrReorgDetected after rewinding.
+func (s *Scanner) ProcessNext(ctx context.Context) ([]source.Event, error) {
+	var events []source.Event
+	err := s.ProcessNextFunc(ctx, func(ev source.Event) error {
+		events = append(events, ev)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return events, nil
+}
 
-	status, err := s.client.Status().Do(ctx)
+// ProcessNextFunc is ProcessNext with each matched event handed to emit as it
+// is decoded. The cursor moves after each round, so if emit fails it is left
+// at the last round fully handled and the error is returned.
+func (s *Scanner) ProcessNextFunc(ctx context.Context, emit func(source.Event) error) error {
+	curRound, curHash, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+	if err != nil {
+		return err
+	}
+
+	latest, err := s.latestHeight(ctx, curRound+1)
 	if err != nil {
-		return nil, fmt.Errorf("latest status: %w", err)
+		return err
+	}
+	if hasCursor && latest < curRound {
+		return &source.TipError{Tip: latest, Previous: curRound, Cursor: true, Unit: "round"}
 	}
-	latest := status.LastRound
 	safe := latest
 	if s.confirmations > 0 {
 		if safe < s.confirmations {
-			return nil, nil
+			return nil
 		}
 		safe -= s.confirmations
 	}
 
 	target := curRound + 1
 	if !hasCursor {
-		start, err := resolveStartRound(s.source.StartRound, safe)
+		start, err := s.resolveStart(ctx, safe)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		target = start
 	}
 
 	if target > safe {
-		return nil, nil
+		return nil
+	}
+
+	end := min(target+s.maxRounds-1, safe)
+	if s.stopAt > 0 {
+		end = min(end, max(s.stopAt, target))
+	}
+	for round := target; round <= end; round++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hash, err := s.processRound(ctx, round, curRound, curHash, hasCursor, emit)
+		if err != nil {
+			return err
+		}
+		curRound, curHash, hasCursor = round, hash, true
 	}
+	return nil
+}
 
+// processRound matches the rules against target, whose parent must be the
+// cursor at curRound when there is one, and moves the cursor to it.
+func (s *Scanner) processRound(ctx context.Context, target, curRound uint64, curHash string, hasCursor bool, emit func(source.Event) error) (string, error) {
 	raw, err := s.client.BlockRaw(target).Do(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("block %d: %w", target, err)
+		return "", fmt.Errorf("block %d: %w", target, err)
 	}
-	var block sdk.Block
-	if err := decodeBlock(raw, &block); err != nil {
-		return nil, fmt.Errorf("decode block: %w", err)
+	block, err := decodeLeanBlock(raw)
+	if err != nil {
+		return "", fmt.Errorf("decode block: %w", err)
 	}
 
 	if hasCursor {
-		prev := digestToString(block.BlockHeader.Branch[:])
+		prev := digestToString(block.Branch[:])
 		if prev != curHash {
 			rewindTo := uint64(0)
 			if target > 0 {
 				rewindTo = target - 1
 			}
-			_ = s.store.UpsertCursor(ctx, s.source.ID, rewindTo, prev)
-			return nil, ErrReorgDetected
+			if err := s.store.UpsertCursor(ctx, s.source.ID, rewindTo, prev); err != nil {
+				return "", err
+			}
+			return "", &source.ReorgError{Height: target, From: curRound, To: curRound, OldHash: curHash, NewHash: prev, Unit: "round"}
 		}
 	}
 
 	hashResp, err := s.client.GetBlockHash(target).Do(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("block hash %d: %w", target, err)
+		return "", fmt.Errorf("block hash %d: %w", target, err)
 	}
 	blockHash := hashResp.Blockhash
-	events, err := s.extractEvents(block)
-	if err != nil {
-		return nil, err
+	stamp := func(ev source.Event) error {
+		ev.Chain = Chain
+		ev.SourceID = s.source.ID
+		ev.Height = target
+		ev.Hash = blockHash
+		ev.Timestamp = time.Unix(block.TimeStamp, 0).UTC()
+		return emit(ev)
 	}
-	for i := range events {
-		events[i].Chain = Chain
-		events[i].SourceID = s.source.ID
-		events[i].Height = target
-		events[i].Hash = blockHash
+	if err := s.extractEvents(block, stamp); err != nil {
+		return "", err
 	}
+	if err := s.checkBalances(ctx, target, stamp); err != nil {
+		return "", err
+	}
+	return blockHash, s.store.UpsertCursor(ctx, s.source.ID, target, blockHash)
+}
 
-	if err := s.store.UpsertCursor(ctx, s.source.ID, target, blockHash); err != nil {
-		return nil, err
+// SkipNext advances the cursor past the next round without matching it.
+// It requires an existing cursor and returns the skipped round.
+func (s *Scanner) SkipNext(ctx context.Context) (uint64, error) {
+	curRound, _, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+	if err != nil {
+		return 0, err
 	}
-	return events, nil
+	if !hasCursor {
+		return 0, fmt.Errorf("source %s has no cursor yet", s.source.ID)
+	}
+	target := curRound + 1
+	hashResp, err := s.client.GetBlockHash(target).Do(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("block hash %d: %w", target, err)
+	}
+	if err := s.store.UpsertCursor(ctx, s.source.ID, target, hashResp.Blockhash); err != nil {
+		return 0, err
+	}
+	return target, nil
 }
 
-func (s *Scanner) extractEvents(block sdk.Block) ([]NormalizedEvent, error) {
-	var out []NormalizedEvent
-	for _, stib := range block.Payset {
-		tx := stib.SignedTxnWithAD.SignedTxn.Txn
-		apply := stib.SignedTxnWithAD.ApplyData
-		txid := crypto.TransactionIDString(tx)
-		for _, m := range s.matchers {
-			ev, ok, err := m.MatchTxn(tx, apply)
-			if err != nil {
-				return nil, err
-			}
-			if !ok {
-				continue
+// extractEvents peeks at each transaction's type, app id and inner
+// transactions and only fully decodes the ones some matcher can accept,
+// passing each match to emit. With group rules, every grouped transaction
+// is decoded too, and a group is matched once its last transaction is read.
+func (s *Scanner) extractEvents(block leanBlock, emit func(source.Event) error) error {
+	dec := newTxnDecoder()
+	var group []groupTxn
+	for _, raw := range block.Payset {
+		peek, err := dec.peek(raw)
+		if err != nil {
+			return fmt.Errorf("decode txn: %w", err)
+		}
+		if len(group) > 0 && group[0].tx.Group != peek.Txn.Group {
+			if err := s.matchGroup(group, emit); err != nil {
+				return err
 			}
+			group = group[:0]
+		}
+		grouped := len(s.groups) > 0 && peek.Txn.Group != (sdk.Digest{})
+		wanted := s.filter.wants(peek)
+		if !wanted && !grouped {
+			continue
+		}
+		stib, err := dec.full(raw)
+		if err != nil {
+			return fmt.Errorf("decode txn: %w", err)
+		}
+		stxn := stib.SignedTxnWithAD
+		txid := crypto.TransactionIDString(stxn.SignedTxn.Txn)
+		if grouped {
+			group = append(group, groupTxn{tx: stxn.SignedTxn.Txn, apply: stxn.ApplyData, txid: txid})
+		}
+		if !wanted {
+			continue
+		}
+		if err := s.matchTxnTree(stxn, txid, "", emit); err != nil {
+			return err
+		}
+	}
+	if len(group) > 0 {
+		return s.matchGroup(group, emit)
+	}
+	return nil
+}
+
+// matchGroup runs the group rules on the top-level transactions of one
+// atomic group.
+func (s *Scanner) matchGroup(txns []groupTxn, emit func(source.Event) error) error {
+	for _, g := range s.groups {
+		ev, ok, err := g.match(txns)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := emit(*ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchTxnTree runs the matchers on a transaction and, depth first, on the
+// inner transactions its app calls issued. Inner transactions have no id of
+// their own in a block, so their events carry the top-level txid and an
+// inner_path arg: the indexes down the tree, such as "0" or "0.2".
+func (s *Scanner) matchTxnTree(stxn sdk.SignedTxnWithAD, txid, path string, emit func(source.Event) error) error {
+	tx, apply := stxn.SignedTxn.Txn, stxn.ApplyData
+	for _, m := range s.matchers {
+		evs, err := m.MatchEvents(tx, apply)
+		if err != nil {
+			return err
+		}
+		for _, ev := range evs {
 			ev.TxHash = txid
 			ev.AppID = uint64(tx.ApplicationID)
-			out = append(out, *ev)
+			if path != "" {
+				ev.Args["inner_path"] = path
+			}
+			if err := emit(ev); err != nil {
+				return err
+			}
 		}
 	}
-	return out, nil
+	for i, inner := range apply.EvalDelta.InnerTxns {
+		child := strconv.Itoa(i)
+		if path != "" {
+			child = path + "." + child
+		}
+		if err := s.matchTxnTree(inner, txid, child, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveStart picks the first round for a source without a cursor. A
+// "time:" start_round is found by binary search over round timestamps.
+func (s *Scanner) resolveStart(ctx context.Context, safe uint64) (uint64, error) {
+	at, ok, err := blocktime.Parse(s.source.StartRound)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return resolveStartRound(s.source.StartRound, safe)
+	}
+	return blocktime.Search(ctx, safe, at, func(ctx context.Context, round uint64) (time.Time, error) {
+		raw, err := s.client.BlockRaw(round).Do(ctx)
+		if err != nil {
+			return time.Time{}, err
+		}
+		block, err := decodeLeanBlock(raw)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(block.TimeStamp, 0), nil
+	})
 }
 
 func resolveStartRound(start string, safe uint64) (uint64, error) {
@@ -216,9 +477,3 @@ func resolveStartRound(start string, safe uint64) (uint64, error) {
 func digestToString(b []byte) string {
 	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
 }
-
-func decodeBlock(raw []byte, dest *sdk.Block) error {
-	h := &codec.MsgpackHandle{}
-	dec := codec.NewDecoderBytes(raw, h)
-	return dec.Decode(dest)
-}
@@ -3,13 +3,19 @@ package algorand
 import (
 	"bytes"
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/common"
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/v2/crypto"
 	"github.com/algorand/go-codec/codec"
 	sdk "github.com/algorand/go-algorand-sdk/v2/types"
 	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
 	"github.com/devblac/watch-tower/internal/storage"
 )
 
@@ -28,15 +34,15 @@ type fakeAlgod struct {
 	blockHashes map[uint64]string
 }
 
-func (f *fakeAlgod) Status() statusGetter {
+func (f *fakeAlgod) Status() StatusGetter {
 	return f.status
 }
 
-func (f *fakeAlgod) BlockRaw(round uint64) blockGetter {
+func (f *fakeAlgod) BlockRaw(round uint64) BlockGetter {
 	return fakeBlock{block: f.blocks[round]}
 }
 
-func (f *fakeAlgod) GetBlockHash(round uint64) blockHashGetter {
+func (f *fakeAlgod) GetBlockHash(round uint64) BlockHashGetter {
 	h := f.blockHashes[round]
 	if h == "" {
 		h = "hash"
@@ -160,9 +166,13 @@ func TestScannerReorgDetection(t *testing.T) {
 		t.Fatalf("new scanner: %v", err)
 	}
 	_, err = scanner.ProcessNext(ctx)
-	if err == nil || err != ErrReorgDetected {
+	var reorg *source.ReorgError
+	if !errors.As(err, &reorg) || !errors.Is(err, source.ErrReorgDetected) {
 		t.Fatalf("expected reorg err, got %v", err)
 	}
+	if reorg.Height != 2 || reorg.Depth() != 1 {
+		t.Fatalf("unexpected reorg: %+v", reorg)
+	}
 }
 
 func mustAddress() sdk.Address {
@@ -180,3 +190,269 @@ func newTestStore(t *testing.T) *storage.Store {
 	t.Cleanup(func() { _ = store.Close() })
 	return store
 }
+
+type balanceAlgod struct {
+	fakeAlgod
+	balance uint64
+}
+
+func (b *balanceAlgod) Balance(context.Context, string, uint64) (uint64, error) {
+	return b.balance, nil
+}
+
+func TestScannerBalanceHysteresis(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &balanceAlgod{balance: 20_000}
+	rule := config.Rule{ID: "hot_wallet", Source: "algo", Match: config.MatchSpec{
+		Type: config.MatchBalance, Account: "HOT", AssetID: 31566704, Below: 10_000, Hysteresis: 1_000, Interval: "1m",
+	}}
+	scanner, err := NewScanner(client, store, config.Source{ID: "algo", Type: "algorand"}, 0, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	scanner.nowFunc = func() time.Time { return now }
+
+	var got []source.Event
+	emit := func(ev source.Event) error {
+		got = append(got, ev)
+		return nil
+	}
+	// The balance dips, hovers inside the hysteresis band, recovers, then dips again.
+	for i, bal := range []uint64{20_000, 9_000, 10_500, 9_500, 11_000, 9_999} {
+		now = now.Add(time.Minute)
+		client.balance = bal
+		if err := scanner.checkBalances(ctx, uint64(i+1), emit); err != nil {
+			t.Fatalf("check %d: %v", i, err)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 alerts, got %d: %+v", len(got), got)
+	}
+	if got[0].Name != BalanceBelowEvent || got[0].Args["balance"] != uint64(9_000) || got[1].Args["balance"] != uint64(9_999) {
+		t.Fatalf("unexpected alerts: %+v", got)
+	}
+
+	// Within the interval the balance is not read again.
+	client.balance = 20_000
+	if err := scanner.checkBalances(ctx, 7, emit); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if v, _, _ := store.GetStateValue(ctx, "algo", "hot_wallet"); v.Value != balanceBelow {
+		t.Fatalf("expected no poll inside interval, state %q", v.Value)
+	}
+}
+
+func TestAlgodClientSendsHeaders(t *testing.T) {
+	var token, user, pass string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Algo-API-Token")
+		user, pass, _ = r.BasicAuth()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"last-round":42}`))
+	}))
+	defer server.Close()
+
+	src := config.Source{
+		RPCHeaders:   map[string]string{"X-Algo-API-Token": "from-headers"},
+		RPCBasicAuth: &config.BasicAuth{Username: "u", Password: "p"},
+		AlgodToken:   "t1",
+	}
+	cli, err := NewAlgodClient(server.URL, src.AlgodHeaders())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	st, err := cli.Status().Do(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if st.LastRound != 42 || token != "t1" || user != "u" || pass != "p" {
+		t.Fatalf("unexpected request: round=%d token=%q user=%q pass=%q", st.LastRound, token, user, pass)
+	}
+}
+
+func TestScannerMatchesInnerTxns(t *testing.T) {
+	store := newTestStore(t)
+	rules := []config.Rule{
+		{ID: "app", Source: "algo", Match: config.MatchSpec{Type: "app_call", AppID: 123}},
+		{ID: "asa", Source: "algo", Match: config.MatchSpec{Type: "asset_transfer"}},
+	}
+	appCall := func(id sdk.AppIndex, inner ...sdk.SignedTxnWithAD) sdk.SignedTxnWithAD {
+		return sdk.SignedTxnWithAD{
+			SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{
+				Type:              sdk.ApplicationCallTx,
+				Header:            sdk.Header{Sender: mustAddress()},
+				ApplicationFields: sdk.ApplicationFields{ApplicationCallTxnFields: sdk.ApplicationCallTxnFields{ApplicationID: id}},
+			}},
+			ApplyData: sdk.ApplyData{EvalDelta: sdk.EvalDelta{InnerTxns: inner}},
+		}
+	}
+	xfer := sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{
+		Type:                   sdk.AssetTransferTx,
+		Header:                 sdk.Header{Sender: mustAddress()},
+		AssetTransferTxnFields: sdk.AssetTransferTxnFields{XferAsset: 7, AssetAmount: 10},
+	}}}
+	// An unwatched router app sends an asset and calls the watched app.
+	block := sdk.Block{
+		BlockHeader: sdk.BlockHeader{Round: 1},
+		Payset:      []sdk.SignedTxnInBlock{{SignedTxnWithAD: appCall(999, xfer, appCall(555, appCall(123)))}},
+	}
+	client := &fakeAlgod{
+		status:      fakeStatus{resp: models.NodeStatus{LastRound: 1}},
+		blocks:      map[uint64]sdk.Block{1: block},
+		blockHashes: map[uint64]string{1: "hash1"},
+	}
+	scanner, err := NewScanner(client, store, config.Source{ID: "algo", Type: "algorand", StartRound: "1"}, 0, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	evs, err := scanner.ProcessNext(context.Background())
+	if err != nil {
+		t.Fatalf("process next: %v", err)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("expected 2 events, got %+v", evs)
+	}
+	txid := crypto.TransactionIDString(block.Payset[0].Txn)
+	if evs[0].RuleID != "asa" || evs[0].Args["inner_path"] != "0" || evs[0].TxHash != txid {
+		t.Fatalf("unexpected transfer event %+v", evs[0])
+	}
+	if evs[1].RuleID != "app" || evs[1].Args["inner_path"] != "1.0" || evs[1].AppID != 123 || evs[1].TxHash != txid {
+		t.Fatalf("unexpected app call event %+v", evs[1])
+	}
+}
+
+func TestScannerRekeyRule(t *testing.T) {
+	store := newTestStore(t)
+	account := mustAddress()
+	var attacker sdk.Address
+	copy(attacker[:], "ATTACKER")
+	rule := config.Rule{ID: "rekeys", Source: "algo", Match: config.MatchSpec{Type: config.MatchRekey, Addresses: []string{account.String()}}}
+	payment := func(rekeyTo sdk.Address) sdk.SignedTxnInBlock {
+		return sdk.SignedTxnInBlock{SignedTxnWithAD: sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{
+			Type:   sdk.PaymentTx,
+			Header: sdk.Header{Sender: account, RekeyTo: rekeyTo},
+		}}}}
+	}
+	block := sdk.Block{
+		BlockHeader: sdk.BlockHeader{Round: 1},
+		Payset:      []sdk.SignedTxnInBlock{payment(sdk.Address{}), payment(attacker)},
+	}
+	client := &fakeAlgod{
+		status:      fakeStatus{resp: models.NodeStatus{LastRound: 1}},
+		blocks:      map[uint64]sdk.Block{1: block},
+		blockHashes: map[uint64]string{1: "hash1"},
+	}
+	scanner, err := NewScanner(client, store, config.Source{ID: "algo", Type: "algorand", StartRound: "1"}, 0, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	evs, err := scanner.ProcessNext(context.Background())
+	if err != nil {
+		t.Fatalf("process next: %v", err)
+	}
+	if len(evs) != 1 || evs[0].Name != config.MatchRekey || evs[0].Args["auth_addr"] != attacker.String() || evs[0].Args["reset"] != false {
+		t.Fatalf("unexpected events %+v", evs)
+	}
+}
+
+func TestScannerProcessesSeveralRoundsPerCall(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	rule := config.Rule{ID: "pay", Source: "algo", Match: config.MatchSpec{Type: "payment"}}
+	// Round n's parent is round n-1, whose hash is the digest {n-1}.
+	blocks := map[uint64]sdk.Block{}
+	hashes := map[uint64]string{}
+	for n := uint64(1); n <= 5; n++ {
+		blocks[n] = sdk.Block{
+			BlockHeader: sdk.BlockHeader{Round: sdk.Round(n), Branch: sdk.BlockHash{byte(n - 1)}},
+			Payset: []sdk.SignedTxnInBlock{{SignedTxnWithAD: sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: sdk.Transaction{
+				Type:   sdk.PaymentTx,
+				Header: sdk.Header{Sender: mustAddress(), FirstValid: sdk.Round(n)},
+			}}}}},
+		}
+		hashes[n] = digestToString([]byte{byte(n), 31: 0})
+	}
+	client := &fakeAlgod{
+		status:      fakeStatus{resp: models.NodeStatus{LastRound: 5}},
+		blocks:      blocks,
+		blockHashes: hashes,
+	}
+	src := config.Source{ID: "algo", Type: "algorand", StartRound: "1", MaxBlocksPerTick: 3}
+	scanner, err := NewScanner(client, store, src, 0, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	scanner.SetStopHeight(4)
+
+	for i, want := range []struct {
+		rounds []uint64
+		cursor uint64
+	}{
+		{[]uint64{1, 2, 3}, 3},
+		{[]uint64{4}, 4}, // stops at the stop height
+	} {
+		evs, err := scanner.ProcessNext(ctx)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if len(evs) != len(want.rounds) {
+			t.Fatalf("call %d: expected %d events, got %d", i, len(want.rounds), len(evs))
+		}
+		for j, ev := range evs {
+			if ev.Height != want.rounds[j] || ev.Hash != hashes[want.rounds[j]] {
+				t.Fatalf("call %d: unexpected event %+v", i, ev)
+			}
+		}
+		if h, _, _, _ := store.GetCursor(ctx, "algo"); h != want.cursor {
+			t.Fatalf("call %d: cursor at %d, want %d", i, h, want.cursor)
+		}
+	}
+}
+
+func TestScannerGroupRule(t *testing.T) {
+	store := newTestStore(t)
+	rule := config.Rule{ID: "swap", Source: "algo", Match: config.MatchSpec{Type: config.MatchGroup, Group: []config.GroupMember{
+		{Name: "deposit", MatchSpec: config.MatchSpec{Type: "payment"}},
+		{Name: "call", MatchSpec: config.MatchSpec{Type: "app_call", AppID: 5}},
+	}}}
+	txn := func(group byte, tx sdk.Transaction) sdk.SignedTxnInBlock {
+		tx.Sender = mustAddress()
+		tx.Group = sdk.Digest{group}
+		return sdk.SignedTxnInBlock{SignedTxnWithAD: sdk.SignedTxnWithAD{SignedTxn: sdk.SignedTxn{Txn: tx}}}
+	}
+	call := sdk.Transaction{Type: sdk.ApplicationCallTx, ApplicationFields: sdk.ApplicationFields{ApplicationCallTxnFields: sdk.ApplicationCallTxnFields{ApplicationID: 5}}}
+	pay := sdk.Transaction{Type: sdk.PaymentTx, PaymentTxnFields: sdk.PaymentTxnFields{Amount: 1000}}
+	block := sdk.Block{
+		BlockHeader: sdk.BlockHeader{Round: 1},
+		Payset: []sdk.SignedTxnInBlock{
+			txn(1, call), txn(1, pay), // matches, in either order
+			txn(2, pay), txn(2, pay), // no app call
+			txn(0, call), txn(0, pay), // not grouped
+		},
+	}
+	client := &fakeAlgod{
+		status:      fakeStatus{resp: models.NodeStatus{LastRound: 1}},
+		blocks:      map[uint64]sdk.Block{1: block},
+		blockHashes: map[uint64]string{1: "hash1"},
+	}
+	scanner, err := NewScanner(client, store, config.Source{ID: "algo", Type: "algorand", StartRound: "1"}, 0, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	evs, err := scanner.ProcessNext(context.Background())
+	if err != nil {
+		t.Fatalf("process next: %v", err)
+	}
+	if len(evs) != 1 {
+		t.Fatalf("expected one group event, got %+v", evs)
+	}
+	ev := evs[0]
+	if ev.Name != config.MatchGroup || ev.Args["group_size"] != 2 || ev.Args["deposit.amount"] != uint64(1000) || ev.Args["deposit.index"] != 1 || ev.Args["call.index"] != 0 {
+		t.Fatalf("unexpected group event %+v", ev)
+	}
+	if ev.TxHash == "" || ev.Args["call.tx_id"] != ev.TxHash {
+		t.Fatalf("expected group tx hash to be its first txn, got %+v", ev)
+	}
+}
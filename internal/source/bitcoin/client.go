@@ -0,0 +1,448 @@
+package bitcoin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/rpclimit"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// Client is what the scanner needs from a Bitcoin node or indexer. Block
+// returns only the header; BlockTxs pages through the transactions, so a
+// rate limit applies to each page of a large block.
+type Client interface {
+	TipHeight(ctx context.Context) (uint64, error)
+	BlockHash(ctx context.Context, height uint64) (string, error)
+	Block(ctx context.Context, hash string) (*Block, error)
+	// BlockTxs returns the block's transactions from index start on. A
+	// client that pages them may return fewer than the rest.
+	BlockTxs(ctx context.Context, hash string, start int) ([]Tx, error)
+}
+
+// Block is a block header and its transaction count.
+type Block struct {
+	Hash     string
+	PrevHash string
+	Height   uint64
+	Time     int64
+	TxCount  int
+}
+
+// Tx is a transaction with the previous outputs its inputs spend, so the
+// senders and amounts are known. Values are in satoshis.
+type Tx struct {
+	Txid     string
+	Coinbase bool
+	Fee      uint64 // zero for coinbase transactions
+	Inputs   []Input
+	Outputs  []Output
+}
+
+// Input is a spent output. Address is empty for scripts without one, and
+// for the input of a coinbase transaction.
+type Input struct {
+	Address string
+	Value   uint64
+}
+
+// Output is a transaction output. Address is empty for scripts without one,
+// such as OP_RETURN.
+type Output struct {
+	Address string
+	Value   uint64
+}
+
+// Value returns the sum of the transaction's outputs.
+func (t *Tx) Value() uint64 {
+	var v uint64
+	for _, o := range t.Outputs {
+		v += o.Value
+	}
+	return v
+}
+
+// JSON-RPC error codes of bitcoind.
+const (
+	codeInWarmup = -28
+)
+
+// RPCError is an error answer from bitcoind.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// Transient reports whether bitcoind is still loading its block index.
+func (e *RPCError) Transient() bool { return e.Code == codeInWarmup }
+
+// DefaultTimeout bounds each request to the node or indexer.
+const DefaultTimeout = 30 * time.Second
+
+// endpoints sends requests to the first of several URLs that answers. A
+// request that fails to connect or gets a 5xx answer is retried on the next.
+type endpoints struct {
+	urls    []string
+	header  http.Header
+	http    *http.Client
+	limiter *rpclimit.Limiter
+}
+
+func newEndpoints(urls []string, header http.Header, rps float64) (endpoints, error) {
+	if len(urls) == 0 {
+		return endpoints{}, errors.New("no endpoints")
+	}
+	return endpoints{urls: urls, header: header, http: &http.Client{Timeout: DefaultTimeout}, limiter: source.NewLimiter(rps)}, nil
+}
+
+// do sends a request built by build to each URL in turn until one answers
+// without failing over, and returns the answer's body. Throttled and
+// transient failures are retried with backoff.
+func (e endpoints) do(ctx context.Context, what string, build func(ctx context.Context, url string) (*http.Request, error)) ([]byte, error) {
+	var body []byte
+	err := e.limiter.Do(ctx, func() error {
+		var lastErr error
+		for _, url := range e.urls {
+			var err error
+			body, err = e.send(ctx, url, build)
+			if err == nil || !source.FailsOver(err) {
+				return err
+			}
+			lastErr = err
+		}
+		return fmt.Errorf("%s: %w", what, lastErr)
+	})
+	return body, err
+}
+
+func (e endpoints) send(ctx context.Context, url string, build func(ctx context.Context, url string) (*http.Request, error)) ([]byte, error) {
+	req, err := build(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	for k, v := range e.header {
+		req.Header[k] = v
+	}
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		// bitcoind answers RPC errors with a 500 and the error in the body.
+		var rpcResp struct {
+			Error *RPCError `json:"error"`
+		}
+		if json.Unmarshal(body, &rpcResp) == nil && rpcResp.Error != nil {
+			return nil, rpcResp.Error
+		}
+		msg := bytes.TrimSpace(body)
+		if len(msg) > 512 {
+			msg = msg[:512]
+		}
+		return nil, &source.HTTPError{Status: resp.StatusCode, Body: string(msg)}
+	}
+	return body, nil
+}
+
+// RPCClient calls the JSON-RPC API of bitcoind. Blocks are read with
+// getblock verbosity 3, which includes the outputs inputs spend and needs
+// Bitcoin Core 23 or later.
+type RPCClient struct {
+	endpoints
+}
+
+// NewRPCClient builds a client for a source's rpc_url, sending header (such
+// as rpc_basic_auth) with every request and making at most rps requests per
+// second (0 for no cap).
+func NewRPCClient(urls []string, header http.Header, rps float64) (*RPCClient, error) {
+	e, err := newEndpoints(urls, header, rps)
+	if err != nil {
+		return nil, err
+	}
+	return &RPCClient{e}, nil
+}
+
+// TipHeight implements Client.
+func (c *RPCClient) TipHeight(ctx context.Context) (uint64, error) {
+	var n uint64
+	err := c.call(ctx, "getblockcount", []any{}, &n)
+	return n, err
+}
+
+// BlockHash implements Client.
+func (c *RPCClient) BlockHash(ctx context.Context, height uint64) (string, error) {
+	var hash string
+	err := c.call(ctx, "getblockhash", []any{height}, &hash)
+	return hash, err
+}
+
+// Block implements Client.
+func (c *RPCClient) Block(ctx context.Context, hash string) (*Block, error) {
+	var h struct {
+		Hash     string `json:"hash"`
+		Height   uint64 `json:"height"`
+		Time     int64  `json:"time"`
+		PrevHash string `json:"previousblockhash"`
+		NTx      int    `json:"nTx"`
+	}
+	if err := c.call(ctx, "getblockheader", []any{hash, true}, &h); err != nil {
+		return nil, err
+	}
+	return &Block{Hash: h.Hash, PrevHash: h.PrevHash, Height: h.Height, Time: h.Time, TxCount: h.NTx}, nil
+}
+
+// BlockTxs implements Client. bitcoind returns the whole block at once.
+func (c *RPCClient) BlockTxs(ctx context.Context, hash string, start int) ([]Tx, error) {
+	var b struct {
+		Tx []struct {
+			Txid string     `json:"txid"`
+			Fee  *btcAmount `json:"fee"`
+			Vin  []struct {
+				Coinbase string `json:"coinbase"`
+				Prevout  *struct {
+					Value        btcAmount `json:"value"`
+					ScriptPubKey struct {
+						Address string `json:"address"`
+					} `json:"scriptPubKey"`
+				} `json:"prevout"`
+			} `json:"vin"`
+			Vout []struct {
+				Value        btcAmount `json:"value"`
+				ScriptPubKey struct {
+					Address string `json:"address"`
+				} `json:"scriptPubKey"`
+			} `json:"vout"`
+		} `json:"tx"`
+	}
+	if err := c.call(ctx, "getblock", []any{hash, 3}, &b); err != nil {
+		return nil, err
+	}
+	if start > len(b.Tx) {
+		return nil, fmt.Errorf("block %s has %d transactions, asked from %d", hash, len(b.Tx), start)
+	}
+	out := make([]Tx, 0, len(b.Tx)-start)
+	for _, raw := range b.Tx[start:] {
+		tx := Tx{Txid: raw.Txid}
+		if raw.Fee != nil {
+			tx.Fee = uint64(*raw.Fee)
+		}
+		for _, in := range raw.Vin {
+			if in.Coinbase != "" {
+				tx.Coinbase = true
+				continue
+			}
+			if in.Prevout == nil {
+				return nil, fmt.Errorf("tx %s: input without prevout; getblock verbosity 3 needs Bitcoin Core 23 or later", raw.Txid)
+			}
+			tx.Inputs = append(tx.Inputs, Input{Address: in.Prevout.ScriptPubKey.Address, Value: uint64(in.Prevout.Value)})
+		}
+		for _, o := range raw.Vout {
+			tx.Outputs = append(tx.Outputs, Output{Address: o.ScriptPubKey.Address, Value: uint64(o.Value)})
+		}
+		out = append(out, tx)
+	}
+	return out, nil
+}
+
+func (c *RPCClient) call(ctx context.Context, method string, params []any, out any) error {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "1.0", "id": "watch-tower", "method": method, "params": params})
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", method, err)
+	}
+	resp, err := c.do(ctx, method, func(ctx context.Context, url string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.Unmarshal(resp, &rpcResp); err != nil {
+		return fmt.Errorf("decode %s response: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if err := json.Unmarshal(rpcResp.Result, out); err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// btcAmount is an amount bitcoind writes in BTC, held in satoshis.
+type btcAmount uint64
+
+func (a *btcAmount) UnmarshalJSON(b []byte) error {
+	r, ok := new(big.Rat).SetString(string(b))
+	if !ok || r.Sign() < 0 {
+		return fmt.Errorf("invalid amount %s", b)
+	}
+	r.Mul(r, big.NewRat(1e8, 1))
+	if !r.IsInt() || !r.Num().IsUint64() {
+		return fmt.Errorf("invalid amount %s", b)
+	}
+	*a = btcAmount(r.Num().Uint64())
+	return nil
+}
+
+// esploraPageSize is how many transactions Esplora returns per page.
+const esploraPageSize = 25
+
+// EsploraClient calls the REST API of an Esplora indexer, such as
+// blockstream.info/api or mempool.space/api.
+type EsploraClient struct {
+	endpoints
+}
+
+// NewEsploraClient builds a client for a source's esplora_url, sending
+// header with every request and making at most rps requests per second (0
+// for no cap).
+func NewEsploraClient(urls []string, header http.Header, rps float64) (*EsploraClient, error) {
+	e, err := newEndpoints(urls, header, rps)
+	if err != nil {
+		return nil, err
+	}
+	return &EsploraClient{e}, nil
+}
+
+// NewSourceClient builds the client a bitcoin source reads through: its
+// esplora_url when set, otherwise bitcoind at its rpc_url, limited to its
+// max_rps.
+func NewSourceClient(src config.Source) (Client, error) {
+	header := config.HTTPHeaders(src.RPCHeaders, src.RPCBasicAuth)
+	var c Client
+	var err error
+	if len(src.EsploraURL) > 0 {
+		c, err = NewEsploraClient(src.EsploraURL, header, src.MaxRPS)
+	} else {
+		c, err = NewRPCClient(src.RPCURL, header, src.MaxRPS)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// TipHeight implements Client.
+func (c *EsploraClient) TipHeight(ctx context.Context) (uint64, error) {
+	body, err := c.get(ctx, "/blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(body)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse tip height: %w", err)
+	}
+	return n, nil
+}
+
+// BlockHash implements Client.
+func (c *EsploraClient) BlockHash(ctx context.Context, height uint64) (string, error) {
+	body, err := c.get(ctx, fmt.Sprintf("/block-height/%d", height))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// Block implements Client.
+func (c *EsploraClient) Block(ctx context.Context, hash string) (*Block, error) {
+	body, err := c.get(ctx, "/block/"+hash)
+	if err != nil {
+		return nil, err
+	}
+	var b struct {
+		ID        string `json:"id"`
+		Height    uint64 `json:"height"`
+		Timestamp int64  `json:"timestamp"`
+		PrevHash  string `json:"previousblockhash"`
+		TxCount   int    `json:"tx_count"`
+	}
+	if err := json.Unmarshal(body, &b); err != nil {
+		return nil, fmt.Errorf("decode block %s: %w", hash, err)
+	}
+	return &Block{Hash: b.ID, PrevHash: b.PrevHash, Height: b.Height, Time: b.Timestamp, TxCount: b.TxCount}, nil
+}
+
+// BlockTxs implements Client. Esplora pages transactions 25 at a time, from
+// a multiple of 25.
+func (c *EsploraClient) BlockTxs(ctx context.Context, hash string, start int) ([]Tx, error) {
+	page := start - start%esploraPageSize
+	body, err := c.get(ctx, fmt.Sprintf("/block/%s/txs/%d", hash, page))
+	if err != nil {
+		return nil, err
+	}
+	var raw []struct {
+		Txid string `json:"txid"`
+		Fee  uint64 `json:"fee"`
+		Vin  []struct {
+			IsCoinbase bool `json:"is_coinbase"`
+			Prevout    *struct {
+				Address string `json:"scriptpubkey_address"`
+				Value   uint64 `json:"value"`
+			} `json:"prevout"`
+		} `json:"vin"`
+		Vout []struct {
+			Address string `json:"scriptpubkey_address"`
+			Value   uint64 `json:"value"`
+		} `json:"vout"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("decode block %s txs: %w", hash, err)
+	}
+	if skip := start - page; skip < len(raw) {
+		raw = raw[skip:]
+	} else {
+		raw = nil
+	}
+	out := make([]Tx, 0, len(raw))
+	for _, r := range raw {
+		tx := Tx{Txid: r.Txid, Fee: r.Fee}
+		for _, in := range r.Vin {
+			if in.IsCoinbase {
+				tx.Coinbase = true
+				continue
+			}
+			if in.Prevout != nil {
+				tx.Inputs = append(tx.Inputs, Input{Address: in.Prevout.Address, Value: in.Prevout.Value})
+			}
+		}
+		for _, o := range r.Vout {
+			tx.Outputs = append(tx.Outputs, Output{Address: o.Address, Value: o.Value})
+		}
+		out = append(out, tx)
+	}
+	return out, nil
+}
+
+func (c *EsploraClient) get(ctx context.Context, path string) ([]byte, error) {
+	return c.do(ctx, "GET "+path, func(ctx context.Context, base string) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(base, "/")+path, nil)
+	})
+}
@@ -0,0 +1,108 @@
+package bitcoin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+func TestRPCClientBlockTxs(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		var req struct {
+			Method string `json:"method"`
+			Params []any  `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch req.Method {
+		case "getblock":
+			if req.Params[1].(float64) != 3 {
+				t.Errorf("expected verbosity 3, got %v", req.Params[1])
+			}
+			_, _ = w.Write([]byte(`{"result":{"hash":"00ab","tx":[
+				{"txid":"cb","vin":[{"coinbase":"03a0bb0d"}],"vout":[{"value":3.13510000,"n":0,"scriptPubKey":{"address":"` + exchange + `"}}]},
+				{"txid":"t1","fee":0.00001200,"vin":[{"txid":"p","vout":0,"prevout":{"value":0.1,"scriptPubKey":{"address":"` + whale + `"}}}],
+				 "vout":[{"value":0.09998800,"n":0,"scriptPubKey":{"address":"` + change + `"}},{"value":0,"n":1,"scriptPubKey":{"type":"nulldata"}}]}
+			]},"error":null,"id":"watch-tower"}`))
+		default:
+			// bitcoind answers RPC errors with a 500.
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"result":null,"error":{"code":-8,"message":"Block height out of range"},"id":"watch-tower"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewRPCClient([]string{srv.URL}, http.Header{"Authorization": {"Basic dTpw"}}, 0)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	txs, err := c.BlockTxs(context.Background(), "00ab", 0)
+	if err != nil {
+		t.Fatalf("block txs: %v", err)
+	}
+	if gotAuth != "Basic dTpw" {
+		t.Fatalf("expected basic auth, got %q", gotAuth)
+	}
+	if len(txs) != 2 || !txs[0].Coinbase || txs[0].Outputs[0].Value != 313510000 {
+		t.Fatalf("unexpected coinbase %+v", txs)
+	}
+	tx := txs[1]
+	if tx.Fee != 1200 || tx.Inputs[0].Address != whale || tx.Inputs[0].Value != 10000000 || tx.Value() != 9998800 || tx.Outputs[1].Address != "" {
+		t.Fatalf("unexpected tx %+v", tx)
+	}
+
+	_, err = c.BlockHash(context.Background(), 900000)
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -8 {
+		t.Fatalf("expected rpc error -8, got %v", err)
+	}
+}
+
+func TestEsploraClientPagesTxs(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch {
+		case r.URL.Path == "/api/blocks/tip/height":
+			_, _ = w.Write([]byte("870000"))
+		case strings.HasPrefix(r.URL.Path, "/api/block/00ab/txs/"):
+			var txs []string
+			for i := range esploraPageSize {
+				txs = append(txs, fmt.Sprintf(`{"txid":"t%d","fee":200,"vin":[{"is_coinbase":false,"prevout":{"scriptpubkey_address":"%s","value":5000}}],"vout":[{"scriptpubkey_address":"%s","value":4800}]}`, i, whale, exchange))
+			}
+			_, _ = w.Write([]byte("[" + strings.Join(txs, ",") + "]"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c, _ := NewEsploraClient([]string{srv.URL + "/api/"}, nil, 0)
+	tip, err := c.TipHeight(context.Background())
+	if err != nil || tip != 870000 {
+		t.Fatalf("expected tip 870000, got %d (%v)", tip, err)
+	}
+	txs, err := c.BlockTxs(context.Background(), "00ab", 30)
+	if err != nil {
+		t.Fatalf("block txs: %v", err)
+	}
+	if paths[len(paths)-1] != "/api/block/00ab/txs/25" || len(txs) != 20 || txs[0].Txid != "t5" {
+		t.Fatalf("expected the page from 25 without its first 5, got %s and %d txs", paths[len(paths)-1], len(txs))
+	}
+	if txs[0].Inputs[0].Address != whale || txs[0].Fee != 200 || txs[0].Value() != 4800 {
+		t.Fatalf("unexpected tx %+v", txs[0])
+	}
+	_, err = c.Block(context.Background(), "missing")
+	var httpErr *source.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Status != http.StatusNotFound {
+		t.Fatalf("expected 404, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package bitcoin
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// ActivityEvent is the event name of watch_address matches on every chain.
+const ActivityEvent = "address_activity"
+
+// address matches a base58 (P2PKH, P2SH) or bech32 (segwit, taproot)
+// address on mainnet, testnet or regtest.
+var address = regexp.MustCompile(`^([13mn2][1-9A-HJ-NP-Za-km-z]{25,34}|(bc|tb|bcrt)1[02-9ac-hj-np-z]{8,87})$`)
+
+// normalize lowercases bech32 addresses, which are case-insensitive and
+// which nodes print in lowercase.
+func normalize(a string) string {
+	if l := strings.ToLower(a); strings.HasPrefix(l, "bc1") || strings.HasPrefix(l, "tb1") || strings.HasPrefix(l, "bcrt1") {
+		return l
+	}
+	return a
+}
+
+// RuleMatcher applies one rule to the transactions of a block.
+type RuleMatcher struct {
+	rule     config.Rule
+	kind     string
+	minValue uint64              // large_tx
+	addrs    map[string]struct{} // large_tx (optional), watch_address
+}
+
+// NewRuleMatcher builds a matcher for Bitcoin rules.
+func NewRuleMatcher(rule config.Rule) (*RuleMatcher, error) {
+	mt := strings.ToLower(rule.Match.Type)
+	m := &RuleMatcher{rule: rule, kind: mt, addrs: map[string]struct{}{}}
+	switch mt {
+	case config.MatchLargeTx:
+		min, err := config.ParseSats(rule.Match.MinValue)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+		m.minValue = min
+		for _, a := range rule.Match.Addresses {
+			m.addrs[normalize(a)] = struct{}{}
+		}
+	case config.MatchWatchAddress:
+		// The list is shared with other chains, so non-Bitcoin entries are skipped.
+		for _, a := range rule.Match.Addresses {
+			if a = normalize(a); address.MatchString(a) {
+				m.addrs[a] = struct{}{}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("rule %s: unsupported match.type %s for bitcoin", rule.ID, rule.Match.Type)
+	}
+	return m, nil
+}
+
+// MatchTx returns the event a transaction matches, if any.
+func (m *RuleMatcher) MatchTx(tx *Tx) (source.Event, bool) {
+	var ev source.Event
+	var ok bool
+	switch m.kind {
+	case config.MatchLargeTx:
+		ev, ok = m.matchLarge(tx)
+	case config.MatchWatchAddress:
+		ev, ok = m.matchActivity(tx)
+	}
+	if !ok {
+		return source.Event{}, false
+	}
+	ev.RuleID = m.rule.ID
+	ev.TxHash = tx.Txid
+	return ev, true
+}
+
+// matchLarge matches a transaction whose outputs add up to at least
+// min_value. Coinbase transactions, which pay the miner every block, never
+// match.
+func (m *RuleMatcher) matchLarge(tx *Tx) (source.Event, bool) {
+	if tx.Coinbase || tx.Value() < m.minValue {
+		return source.Event{}, false
+	}
+	from, to := inputAddresses(tx), outputAddresses(tx)
+	if len(m.addrs) > 0 && !m.watchesAny(from) && !m.watchesAny(to) {
+		return source.Event{}, false
+	}
+	return source.Event{Name: config.MatchLargeTx, Args: map[string]any{
+		"value":   tx.Value(),
+		"fee":     tx.Fee,
+		"from":    from,
+		"to":      to,
+		"inputs":  len(tx.Inputs),
+		"outputs": len(tx.Outputs),
+	}}, true
+}
+
+// matchActivity matches a transaction spending from or paying to a watched
+// address. role is "sender" when the address is among the inputs and
+// "receiver" otherwise; sent and received are the satoshis it spent and got.
+func (m *RuleMatcher) matchActivity(tx *Tx) (source.Event, bool) {
+	watched, role := "", ""
+	for _, in := range tx.Inputs {
+		if _, ok := m.addrs[in.Address]; ok {
+			watched, role = in.Address, "sender"
+			break
+		}
+	}
+	if role == "" {
+		for _, o := range tx.Outputs {
+			if _, ok := m.addrs[o.Address]; ok {
+				watched, role = o.Address, "receiver"
+				break
+			}
+		}
+	}
+	if role == "" {
+		return source.Event{}, false
+	}
+	var sent, received uint64
+	for _, in := range tx.Inputs {
+		if in.Address == watched {
+			sent += in.Value
+		}
+	}
+	for _, o := range tx.Outputs {
+		if o.Address == watched {
+			received += o.Value
+		}
+	}
+	return source.Event{Name: ActivityEvent, Args: map[string]any{
+		"kind":     "tx",
+		"watched":  watched,
+		"role":     role,
+		"sent":     sent,
+		"received": received,
+		"value":    tx.Value(),
+		"fee":      tx.Fee,
+		"coinbase": tx.Coinbase,
+	}}, true
+}
+
+func (m *RuleMatcher) watchesAny(addrs []string) bool {
+	for _, a := range addrs {
+		if _, ok := m.addrs[a]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// inputAddresses returns the distinct addresses a transaction spends from.
+func inputAddresses(tx *Tx) []string {
+	addrs := make([]string, 0, len(tx.Inputs))
+	for _, in := range tx.Inputs {
+		addrs = appendAddress(addrs, in.Address)
+	}
+	return addrs
+}
+
+// outputAddresses returns the distinct addresses a transaction pays to.
+func outputAddresses(tx *Tx) []string {
+	addrs := make([]string, 0, len(tx.Outputs))
+	for _, o := range tx.Outputs {
+		addrs = appendAddress(addrs, o.Address)
+	}
+	return addrs
+}
+
+func appendAddress(addrs []string, a string) []string {
+	if a == "" || slices.Contains(addrs, a) {
+		return addrs
+	}
+	return append(addrs, a)
+}
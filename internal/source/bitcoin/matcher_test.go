@@ -0,0 +1,106 @@
+package bitcoin
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+)
+
+const (
+	exchange = "bc1qgdjqv0av3q56jvd82tkdjpy7gdp9ut8tlqmgrpmv24sq90ecnvqqjwvw97"
+	whale    = "1BoatSLRHtKNngkdXEeobR76b53LETtpyT"
+	change   = "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy"
+)
+
+func sampleTx() *Tx {
+	return &Tx{
+		Txid: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+		Fee:  1200,
+		Inputs: []Input{
+			{Address: whale, Value: 30_0000_0000},
+			{Address: whale, Value: 25_0000_0000},
+		},
+		Outputs: []Output{
+			{Address: exchange, Value: 50_0000_0000},
+			{Address: change, Value: 4_9999_8800},
+			{Value: 0}, // OP_RETURN
+		},
+	}
+}
+
+func TestMatcher_LargeTx(t *testing.T) {
+	rule := config.Rule{ID: "big", Match: config.MatchSpec{Type: config.MatchLargeTx, MinValue: "50 btc"}}
+	m, err := NewRuleMatcher(rule)
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	ev, ok := m.MatchTx(sampleTx())
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	if ev.Name != config.MatchLargeTx || ev.RuleID != "big" || ev.TxHash != sampleTx().Txid {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if ev.Args["value"] != uint64(54_9999_8800) || ev.Args["fee"] != uint64(1200) {
+		t.Fatalf("unexpected amounts %v", ev.Args)
+	}
+	if from := ev.Args["from"].([]string); !slices.Equal(from, []string{whale}) {
+		t.Fatalf("unexpected from %v", from)
+	}
+	if to := ev.Args["to"].([]string); !slices.Equal(to, []string{exchange, change}) {
+		t.Fatalf("unexpected to %v", to)
+	}
+
+	rule.Match.MinValue = "60 btc"
+	m, _ = NewRuleMatcher(rule)
+	if _, ok := m.MatchTx(sampleTx()); ok {
+		t.Fatal("expected no match below min_value")
+	}
+
+	// Scoped to addresses, only transactions touching one match.
+	rule.Match.MinValue = "1000"
+	rule.Match.Addresses = []string{"bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq"}
+	m, _ = NewRuleMatcher(rule)
+	if _, ok := m.MatchTx(sampleTx()); ok {
+		t.Fatal("expected no match without a watched address")
+	}
+	rule.Match.Addresses = []string{"BC1QGDJQV0AV3Q56JVD82TKDJPY7GDP9UT8TLQMGRPMV24SQ90ECNVQQJWVW97"}
+	m, _ = NewRuleMatcher(rule)
+	if _, ok := m.MatchTx(sampleTx()); !ok {
+		t.Fatal("expected a match for an uppercase bech32 address")
+	}
+
+	coinbase := &Tx{Txid: "cb", Coinbase: true, Outputs: []Output{{Address: exchange, Value: 3_1250_0000}}}
+	if _, ok := m.MatchTx(coinbase); ok {
+		t.Fatal("expected coinbase transactions not to match")
+	}
+}
+
+func TestMatcher_WatchAddress(t *testing.T) {
+	rule := config.Rule{ID: "watch", Match: config.MatchSpec{
+		Type:      config.MatchWatchAddress,
+		Addresses: []string{"0x00000000219ab540356cBB839Cbe05303d7705Fa", change},
+	}}
+	m, err := NewRuleMatcher(rule)
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	if len(m.addrs) != 1 {
+		t.Fatalf("expected the EVM address to be skipped, got %v", m.addrs)
+	}
+	ev, ok := m.MatchTx(sampleTx())
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	if ev.Name != ActivityEvent || ev.Args["watched"] != change || ev.Args["role"] != "receiver" || ev.Args["received"] != uint64(4_9999_8800) || ev.Args["sent"] != uint64(0) {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+
+	rule.Match.Addresses = []string{whale}
+	m, _ = NewRuleMatcher(rule)
+	ev, ok = m.MatchTx(sampleTx())
+	if !ok || ev.Args["role"] != "sender" || ev.Args["sent"] != uint64(55_0000_0000) {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+}
@@ -0,0 +1,165 @@
+package bitcoin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// fakeClient serves a chain of blocks by height, paging their transactions
+// pageSize at a time like Esplora.
+type fakeClient struct {
+	tip      uint64
+	chain    map[uint64]string // height -> hash
+	blocks   map[string]*Block
+	txs      map[string][]Tx
+	pageSize int
+	pages    int
+}
+
+func (f *fakeClient) TipHeight(ctx context.Context) (uint64, error) {
+	return f.tip, nil
+}
+
+func (f *fakeClient) BlockHash(ctx context.Context, height uint64) (string, error) {
+	hash, ok := f.chain[height]
+	if !ok {
+		return "", &RPCError{Code: -8, Message: "Block height out of range"}
+	}
+	return hash, nil
+}
+
+func (f *fakeClient) Block(ctx context.Context, hash string) (*Block, error) {
+	b, ok := f.blocks[hash]
+	if !ok {
+		return nil, &source.HTTPError{Status: 404, Body: "Block not found"}
+	}
+	return b, nil
+}
+
+func (f *fakeClient) BlockTxs(ctx context.Context, hash string, start int) ([]Tx, error) {
+	f.pages++
+	txs := f.txs[hash][start:]
+	if f.pageSize > 0 && len(txs) > f.pageSize {
+		txs = txs[:f.pageSize]
+	}
+	return txs, nil
+}
+
+// add puts a block at height on the chain, building on whatever is below it.
+func (f *fakeClient) add(height uint64, hash string, txs ...Tx) {
+	if f.chain == nil {
+		f.chain, f.blocks, f.txs = map[uint64]string{}, map[string]*Block{}, map[string][]Tx{}
+	}
+	f.chain[height] = hash
+	f.blocks[hash] = &Block{Hash: hash, PrevHash: f.chain[height-1], Height: height, Time: int64(1700000000 + height*600), TxCount: len(txs)}
+	f.txs[hash] = txs
+	f.tip = max(f.tip, height)
+}
+
+func newTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.Open(t.TempDir() + "/db.sqlite")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestScannerPagesTransactions(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &fakeClient{pageSize: 2}
+	client.add(99, "hash99")
+	var txs []Tx
+	for i := range 5 {
+		txs = append(txs, Tx{Txid: fmt.Sprintf("tx%d", i), Outputs: []Output{{Address: exchange, Value: 1000}}})
+	}
+	txs[3] = *sampleTx()
+	client.add(100, "hash100", txs...)
+	client.add(101, "hash101")
+
+	src := config.Source{ID: "btc", Type: "bitcoin", StartBlock: "100"}
+	rule := config.Rule{ID: "big", Source: "btc", Match: config.MatchSpec{Type: config.MatchLargeTx, MinValue: "10 btc"}}
+	sc, err := NewScanner(client, store, src, 1, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	evs, err := sc.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process next: %v", err)
+	}
+	if len(evs) != 1 || evs[0].TxHash != sampleTx().Txid || evs[0].Height != 100 || evs[0].Hash != "hash100" || evs[0].Chain != Chain || evs[0].Timestamp.Unix() != 1700060000 {
+		t.Fatalf("unexpected events %+v", evs)
+	}
+	if client.pages != 3 {
+		t.Fatalf("expected 3 pages of transactions, got %d", client.pages)
+	}
+	height, hash, _, _ := store.GetCursor(ctx, "btc")
+	if height != 100 || hash != "hash100" {
+		t.Fatalf("expected cursor at 100/hash100, got %d/%s", height, hash)
+	}
+	// Block 101 has one confirmation only.
+	if evs, err := sc.ProcessNext(ctx); err != nil || len(evs) != 0 {
+		t.Fatalf("expected nothing to scan, got %v (%v)", evs, err)
+	}
+	if height, _, _, _ := store.GetCursor(ctx, "btc"); height != 100 {
+		t.Fatalf("expected cursor to stay at 100, got %d", height)
+	}
+}
+
+func TestScannerRewindsToCommonAncestor(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &fakeClient{}
+	for h := uint64(100); h <= 103; h++ {
+		client.add(h, fmt.Sprintf("hash%d", h))
+	}
+	sc, err := NewScanner(client, store, config.Source{ID: "btc", Type: "bitcoin", StartBlock: "101", MaxBlocksPerTick: 10}, 0, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	sc.tipTTL = 0
+	if _, err := sc.ProcessNext(ctx); err != nil {
+		t.Fatalf("process next: %v", err)
+	}
+
+	// Blocks 102 and 103 are replaced by a longer fork.
+	for h := uint64(102); h <= 104; h++ {
+		client.add(h, fmt.Sprintf("fork%d", h))
+	}
+	_, err = sc.ProcessNext(ctx)
+	var rg *source.ReorgError
+	if !errors.As(err, &rg) || !errors.Is(err, source.ErrReorgDetected) {
+		t.Fatalf("expected reorg error, got %v", err)
+	}
+	if rg.Height != 104 || rg.From != 102 || rg.To != 103 || rg.Exceeded || rg.OldHash != "hash103" || rg.NewHash != "fork103" {
+		t.Fatalf("unexpected reorg %+v", rg)
+	}
+	height, hash, _, _ := store.GetCursor(ctx, "btc")
+	if height != 101 || hash != "hash101" {
+		t.Fatalf("expected cursor rewound to 101/hash101, got %d/%s", height, hash)
+	}
+	if _, err := sc.ProcessNext(ctx); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	if height, hash, _, _ := store.GetCursor(ctx, "btc"); height != 104 || hash != "fork104" {
+		t.Fatalf("expected cursor at 104/fork104, got %d/%s", height, hash)
+	}
+}
+
+func TestResolveStartHeight(t *testing.T) {
+	cases := map[string]uint64{"": 100, "latest": 100, "latest-10": 90, "latest-500": 0, "42": 42}
+	for start, want := range cases {
+		got, err := resolveStartHeight(start, 100)
+		if err != nil || got != want {
+			t.Fatalf("%q: got %d (%v), want %d", start, got, err, want)
+		}
+	}
+}
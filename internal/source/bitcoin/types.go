@@ -0,0 +1,4 @@
+package bitcoin
+
+// Chain identifier for Bitcoin. Its events' TxHash is the txid.
+const Chain = "bitcoin"
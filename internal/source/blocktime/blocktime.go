@@ -0,0 +1,47 @@
+// Package blocktime maps wall-clock times to block heights by binary search
+// over block timestamps, for time-based start offsets.
+package blocktime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Prefix marks a start_block or start_round given as a time, e.g. "time:2024-06-01".
+const Prefix = "time:"
+
+// Parse reads a "time:" start value. It accepts RFC 3339 timestamps and
+// dates (taken as midnight UTC). ok is false when start has no time prefix.
+func Parse(start string) (t time.Time, ok bool, err error) {
+	v, ok := strings.CutPrefix(start, Prefix)
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true, nil
+		}
+	}
+	return time.Time{}, true, fmt.Errorf("parse start time %q: want RFC 3339 or YYYY-MM-DD", v)
+}
+
+// Search returns the first height in [0, tip] whose block time is at or after
+// t, or tip when every block is older. timeAt returns a height's block time.
+func Search(ctx context.Context, tip uint64, t time.Time, timeAt func(context.Context, uint64) (time.Time, error)) (uint64, error) {
+	lo, hi := uint64(0), tip
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		at, err := timeAt(ctx, mid)
+		if err != nil {
+			return 0, fmt.Errorf("block time %d: %w", mid, err)
+		}
+		if at.Before(t) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
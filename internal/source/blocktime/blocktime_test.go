@@ -0,0 +1,55 @@
+package blocktime
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSearch(t *testing.T) {
+	genesis := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	// One block every 12 seconds.
+	timeAt := func(_ context.Context, h uint64) (time.Time, error) {
+		calls++
+		return genesis.Add(time.Duration(h) * 12 * time.Second), nil
+	}
+	ctx := context.Background()
+
+	cases := []struct {
+		at   time.Time
+		want uint64
+	}{
+		{genesis.Add(-time.Hour), 0},
+		{genesis.Add(120 * time.Second), 10},
+		{genesis.Add(121 * time.Second), 11},
+		{genesis.Add(time.Hour * 24 * 365), 1_000_000},
+	}
+	for _, c := range cases {
+		calls = 0
+		got, err := Search(ctx, 1_000_000, c.at, timeAt)
+		if err != nil || got != c.want {
+			t.Fatalf("search %s: got %d, %v; want %d", c.at, got, err, c.want)
+		}
+		if calls > 21 {
+			t.Fatalf("expected a binary search, made %d calls", calls)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, ok, err := Parse("latest-100"); ok || err != nil {
+		t.Fatalf("expected non-time start to be ignored")
+	}
+	got, ok, err := Parse("time:2024-06-01")
+	if !ok || err != nil || !got.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("date: %v %v %v", got, ok, err)
+	}
+	got, _, err = Parse("time:2024-06-01T08:30:00+02:00")
+	if err != nil || !got.Equal(time.Date(2024, 6, 1, 6, 30, 0, 0, time.UTC)) {
+		t.Fatalf("rfc3339: %v %v", got, err)
+	}
+	if _, ok, err := Parse("time:yesterday"); !ok || err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
@@ -0,0 +1,64 @@
+// Package source holds what the chain source packages share: the events and
+// reorg errors their scanners report, and error handling and request pacing
+// for their node clients.
+package source
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devblac/watch-tower/internal/rpclimit"
+)
+
+// HTTPError is a non-2xx HTTP response from a node or indexer.
+type HTTPError struct {
+	Status int
+	Body   string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.Status, e.Body)
+}
+
+// NewLimiter paces a client's requests to rps per second (0 for no cap) and
+// retries those that are throttled or transient.
+func NewLimiter(rps float64) *rpclimit.Limiter {
+	return rpclimit.New(rps, func(err error) bool {
+		return IsThrottled(err) || IsTransient(err)
+	})
+}
+
+// IsThrottled reports whether err is the node or provider asking for fewer
+// requests: a 429, or a chain's error whose Throttled method says so.
+func IsThrottled(err error) bool {
+	var h *HTTPError
+	if errors.As(err, &h) {
+		return h.Status == 429
+	}
+	var t interface{ Throttled() bool }
+	return errors.As(err, &t) && t.Throttled()
+}
+
+// IsTransient reports whether err may pass if the call is repeated: a 5xx, a
+// network error, or a chain's error whose Transient method says so.
+func IsTransient(err error) bool {
+	var h *HTTPError
+	if errors.As(err, &h) {
+		return h.Status >= 500
+	}
+	var t interface{ Transient() bool }
+	if errors.As(err, &t) {
+		return t.Transient()
+	}
+	return rpclimit.IsNetworkError(err)
+}
+
+// FailsOver reports whether a request that failed with err should be tried
+// on a client's next endpoint: a 5xx or a network error.
+func FailsOver(err error) bool {
+	var h *HTTPError
+	if errors.As(err, &h) {
+		return h.Status >= 500
+	}
+	return rpclimit.IsNetworkError(err)
+}
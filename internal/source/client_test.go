@@ -0,0 +1,38 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type codeError struct{ throttled, transient bool }
+
+func (e codeError) Error() string   { return "rpc error" }
+func (e codeError) Throttled() bool { return e.throttled }
+func (e codeError) Transient() bool { return e.transient }
+
+func TestRetryClassification(t *testing.T) {
+	for _, tc := range []struct {
+		err                  error
+		throttled, transient bool
+	}{
+		{&HTTPError{Status: 429}, true, false},
+		{fmt.Errorf("getBlock: %w", &HTTPError{Status: 502}), false, true},
+		{&HTTPError{Status: 404}, false, false},
+		{codeError{throttled: true}, true, false},
+		{fmt.Errorf("getblock: %w", codeError{transient: true}), false, true},
+		{codeError{}, false, false},
+		{errors.New("invalid params"), false, false},
+	} {
+		if got := IsThrottled(tc.err); got != tc.throttled {
+			t.Errorf("IsThrottled(%v) = %v", tc.err, got)
+		}
+		if got := IsTransient(tc.err); got != tc.transient {
+			t.Errorf("IsTransient(%v) = %v", tc.err, got)
+		}
+	}
+	if FailsOver(codeError{transient: true}) || !FailsOver(&HTTPError{Status: 503}) {
+		t.Fatalf("only 5xx and network errors fail over")
+	}
+}
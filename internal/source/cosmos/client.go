@@ -0,0 +1,258 @@
+package cosmos
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/rpclimit"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// Client is the slice of the CometBFT (Tendermint) RPC the scanner uses.
+type Client interface {
+	LatestHeight(ctx context.Context) (uint64, error)
+	Block(ctx context.Context, height uint64) (*Block, error)
+	BlockResults(ctx context.Context, height uint64) (*BlockResults, error)
+}
+
+// Block is a block's header and raw transactions.
+type Block struct {
+	Hash     string
+	PrevHash string
+	Height   uint64
+	Time     time.Time
+	Txs      [][]byte
+}
+
+// BlockResults are the ABCI events a block emitted. Chains on CometBFT
+// 0.34 and 0.37 emit block events in BeginBlock and EndBlock; 0.38 chains
+// in FinalizeBlock.
+type BlockResults struct {
+	TxsResults          []TxResult `json:"txs_results"`
+	BeginBlockEvents    []Event    `json:"begin_block_events"`
+	EndBlockEvents      []Event    `json:"end_block_events"`
+	FinalizeBlockEvents []Event    `json:"finalize_block_events"`
+}
+
+// TxResult is the outcome of one transaction. A non-zero Code means it
+// failed.
+type TxResult struct {
+	Code   uint32  `json:"code"`
+	Events []Event `json:"events"`
+}
+
+// Event is an ABCI event, such as a bank "transfer" or a CosmWasm "wasm".
+type Event struct {
+	Type       string      `json:"type"`
+	Attributes []Attribute `json:"attributes"`
+}
+
+// Attribute is a key/value pair of an event.
+type Attribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Get returns the value of the event's first attribute named key.
+func (e *Event) Get(key string) (string, bool) {
+	for _, a := range e.Attributes {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// RPCError is an error answer from the node. Data carries the detail, such
+// as the lowest height a pruned node still has.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data"`
+}
+
+func (e *RPCError) Error() string {
+	if e.Data != "" {
+		return fmt.Sprintf("rpc error %d: %s: %s", e.Code, e.Message, e.Data)
+	}
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// RPCClient calls the CometBFT JSON-RPC API over HTTP. With several URLs, a
+// request that fails to connect or gets a 5xx answer is retried on the next.
+type RPCClient struct {
+	urls    []string
+	header  http.Header
+	http    *http.Client
+	limiter *rpclimit.Limiter
+}
+
+// DefaultTimeout bounds each request to the node.
+const DefaultTimeout = 30 * time.Second
+
+// NewRPCClient builds a client for a source's rpc_url, sending header with
+// every request and making at most rps requests per second (0 for no cap).
+// Throttled and transient failures are retried with backoff.
+func NewRPCClient(urls []string, header http.Header, rps float64) (*RPCClient, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no rpc endpoints")
+	}
+	return &RPCClient{urls: urls, header: header, http: &http.Client{Timeout: DefaultTimeout}, limiter: source.NewLimiter(rps)}, nil
+}
+
+// LatestHeight implements Client.
+func (c *RPCClient) LatestHeight(ctx context.Context) (uint64, error) {
+	var status struct {
+		SyncInfo struct {
+			LatestBlockHeight uint64 `json:"latest_block_height,string"`
+		} `json:"sync_info"`
+	}
+	if err := c.call(ctx, "status", map[string]any{}, &status); err != nil {
+		return 0, err
+	}
+	return status.SyncInfo.LatestBlockHeight, nil
+}
+
+// Block implements Client.
+func (c *RPCClient) Block(ctx context.Context, height uint64) (*Block, error) {
+	var res struct {
+		BlockID struct {
+			Hash string `json:"hash"`
+		} `json:"block_id"`
+		Block struct {
+			Header struct {
+				Height      uint64    `json:"height,string"`
+				Time        time.Time `json:"time"`
+				LastBlockID struct {
+					Hash string `json:"hash"`
+				} `json:"last_block_id"`
+			} `json:"header"`
+			Data struct {
+				Txs [][]byte `json:"txs"`
+			} `json:"data"`
+		} `json:"block"`
+	}
+	if err := c.call(ctx, "block", map[string]any{"height": strconv.FormatUint(height, 10)}, &res); err != nil {
+		return nil, err
+	}
+	h := res.Block.Header
+	return &Block{Hash: res.BlockID.Hash, PrevHash: h.LastBlockID.Hash, Height: h.Height, Time: h.Time, Txs: res.Block.Data.Txs}, nil
+}
+
+// BlockResults implements Client.
+func (c *RPCClient) BlockResults(ctx context.Context, height uint64) (*BlockResults, error) {
+	var res BlockResults
+	if err := c.call(ctx, "block_results", map[string]any{"height": strconv.FormatUint(height, 10)}, &res); err != nil {
+		return nil, err
+	}
+	for _, events := range [][]Event{res.BeginBlockEvents, res.EndBlockEvents, res.FinalizeBlockEvents} {
+		decodeAttributes(events)
+	}
+	for _, tx := range res.TxsResults {
+		decodeAttributes(tx.Events)
+	}
+	return &res, nil
+}
+
+// attributeKey matches the keys modules give event attributes.
+var attributeKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// decodeAttributes undoes the base64 encoding CometBFT 0.34 applies to
+// attribute keys and values. Later versions send them as plain strings,
+// so an event's attributes are decoded only when every key decodes to a
+// plain key.
+func decodeAttributes(events []Event) {
+	for i := range events {
+		attrs := events[i].Attributes
+		decoded := make([]Attribute, len(attrs))
+		ok := len(attrs) > 0
+		for j, a := range attrs {
+			k, err := base64.StdEncoding.DecodeString(a.Key)
+			if err != nil || !attributeKey.Match(k) {
+				ok = false
+				break
+			}
+			v, err := base64.StdEncoding.DecodeString(a.Value)
+			if err != nil {
+				ok = false
+				break
+			}
+			decoded[j] = Attribute{Key: string(k), Value: string(v)}
+		}
+		if ok {
+			events[i].Attributes = decoded
+		}
+	}
+}
+
+func (c *RPCClient) call(ctx context.Context, method string, params map[string]any, out any) error {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", method, err)
+	}
+	return c.limiter.Do(ctx, func() error {
+		var lastErr error
+		for _, url := range c.urls {
+			err := c.post(ctx, url, body, out)
+			if err == nil || !source.FailsOver(err) {
+				return err
+			}
+			lastErr = err
+		}
+		return fmt.Errorf("%s: %w", method, lastErr)
+	})
+}
+
+func (c *RPCClient) post(ctx context.Context, url string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header = c.header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		// Some versions answer RPC errors with a 500 and the error in the body.
+		var rpcResp struct {
+			Error *RPCError `json:"error"`
+		}
+		if json.Unmarshal(msg, &rpcResp) == nil && rpcResp.Error != nil {
+			return rpcResp.Error
+		}
+		if len(msg) > 512 {
+			msg = msg[:512]
+		}
+		return &source.HTTPError{Status: resp.StatusCode, Body: string(bytes.TrimSpace(msg))}
+	}
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if err := json.Unmarshal(rpcResp.Result, out); err != nil {
+		return fmt.Errorf("decode result: %w", err)
+	}
+	return nil
+}
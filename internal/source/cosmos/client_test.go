@@ -0,0 +1,85 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func TestRPCClient(t *testing.T) {
+	tx := base64.StdEncoding.EncodeToString([]byte("raw tx"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string            `json:"method"`
+			Params map[string]string `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch req.Method {
+		case "status":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"sync_info":{"latest_block_height":"20411235"}}}`))
+		case "block":
+			if req.Params["height"] != "20411230" {
+				t.Errorf("expected the height as a string, got %v", req.Params)
+			}
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"block_id":{"hash":"AB12"},"block":{
+				"header":{"height":"20411230","time":"2024-05-01T10:00:00.123456789Z","last_block_id":{"hash":"CD34"}},
+				"data":{"txs":["` + tx + `"]}}}}`))
+		case "block_results":
+			// CometBFT 0.34 encodes attributes in base64; 0.38 does not.
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"height":"20411230",
+				"txs_results":[{"code":0,"events":[{"type":"transfer","attributes":[
+					{"key":"` + b64("recipient") + `","value":"` + b64(alice) + `","index":true},
+					{"key":"` + b64("amount") + `","value":"` + b64("10uatom") + `","index":true}]}]}],
+				"finalize_block_events":[{"type":"mint","attributes":[{"key":"amount","value":"123"}]}]}}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"Internal error","data":"height 1 is not available, lowest height is 1000"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewRPCClient([]string{srv.URL}, nil, 0)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	ctx := context.Background()
+	tip, err := c.LatestHeight(ctx)
+	if err != nil || tip != 20411235 {
+		t.Fatalf("latest height: %d, %v", tip, err)
+	}
+	b, err := c.Block(ctx, 20411230)
+	if err != nil {
+		t.Fatalf("block: %v", err)
+	}
+	if b.Hash != "AB12" || b.PrevHash != "CD34" || b.Height != 20411230 || len(b.Txs) != 1 || string(b.Txs[0]) != "raw tx" || b.Time.Nanosecond() != 123456789 {
+		t.Fatalf("unexpected block %+v", b)
+	}
+	res, err := c.BlockResults(ctx, 20411230)
+	if err != nil {
+		t.Fatalf("block results: %v", err)
+	}
+	if v, _ := res.TxsResults[0].Events[0].Get("recipient"); v != alice {
+		t.Fatalf("expected decoded 0.34 attributes, got %+v", res.TxsResults[0].Events[0])
+	}
+	if v, _ := res.FinalizeBlockEvents[0].Get("amount"); v != "123" {
+		t.Fatalf("expected plain attributes left alone, got %+v", res.FinalizeBlockEvents[0])
+	}
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"Internal error","data":"height 1 is not available, lowest height is 1000"}}`))
+	})
+	_, err = c.Block(ctx, 1)
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Data == "" {
+		t.Fatalf("expected an rpc error, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package cosmos
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// ActivityEvent is the event name of watch_address matches on every chain.
+const ActivityEvent = "address_activity"
+
+// contractAttribute is the attribute CosmWasm adds to the events of a
+// contract call, naming the contract.
+const contractAttribute = "_contract_address"
+
+// address matches a bech32 account or contract address with any prefix,
+// such as cosmos1…, osmo1… or neutron1….
+var address = regexp.MustCompile(`^[a-z][a-z0-9]*1[02-9ac-hj-np-z]{38,58}$`)
+
+// RuleMatcher applies one rule to the ABCI events of a block.
+type RuleMatcher struct {
+	rule  config.Rule
+	kind  string
+	event string              // abci_event
+	attrs map[string]string   // abci_event
+	addrs map[string]struct{} // watch_address
+}
+
+// NewRuleMatcher builds a matcher for Cosmos rules.
+func NewRuleMatcher(rule config.Rule) (*RuleMatcher, error) {
+	mt := strings.ToLower(rule.Match.Type)
+	m := &RuleMatcher{rule: rule, kind: mt, addrs: map[string]struct{}{}}
+	switch mt {
+	case config.MatchABCIEvent:
+		m.event = rule.Match.Event
+		m.attrs = rule.Match.Attributes
+	case config.MatchWatchAddress:
+		// The list is shared with other chains, so non-bech32 entries are skipped.
+		for _, a := range rule.Match.Addresses {
+			if a = strings.ToLower(a); address.MatchString(a) {
+				m.addrs[a] = struct{}{}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("rule %s: unsupported match.type %s for cosmos", rule.ID, rule.Match.Type)
+	}
+	return m, nil
+}
+
+// MatchEvents returns the events one transaction, or one phase of block
+// events, matches. first is the block-wide index of events[0], so each
+// match's LogIndex places it among all of the block's events.
+func (m *RuleMatcher) MatchEvents(events []Event, first uint) []source.Event {
+	var out []source.Event
+	switch m.kind {
+	case config.MatchABCIEvent:
+		for i := range events {
+			if ev, ok := m.matchEvent(&events[i]); ok {
+				out = append(out, m.finish(ev, &events[i], first+uint(i)))
+			}
+		}
+	case config.MatchWatchAddress:
+		// One activity event per transaction or phase, at the first event
+		// naming a watched address.
+		for i := range events {
+			if ev, ok := m.matchActivity(&events[i]); ok {
+				out = append(out, m.finish(ev, &events[i], first+uint(i)))
+				break
+			}
+		}
+	}
+	return out
+}
+
+func (m *RuleMatcher) finish(ev source.Event, e *Event, index uint) source.Event {
+	ev.RuleID = m.rule.ID
+	ev.LogIndex = &index
+	ev.Contract, _ = e.Get(contractAttribute)
+	return ev
+}
+
+// matchEvent matches an event of the rule's type carrying every attribute
+// the rule names with the given value. Its attributes become the args; of
+// a key that repeats, the first value is kept.
+func (m *RuleMatcher) matchEvent(e *Event) (source.Event, bool) {
+	if e.Type != m.event {
+		return source.Event{}, false
+	}
+	for k, want := range m.attrs {
+		if !hasAttribute(e, k, want) {
+			return source.Event{}, false
+		}
+	}
+	args := make(map[string]any, len(e.Attributes)+1)
+	for _, a := range e.Attributes {
+		if _, ok := args[a.Key]; !ok {
+			args[a.Key] = a.Value
+		}
+	}
+	args["event_type"] = e.Type
+	return source.Event{Name: e.Type, Args: args}, true
+}
+
+// matchActivity matches an event with an attribute holding a watched
+// address. role is that attribute's key, such as "sender" or "recipient".
+func (m *RuleMatcher) matchActivity(e *Event) (source.Event, bool) {
+	for _, a := range e.Attributes {
+		if _, ok := m.addrs[a.Value]; !ok {
+			continue
+		}
+		return source.Event{Name: ActivityEvent, Args: map[string]any{
+			"watched":    a.Value,
+			"role":       a.Key,
+			"event_type": e.Type,
+		}}, true
+	}
+	return source.Event{}, false
+}
+
+func hasAttribute(e *Event, key, value string) bool {
+	for _, a := range e.Attributes {
+		if a.Key == key && a.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package cosmos
+
+import (
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+)
+
+const (
+	alice    = "cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu"
+	contract = "neutron14hj2tavq8fpesdwxxcu44rty3hh90vhujrvcmstl4zr3txmfvw9s5c2epq"
+)
+
+func transfer(from, to, amount string) Event {
+	return Event{Type: "transfer", Attributes: []Attribute{
+		{Key: "recipient", Value: to},
+		{Key: "sender", Value: from},
+		{Key: "amount", Value: amount},
+	}}
+}
+
+func TestMatcher_ABCIEvent(t *testing.T) {
+	m, err := NewRuleMatcher(config.Rule{ID: "r1", Match: config.MatchSpec{
+		Type:       config.MatchABCIEvent,
+		Event:      "wasm",
+		Attributes: map[string]string{"action": "transfer"},
+	}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	events := []Event{
+		transfer(alice, contract, "1untrn"),
+		{Type: "wasm", Attributes: []Attribute{{Key: contractAttribute, Value: contract}, {Key: "action", Value: "mint"}}},
+		{Type: "wasm", Attributes: []Attribute{
+			{Key: contractAttribute, Value: contract},
+			{Key: "action", Value: "transfer"},
+			{Key: "amount", Value: "5"},
+			{Key: "amount", Value: "6"},
+		}},
+	}
+	got := m.MatchEvents(events, 10)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(got))
+	}
+	ev := got[0]
+	if ev.RuleID != "r1" || ev.Name != "wasm" || *ev.LogIndex != 12 || ev.Contract != contract {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if ev.Args["amount"] != "5" || ev.Args["event_type"] != "wasm" || ev.Args[contractAttribute] != contract {
+		t.Fatalf("unexpected args %+v", ev.Args)
+	}
+}
+
+func TestMatcher_WatchAddress(t *testing.T) {
+	m, err := NewRuleMatcher(config.Rule{ID: "w", Match: config.MatchSpec{
+		Type:      config.MatchWatchAddress,
+		Addresses: []string{"0x00000000000000000000000000000000000000aa", alice},
+	}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	if len(m.addrs) != 1 {
+		t.Fatalf("expected only the bech32 address kept, got %v", m.addrs)
+	}
+	events := []Event{
+		{Type: "message", Attributes: []Attribute{{Key: "action", Value: "/cosmos.bank.v1beta1.MsgSend"}}},
+		transfer(contract, alice, "7uatom"),
+		transfer(alice, contract, "1uatom"),
+	}
+	got := m.MatchEvents(events, 0)
+	if len(got) != 1 {
+		t.Fatalf("expected one activity event per tx, got %d", len(got))
+	}
+	if ev := got[0]; ev.Name != ActivityEvent || *ev.LogIndex != 1 || ev.Args["role"] != "recipient" || ev.Args["watched"] != alice {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if got := m.MatchEvents(events[:1], 0); len(got) != 0 {
+		t.Fatalf("expected no match, got %+v", got)
+	}
+}
+
+func TestMatcher_RejectsOtherTypes(t *testing.T) {
+	if _, err := NewRuleMatcher(config.Rule{ID: "x", Match: config.MatchSpec{Type: config.MatchLargeTx}}); err == nil {
+		t.Fatal("expected large_tx to be unsupported")
+	}
+}
@@ -0,0 +1,329 @@
+package cosmos
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/source/blocktime"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// Scanner walks a Cosmos source block by block and matches its rules
+// against the ABCI events each block emitted, read from /block_results.
+type Scanner struct {
+	client        Client
+	store         *storage.Store
+	source        config.Source
+	confirmations uint64
+	matchers      []*RuleMatcher
+	tipTTL        time.Duration
+	nowFunc       func() time.Time
+	// maxBlocks is how many blocks one call may scan (source
+	// max_blocks_per_tick); stopAt, when set, is the last block to scan.
+	maxBlocks uint64
+	stopAt    uint64
+	// tip is the latest block seen, read by the dashboard.
+	tip   atomic.Uint64
+	tipAt time.Time
+}
+
+// NewScanner builds a scanner for a Cosmos source and its rules.
+func NewScanner(client Client, store *storage.Store, src config.Source, confirmations uint64, rules []config.Rule) (*Scanner, error) {
+	s := &Scanner{
+		client:        client,
+		store:         store,
+		source:        src,
+		confirmations: confirmations,
+		tipTTL:        src.TipCacheTTL(),
+		nowFunc:       time.Now,
+		maxBlocks:     1,
+	}
+	if src.MaxBlocksPerTick > 1 {
+		s.maxBlocks = uint64(src.MaxBlocksPerTick)
+	}
+	commit, err := s.PrepareRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	commit()
+	return s, nil
+}
+
+// PrepareRules builds matchers for the source's rules without touching the
+// running scanner. Calling the returned commit func swaps them in.
+func (s *Scanner) PrepareRules(rules []config.Rule) (commit func(), err error) {
+	matchers := []*RuleMatcher{}
+	for _, r := range rules {
+		if !r.AppliesTo(s.source.ID) {
+			continue
+		}
+		if strings.EqualFold(r.Match.Type, config.MatchReorg) {
+			continue // raised by the engine when the scanner rewinds
+		}
+		m, err := NewRuleMatcher(r)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return func() {
+		s.matchers = matchers
+	}, nil
+}
+
+// SetStopHeight keeps a call from scanning past height, the end of a
+// bounded replay. Zero means no limit.
+func (s *Scanner) SetStopHeight(height uint64) {
+	s.stopAt = height
+}
+
+// Tip returns the latest block observed by ProcessNext, or 0 before the first poll.
+func (s *Scanner) Tip() uint64 {
+	return s.tip.Load()
+}
+
+// latestHeight returns the chain tip. The cached tip is reused while next is
+// still confirmed below it (catching up) or while it is younger than tipTTL.
+func (s *Scanner) latestHeight(ctx context.Context, next uint64) (uint64, error) {
+	if tip := s.tip.Load(); tip > 0 {
+		if next+s.confirmations <= tip || s.nowFunc().Sub(s.tipAt) < s.tipTTL {
+			return tip, nil
+		}
+	}
+	height, err := s.client.LatestHeight(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("latest block: %w", err)
+	}
+	if prev := s.tip.Load(); height < prev {
+		// The lower tip is not kept, so the next tick asks again.
+		return 0, &source.TipError{Tip: height, Previous: prev}
+	}
+	s.tip.Store(height)
+	s.tipAt = s.nowFunc()
+	return height, nil
+}
+
+// ProcessNext handles the next eligible block (respecting confirmations) and returns matched events.
+// A source with max_blocks_per_tick that is behind handles up to that many blocks at once.
+// On success advances the cursor. On reorg returns source.ErrReorgDetected after rewinding.
+func (s *Scanner) ProcessNext(ctx context.Context) ([]source.Event, error) {
+	var events []source.Event
+	err := s.ProcessNextFunc(ctx, func(ev source.Event) error {
+		events = append(events, ev)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ProcessNextFunc is ProcessNext with each matched event handed to emit as it
+// is decoded. The cursor moves after each block, so if emit fails it is left
+// at the last block fully handled and the error is returned.
+func (s *Scanner) ProcessNextFunc(ctx context.Context, emit func(source.Event) error) error {
+	curHeight, curHash, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+	if err != nil {
+		return err
+	}
+
+	latest, err := s.latestHeight(ctx, curHeight+1)
+	if err != nil {
+		return err
+	}
+	if hasCursor && latest < curHeight {
+		return &source.TipError{Tip: latest, Previous: curHeight, Cursor: true}
+	}
+	safe := latest
+	if s.confirmations > 0 {
+		if safe < s.confirmations {
+			return nil
+		}
+		safe -= s.confirmations
+	}
+
+	target := curHeight + 1
+	if !hasCursor {
+		start, err := s.resolveStart(ctx, safe)
+		if err != nil {
+			return err
+		}
+		target = start
+	}
+
+	if target > safe {
+		return nil
+	}
+
+	end := min(target+s.maxBlocks-1, safe)
+	if s.stopAt > 0 {
+		end = min(end, max(s.stopAt, target))
+	}
+	for height := target; height <= end; height++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hash, err := s.processBlock(ctx, height, curHeight, curHash, hasCursor, emit)
+		if err != nil {
+			return err
+		}
+		curHeight, curHash, hasCursor = height, hash, true
+	}
+	return nil
+}
+
+// processBlock matches the rules against the events of the block at
+// target, whose parent must be the block the cursor is at, and moves the
+// cursor to it. It returns the block's hash.
+func (s *Scanner) processBlock(ctx context.Context, target, curHeight uint64, curHash string, hasCursor bool, emit func(source.Event) error) (string, error) {
+	block, err := s.client.Block(ctx, target)
+	if err != nil {
+		return "", fmt.Errorf("block %d: %w", target, err)
+	}
+	if hasCursor && curHash != "" && !strings.EqualFold(block.PrevHash, curHash) {
+		// CometBFT blocks are final once committed, so the endpoint serves
+		// another chain than before, such as a fallback node on a different
+		// network or a chain restarted after a halt. Rescan from the block
+		// below the cursor, without a hash to check it against.
+		rewindTo := uint64(0)
+		if curHeight > 0 {
+			rewindTo = curHeight - 1
+		}
+		if err := s.store.UpsertCursor(ctx, s.source.ID, rewindTo, ""); err != nil {
+			return "", err
+		}
+		return "", &source.ReorgError{Height: target, From: curHeight, To: curHeight, OldHash: curHash, NewHash: block.PrevHash}
+	}
+	results, err := s.client.BlockResults(ctx, target)
+	if err != nil {
+		return "", fmt.Errorf("block results %d: %w", target, err)
+	}
+	if len(results.TxsResults) != len(block.Txs) {
+		return "", fmt.Errorf("block %d: %d transactions but %d results", target, len(block.Txs), len(results.TxsResults))
+	}
+
+	stamp := block.Time.UTC()
+	var index uint
+	match := func(phase string, txIndex int, txHash string, events []Event) error {
+		first := index
+		index += uint(len(events))
+		for _, m := range s.matchers {
+			for _, ev := range m.MatchEvents(events, first) {
+				ev.Chain = Chain
+				ev.SourceID = s.source.ID
+				ev.Height = target
+				ev.Hash = block.Hash
+				ev.TxHash = txHash
+				ev.Timestamp = stamp
+				ev.Args["phase"] = phase
+				if txIndex >= 0 {
+					ev.Args["tx_index"] = txIndex
+				}
+				if err := emit(ev); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := match("begin_block", -1, "", results.BeginBlockEvents); err != nil {
+		return "", err
+	}
+	for i, res := range results.TxsResults {
+		if res.Code != 0 {
+			// A failed transaction's events were reverted; they still count
+			// toward the block's event positions.
+			index += uint(len(res.Events))
+			continue
+		}
+		if err := match("tx", i, TxHash(block.Txs[i]), res.Events); err != nil {
+			return "", err
+		}
+	}
+	if err := match("end_block", -1, "", results.EndBlockEvents); err != nil {
+		return "", err
+	}
+	if err := match("finalize_block", -1, "", results.FinalizeBlockEvents); err != nil {
+		return "", err
+	}
+	return block.Hash, s.store.UpsertCursor(ctx, s.source.ID, target, block.Hash)
+}
+
+// TxHash returns the hash explorers and the RPC know a transaction by: the
+// uppercase hex SHA-256 of its bytes.
+func TxHash(tx []byte) string {
+	sum := sha256.Sum256(tx)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// SkipNext advances the cursor past the next block without matching it.
+// It requires an existing cursor and returns the skipped height.
+func (s *Scanner) SkipNext(ctx context.Context) (uint64, error) {
+	curHeight, _, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+	if err != nil {
+		return 0, err
+	}
+	if !hasCursor {
+		return 0, fmt.Errorf("source %s has no cursor yet", s.source.ID)
+	}
+	target := curHeight + 1
+	block, err := s.client.Block(ctx, target)
+	if err != nil {
+		return 0, fmt.Errorf("block %d: %w", target, err)
+	}
+	if err := s.store.UpsertCursor(ctx, s.source.ID, target, block.Hash); err != nil {
+		return 0, err
+	}
+	return target, nil
+}
+
+// resolveStart picks the first block for a source without a cursor. A
+// "time:" start_block is found by binary search over block times.
+func (s *Scanner) resolveStart(ctx context.Context, safe uint64) (uint64, error) {
+	at, ok, err := blocktime.Parse(s.source.StartBlock)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return resolveStartHeight(s.source.StartBlock, safe)
+	}
+	return blocktime.Search(ctx, safe, at, func(ctx context.Context, height uint64) (time.Time, error) {
+		block, err := s.client.Block(ctx, height)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return block.Time, nil
+	})
+}
+
+// resolveStartHeight reads a start_block: a height, or "latest" or
+// "latest-N" relative to the newest confirmed block. Most nodes prune old
+// block results, so unlike on EVM sources it defaults to the latest block.
+func resolveStartHeight(start string, safe uint64) (uint64, error) {
+	if start == "" || start == "latest" {
+		return safe, nil
+	}
+	if strings.HasPrefix(start, "latest-") {
+		n, err := strconv.ParseUint(strings.TrimPrefix(start, "latest-"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse start_block %q: %w", start, err)
+		}
+		if n > safe {
+			return 0, nil
+		}
+		return safe - n, nil
+	}
+	n, err := strconv.ParseUint(start, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse start_block %q: %w", start, err)
+	}
+	return n, nil
+}
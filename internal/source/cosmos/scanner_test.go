@@ -0,0 +1,192 @@
+package cosmos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// fakeClient serves a chain of blocks and their results by height.
+type fakeClient struct {
+	tip     uint64
+	blocks  map[uint64]*Block
+	results map[uint64]*BlockResults
+}
+
+func (f *fakeClient) LatestHeight(ctx context.Context) (uint64, error) {
+	return f.tip, nil
+}
+
+func (f *fakeClient) Block(ctx context.Context, height uint64) (*Block, error) {
+	b, ok := f.blocks[height]
+	if !ok {
+		return nil, &RPCError{Code: -32603, Message: "Internal error", Data: fmt.Sprintf("height %d must be less than or equal to the current blockchain height %d", height, f.tip)}
+	}
+	return b, nil
+}
+
+func (f *fakeClient) BlockResults(ctx context.Context, height uint64) (*BlockResults, error) {
+	return f.results[height], nil
+}
+
+// add puts a block at height on the chain, building on whatever is below it.
+func (f *fakeClient) add(height uint64, hash string, txs []TxResult, end ...Event) {
+	if f.blocks == nil {
+		f.blocks, f.results = map[uint64]*Block{}, map[uint64]*BlockResults{}
+	}
+	var prev string
+	if b, ok := f.blocks[height-1]; ok {
+		prev = b.Hash
+	}
+	raw := make([][]byte, len(txs))
+	for i := range txs {
+		raw[i] = []byte(fmt.Sprintf("%s-tx%d", hash, i))
+	}
+	f.blocks[height] = &Block{Hash: hash, PrevHash: prev, Height: height, Time: time.Unix(int64(1700000000+height*6), 0), Txs: raw}
+	f.results[height] = &BlockResults{TxsResults: txs, FinalizeBlockEvents: end}
+	f.tip = max(f.tip, height)
+}
+
+func newTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.Open(t.TempDir() + "/db.sqlite")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestScannerMatchesBlockEvents(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &fakeClient{}
+	client.add(99, "HASH99", nil)
+	client.add(100, "HASH100", []TxResult{
+		{Code: 5, Events: []Event{transfer(alice, contract, "1uatom")}},
+		{Events: []Event{{Type: "message"}, transfer(alice, contract, "2uatom")}},
+	}, transfer(contract, alice, "3uatom"))
+	if err := store.UpsertCursor(ctx, "hub", 99, "HASH99"); err != nil {
+		t.Fatalf("seed cursor: %v", err)
+	}
+
+	rules := []config.Rule{{ID: "moves", Source: "hub", Match: config.MatchSpec{Type: config.MatchABCIEvent, Event: "transfer"}}}
+	sc, err := NewScanner(client, store, config.Source{ID: "hub", Type: Chain}, 0, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	events, err := sc.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected the failed tx skipped, got %d events", len(events))
+	}
+	tx := events[0]
+	if tx.TxHash != TxHash([]byte("HASH100-tx1")) || *tx.LogIndex != 2 || tx.Args["amount"] != "2uatom" || tx.Args["phase"] != "tx" || tx.Args["tx_index"] != 1 {
+		t.Fatalf("unexpected tx event %+v", tx)
+	}
+	end := events[1]
+	if end.TxHash != "" || *end.LogIndex != 3 || end.Args["phase"] != "finalize_block" || end.Hash != "HASH100" || end.Timestamp.Unix() != 1700000600 {
+		t.Fatalf("unexpected block event %+v", end)
+	}
+	if h, hash, _, _ := store.GetCursor(ctx, "hub"); h != 100 || hash != "HASH100" {
+		t.Fatalf("expected cursor at 100, got %d %s", h, hash)
+	}
+}
+
+func TestScannerRewindsOnParentMismatch(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &fakeClient{}
+	client.add(10, "NEW10", nil)
+	client.add(11, "NEW11", nil)
+	if err := store.UpsertCursor(ctx, "hub", 10, "OLD10"); err != nil {
+		t.Fatalf("seed cursor: %v", err)
+	}
+	sc, err := NewScanner(client, store, config.Source{ID: "hub", Type: Chain}, 0, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	_, err = sc.ProcessNext(ctx)
+	var rg *source.ReorgError
+	if !errors.As(err, &rg) || !errors.Is(err, source.ErrReorgDetected) {
+		t.Fatalf("expected reorg, got %v", err)
+	}
+	if rg.From != 10 || rg.To != 10 || rg.NewHash != "NEW10" {
+		t.Fatalf("unexpected reorg %+v", rg)
+	}
+	if h, hash, _, _ := store.GetCursor(ctx, "hub"); h != 9 || hash != "" {
+		t.Fatalf("expected cursor rewound to 9, got %d %q", h, hash)
+	}
+	if _, err := sc.ProcessNext(ctx); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	if h, hash, _, _ := store.GetCursor(ctx, "hub"); h != 10 || hash != "NEW10" {
+		t.Fatalf("expected cursor at the new block 10, got %d %q", h, hash)
+	}
+}
+
+func TestScannerReportsFailedRewind(t *testing.T) {
+	path := t.TempDir() + "/db.sqlite"
+	store, err := storage.Open(path)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+	client := &fakeClient{}
+	client.add(10, "NEW10", nil)
+	client.add(11, "NEW11", nil)
+	if err := store.UpsertCursor(ctx, "hub", 10, "OLD10"); err != nil {
+		t.Fatalf("seed cursor: %v", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TRIGGER no_rewind BEFORE UPDATE ON cursors BEGIN SELECT RAISE(ABORT, 'disk full'); END;`); err != nil {
+		t.Fatalf("trigger: %v", err)
+	}
+	sc, err := NewScanner(client, store, config.Source{ID: "hub", Type: Chain}, 0, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	_, err = sc.ProcessNext(ctx)
+	if err == nil || errors.Is(err, source.ErrReorgDetected) {
+		t.Fatalf("expected the failed cursor write, not a reorg, got %v", err)
+	}
+	if h, hash, _, _ := store.GetCursor(ctx, "hub"); h != 10 || hash != "OLD10" {
+		t.Fatalf("expected the cursor left at 10, got %d %q", h, hash)
+	}
+}
+
+func TestResolveStartHeight(t *testing.T) {
+	cases := []struct {
+		start string
+		want  uint64
+	}{
+		{"", 500},
+		{"latest", 500},
+		{"latest-20", 480},
+		{"latest-900", 0},
+		{"123", 123},
+	}
+	for _, c := range cases {
+		got, err := resolveStartHeight(c.start, 500)
+		if err != nil || got != c.want {
+			t.Fatalf("%q: got %d, %v; want %d", c.start, got, err, c.want)
+		}
+	}
+	if _, err := resolveStartHeight("soon", 500); err == nil {
+		t.Fatal("expected an error for a bad start_block")
+	}
+}
@@ -0,0 +1,6 @@
+package cosmos
+
+// Chain identifier for Cosmos SDK chains. Its events' TxHash is empty for
+// block events, LogIndex is the position of the ABCI event among the
+// block's, and Contract the _contract_address of CosmWasm events.
+const Chain = "cosmos"
@@ -0,0 +1,94 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrReorgDetected signals that the chain rewound; caller should restart from the updated cursor.
+var ErrReorgDetected = errors.New("reorg detected")
+
+// ReorgError describes a detected reorg and matches ErrReorgDetected.
+type ReorgError struct {
+	Height  uint64 // block whose parent no longer matched the cursor
+	From    uint64 // first orphaned block that had been processed
+	To      uint64 // last orphaned block that had been processed
+	OldHash string // hash recorded for To
+	NewHash string // hash the chain now has at To
+	// Exceeded is set when no common ancestor was found within the
+	// source's max_reorg_depth; the cursor was rewound that far anyway.
+	Exceeded bool
+	// Unit is what the chain calls its blocks, such as "round" or "slot";
+	// empty for "block".
+	Unit string
+}
+
+func (e *ReorgError) Error() string {
+	u := unit(e.Unit)
+	if e.Exceeded {
+		return fmt.Sprintf("reorg detected at %s %d: no common ancestor within %d %s(s), rolled back that far", u, e.Height, e.Depth(), u)
+	}
+	return fmt.Sprintf("reorg detected at %s %d: %d %s(s) rolled back", u, e.Height, e.Depth(), u)
+}
+
+// Is makes errors.Is(err, ErrReorgDetected) hold.
+func (e *ReorgError) Is(target error) bool {
+	return target == ErrReorgDetected
+}
+
+// Depth is how many processed blocks were orphaned.
+func (e *ReorgError) Depth() uint64 {
+	return e.To - e.From + 1
+}
+
+// ErrTipBehind signals that the node reported a chain tip below one already
+// seen; nothing was scanned.
+var ErrTipBehind = errors.New("rpc tip behind")
+
+// TipError describes a node reporting a latest block below the source's
+// cursor, or below the tip it reported on an earlier tick. Load-balanced
+// providers do this when a request lands on a lagging node. It matches
+// ErrTipBehind.
+type TipError struct {
+	Tip      uint64 // latest block the node reported
+	Previous uint64 // the cursor, or the tip seen before
+	Cursor   bool   // Previous is the cursor
+	Unit     string // as in ReorgError
+}
+
+func (e *TipError) Error() string {
+	if e.Cursor {
+		return fmt.Sprintf("rpc reported latest %s %d, behind the cursor at %d", unit(e.Unit), e.Tip, e.Previous)
+	}
+	return fmt.Sprintf("rpc reported latest %s %d, below %d seen before", unit(e.Unit), e.Tip, e.Previous)
+}
+
+// Is makes errors.Is(err, ErrTipBehind) hold.
+func (e *TipError) Is(target error) bool {
+	return target == ErrTipBehind
+}
+
+func unit(u string) string {
+	if u == "" {
+		return "block"
+	}
+	return u
+}
+
+// Event is a decoded on-chain event in the shape every source reports it.
+// Each chain's package says what its hashes and positions refer to.
+type Event struct {
+	Chain     string
+	SourceID  string
+	RuleID    string
+	Height    uint64
+	Hash      string // block hash
+	TxHash    string
+	LogIndex  *uint  // position among the block's or transaction's matches
+	AppID     uint64 // Algorand application
+	Contract  string // contract, program or account the event came from
+	Timestamp time.Time
+	Name      string
+	Args      map[string]any
+}
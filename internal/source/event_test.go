@@ -0,0 +1,21 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestReorgErrorNamesUnit(t *testing.T) {
+	err := fmt.Errorf("solana source sol: %w", &ReorgError{Height: 12, From: 10, To: 11, Unit: "slot"})
+	if !errors.Is(err, ErrReorgDetected) {
+		t.Fatalf("expected ErrReorgDetected, got %v", err)
+	}
+	if got := err.Error(); got != "solana source sol: reorg detected at slot 12: 2 slot(s) rolled back" {
+		t.Fatalf("unexpected message %q", got)
+	}
+	tip := &TipError{Tip: 5, Previous: 9, Cursor: true}
+	if !errors.Is(tip, ErrTipBehind) || tip.Error() != "rpc reported latest block 5, behind the cursor at 9" {
+		t.Fatalf("unexpected tip error %v", tip)
+	}
+}
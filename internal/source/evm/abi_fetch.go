@@ -0,0 +1,261 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Default API base URLs of the ABI providers.
+const (
+	DefaultEtherscanURL = "https://api.etherscan.io/v2/api"
+	DefaultSourcifyURL  = "https://sourcify.dev/server"
+)
+
+var errNotVerified = errors.New("contract is not verified")
+
+// EIP-1967 storage slots holding a proxy's implementation, or the beacon
+// that names it.
+var (
+	eip1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	eip1967BeaconSlot         = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50")
+	// implementation() on a beacon.
+	beaconImplementationSelector = []byte{0x5c, 0x60, 0xda, 0x1b}
+)
+
+// ABIFetcher downloads the verified ABIs of contracts into the abi_fetch
+// cache directory, where LoadABIs picks them up with the source's abi_dirs.
+// Contracts already in the cache are not fetched again.
+type ABIFetcher struct {
+	cfg    config.ABIFetch
+	client *http.Client
+	reader StateReader // nil to take contracts as they are
+}
+
+// NewABIFetcher builds a fetcher for a source's abi_fetch settings.
+func NewABIFetcher(cfg config.ABIFetch) *ABIFetcher {
+	return &ABIFetcher{cfg: cfg, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// SetStateReader lets the fetcher follow EIP-1967 proxies: the ABI of each
+// proxy's current implementation is fetched too, since that is where the
+// events the proxy emits are declared.
+func (f *ABIFetcher) SetStateReader(r StateReader) {
+	f.reader = r
+}
+
+// RuleContracts returns the contracts the source's rules name, sorted.
+func RuleContracts(sourceID string, rules []config.Rule) []common.Address {
+	seen := map[common.Address]struct{}{}
+	var out []common.Address
+	for _, r := range rules {
+		if !r.AppliesTo(sourceID) {
+			continue
+		}
+		for _, c := range r.Match.ContractList() {
+			if !common.IsHexAddress(c) {
+				continue
+			}
+			a := common.HexToAddress(c)
+			if _, ok := seen[a]; !ok {
+				seen[a] = struct{}{}
+				out = append(out, a)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Hex() < out[j].Hex() })
+	return out
+}
+
+// Fetch caches the ABI of each contract not cached yet, and of the
+// implementation behind each one that is a proxy. A contract that cannot be
+// fetched, such as an unverified one, does not stop the others; their errors
+// are joined.
+func (f *ABIFetcher) Fetch(ctx context.Context, contracts []common.Address) error {
+	dir := f.cfg.Dir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("abi cache: %w", err)
+	}
+	var errs []error
+	if f.reader != nil {
+		// Implementations are looked up every time: a proxy may have been
+		// upgraded since its ABIs were cached.
+		seen := map[common.Address]struct{}{}
+		for _, c := range contracts {
+			seen[c] = struct{}{}
+		}
+		for _, c := range contracts {
+			impl, ok, err := ProxyImplementation(ctx, f.reader, c)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("read proxy %s: %w", c.Hex(), err))
+				continue
+			}
+			if _, dup := seen[impl]; ok && !dup {
+				seen[impl] = struct{}{}
+				contracts = append(contracts, impl)
+			}
+		}
+	}
+	for _, c := range contracts {
+		path := filepath.Join(dir, strings.ToLower(c.Hex())+".json")
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		data, err := f.fetch(ctx, c)
+		if err == nil {
+			// Checked before caching, so a bad answer is fetched again next time.
+			_, err = abi.JSON(bytes.NewReader(data))
+		}
+		if err == nil {
+			err = writeFileAtomic(path, data)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("fetch abi of %s: %w", c.Hex(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ProxyImplementation returns the implementation an EIP-1967 proxy
+// delegates to, read from its implementation slot or through its beacon. ok
+// is false for contracts that are not such a proxy.
+func ProxyImplementation(ctx context.Context, r StateReader, proxy common.Address) (common.Address, bool, error) {
+	word, err := r.StorageAt(ctx, proxy, eip1967ImplementationSlot, nil)
+	if err != nil {
+		return common.Address{}, false, err
+	}
+	if impl := common.BytesToAddress(word); impl != (common.Address{}) {
+		return impl, true, nil
+	}
+	word, err = r.StorageAt(ctx, proxy, eip1967BeaconSlot, nil)
+	if err != nil {
+		return common.Address{}, false, err
+	}
+	beacon := common.BytesToAddress(word)
+	if beacon == (common.Address{}) {
+		return common.Address{}, false, nil
+	}
+	out, err := r.CallContract(ctx, ethereum.CallMsg{To: &beacon, Data: beaconImplementationSelector}, nil)
+	if err != nil {
+		return common.Address{}, false, fmt.Errorf("beacon %s: %w", beacon.Hex(), err)
+	}
+	if len(out) < 32 {
+		return common.Address{}, false, fmt.Errorf("beacon %s: unexpected implementation() result", beacon.Hex())
+	}
+	impl := common.BytesToAddress(out[:32])
+	return impl, impl != (common.Address{}), nil
+}
+
+func (f *ABIFetcher) fetch(ctx context.Context, contract common.Address) ([]byte, error) {
+	chain := f.cfg.ChainID
+	if chain == 0 {
+		chain = 1
+	}
+	if strings.ToLower(f.cfg.Provider) == config.ABIFetchSourcify {
+		return f.fetchSourcify(ctx, chain, contract)
+	}
+	return f.fetchEtherscan(ctx, chain, contract)
+}
+
+// fetchEtherscan asks Etherscan's multichain API for the contract's ABI.
+func (f *ABIFetcher) fetchEtherscan(ctx context.Context, chain uint64, contract common.Address) ([]byte, error) {
+	base := f.cfg.URL
+	if base == "" {
+		base = DefaultEtherscanURL
+	}
+	q := url.Values{}
+	q.Set("chainid", fmt.Sprint(chain))
+	q.Set("module", "contract")
+	q.Set("action", "getabi")
+	q.Set("address", contract.Hex())
+	q.Set("apikey", f.cfg.APIKey)
+	var res struct {
+		Status string `json:"status"`
+		Result string `json:"result"`
+	}
+	if err := f.get(ctx, base+"?"+q.Encode(), &res); err != nil {
+		return nil, err
+	}
+	if res.Status != "1" {
+		if strings.Contains(strings.ToLower(res.Result), "not verified") {
+			return nil, errNotVerified
+		}
+		return nil, fmt.Errorf("etherscan: %s", res.Result)
+	}
+	return []byte(res.Result), nil
+}
+
+// fetchSourcify reads the contract's ABI from Sourcify's verified contracts.
+func (f *ABIFetcher) fetchSourcify(ctx context.Context, chain uint64, contract common.Address) ([]byte, error) {
+	base := f.cfg.URL
+	if base == "" {
+		base = DefaultSourcifyURL
+	}
+	var res struct {
+		ABI json.RawMessage `json:"abi"`
+	}
+	err := f.get(ctx, fmt.Sprintf("%s/v2/contract/%d/%s?fields=abi", strings.TrimSuffix(base, "/"), chain, contract.Hex()), &res)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.ABI) == 0 || string(res.ABI) == "null" {
+		return nil, errNotVerified
+	}
+	return res.ABI, nil
+}
+
+func (f *ABIFetcher) get(ctx context.Context, u string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		// Without the URL, which carries the API key.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			return uerr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotVerified
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// writeFileAtomic writes through a temporary file, so the ABI watcher
+// never reads half a file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".abi-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,154 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const pausedABI = `[{"type":"event","name":"Paused","inputs":[{"name":"account","type":"address","indexed":false}]}]`
+
+func TestABIFetcherEtherscan(t *testing.T) {
+	verified := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	unverified := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		requests = append(requests, q.Get("address"))
+		if q.Get("apikey") != "k1" || q.Get("chainid") != "10" || q.Get("action") != "getabi" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if common.HexToAddress(q.Get("address")) == verified {
+			result, _ := json.Marshal(pausedABI)
+			_, _ = w.Write([]byte(`{"status":"1","message":"OK","result":` + string(result) + `}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"0","message":"NOTOK","result":"Contract source code not verified"}`))
+	}))
+	defer server.Close()
+
+	cfg := config.ABIFetch{Provider: config.ABIFetchEtherscan, APIKey: "k1", ChainID: 10, CacheDir: t.TempDir(), URL: server.URL}
+	rules := []config.Rule{
+		{ID: "a", Source: "evm_main", Match: config.MatchSpec{Type: "log", Contract: verified.Hex()}},
+		{ID: "b", Source: "evm_main", Match: config.MatchSpec{Type: "log", Contract: unverified.Hex()}},
+		{ID: "c", Source: "evm_other", Match: config.MatchSpec{Type: "log", Contract: "0x00000000000000000000000000000000000000cc"}},
+	}
+	contracts := RuleContracts("evm_main", rules)
+	if len(contracts) != 2 {
+		t.Fatalf("unexpected rule contracts: %v", contracts)
+	}
+	f := NewABIFetcher(cfg)
+	err := f.Fetch(context.Background(), contracts)
+	if err == nil || !strings.Contains(err.Error(), unverified.Hex()) || strings.Contains(err.Error(), verified.Hex()) {
+		t.Fatalf("expected only the unverified contract to fail, got %v", err)
+	}
+	abis, err := LoadABIs([]string{cfg.Dir()})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	a, ok := abis[filepath.Join(cfg.Dir(), strings.ToLower(verified.Hex())+".json")]
+	if len(abis) != 1 || !ok {
+		t.Fatalf("unexpected cache contents: %v", abis)
+	}
+	if _, ok := a.Events["Paused"]; !ok {
+		t.Fatalf("fetched abi lacks its event")
+	}
+
+	// Cached contracts are not fetched again; unverified ones are retried.
+	requests = nil
+	_ = f.Fetch(context.Background(), contracts)
+	if len(requests) != 1 || requests[0] != unverified.Hex() {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+}
+
+func TestABIFetcherSourcify(t *testing.T) {
+	contract := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/contract/1/"+contract.Hex() || r.URL.Query().Get("fields") != "abi" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"abi":` + pausedABI + `}`))
+	}))
+	defer server.Close()
+
+	cfg := config.ABIFetch{Provider: config.ABIFetchSourcify, CacheDir: t.TempDir(), URL: server.URL}
+	if err := NewABIFetcher(cfg).Fetch(context.Background(), []common.Address{contract}); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	abis, err := LoadABIs([]string{cfg.Dir()})
+	if err != nil || len(abis) != 1 {
+		t.Fatalf("expected one cached abi, got %d (%v)", len(abis), err)
+	}
+	if _, ok := findABI(abis, strings.ToLower(contract.Hex())+".json"); !ok {
+		t.Fatalf("cached abi not found by file name")
+	}
+}
+
+type proxyReader struct {
+	slots  map[common.Address]map[common.Hash][]byte
+	values map[common.Address][]byte
+}
+
+func (r *proxyReader) StorageAt(_ context.Context, account common.Address, key common.Hash, _ *big.Int) ([]byte, error) {
+	if v, ok := r.slots[account][key]; ok {
+		return v, nil
+	}
+	return make([]byte, 32), nil
+}
+
+func (r *proxyReader) CallContract(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	return r.values[*msg.To], nil
+}
+
+func TestABIFetcherFollowsProxies(t *testing.T) {
+	proxy := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	beaconProxy := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	plain := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	beacon := common.HexToAddress("0x00000000000000000000000000000000000000dd")
+	impl := common.HexToAddress("0x00000000000000000000000000000000000000e1")
+	beaconImpl := common.HexToAddress("0x00000000000000000000000000000000000000e2")
+	word := func(a common.Address) []byte { return common.LeftPadBytes(a.Bytes(), 32) }
+	reader := &proxyReader{
+		slots: map[common.Address]map[common.Hash][]byte{
+			proxy:       {eip1967ImplementationSlot: word(impl)},
+			beaconProxy: {eip1967BeaconSlot: word(beacon)},
+		},
+		values: map[common.Address][]byte{beacon: word(beaconImpl)},
+	}
+	for c, want := range map[common.Address]common.Address{proxy: impl, beaconProxy: beaconImpl, plain: {}} {
+		got, ok, err := ProxyImplementation(context.Background(), reader, c)
+		if err != nil || got != want || ok != (want != common.Address{}) {
+			t.Fatalf("%s: got %s ok=%v err=%v, want %s", c.Hex(), got.Hex(), ok, err, want.Hex())
+		}
+	}
+
+	var fetched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = append(fetched, filepath.Base(r.URL.Path))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"abi":` + pausedABI + `}`))
+	}))
+	defer server.Close()
+	f := NewABIFetcher(config.ABIFetch{Provider: config.ABIFetchSourcify, CacheDir: t.TempDir(), URL: server.URL})
+	f.SetStateReader(reader)
+	if err := f.Fetch(context.Background(), []common.Address{proxy, beaconProxy, plain}); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	want := []string{proxy.Hex(), beaconProxy.Hex(), plain.Hex(), impl.Hex(), beaconImpl.Hex()}
+	if strings.Join(fetched, ",") != strings.Join(want, ",") {
+		t.Fatalf("fetched %v, want %v", fetched, want)
+	}
+}
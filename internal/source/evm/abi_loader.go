@@ -2,16 +2,24 @@ package evm
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
 )
 
-// LoadABIs loads ABI JSON files from the provided directories.
+// LoadABIs loads ABI JSON files from the provided directories. Besides raw
+// ABI arrays it reads Hardhat and Foundry build artifacts, so abi_dirs can
+// point at artifacts/ or out/; JSON files there without an ABI are skipped.
 func LoadABIs(dirs []string) (map[string]*abi.ABI, error) {
 	abis := map[string]*abi.ABI{}
 	for _, dir := range dirs {
@@ -29,6 +37,13 @@ func LoadABIs(dirs []string) (map[string]*abi.ABI, error) {
 			if err != nil {
 				return fmt.Errorf("read abi %s: %w", path, err)
 			}
+			data, ok, err := extractABI(data)
+			if err != nil {
+				return fmt.Errorf("parse abi %s: %w", path, err)
+			}
+			if !ok {
+				return nil
+			}
 			a, err := abi.JSON(bytes.NewReader(data))
 			if err != nil {
 				return fmt.Errorf("parse abi %s: %w", path, err)
@@ -43,6 +58,156 @@ func LoadABIs(dirs []string) (map[string]*abi.ABI, error) {
 	return abis, nil
 }
 
+// DefaultABIPoll is how often an ABIWatcher checks its directories.
+const DefaultABIPoll = 10 * time.Second
+
+// ABIWatcher reloads a source's abi_dirs when the JSON files in them are
+// added, removed, or modified, and hands the result to apply.
+type ABIWatcher struct {
+	dirs  []string
+	apply func(map[string]*abi.ABI) error
+	last  string
+}
+
+// NewABIWatcher watches dirs, taking their current contents as loaded.
+func NewABIWatcher(dirs []string, apply func(map[string]*abi.ABI) error) *ABIWatcher {
+	w := &ABIWatcher{dirs: dirs, apply: apply}
+	w.last, _ = abiDirsVersion(dirs)
+	return w
+}
+
+// Check reloads the ABIs if the directories changed since the last load and
+// reports whether it did. A change that fails to load or apply is reported
+// once and not retried until the files change again.
+func (w *ABIWatcher) Check() (bool, error) {
+	v, err := abiDirsVersion(w.dirs)
+	if err != nil {
+		return false, err
+	}
+	if v == w.last {
+		return false, nil
+	}
+	w.last = v
+	return true, w.load()
+}
+
+// Reload loads and applies the ABIs unconditionally, e.g. on SIGHUP.
+func (w *ABIWatcher) Reload() error {
+	w.last, _ = abiDirsVersion(w.dirs)
+	return w.load()
+}
+
+func (w *ABIWatcher) load() error {
+	abis, err := LoadABIs(w.dirs)
+	if err != nil {
+		return err
+	}
+	return w.apply(abis)
+}
+
+// abiDirsVersion summarizes the name, size, and modification time of every
+// JSON file under dirs.
+func abiDirsVersion(dirs []string) (string, error) {
+	h := sha256.New()
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(strings.ToLower(d.Name()), ".json") {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// extractABI returns the ABI array in data: data itself, or the "abi" field
+// of an artifact object. ok is false for objects without one, such as
+// Hardhat debug files and build info.
+func extractABI(data []byte) (abiJSON []byte, ok bool, err error) {
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte("{")) {
+		return data, true, nil
+	}
+	var artifact struct {
+		ABI json.RawMessage `json:"abi"`
+	}
+	if err := json.Unmarshal(trimmed, &artifact); err != nil {
+		return nil, false, err
+	}
+	if len(artifact.ABI) == 0 {
+		return nil, false, nil
+	}
+	return artifact.ABI, true, nil
+}
+
+// findABI returns the loaded ABI whose path is name, ends in /name, or has
+// the base name name.
+func findABI(abis map[string]*abi.ABI, name string) (*abi.ABI, bool) {
+	name = filepath.ToSlash(name)
+	for path, a := range abis {
+		p := filepath.ToSlash(path)
+		if p == name || strings.HasSuffix(p, "/"+name) {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// findEventByID returns the event whose signature hashes to topic0 across
+// the loaded ABIs, or nil if none defines it. ABIs that agree on which
+// arguments are indexed decode a log the same way; the first by path is
+// used. If they disagree, as ERC-20 and ERC-721 Transfer do, the event is
+// ambiguous and the rule must bind one ABI with match.abi.
+func findEventByID(abis map[string]*abi.ABI, topic0 common.Hash) (*abi.Event, error) {
+	paths := make([]string, 0, len(abis))
+	for path := range abis {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var found *abi.Event
+	var foundIn string
+	for _, path := range paths {
+		for _, ev := range abis[path].Events {
+			if ev.ID != topic0 {
+				continue
+			}
+			if found == nil {
+				ev := ev
+				found, foundIn = &ev, path
+			} else if indexedLayout(*found) != indexedLayout(ev) {
+				return nil, fmt.Errorf("event is indexed differently in %s and %s; set match.abi to choose one", foundIn, path)
+			}
+		}
+	}
+	return found, nil
+}
+
+func indexedLayout(ev abi.Event) string {
+	var b strings.Builder
+	for _, in := range ev.Inputs {
+		if in.Indexed {
+			b.WriteByte('i')
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
 // FindEvent searches loaded ABIs for an event with the given name.
 func FindEvent(abis map[string]*abi.ABI, eventName string) (*abi.Event, bool) {
 	for _, a := range abis {
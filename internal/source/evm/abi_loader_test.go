@@ -0,0 +1,102 @@
+package evm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+func TestLoadABIsReadsArtifacts(t *testing.T) {
+	const events = `[{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+		{"name":"from","type":"address","indexed":true},
+		{"name":"to","type":"address","indexed":true},
+		{"name":"value","type":"uint256","indexed":false}]}]`
+	dir := t.TempDir()
+	files := map[string]string{
+		"raw/Token.json": events,
+		// Hardhat: artifacts/contracts/Token.sol/Token.json plus a debug file.
+		"artifacts/Token.sol/Token.json":     `{"_format":"hh-sol-artifact-1","contractName":"Token","abi":` + events + `,"bytecode":"0x"}`,
+		"artifacts/Token.sol/Token.dbg.json": `{"_format":"hh-sol-dbg-1","buildInfo":"../build-info/1.json"}`,
+		// Foundry: out/Token.sol/Token.json.
+		"out/Token.sol/Token.json": `{"abi":` + events + `,"bytecode":{"object":"0x"},"metadata":{}}`,
+	}
+	for name, body := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	abis, err := LoadABIs([]string{dir})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(abis) != 3 {
+		t.Fatalf("expected 3 ABIs (debug file skipped), got %d", len(abis))
+	}
+	for path, a := range abis {
+		if _, ok := a.Events["Transfer"]; !ok {
+			t.Fatalf("%s: Transfer event missing", path)
+		}
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"abi": 5}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadABIs([]string{filepath.Dir(bad)}); err == nil {
+		t.Fatalf("expected error for a malformed abi field")
+	}
+}
+
+func TestABIWatcherReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string, mod time.Time) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("a.json", `[{"type":"event","name":"A","inputs":[]}]`, start)
+
+	var loaded map[string]*abi.ABI
+	w := NewABIWatcher([]string{dir}, func(abis map[string]*abi.ABI) error {
+		loaded = abis
+		return nil
+	})
+	if reloaded, err := w.Check(); err != nil || reloaded {
+		t.Fatalf("expected no reload without changes, got %v %v", reloaded, err)
+	}
+
+	write("b.json", `[{"type":"event","name":"B","inputs":[]}]`, start)
+	if reloaded, err := w.Check(); err != nil || !reloaded || len(loaded) != 2 {
+		t.Fatalf("expected reload with 2 ABIs, got %v %v (%d)", reloaded, err, len(loaded))
+	}
+
+	// A broken edit is reported once, then left until the file changes again.
+	write("b.json", `[{"type":`, start.Add(time.Minute))
+	if _, err := w.Check(); err == nil {
+		t.Fatalf("expected parse error")
+	}
+	if reloaded, err := w.Check(); err != nil || reloaded {
+		t.Fatalf("expected broken version not retried, got %v %v", reloaded, err)
+	}
+	write("b.json", `[{"type":"event","name":"C","inputs":[]}]`, start.Add(2*time.Minute))
+	if reloaded, err := w.Check(); err != nil || !reloaded {
+		t.Fatalf("expected reload after fix, got %v %v", reloaded, err)
+	}
+	if _, ok := FindEvent(loaded, "C"); !ok {
+		t.Fatalf("expected fixed ABI to be applied")
+	}
+}
@@ -0,0 +1,113 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// DefaultBackfillWindow is how many blocks one eth_getLogs call of a
+// backfill spans until the node asks for less.
+const DefaultBackfillWindow = 2000
+
+// Backfill scans blocks from..to for log rules and address watchers with
+// eth_getLogs calls of up to window blocks each, without moving the cursor.
+// A window the node refuses as too large is halved and tried again; after a
+// call succeeds the window grows back toward its limit. Rules that read
+// transactions, traces or state are not backfilled. The headers of a
+// window's blocks with logs are fetched in one batch when the client
+// batches calls. Events are stamped with
+// their block like live ones; progress, when set, is called after each
+// window with its last block.
+func (s *Scanner) Backfill(ctx context.Context, from, to, window uint64, emit func(source.Event) error, progress func(height uint64)) error {
+	if from > to {
+		return fmt.Errorf("backfill range %d-%d is empty", from, to)
+	}
+	if len(s.matchers) == 0 && len(s.watchers) == 0 {
+		return nil
+	}
+	if window == 0 {
+		window = DefaultBackfillWindow
+	}
+	if s.ens != nil {
+		next := emit
+		emit = func(ev source.Event) error {
+			s.ens.annotate(ctx, &ev)
+			return next(ev)
+		}
+	}
+	size := window
+	for start := from; start <= to; {
+		end := min(start+size-1, to)
+		logs, err := s.client.FilterLogs(ctx, s.logQuery(start, end))
+		if err != nil {
+			if IsTooManyResults(err) && size > 1 {
+				size /= 2
+				continue
+			}
+			return fmt.Errorf("filter logs %d-%d: %w", start, end, err)
+		}
+		headers := map[uint64]*types.Header{}
+		if err := s.batchHeaders(ctx, logs, headers); err != nil {
+			return err
+		}
+		for _, lg := range logs {
+			h, ok := headers[lg.BlockNumber]
+			if !ok {
+				if h, err = s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(lg.BlockNumber)); err != nil {
+					return fmt.Errorf("header %d: %w", lg.BlockNumber, err)
+				}
+				headers[lg.BlockNumber] = h
+			}
+			if lg.BlockHash != (common.Hash{}) && lg.BlockHash != h.Hash() {
+				return fmt.Errorf("block %d changed during backfill", lg.BlockNumber)
+			}
+			if err := s.matchLog(ctx, lg, stamper(s.source.ID, h, emit)); err != nil {
+				return err
+			}
+		}
+		if progress != nil {
+			progress(end)
+		}
+		if end == to {
+			break
+		}
+		start = end + 1
+		size = min(size*2, window)
+	}
+	return nil
+}
+
+// IsTooManyResults reports whether err is a node refusing an eth_getLogs
+// call for spanning too many blocks or returning too many logs. Providers
+// word it differently, and some use the -32005 rate limit code for it.
+func IsTooManyResults(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range tooManyResults {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// tooManyResults are the error messages of providers that cap eth_getLogs.
+var tooManyResults = []string{
+	"too many results",
+	"query returned more than",
+	"response size exceeded",
+	"response size should not greater than",
+	"block range is too wide",
+	"block range too large",
+	"exceed maximum block range",
+	"exceeds the range allowed",
+}
@@ -0,0 +1,97 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// cappedClient refuses eth_getLogs calls spanning more than maxRange blocks.
+type cappedClient struct {
+	*fakeClient
+	maxRange uint64
+	ranges   [][2]uint64
+}
+
+func (c *cappedClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	from, to := q.FromBlock.Uint64(), q.ToBlock.Uint64()
+	c.ranges = append(c.ranges, [2]uint64{from, to})
+	if to-from+1 > c.maxRange {
+		return nil, errors.New("query returned more than 10000 results")
+	}
+	return c.fakeClient.FilterLogs(ctx, q)
+}
+
+func TestScannerBackfill(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	rule := config.Rule{
+		ID:     "any_transfer",
+		Source: "evm_main",
+		Match:  config.MatchSpec{Type: MatchERC721Transfer, Contract: "0x00000000000000000000000000000000000000aa"},
+	}
+	fc := &fakeClient{headers: map[uint64]*types.Header{}, logs: map[uint64][]types.Log{}}
+	for n := uint64(1); n <= 20; n++ {
+		fc.headers[n] = &types.Header{Number: new(big.Int).SetUint64(n), Time: 1_700_000_000 + n*12}
+	}
+	for _, n := range []uint64{2, 9, 17} {
+		fc.logs[n] = []types.Log{{
+			Address: common.HexToAddress(rule.Match.Contract),
+			Topics: []common.Hash{
+				transferTopic("Transfer(address,address,uint256)"),
+				addrTopic(common.HexToAddress("0x01")),
+				addrTopic(common.HexToAddress("0x02")),
+				common.BigToHash(new(big.Int).SetUint64(n)),
+			},
+			BlockNumber: n,
+			BlockHash:   fc.headers[n].Hash(),
+		}}
+	}
+	client := &cappedClient{fakeClient: fc, maxRange: 4}
+	scanner, err := NewScanner(client, store, config.Source{ID: "evm_main", Type: "evm"}, 0, nil, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+
+	var heights []uint64
+	var done []uint64
+	err = scanner.Backfill(ctx, 1, 18, 8, func(ev source.Event) error {
+		heights = append(heights, ev.Height)
+		return nil
+	}, func(h uint64) { done = append(done, h) })
+	if err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if len(heights) != 3 || heights[0] != 2 || heights[1] != 9 || heights[2] != 17 {
+		t.Fatalf("unexpected events at %v", heights)
+	}
+	// 1-8 is refused and halved; each success doubles the window again,
+	// so the refusals repeat.
+	want := [][2]uint64{{1, 8}, {1, 4}, {5, 12}, {5, 8}, {9, 16}, {9, 12}, {13, 18}, {13, 16}, {17, 18}}
+	if len(client.ranges) != len(want) {
+		t.Fatalf("unexpected windows %v", client.ranges)
+	}
+	for i := range want {
+		if client.ranges[i] != want[i] {
+			t.Fatalf("unexpected windows %v", client.ranges)
+		}
+	}
+	if len(done) != 5 || done[4] != 18 {
+		t.Fatalf("unexpected progress %v", done)
+	}
+	if _, _, ok, _ := store.GetCursor(ctx, "evm_main"); ok {
+		t.Fatalf("backfill moved the cursor")
+	}
+
+	client.maxRange = 0
+	if err := scanner.Backfill(ctx, 1, 2, 8, func(source.Event) error { return nil }, nil); err == nil {
+		t.Fatalf("expected an error once the window cannot shrink")
+	}
+}
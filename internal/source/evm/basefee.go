@@ -0,0 +1,170 @@
+package evm
+
+import (
+	"context"
+	"math"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Event names of base_fee rule matches.
+const (
+	BaseFeeBelowEvent  = "base_fee_below"
+	BaseFeeAboveEvent  = "base_fee_above"
+	BaseFeeChangeEvent = "base_fee_change"
+)
+
+// Threshold states recorded for a base_fee rule.
+const (
+	baseFeeOK    = "ok"
+	baseFeeBelow = "below"
+	baseFeeAbove = "above"
+)
+
+// baseFeeWatcher follows the EIP-1559 base fee of each block header. Its
+// thresholds fire once per crossing and re-arm only after the fee moves back
+// past them by the rule's hysteresis. change_pct fires when the fee is that
+// much above the lowest, or below the highest, fee of the last within_blocks
+// blocks; the window then starts over, so one spike alerts once.
+type baseFeeWatcher struct {
+	rule   config.Rule
+	window uint64
+	recent []baseFeeAt // oldest first, kept in memory only
+}
+
+type baseFeeAt struct {
+	height, fee uint64
+}
+
+func newBaseFeeWatcher(rule config.Rule) *baseFeeWatcher {
+	return &baseFeeWatcher{rule: rule, window: max(rule.Match.WithinBlocks, 1)}
+}
+
+// next returns the threshold state for fee given the previous state.
+func (w *baseFeeWatcher) next(prev string, fee uint64) string {
+	m := w.rule.Match
+	switch {
+	case m.Below != 0 && fee < m.Below:
+		return baseFeeBelow
+	case m.Above != 0 && fee > m.Above:
+		return baseFeeAbove
+	case prev == baseFeeBelow && fee-m.Below < m.Hysteresis:
+		return baseFeeBelow
+	case prev == baseFeeAbove && m.Above-fee < m.Hysteresis:
+		return baseFeeAbove
+	default:
+		return baseFeeOK
+	}
+}
+
+func (w *baseFeeWatcher) thresholdEvent(state string, fee uint64) source.Event {
+	m := w.rule.Match
+	name, threshold := BaseFeeBelowEvent, m.Below
+	if state == baseFeeAbove {
+		name, threshold = BaseFeeAboveEvent, m.Above
+	}
+	return source.Event{
+		RuleID: w.rule.ID,
+		Name:   name,
+		Args: map[string]any{
+			"base_fee":      fee,
+			"base_fee_gwei": gwei(fee),
+			"threshold":     threshold,
+			"hysteresis":    m.Hysteresis,
+		},
+	}
+}
+
+// change adds the fee at height to the window and reports a move of
+// change_pct or more against it.
+func (w *baseFeeWatcher) change(height, fee uint64) (source.Event, bool) {
+	// Blocks at or above height were rewound by a reorg.
+	kept := w.recent[:0]
+	for _, b := range w.recent {
+		if b.height < height && b.height+w.window >= height {
+			kept = append(kept, b)
+		}
+	}
+	w.recent = append(kept, baseFeeAt{height, fee})
+	var low, high *baseFeeAt
+	for i := range w.recent[:len(w.recent)-1] {
+		b := &w.recent[i]
+		if low == nil || b.fee < low.fee {
+			low = b
+		}
+		if high == nil || b.fee > high.fee {
+			high = b
+		}
+	}
+	pct := w.rule.Match.ChangePct
+	var from baseFeeAt
+	switch {
+	case low != nil && low.fee > 0 && float64(fee) >= float64(low.fee)*(1+pct/100):
+		from = *low
+	case high != nil && high.fee > 0 && float64(fee) <= float64(high.fee)*(1-pct/100):
+		from = *high
+	default:
+		return source.Event{}, false
+	}
+	w.recent = []baseFeeAt{{height, fee}}
+	return source.Event{
+		RuleID: w.rule.ID,
+		Name:   BaseFeeChangeEvent,
+		Args: map[string]any{
+			"base_fee":      fee,
+			"base_fee_gwei": gwei(fee),
+			"from_base_fee": from.fee,
+			"from_height":   from.height,
+			"change_pct":    (float64(fee) - float64(from.fee)) / float64(from.fee) * 100,
+			"blocks":        height - from.height,
+		},
+	}, true
+}
+
+func gwei(wei uint64) float64 {
+	return float64(wei) / 1e9
+}
+
+// checkBaseFee runs the base_fee rules on a header. Blocks from before
+// EIP-1559 have no base fee and are skipped.
+func (s *Scanner) checkBaseFee(ctx context.Context, header *types.Header, emit func(source.Event) error) error {
+	if len(s.baseFees) == 0 || header.BaseFee == nil {
+		return nil
+	}
+	height := header.Number.Uint64()
+	fee := uint64(math.MaxUint64)
+	if header.BaseFee.IsUint64() {
+		fee = header.BaseFee.Uint64()
+	}
+	for _, w := range s.baseFees {
+		m := w.rule.Match
+		if m.Below != 0 || m.Above != 0 {
+			prev, _, err := s.store.GetStateValue(ctx, s.source.ID, w.rule.ID)
+			if err != nil {
+				return err
+			}
+			state := w.next(prev.Value, fee)
+			if state != baseFeeOK && state != prev.Value {
+				if err := emit(w.thresholdEvent(state, fee)); err != nil {
+					return err
+				}
+			}
+			if state != prev.Value {
+				if err := s.store.UpsertStateValue(ctx, storage.StateValue{SourceID: s.source.ID, RuleID: w.rule.ID, Value: state, Height: height}); err != nil {
+					return err
+				}
+			}
+		}
+		if m.ChangePct > 0 {
+			if ev, ok := w.change(height, fee); ok {
+				if err := emit(ev); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestScannerBaseFeeRules(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	const gwei = 1_000_000_000
+	rules := []config.Rule{
+		{ID: "cheap_gas", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchBaseFee, Below: 10 * gwei, Hysteresis: 2 * gwei}},
+		{ID: "gas_spike", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchBaseFee, ChangePct: 50, WithinBlocks: 3}},
+	}
+	scanner, err := NewScanner(&fakeClient{}, store, config.Source{ID: "evm_main", Type: "evm"}, 0, nil, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+
+	var got []string
+	emit := func(ev source.Event) error {
+		got = append(got, ev.RuleID+":"+ev.Name)
+		return nil
+	}
+	// Fees in gwei: a drop below 10 that hovers within the hysteresis band,
+	// then a climb that only reaches 50% over three blocks, and a 50% jump
+	// in one. A pre-London header is skipped.
+	fees := []int64{12, 9, 11, 12, 13, 16, 18, 28, 0}
+	want := [][]string{
+		nil,
+		{"cheap_gas:base_fee_below"},
+		nil,
+		nil,
+		nil,
+		nil,
+		{"gas_spike:base_fee_change"},
+		{"gas_spike:base_fee_change"},
+		nil,
+	}
+	for i, fee := range fees {
+		h := &types.Header{Number: big.NewInt(int64(i + 1))}
+		if fee > 0 {
+			h.BaseFee = big.NewInt(fee * gwei)
+		}
+		got = nil
+		if err := scanner.checkBaseFee(ctx, h, emit); err != nil {
+			t.Fatalf("block %d: %v", i+1, err)
+		}
+		if len(got) != len(want[i]) || (len(got) > 0 && got[0] != want[i][0]) {
+			t.Fatalf("block %d (%d gwei): got %v, want %v", i+1, fee, got, want[i])
+		}
+	}
+
+	// Re-armed by block 4, so the next drop alerts again.
+	got = nil
+	if err := scanner.checkBaseFee(ctx, &types.Header{Number: big.NewInt(10), BaseFee: big.NewInt(5 * gwei)}, emit); err != nil {
+		t.Fatalf("block 10: %v", err)
+	}
+	if len(got) != 2 || got[0] != "cheap_gas:base_fee_below" || got[1] != "gas_spike:base_fee_change" {
+		t.Fatalf("unexpected events %v", got)
+	}
+}
@@ -0,0 +1,128 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var errNoBatch = errors.New("client does not batch calls")
+
+// BatchClient is implemented by clients that can send several JSON-RPC calls
+// in one request.
+type BatchClient interface {
+	// HeadersAndLogs fetches the headers at numbers and, when q is set, the
+	// logs it selects. q must name its block range.
+	HeadersAndLogs(ctx context.Context, numbers []uint64, q *ethereum.FilterQuery) ([]*types.Header, []types.Log, error)
+}
+
+// HeadersAndLogs implements BatchClient with one JSON-RPC batch of
+// eth_getBlockByNumber calls and an eth_getLogs call.
+func (c *RPCClient) HeadersAndLogs(ctx context.Context, numbers []uint64, q *ethereum.FilterQuery) ([]*types.Header, []types.Log, error) {
+	headers := make([]*types.Header, len(numbers))
+	batch := make([]rpc.BatchElem, 0, len(numbers)+1)
+	for i, n := range numbers {
+		batch = append(batch, rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []any{hexutil.EncodeUint64(n), false},
+			Result: &headers[i],
+		})
+	}
+	var logs []types.Log
+	if q != nil {
+		batch = append(batch, rpc.BatchElem{
+			Method: "eth_getLogs",
+			Args:   []any{filterArg(*q)},
+			Result: &logs,
+		})
+	}
+	if err := c.Client.Client().BatchCallContext(ctx, batch); err != nil {
+		return nil, nil, err
+	}
+	for i, n := range numbers {
+		if err := batch[i].Error; err != nil {
+			return nil, nil, fmt.Errorf("header %d: %w", n, err)
+		}
+		if headers[i] == nil {
+			return nil, nil, fmt.Errorf("header %d: %w", n, ethereum.NotFound)
+		}
+	}
+	if q != nil {
+		if err := batch[len(numbers)].Error; err != nil {
+			return nil, nil, fmt.Errorf("filter logs %d-%d: %w", q.FromBlock, q.ToBlock, err)
+		}
+	}
+	return headers, logs, nil
+}
+
+// filterArg is the eth_getLogs parameter for a query over a block range.
+func filterArg(q ethereum.FilterQuery) map[string]any {
+	arg := map[string]any{
+		"fromBlock": hexutil.EncodeBig(q.FromBlock),
+		"toBlock":   hexutil.EncodeBig(q.ToBlock),
+		"topics":    q.Topics,
+	}
+	if len(q.Addresses) > 0 {
+		arg["address"] = q.Addresses
+	}
+	return arg
+}
+
+// logQuery is the eth_getLogs query for the source's log rules over blocks
+// from..to.
+func (s *Scanner) logQuery(from, to uint64) ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: s.addresses,
+		Topics:    s.topics,
+	}
+}
+
+// batch fetches headers and logs in one round trip. ok is false when the
+// client cannot batch, and the caller fetches them one call at a time.
+func (s *Scanner) batch(ctx context.Context, numbers []uint64, q *ethereum.FilterQuery) (headers []*types.Header, logs []types.Log, ok bool, err error) {
+	bc, isBatch := s.client.(BatchClient)
+	if !isBatch {
+		return nil, nil, false, nil
+	}
+	headers, logs, err = bc.HeadersAndLogs(ctx, numbers, q)
+	if errors.Is(err, errNoBatch) {
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return headers, logs, true, nil
+}
+
+// batchHeaders adds the headers of the blocks with logs that are not in
+// headers yet, fetched in one request. Clients that cannot batch are left
+// to fetch them one at a time.
+func (s *Scanner) batchHeaders(ctx context.Context, logs []types.Log, headers map[uint64]*types.Header) error {
+	var missing []uint64
+	seen := map[uint64]bool{}
+	for _, lg := range logs {
+		if _, ok := headers[lg.BlockNumber]; !ok && !seen[lg.BlockNumber] {
+			seen[lg.BlockNumber] = true
+			missing = append(missing, lg.BlockNumber)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	hs, _, ok, err := s.batch(ctx, missing, nil)
+	if err != nil || !ok {
+		return err
+	}
+	for i, n := range missing {
+		headers[n] = hs[i]
+	}
+	return nil
+}
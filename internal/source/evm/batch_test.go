@@ -0,0 +1,153 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// rpcCall is one JSON-RPC request as the test node sees it.
+type rpcCall struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+func TestScannerBatchesHeadersAndLogs(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	rule := config.Rule{
+		ID:     "any_transfer",
+		Source: "evm_main",
+		Match:  config.MatchSpec{Type: MatchERC721Transfer, Contract: "0x00000000000000000000000000000000000000aa"},
+	}
+	headers := map[uint64]*types.Header{}
+	var parent common.Hash
+	for n := uint64(1); n <= 10; n++ {
+		h := &types.Header{Number: new(big.Int).SetUint64(n), ParentHash: parent, Time: 1_700_000_000 + n*12, Difficulty: big.NewInt(0)}
+		headers[n] = h
+		parent = h.Hash()
+	}
+	logs := map[uint64]types.Log{}
+	for _, n := range []uint64{3, 7} {
+		logs[n] = types.Log{
+			Address: common.HexToAddress(rule.Match.Contract),
+			Topics: []common.Hash{
+				transferTopic("Transfer(address,address,uint256)"),
+				addrTopic(common.HexToAddress("0x01")),
+				addrTopic(common.HexToAddress("0x02")),
+				common.BigToHash(new(big.Int).SetUint64(n)),
+			},
+			BlockNumber: n,
+			BlockHash:   headers[n].Hash(),
+		}
+	}
+
+	var single, batches [][]string
+	answer := func(call rpcCall) map[string]any {
+		out := map[string]any{"jsonrpc": "2.0", "id": call.ID}
+		switch call.Method {
+		case "eth_getBlockByNumber":
+			var tag string
+			_ = json.Unmarshal(call.Params[0], &tag)
+			n := uint64(10)
+			if tag != "latest" {
+				n, _ = hexutil.DecodeUint64(tag)
+			}
+			out["result"] = headers[n]
+		case "eth_getLogs":
+			var q struct{ FromBlock, ToBlock hexutil.Uint64 }
+			_ = json.Unmarshal(call.Params[0], &q)
+			found := []types.Log{}
+			for n := uint64(q.FromBlock); n <= uint64(q.ToBlock); n++ {
+				if lg, ok := logs[n]; ok {
+					found = append(found, lg)
+				}
+			}
+			out["result"] = found
+		}
+		return out
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		var calls []rpcCall
+		if err := json.Unmarshal(body, &calls); err != nil {
+			var call rpcCall
+			_ = json.Unmarshal(body, &call)
+			single = append(single, []string{call.Method})
+			_ = json.NewEncoder(w).Encode(answer(call))
+			return
+		}
+		var methods []string
+		var out []map[string]any
+		for _, c := range calls {
+			methods = append(methods, c.Method)
+			out = append(out, answer(c))
+		}
+		batches = append(batches, methods)
+		_ = json.NewEncoder(w).Encode(out)
+	}))
+	defer server.Close()
+
+	c, err := NewRPCClient(server.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	client := NewLimitedClient(c, 0)
+
+	// Catching up five blocks at a time: the range's ends and its logs, then
+	// the header of the block with a log.
+	source := config.Source{ID: "evm_main", Type: "evm", StartBlock: "1", MaxBlocksPerTick: 5}
+	scanner, err := NewScanner(client, store, source, 0, nil, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	for i, want := range []uint64{3, 7} {
+		evs, err := scanner.ProcessNext(ctx)
+		if err != nil {
+			t.Fatalf("batch %d: %v", i, err)
+		}
+		if len(evs) != 1 || evs[0].Height != want {
+			t.Fatalf("batch %d: unexpected events %+v", i, evs)
+		}
+	}
+	if len(single) != 1 || len(batches) != 4 {
+		t.Fatalf("unexpected requests: single %v, batches %v", single, batches)
+	}
+	if b := batches[0]; len(b) != 3 || b[0] != "eth_getBlockByNumber" || b[2] != "eth_getLogs" {
+		t.Fatalf("unexpected range batch %v", b)
+	}
+	if b := batches[1]; len(b) != 1 || b[0] != "eth_getBlockByNumber" {
+		t.Fatalf("unexpected header batch %v", b)
+	}
+
+	// One block at a time: its header and logs in one request.
+	single, batches = nil, nil
+	source = config.Source{ID: "evm_tail", Type: "evm", StartBlock: "7"}
+	rule.Source = source.ID
+	scanner, err = NewScanner(client, store, source, 0, nil, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	evs, err := scanner.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(evs) != 1 || evs[0].Height != 7 {
+		t.Fatalf("unexpected events %+v", evs)
+	}
+	if len(single) != 1 || len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("unexpected requests: single %v, batches %v", single, batches)
+	}
+}
@@ -0,0 +1,97 @@
+package evm
+
+import (
+	"math/big"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BlobTxEvent is the event name of blob_tx matches.
+const BlobTxEvent = "blob_tx"
+
+// blobWatcher matches EIP-4844 blob transactions, from any sender or from
+// one of a blob_tx rule's addresses, carrying at least min_blobs blobs and
+// paying at least min_blob_fee for them.
+type blobWatcher struct {
+	rule     config.Rule
+	senders  map[common.Address]struct{} // empty for any sender
+	minBlobs uint64
+	minFee   *big.Int // nil for any fee
+}
+
+func newBlobWatcher(rule config.Rule) (*blobWatcher, error) {
+	w := &blobWatcher{rule: rule, senders: map[common.Address]struct{}{}, minBlobs: rule.Match.MinBlobs}
+	for _, a := range rule.Match.Addresses {
+		w.senders[common.HexToAddress(a)] = struct{}{}
+	}
+	if rule.Match.MinBlobFee != "" {
+		fee, err := config.ParseWei(rule.Match.MinBlobFee)
+		if err != nil {
+			return nil, err
+		}
+		w.minFee = fee
+	}
+	return w, nil
+}
+
+// blobBaseFee returns the blob gas price of the block, or nil before
+// Cancun.
+func blobBaseFee(header *types.Header) *big.Int {
+	if header.ExcessBlobGas == nil {
+		return nil
+	}
+	return eip4844.CalcBlobFee(*header.ExcessBlobGas)
+}
+
+// matchTx reports whether tx is a watched blob transaction. The fee it paid
+// for its blobs is their gas times the block's blob base fee; a min_blob_fee
+// rule never matches in a block without one.
+func (w *blobWatcher) matchTx(tx *types.Transaction, from common.Address, baseFee *big.Int) (*source.Event, bool) {
+	if tx.Type() != types.BlobTxType {
+		return nil, false
+	}
+	if len(w.senders) > 0 {
+		if _, ok := w.senders[from]; !ok {
+			return nil, false
+		}
+	}
+	blobs := uint64(len(tx.BlobHashes()))
+	if blobs < w.minBlobs {
+		return nil, false
+	}
+	var fee *big.Int
+	if baseFee != nil {
+		fee = new(big.Int).Mul(new(big.Int).SetUint64(tx.BlobGas()), baseFee)
+	}
+	if w.minFee != nil && (fee == nil || fee.Cmp(w.minFee) < 0) {
+		return nil, false
+	}
+	hashes := make([]string, len(tx.BlobHashes()))
+	for i, h := range tx.BlobHashes() {
+		hashes[i] = h.Hex()
+	}
+	args := map[string]any{
+		"sender":               from.Hex(),
+		"blobs":                blobs,
+		"blob_gas":             tx.BlobGas(),
+		"max_fee_per_blob_gas": tx.BlobGasFeeCap(),
+		"blob_hashes":          hashes,
+	}
+	if to := tx.To(); to != nil {
+		args["to"] = to.Hex()
+	}
+	if fee != nil {
+		args["blob_base_fee"] = baseFee
+		args["blob_fee"] = fee
+	}
+	return &source.Event{
+		RuleID: w.rule.ID,
+		Name:   BlobTxEvent,
+		TxHash: tx.Hash().Hex(),
+		Args:   args,
+	}, true
+}
@@ -0,0 +1,61 @@
+package evm
+
+import (
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ContractCreatedEvent is the event name of contract_creation matches.
+const ContractCreatedEvent = "contract_created"
+
+// creationWatcher matches deployment transactions, from any sender or from
+// one of a contract_creation rule's addresses. Contracts deployed by other
+// contracts (factories) are internal and not visible in the block.
+type creationWatcher struct {
+	rule      config.Rule
+	deployers map[common.Address]struct{} // empty for any deployer
+}
+
+func newCreationWatcher(rule config.Rule) *creationWatcher {
+	w := &creationWatcher{rule: rule, deployers: map[common.Address]struct{}{}}
+	for _, a := range rule.Match.Addresses {
+		w.deployers[common.HexToAddress(a)] = struct{}{}
+	}
+	return w
+}
+
+// matchTx reports whether tx deploys a contract from a watched deployer.
+func (w *creationWatcher) matchTx(tx *types.Transaction, from common.Address) bool {
+	if tx.To() != nil {
+		return false
+	}
+	if len(w.deployers) == 0 {
+		return true
+	}
+	_, ok := w.deployers[from]
+	return ok
+}
+
+// event builds the match for a deployment. The new address comes from the
+// receipt, or is derived from the deployer and nonce without one.
+func (w *creationWatcher) event(tx *types.Transaction, from common.Address, rcpt *types.Receipt) source.Event {
+	addr := crypto.CreateAddress(from, tx.Nonce())
+	if rcpt != nil && rcpt.ContractAddress != (common.Address{}) {
+		addr = rcpt.ContractAddress
+	}
+	return source.Event{
+		RuleID:   w.rule.ID,
+		Contract: addr.Hex(),
+		Name:     ContractCreatedEvent,
+		TxHash:   tx.Hash().Hex(),
+		Args: map[string]any{
+			"deployer":       from.Hex(),
+			"address":        addr.Hex(),
+			"value":          tx.Value(),
+			"init_code_size": len(tx.Data()),
+		},
+	}
+}
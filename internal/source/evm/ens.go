@@ -0,0 +1,189 @@
+package evm
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DefaultENSRegistry is the ENS registry on Ethereum mainnet and its testnets.
+var DefaultENSRegistry = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+// DefaultENSTTL is how long a looked up name is trusted before it is looked
+// up again, since owners can change their primary name at any time.
+const DefaultENSTTL = 24 * time.Hour
+
+var (
+	// resolver(bytes32) on the registry, name(bytes32) and addr(bytes32) on
+	// resolvers.
+	selENSResolver = common.FromHex("0x0178b8bf")
+	selENSName     = common.FromHex("0x691f3431")
+	selENSAddr     = common.FromHex("0x3b3b57de")
+)
+
+// ENSResolver looks up the primary ENS names of addresses with eth_call.
+// A name counts only when it resolves back to the address, as ENS requires,
+// so no one can pass their address off under someone else's name. Results,
+// including addresses without a name, are kept in memory and in the store
+// for ttl.
+type ENSResolver struct {
+	caller   ethereum.ContractCaller
+	store    *storage.Store
+	sourceID string
+	registry common.Address
+	ttl      time.Duration
+	nowFunc  func() time.Time
+
+	mu  sync.Mutex
+	mem map[common.Address]storage.ENSName
+}
+
+// NewENSResolver builds a resolver for one source, using the canonical
+// registry.
+func NewENSResolver(caller ethereum.ContractCaller, store *storage.Store, sourceID string) *ENSResolver {
+	return &ENSResolver{
+		caller:   caller,
+		store:    store,
+		sourceID: sourceID,
+		registry: DefaultENSRegistry,
+		ttl:      DefaultENSTTL,
+		nowFunc:  time.Now,
+		mem:      map[common.Address]storage.ENSName{},
+	}
+}
+
+// SetRegistry points the resolver at another ENS registry.
+func (r *ENSResolver) SetRegistry(registry common.Address) {
+	r.registry = registry
+}
+
+// Lookup returns the primary name of addr, or "" when it has none. RPC
+// errors are returned and the lookup is retried next time.
+func (r *ENSResolver) Lookup(ctx context.Context, addr common.Address) (string, error) {
+	now := r.nowFunc()
+	r.mu.Lock()
+	n, ok := r.mem[addr]
+	r.mu.Unlock()
+	if ok && now.Sub(n.UpdatedAt) < r.ttl {
+		return n.Name, nil
+	}
+
+	key := strings.ToLower(addr.Hex())
+	n, ok, err := r.store.GetENSName(ctx, r.sourceID, key)
+	if err != nil {
+		return "", err
+	}
+	if !ok || now.Sub(n.UpdatedAt) >= r.ttl {
+		name, err := r.reverse(ctx, addr)
+		if err != nil {
+			return "", err
+		}
+		n = storage.ENSName{SourceID: r.sourceID, Address: key, Name: name, UpdatedAt: now}
+		if err := r.store.UpsertENSName(ctx, n); err != nil {
+			return "", err
+		}
+	}
+	r.mu.Lock()
+	r.mem[addr] = n
+	r.mu.Unlock()
+	return n.Name, nil
+}
+
+// reverse reads the name set for addr's reverse record and checks that the
+// name's addr record points back at addr.
+func (r *ENSResolver) reverse(ctx context.Context, addr common.Address) (string, error) {
+	node := ensNamehash(hex.EncodeToString(addr.Bytes()) + ".addr.reverse")
+	out, err := r.resolverCall(ctx, node, selENSName)
+	if err != nil || len(out) < 64 {
+		return "", err
+	}
+	vals, err := stringArgs.Unpack(out)
+	if err != nil {
+		return "", nil
+	}
+	name, _ := vals[0].(string)
+	if name == "" {
+		return "", nil
+	}
+	out, err = r.resolverCall(ctx, ensNamehash(name), selENSAddr)
+	if err != nil || len(out) < 32 || common.BytesToAddress(out[:32]) != addr {
+		return "", err
+	}
+	return name, nil
+}
+
+// resolverCall calls selector(node) on the resolver the registry names for
+// node. It returns nil output when there is no resolver or the call reverts.
+func (r *ENSResolver) resolverCall(ctx context.Context, node common.Hash, selector []byte) ([]byte, error) {
+	out, err := r.caller.CallContract(ctx, ethereum.CallMsg{To: &r.registry, Data: append(append([]byte{}, selENSResolver...), node[:]...)}, nil)
+	if err != nil || len(out) < 32 {
+		return nil, err
+	}
+	resolver := common.BytesToAddress(out[:32])
+	if resolver == (common.Address{}) {
+		return nil, nil
+	}
+	out, err = r.caller.CallContract(ctx, ethereum.CallMsg{To: &resolver, Data: append(append([]byte{}, selector...), node[:]...)}, nil)
+	if isRevert(err) {
+		return nil, nil
+	}
+	return out, err
+}
+
+// annotate adds from_ens, to_ens and contract_ens to an event for the
+// addresses that have a name.
+func (r *ENSResolver) annotate(ctx context.Context, ev *source.Event) {
+	for _, key := range []string{"from", "to"} {
+		addr, ok := addressArg(ev.Args[key])
+		if !ok {
+			continue
+		}
+		if name, err := r.Lookup(ctx, addr); err == nil && name != "" {
+			ev.Args[key+"_ens"] = name
+		}
+	}
+	if !common.IsHexAddress(ev.Contract) {
+		return
+	}
+	if name, err := r.Lookup(ctx, common.HexToAddress(ev.Contract)); err == nil && name != "" {
+		if ev.Args == nil {
+			ev.Args = map[string]any{}
+		}
+		ev.Args["contract_ens"] = name
+	}
+}
+
+// addressArg reads an address arg, decoded from an ABI or written as hex.
+func addressArg(v any) (common.Address, bool) {
+	switch a := v.(type) {
+	case common.Address:
+		return a, a != (common.Address{})
+	case string:
+		if common.IsHexAddress(a) {
+			addr := common.HexToAddress(a)
+			return addr, addr != (common.Address{})
+		}
+	}
+	return common.Address{}, false
+}
+
+// ensNamehash is the EIP-137 namehash of a name.
+func ensNamehash(name string) common.Hash {
+	var node common.Hash
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256Hash(node[:], crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
@@ -0,0 +1,88 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// fakeENS is a registry whose nodes all use one resolver.
+type fakeENS struct {
+	resolver common.Address
+	names    map[common.Hash]string         // reverse node => name
+	addrs    map[common.Hash]common.Address // name node => addr
+	calls    int
+}
+
+func (f *fakeENS) CallContract(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	f.calls++
+	sel, node := msg.Data[:4], common.BytesToHash(msg.Data[4:])
+	switch {
+	case *msg.To == DefaultENSRegistry && bytes.Equal(sel, selENSResolver):
+		return common.LeftPadBytes(f.resolver.Bytes(), 32), nil
+	case *msg.To == f.resolver && bytes.Equal(sel, selENSName):
+		return stringArgs.Pack(f.names[node])
+	case *msg.To == f.resolver && bytes.Equal(sel, selENSAddr):
+		return common.LeftPadBytes(f.addrs[node].Bytes(), 32), nil
+	}
+	return nil, nil
+}
+
+func TestENSResolver(t *testing.T) {
+	if got := ensNamehash("eth").Hex(); got != "0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae" {
+		t.Fatalf("unexpected namehash: %s", got)
+	}
+	reverse := func(a common.Address) common.Hash {
+		return ensNamehash(hex.EncodeToString(a.Bytes()) + ".addr.reverse")
+	}
+	alice := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	mallory := common.HexToAddress("0x00000000000000000000000000000000000000b2")
+	token := common.HexToAddress("0x00000000000000000000000000000000000000c3")
+	f := &fakeENS{
+		resolver: common.HexToAddress("0x00000000000000000000000000000000000000ee"),
+		// Mallory claims alice's name, which does not point back at mallory.
+		names: map[common.Hash]string{reverse(alice): "alice.eth", reverse(mallory): "alice.eth", reverse(token): "token.eth"},
+		addrs: map[common.Hash]common.Address{ensNamehash("alice.eth"): alice, ensNamehash("token.eth"): token},
+	}
+	store := newTestStore(t)
+	ctx := context.Background()
+	r := NewENSResolver(f, store, "evm_main")
+	now := time.Unix(1_700_000_000, 0)
+	r.nowFunc = func() time.Time { return now }
+
+	ev := source.Event{Contract: token.Hex(), Args: map[string]any{"from": alice, "to": mallory.Hex()}}
+	r.annotate(ctx, &ev)
+	if ev.Args["from_ens"] != "alice.eth" || ev.Args["contract_ens"] != "token.eth" {
+		t.Fatalf("unexpected args: %v", ev.Args)
+	}
+	if _, ok := ev.Args["to_ens"]; ok {
+		t.Fatalf("expected an unverified name to be dropped, got %v", ev.Args)
+	}
+
+	// A fresh resolver on the same store does not go back to the node until
+	// the names are stale.
+	calls := f.calls
+	again := NewENSResolver(f, store, "evm_main")
+	again.nowFunc = r.nowFunc
+	for _, a := range []common.Address{alice, mallory} {
+		if _, err := again.Lookup(ctx, a); err != nil {
+			t.Fatalf("lookup: %v", err)
+		}
+	}
+	if f.calls != calls {
+		t.Fatalf("expected stored names, got %d new calls", f.calls-calls)
+	}
+	now = now.Add(DefaultENSTTL)
+	f.addrs[ensNamehash("alice.eth")] = mallory
+	if name, _ := again.Lookup(ctx, alice); name != "" || f.calls == calls {
+		t.Fatalf("expected a stale name to be looked up again, got %q", name)
+	}
+}
@@ -0,0 +1,142 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// FailedTxEvent is the event name of failed_tx matches.
+const FailedTxEvent = "failed_tx"
+
+// failedTxWatcher matches transactions whose receipt shows they reverted,
+// sent to a failed_tx rule's contract, from one of its addresses, or both
+// when both are set.
+type failedTxWatcher struct {
+	rule     config.Rule
+	contract *common.Address // nil for any recipient
+	senders  map[common.Address]struct{}
+	abis     map[string]*abi.ABI // name functions and custom errors
+}
+
+func newFailedTxWatcher(rule config.Rule, abis map[string]*abi.ABI) *failedTxWatcher {
+	w := &failedTxWatcher{rule: rule, senders: map[common.Address]struct{}{}, abis: abis}
+	if rule.Match.Contract != "" {
+		c := common.HexToAddress(rule.Match.Contract)
+		w.contract = &c
+	}
+	for _, a := range rule.Match.Addresses {
+		w.senders[common.HexToAddress(a)] = struct{}{}
+	}
+	return w
+}
+
+// matchTx reports whether tx is one the rule watches, before its receipt is
+// checked.
+func (w *failedTxWatcher) matchTx(tx *types.Transaction, from common.Address) bool {
+	if w.contract != nil && (tx.To() == nil || *tx.To() != *w.contract) {
+		return false
+	}
+	if len(w.senders) > 0 {
+		if _, ok := w.senders[from]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// event builds the match for a failed transaction. revert is the data it
+// reverted with, when the node gave it back; it is decoded as an
+// Error(string) message, a panic, or the name of a custom error from the
+// loaded ABIs.
+func (w *failedTxWatcher) event(tx *types.Transaction, from common.Address, rcpt *types.Receipt, revert []byte) source.Event {
+	args := map[string]any{
+		"from":      from.Hex(),
+		"value":     tx.Value(),
+		"gas_limit": tx.Gas(),
+		"gas_used":  rcpt.GasUsed,
+	}
+	contract := ""
+	if to := tx.To(); to != nil {
+		contract = to.Hex()
+		args["to"] = contract
+	}
+	if input := tx.Data(); len(input) >= 4 {
+		args["selector"] = hexutil.Encode(input[:4])
+		if method, ok := findMethodByID(w.abis, input[:4]); ok {
+			args["function"] = method.Sig
+		}
+	}
+	if len(revert) >= 4 {
+		args["revert_data"] = hexutil.Encode(revert)
+		if reason, err := abi.UnpackRevert(revert); err == nil {
+			args["revert_reason"] = reason
+		} else if sig := customError(w.abis, revert[:4]); sig != "" {
+			args["revert_reason"] = sig
+		}
+	}
+	return source.Event{
+		RuleID:   w.rule.ID,
+		Contract: contract,
+		Name:     FailedTxEvent,
+		TxHash:   tx.Hash().Hex(),
+		Args:     args,
+	}
+}
+
+// revertData replays tx with eth_call on the state before its block and
+// returns the data it reverts with. Transactions earlier in the block can
+// change the outcome, so the replay may not revert; the data is then nil,
+// as it is when the client cannot call or the node returns none.
+func (s *Scanner) revertData(ctx context.Context, tx *types.Transaction, from common.Address, height uint64) []byte {
+	caller, ok := s.client.(ethereum.ContractCaller)
+	if !ok || height == 0 {
+		return nil
+	}
+	_, err := caller.CallContract(ctx, ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}, new(big.Int).SetUint64(height-1))
+	var de rpc.DataError
+	if err == nil || !errors.As(err, &de) {
+		return nil
+	}
+	hexData, _ := de.ErrorData().(string)
+	data, err := hexutil.Decode(hexData)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// customError returns the signature of the ABI error with the given
+// selector, or "" when no loaded ABI defines one.
+func customError(abis map[string]*abi.ABI, selector []byte) string {
+	paths := make([]string, 0, len(abis))
+	for path := range abis {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		for _, e := range abis[path].Errors {
+			if bytes.Equal(e.ID[:4], selector) {
+				return e.Sig
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,329 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// DefaultFailoverCooldown is how long an endpoint that failed is passed
+	// over. It doubles with each failure in a row, up to maxFailoverCooldown.
+	DefaultFailoverCooldown = 30 * time.Second
+	maxFailoverCooldown     = 10 * time.Minute
+	// DefaultAttemptTimeout bounds each request, so an endpoint that hangs
+	// fails over instead of stalling the source.
+	DefaultAttemptTimeout = 30 * time.Second
+)
+
+// Dial connects to a source's rpc_url: one endpoint gives a plain RPCClient,
+// several give a FailoverClient over them.
+func Dial(urls []string, headers map[string]string, auth *config.BasicAuth) (BlockClient, error) {
+	if len(urls) == 1 {
+		return NewRPCClient(urls[0], headers, auth)
+	}
+	return NewFailoverClient(urls, headers, auth)
+}
+
+// FailoverClient spreads a source over several endpoints of the same chain.
+// Requests go to the first healthy endpoint in order; one that errors or
+// times out is marked down for a cooldown and the request is retried on the
+// next. Answers from a node, such as a revert or an unknown method, are
+// returned as they are, so every endpoint should serve the same APIs.
+type FailoverClient struct {
+	endpoints []*endpoint
+	cooldown  time.Duration
+	timeout   time.Duration
+	nowFunc   func() time.Time
+	dial      func(url string) (*RPCClient, error)
+
+	mu sync.Mutex
+}
+
+// endpoint is one node and its health.
+type endpoint struct {
+	url       string
+	client    *RPCClient // nil until dialed
+	failures  int        // in a row
+	downUntil time.Time
+}
+
+// NewFailoverClient builds a client over urls, in order of preference.
+// Endpoints that cannot be dialed now, such as a websocket node that is
+// down, are retried once their cooldown ends; it fails only when none can.
+func NewFailoverClient(urls []string, headers map[string]string, auth *config.BasicAuth) (*FailoverClient, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no rpc endpoints")
+	}
+	f := &FailoverClient{
+		cooldown: DefaultFailoverCooldown,
+		timeout:  DefaultAttemptTimeout,
+		nowFunc:  time.Now,
+		dial: func(url string) (*RPCClient, error) {
+			return NewRPCClient(url, headers, auth)
+		},
+	}
+	var lastErr error
+	for _, u := range urls {
+		e := &endpoint{url: u}
+		if c, err := f.dial(u); err != nil {
+			f.markDown(e)
+			lastErr = err
+		} else {
+			e.client = c
+		}
+		f.endpoints = append(f.endpoints, e)
+	}
+	for _, e := range f.endpoints {
+		if e.client != nil {
+			return f, nil
+		}
+	}
+	return nil, lastErr
+}
+
+// SetCooldown changes how long a failed endpoint is passed over.
+func (f *FailoverClient) SetCooldown(d time.Duration) {
+	f.cooldown = d
+}
+
+// SetAttemptTimeout changes how long one request may take before the next
+// endpoint is tried (0 for no limit).
+func (f *FailoverClient) SetAttemptTimeout(d time.Duration) {
+	f.timeout = d
+}
+
+// order lists healthy endpoints in preference order, then those cooling
+// down by when they come back, so a request is tried everywhere before it
+// fails.
+func (f *FailoverClient) order() []*endpoint {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.nowFunc()
+	var healthy, down []*endpoint
+	for _, e := range f.endpoints {
+		if now.Before(e.downUntil) {
+			down = append(down, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+	sort.SliceStable(down, func(i, j int) bool { return down[i].downUntil.Before(down[j].downUntil) })
+	return append(healthy, down...)
+}
+
+func (f *FailoverClient) client(e *endpoint) (*RPCClient, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e.client != nil {
+		return e.client, nil
+	}
+	c, err := f.dial(e.url)
+	if err != nil {
+		return nil, err
+	}
+	e.client = c
+	return c, nil
+}
+
+func (f *FailoverClient) markDown(e *endpoint) {
+	backoff := f.cooldown << min(e.failures, 10)
+	if backoff > maxFailoverCooldown || backoff <= 0 {
+		backoff = maxFailoverCooldown
+	}
+	e.failures++
+	e.downUntil = f.nowFunc().Add(backoff)
+}
+
+func (f *FailoverClient) record(e *endpoint, healthy bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if healthy {
+		e.failures, e.downUntil = 0, time.Time{}
+		return
+	}
+	f.markDown(e)
+}
+
+// do runs fn against endpoints until one answers. timeout bounds each
+// attempt (0 for none).
+func (f *FailoverClient) do(ctx context.Context, timeout time.Duration, fn func(ctx context.Context, c *RPCClient) error) error {
+	var lastErr error
+	for _, e := range f.order() {
+		c, err := f.client(e)
+		if err == nil {
+			actx, cancel := ctx, context.CancelFunc(func() {})
+			if timeout > 0 {
+				actx, cancel = context.WithTimeout(ctx, timeout)
+			}
+			err = fn(actx, c)
+			cancel()
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if err == nil || !shouldFailover(err) {
+			f.record(e, true)
+			return err
+		}
+		f.record(e, false)
+		lastErr = fmt.Errorf("%s: %w", e.url, err)
+	}
+	if len(f.endpoints) > 1 {
+		return fmt.Errorf("all %d rpc endpoints failed, last: %w", len(f.endpoints), lastErr)
+	}
+	return lastErr
+}
+
+// shouldFailover reports whether err says the endpoint is unwell rather
+// than answering the request: transport errors, timeouts, HTTP errors and
+// rate limits. JSON-RPC errors and missing data are the node's answer.
+func shouldFailover(err error) bool {
+	if IsThrottled(err) {
+		return true
+	}
+	if errors.Is(err, ethereum.NotFound) || errors.Is(err, rpc.ErrNotificationsUnsupported) {
+		return false
+	}
+	var rpcErr rpc.Error
+	return !errors.As(err, &rpcErr)
+}
+
+// HeaderByNumber implements BlockClient.
+func (f *FailoverClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var h *types.Header
+	err := f.do(ctx, f.timeout, func(ctx context.Context, c *RPCClient) error {
+		var err error
+		h, err = c.HeaderByNumber(ctx, number)
+		return err
+	})
+	return h, err
+}
+
+// FilterLogs implements BlockClient.
+func (f *FailoverClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := f.do(ctx, f.timeout, func(ctx context.Context, c *RPCClient) error {
+		var err error
+		logs, err = c.FilterLogs(ctx, q)
+		return err
+	})
+	return logs, err
+}
+
+// BlockByNumber implements TxClient.
+func (f *FailoverClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	var b *types.Block
+	err := f.do(ctx, f.timeout, func(ctx context.Context, c *RPCClient) error {
+		var err error
+		b, err = c.BlockByNumber(ctx, number)
+		return err
+	})
+	return b, err
+}
+
+// TransactionByHash implements ReceiptClient.
+func (f *FailoverClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	var (
+		tx      *types.Transaction
+		pending bool
+	)
+	err := f.do(ctx, f.timeout, func(ctx context.Context, c *RPCClient) error {
+		var err error
+		tx, pending, err = c.TransactionByHash(ctx, hash)
+		return err
+	})
+	return tx, pending, err
+}
+
+// TransactionReceipt implements ReceiptClient.
+func (f *FailoverClient) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	var r *types.Receipt
+	err := f.do(ctx, f.timeout, func(ctx context.Context, c *RPCClient) error {
+		var err error
+		r, err = c.TransactionReceipt(ctx, hash)
+		return err
+	})
+	return r, err
+}
+
+// StorageAt implements StateReader.
+func (f *FailoverClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, block *big.Int) ([]byte, error) {
+	var out []byte
+	err := f.do(ctx, f.timeout, func(ctx context.Context, c *RPCClient) error {
+		var err error
+		out, err = c.StorageAt(ctx, account, key, block)
+		return err
+	})
+	return out, err
+}
+
+// CallContract implements StateReader.
+func (f *FailoverClient) CallContract(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	var out []byte
+	err := f.do(ctx, f.timeout, func(ctx context.Context, c *RPCClient) error {
+		var err error
+		out, err = c.CallContract(ctx, msg, block)
+		return err
+	})
+	return out, err
+}
+
+// HeadersAndLogs implements BatchClient.
+func (f *FailoverClient) HeadersAndLogs(ctx context.Context, numbers []uint64, q *ethereum.FilterQuery) ([]*types.Header, []types.Log, error) {
+	var (
+		headers []*types.Header
+		logs    []types.Log
+	)
+	err := f.do(ctx, f.timeout, func(ctx context.Context, c *RPCClient) error {
+		var err error
+		headers, logs, err = c.HeadersAndLogs(ctx, numbers, q)
+		return err
+	})
+	return headers, logs, err
+}
+
+// TraceBlock implements TraceClient.
+func (f *FailoverClient) TraceBlock(ctx context.Context, number uint64) ([]TraceFrame, error) {
+	var frames []TraceFrame
+	err := f.do(ctx, f.timeout, func(ctx context.Context, c *RPCClient) error {
+		var err error
+		frames, err = c.TraceBlock(ctx, number)
+		return err
+	})
+	return frames, err
+}
+
+// SubscribePendingTransactions implements MempoolClient. The subscription
+// stays on the endpoint that accepted it; when it drops, the mempool watcher
+// returns and the next subscription fails over.
+func (f *FailoverClient) SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (ethereum.Subscription, error) {
+	var sub ethereum.Subscription
+	err := f.do(ctx, 0, func(ctx context.Context, c *RPCClient) error {
+		var err error
+		sub, err = c.SubscribePendingTransactions(ctx, ch)
+		return err
+	})
+	return sub, err
+}
+
+// PendingTransactions implements MempoolClient.
+func (f *FailoverClient) PendingTransactions(ctx context.Context) ([]*types.Transaction, error) {
+	var txs []*types.Transaction
+	err := f.do(ctx, f.timeout, func(ctx context.Context, c *RPCClient) error {
+		var err error
+		txs, err = c.PendingTransactions(ctx)
+		return err
+	})
+	return txs, err
+}
@@ -0,0 +1,118 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// rpcNode answers eth_call with 0x01, or fails as mode says.
+type rpcNode struct {
+	mode  atomic.Value // "", "down", "revert" or "hang"
+	calls atomic.Int32
+}
+
+func (n *rpcNode) serve(t *testing.T) *httptest.Server {
+	n.mode.Store("")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.calls.Add(1)
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		switch n.mode.Load() {
+		case "down":
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		case "hang":
+			<-r.Context().Done()
+		case "revert":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"error":{"code":3,"message":"execution reverted"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":"0x01"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFailoverClient(t *testing.T) {
+	var primary, backup rpcNode
+	c, err := NewFailoverClient([]string{primary.serve(t).URL, backup.serve(t).URL}, nil, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	c.nowFunc = func() time.Time { return now }
+	call := func() error {
+		_, err := c.CallContract(context.Background(), ethereum.CallMsg{To: &common.Address{}}, nil)
+		return err
+	}
+	hits := func() (int32, int32) {
+		p, b := primary.calls.Load(), backup.calls.Load()
+		primary.calls.Store(0)
+		backup.calls.Store(0)
+		return p, b
+	}
+
+	if err := call(); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if p, b := hits(); p != 1 || b != 0 {
+		t.Fatalf("expected the primary to serve, got primary=%d backup=%d", p, b)
+	}
+
+	// A failing endpoint hands the request on and sits out its cooldown.
+	primary.mode.Store("down")
+	for i := 0; i < 2; i++ {
+		if err := call(); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if p, b := hits(); p != 1 || b != 2 {
+		t.Fatalf("expected one failed try then the backup, got primary=%d backup=%d", p, b)
+	}
+
+	// Once the cooldown ends the primary is preferred again.
+	primary.mode.Store("")
+	now = now.Add(DefaultFailoverCooldown)
+	if err := call(); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if p, b := hits(); p != 1 || b != 0 {
+		t.Fatalf("expected the primary back, got primary=%d backup=%d", p, b)
+	}
+
+	// A node's answer, such as a revert, is not a reason to fail over.
+	primary.mode.Store("revert")
+	if err := call(); err == nil || shouldFailover(err) {
+		t.Fatalf("expected the revert back, got %v", err)
+	}
+	if p, b := hits(); p != 1 || b != 0 {
+		t.Fatalf("expected no failover on revert, got primary=%d backup=%d", p, b)
+	}
+
+	// An endpoint that hangs times out and fails over.
+	primary.mode.Store("hang")
+	c.SetAttemptTimeout(50 * time.Millisecond)
+	if err := call(); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if p, b := hits(); p != 1 || b != 1 {
+		t.Fatalf("expected a timeout then the backup, got primary=%d backup=%d", p, b)
+	}
+
+	// With every endpoint down the error names the last one tried.
+	primary.mode.Store("down")
+	backup.mode.Store("down")
+	if err := call(); err == nil {
+		t.Fatalf("expected an error with every endpoint down")
+	}
+}
@@ -0,0 +1,160 @@
+package evm
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// functionMatcher matches transactions sent straight to a contract that call
+// one function, and decodes their calldata. Calls made by other contracts
+// are internal and not visible in the block.
+type functionMatcher struct {
+	rule     config.Rule
+	contract common.Address
+	selector []byte
+	method   abi.Method
+}
+
+func newFunctionMatcher(rule config.Rule, abis map[string]*abi.ABI) (*functionMatcher, error) {
+	method, selector, err := resolveMethod(rule, abis)
+	if err != nil {
+		return nil, err
+	}
+	return &functionMatcher{
+		rule:     rule,
+		contract: common.HexToAddress(rule.Match.Contract),
+		selector: selector,
+		method:   method,
+	}, nil
+}
+
+// resolveMethod finds the rule's function in the loaded ABIs, or the one
+// named by match.abi, by selector. Without one it is built from the
+// signature.
+func resolveMethod(rule config.Rule, abis map[string]*abi.ABI) (abi.Method, []byte, error) {
+	sig := strings.ReplaceAll(rule.Match.Function, " ", "")
+	selector := crypto.Keccak256([]byte(sig))[:4]
+	if rule.Match.ABI != "" {
+		bound, ok := findABI(abis, rule.Match.ABI)
+		if !ok {
+			return abi.Method{}, nil, fmt.Errorf("rule %s: abi %s not found in abi_dirs", rule.ID, rule.Match.ABI)
+		}
+		abis = map[string]*abi.ABI{rule.Match.ABI: bound}
+	}
+	if method, ok := findMethodByID(abis, selector); ok {
+		return method, selector, nil
+	}
+	if rule.Match.ABI != "" {
+		return abi.Method{}, nil, fmt.Errorf("rule %s: abi %s does not define %s", rule.ID, rule.Match.ABI, sig)
+	}
+	method, err := syntheticMethod(sig)
+	if err != nil {
+		return abi.Method{}, nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+	}
+	return method, selector, nil
+}
+
+// findMethodByID returns the function with the selector from the loaded
+// ABIs, taking the first by path. Argument types are fixed by the selector,
+// so ABIs only differ in argument names.
+func findMethodByID(abis map[string]*abi.ABI, selector []byte) (abi.Method, bool) {
+	paths := make([]string, 0, len(abis))
+	for path := range abis {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if method, err := abis[path].MethodById(selector); err == nil {
+			return *method, true
+		}
+	}
+	return abi.Method{}, false
+}
+
+// syntheticMethod builds a method from a signature such as
+// transferOwnership(address). Its arguments are named arg0, arg1, and so on.
+func syntheticMethod(signature string) (abi.Method, error) {
+	l := strings.Index(signature, "(")
+	r := strings.LastIndex(signature, ")")
+	if l <= 0 || r <= l {
+		return abi.Method{}, fmt.Errorf("invalid function signature: %s", signature)
+	}
+	name := signature[:l]
+	inputs, err := parseArguments(signature[l+1:r], "arg")
+	if err != nil {
+		return abi.Method{}, err
+	}
+	return abi.NewMethod(name, name, abi.Function, "", false, false, inputs, nil), nil
+}
+
+// parseArguments parses a comma-separated type list, naming the arguments
+// prefix0, prefix1, and so on.
+func parseArguments(raw, prefix string) (abi.Arguments, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var args abi.Arguments
+	for i, a := range splitTypes(raw) {
+		t, err := abi.NewType(a, "", nil)
+		if err != nil {
+			return nil, fmt.Errorf("parse type %s: %w", a, err)
+		}
+		args = append(args, abi.Argument{Name: fmt.Sprintf("%s%d", prefix, i), Type: t})
+	}
+	return args, nil
+}
+
+// splitTypes splits a signature's argument list on top-level commas, so
+// tuple types such as (address,uint256)[] stay whole.
+func splitTypes(raw string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i, c := range raw {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, raw[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, raw[start:])
+}
+
+// matchTx returns an event named after the function when tx calls it on the
+// contract. Calldata that does not decode, which anyone can send, is reported
+// with a decode_error arg rather than failing the block.
+func (m *functionMatcher) matchTx(tx *types.Transaction, from common.Address) (*source.Event, bool) {
+	to, data := tx.To(), tx.Data()
+	if to == nil || *to != m.contract || len(data) < 4 || !bytes.Equal(data[:4], m.selector) {
+		return nil, false
+	}
+	args := map[string]any{}
+	if err := m.method.Inputs.UnpackIntoMap(args, data[4:]); err != nil {
+		args = map[string]any{"decode_error": err.Error()}
+	}
+	args["caller"] = from.Hex()
+	args["tx_value"] = tx.Value()
+	args["selector"] = hexutil.Encode(m.selector)
+	return &source.Event{
+		RuleID:   m.rule.ID,
+		Contract: to.Hex(),
+		Name:     m.method.RawName,
+		TxHash:   tx.Hash().Hex(),
+		Args:     args,
+	}, true
+}
@@ -0,0 +1,205 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/rpclimit"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// limitExceededCode is the JSON-RPC error code Infura and Alchemy use for rate limits.
+const limitExceededCode = -32005
+
+// methodNotFoundCode is the JSON-RPC error code for an unknown method.
+const methodNotFoundCode = -32601
+
+var (
+	errNoEthCall = errors.New("client does not support eth_call")
+	errNoBlocks  = errors.New("client does not fetch full blocks")
+	errNoTxs     = errors.New("client does not look up transactions")
+)
+
+// LimitedClient paces calls to an inner BlockClient and retries throttled
+// and transiently failed ones.
+type LimitedClient struct {
+	inner   BlockClient
+	limiter *rpclimit.Limiter
+}
+
+// NewLimitedClient wraps c so it makes at most rps requests per second
+// (0 for no cap) and backs off on 429 / -32005 responses, 5xx responses and
+// dropped connections.
+func NewLimitedClient(c BlockClient, rps float64) *LimitedClient {
+	return &LimitedClient{inner: c, limiter: rpclimit.New(rps, func(err error) bool {
+		return IsThrottled(err) || IsTransient(err)
+	})}
+}
+
+// HeaderByNumber implements BlockClient.
+func (c *LimitedClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var h *types.Header
+	err := c.limiter.Do(ctx, func() error {
+		var err error
+		h, err = c.inner.HeaderByNumber(ctx, number)
+		return err
+	})
+	return h, err
+}
+
+// FilterLogs implements BlockClient.
+func (c *LimitedClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := c.limiter.Do(ctx, func() error {
+		var err error
+		logs, err = c.inner.FilterLogs(ctx, q)
+		return err
+	})
+	return logs, err
+}
+
+// HeadersAndLogs forwards batched calls when the inner client supports them.
+// A batch is paced as one request.
+func (c *LimitedClient) HeadersAndLogs(ctx context.Context, numbers []uint64, q *ethereum.FilterQuery) ([]*types.Header, []types.Log, error) {
+	bc, ok := c.inner.(BatchClient)
+	if !ok {
+		return nil, nil, errNoBatch
+	}
+	var (
+		headers []*types.Header
+		logs    []types.Log
+	)
+	err := c.limiter.Do(ctx, func() error {
+		var err error
+		headers, logs, err = bc.HeadersAndLogs(ctx, numbers, q)
+		return err
+	})
+	return headers, logs, err
+}
+
+// BlockByNumber forwards full block fetches when the inner client supports them.
+func (c *LimitedClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	bc, ok := c.inner.(TxClient)
+	if !ok {
+		return nil, errNoBlocks
+	}
+	var b *types.Block
+	err := c.limiter.Do(ctx, func() error {
+		var err error
+		b, err = bc.BlockByNumber(ctx, number)
+		return err
+	})
+	return b, err
+}
+
+// TransactionByHash forwards transaction lookups when the inner client supports them.
+func (c *LimitedClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	rc, ok := c.inner.(ReceiptClient)
+	if !ok {
+		return nil, false, errNoTxs
+	}
+	var (
+		tx      *types.Transaction
+		pending bool
+	)
+	err := c.limiter.Do(ctx, func() error {
+		var err error
+		tx, pending, err = rc.TransactionByHash(ctx, hash)
+		return err
+	})
+	return tx, pending, err
+}
+
+// TransactionReceipt forwards receipt lookups when the inner client supports them.
+func (c *LimitedClient) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	rc, ok := c.inner.(ReceiptClient)
+	if !ok {
+		return nil, errNoTxs
+	}
+	var r *types.Receipt
+	err := c.limiter.Do(ctx, func() error {
+		var err error
+		r, err = rc.TransactionReceipt(ctx, hash)
+		return err
+	})
+	return r, err
+}
+
+// StorageAt forwards storage reads when the inner client supports them.
+func (c *LimitedClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, block *big.Int) ([]byte, error) {
+	sr, ok := c.inner.(ethereum.ChainStateReader)
+	if !ok {
+		return nil, errNoState
+	}
+	var out []byte
+	err := c.limiter.Do(ctx, func() error {
+		var err error
+		out, err = sr.StorageAt(ctx, account, key, block)
+		return err
+	})
+	return out, err
+}
+
+// CallContract forwards eth_call when the inner client supports it.
+func (c *LimitedClient) CallContract(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	caller, ok := c.inner.(ethereum.ContractCaller)
+	if !ok {
+		return nil, errNoEthCall
+	}
+	var out []byte
+	err := c.limiter.Do(ctx, func() error {
+		var err error
+		out, err = caller.CallContract(ctx, msg, block)
+		return err
+	})
+	return out, err
+}
+
+// IsThrottled reports whether err is a provider rate-limit response. An
+// eth_getLogs call refused for its size is not, even under -32005: retrying
+// it cannot help.
+func IsThrottled(err error) bool {
+	if IsTooManyResults(err) {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == limitExceededCode {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "too many requests") || strings.Contains(msg, "rate limit")
+}
+
+// IsTransient reports whether err is a failure that may pass if the call is
+// repeated: a 5xx or 408 from the endpoint, or a network error.
+func IsTransient(err error) bool {
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) && (httpErr.StatusCode >= 500 || httpErr.StatusCode == http.StatusRequestTimeout) {
+		return true
+	}
+	return rpclimit.IsNetworkError(err)
+}
+
+// isMethodNotFound reports whether err says the node does not offer the
+// method, such as a txpool or trace API it does not enable.
+func isMethodNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFoundCode {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "method not found") || strings.Contains(msg, "does not exist")
+}
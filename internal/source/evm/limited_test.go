@@ -0,0 +1,60 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type jsonRPCError struct{ code int }
+
+func (e jsonRPCError) Error() string  { return fmt.Sprintf("rpc error %d", e.code) }
+func (e jsonRPCError) ErrorCode() int { return e.code }
+
+func TestIsThrottled(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"http 429", rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, true},
+		{"wrapped http 429", fmt.Errorf("latest header: %w", rpc.HTTPError{StatusCode: 429}), true},
+		{"limit exceeded", jsonRPCError{code: -32005}, true},
+		{"other rpc error", jsonRPCError{code: -32000}, false},
+		{"http 500", rpc.HTTPError{StatusCode: 500, Status: "500 Internal Server Error"}, false},
+		{"provider message", errors.New("daily request count exceeded, request rate limited"), true},
+		{"plain", errors.New("header 429 not found"), false},
+	}
+	for _, tc := range cases {
+		if got := IsThrottled(tc.err); got != tc.want {
+			t.Errorf("%s: IsThrottled = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"http 502", fmt.Errorf("latest header: %w", rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"}), true},
+		{"http 408", rpc.HTTPError{StatusCode: 408}, true},
+		{"http 401", rpc.HTTPError{StatusCode: 401}, false},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{"closed mid-response", fmt.Errorf("decode: %w", io.ErrUnexpectedEOF), true},
+		{"cancelled", context.Canceled, false},
+		{"rpc error", jsonRPCError{code: -32000}, false},
+	}
+	for _, tc := range cases {
+		if got := IsTransient(tc.err); got != tc.want {
+			t.Errorf("%s: IsTransient = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
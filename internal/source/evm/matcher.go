@@ -2,9 +2,11 @@ package evm
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -13,68 +15,344 @@ import (
 
 // RuleMatcher filters and decodes logs for a given rule.
 type RuleMatcher struct {
-	rule    config.Rule
-	address common.Address
-	topic0  common.Hash
-	event   *abi.Event
+	rule      config.Rule
+	kind      string
+	contracts map[common.Address]struct{}
+	// events maps each accepted topic0 to its decoder.
+	events map[common.Hash]logEvent
+	// anonymous holds ABI events declared anonymous, which have no topic0:
+	// every log of the contracts is tried against them.
+	anonymous []logEvent
+}
+
+// logEvent decodes one event signature. A nil event means the signature could
+// not be parsed, so the log matches without args. Built-in standard events
+// come as variants instead, one per indexed layout; a log only matches the
+// variant its topics fit. filter, when set, comes from match.topics.
+type logEvent struct {
+	name     string
+	event    *abi.Event
+	variants []abi.Event
+	filter   *topicFilter
+}
+
+// decoders returns the events that may decode the log.
+func (le logEvent) decoders() []abi.Event {
+	if le.event != nil {
+		return []abi.Event{*le.event}
+	}
+	return le.variants
+}
+
+// IsMatchType reports whether match.type is handled by the EVM matcher.
+func IsMatchType(t string) bool {
+	switch strings.ToLower(t) {
+	case "log", MatchERC721Transfer, MatchERC1155Transfer:
+		return true
+	}
+	return false
 }
 
-// NewRuleMatcher builds a matcher for a log rule using available ABIs. Supports only log rules.
+// NewRuleMatcher builds a matcher for a log or NFT transfer rule using available ABIs.
 func NewRuleMatcher(rule config.Rule, abis map[string]*abi.ABI) (*RuleMatcher, error) {
-	if strings.ToLower(rule.Match.Type) != "log" {
+	kind := strings.ToLower(rule.Match.Type)
+	m := &RuleMatcher{
+		rule:      rule,
+		kind:      kind,
+		contracts: map[common.Address]struct{}{},
+		events:    map[common.Hash]logEvent{},
+	}
+	contracts := rule.Match.ContractList()
+	for _, c := range contracts {
+		m.contracts[common.HexToAddress(c)] = struct{}{}
+	}
+	switch kind {
+	case "log":
+		signatures := rule.Match.Events
+		if rule.Match.Event != "" {
+			signatures = append([]string{rule.Match.Event}, signatures...)
+		}
+		if rule.Match.Standard != "" {
+			if len(contracts) == 0 {
+				return nil, fmt.Errorf("rule %s: contract is required", rule.ID)
+			}
+			events, err := standardEvents(rule.Match.Standard, signatures)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+			}
+			for _, ev := range events {
+				m.events[ev.ID] = logEvent{name: ev.Name, variants: []abi.Event{ev}}
+			}
+			break
+		}
+		if len(contracts) == 0 || (len(signatures) == 0 && rule.Match.Topic0 == "") {
+			return nil, fmt.Errorf("rule %s: contract and event are required", rule.ID)
+		}
+		if rule.Match.ABI != "" {
+			bound, ok := findABI(abis, rule.Match.ABI)
+			if !ok {
+				return nil, fmt.Errorf("rule %s: abi %s not found in abi_dirs", rule.ID, rule.Match.ABI)
+			}
+			abis = map[string]*abi.ABI{rule.Match.ABI: bound}
+		}
+		for _, sig := range signatures {
+			evName := eventName(sig)
+			topic0 := crypto.Keccak256Hash([]byte(sig))
+			ev, err := findEventByID(abis, topic0)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %s: %w", rule.ID, sig, err)
+			}
+			if ev == nil && rule.Match.ABI != "" {
+				return nil, fmt.Errorf("rule %s: abi %s does not define %s", rule.ID, rule.Match.ABI, sig)
+			}
+			if ev == nil {
+				// Standard token events know which arguments are indexed,
+				// which a bare signature does not say.
+				if variants := standardVariants(topic0); len(variants) > 0 {
+					m.events[topic0] = logEvent{name: evName, variants: variants}
+					continue
+				}
+				if synthetic, err := syntheticEvent(sig); err == nil {
+					ev = synthetic
+				}
+			}
+			if ev != nil && ev.Anonymous {
+				m.anonymous = append(m.anonymous, logEvent{name: evName, event: ev})
+				continue
+			}
+			m.events[topic0] = logEvent{name: evName, event: ev}
+		}
+		if rule.Match.Topic0 != "" {
+			topic0 := common.HexToHash(rule.Match.Topic0)
+			ev, err := findEventByID(abis, topic0)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: topic0 %s: %w", rule.ID, rule.Match.Topic0, err)
+			}
+			switch {
+			case ev != nil && !ev.Anonymous:
+				m.events[topic0] = logEvent{name: ev.Name, event: ev}
+			case len(standardVariants(topic0)) > 0:
+				variants := standardVariants(topic0)
+				m.events[topic0] = logEvent{name: variants[0].Name, variants: variants}
+			default:
+				// Nothing decodes it: the log matches without args.
+				m.events[topic0] = logEvent{name: topic0.Hex()}
+			}
+		}
+	case MatchERC721Transfer, MatchERC1155Transfer:
+		if len(contracts) == 0 {
+			return nil, fmt.Errorf("rule %s: contract is required", rule.ID)
+		}
+		for _, ev := range presetEvents(kind) {
+			m.events[ev.ID] = logEvent{name: ev.Name, event: &ev}
+		}
+	default:
 		return nil, fmt.Errorf("rule %s: match.type %s unsupported in evm matcher", rule.ID, rule.Match.Type)
 	}
-	if rule.Match.Contract == "" || rule.Match.Event == "" {
-		return nil, fmt.Errorf("rule %s: contract and event are required", rule.ID)
+	if len(rule.Match.Topics) > 0 {
+		if len(m.anonymous) > 0 {
+			return nil, fmt.Errorf("rule %s: match.topics cannot filter anonymous events", rule.ID)
+		}
+		for topic0, le := range m.events {
+			f, err := newTopicFilter(rule.Match.Topics, le.decoders())
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %s: %w", rule.ID, le.name, err)
+			}
+			le.filter = f
+			m.events[topic0] = le
+		}
 	}
+	return m, nil
+}
 
-	evName := eventName(rule.Match.Event)
-	var ev *abi.Event
-	if found, ok := FindEvent(abis, evName); ok {
-		ev = found
-	} else if synthetic, err := syntheticEvent(rule.Match.Event); err == nil {
-		ev = synthetic
+// RuleEvents returns the events a log or NFT transfer rule accepts, resolved
+// as NewRuleMatcher resolves them, with each ID set to the topic0 it matches.
+// Signatures that could not be parsed are left out. Anonymous events come
+// last, in rule order.
+func RuleEvents(rule config.Rule, abis map[string]*abi.ABI) ([]abi.Event, error) {
+	m, err := NewRuleMatcher(rule, abis)
+	if err != nil {
+		return nil, err
 	}
+	out := make([]abi.Event, 0, len(m.events)+len(m.anonymous))
+	for topic0, le := range m.events {
+		for _, ev := range le.decoders() {
+			ev.ID = topic0
+			out = append(out, ev)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ID.Cmp(out[j].ID) < 0 })
+	for _, le := range m.anonymous {
+		out = append(out, *le.event)
+	}
+	return out, nil
+}
 
-	topic := crypto.Keccak256Hash([]byte(rule.Match.Event))
+// matcherKey is the (address, topic0) pair a matcher accepts. A zero topic0
+// stands for any log of the address, for matchers of anonymous events.
+type matcherKey struct {
+	address common.Address
+	topic0  common.Hash
+}
 
-	return &RuleMatcher{
-		rule:    rule,
-		address: common.HexToAddress(rule.Match.Contract),
-		topic0:  topic,
-		event:   ev,
-	}, nil
+// matcherIndex maps (address, topic0) to the matchers that accept it, so each
+// log is dispatched in O(1) instead of being tried against every rule.
+type matcherIndex map[matcherKey][]*RuleMatcher
+
+func newMatcherIndex(matchers []*RuleMatcher) matcherIndex {
+	idx := make(matcherIndex, len(matchers))
+	pos := make(map[*RuleMatcher]int, len(matchers))
+	for i, m := range matchers {
+		pos[m] = i
+		for address := range m.contracts {
+			if len(m.anonymous) > 0 {
+				k := matcherKey{address: address}
+				idx[k] = append(idx[k], m)
+				continue
+			}
+			for topic0 := range m.events {
+				k := matcherKey{address: address, topic0: topic0}
+				idx[k] = append(idx[k], m)
+			}
+		}
+	}
+	// Logs with a known topic0 may still be anonymous events of another rule
+	// on the same contract.
+	for k, ms := range idx {
+		wild := idx[matcherKey{address: k.address}]
+		if k.topic0 == (common.Hash{}) || len(wild) == 0 {
+			continue
+		}
+		ms = append(ms, wild...)
+		sort.SliceStable(ms, func(i, j int) bool { return pos[ms[i]] < pos[ms[j]] })
+		idx[k] = ms
+	}
+	return idx
+}
+
+// lookup returns the matchers for a log, in rule order.
+func (idx matcherIndex) lookup(log types.Log) []*RuleMatcher {
+	if len(log.Topics) == 0 {
+		return idx[matcherKey{address: log.Address}]
+	}
+	if ms, ok := idx[matcherKey{address: log.Address, topic0: log.Topics[0]}]; ok {
+		return ms
+	}
+	return idx[matcherKey{address: log.Address}]
+}
+
+// mayMatch reports whether a block with this logs bloom can contain a log
+// for any matcher. Blooms have false positives but no false negatives, so a
+// false result means FilterLogs would return nothing we care about.
+func (idx matcherIndex) mayMatch(bloom types.Bloom) bool {
+	for k := range idx {
+		if bloom.Test(k.address.Bytes()) && (k.topic0 == (common.Hash{}) || bloom.Test(k.topic0.Bytes())) {
+			return true
+		}
+	}
+	return false
 }
 
 // Match checks the log against the matcher; returns a normalized event on success.
-func (m *RuleMatcher) Match(log types.Log) (*NormalizedEvent, bool, error) {
-	if log.Address != m.address {
+// An ERC-1155 TransferBatch is returned as one event with token_ids and values
+// arrays; MatchAll expands it.
+func (m *RuleMatcher) Match(log types.Log) (*source.Event, bool, error) {
+	if _, ok := m.contracts[log.Address]; !ok {
+		return nil, false, nil
+	}
+	var le logEvent
+	ok := len(log.Topics) > 0
+	if ok {
+		le, ok = m.events[log.Topics[0]]
+	}
+	if !ok {
+		return m.matchAnonymous(log)
+	}
+	if le.filter != nil && !le.filter.accepts(log.Topics) {
 		return nil, false, nil
 	}
-	if len(log.Topics) == 0 || log.Topics[0] != m.topic0 {
+	// ERC-20 and ERC-721 share the Transfer signature; only ERC-721 indexes
+	// the third argument.
+	if m.kind == MatchERC721Transfer && len(log.Topics) != 4 {
 		return nil, false, nil
 	}
 
+	event := le.event
+	if len(le.variants) > 0 {
+		event = nil
+		for i := range le.variants {
+			if fitsTopics(le.variants[i], len(log.Topics)-1) {
+				event = &le.variants[i]
+				break
+			}
+		}
+		if event == nil {
+			return nil, false, nil
+		}
+	}
+
 	args := map[string]any{}
-	if m.event != nil {
-		indexed, nonIndexed := splitIndexed(m.event.Inputs)
-		if err := abi.ParseTopicsIntoMap(args, indexed, log.Topics[1:]); err != nil {
-			return nil, false, fmt.Errorf("parse topics: %w", err)
+	if event != nil {
+		if err := decodeLog(args, event, log.Topics[1:], log.Data); err != nil {
+			return nil, false, err
 		}
-		if err := nonIndexed.UnpackIntoMap(args, log.Data); err != nil {
-			return nil, false, fmt.Errorf("unpack data: %w", err)
+	}
+	return m.event(log, le.name, args), true, nil
+}
+
+// matchAnonymous tries the rule's anonymous events, which have no topic0 to
+// check: every topic is an indexed argument. The first event whose indexed
+// arguments fill the topics and that decodes the log matches.
+func (m *RuleMatcher) matchAnonymous(log types.Log) (*source.Event, bool, error) {
+	for _, le := range m.anonymous {
+		if !fitsTopics(*le.event, len(log.Topics)) {
+			continue
 		}
+		args := map[string]any{}
+		if err := decodeLog(args, le.event, log.Topics, log.Data); err != nil {
+			continue
+		}
+		return m.event(log, le.name, args), true, nil
 	}
+	return nil, false, nil
+}
 
+// decodeLog decodes the indexed arguments of event from topics, without
+// topic0, and the others from data.
+func decodeLog(args map[string]any, event *abi.Event, topics []common.Hash, data []byte) error {
+	indexed, nonIndexed := splitIndexed(event.Inputs)
+	if err := abi.ParseTopicsIntoMap(args, indexed, topics); err != nil {
+		return fmt.Errorf("parse topics: %w", err)
+	}
+	if err := nonIndexed.UnpackIntoMap(args, data); err != nil {
+		return fmt.Errorf("unpack data: %w", err)
+	}
+	return nil
+}
+
+func (m *RuleMatcher) event(log types.Log, name string, args map[string]any) *source.Event {
 	idx := uint(log.Index)
-	return &NormalizedEvent{
+	return &source.Event{
 		RuleID:   m.rule.ID,
 		Contract: log.Address.Hex(),
-		Name:     eventName(m.rule.Match.Event),
+		Name:     name,
 		TxHash:   log.TxHash.Hex(),
 		LogIndex: &idx,
 		Args:     args,
-	}, true, nil
+	}
+}
+
+// MatchAll is Match with ERC-1155 batch transfers expanded into one event per
+// token id.
+func (m *RuleMatcher) MatchAll(log types.Log) ([]source.Event, error) {
+	ev, ok, err := m.Match(log)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if m.kind == MatchERC1155Transfer && ev.Name == "TransferBatch" {
+		return expandBatch(*ev)
+	}
+	return []source.Event{*ev}, nil
 }
 
 func eventName(signature string) string {
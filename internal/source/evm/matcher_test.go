@@ -2,6 +2,7 @@ package evm
 
 import (
 	"math/big"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -69,3 +70,327 @@ func TestRuleMatcher_DecodesTransfer(t *testing.T) {
 		t.Fatalf("unexpected value %s", got)
 	}
 }
+
+func TestRuleMatcherResolvesAmbiguousEvents(t *testing.T) {
+	parse := func(indexed bool) *abi.ABI {
+		t.Helper()
+		a, err := abi.JSON(strings.NewReader(`[{"type":"event","name":"Transfer","inputs":[
+			{"name":"from","type":"address","indexed":true},
+			{"name":"to","type":"address","indexed":true},
+			{"name":"value","type":"uint256","indexed":` + strconv.FormatBool(indexed) + `}]}]`))
+		if err != nil {
+			t.Fatalf("parse abi: %v", err)
+		}
+		return &a
+	}
+	abis := map[string]*abi.ABI{"abis/ERC20.json": parse(false), "abis/nft/ERC721.json": parse(true)}
+	rule := config.Rule{ID: "r", Match: config.MatchSpec{
+		Type:     "log",
+		Contract: "0x0000000000000000000000000000000000000009",
+		Event:    "Transfer(address,address,uint256)",
+	}}
+
+	if _, err := NewRuleMatcher(rule, abis); err == nil || !strings.Contains(err.Error(), "match.abi") {
+		t.Fatalf("expected ambiguity error pointing at match.abi, got %v", err)
+	}
+
+	rule.Match.ABI = "ERC721.json"
+	m, err := NewRuleMatcher(rule, abis)
+	if err != nil {
+		t.Fatalf("bound matcher: %v", err)
+	}
+	from, to := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	log := types.Log{
+		Address: common.HexToAddress(rule.Match.Contract),
+		Topics:  []common.Hash{crypto.Keccak256Hash([]byte(rule.Match.Event)), addrTopic(from), addrTopic(to), common.BigToHash(big.NewInt(7))},
+	}
+	ev, ok, err := m.Match(log)
+	if err != nil || !ok {
+		t.Fatalf("match: %v %v", ok, err)
+	}
+	if v, _ := ev.Args["value"].(*big.Int); v == nil || v.Int64() != 7 {
+		t.Fatalf("expected indexed value 7, got %v", ev.Args["value"])
+	}
+
+	rule.Match.ABI = "missing.json"
+	if _, err := NewRuleMatcher(rule, abis); err == nil {
+		t.Fatalf("expected error for unknown abi")
+	}
+}
+
+func TestMatcherIndexLookup(t *testing.T) {
+	mk := func(id, contract, event string) *RuleMatcher {
+		m, err := NewRuleMatcher(config.Rule{ID: id, Match: config.MatchSpec{Type: "log", Contract: contract, Event: event}}, nil)
+		if err != nil {
+			t.Fatalf("new matcher: %v", err)
+		}
+		return m
+	}
+	usdc := "0xA0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	transfer := "Transfer(address,address,uint256)"
+	approval := "Approval(address,address,uint256)"
+	idx := newMatcherIndex([]*RuleMatcher{
+		mk("whale", usdc, transfer),
+		mk("approvals", usdc, approval),
+		mk("all_transfers", usdc, transfer),
+	})
+
+	log := types.Log{Address: common.HexToAddress(usdc), Topics: []common.Hash{crypto.Keccak256Hash([]byte(transfer))}}
+	got := idx.lookup(log)
+	if len(got) != 2 || got[0].rule.ID != "whale" || got[1].rule.ID != "all_transfers" {
+		t.Fatalf("unexpected matchers: %+v", got)
+	}
+	log.Address = common.HexToAddress("0x01")
+	if got := idx.lookup(log); len(got) != 0 {
+		t.Fatalf("expected no matchers for other address, got %d", len(got))
+	}
+	if got := idx.lookup(types.Log{Address: common.HexToAddress(usdc)}); len(got) != 0 {
+		t.Fatalf("expected no matchers for anonymous log")
+	}
+}
+
+func TestRuleMatcherContracts(t *testing.T) {
+	vaults := []string{"0x00000000000000000000000000000000000000a1", "0x00000000000000000000000000000000000000a2"}
+	deposit := "Deposit(address,uint256)"
+	rule := config.Rule{ID: "vault_deposits", Match: config.MatchSpec{Type: "log", Contract: vaults[0], Contracts: vaults, Event: deposit}}
+	m, err := NewRuleMatcher(rule, nil)
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	idx := newMatcherIndex([]*RuleMatcher{m})
+	if len(idx) != 2 {
+		t.Fatalf("expected one index entry per contract, got %d", len(idx))
+	}
+	topics := []common.Hash{crypto.Keccak256Hash([]byte(deposit))}
+	for _, v := range vaults {
+		lg := types.Log{Address: common.HexToAddress(v), Topics: topics, Data: make([]byte, 64)}
+		if got := idx.lookup(lg); len(got) != 1 {
+			t.Fatalf("expected %s to be matched", v)
+		}
+		if ev, ok, err := m.Match(lg); err != nil || !ok || ev.Contract != common.HexToAddress(v).Hex() {
+			t.Fatalf("unexpected match for %s: %+v ok=%v err=%v", v, ev, ok, err)
+		}
+	}
+	if _, ok, _ := m.Match(types.Log{Address: common.HexToAddress("0x01"), Topics: topics}); ok {
+		t.Fatalf("expected other contracts to be ignored")
+	}
+}
+
+func TestRuleMatcherTopicFilters(t *testing.T) {
+	usdc := "0xA0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	treasury := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	rule := config.Rule{ID: "to_treasury", Match: config.MatchSpec{
+		Type: "log", Contract: usdc, Standard: config.StandardERC20, Event: "Transfer",
+		Topics: map[string]config.TopicValues{"to": {treasury.Hex()}},
+	}}
+	m, err := NewRuleMatcher(rule, nil)
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	transfer := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	lg := types.Log{Address: common.HexToAddress(usdc), Topics: []common.Hash{transfer, addrTopic(other), addrTopic(treasury)}, Data: make([]byte, 32)}
+	if _, ok, err := m.Match(lg); !ok || err != nil {
+		t.Fatalf("expected a transfer to the treasury to match, got ok=%v err=%v", ok, err)
+	}
+	lg.Topics[2] = addrTopic(other)
+	if _, ok, _ := m.Match(lg); ok {
+		t.Fatalf("expected other recipients to be filtered out")
+	}
+
+	// The filter is pushed to the node while every rule on the source has one.
+	got := queryTopics([]*RuleMatcher{m})
+	if len(got) != 3 || got[0] != nil || got[1] != nil || len(got[2]) != 1 || got[2][0] != addrTopic(treasury) {
+		t.Fatalf("unexpected query topics: %v", got)
+	}
+	unfiltered, err := NewRuleMatcher(config.Rule{ID: "all", Match: config.MatchSpec{Type: "log", Contract: usdc, Standard: config.StandardERC20}}, nil)
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	if got := queryTopics([]*RuleMatcher{m, unfiltered}); got != nil {
+		t.Fatalf("expected no query topics with an unfiltered rule, got %v", got)
+	}
+
+	for _, topics := range []map[string]config.TopicValues{
+		{"value": {"1"}},     // not indexed
+		{"to": {"treasury"}}, // not an address
+	} {
+		rule.Match.Topics = topics
+		if _, err := NewRuleMatcher(rule, nil); err == nil {
+			t.Fatalf("expected %v to be rejected", topics)
+		}
+	}
+}
+
+func TestRuleMatcherAnonymousEvents(t *testing.T) {
+	a, err := abi.JSON(strings.NewReader(`[
+		{"type":"event","name":"Deposit","anonymous":true,"inputs":[
+			{"name":"account","type":"address","indexed":true},
+			{"name":"amount","type":"uint256","indexed":false}]},
+		{"type":"event","name":"Paused","inputs":[{"name":"account","type":"address","indexed":false}]}]`))
+	if err != nil {
+		t.Fatalf("parse abi: %v", err)
+	}
+	abis := map[string]*abi.ABI{"abis/Vault.json": &a}
+	vault := "0x00000000000000000000000000000000000000aa"
+	alice := common.HexToAddress("0x01")
+	deposits, err := NewRuleMatcher(config.Rule{ID: "deposits", Match: config.MatchSpec{Type: "log", Contract: vault, Event: "Deposit(address,uint256)"}}, abis)
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	paused := a.Events["Paused"].ID
+	pauses, err := NewRuleMatcher(config.Rule{ID: "pauses", Match: config.MatchSpec{Type: "log", Contract: vault, Topic0: paused.Hex()}}, abis)
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	unknown := common.HexToHash("0x01")
+	raw, err := NewRuleMatcher(config.Rule{ID: "raw", Match: config.MatchSpec{Type: "log", Contract: vault, Topic0: unknown.Hex()}}, nil)
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+
+	// The first topic of an anonymous event is its first indexed argument.
+	deposit := types.Log{Address: common.HexToAddress(vault), Topics: []common.Hash{addrTopic(alice)}, Data: common.LeftPadBytes([]byte{5}, 32)}
+	ev, ok, err := deposits.Match(deposit)
+	if err != nil || !ok || ev.Name != "Deposit" || ev.Args["account"] != alice {
+		t.Fatalf("unexpected deposit match: %+v ok=%v err=%v", ev, ok, err)
+	}
+	if v, _ := ev.Args["amount"].(*big.Int); v == nil || v.Int64() != 5 {
+		t.Fatalf("expected amount 5, got %v", ev.Args["amount"])
+	}
+	pause := types.Log{Address: common.HexToAddress(vault), Topics: []common.Hash{paused}, Data: common.LeftPadBytes(alice.Bytes(), 32)}
+	if ev, ok, err := pauses.Match(pause); err != nil || !ok || ev.Name != "Paused" || ev.Args["account"] != alice {
+		t.Fatalf("unexpected topic0 match: %+v ok=%v err=%v", ev, ok, err)
+	}
+	if ev, ok, _ := raw.Match(types.Log{Address: common.HexToAddress(vault), Topics: []common.Hash{unknown}}); !ok || ev.Name != unknown.Hex() || len(ev.Args) != 0 {
+		t.Fatalf("expected an undecoded topic0 match, got %+v ok=%v", ev, ok)
+	}
+	if _, ok, _ := deposits.Match(types.Log{Address: common.HexToAddress(vault)}); ok {
+		t.Fatalf("expected a log without the indexed argument not to match")
+	}
+
+	// Logs with a topic0 of another rule still reach the anonymous matcher.
+	idx := newMatcherIndex([]*RuleMatcher{deposits, pauses})
+	if got := idx.lookup(pause); len(got) != 2 || got[0] != deposits || got[1] != pauses {
+		t.Fatalf("unexpected matchers: %+v", got)
+	}
+	if got := idx.lookup(deposit); len(got) != 1 || got[0] != deposits {
+		t.Fatalf("unexpected matchers: %+v", got)
+	}
+	if got := queryTopics([]*RuleMatcher{deposits}); got != nil {
+		t.Fatalf("expected no query topics with anonymous events, got %v", got)
+	}
+}
+
+func TestNFTTransferMatchers(t *testing.T) {
+	contract := "0x00000000000000000000000000000000000000aa"
+	from := common.HexToAddress("0x01")
+	to := common.HexToAddress("0x02")
+	operator := common.HexToAddress("0x03")
+
+	erc721, err := NewRuleMatcher(config.Rule{ID: "nft", Match: config.MatchSpec{Type: "erc721_transfer", Contract: contract}}, nil)
+	if err != nil {
+		t.Fatalf("erc721 matcher: %v", err)
+	}
+	transfer := standardABIs[config.StandardERC721].Events["Transfer"].ID
+	log := types.Log{
+		Address: common.HexToAddress(contract),
+		Topics:  []common.Hash{transfer, addrTopic(from), addrTopic(to), common.BigToHash(big.NewInt(42))},
+	}
+	evs, err := erc721.MatchAll(log)
+	if err != nil {
+		t.Fatalf("match erc721: %v", err)
+	}
+	if len(evs) != 1 || evs[0].Name != "Transfer" || evs[0].Args["token_id"].(*big.Int).Int64() != 42 {
+		t.Fatalf("unexpected erc721 events: %+v", evs)
+	}
+	// An ERC-20 Transfer carries the amount in data, not a third topic.
+	log.Topics = log.Topics[:3]
+	log.Data = common.LeftPadBytes(big.NewInt(42).Bytes(), 32)
+	if evs, err := erc721.MatchAll(log); err != nil || len(evs) != 0 {
+		t.Fatalf("expected erc20 transfer to be ignored, got %+v, %v", evs, err)
+	}
+
+	erc1155, err := NewRuleMatcher(config.Rule{ID: "multi", Match: config.MatchSpec{Type: "erc1155_transfer", Contract: contract}}, nil)
+	if err != nil {
+		t.Fatalf("erc1155 matcher: %v", err)
+	}
+	batch := standardABIs[config.StandardERC1155].Events["TransferBatch"]
+	data, err := batch.Inputs.NonIndexed().Pack(
+		[]*big.Int{big.NewInt(1), big.NewInt(2)},
+		[]*big.Int{big.NewInt(10), big.NewInt(20)},
+	)
+	if err != nil {
+		t.Fatalf("pack batch: %v", err)
+	}
+	evs, err = erc1155.MatchAll(types.Log{
+		Address: common.HexToAddress(contract),
+		Topics:  []common.Hash{batch.ID, addrTopic(operator), addrTopic(from), addrTopic(to)},
+		Data:    data,
+	})
+	if err != nil {
+		t.Fatalf("match batch: %v", err)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("expected batch expanded to 2 events, got %d", len(evs))
+	}
+	for i, ev := range evs {
+		if ev.Args["token_id"].(*big.Int).Int64() != int64(i+1) || ev.Args["value"].(*big.Int).Int64() != int64(10*(i+1)) || ev.Args["batch_index"] != i {
+			t.Fatalf("unexpected batch item %d: %+v", i, ev.Args)
+		}
+		if ev.Args["to"].(common.Address) != to {
+			t.Fatalf("unexpected recipient: %v", ev.Args["to"])
+		}
+	}
+	if len(newMatcherIndex([]*RuleMatcher{erc1155})) != 2 {
+		t.Fatalf("expected erc1155 matcher indexed under both topics")
+	}
+}
+
+func TestRuleMatcherStandardEvents(t *testing.T) {
+	token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	from := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	to := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	transfer := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	erc20Log := types.Log{Address: token, Topics: []common.Hash{transfer, addrTopic(from), addrTopic(to)}, Data: common.LeftPadBytes(big.NewInt(7).Bytes(), 32)}
+	erc721Log := types.Log{Address: token, Topics: []common.Hash{transfer, addrTopic(from), addrTopic(to), common.BigToHash(big.NewInt(9))}}
+
+	// Without an ABI, a standard signature decodes with the layout the log was
+	// emitted with rather than as all non-indexed arguments.
+	m, err := NewRuleMatcher(config.Rule{ID: "r", Match: config.MatchSpec{Type: "log", Contract: token.Hex(), Event: "Transfer(address,address,uint256)"}}, nil)
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	ev, ok, err := m.Match(erc20Log)
+	if err != nil || !ok || ev.Args["from"] != from || ev.Args["to"] != to || ev.Args["value"].(*big.Int).Int64() != 7 {
+		t.Fatalf("unexpected erc20 decode: %+v ok=%v err=%v", ev, ok, err)
+	}
+	ev, ok, err = m.Match(erc721Log)
+	if err != nil || !ok || ev.Args["token_id"].(*big.Int).Int64() != 9 {
+		t.Fatalf("unexpected erc721 decode: %+v ok=%v err=%v", ev, ok, err)
+	}
+
+	// match.standard binds one standard: all of its events by default, or
+	// the ones named.
+	m, err = NewRuleMatcher(config.Rule{ID: "r", Match: config.MatchSpec{Type: "log", Contract: token.Hex(), Standard: config.StandardERC20}}, nil)
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	if len(m.events) != 2 {
+		t.Fatalf("expected Transfer and Approval, got %d events", len(m.events))
+	}
+	if _, ok, _ := m.Match(erc721Log); ok {
+		t.Fatalf("an erc721 transfer must not match an erc20 rule")
+	}
+	approval := types.Log{Address: token, Topics: []common.Hash{crypto.Keccak256Hash([]byte("Approval(address,address,uint256)")), addrTopic(from), addrTopic(to)}, Data: common.LeftPadBytes(big.NewInt(3).Bytes(), 32)}
+	ev, ok, err = m.Match(approval)
+	if err != nil || !ok || ev.Name != "Approval" || ev.Args["spender"] != to {
+		t.Fatalf("unexpected approval decode: %+v ok=%v err=%v", ev, ok, err)
+	}
+	if _, err := NewRuleMatcher(config.Rule{ID: "r", Match: config.MatchSpec{Type: "log", Contract: token.Hex(), Standard: config.StandardERC721, Event: "ApprovalForAll"}}, nil); err != nil {
+		t.Fatalf("expected a named event to resolve: %v", err)
+	}
+	if _, err := NewRuleMatcher(config.Rule{ID: "r", Match: config.MatchSpec{Type: "log", Contract: token.Hex(), Standard: config.StandardERC20, Event: "TransferSingle"}}, nil); err == nil {
+		t.Fatalf("expected an event outside the standard to fail")
+	}
+}
@@ -0,0 +1,302 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// PendingEvent is the event name of pending_tx matches.
+const PendingEvent = "pending_tx"
+
+// DefaultMempoolPoll is how often txpool_content is read from nodes that
+// cannot push pending transactions.
+const DefaultMempoolPoll = 2 * time.Second
+
+// pendingSeenTTL is how long a matched pending transaction is remembered,
+// so one that lingers in the mempool is reported once.
+const pendingSeenTTL = 30 * time.Minute
+
+var errNoMempool = errors.New("client does not read the mempool")
+
+// MempoolClient is implemented by clients that see transactions before they
+// are mined.
+type MempoolClient interface {
+	// SubscribePendingTransactions pushes each new pending transaction to ch
+	// (eth_subscribe newPendingTransactions with full bodies). It needs a
+	// websocket endpoint.
+	SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (ethereum.Subscription, error)
+	// PendingTransactions lists the node's pending pool (txpool_content).
+	PendingTransactions(ctx context.Context) ([]*types.Transaction, error)
+}
+
+// SubscribePendingTransactions implements MempoolClient.
+func (c *RPCClient) SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (ethereum.Subscription, error) {
+	return c.Client.Client().EthSubscribe(ctx, ch, "newPendingTransactions", true)
+}
+
+// PendingTransactions implements MempoolClient.
+func (c *RPCClient) PendingTransactions(ctx context.Context) ([]*types.Transaction, error) {
+	var content struct {
+		Pending map[string]map[string]*types.Transaction `json:"pending"`
+	}
+	if err := c.Client.Client().CallContext(ctx, &content, "txpool_content"); err != nil {
+		return nil, fmt.Errorf("txpool_content: %w", err)
+	}
+	var out []*types.Transaction
+	for _, byNonce := range content.Pending {
+		for _, tx := range byNonce {
+			out = append(out, tx)
+		}
+	}
+	return out, nil
+}
+
+// SubscribePendingTransactions forwards mempool subscriptions when the inner
+// client supports them. A subscription is one request, so it is not paced.
+func (c *LimitedClient) SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (ethereum.Subscription, error) {
+	mc, ok := c.inner.(MempoolClient)
+	if !ok {
+		return nil, errNoMempool
+	}
+	return mc.SubscribePendingTransactions(ctx, ch)
+}
+
+// PendingTransactions forwards txpool reads when the inner client supports them.
+func (c *LimitedClient) PendingTransactions(ctx context.Context) ([]*types.Transaction, error) {
+	mc, ok := c.inner.(MempoolClient)
+	if !ok {
+		return nil, errNoMempool
+	}
+	var txs []*types.Transaction
+	err := c.limiter.Do(ctx, func() error {
+		var err error
+		txs, err = mc.PendingTransactions(ctx)
+		return err
+	})
+	return txs, err
+}
+
+// pendingRule is a compiled pending_tx rule: a transaction matches when it
+// is sent to the contract or one of the addresses, or from one of the
+// addresses.
+type pendingRule struct {
+	rule  config.Rule
+	to    map[common.Address]struct{}
+	addrs map[common.Address]struct{}
+}
+
+func newPendingRule(r config.Rule) *pendingRule {
+	p := &pendingRule{rule: r, to: map[common.Address]struct{}{}, addrs: map[common.Address]struct{}{}}
+	if r.Match.Contract != "" {
+		p.to[common.HexToAddress(r.Match.Contract)] = struct{}{}
+	}
+	for _, a := range r.Match.Addresses {
+		p.addrs[common.HexToAddress(a)] = struct{}{}
+	}
+	return p
+}
+
+func (p *pendingRule) matches(from common.Address, to *common.Address) bool {
+	if _, ok := p.addrs[from]; ok {
+		return true
+	}
+	if to == nil {
+		return false
+	}
+	if _, ok := p.to[*to]; ok {
+		return true
+	}
+	_, ok := p.addrs[*to]
+	return ok
+}
+
+// MempoolWatcher matches a source's pending_tx rules against transactions
+// in its node's mempool. Matches carry no height: they are not on chain yet.
+type MempoolWatcher struct {
+	client   MempoolClient
+	sourceID string
+	poll     time.Duration
+	nowFunc  func() time.Time
+
+	mu    sync.Mutex
+	rules []*pendingRule
+	seen  map[common.Hash]time.Time
+}
+
+// NewMempoolWatcher builds a watcher for the pending_tx rules of sourceID.
+func NewMempoolWatcher(client MempoolClient, sourceID string, rules []config.Rule) *MempoolWatcher {
+	w := &MempoolWatcher{
+		client:   client,
+		sourceID: sourceID,
+		poll:     DefaultMempoolPoll,
+		nowFunc:  time.Now,
+		seen:     map[common.Hash]time.Time{},
+	}
+	commit, _ := w.PrepareRules(rules)
+	commit()
+	return w
+}
+
+// HasPendingRules reports whether any of rules is a pending_tx rule for sourceID.
+func HasPendingRules(sourceID string, rules []config.Rule) bool {
+	for _, r := range rules {
+		if r.Match.Type == config.MatchPendingTx && r.AppliesTo(sourceID) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrepareRules compiles the source's pending_tx rules; the returned commit
+// func swaps them in.
+func (w *MempoolWatcher) PrepareRules(rules []config.Rule) (commit func(), err error) {
+	var next []*pendingRule
+	for _, r := range rules {
+		if r.Match.Type == config.MatchPendingTx && r.AppliesTo(w.sourceID) {
+			next = append(next, newPendingRule(r))
+		}
+	}
+	return func() {
+		w.mu.Lock()
+		w.rules = next
+		w.mu.Unlock()
+	}, nil
+}
+
+// Run hands each matching pending transaction to emit until ctx is
+// cancelled. It subscribes to the node's mempool and polls txpool_content
+// when the node cannot push, such as over HTTP. It returns when the
+// subscription fails or emit does.
+func (w *MempoolWatcher) Run(ctx context.Context, emit func(source.Event) error) error {
+	ch := make(chan *types.Transaction, 256)
+	sub, err := w.client.SubscribePendingTransactions(ctx, ch)
+	if err != nil {
+		return w.runPolling(ctx, emit)
+	}
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-sub.Err():
+			return fmt.Errorf("mempool subscription: %w", err)
+		case tx := <-ch:
+			for _, ev := range w.match([]*types.Transaction{tx}) {
+				if err := emit(ev); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (w *MempoolWatcher) runPolling(ctx context.Context, emit func(source.Event) error) error {
+	ticker := time.NewTicker(w.poll)
+	defer ticker.Stop()
+	for {
+		if err := w.Poll(ctx, emit); err != nil {
+			if isMethodNotFound(err) {
+				return fmt.Errorf("node offers neither pending transaction subscriptions nor txpool_content: %w", err)
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll reads the node's pending pool once and emits new matches.
+func (w *MempoolWatcher) Poll(ctx context.Context, emit func(source.Event) error) error {
+	txs, err := w.client.PendingTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	// Sorted so matches come out in a stable order.
+	sort.Slice(txs, func(i, j int) bool { return txs[i].Hash().Hex() < txs[j].Hash().Hex() })
+	for _, ev := range w.match(txs) {
+		if err := emit(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// match returns an event per rule for each transaction not matched before.
+func (w *MempoolWatcher) match(txs []*types.Transaction) []source.Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.nowFunc()
+	for h, at := range w.seen {
+		if now.Sub(at) > pendingSeenTTL {
+			delete(w.seen, h)
+		}
+	}
+	var out []source.Event
+	for _, tx := range txs {
+		if tx == nil {
+			continue
+		}
+		if _, ok := w.seen[tx.Hash()]; ok {
+			continue
+		}
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			continue
+		}
+		matched := false
+		for _, r := range w.rules {
+			if r.matches(from, tx.To()) {
+				out = append(out, w.event(r.rule.ID, tx, from, now))
+				matched = true
+			}
+		}
+		if matched {
+			w.seen[tx.Hash()] = now
+		}
+	}
+	return out
+}
+
+func (w *MempoolWatcher) event(ruleID string, tx *types.Transaction, from common.Address, now time.Time) source.Event {
+	args := map[string]any{
+		"from":      from.Hex(),
+		"value":     tx.Value(),
+		"nonce":     tx.Nonce(),
+		"gas":       tx.Gas(),
+		"gas_price": tx.GasFeeCap(),
+		"tip":       tx.GasTipCap(),
+		"input":     hexutil.Encode(tx.Data()),
+	}
+	if len(tx.Data()) >= 4 {
+		args["selector"] = hexutil.Encode(tx.Data()[:4])
+	}
+	var contract string
+	if to := tx.To(); to != nil {
+		contract = to.Hex()
+		args["to"] = contract
+	}
+	return source.Event{
+		Chain:     Chain,
+		SourceID:  w.sourceID,
+		RuleID:    ruleID,
+		TxHash:    tx.Hash().Hex(),
+		Timestamp: now.UTC(),
+		Contract:  contract,
+		Name:      PendingEvent,
+		Args:      args,
+	}
+}
@@ -0,0 +1,134 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+type fakeMempool struct {
+	pending   []*types.Transaction
+	subscribe bool
+	ch        chan<- *types.Transaction
+	ready     chan struct{} // closed once subscribed
+}
+
+func (f *fakeMempool) SubscribePendingTransactions(_ context.Context, ch chan<- *types.Transaction) (ethereum.Subscription, error) {
+	if !f.subscribe {
+		return nil, errNoMempool
+	}
+	f.ch = ch
+	close(f.ready)
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	}), nil
+}
+
+func (f *fakeMempool) PendingTransactions(context.Context) ([]*types.Transaction, error) {
+	return f.pending, nil
+}
+
+func TestMempoolWatcherPoll(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	router := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	tx := func(nonce uint64, to common.Address, data []byte) *types.Transaction {
+		signed, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID: big.NewInt(1), Nonce: nonce, To: &to, Value: big.NewInt(5), Gas: 21000,
+			GasFeeCap: big.NewInt(2), GasTipCap: big.NewInt(1), Data: data,
+		})
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return signed
+	}
+	toRouter := tx(0, router, []byte{0xa9, 0x05, 0x9c, 0xbb, 0x01})
+	other := tx(1, common.HexToAddress("0x00000000000000000000000000000000000000bb"), nil)
+
+	rules := []config.Rule{
+		{ID: "router", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchPendingTx, Contract: router.Hex()}},
+		{ID: "treasury", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchPendingTx, Addresses: []string{sender.Hex()}}},
+		{ID: "elsewhere", Source: "evm_other", Match: config.MatchSpec{Type: config.MatchPendingTx, Contract: router.Hex()}},
+	}
+	if !HasPendingRules("evm_main", rules) || HasPendingRules("evm_none", rules) {
+		t.Fatalf("unexpected HasPendingRules result")
+	}
+	client := &fakeMempool{pending: []*types.Transaction{toRouter, other}}
+	w := NewMempoolWatcher(client, "evm_main", rules)
+
+	var got []source.Event
+	collect := func(ev source.Event) error {
+		got = append(got, ev)
+		return nil
+	}
+	if err := w.Poll(context.Background(), collect); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	byRule := map[string][]source.Event{}
+	for _, ev := range got {
+		byRule[ev.RuleID] = append(byRule[ev.RuleID], ev)
+	}
+	if len(byRule["router"]) != 1 || len(byRule["treasury"]) != 2 || len(got) != 3 {
+		t.Fatalf("unexpected matches: %+v", got)
+	}
+	ev := byRule["router"][0]
+	if ev.TxHash != toRouter.Hash().Hex() || ev.Height != 0 || ev.Name != PendingEvent || ev.Contract != router.Hex() ||
+		ev.Args["from"] != sender.Hex() || ev.Args["selector"] != "0xa9059cbb" || ev.Args["value"].(*big.Int).Int64() != 5 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+
+	// Transactions still pending on the next poll are not reported again.
+	got = nil
+	if err := w.Poll(context.Background(), collect); err != nil || len(got) != 0 {
+		t.Fatalf("expected no repeat matches, got %d (%v)", len(got), err)
+	}
+}
+
+func TestMempoolWatcherSubscription(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	router := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	signed, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.LegacyTx{To: &router, Gas: 21000, GasPrice: big.NewInt(1)})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	client := &fakeMempool{subscribe: true, ready: make(chan struct{})}
+	w := NewMempoolWatcher(client, "evm_main", []config.Rule{
+		{ID: "router", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchPendingTx, Contract: router.Hex()}},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events := make(chan source.Event, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(ev source.Event) error {
+			events <- ev
+			return nil
+		})
+	}()
+	<-client.ready
+	client.ch <- signed
+	select {
+	case ev := <-events:
+		if ev.RuleID != "router" || ev.TxHash != signed.Hash().Hex() {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	case <-ctx.Done():
+		t.Fatalf("no event from subscription")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+}
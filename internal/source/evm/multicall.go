@@ -0,0 +1,73 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Multicall3Address is where Multicall3 is deployed on most EVM chains.
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+const multicall3JSON = `[{"type":"function","name":"aggregate3","stateMutability":"payable",
+	"inputs":[{"name":"calls","type":"tuple[]","components":[
+		{"name":"target","type":"address"},
+		{"name":"allowFailure","type":"bool"},
+		{"name":"callData","type":"bytes"}]}],
+	"outputs":[{"name":"returnData","type":"tuple[]","components":[
+		{"name":"success","type":"bool"},
+		{"name":"returnData","type":"bytes"}]}]}]`
+
+var multicall3ABI = func() abi.ABI {
+	a, err := abi.JSON(strings.NewReader(multicall3JSON))
+	if err != nil {
+		panic(err)
+	}
+	return a
+}()
+
+// errNoMulticall means the multicall address holds no contract, so calls
+// must be made one at a time.
+var errNoMulticall = errors.New("multicall contract not deployed")
+
+type call3 struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type result3 struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// multicall runs calls in one eth_call through Multicall3's aggregate3. Each
+// call may fail on its own; check the result's Success.
+func multicall(ctx context.Context, c ethereum.ContractCaller, at common.Address, calls []call3, block *big.Int) ([]result3, error) {
+	data, err := multicall3ABI.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, fmt.Errorf("pack multicall: %w", err)
+	}
+	out, err := c.CallContract(ctx, ethereum.CallMsg{To: &at, Data: data}, block)
+	if err != nil {
+		return nil, fmt.Errorf("multicall: %w", err)
+	}
+	// A call to an address without code succeeds with no output.
+	if len(out) == 0 {
+		return nil, errNoMulticall
+	}
+	var results []result3
+	if err := multicall3ABI.UnpackIntoInterface(&results, "aggregate3", out); err != nil {
+		return nil, fmt.Errorf("unpack multicall: %w", err)
+	}
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("multicall returned %d results for %d calls", len(results), len(calls))
+	}
+	return results, nil
+}
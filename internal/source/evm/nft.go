@@ -0,0 +1,53 @@
+package evm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Match types with built-in event definitions for NFT transfers.
+const (
+	MatchERC721Transfer  = "erc721_transfer"
+	MatchERC1155Transfer = "erc1155_transfer"
+)
+
+// presetEvents returns the events a built-in match type listens for.
+func presetEvents(matchType string) []abi.Event {
+	switch matchType {
+	case MatchERC721Transfer:
+		return []abi.Event{standardABIs[config.StandardERC721].Events["Transfer"]}
+	case MatchERC1155Transfer:
+		erc1155 := standardABIs[config.StandardERC1155]
+		return []abi.Event{erc1155.Events["TransferSingle"], erc1155.Events["TransferBatch"]}
+	}
+	return nil
+}
+
+// expandBatch splits a decoded TransferBatch into one event per token, each
+// shaped like a TransferSingle plus a batch_index.
+func expandBatch(ev source.Event) ([]source.Event, error) {
+	ids, _ := ev.Args["token_ids"].([]*big.Int)
+	values, _ := ev.Args["values"].([]*big.Int)
+	if len(ids) != len(values) {
+		return nil, fmt.Errorf("transfer batch: %d token ids but %d values", len(ids), len(values))
+	}
+	out := make([]source.Event, 0, len(ids))
+	for i := range ids {
+		args := map[string]any{
+			"operator":    ev.Args["operator"],
+			"from":        ev.Args["from"],
+			"to":          ev.Args["to"],
+			"token_id":    ids[i],
+			"value":       values[i],
+			"batch_index": i,
+		}
+		item := ev
+		item.Args = args
+		out = append(out, item)
+	}
+	return out, nil
+}
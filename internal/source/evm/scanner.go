@@ -2,18 +2,24 @@ package evm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/source/blocktime"
 	"github.com/devblac/watch-tower/internal/storage"
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // BlockClient captures the subset of ethclient used by the scanner.
@@ -25,15 +31,18 @@ type BlockClient interface {
 // RPCClient is a thin wrapper over ethclient.Client that satisfies BlockClient.
 type RPCClient struct {
 	*ethclient.Client
+	parityTraces atomic.Bool // the node has trace_block but not debug_traceBlockByNumber
 }
 
-// NewRPCClient builds an RPC client to an EVM node.
-func NewRPCClient(rpcURL string) (*RPCClient, error) {
-	c, err := ethclient.Dial(rpcURL)
+// NewRPCClient builds an RPC client to an EVM node. headers are sent with
+// every HTTP request and the websocket handshake; auth, when set, adds basic
+// authentication. Both may be nil.
+func NewRPCClient(rpcURL string, headers map[string]string, auth *config.BasicAuth) (*RPCClient, error) {
+	c, err := rpc.DialOptions(context.Background(), rpcURL, rpc.WithHeaders(config.HTTPHeaders(headers, auth)))
 	if err != nil {
 		return nil, fmt.Errorf("dial evm rpc: %w", err)
 	}
-	return &RPCClient{Client: c}, nil
+	return &RPCClient{Client: ethclient.NewClient(c)}, nil
 }
 
 // Scanner processes blocks sequentially with confirmation safety.
@@ -42,16 +51,184 @@ type Scanner struct {
 	store         *storage.Store
 	source        config.Source
 	confirmations uint64
-	matchers      []*RuleMatcher
+	abis          map[string]*abi.ABI
+	matchers      matcherIndex
 	addresses     []common.Address
+	topics        [][]common.Hash // FilterQuery topics from match.topics
+	watchers      []*addressWatcher
+	states        []*stateWatcher
+	transfers     []*transferWatcher
+	functions     []*functionMatcher
+	creations     []*creationWatcher
+	blobs         []*blobWatcher
+	failures      []*failedTxWatcher
+	views         []*viewWatcher
+	traces        []*traceMatcher
+	baseFees      []*baseFeeWatcher
+	lags          []*lagWatcher
+	l1            BlockClient // client of the l2 source's l1_source
+	multicall     common.Address
+	multicallOff  bool // set once the multicall address turns out to hold no contract
+	tokens        *TokenResolver
+	ens           *ENSResolver
+	reorgDepth    uint64
+	tipTTL        time.Duration
+	nowFunc       func() time.Time
+	// maxBatch is how many blocks one call may scan (source
+	// max_blocks_per_tick); stopAt, when set, is the last block to scan.
+	maxBatch uint64
+	stopAt   uint64
+	// head is the block tag scanning stops at: nil for the latest block,
+	// or the finalized or safe tag.
+	head *big.Int
+	// tip is the height of head last seen, read by the dashboard.
+	tip   atomic.Uint64
+	tipAt time.Time
 }
 
 // NewScanner builds a scanner for a given source and its log rules.
-func NewScanner(client BlockClient, store *storage.Store, source config.Source, confirmations uint64, abis map[string]*abi.ABI, rules []config.Rule) (*Scanner, error) {
+func NewScanner(client BlockClient, store *storage.Store, src config.Source, confirmations uint64, abis map[string]*abi.ABI, rules []config.Rule) (*Scanner, error) {
+	s := &Scanner{
+		client:        client,
+		store:         store,
+		source:        src,
+		confirmations: confirmations,
+		tipTTL:        src.TipCacheTTL(),
+		reorgDepth:    src.ReorgDepth(),
+		nowFunc:       time.Now,
+		abis:          abis,
+		multicall:     Multicall3Address,
+		maxBatch:      1,
+	}
+	if src.MaxBlocksPerTick > 1 {
+		s.maxBatch = uint64(src.MaxBlocksPerTick)
+	}
+	if src.MulticallAddress != "" {
+		s.multicall = common.HexToAddress(src.MulticallAddress)
+	}
+	commit, err := s.PrepareRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	commit()
+	return s, nil
+}
+
+// PrepareRules builds matchers for the source's log rules without touching the
+// running scanner. Calling the returned commit func swaps them in.
+func (s *Scanner) PrepareRules(rules []config.Rule) (commit func(), err error) {
+	return s.prepare(rules, s.abis)
+}
+
+// PrepareABIs is PrepareRules with a reloaded set of ABIs, which commit
+// installs along with the rebuilt matchers.
+func (s *Scanner) PrepareABIs(abis map[string]*abi.ABI, rules []config.Rule) (commit func(), err error) {
+	commitRules, err := s.prepare(rules, abis)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		commitRules()
+		s.abis = abis
+	}, nil
+}
+
+func (s *Scanner) prepare(rules []config.Rule, abis map[string]*abi.ABI) (commit func(), err error) {
 	matchers := []*RuleMatcher{}
+	watchers := []*addressWatcher{}
+	states := []*stateWatcher{}
+	transfers := []*transferWatcher{}
+	functions := []*functionMatcher{}
+	creations := []*creationWatcher{}
+	blobs := []*blobWatcher{}
+	failures := []*failedTxWatcher{}
+	views := []*viewWatcher{}
+	traces := []*traceMatcher{}
+	baseFees := []*baseFeeWatcher{}
+	lags := []*lagWatcher{}
 	addrSet := map[common.Address]struct{}{}
 	for _, r := range rules {
-		if r.Source != source.ID || strings.ToLower(r.Match.Type) != "log" {
+		if !r.AppliesTo(s.source.ID) {
+			continue
+		}
+		if r.Match.Type == config.MatchWatchAddress {
+			if w := newAddressWatcher(r); len(w.addrs) > 0 {
+				watchers = append(watchers, w)
+			}
+			continue
+		}
+		if r.Match.Type == config.MatchTransfer {
+			w, err := newTransferWatcher(r)
+			if err != nil {
+				return nil, err
+			}
+			transfers = append(transfers, w)
+			continue
+		}
+		if r.Match.Type == config.MatchFunctionCall {
+			m, err := newFunctionMatcher(r, abis)
+			if err != nil {
+				return nil, err
+			}
+			functions = append(functions, m)
+			continue
+		}
+		if r.Match.Type == config.MatchInternalCall {
+			m, err := newTraceMatcher(r, abis)
+			if err != nil {
+				return nil, err
+			}
+			traces = append(traces, m)
+			continue
+		}
+		if r.Match.Type == config.MatchView {
+			w, err := newViewWatcher(r, abis)
+			if err != nil {
+				return nil, err
+			}
+			views = append(views, w)
+			continue
+		}
+		if r.Match.Type == config.MatchContractCreation {
+			creations = append(creations, newCreationWatcher(r))
+			continue
+		}
+		if r.Match.Type == config.MatchFailedTx {
+			failures = append(failures, newFailedTxWatcher(r, abis))
+			continue
+		}
+		if r.Match.Type == config.MatchBlobTx {
+			w, err := newBlobWatcher(r)
+			if err != nil {
+				return nil, err
+			}
+			blobs = append(blobs, w)
+			continue
+		}
+		if r.Match.Type == config.MatchBaseFee {
+			baseFees = append(baseFees, newBaseFeeWatcher(r))
+			continue
+		}
+		if r.Match.Type == config.MatchSequencerLag {
+			if s.source.L2 == nil {
+				return nil, fmt.Errorf("rule %s: source %s has no l2 settings", r.ID, s.source.ID)
+			}
+			w, err := newLagWatcher(r, s.source.L2)
+			if err != nil {
+				return nil, err
+			}
+			lags = append(lags, w)
+			continue
+		}
+		if isStateMatchType(r.Match.Type) {
+			w, err := newStateWatcher(r)
+			if err != nil {
+				return nil, err
+			}
+			states = append(states, w)
+			continue
+		}
+		if !IsMatchType(r.Match.Type) {
 			continue
 		}
 		m, err := NewRuleMatcher(r, abis)
@@ -59,105 +236,667 @@ func NewScanner(client BlockClient, store *storage.Store, source config.Source,
 			return nil, err
 		}
 		matchers = append(matchers, m)
-		addrSet[m.address] = struct{}{}
+		for a := range m.contracts {
+			addrSet[a] = struct{}{}
+		}
 	}
 
 	addresses := make([]common.Address, 0, len(addrSet))
 	for a := range addrSet {
 		addresses = append(addresses, a)
 	}
+	// Watched addresses can appear in any contract's topics, so the node
+	// cannot filter by emitter for them.
+	var topics [][]common.Hash
+	if len(watchers) > 0 {
+		addresses = nil
+	} else {
+		topics = queryTopics(matchers)
+	}
 
-	return &Scanner{
-		client:        client,
-		store:         store,
-		source:        source,
-		confirmations: confirmations,
-		matchers:      matchers,
-		addresses:     addresses,
+	index := newMatcherIndex(matchers)
+	return func() {
+		s.matchers = index
+		s.addresses = addresses
+		s.topics = topics
+		s.watchers = watchers
+		s.states = states
+		s.transfers = transfers
+		s.functions = functions
+		s.creations = creations
+		s.blobs = blobs
+		s.failures = failures
+		s.views = views
+		s.traces = traces
+		s.baseFees = baseFees
+		s.lags = lags
 	}, nil
 }
 
+// SetTokenResolver makes transfer events carry token_symbol and token_decimals.
+func (s *Scanner) SetTokenResolver(t *TokenResolver) {
+	s.tokens = t
+}
+
+// SetENSResolver makes events carry the ENS names of their from, to and
+// contract addresses.
+func (s *Scanner) SetENSResolver(r *ENSResolver) {
+	s.ens = r
+}
+
+// SetL1Client gives sequencer_lag rules the client of the L1 the source
+// posts to.
+func (s *Scanner) SetL1Client(c BlockClient) {
+	s.l1 = c
+}
+
+// SetStopHeight keeps batches from scanning past height, the end of a
+// bounded replay. Zero means no limit.
+func (s *Scanner) SetStopHeight(height uint64) {
+	s.stopAt = height
+}
+
+// SetFinality makes the scanner stop at the block the node reports as
+// config.ConfirmFinalized or config.ConfirmSafe, instead of a fixed depth
+// below the latest block. Other tags leave it at the latest block.
+func (s *Scanner) SetFinality(tag string) {
+	switch tag {
+	case config.ConfirmFinalized:
+		s.head = big.NewInt(int64(rpc.FinalizedBlockNumber))
+	case config.ConfirmSafe:
+		s.head = big.NewInt(int64(rpc.SafeBlockNumber))
+	default:
+		s.head = nil
+	}
+}
+
+// Tip returns the latest chain height observed by ProcessNext, or 0 before the first poll.
+// With SetFinality it is the finalized or safe height.
+func (s *Scanner) Tip() uint64 {
+	return s.tip.Load()
+}
+
+// latestHeight returns the chain tip. The cached tip is reused while next is
+// still confirmed below it (catching up) or while it is younger than tipTTL.
+func (s *Scanner) latestHeight(ctx context.Context, next uint64) (uint64, error) {
+	if tip := s.tip.Load(); tip > 0 {
+		if next+s.confirmations <= tip || s.nowFunc().Sub(s.tipAt) < s.tipTTL {
+			return tip, nil
+		}
+	}
+	latest, err := s.client.HeaderByNumber(ctx, s.head)
+	if err != nil {
+		if s.head != nil {
+			return 0, fmt.Errorf("%s header: %w", rpc.BlockNumber(s.head.Int64()), err)
+		}
+		return 0, fmt.Errorf("latest header: %w", err)
+	}
+	height := latest.Number.Uint64()
+	if prev := s.tip.Load(); height < prev {
+		// The lower tip is not kept, so the next tick asks again.
+		return 0, &source.TipError{Tip: height, Previous: prev}
+	}
+	s.tip.Store(height)
+	s.tipAt = s.nowFunc()
+	return height, nil
+}
+
 // ProcessNext handles the next eligible block (respecting confirmations) and returns matched events.
-// It advances the cursor on success. If a reorg is detected, ErrReorgDetected is returned after rewinding.
-func (s *Scanner) ProcessNext(ctx context.Context) ([]NormalizedEvent, error) {
-	curHeight, curHash, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+// A source with max_blocks_per_tick that is behind handles up to that many blocks at once.
+// It advances the cursor on success. If a reorg is detected, source.ErrReorgDetected is returned after rewinding.
+func (s *Scanner) ProcessNext(ctx context.Context) ([]source.Event, error) {
+	var events []source.Event
+	err := s.ProcessNextFunc(ctx, func(ev source.Event) error {
+		events = append(events, ev)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return events, nil
+}
+
+// ProcessNextFunc is ProcessNext with each matched event handed to emit as it
+// is decoded, so a busy block is never held in memory as one slice. If emit
+// fails, the cursor is left in place and the error is returned.
+func (s *Scanner) ProcessNextFunc(ctx context.Context, emit func(source.Event) error) error {
+	if s.ens != nil {
+		next := emit
+		emit = func(ev source.Event) error {
+			s.ens.annotate(ctx, &ev)
+			return next(ev)
+		}
+	}
+	curHeight, curHash, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+	if err != nil {
+		return err
+	}
 
-	latest, err := s.client.HeaderByNumber(ctx, nil)
+	latestHeight, err := s.latestHeight(ctx, curHeight+1)
 	if err != nil {
-		return nil, fmt.Errorf("latest header: %w", err)
+		return err
+	}
+	// A finalized or safe tip trails the cursor for a while after the
+	// finality setting changes, so only the latest block is held to it.
+	if hasCursor && s.head == nil && latestHeight < curHeight {
+		return &source.TipError{Tip: latestHeight, Previous: curHeight, Cursor: true}
 	}
-	latestHeight := latest.Number.Uint64()
 
 	safeHeight := latestHeight
 	if s.confirmations > 0 {
 		if s.confirmations > safeHeight {
-			return nil, nil
+			return nil
 		}
 		safeHeight -= s.confirmations
 	}
 
 	target := curHeight + 1
 	if !hasCursor {
-		start, err := resolveStartHeight(s.source.StartBlock, safeHeight)
+		start, err := s.resolveStart(ctx, safeHeight)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		target = start
 	}
 
 	if target > safeHeight {
-		return nil, nil
+		return nil
+	}
+
+	if end := s.batchEnd(target, safeHeight); end > target {
+		return s.processRange(ctx, target, end, hasCursor, curHeight, curHash, emit)
 	}
 
-	header, err := s.client.HeaderByNumber(ctx, big.NewInt(int64(target)))
+	// Rules that read transactions take the header from the full block
+	// rather than fetching it separately. Otherwise a client that batches
+	// calls fetches the header and logs together, trading the bloom check
+	// below for one round trip.
+	block, err := s.fetchBlock(ctx, target)
 	if err != nil {
-		return nil, fmt.Errorf("header %d: %w", target, err)
+		return err
+	}
+	var (
+		header  *types.Header
+		logs    []types.Log
+		batched bool
+	)
+	if block != nil {
+		header = block.Header()
+	} else if len(s.matchers) > 0 || len(s.watchers) > 0 {
+		q := s.logQuery(target, target)
+		var headers []*types.Header
+		if headers, logs, batched, err = s.batch(ctx, []uint64{target}, &q); err != nil {
+			return err
+		}
+		if batched {
+			header = headers[0]
+		}
+	}
+	if header == nil {
+		if header, err = s.client.HeaderByNumber(ctx, big.NewInt(int64(target))); err != nil {
+			return fmt.Errorf("header %d: %w", target, err)
+		}
+	}
+	if hasCursor {
+		if err := s.checkParent(ctx, header, target, curHeight, curHash); err != nil {
+			return err
+		}
 	}
 
-	if hasCursor && header.ParentHash.Hex() != curHash {
-		rewindTo := uint64(0)
-		if target > 0 {
-			rewindTo = target - 1
+	// An all-zero bloom is either an empty block or a node that does not
+	// populate blooms, so only a non-empty bloom is trusted to skip the call.
+	if !batched && (header.Bloom == (types.Bloom{}) || s.matchers.mayMatch(header.Bloom) || s.watchersMayMatch(header.Bloom)) {
+		logs, err = s.client.FilterLogs(ctx, s.logQuery(target, target))
+		if err != nil {
+			return fmt.Errorf("filter logs: %w", err)
 		}
-		_ = s.store.UpsertCursor(ctx, s.source.ID, rewindTo, header.ParentHash.Hex())
-		return nil, ErrReorgDetected
 	}
 
-	logs, err := s.client.FilterLogs(ctx, ethereum.FilterQuery{
-		FromBlock: big.NewInt(int64(target)),
-		ToBlock:   big.NewInt(int64(target)),
-		Addresses: s.addresses,
-	})
+	stamp := stamper(s.source.ID, header, emit)
+	for _, lg := range logs {
+		if err := s.matchLog(ctx, lg, stamp); err != nil {
+			return err
+		}
+	}
+	if block != nil {
+		if err := s.matchTxs(ctx, block, stamp); err != nil {
+			return fmt.Errorf("block %d: %w", target, err)
+		}
+	}
+
+	if err := s.checkTraces(ctx, target, stamp); err != nil {
+		return fmt.Errorf("block %d: %w", target, err)
+	}
+	if err := s.checkState(ctx, target, stamp); err != nil {
+		return fmt.Errorf("block %d: %w", target, err)
+	}
+	if err := s.checkViews(ctx, target, stamp); err != nil {
+		return fmt.Errorf("block %d: %w", target, err)
+	}
+	if err := s.checkBaseFee(ctx, header, stamp); err != nil {
+		return fmt.Errorf("block %d: %w", target, err)
+	}
+	if err := s.checkLag(ctx, header, stamp); err != nil {
+		return fmt.Errorf("block %d: %w", target, err)
+	}
+
+	return s.advance(ctx, target, map[uint64]*types.Header{target: header})
+}
+
+// batchEnd returns the last block to scan in one call starting at target.
+// Rules that look at every block (storage, call, internal_call, base_fee,
+// and those that read full blocks) keep the source to one block per call.
+func (s *Scanner) batchEnd(target, safeHeight uint64) uint64 {
+	if s.maxBatch <= 1 || len(s.states) > 0 || len(s.traces) > 0 || len(s.baseFees) > 0 {
+		return target
+	}
+	if _, ok := s.client.(TxClient); ok && s.readsBlocks() {
+		return target
+	}
+	end := min(target+s.maxBatch-1, safeHeight)
+	if s.stopAt > 0 {
+		end = min(end, max(s.stopAt, target))
+	}
+	return end
+}
+
+// processRange scans blocks from..to with a single FilterLogs call and moves
+// the cursor to the last of them. Headers are fetched for the first block,
+// to check it extends the cursor, for the last, whose hash becomes the
+// cursor, and for each block with logs, for its time. A log whose block
+// hash no longer matches its header means the range changed mid-scan; the
+// call fails and the range is scanned again. Due view and sequencer_lag
+// rules are read at the last block. A client that batches calls fetches the first and last
+// headers with the logs in one request, then the other headers in another.
+func (s *Scanner) processRange(ctx context.Context, from, to uint64, hasCursor bool, curHeight uint64, curHash string, emit func(source.Event) error) error {
+	headers := map[uint64]*types.Header{}
+	q := s.logQuery(from, to)
+	hs, logs, batched, err := s.batch(ctx, []uint64{from, to}, &q)
+	if err != nil {
+		return err
+	}
+	if batched {
+		headers[from], headers[to] = hs[0], hs[1]
+	}
+	header := func(n uint64) (*types.Header, error) {
+		if h, ok := headers[n]; ok {
+			return h, nil
+		}
+		h, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			return nil, fmt.Errorf("header %d: %w", n, err)
+		}
+		headers[n] = h
+		return h, nil
+	}
+	first, err := header(from)
+	if err != nil {
+		return err
+	}
+	if hasCursor {
+		if err := s.checkParent(ctx, first, from, curHeight, curHash); err != nil {
+			return err
+		}
+	}
+	last, err := header(to)
 	if err != nil {
-		return nil, fmt.Errorf("filter logs: %w", err)
+		return err
 	}
 
-	events := []NormalizedEvent{}
+	if !batched {
+		if logs, err = s.client.FilterLogs(ctx, q); err != nil {
+			return fmt.Errorf("filter logs %d-%d: %w", from, to, err)
+		}
+	}
+	if err := s.batchHeaders(ctx, logs, headers); err != nil {
+		return err
+	}
 	for _, lg := range logs {
-		for _, m := range s.matchers {
-			ev, ok, err := m.Match(lg)
+		h, err := header(lg.BlockNumber)
+		if err != nil {
+			return err
+		}
+		if lg.BlockHash != (common.Hash{}) && lg.BlockHash != h.Hash() {
+			return fmt.Errorf("block %d changed during batch scan", lg.BlockNumber)
+		}
+		if err := s.matchLog(ctx, lg, stamper(s.source.ID, h, emit)); err != nil {
+			return err
+		}
+	}
+	if err := s.checkViews(ctx, to, stamper(s.source.ID, last, emit)); err != nil {
+		return fmt.Errorf("block %d: %w", to, err)
+	}
+	if err := s.checkLag(ctx, last, stamper(s.source.ID, last, emit)); err != nil {
+		return fmt.Errorf("block %d: %w", to, err)
+	}
+	return s.advance(ctx, to, headers)
+}
+
+// advance records the hashes of the scanned headers and moves the cursor
+// to height.
+func (s *Scanner) advance(ctx context.Context, height uint64, headers map[uint64]*types.Header) error {
+	hashes := make(map[uint64]string, len(headers))
+	for n, h := range headers {
+		hashes[n] = h.Hash().Hex()
+	}
+	if err := s.store.RecordBlockHashes(ctx, s.source.ID, hashes, s.reorgDepth); err != nil {
+		return err
+	}
+	return s.store.UpsertCursor(ctx, s.source.ID, height, hashes[height])
+}
+
+// checkParent rewinds the cursor and returns a source.ReorgError when header, at
+// height target, does not build on the stored cursor. The cursor goes back
+// to the common ancestor, so every orphaned block is scanned again.
+func (s *Scanner) checkParent(ctx context.Context, header *types.Header, target, curHeight uint64, curHash string) error {
+	if header.ParentHash.Hex() == curHash {
+		return nil
+	}
+	ancestor, hash, exceeded, err := s.commonAncestor(ctx, curHeight, header.ParentHash.Hex())
+	if err != nil {
+		return fmt.Errorf("reorg at block %d: %w", target, err)
+	}
+	if err := s.store.RewindCursor(ctx, s.source.ID, ancestor, hash); err != nil {
+		return err
+	}
+	from := min(ancestor+1, curHeight)
+	return &source.ReorgError{Height: target, From: from, To: curHeight, OldHash: curHash, NewHash: header.ParentHash.Hex(), Exceeded: exceeded}
+}
+
+// commonAncestor walks back from the orphaned cursor at curHeight to the
+// highest block whose recorded hash the chain still has, looking at most
+// reorgDepth blocks down. Blocks with no recorded hash, such as those inside
+// a batch, are stepped over. With no hashes recorded at all, the block below
+// the cursor is taken as the ancestor; when recorded hashes exist but none
+// match, the walk stops at its bound and exceeded is set.
+func (s *Scanner) commonAncestor(ctx context.Context, curHeight uint64, newHash string) (height uint64, hash string, exceeded bool, err error) {
+	if curHeight == 0 {
+		return 0, newHash, false, nil
+	}
+	lowest := uint64(0)
+	if curHeight > s.reorgDepth {
+		lowest = curHeight - s.reorgDepth
+	}
+	recorded := false
+	for h := curHeight - 1; ; h-- {
+		stored, ok, err := s.store.BlockHash(ctx, s.source.ID, h)
+		if err != nil {
+			return 0, "", false, err
+		}
+		if ok {
+			recorded = true
+			header, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(h))
 			if err != nil {
-				return nil, err
+				return 0, "", false, fmt.Errorf("header %d: %w", h, err)
+			}
+			if stored == header.Hash().Hex() {
+				return h, stored, false, nil
+			}
+		}
+		if h == lowest {
+			break
+		}
+	}
+	height = lowest
+	if !recorded {
+		height = curHeight - 1
+	}
+	header, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(height))
+	if err != nil {
+		return 0, "", false, fmt.Errorf("header %d: %w", height, err)
+	}
+	return height, header.Hash().Hex(), recorded, nil
+}
+
+// stamper returns emit with each event stamped with its source and block.
+func stamper(sourceID string, header *types.Header, emit func(source.Event) error) func(source.Event) error {
+	return func(ev source.Event) error {
+		ev.Chain = Chain
+		ev.SourceID = sourceID
+		ev.Height = header.Number.Uint64()
+		ev.Hash = header.Hash().Hex()
+		ev.Timestamp = time.Unix(int64(header.Time), 0).UTC()
+		return emit(ev)
+	}
+}
+
+// matchLog runs lg past the rule matchers and address watchers.
+func (s *Scanner) matchLog(ctx context.Context, lg types.Log, stamp func(source.Event) error) error {
+	for _, m := range s.matchers.lookup(lg) {
+		evs, err := m.MatchAll(lg)
+		if err != nil {
+			return err
+		}
+		for _, ev := range evs {
+			if s.tokens != nil {
+				s.tokens.annotate(ctx, &ev)
 			}
+			if err := stamp(ev); err != nil {
+				return err
+			}
+		}
+	}
+	for _, w := range s.watchers {
+		if ev, ok := w.matchLog(lg); ok {
+			if err := stamp(*ev); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readsBlocks reports whether any rule matches transactions, which are only
+// visible in full blocks: address watching, transfer, function_call,
+// contract_creation, blob_tx and failed_tx.
+func (s *Scanner) readsBlocks() bool {
+	return len(s.watchers) > 0 || len(s.transfers) > 0 || len(s.functions) > 0 || len(s.creations) > 0 ||
+		len(s.blobs) > 0 || len(s.failures) > 0
+}
+
+// fetchBlock returns the full block at number when the source has rules
+// that read transactions, or nil when it does not or the block cannot be
+// decoded (e.g. L2 deposit txs), in which case the caller fetches the header.
+func (s *Scanner) fetchBlock(ctx context.Context, number uint64) (*types.Block, error) {
+	txc, ok := s.client.(TxClient)
+	if !ok || !s.readsBlocks() {
+		return nil, nil
+	}
+	block, err := txc.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		if errors.Is(err, types.ErrTxTypeNotSupported) || errors.Is(err, errNoBlocks) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("block %d: %w", number, err)
+	}
+	return block, nil
+}
+
+// matchTxs runs the block's transactions past the address, transfer,
+// function, creation, blob and failed_tx watchers. A transfer whose receipt
+// shows it reverted moved no value and is skipped, as is a failed
+// deployment; function calls carry the outcome as a reverted arg. A
+// reverted blob transaction still paid for its blobs and is matched.
+// Receipts are only fetched for matches, and clients without receipts treat
+// every transaction as successful, so failed_tx rules need them.
+func (s *Scanner) matchTxs(ctx context.Context, block *types.Block, stamp func(source.Event) error) error {
+	var blobFee *big.Int
+	if len(s.blobs) > 0 {
+		blobFee = blobBaseFee(block.Header())
+	}
+	for _, tx := range block.Transactions() {
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			continue
+		}
+		for _, w := range s.watchers {
+			if ev, ok := w.matchTx(tx, from); ok {
+				if err := stamp(*ev); err != nil {
+					return err
+				}
+			}
+		}
+		for _, w := range s.blobs {
+			if ev, ok := w.matchTx(tx, from, blobFee); ok {
+				if err := stamp(*ev); err != nil {
+					return err
+				}
+			}
+		}
+		var (
+			rcpt    *types.Receipt
+			fetched bool
+		)
+		receipt := func() (*types.Receipt, error) {
+			if !fetched {
+				r, err := s.receipt(ctx, tx.Hash())
+				if err != nil {
+					return nil, err
+				}
+				rcpt, fetched = r, true
+			}
+			return rcpt, nil
+		}
+		for _, w := range s.transfers {
+			ev, ok := w.matchTx(tx, from)
+			if !ok {
+				continue
+			}
+			r, err := receipt()
+			if err != nil {
+				return err
+			}
+			if failed(r) {
+				break
+			}
+			if err := stamp(*ev); err != nil {
+				return err
+			}
+		}
+		for _, m := range s.functions {
+			ev, ok := m.matchTx(tx, from)
 			if !ok {
 				continue
 			}
-			ev.Chain = Chain
-			ev.SourceID = s.source.ID
-			ev.Height = target
-			ev.Hash = header.Hash().Hex()
-			events = append(events, *ev)
+			r, err := receipt()
+			if err != nil {
+				return err
+			}
+			ev.Args["reverted"] = failed(r)
+			if err := stamp(*ev); err != nil {
+				return err
+			}
+		}
+		for _, w := range s.creations {
+			if !w.matchTx(tx, from) {
+				continue
+			}
+			r, err := receipt()
+			if err != nil {
+				return err
+			}
+			if failed(r) {
+				break
+			}
+			if err := stamp(w.event(tx, from, r)); err != nil {
+				return err
+			}
+		}
+		var (
+			revert   []byte
+			replayed bool
+		)
+		for _, w := range s.failures {
+			if !w.matchTx(tx, from) {
+				continue
+			}
+			r, err := receipt()
+			if err != nil {
+				return err
+			}
+			if !failed(r) {
+				break
+			}
+			if !replayed {
+				revert, replayed = s.revertData(ctx, tx, from, block.NumberU64()), true
+			}
+			if err := stamp(w.event(tx, from, r, revert)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// receipt returns the mined transaction's receipt, or nil when the client
+// does not look up receipts.
+func (s *Scanner) receipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	rc, ok := s.client.(ReceiptClient)
+	if !ok {
+		return nil, nil
+	}
+	rcpt, err := rc.TransactionReceipt(ctx, hash)
+	if err != nil {
+		if errors.Is(err, errNoTxs) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("receipt %s: %w", hash.Hex(), err)
+	}
+	return rcpt, nil
+}
+
+// failed reports whether rcpt shows the transaction reverted.
+func failed(rcpt *types.Receipt) bool {
+	return rcpt != nil && rcpt.Status == types.ReceiptStatusFailed
+}
+
+func (s *Scanner) watchersMayMatch(bloom types.Bloom) bool {
+	for _, w := range s.watchers {
+		if w.mayMatch(bloom) {
+			return true
 		}
 	}
+	return false
+}
 
+// SkipNext advances the cursor past the next block without matching it.
+// It requires an existing cursor and returns the skipped height.
+func (s *Scanner) SkipNext(ctx context.Context) (uint64, error) {
+	curHeight, _, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+	if err != nil {
+		return 0, err
+	}
+	if !hasCursor {
+		return 0, fmt.Errorf("source %s has no cursor yet", s.source.ID)
+	}
+	target := curHeight + 1
+	header, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(target))
+	if err != nil {
+		return 0, fmt.Errorf("header %d: %w", target, err)
+	}
 	if err := s.store.UpsertCursor(ctx, s.source.ID, target, header.Hash().Hex()); err != nil {
-		return nil, err
+		return 0, err
 	}
+	return target, nil
+}
 
-	return events, nil
+// resolveStart picks the first block for a source without a cursor. A
+// "time:" start_block is found by binary search over block timestamps.
+func (s *Scanner) resolveStart(ctx context.Context, safeHeight uint64) (uint64, error) {
+	at, ok, err := blocktime.Parse(s.source.StartBlock)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return resolveStartHeight(s.source.StartBlock, safeHeight)
+	}
+	return blocktime.Search(ctx, safeHeight, at, func(ctx context.Context, h uint64) (time.Time, error) {
+		header, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(h))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(int64(header.Time), 0), nil
+	})
 }
 
 func resolveStartHeight(start string, safeHeight uint64) (uint64, error) {
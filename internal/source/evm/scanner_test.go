@@ -1,31 +1,44 @@
 package evm
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
 	"github.com/devblac/watch-tower/internal/storage"
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
 )
 
 type fakeClient struct {
-	headers map[uint64]*types.Header
-	logs    map[uint64][]types.Log
+	headers     map[uint64]*types.Header
+	logs        map[uint64][]types.Log
+	tipCalls    int
+	filterCalls int
 }
 
 func (f *fakeClient) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
 	if number == nil {
+		f.tipCalls++
 		var max uint64
 		for n := range f.headers {
 			if n > max {
@@ -44,8 +57,35 @@ func (f *fakeClient) HeaderByNumber(_ context.Context, number *big.Int) (*types.
 }
 
 func (f *fakeClient) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
-	from := q.FromBlock.Uint64()
-	return f.logs[from], nil
+	f.filterCalls++
+	var out []types.Log
+	for n := q.FromBlock.Uint64(); n <= q.ToBlock.Uint64(); n++ {
+		out = append(out, f.logs[n]...)
+	}
+	return out, nil
+}
+
+func TestRPCClientSendsHeaders(t *testing.T) {
+	var apiKey, user, pass string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("X-Api-Key")
+		user, pass, _ = r.BasicAuth()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer server.Close()
+
+	c, err := NewRPCClient(server.URL, map[string]string{"x-api-key": "k1"}, &config.BasicAuth{Username: "u", Password: "p"})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	if _, err := c.ChainID(context.Background()); err != nil {
+		t.Fatalf("chain id: %v", err)
+	}
+	if apiKey != "k1" || user != "u" || pass != "p" {
+		t.Fatalf("unexpected request auth: key=%q user=%q pass=%q", apiKey, user, pass)
+	}
 }
 
 func TestScannerProcessesBlock(t *testing.T) {
@@ -74,7 +114,7 @@ func TestScannerProcessesBlock(t *testing.T) {
 	}
 
 	parent := &types.Header{Number: big.NewInt(0)}
-	h1 := &types.Header{Number: big.NewInt(1), ParentHash: parent.Hash()}
+	h1 := &types.Header{Number: big.NewInt(1), ParentHash: parent.Hash(), Time: 1_700_000_000}
 
 	fc := &fakeClient{
 		headers: map[uint64]*types.Header{
@@ -99,8 +139,8 @@ func TestScannerProcessesBlock(t *testing.T) {
 		},
 	}
 
-	source := config.Source{ID: "evm_main", Type: "evm", RPCURL: "stub", StartBlock: "1"}
-	scanner, err := NewScanner(fc, store, source, 0, abis, []config.Rule{rule})
+	src := config.Source{ID: "evm_main", Type: "evm", RPCURL: config.URLs{"stub"}, StartBlock: "1", TipTTL: "0s"}
+	scanner, err := NewScanner(fc, store, src, 0, abis, []config.Rule{rule})
 	if err != nil {
 		t.Fatalf("new scanner: %v", err)
 	}
@@ -112,7 +152,10 @@ func TestScannerProcessesBlock(t *testing.T) {
 	if len(evs) != 1 {
 		t.Fatalf("expected 1 event, got %d", len(evs))
 	}
-	h, _, ok, _ := store.GetCursor(context.Background(), source.ID)
+	if want := time.Unix(1_700_000_000, 0).UTC(); !evs[0].Timestamp.Equal(want) {
+		t.Fatalf("expected block time %v, got %v", want, evs[0].Timestamp)
+	}
+	h, _, ok, _ := store.GetCursor(context.Background(), src.ID)
 	if !ok || h != 1 {
 		t.Fatalf("cursor not advanced, h=%d ok=%v", h, ok)
 	}
@@ -128,21 +171,79 @@ func TestScannerReorgDetection(t *testing.T) {
 	h2 := &types.Header{Number: big.NewInt(2), ParentHash: common.HexToHash("0xother")}
 	fc := &fakeClient{
 		headers: map[uint64]*types.Header{
+			0: {Number: big.NewInt(0)},
 			2: h2,
 		},
 	}
 
-	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", RPCURL: "stub"}, 0, nil, nil)
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", RPCURL: config.URLs{"stub"}}, 0, nil, nil)
 	if err != nil {
 		t.Fatalf("new scanner: %v", err)
 	}
 
 	_, err = scanner.ProcessNext(ctx)
-	if !errors.Is(err, ErrReorgDetected) {
+	if !errors.Is(err, source.ErrReorgDetected) {
 		t.Fatalf("expected reorg error, got %v", err)
 	}
 }
 
+func TestScannerReorgRewindsToCommonAncestor(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	// chain builds headers from..to on top of parent; fork tells the two
+	// chains' blocks apart.
+	chain := func(headers map[uint64]*types.Header, parent *types.Header, from, to uint64, fork byte) {
+		for n := from; n <= to; n++ {
+			h := &types.Header{Number: new(big.Int).SetUint64(n), ParentHash: parent.Hash(), Extra: []byte{fork}}
+			headers[n] = h
+			parent = h
+		}
+	}
+	fc := &fakeClient{headers: map[uint64]*types.Header{0: {Number: big.NewInt(0)}}}
+	chain(fc.headers, fc.headers[0], 1, 5, 'a')
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", RPCURL: config.URLs{"stub"}, StartBlock: "1", TipTTL: "0s"}, 0, nil, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := scanner.ProcessNext(ctx); err != nil {
+			t.Fatalf("process: %v", err)
+		}
+	}
+	orphaned := fc.headers[5].Hash().Hex()
+
+	// Blocks 3 to 5 are replaced by a longer fork off block 2.
+	chain(fc.headers, fc.headers[2], 3, 7, 'b')
+	_, err = scanner.ProcessNext(ctx)
+	var rg *source.ReorgError
+	if !errors.As(err, &rg) || rg.From != 3 || rg.To != 5 || rg.Exceeded || rg.OldHash != orphaned {
+		t.Fatalf("expected blocks 3-5 rolled back, got %v", err)
+	}
+	height, hash, _, _ := store.GetCursor(ctx, "evm_main")
+	if height != 2 || hash != fc.headers[2].Hash().Hex() {
+		t.Fatalf("expected the cursor at the common ancestor, got %d %s", height, hash)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := scanner.ProcessNext(ctx); err != nil {
+			t.Fatalf("process fork: %v", err)
+		}
+	}
+	if height, hash, _, _ := store.GetCursor(ctx, "evm_main"); height != 7 || hash != fc.headers[7].Hash().Hex() {
+		t.Fatalf("expected the fork scanned, got %d %s", height, hash)
+	}
+
+	// A fork deeper than max_reorg_depth rewinds only that far.
+	chain(fc.headers, fc.headers[0], 1, 8, 'c')
+	scanner.reorgDepth = 3
+	_, err = scanner.ProcessNext(ctx)
+	if !errors.As(err, &rg) || rg.From != 5 || rg.To != 7 || !rg.Exceeded {
+		t.Fatalf("expected a bounded rewind, got %v", err)
+	}
+	if height, hash, _, _ := store.GetCursor(ctx, "evm_main"); height != 4 || hash != fc.headers[4].Hash().Hex() {
+		t.Fatalf("expected the cursor at the depth bound, got %d %s", height, hash)
+	}
+}
+
 func transferTopic(signature string) common.Hash {
 	return crypto.Keccak256Hash([]byte(signature))
 }
@@ -165,3 +266,761 @@ func newTestStore(t *testing.T) *storage.Store {
 func addrTopic(addr common.Address) common.Hash {
 	return common.BytesToHash(common.LeftPadBytes(addr.Bytes(), 32))
 }
+
+func TestScannerSkipNext(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	h1 := &types.Header{Number: big.NewInt(1)}
+	h2 := &types.Header{Number: big.NewInt(2), ParentHash: h1.Hash()}
+	fc := &fakeClient{headers: map[uint64]*types.Header{1: h1, 2: h2}}
+
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm"}, 0, nil, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	if _, err := scanner.SkipNext(ctx); err == nil {
+		t.Fatalf("expected error without cursor")
+	}
+	if err := store.UpsertCursor(ctx, "evm_main", 1, h1.Hash().Hex()); err != nil {
+		t.Fatalf("seed cursor: %v", err)
+	}
+	skipped, err := scanner.SkipNext(ctx)
+	if err != nil || skipped != 2 {
+		t.Fatalf("skip: %d err=%v", skipped, err)
+	}
+	h, hash, _, _ := store.GetCursor(ctx, "evm_main")
+	if h != 2 || hash != h2.Hash().Hex() {
+		t.Fatalf("cursor not advanced: %d %s", h, hash)
+	}
+}
+
+func TestScannerCachesTipWhileCatchingUp(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	fc := &fakeClient{headers: map[uint64]*types.Header{}}
+	var parent common.Hash
+	for n := uint64(1); n <= 5; n++ {
+		h := &types.Header{Number: new(big.Int).SetUint64(n), ParentHash: parent}
+		fc.headers[n] = h
+		parent = h.Hash()
+	}
+
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", StartBlock: "1"}, 0, nil, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	scanner.nowFunc = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		if _, err := scanner.ProcessNext(ctx); err != nil {
+			t.Fatalf("process %d: %v", i, err)
+		}
+	}
+	if fc.tipCalls != 1 {
+		t.Fatalf("expected one tip fetch while catching up, got %d", fc.tipCalls)
+	}
+
+	// Caught up: the cached tip is reused until it expires.
+	if _, err := scanner.ProcessNext(ctx); err != nil {
+		t.Fatalf("process at tip: %v", err)
+	}
+	if fc.tipCalls != 1 {
+		t.Fatalf("expected cached tip within ttl, got %d fetches", fc.tipCalls)
+	}
+	now = now.Add(config.DefaultTipTTL)
+	if _, err := scanner.ProcessNext(ctx); err != nil {
+		t.Fatalf("process after ttl: %v", err)
+	}
+	if fc.tipCalls != 2 || scanner.Tip() != 5 {
+		t.Fatalf("expected refetch after ttl: calls=%d tip=%d", fc.tipCalls, scanner.Tip())
+	}
+}
+
+func TestScannerTipBehind(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	fc := &fakeClient{headers: map[uint64]*types.Header{}}
+	for n := uint64(1); n <= 5; n++ {
+		fc.headers[n] = &types.Header{Number: new(big.Int).SetUint64(n)}
+	}
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", TipTTL: "0s"}, 0, nil, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+
+	// A node behind the cursor is reported rather than taken as idle.
+	if err := store.UpsertCursor(ctx, "evm_main", 8, "0x08"); err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+	var tipErr *source.TipError
+	if _, err := scanner.ProcessNext(ctx); !errors.As(err, &tipErr) || !tipErr.Cursor || tipErr.Tip != 5 || tipErr.Previous != 8 {
+		t.Fatalf("expected a tip behind the cursor, got %v", err)
+	}
+
+	// So is a tip lower than one seen on an earlier tick.
+	if err := store.UpsertCursor(ctx, "evm_main", 5, fc.headers[5].Hash().Hex()); err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+	if _, err := scanner.ProcessNext(ctx); err != nil {
+		t.Fatalf("process at tip: %v", err)
+	}
+	delete(fc.headers, 5)
+	if _, err := scanner.ProcessNext(ctx); !errors.Is(err, source.ErrTipBehind) || scanner.Tip() != 5 {
+		t.Fatalf("expected a tip below the last one, got %v (tip %d)", err, scanner.Tip())
+	}
+}
+
+func TestScannerBatchesCatchUp(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	rule := config.Rule{
+		ID:     "any_transfer",
+		Source: "evm_main",
+		Match:  config.MatchSpec{Type: MatchERC721Transfer, Contract: "0x00000000000000000000000000000000000000aa"},
+	}
+	fc := &fakeClient{headers: map[uint64]*types.Header{}, logs: map[uint64][]types.Log{}}
+	var parent common.Hash
+	for n := uint64(1); n <= 10; n++ {
+		h := &types.Header{Number: new(big.Int).SetUint64(n), ParentHash: parent, Time: 1_700_000_000 + n*12}
+		fc.headers[n] = h
+		parent = h.Hash()
+	}
+	transfer := func(n uint64) types.Log {
+		return types.Log{
+			Address: common.HexToAddress(rule.Match.Contract),
+			Topics: []common.Hash{
+				transferTopic("Transfer(address,address,uint256)"),
+				addrTopic(common.HexToAddress("0x01")),
+				addrTopic(common.HexToAddress("0x02")),
+				common.BigToHash(big.NewInt(int64(n))),
+			},
+			BlockNumber: n,
+			BlockHash:   fc.headers[n].Hash(),
+		}
+	}
+	fc.logs[3] = []types.Log{transfer(3)}
+	fc.logs[7] = []types.Log{transfer(7)}
+
+	src := config.Source{ID: "evm_main", Type: "evm", StartBlock: "1", MaxBlocksPerTick: 5}
+	scanner, err := NewScanner(fc, store, src, 0, nil, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+
+	for i, want := range []uint64{3, 7} {
+		evs, err := scanner.ProcessNext(ctx)
+		if err != nil {
+			t.Fatalf("batch %d: %v", i, err)
+		}
+		if len(evs) != 1 || evs[0].Height != want || !evs[0].Timestamp.Equal(time.Unix(int64(1_700_000_000+want*12), 0)) {
+			t.Fatalf("batch %d: unexpected events %+v", i, evs)
+		}
+		if h, hash, _, _ := store.GetCursor(ctx, src.ID); h != uint64(5*(i+1)) || hash != fc.headers[h].Hash().Hex() {
+			t.Fatalf("batch %d: cursor at %d %s", i, h, hash)
+		}
+	}
+	if fc.filterCalls != 2 {
+		t.Fatalf("expected one eth_getLogs per batch, got %d", fc.filterCalls)
+	}
+
+	// A log from a block replaced since its header was read fails the batch.
+	if err := store.UpsertCursor(ctx, src.ID, 5, fc.headers[5].Hash().Hex()); err != nil {
+		t.Fatalf("rewind: %v", err)
+	}
+	fc.logs[7][0].BlockHash = common.HexToHash("0xdead")
+	if _, err := scanner.ProcessNext(ctx); err == nil {
+		t.Fatalf("expected an error for a changed block")
+	}
+	if h, _, _, _ := store.GetCursor(ctx, src.ID); h != 5 {
+		t.Fatalf("cursor moved after a failed batch: %d", h)
+	}
+}
+
+func TestScannerSkipsFilterLogsOnBloomMiss(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	rule := config.Rule{
+		ID:     "usdc_whale",
+		Source: "evm_main",
+		Match:  config.MatchSpec{Type: "log", Contract: "0xA0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", Event: "Transfer(address,address,uint256)"},
+	}
+	bloomOf := func(addr common.Address, topic common.Hash) types.Bloom {
+		var b types.Bloom
+		b.Add(addr.Bytes())
+		b.Add(topic.Bytes())
+		return b
+	}
+	other := bloomOf(common.HexToAddress("0x01"), transferTopic(rule.Match.Event))
+	watched := bloomOf(common.HexToAddress(rule.Match.Contract), transferTopic(rule.Match.Event))
+
+	h1 := &types.Header{Number: big.NewInt(1), Bloom: other}
+	h2 := &types.Header{Number: big.NewInt(2), ParentHash: h1.Hash(), Bloom: watched}
+	fc := &fakeClient{headers: map[uint64]*types.Header{1: h1, 2: h2}}
+
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", StartBlock: "1"}, 0, nil, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	if _, err := scanner.ProcessNext(ctx); err != nil {
+		t.Fatalf("process 1: %v", err)
+	}
+	if fc.filterCalls != 0 {
+		t.Fatalf("expected bloom miss to skip FilterLogs, got %d calls", fc.filterCalls)
+	}
+	if h, _, _, _ := store.GetCursor(ctx, "evm_main"); h != 1 {
+		t.Fatalf("cursor should still advance on a skipped block, got %d", h)
+	}
+	if _, err := scanner.ProcessNext(ctx); err != nil {
+		t.Fatalf("process 2: %v", err)
+	}
+	if fc.filterCalls != 1 {
+		t.Fatalf("expected bloom hit to call FilterLogs once, got %d", fc.filterCalls)
+	}
+}
+
+type stateClient struct {
+	fakeClient
+	slots map[uint64][]byte
+}
+
+func (c *stateClient) StorageAt(_ context.Context, _ common.Address, _ common.Hash, block *big.Int) ([]byte, error) {
+	return c.slots[block.Uint64()], nil
+}
+
+func (c *stateClient) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	return nil, errors.New("unexpected call")
+}
+
+func TestScannerStorageRule(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	owner := func(a string) []byte { return common.LeftPadBytes(common.HexToAddress(a).Bytes(), 32) }
+
+	headers := map[uint64]*types.Header{0: {Number: big.NewInt(0)}}
+	for n := uint64(1); n <= 3; n++ {
+		headers[n] = &types.Header{Number: new(big.Int).SetUint64(n), ParentHash: headers[n-1].Hash()}
+	}
+	fc := &stateClient{
+		fakeClient: fakeClient{headers: headers},
+		slots:      map[uint64][]byte{1: owner("0x01"), 2: owner("0x01"), 3: owner("0x02")},
+	}
+	rule := config.Rule{ID: "owner", Source: "evm_main", Match: config.MatchSpec{Type: MatchStorage, Contract: "0x00000000000000000000000000000000000000aa", Slot: "0"}}
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", StartBlock: "1"}, 0, nil, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+
+	var evs []source.Event
+	for i := 0; i < 3; i++ {
+		got, err := scanner.ProcessNext(ctx)
+		if err != nil {
+			t.Fatalf("process block %d: %v", i+1, err)
+		}
+		evs = append(evs, got...)
+	}
+	if len(evs) != 1 {
+		t.Fatalf("expected one change event, got %+v", evs)
+	}
+	ev := evs[0]
+	if ev.Name != StateChangedEvent || ev.Height != 3 || ev.Args["old"] != hexutil.Encode(owner("0x01")) || ev.Args["new"] != hexutil.Encode(owner("0x02")) {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+// callClient answers view calls from values keyed by contract, either
+// directly or through Multicall3 when deployed is set.
+type callClient struct {
+	fakeClient
+	values   map[common.Address][]byte
+	deployed bool
+	calls    int
+}
+
+func (c *callClient) StorageAt(context.Context, common.Address, common.Hash, *big.Int) ([]byte, error) {
+	return nil, errors.New("unexpected storage read")
+}
+
+func (c *callClient) CallContract(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	c.calls++
+	if *msg.To != Multicall3Address {
+		return c.values[*msg.To], nil
+	}
+	if !c.deployed {
+		return nil, nil
+	}
+	method := multicall3ABI.Methods["aggregate3"]
+	in, err := method.Inputs.Unpack(msg.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	var calls []call3
+	if err := method.Inputs.Copy(&calls, in); err != nil {
+		return nil, err
+	}
+	results := make([]result3, len(calls))
+	for i, call := range calls {
+		results[i] = result3{Success: true, ReturnData: c.values[call.Target]}
+	}
+	return method.Outputs.Pack(results)
+}
+
+func TestScannerBatchesCallRules(t *testing.T) {
+	a := common.HexToAddress("0xaa")
+	b := common.HexToAddress("0xbb")
+	rules := []config.Rule{
+		{ID: "owner_a", Source: "evm_main", Match: config.MatchSpec{Type: MatchCall, Contract: a.Hex(), Function: "owner()"}},
+		{ID: "owner_b", Source: "evm_main", Match: config.MatchSpec{Type: MatchCall, Contract: b.Hex(), Function: "owner()"}},
+	}
+	for _, deployed := range []bool{true, false} {
+		store := newTestStore(t)
+		ctx := context.Background()
+		fc := &callClient{
+			fakeClient: fakeClient{headers: map[uint64]*types.Header{1: {Number: big.NewInt(1)}}},
+			values:     map[common.Address][]byte{a: {1}, b: {2}},
+			deployed:   deployed,
+		}
+		scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", StartBlock: "1"}, 0, nil, rules)
+		if err != nil {
+			t.Fatalf("new scanner: %v", err)
+		}
+		if _, err := scanner.ProcessNext(ctx); err != nil {
+			t.Fatalf("process: %v", err)
+		}
+		want := 1
+		if !deployed {
+			want = 3 // the probe, then one call per rule
+		}
+		if fc.calls != want {
+			t.Fatalf("deployed=%v: expected %d eth_calls, got %d", deployed, want, fc.calls)
+		}
+		v, ok, err := store.GetStateValue(ctx, "evm_main", "owner_b")
+		if err != nil || !ok || v.Value != "0x02" {
+			t.Fatalf("deployed=%v: unexpected stored value %+v, %v, %v", deployed, v, ok, err)
+		}
+	}
+}
+
+// blockClient serves full blocks built from the fake headers, and receipts
+// for the transactions listed in failed.
+type blockClient struct {
+	fakeClient
+	txs    map[uint64][]*types.Transaction
+	failed map[common.Hash]bool
+}
+
+func (c *blockClient) BlockByNumber(_ context.Context, number *big.Int) (*types.Block, error) {
+	h, ok := c.headers[number.Uint64()]
+	if !ok {
+		return nil, fmt.Errorf("block %d not found", number.Uint64())
+	}
+	return types.NewBlockWithHeader(h).WithBody(c.txs[number.Uint64()], nil), nil
+}
+
+func (c *blockClient) TransactionByHash(context.Context, common.Hash) (*types.Transaction, bool, error) {
+	return nil, false, errors.New("unexpected lookup")
+}
+
+func (c *blockClient) TransactionReceipt(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+	if c.failed[hash] {
+		return &types.Receipt{Status: types.ReceiptStatusFailed}, nil
+	}
+	return &types.Receipt{Status: types.ReceiptStatusSuccessful}, nil
+}
+
+func TestScannerTransferRule(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	treasury := crypto.PubkeyToAddress(key.PublicKey)
+	exchange := common.HexToAddress("0x00000000000000000000000000000000000000ee")
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	send := func(nonce uint64, ether int64) *types.Transaction {
+		value := new(big.Int).Mul(big.NewInt(ether), big.NewInt(1e18))
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: &exchange, Value: value, Gas: 21000, GasPrice: big.NewInt(1)})
+	}
+	large, small, reverted := send(0, 20), send(1, 1), send(2, 50)
+
+	headers := map[uint64]*types.Header{0: {Number: big.NewInt(0)}}
+	headers[1] = &types.Header{Number: big.NewInt(1), ParentHash: headers[0].Hash(), Time: 1_700_000_000}
+	fc := &blockClient{
+		fakeClient: fakeClient{headers: headers},
+		txs:        map[uint64][]*types.Transaction{1: {large, small, reverted}},
+		failed:     map[common.Hash]bool{reverted.Hash(): true},
+	}
+	rule := config.Rule{ID: "outflow", Source: "evm_main", Match: config.MatchSpec{
+		Type: config.MatchTransfer, Addresses: []string{treasury.Hex()}, MinValue: "10 ether",
+	}}
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", StartBlock: "1"}, 0, nil, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	evs, err := scanner.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(evs) != 1 {
+		t.Fatalf("expected only the large successful transfer, got %+v", evs)
+	}
+	ev := evs[0]
+	if ev.Name != NativeTransferEvent || ev.TxHash != large.Hash().Hex() || ev.Height != 1 || ev.Hash != headers[1].Hash().Hex() ||
+		ev.Args["direction"] != "out" || ev.Args["to"] != exchange.Hex() || ev.Args["value"].(*big.Int).Cmp(large.Value()) != 0 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	if _, hash, _, _ := store.GetCursor(ctx, "evm_main"); hash != headers[1].Hash().Hex() {
+		t.Fatalf("expected cursor at block 1, got %s", hash)
+	}
+}
+
+func TestScannerFunctionCallRule(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	vault := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	attacker := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	call := func(nonce uint64, data []byte) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: &vault, Gas: 50000, GasPrice: big.NewInt(1), Data: data})
+	}
+	selector := crypto.Keccak256([]byte("transferOwnership(address)"))[:4]
+	transferOwnership := call(0, append(append([]byte{}, selector...), common.LeftPadBytes(attacker.Bytes(), 32)...))
+	other := call(1, crypto.Keccak256([]byte("pause()"))[:4])
+	garbage := call(2, append(append([]byte{}, selector...), 0x01))
+
+	headers := map[uint64]*types.Header{0: {Number: big.NewInt(0)}}
+	headers[1] = &types.Header{Number: big.NewInt(1), ParentHash: headers[0].Hash()}
+	fc := &blockClient{
+		fakeClient: fakeClient{headers: headers},
+		txs:        map[uint64][]*types.Transaction{1: {transferOwnership, other, garbage}},
+		failed:     map[common.Hash]bool{garbage.Hash(): true},
+	}
+	ownable, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"transferOwnership","inputs":[{"name":"newOwner","type":"address"}],"outputs":[]}]`))
+	if err != nil {
+		t.Fatalf("parse abi: %v", err)
+	}
+	rule := config.Rule{ID: "owner_change", Source: "evm_main", Match: config.MatchSpec{
+		Type: config.MatchFunctionCall, Contract: vault.Hex(), Function: "transferOwnership(address)",
+	}}
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", StartBlock: "1"}, 0, map[string]*abi.ABI{"ownable.json": &ownable}, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	evs, err := scanner.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("expected the two transferOwnership calls, got %+v", evs)
+	}
+	ev := evs[0]
+	if ev.Name != "transferOwnership" || ev.TxHash != transferOwnership.Hash().Hex() || ev.Contract != vault.Hex() ||
+		ev.Args["newOwner"] != attacker || ev.Args["reverted"] != false || ev.Args["selector"] != hexutil.Encode(selector) {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	if ev := evs[1]; ev.Args["decode_error"] == nil || ev.Args["reverted"] != true {
+		t.Fatalf("expected undecodable reverted call to be reported, got %+v", ev)
+	}
+
+	// Without an ABI the arguments are named by position.
+	m, err := newFunctionMatcher(rule, nil)
+	if err != nil {
+		t.Fatalf("matcher: %v", err)
+	}
+	from, _ := types.Sender(signer, transferOwnership)
+	if ev, ok := m.matchTx(transferOwnership, from); !ok || ev.Args["arg0"] != attacker {
+		t.Fatalf("unexpected synthetic decode: %v %+v", ok, ev)
+	}
+}
+
+func TestScannerContractCreationRule(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	deployerKey, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+	deployer := crypto.PubkeyToAddress(deployerKey.PublicKey)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	deploy := func(key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, Gas: 100000, GasPrice: big.NewInt(1), Data: []byte{0x60, 0x00}})
+	}
+	deployed, failedDeploy, elsewhere := deploy(deployerKey, 4), deploy(deployerKey, 5), deploy(otherKey, 0)
+
+	headers := map[uint64]*types.Header{0: {Number: big.NewInt(0)}}
+	headers[1] = &types.Header{Number: big.NewInt(1), ParentHash: headers[0].Hash()}
+	fc := &blockClient{
+		fakeClient: fakeClient{headers: headers},
+		txs:        map[uint64][]*types.Transaction{1: {deployed, failedDeploy, elsewhere}},
+		failed:     map[common.Hash]bool{failedDeploy.Hash(): true},
+	}
+	rules := []config.Rule{
+		{ID: "ours", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchContractCreation, Addresses: []string{deployer.Hex()}}},
+		{ID: "any", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchContractCreation}},
+	}
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", StartBlock: "1"}, 0, nil, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	evs, err := scanner.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	byRule := map[string][]source.Event{}
+	for _, ev := range evs {
+		byRule[ev.RuleID] = append(byRule[ev.RuleID], ev)
+	}
+	if len(byRule["ours"]) != 1 || len(byRule["any"]) != 2 {
+		t.Fatalf("unexpected matches: %+v", evs)
+	}
+	ev := byRule["ours"][0]
+	want := crypto.CreateAddress(deployer, 4).Hex()
+	if ev.Name != ContractCreatedEvent || ev.TxHash != deployed.Hash().Hex() || ev.Contract != want ||
+		ev.Args["address"] != want || ev.Args["deployer"] != deployer.Hex() || ev.Args["init_code_size"] != 2 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+// revertClient replays every call as a revert with data.
+type revertClient struct {
+	blockClient
+	data []byte
+}
+
+func (c *revertClient) StorageAt(context.Context, common.Address, common.Hash, *big.Int) ([]byte, error) {
+	return nil, errors.New("unexpected storage read")
+}
+
+func (c *revertClient) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	return nil, revertError{hexutil.Encode(c.data)}
+}
+
+type revertError struct{ data string }
+
+func (e revertError) Error() string  { return "execution reverted" }
+func (e revertError) ErrorCode() int { return 3 }
+func (e revertError) ErrorData() any { return e.data }
+
+func TestScannerFailedTxRule(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	keeperKey, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+	keeper := crypto.PubkeyToAddress(keeperKey.PublicKey)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	registry := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	elsewhere := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	upkeep := crypto.Keccak256([]byte("performUpkeep(bytes)"))[:4]
+	send := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, Gas: 100000, GasPrice: big.NewInt(1), To: &to, Data: upkeep})
+	}
+	reverted, succeeded, strayed, unrelated := send(keeperKey, 0, registry), send(keeperKey, 1, registry), send(keeperKey, 2, elsewhere), send(otherKey, 0, elsewhere)
+
+	str, _ := abi.NewType("string", "", nil)
+	packed, _ := abi.Arguments{{Type: str}}.Pack("not eligible")
+	headers := map[uint64]*types.Header{0: {Number: big.NewInt(0)}}
+	headers[1] = &types.Header{Number: big.NewInt(1), ParentHash: headers[0].Hash()}
+	fc := &revertClient{
+		blockClient: blockClient{
+			fakeClient: fakeClient{headers: headers},
+			txs:        map[uint64][]*types.Transaction{1: {reverted, succeeded, strayed, unrelated}},
+			failed:     map[common.Hash]bool{reverted.Hash(): true, strayed.Hash(): true, unrelated.Hash(): true},
+		},
+		data: append(crypto.Keccak256([]byte("Error(string)"))[:4], packed...),
+	}
+	rules := []config.Rule{
+		{ID: "upkeep", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchFailedTx, Contract: registry.Hex()}},
+		{ID: "bot", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchFailedTx, Addresses: []string{keeper.Hex()}}},
+	}
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", StartBlock: "1"}, 0, nil, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	evs, err := scanner.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	byRule := map[string][]source.Event{}
+	for _, ev := range evs {
+		byRule[ev.RuleID] = append(byRule[ev.RuleID], ev)
+	}
+	if len(byRule["upkeep"]) != 1 || len(byRule["bot"]) != 2 {
+		t.Fatalf("unexpected matches: %+v", evs)
+	}
+	ev := byRule["upkeep"][0]
+	if ev.Name != FailedTxEvent || ev.TxHash != reverted.Hash().Hex() || ev.Contract != registry.Hex() ||
+		ev.Args["from"] != keeper.Hex() || ev.Args["selector"] != hexutil.Encode(upkeep) || ev.Args["revert_reason"] != "not eligible" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestScannerBlobTxRule(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	rollupKey, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+	rollup := crypto.PubkeyToAddress(rollupKey.PublicKey)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	inbox := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	post := func(key *ecdsa.PrivateKey, nonce uint64, blobs int) *types.Transaction {
+		hashes := make([]common.Hash, blobs)
+		for i := range hashes {
+			hashes[i] = common.Hash{0x01, byte(i)}
+		}
+		return types.MustSignNewTx(key, signer, &types.BlobTx{
+			ChainID:    uint256.NewInt(1),
+			Nonce:      nonce,
+			Gas:        21000,
+			GasTipCap:  uint256.NewInt(1),
+			GasFeeCap:  uint256.NewInt(1),
+			To:         inbox,
+			BlobFeeCap: uint256.NewInt(1_000_000_000),
+			BlobHashes: hashes,
+		})
+	}
+	big3, small, other := post(rollupKey, 0, 3), post(rollupKey, 1, 1), post(otherKey, 0, 6)
+	plain := types.MustSignNewTx(rollupKey, signer, &types.LegacyTx{Nonce: 2, Gas: 21000, GasPrice: big.NewInt(1), To: &inbox})
+
+	// An excess of 10M blob gas prices blob gas at 19 wei.
+	excess := uint64(10_000_000)
+	headers := map[uint64]*types.Header{0: {Number: big.NewInt(0)}}
+	headers[1] = &types.Header{Number: big.NewInt(1), ParentHash: headers[0].Hash(), ExcessBlobGas: &excess}
+	fc := &blockClient{
+		fakeClient: fakeClient{headers: headers},
+		txs:        map[uint64][]*types.Transaction{1: {big3, small, other, plain}},
+		failed:     map[common.Hash]bool{small.Hash(): true},
+	}
+	rules := []config.Rule{
+		{ID: "ours", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchBlobTx, Addresses: []string{rollup.Hex()}}},
+		{ID: "busy", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchBlobTx, MinBlobs: 3}},
+		{ID: "costly", Source: "evm_main", Match: config.MatchSpec{Type: config.MatchBlobTx, MinBlobFee: "10000000"}},
+	}
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", StartBlock: "1"}, 0, nil, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	evs, err := scanner.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	byRule := map[string][]source.Event{}
+	for _, ev := range evs {
+		byRule[ev.RuleID] = append(byRule[ev.RuleID], ev)
+	}
+	// A reverted post still paid for its blob. Three blobs of 131072 gas at
+	// 19 wei cost 7471104 wei, under the 10M floor; six cost 14942208.
+	if len(byRule["ours"]) != 2 || len(byRule["busy"]) != 2 || len(byRule["costly"]) != 1 {
+		t.Fatalf("unexpected matches: %+v", evs)
+	}
+	ev := byRule["costly"][0]
+	if ev.Name != BlobTxEvent || ev.TxHash != other.Hash().Hex() || ev.Args["blobs"] != uint64(6) ||
+		ev.Args["blob_gas"] != uint64(6*131072) || ev.Args["blob_fee"].(*big.Int).Int64() != 14942208 || ev.Args["to"] != inbox.Hex() {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestScannerViewRules(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	feed := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	holder := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	word := func(n int64) []byte { return math.U256Bytes(big.NewInt(n)) }
+
+	headers := map[uint64]*types.Header{0: {Number: big.NewInt(0)}}
+	for n := uint64(1); n <= 3; n++ {
+		headers[n] = &types.Header{Number: new(big.Int).SetUint64(n), ParentHash: headers[n-1].Hash()}
+	}
+	fc := &callClient{
+		fakeClient: fakeClient{headers: headers},
+		values: map[common.Address][]byte{
+			token: word(500),
+			feed:  bytes.Join([][]byte{word(7), word(-3), word(1), word(2), word(7)}, nil),
+		},
+		deployed: true,
+	}
+	rules := []config.Rule{
+		{ID: "reserves", Source: "evm_main", Match: config.MatchSpec{
+			Type: config.MatchView, Contract: token.Hex(), Function: "balanceOf(address)", Inputs: []string{holder.Hex()}, Returns: "uint256",
+		}},
+		{ID: "price", Source: "evm_main", Match: config.MatchSpec{
+			Type: config.MatchView, Contract: feed.Hex(), Function: "latestRoundData()", Returns: "(uint80,int256,uint256,uint256,uint80)", Interval: "5m",
+		}},
+	}
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", StartBlock: "1"}, 0, nil, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	want := append(crypto.Keccak256([]byte("balanceOf(address)"))[:4], common.LeftPadBytes(holder.Bytes(), 32)...)
+	if !bytes.Equal(scanner.views[0].data, want) {
+		t.Fatalf("unexpected calldata %x", scanner.views[0].data)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	scanner.nowFunc = func() time.Time { return now }
+
+	evs, err := scanner.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process block 1: %v", err)
+	}
+	if len(evs) != 2 || fc.calls != 1 {
+		t.Fatalf("expected both views read in one multicall, got %d events and %d calls", len(evs), fc.calls)
+	}
+	bal, price := evs[0], evs[1]
+	if bal.Name != "balanceOf" || bal.Height != 1 || bal.Args["value"].(*big.Int).Int64() != 500 || bal.Args["function"] != "balanceOf(address)" {
+		t.Fatalf("unexpected balance event: %+v", bal)
+	}
+	if price.Args["out1"].(*big.Int).Int64() != -3 || price.Args["out0"].(*big.Int).Int64() != 7 {
+		t.Fatalf("unexpected price event: %+v", price)
+	}
+
+	// Nothing is due on the next block; after a minute only the 1m rule is.
+	if evs, err = scanner.ProcessNext(ctx); err != nil || len(evs) != 0 {
+		t.Fatalf("expected no reads before the interval, got %d (%v)", len(evs), err)
+	}
+	now = now.Add(time.Minute)
+	if evs, err = scanner.ProcessNext(ctx); err != nil || len(evs) != 1 || evs[0].RuleID != "reserves" {
+		t.Fatalf("expected only the reserves read, got %+v (%v)", evs, err)
+	}
+}
+
+// finalityClient reports finalized as the finalized block, and the highest
+// header as latest.
+type finalityClient struct {
+	fakeClient
+	finalized uint64
+}
+
+func (c *finalityClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number != nil && number.Int64() == int64(rpc.FinalizedBlockNumber) {
+		return c.headers[c.finalized], nil
+	}
+	return c.fakeClient.HeaderByNumber(ctx, number)
+}
+
+func TestScannerStopsAtFinalizedBlock(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	headers := map[uint64]*types.Header{0: {Number: big.NewInt(0)}}
+	for n := uint64(1); n <= 10; n++ {
+		headers[n] = &types.Header{Number: new(big.Int).SetUint64(n), ParentHash: headers[n-1].Hash()}
+	}
+	fc := &finalityClient{fakeClient: fakeClient{headers: headers}, finalized: 2}
+	scanner, err := NewScanner(fc, store, config.Source{ID: "evm_main", Type: "evm", StartBlock: "1"}, 0, nil, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	scanner.SetFinality(config.ConfirmFinalized)
+	scanner.tipTTL = 0
+	for i := 0; i < 4; i++ {
+		if _, err := scanner.ProcessNext(ctx); err != nil {
+			t.Fatalf("process: %v", err)
+		}
+	}
+	if height, _, _, _ := store.GetCursor(ctx, "evm_main"); height != 2 || scanner.Tip() != 2 {
+		t.Fatalf("expected to stop at the finalized block 2, got cursor %d tip %d", height, scanner.Tip())
+	}
+	fc.finalized = 3
+	if _, err := scanner.ProcessNext(ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if height, _, _, _ := store.GetCursor(ctx, "evm_main"); height != 3 {
+		t.Fatalf("expected block 3 once finalized, got %d", height)
+	}
+}
@@ -0,0 +1,159 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SequencerLagEvent is the event name of sequencer_lag matches.
+const SequencerLagEvent = "sequencer_lag"
+
+// Lag states recorded for a sequencer_lag rule.
+const (
+	lagOK     = "ok"
+	lagBehind = "behind"
+)
+
+var (
+	latestBlockNumberSelector = crypto.Keccak256([]byte("latestBlockNumber()"))[:4]
+	batchCountSelector        = crypto.Keccak256([]byte("batchCount()"))[:4]
+)
+
+var errNoL1 = errors.New("no l1 client for sequencer_lag rules")
+
+// lagWatcher measures how far a rollup's posts to L1 trail the L2 block
+// being scanned. An optimism L2OutputOracle names the last L2 block it holds
+// an output for, and the lag is the time from that block to the scanned one.
+// An arbitrum SequencerInbox only counts batches, so the lag is the L2 time
+// since the count last changed; it starts over when the scanner does. The
+// rule fires once when the lag passes max_lag and re-arms once it is back
+// under.
+type lagWatcher struct {
+	rule     config.Rule
+	stack    string
+	contract common.Address
+	maxLag   time.Duration
+	every    time.Duration
+	lastRead time.Time
+	batches  *big.Int // arbitrum: batch count last seen
+	postedAt uint64   // arbitrum: L2 time the count was first seen
+}
+
+func newLagWatcher(rule config.Rule, l2 *config.L2) (*lagWatcher, error) {
+	maxLag, err := time.ParseDuration(rule.Match.MaxLag)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: invalid max_lag: %w", rule.ID, err)
+	}
+	return &lagWatcher{
+		rule:     rule,
+		stack:    l2.Stack,
+		contract: common.HexToAddress(l2.Contract),
+		maxLag:   maxLag,
+		every:    rule.Match.PollInterval(),
+	}, nil
+}
+
+// measure returns the lag at header and the args describing it.
+func (w *lagWatcher) measure(ctx context.Context, l1 ethereum.ContractCaller, l2 BlockClient, header *types.Header) (time.Duration, map[string]any, error) {
+	selector := latestBlockNumberSelector
+	if w.stack == config.L2Arbitrum {
+		selector = batchCountSelector
+	}
+	out, err := l1.CallContract(ctx, ethereum.CallMsg{To: &w.contract, Data: selector}, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("l1 call: %w", err)
+	}
+	if len(out) != 32 {
+		return 0, nil, fmt.Errorf("l1 call: unexpected %d-byte result from %s", len(out), w.contract.Hex())
+	}
+	n := new(big.Int).SetBytes(out)
+	height := header.Number.Uint64()
+	args := map[string]any{"stack": w.stack, "l2_height": height}
+
+	if w.stack == config.L2Arbitrum {
+		if w.batches == nil || n.Cmp(w.batches) != 0 {
+			w.batches, w.postedAt = n, header.Time
+		}
+		args["batch_count"] = n
+		return time.Duration(header.Time-w.postedAt) * time.Second, args, nil
+	}
+
+	args["posted_height"] = n
+	if !n.IsUint64() || n.Uint64() >= height {
+		args["lag_blocks"] = uint64(0)
+		return 0, args, nil
+	}
+	posted, err := l2.HeaderByNumber(ctx, n)
+	if err != nil {
+		return 0, nil, fmt.Errorf("header %d: %w", n, err)
+	}
+	args["lag_blocks"] = height - n.Uint64()
+	var lag time.Duration
+	if header.Time > posted.Time {
+		lag = time.Duration(header.Time-posted.Time) * time.Second
+	}
+	return lag, args, nil
+}
+
+// checkLag runs the sequencer_lag rules whose interval has passed against
+// header, reading the rollup's L1 contract through the L1 source's client.
+func (s *Scanner) checkLag(ctx context.Context, header *types.Header, emit func(source.Event) error) error {
+	if len(s.lags) == 0 {
+		return nil
+	}
+	if s.l1 == nil {
+		return errNoL1
+	}
+	l1, ok := s.l1.(ethereum.ContractCaller)
+	if !ok {
+		return errNoEthCall
+	}
+	now := s.nowFunc()
+	for _, w := range s.lags {
+		if now.Sub(w.lastRead) < w.every {
+			continue
+		}
+		lag, args, err := w.measure(ctx, l1, s.client, header)
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", w.rule.ID, err)
+		}
+		w.lastRead = now
+		state := lagOK
+		if lag > w.maxLag {
+			state = lagBehind
+		}
+		prev, _, err := s.store.GetStateValue(ctx, s.source.ID, w.rule.ID)
+		if err != nil {
+			return err
+		}
+		if state == lagBehind && prev.Value != lagBehind {
+			args["lag_seconds"] = uint64(lag / time.Second)
+			args["max_lag_seconds"] = uint64(w.maxLag / time.Second)
+			if err := emit(source.Event{
+				RuleID:   w.rule.ID,
+				Contract: w.contract.Hex(),
+				Name:     SequencerLagEvent,
+				Args:     args,
+			}); err != nil {
+				return err
+			}
+		}
+		if state != prev.Value {
+			if err := s.store.UpsertStateValue(ctx, storage.StateValue{SourceID: s.source.ID, RuleID: w.rule.ID, Value: state, Height: header.Number.Uint64()}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
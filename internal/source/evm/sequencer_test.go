@@ -0,0 +1,120 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestScannerSequencerLag(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	oracle := common.HexToAddress("0x00000000000000000000000000000000000000dd")
+	word := func(n int64) []byte { return math.U256Bytes(big.NewInt(n)) }
+
+	// Two-second L2 blocks.
+	headers := map[uint64]*types.Header{}
+	for n := uint64(1); n <= 100; n++ {
+		headers[n] = &types.Header{Number: new(big.Int).SetUint64(n), Time: 1_000 + 2*n}
+	}
+	l1 := &callClient{values: map[common.Address][]byte{}}
+	src := config.Source{ID: "op", Type: "evm", L2: &config.L2{Stack: config.L2Optimism, L1Source: "mainnet", Contract: oracle.Hex()}}
+	rule := config.Rule{ID: "posting_lag", Source: "op", Match: config.MatchSpec{Type: config.MatchSequencerLag, MaxLag: "1m"}}
+	scanner, err := NewScanner(&fakeClient{headers: headers}, store, src, 0, nil, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	if err := scanner.checkLag(ctx, headers[50], func(source.Event) error { return nil }); err != errNoL1 {
+		t.Fatalf("expected errNoL1, got %v", err)
+	}
+	scanner.SetL1Client(l1)
+	now := time.Unix(1_700_000_000, 0)
+	scanner.nowFunc = func() time.Time { return now }
+
+	steps := []struct {
+		head, posted int64
+		want         bool
+	}{
+		{50, 40, false}, // 20s behind
+		{80, 40, true},  // 80s behind
+		{90, 40, false}, // still behind, already alerted
+		{90, 85, false}, // caught up, re-armed
+		{100, 40, true},
+	}
+	for i, st := range steps {
+		l1.values[oracle] = word(st.posted)
+		var got []source.Event
+		if err := scanner.checkLag(ctx, headers[uint64(st.head)], func(ev source.Event) error {
+			got = append(got, ev)
+			return nil
+		}); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if (len(got) == 1) != st.want || len(got) > 1 {
+			t.Fatalf("step %d: unexpected events %+v", i, got)
+		}
+		if st.want && (got[0].Name != SequencerLagEvent || got[0].Args["lag_blocks"] != uint64(st.head-st.posted) ||
+			got[0].Args["lag_seconds"] != uint64(2*(st.head-st.posted))) {
+			t.Fatalf("step %d: unexpected event %+v", i, got[0])
+		}
+		now = now.Add(time.Minute)
+	}
+
+	// Within the interval the L1 contract is not read again.
+	calls := l1.calls
+	now = now.Add(-30 * time.Second)
+	if err := scanner.checkLag(ctx, headers[100], func(source.Event) error { return nil }); err != nil || l1.calls != calls {
+		t.Fatalf("expected no read within the interval: err=%v calls=%d", err, l1.calls-calls)
+	}
+}
+
+func TestScannerSequencerLagArbitrum(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	inbox := common.HexToAddress("0x00000000000000000000000000000000000000ee")
+	l1 := &callClient{values: map[common.Address][]byte{}}
+	src := config.Source{ID: "arb", Type: "evm", L2: &config.L2{Stack: config.L2Arbitrum, L1Source: "mainnet", Contract: inbox.Hex()}}
+	rule := config.Rule{ID: "posting_lag", Source: "arb", Match: config.MatchSpec{Type: config.MatchSequencerLag, MaxLag: "10m", Interval: "1s"}}
+	scanner, err := NewScanner(&fakeClient{}, store, src, 0, nil, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	scanner.SetL1Client(l1)
+	now := time.Unix(1_700_000_000, 0)
+	scanner.nowFunc = func() time.Time { return now }
+
+	var got []source.Event
+	emit := func(ev source.Event) error {
+		got = append(got, ev)
+		return nil
+	}
+	// The batch count stays at 7 for over ten minutes of L2 time.
+	for i, at := range []uint64{0, 300, 601, 700} {
+		l1.values[inbox] = math.U256Bytes(big.NewInt(7))
+		if err := scanner.checkLag(ctx, &types.Header{Number: big.NewInt(int64(i + 1)), Time: 1_000 + at}, emit); err != nil {
+			t.Fatalf("check %d: %v", i, err)
+		}
+		now = now.Add(time.Second)
+	}
+	if len(got) != 1 || got[0].Args["lag_seconds"] != uint64(601) || got[0].Args["batch_count"].(*big.Int).Int64() != 7 {
+		t.Fatalf("unexpected events %+v", got)
+	}
+	if v, _, _ := store.GetStateValue(ctx, "arb", "posting_lag"); v.Value != lagBehind {
+		t.Fatalf("expected behind state, got %q", v.Value)
+	}
+	// A new batch resets the lag.
+	l1.values[inbox] = math.U256Bytes(big.NewInt(8))
+	if err := scanner.checkLag(ctx, &types.Header{Number: big.NewInt(5), Time: 1_800}, emit); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if v, _, _ := store.GetStateValue(ctx, "arb", "posting_lag"); v.Value != lagOK || len(got) != 1 {
+		t.Fatalf("expected re-armed state, got %q and %d events", v.Value, len(got))
+	}
+}
@@ -0,0 +1,147 @@
+package evm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// standardABIJSON declares the events of the standard token interfaces, so
+// rules on tokens decode without ABI files. Argument names are the arg keys
+// rules see, so they follow the snake_case used elsewhere.
+var standardABIJSON = map[string]string{
+	config.StandardERC20: `[
+		{"type":"event","name":"Transfer","inputs":[
+			{"name":"from","type":"address","indexed":true},
+			{"name":"to","type":"address","indexed":true},
+			{"name":"value","type":"uint256","indexed":false}
+		]},
+		{"type":"event","name":"Approval","inputs":[
+			{"name":"owner","type":"address","indexed":true},
+			{"name":"spender","type":"address","indexed":true},
+			{"name":"value","type":"uint256","indexed":false}
+		]}
+	]`,
+	config.StandardERC721: `[
+		{"type":"event","name":"Transfer","inputs":[
+			{"name":"from","type":"address","indexed":true},
+			{"name":"to","type":"address","indexed":true},
+			{"name":"token_id","type":"uint256","indexed":true}
+		]},
+		{"type":"event","name":"Approval","inputs":[
+			{"name":"owner","type":"address","indexed":true},
+			{"name":"approved","type":"address","indexed":true},
+			{"name":"token_id","type":"uint256","indexed":true}
+		]},
+		{"type":"event","name":"ApprovalForAll","inputs":[
+			{"name":"owner","type":"address","indexed":true},
+			{"name":"operator","type":"address","indexed":true},
+			{"name":"approved","type":"bool","indexed":false}
+		]}
+	]`,
+	config.StandardERC1155: `[
+		{"type":"event","name":"TransferSingle","inputs":[
+			{"name":"operator","type":"address","indexed":true},
+			{"name":"from","type":"address","indexed":true},
+			{"name":"to","type":"address","indexed":true},
+			{"name":"token_id","type":"uint256","indexed":false},
+			{"name":"value","type":"uint256","indexed":false}
+		]},
+		{"type":"event","name":"TransferBatch","inputs":[
+			{"name":"operator","type":"address","indexed":true},
+			{"name":"from","type":"address","indexed":true},
+			{"name":"to","type":"address","indexed":true},
+			{"name":"token_ids","type":"uint256[]","indexed":false},
+			{"name":"values","type":"uint256[]","indexed":false}
+		]},
+		{"type":"event","name":"ApprovalForAll","inputs":[
+			{"name":"account","type":"address","indexed":true},
+			{"name":"operator","type":"address","indexed":true},
+			{"name":"approved","type":"bool","indexed":false}
+		]},
+		{"type":"event","name":"URI","inputs":[
+			{"name":"value","type":"string","indexed":false},
+			{"name":"token_id","type":"uint256","indexed":true}
+		]}
+	]`,
+}
+
+// standardABIs holds the parsed standardABIJSON.
+var standardABIs = func() map[string]abi.ABI {
+	out := map[string]abi.ABI{}
+	for name, raw := range standardABIJSON {
+		a, err := abi.JSON(strings.NewReader(raw))
+		if err != nil {
+			panic(err)
+		}
+		out[name] = a
+	}
+	return out
+}()
+
+// standardVariants returns the standard events with the given topic0, one
+// per indexed layout, ERC-20 first. ERC-20 and ERC-721 share the Transfer and
+// Approval signatures and only differ in whether the last argument is
+// indexed, so a log is decoded with the variant its topic count fits.
+func standardVariants(topic0 common.Hash) []abi.Event {
+	var out []abi.Event
+	seen := map[string]bool{}
+	for _, name := range config.Standards {
+		for _, ev := range standardABIs[name].Events {
+			if ev.ID != topic0 || seen[indexedLayout(ev)] {
+				continue
+			}
+			seen[indexedLayout(ev)] = true
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// fitsTopics reports whether a log with n topics after topic0 was emitted
+// with ev's indexed layout.
+func fitsTopics(ev abi.Event, n int) bool {
+	indexed, _ := splitIndexed(ev.Inputs)
+	return len(indexed) == n
+}
+
+// standardEvents resolves a match.standard rule's events, given as names
+// such as "Approval" or full signatures, against the standard's built-in
+// definitions. No events means all of them.
+func standardEvents(standard string, entries []string) ([]abi.Event, error) {
+	std, ok := standardABIs[standard]
+	if !ok {
+		return nil, fmt.Errorf("unknown standard %s", standard)
+	}
+	if len(entries) == 0 {
+		names := make([]string, 0, len(std.Events))
+		for name := range std.Events {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		entries = names
+	}
+	var out []abi.Event
+	for _, entry := range entries {
+		if !strings.Contains(entry, "(") {
+			ev, ok := std.Events[entry]
+			if !ok {
+				return nil, fmt.Errorf("%s has no %s event", standard, entry)
+			}
+			out = append(out, ev)
+			continue
+		}
+		topic0 := crypto.Keccak256Hash([]byte(strings.ReplaceAll(entry, " ", "")))
+		ev, err := std.EventByID(topic0)
+		if err != nil {
+			return nil, fmt.Errorf("%s has no %s event", standard, entry)
+		}
+		out = append(out, *ev)
+	}
+	return out, nil
+}
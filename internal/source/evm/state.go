@@ -0,0 +1,191 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Match types that poll contract state instead of matching logs.
+const (
+	MatchStorage = "storage"
+	MatchCall    = "call"
+)
+
+// StateChangedEvent is the event name of storage and call rule matches.
+const StateChangedEvent = "state_changed"
+
+var errNoState = errors.New("client does not read contract state")
+
+// StateReader is implemented by clients that can read contract storage and
+// call view functions at a given block.
+type StateReader interface {
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+	ethereum.ContractCaller
+}
+
+// stateWatcher reads one storage slot or no-argument view function of a
+// contract. Admin and owner slots are often changed without an event, so
+// this catches what log rules cannot.
+type stateWatcher struct {
+	rule     config.Rule
+	contract common.Address
+	slot     common.Hash
+	selector []byte // set for call rules
+	every    uint64
+}
+
+func isStateMatchType(t string) bool {
+	switch strings.ToLower(t) {
+	case MatchStorage, MatchCall:
+		return true
+	}
+	return false
+}
+
+func newStateWatcher(rule config.Rule) (*stateWatcher, error) {
+	w := &stateWatcher{
+		rule:     rule,
+		contract: common.HexToAddress(rule.Match.Contract),
+		every:    max(rule.Match.EveryBlocks, 1),
+	}
+	if strings.EqualFold(rule.Match.Type, MatchCall) {
+		w.selector = crypto.Keccak256([]byte(rule.Match.Function))[:4]
+		return w, nil
+	}
+	slot, err := config.ParseSlot(rule.Match.Slot)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+	}
+	w.slot = common.BigToHash(slot)
+	return w, nil
+}
+
+// read returns the watched value at height as hex.
+func (w *stateWatcher) read(ctx context.Context, c StateReader, height uint64) (string, error) {
+	at := new(big.Int).SetUint64(height)
+	var (
+		out []byte
+		err error
+	)
+	if w.selector != nil {
+		out, err = c.CallContract(ctx, ethereum.CallMsg{To: &w.contract, Data: w.selector}, at)
+	} else {
+		out, err = c.StorageAt(ctx, w.contract, w.slot, at)
+	}
+	if err != nil {
+		return "", fmt.Errorf("rule %s: read state: %w", w.rule.ID, err)
+	}
+	return hexutil.Encode(out), nil
+}
+
+// event describes a change from before to after.
+func (w *stateWatcher) event(before, after string) source.Event {
+	args := map[string]any{"old": before, "new": after}
+	if w.selector != nil {
+		args["function"] = w.rule.Match.Function
+	} else {
+		args["slot"] = w.slot.Hex()
+	}
+	return source.Event{
+		RuleID:   w.rule.ID,
+		Contract: w.contract.Hex(),
+		Name:     StateChangedEvent,
+		Args:     args,
+	}
+}
+
+// checkState reads each due state watcher at height and emits an event for
+// every value that differs from the last one recorded. The first read of a
+// rule only records a baseline.
+func (s *Scanner) checkState(ctx context.Context, height uint64, emit func(source.Event) error) error {
+	if len(s.states) == 0 {
+		return nil
+	}
+	reader, ok := s.client.(StateReader)
+	if !ok {
+		return errNoState
+	}
+	var due []*stateWatcher
+	for _, w := range s.states {
+		if height%w.every == 0 {
+			due = append(due, w)
+		}
+	}
+	vals, err := s.readState(ctx, reader, due, height)
+	if err != nil {
+		return err
+	}
+	for i, w := range due {
+		prev, seen, err := s.store.GetStateValue(ctx, s.source.ID, w.rule.ID)
+		if err != nil {
+			return err
+		}
+		if seen && prev.Value == vals[i] {
+			continue
+		}
+		if seen {
+			if err := emit(w.event(prev.Value, vals[i])); err != nil {
+				return err
+			}
+		}
+		if err := s.store.UpsertStateValue(ctx, storage.StateValue{SourceID: s.source.ID, RuleID: w.rule.ID, Value: vals[i], Height: height}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readState reads the watchers' values at height. When several call rules
+// are due they share one Multicall3 request; storage slots and chains
+// without Multicall3 are read one call at a time.
+func (s *Scanner) readState(ctx context.Context, c StateReader, due []*stateWatcher, height uint64) ([]string, error) {
+	vals := make([]string, len(due))
+	var batch []int
+	for i, w := range due {
+		if w.selector != nil {
+			batch = append(batch, i)
+		}
+	}
+	if len(batch) > 1 && !s.multicallOff {
+		calls := make([]call3, len(batch))
+		for j, i := range batch {
+			calls[j] = call3{Target: due[i].contract, AllowFailure: true, CallData: due[i].selector}
+		}
+		results, err := multicall(ctx, c, s.multicall, calls, new(big.Int).SetUint64(height))
+		switch {
+		case errors.Is(err, errNoMulticall):
+			s.multicallOff = true
+		case err != nil:
+			return nil, err
+		default:
+			for j, i := range batch {
+				if !results[j].Success {
+					return nil, fmt.Errorf("rule %s: read state: call reverted", due[i].rule.ID)
+				}
+				vals[i] = hexutil.Encode(results[j].ReturnData)
+			}
+		}
+	}
+	for i, w := range due {
+		if vals[i] != "" {
+			continue
+		}
+		val, err := w.read(ctx, c, height)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = val
+	}
+	return vals, nil
+}
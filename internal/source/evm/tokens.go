@@ -0,0 +1,134 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// symbol() and decimals() selectors shared by ERC-20 and most ERC-721s.
+	selSymbol   = common.FromHex("0x95d89b41")
+	selDecimals = common.FromHex("0x313ce567")
+
+	stringArgs = func() abi.Arguments {
+		t, _ := abi.NewType("string", "", nil)
+		return abi.Arguments{{Type: t}}
+	}()
+)
+
+// TokenResolver looks up token symbols and decimals with eth_call. Results
+// are kept in memory and in the store, so each contract is queried once.
+type TokenResolver struct {
+	caller   ethereum.ContractCaller
+	store    *storage.Store
+	sourceID string
+
+	mu  sync.Mutex
+	mem map[common.Address]storage.TokenMeta
+}
+
+// NewTokenResolver builds a resolver for one source.
+func NewTokenResolver(caller ethereum.ContractCaller, store *storage.Store, sourceID string) *TokenResolver {
+	return &TokenResolver{
+		caller:   caller,
+		store:    store,
+		sourceID: sourceID,
+		mem:      map[common.Address]storage.TokenMeta{},
+	}
+}
+
+// Resolve returns the token metadata of addr. Contracts that revert on
+// symbol() are cached with an empty symbol; other RPC errors are returned
+// and the lookup is retried next time.
+func (t *TokenResolver) Resolve(ctx context.Context, addr common.Address) (storage.TokenMeta, error) {
+	t.mu.Lock()
+	meta, ok := t.mem[addr]
+	t.mu.Unlock()
+	if ok {
+		return meta, nil
+	}
+
+	key := strings.ToLower(addr.Hex())
+	meta, ok, err := t.store.GetToken(ctx, t.sourceID, key)
+	if err != nil {
+		return meta, err
+	}
+	if !ok {
+		if meta, err = t.fetch(ctx, addr); err != nil {
+			return meta, err
+		}
+		if err := t.store.UpsertToken(ctx, meta); err != nil {
+			return meta, err
+		}
+	}
+	t.mu.Lock()
+	t.mem[addr] = meta
+	t.mu.Unlock()
+	return meta, nil
+}
+
+func (t *TokenResolver) fetch(ctx context.Context, addr common.Address) (storage.TokenMeta, error) {
+	meta := storage.TokenMeta{SourceID: t.sourceID, Address: strings.ToLower(addr.Hex())}
+	out, err := t.caller.CallContract(ctx, ethereum.CallMsg{To: &addr, Data: selSymbol}, nil)
+	if err != nil {
+		if isRevert(err) {
+			return meta, nil
+		}
+		return meta, err
+	}
+	meta.Symbol = decodeSymbol(out)
+	if meta.Symbol == "" {
+		return meta, nil
+	}
+	// NFTs have no decimals(); a revert there just means 0.
+	out, err = t.caller.CallContract(ctx, ethereum.CallMsg{To: &addr, Data: selDecimals}, nil)
+	if err != nil && !isRevert(err) {
+		return meta, err
+	}
+	if len(out) >= 32 {
+		meta.Decimals = int(new(big.Int).SetBytes(out[:32]).Int64())
+	}
+	return meta, nil
+}
+
+// annotate adds token_symbol and token_decimals to a transfer event.
+func (t *TokenResolver) annotate(ctx context.Context, ev *source.Event) {
+	if ev.Name != "Transfer" || ev.Args == nil {
+		return
+	}
+	meta, err := t.Resolve(ctx, common.HexToAddress(ev.Contract))
+	if err != nil || meta.Symbol == "" {
+		return
+	}
+	ev.Args["token_symbol"] = meta.Symbol
+	ev.Args["token_decimals"] = meta.Decimals
+}
+
+// decodeSymbol handles the ABI string return of most tokens and the bytes32
+// return of older ones such as MKR.
+func decodeSymbol(out []byte) string {
+	if len(out) >= 64 {
+		if vals, err := stringArgs.Unpack(out); err == nil {
+			if s, ok := vals[0].(string); ok {
+				return s
+			}
+		}
+	}
+	if len(out) == 32 {
+		return string(bytes.TrimRight(out, "\x00"))
+	}
+	return ""
+}
+
+func isRevert(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "execution reverted")
+}
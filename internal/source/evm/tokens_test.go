@@ -0,0 +1,78 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+type fakeCaller struct {
+	symbols map[common.Address][]byte
+	calls   int
+}
+
+func (f *fakeCaller) CallContract(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	f.calls++
+	sym, ok := f.symbols[*msg.To]
+	if !ok {
+		return nil, errors.New("execution reverted")
+	}
+	if bytes.Equal(msg.Data, selDecimals) {
+		return common.LeftPadBytes([]byte{6}, 32), nil
+	}
+	return sym, nil
+}
+
+func TestTokenResolverCachesMetadata(t *testing.T) {
+	store := newTestStore(t)
+	usdc := common.HexToAddress("0xA0b86991c6218b36c1d19d4a2e9eb0ce3606eb48")
+	mkr := common.HexToAddress("0x9f8F72aA9304c8B593d555F12eF6589cC3A579A2")
+	encoded, err := stringArgs.Pack("USDC")
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+	caller := &fakeCaller{symbols: map[common.Address][]byte{
+		usdc: encoded,
+		mkr:  common.RightPadBytes([]byte("MKR"), 32),
+	}}
+	ctx := context.Background()
+
+	r := NewTokenResolver(caller, store, "evm_main")
+	meta, err := r.Resolve(ctx, usdc)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if meta.Symbol != "USDC" || meta.Decimals != 6 {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+	if meta, _ := r.Resolve(ctx, mkr); meta.Symbol != "MKR" {
+		t.Fatalf("expected bytes32 symbol, got %+v", meta)
+	}
+	if meta, err := r.Resolve(ctx, common.HexToAddress("0x01")); err != nil || meta.Symbol != "" {
+		t.Fatalf("expected non-token to resolve empty, got %+v, %v", meta, err)
+	}
+
+	// A fresh resolver on the same store does not go back to the node.
+	calls := caller.calls
+	again := NewTokenResolver(caller, store, "evm_main")
+	if meta, _ := again.Resolve(ctx, usdc); meta.Symbol != "USDC" || caller.calls != calls {
+		t.Fatalf("expected stored metadata, got %+v after %d new calls", meta, caller.calls-calls)
+	}
+	_, _ = again.Resolve(ctx, common.HexToAddress("0x01"))
+	if caller.calls != calls {
+		t.Fatalf("expected non-token to stay cached")
+	}
+
+	ev := source.Event{Name: "Transfer", Contract: usdc.Hex(), Args: map[string]any{}}
+	again.annotate(ctx, &ev)
+	if ev.Args["token_symbol"] != "USDC" || ev.Args["token_decimals"] != 6 {
+		t.Fatalf("unexpected args: %v", ev.Args)
+	}
+}
@@ -0,0 +1,167 @@
+package evm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// topicFilter holds, for each topic after topic0, the values a log must have
+// there. Empty positions accept anything.
+type topicFilter [3][]common.Hash
+
+// accepts reports whether a log's topics pass the filter.
+func (f *topicFilter) accepts(topics []common.Hash) bool {
+	for i, want := range f {
+		if len(want) > 0 && (len(topics) <= i+1 || !slices.Contains(want, topics[i+1])) {
+			return false
+		}
+	}
+	return true
+}
+
+// newTopicFilter resolves match.topics against the events decoding one
+// topic0: several when standard variants index differently.
+func newTopicFilter(topics map[string]config.TopicValues, events []abi.Event) (*topicFilter, error) {
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	f := &topicFilter{}
+	for _, name := range names {
+		pos, typ, err := indexedPosition(name, events)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range topics[name] {
+			h, err := encodeTopic(typ, v)
+			if err != nil {
+				return nil, fmt.Errorf("match.topics.%s: %w", name, err)
+			}
+			f[pos] = append(f[pos], h)
+		}
+	}
+	return f, nil
+}
+
+// indexedPosition finds which topic after topic0 holds the named argument.
+func indexedPosition(name string, events []abi.Event) (int, abi.Type, error) {
+	if len(events) == 0 {
+		return 0, abi.Type{}, errors.New("match.topics needs an ABI that says which arguments are indexed")
+	}
+	pos := -1
+	var typ abi.Type
+	for _, ev := range events {
+		i := 0
+		for _, in := range ev.Inputs {
+			if !in.Indexed {
+				continue
+			}
+			if in.Name == name {
+				if pos >= 0 && (pos != i || typ.String() != in.Type.String()) {
+					return 0, abi.Type{}, fmt.Errorf("match.topics.%s: %s indexes it differently in its variants", name, ev.Name)
+				}
+				pos, typ = i, in.Type
+			}
+			i++
+		}
+	}
+	if pos < 0 {
+		return 0, abi.Type{}, fmt.Errorf("match.topics.%s: not an indexed argument of %s", name, events[0].Name)
+	}
+	return pos, typ, nil
+}
+
+// encodeTopic encodes v as an indexed argument of type t. Strings and bytes
+// are indexed by their hash.
+func encodeTopic(t abi.Type, v string) (common.Hash, error) {
+	switch t.T {
+	case abi.AddressTy:
+		if !common.IsHexAddress(v) {
+			return common.Hash{}, fmt.Errorf("invalid address %q", v)
+		}
+		return common.BytesToHash(common.HexToAddress(v).Bytes()), nil
+	case abi.UintTy, abi.IntTy:
+		n, ok := new(big.Int).SetString(v, 0)
+		if !ok {
+			return common.Hash{}, fmt.Errorf("invalid %s %q", t, v)
+		}
+		return common.BytesToHash(math.U256Bytes(n)), nil
+	case abi.BoolTy:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("invalid bool %q", v)
+		}
+		if b {
+			return common.BigToHash(big.NewInt(1)), nil
+		}
+		return common.Hash{}, nil
+	case abi.FixedBytesTy:
+		b, err := hexutil.Decode(v)
+		if err != nil || len(b) > t.Size {
+			return common.Hash{}, fmt.Errorf("invalid %s %q", t, v)
+		}
+		return common.BytesToHash(common.RightPadBytes(b, 32)), nil
+	case abi.StringTy:
+		return crypto.Keccak256Hash([]byte(v)), nil
+	case abi.BytesTy:
+		b, err := hexutil.Decode(v)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("invalid bytes %q", v)
+		}
+		return crypto.Keccak256Hash(b), nil
+	}
+	return common.Hash{}, fmt.Errorf("cannot filter on %s arguments", t)
+}
+
+// queryTopics returns FilterQuery topics that keep the node from returning
+// logs no matcher wants. A position is only restricted when every event of
+// every matcher filters it, and then to the union of their values; matchers
+// still check their own filters. Anonymous events, whose topics are all
+// arguments, leave the query unrestricted.
+func queryTopics(matchers []*RuleMatcher) [][]common.Hash {
+	if len(matchers) == 0 {
+		return nil
+	}
+	var union topicFilter
+	restricted := [3]bool{true, true, true}
+	for _, m := range matchers {
+		if len(m.anonymous) > 0 {
+			return nil
+		}
+		for _, le := range m.events {
+			for i := range restricted {
+				if le.filter == nil || len(le.filter[i]) == 0 {
+					restricted[i] = false
+					continue
+				}
+				union[i] = append(union[i], le.filter[i]...)
+			}
+		}
+	}
+	var out [][]common.Hash
+	for i, ok := range restricted {
+		if !ok {
+			continue
+		}
+		for len(out) <= i {
+			out = append(out, nil)
+		}
+		values := union[i]
+		sort.Slice(values, func(a, b int) bool { return bytes.Compare(values[a][:], values[b][:]) < 0 })
+		out = append(out, slices.Compact(values))
+	}
+	return out
+}
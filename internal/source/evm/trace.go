@@ -0,0 +1,323 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// InternalCallEvent is the event name of internal_call matches.
+const InternalCallEvent = "internal_call"
+
+var errNoTraces = errors.New("client does not trace blocks")
+
+// TraceFrame is one call in a transaction's call tree.
+type TraceFrame struct {
+	TxHash common.Hash
+	Type   string // call, delegatecall, staticcall, callcode, create, create2 or selfdestruct
+	From   common.Address
+	To     common.Address
+	Value  *big.Int
+	Input  []byte
+	// Path is the frame's position in the tree: empty for the transaction's
+	// own call, [0] for its first subcall, [0 1] for that one's second.
+	Path  []int
+	Error string
+	// Reverted is set when this frame or one of its callers failed, so
+	// nothing it did took effect.
+	Reverted bool
+}
+
+// TraceClient is implemented by clients that can trace every call a block
+// made, which is the only way to see calls between contracts.
+type TraceClient interface {
+	TraceBlock(ctx context.Context, number uint64) ([]TraceFrame, error)
+}
+
+// TraceBlock implements TraceClient with debug_traceBlockByNumber's
+// callTracer (geth, Nethermind, Reth). Nodes without it are asked with
+// trace_block (Erigon, OpenEthereum) from then on.
+func (c *RPCClient) TraceBlock(ctx context.Context, number uint64) ([]TraceFrame, error) {
+	if !c.parityTraces.Load() {
+		frames, err := c.debugTraceBlock(ctx, number)
+		if !isMethodNotFound(err) {
+			return frames, err
+		}
+		c.parityTraces.Store(true)
+	}
+	return c.parityTraceBlock(ctx, number)
+}
+
+// callFrame is a callTracer frame.
+type callFrame struct {
+	Type  string         `json:"type"`
+	From  common.Address `json:"from"`
+	To    common.Address `json:"to"`
+	Value *hexutil.Big   `json:"value"`
+	Input hexutil.Bytes  `json:"input"`
+	Error string         `json:"error"`
+	Calls []callFrame    `json:"calls"`
+}
+
+func (c *RPCClient) debugTraceBlock(ctx context.Context, number uint64) ([]TraceFrame, error) {
+	var res []struct {
+		TxHash common.Hash `json:"txHash"`
+		Result callFrame   `json:"result"`
+	}
+	err := c.Client.Client().CallContext(ctx, &res, "debug_traceBlockByNumber", hexutil.EncodeUint64(number), map[string]any{"tracer": "callTracer"})
+	if err != nil {
+		return nil, fmt.Errorf("debug_traceBlockByNumber: %w", err)
+	}
+	// Older nodes leave out txHash; results are in block order.
+	var txs []common.Hash
+	for _, r := range res {
+		if r.TxHash == (common.Hash{}) {
+			block, err := c.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+			if err != nil {
+				return nil, fmt.Errorf("block %d: %w", number, err)
+			}
+			for _, tx := range block.Transactions() {
+				txs = append(txs, tx.Hash())
+			}
+			break
+		}
+	}
+	var out []TraceFrame
+	for i, r := range res {
+		hash := r.TxHash
+		if hash == (common.Hash{}) && i < len(txs) {
+			hash = txs[i]
+		}
+		out = flattenCallFrame(out, hash, r.Result, nil, false)
+	}
+	return out, nil
+}
+
+func flattenCallFrame(out []TraceFrame, tx common.Hash, f callFrame, path []int, reverted bool) []TraceFrame {
+	reverted = reverted || f.Error != ""
+	frame := TraceFrame{
+		TxHash:   tx,
+		Type:     strings.ToLower(f.Type),
+		From:     f.From,
+		To:       f.To,
+		Value:    new(big.Int),
+		Input:    f.Input,
+		Path:     path,
+		Error:    f.Error,
+		Reverted: reverted,
+	}
+	if f.Value != nil {
+		frame.Value = f.Value.ToInt()
+	}
+	out = append(out, frame)
+	for i, sub := range f.Calls {
+		out = flattenCallFrame(out, tx, sub, append(append([]int{}, path...), i), reverted)
+	}
+	return out
+}
+
+// parityTrace is a trace_block entry.
+type parityTrace struct {
+	Type   string `json:"type"`
+	Action struct {
+		CallType string         `json:"callType"`
+		From     common.Address `json:"from"`
+		To       common.Address `json:"to"`
+		Value    *hexutil.Big   `json:"value"`
+		Input    hexutil.Bytes  `json:"input"`
+		Init     hexutil.Bytes  `json:"init"`
+		Address  common.Address `json:"address"` // selfdestruct
+		Refund   common.Address `json:"refundAddress"`
+		Balance  *hexutil.Big   `json:"balance"`
+	} `json:"action"`
+	Result *struct {
+		Address common.Address `json:"address"`
+	} `json:"result"`
+	Error        string      `json:"error"`
+	TraceAddress []int       `json:"traceAddress"`
+	TxHash       common.Hash `json:"transactionHash"`
+}
+
+func (c *RPCClient) parityTraceBlock(ctx context.Context, number uint64) ([]TraceFrame, error) {
+	var res []parityTrace
+	if err := c.Client.Client().CallContext(ctx, &res, "trace_block", hexutil.EncodeUint64(number)); err != nil {
+		return nil, fmt.Errorf("trace_block: %w", err)
+	}
+	// Traces come parents first, so a failed caller is known before its calls.
+	failed := map[string]bool{}
+	var out []TraceFrame
+	for _, t := range res {
+		a := t.Action
+		frame := TraceFrame{TxHash: t.TxHash, From: a.From, To: a.To, Value: new(big.Int), Input: a.Input, Path: t.TraceAddress, Error: t.Error}
+		if a.Value != nil {
+			frame.Value = a.Value.ToInt()
+		}
+		switch t.Type {
+		case "call":
+			frame.Type = strings.ToLower(a.CallType)
+		case "create":
+			frame.Type, frame.Input = "create", a.Init
+			if t.Result != nil {
+				frame.To = t.Result.Address
+			}
+		case "suicide":
+			frame.Type, frame.From, frame.To = "selfdestruct", a.Address, a.Refund
+			if a.Balance != nil {
+				frame.Value = a.Balance.ToInt()
+			}
+		default:
+			continue // block and uncle rewards
+		}
+		key := t.TxHash.Hex() + pathString(t.TraceAddress)
+		parent := ""
+		if n := len(t.TraceAddress); n > 0 {
+			parent = t.TxHash.Hex() + pathString(t.TraceAddress[:n-1])
+		}
+		frame.Reverted = t.Error != "" || (parent != "" && failed[parent])
+		failed[key] = frame.Reverted
+		out = append(out, frame)
+	}
+	return out, nil
+}
+
+func pathString(path []int) string {
+	parts := make([]string, len(path))
+	for i, p := range path {
+		parts[i] = strconv.Itoa(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// TraceBlock forwards block traces when the inner client supports them.
+func (c *LimitedClient) TraceBlock(ctx context.Context, number uint64) ([]TraceFrame, error) {
+	tc, ok := c.inner.(TraceClient)
+	if !ok {
+		return nil, errNoTraces
+	}
+	var frames []TraceFrame
+	err := c.limiter.Do(ctx, func() error {
+		var err error
+		frames, err = tc.TraceBlock(ctx, number)
+		return err
+	})
+	return frames, err
+}
+
+// traceMatcher matches internal calls: frames below a transaction's own
+// call, made by one contract to another. A frame matches when it is sent to
+// the contract or to or from one of the addresses, calls the function if
+// one is set, and moves at least min_value.
+type traceMatcher struct {
+	rule     config.Rule
+	contract *common.Address
+	addrs    map[common.Address]struct{}
+	selector []byte // nil for any input
+	method   abi.Method
+	min      *big.Int
+}
+
+func newTraceMatcher(rule config.Rule, abis map[string]*abi.ABI) (*traceMatcher, error) {
+	m := &traceMatcher{rule: rule, addrs: map[common.Address]struct{}{}, min: new(big.Int)}
+	if rule.Match.Contract != "" {
+		c := common.HexToAddress(rule.Match.Contract)
+		m.contract = &c
+	}
+	for _, a := range rule.Match.Addresses {
+		m.addrs[common.HexToAddress(a)] = struct{}{}
+	}
+	if rule.Match.Function != "" {
+		method, selector, err := resolveMethod(rule, abis)
+		if err != nil {
+			return nil, err
+		}
+		m.method, m.selector = method, selector
+	}
+	if rule.Match.MinValue != "" {
+		min, err := config.ParseWei(rule.Match.MinValue)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+		m.min = min
+	}
+	return m, nil
+}
+
+func (m *traceMatcher) match(f TraceFrame) (*source.Event, bool) {
+	if len(f.Path) == 0 || f.Value.Cmp(m.min) < 0 {
+		return nil, false
+	}
+	hit := m.contract != nil && f.To == *m.contract
+	if !hit {
+		_, from := m.addrs[f.From]
+		_, to := m.addrs[f.To]
+		hit = from || to
+	}
+	if !hit || (m.selector != nil && (len(f.Input) < 4 || !bytes.Equal(f.Input[:4], m.selector))) {
+		return nil, false
+	}
+	args := map[string]any{}
+	if m.selector != nil {
+		if err := m.method.Inputs.UnpackIntoMap(args, f.Input[4:]); err != nil {
+			args = map[string]any{"decode_error": err.Error()}
+		}
+	}
+	args["from"] = f.From.Hex()
+	args["to"] = f.To.Hex()
+	args["value"] = f.Value
+	args["call_type"] = f.Type
+	args["depth"] = len(f.Path)
+	args["trace_address"] = pathString(f.Path)
+	args["input"] = hexutil.Encode(f.Input)
+	args["reverted"] = f.Reverted
+	if len(f.Input) >= 4 {
+		args["selector"] = hexutil.Encode(f.Input[:4])
+	}
+	if f.Error != "" {
+		args["error"] = f.Error
+	}
+	return &source.Event{
+		RuleID:   m.rule.ID,
+		Contract: f.To.Hex(),
+		Name:     InternalCallEvent,
+		TxHash:   f.TxHash.Hex(),
+		Args:     args,
+	}, true
+}
+
+// checkTraces traces the block and emits the internal calls the rules match.
+func (s *Scanner) checkTraces(ctx context.Context, height uint64, emit func(source.Event) error) error {
+	if len(s.traces) == 0 {
+		return nil
+	}
+	tc, ok := s.client.(TraceClient)
+	if !ok {
+		return errNoTraces
+	}
+	frames, err := tc.TraceBlock(ctx, height)
+	if err != nil {
+		if isMethodNotFound(err) || errors.Is(err, errNoTraces) {
+			return fmt.Errorf("internal_call rules need debug_traceBlockByNumber or trace_block: %w", err)
+		}
+		return err
+	}
+	for _, f := range frames {
+		for _, m := range s.traces {
+			if ev, ok := m.match(f); ok {
+				if err := emit(*ev); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
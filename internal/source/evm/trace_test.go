@@ -0,0 +1,102 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestRPCClientTraceBlockFallsBackToTraceBlock(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		methods = append(methods, req.Method)
+		w.Header().Set("Content-Type", "application/json")
+		if req.Method == "debug_traceBlockByNumber" {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"error":{"code":-32601,"message":"the method debug_traceBlockByNumber does not exist/is not available"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":[
+			{"type":"call","action":{"callType":"call","from":"0x0000000000000000000000000000000000000001","to":"0x000000000000000000000000000000000000000a","value":"0x0","input":"0x"},"error":"Reverted","traceAddress":[],"transactionHash":"0x1111111111111111111111111111111111111111111111111111111111111111"},
+			{"type":"call","action":{"callType":"delegatecall","from":"0x000000000000000000000000000000000000000a","to":"0x000000000000000000000000000000000000000b","value":"0x5","input":"0xa9059cbb"},"traceAddress":[0],"transactionHash":"0x1111111111111111111111111111111111111111111111111111111111111111"},
+			{"type":"reward","action":{"author":"0x0000000000000000000000000000000000000002","value":"0x1"},"traceAddress":[]}
+		]}`))
+	}))
+	defer server.Close()
+
+	c, err := NewRPCClient(server.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	for i := 0; i < 2; i++ {
+		frames, err := c.TraceBlock(context.Background(), 7)
+		if err != nil {
+			t.Fatalf("trace: %v", err)
+		}
+		if len(frames) != 2 {
+			t.Fatalf("expected rewards to be dropped, got %+v", frames)
+		}
+		sub := frames[1]
+		if sub.Type != "delegatecall" || sub.Value.Int64() != 5 || len(sub.Path) != 1 || !sub.Reverted || sub.From != common.HexToAddress("0x0a") {
+			t.Fatalf("unexpected frame: %+v", sub)
+		}
+	}
+	// The debug method is only tried once.
+	if len(methods) != 3 || methods[0] != "debug_traceBlockByNumber" || methods[2] != "trace_block" {
+		t.Fatalf("unexpected calls: %v", methods)
+	}
+}
+
+func TestTraceMatcher(t *testing.T) {
+	treasury := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	thief := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	input := append(crypto.Keccak256([]byte("withdraw(address,uint256)"))[:4], append(common.LeftPadBytes(thief.Bytes(), 32), common.LeftPadBytes(big.NewInt(9).Bytes(), 32)...)...)
+	frames := flattenCallFrame(nil, common.HexToHash("0x0000000000000000000000000000000000000001"), callFrame{
+		Type: "CALL", From: common.HexToAddress("0x0000000000000000000000000000000000000001"), To: common.HexToAddress("0x0c"),
+		Calls: []callFrame{
+			{Type: "DELEGATECALL", From: common.HexToAddress("0x0c"), To: treasury, Input: input},
+			{Type: "CALL", From: treasury, To: thief, Error: "out of gas"},
+		},
+	}, nil, false)
+	if len(frames) != 3 || frames[2].Path[0] != 1 || !frames[2].Reverted || frames[1].Reverted {
+		t.Fatalf("unexpected flattening: %+v", frames)
+	}
+
+	m, err := newTraceMatcher(config.Rule{ID: "drain", Match: config.MatchSpec{
+		Type: config.MatchInternalCall, Contract: treasury.Hex(), Function: "withdraw(address,uint256)",
+	}}, nil)
+	if err != nil {
+		t.Fatalf("matcher: %v", err)
+	}
+	ev, ok := m.match(frames[1])
+	if !ok || ev.Name != InternalCallEvent || ev.Args["call_type"] != "delegatecall" || ev.Args["arg0"] != thief ||
+		ev.Args["arg1"].(*big.Int).Int64() != 9 || ev.Args["depth"] != 1 || ev.Args["trace_address"] != "0" {
+		t.Fatalf("unexpected match: %v %+v", ok, ev)
+	}
+	if _, ok := m.match(frames[2]); ok {
+		t.Fatalf("expected call from the treasury without the selector to be skipped")
+	}
+
+	// Address rules match frames from the address, but never the top-level call.
+	m, _ = newTraceMatcher(config.Rule{ID: "out", Match: config.MatchSpec{Type: config.MatchInternalCall, Addresses: []string{treasury.Hex()}}}, nil)
+	if ev, ok := m.match(frames[2]); !ok || ev.Args["reverted"] != true || ev.Args["error"] != "out of gas" {
+		t.Fatalf("expected reverted outflow to match, got %v %+v", ok, ev)
+	}
+	top := frames[0]
+	top.From = treasury
+	if _, ok := m.match(top); ok {
+		t.Fatalf("expected the top-level call to be skipped")
+	}
+}
@@ -0,0 +1,72 @@
+package evm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// NativeTransferEvent is the event name of transfer matches.
+const NativeTransferEvent = "native_transfer"
+
+// transferWatcher matches transactions that move at least min of the chain's
+// native coin to or from one of a transfer rule's addresses. Such transfers
+// emit no logs, so they are only visible in full blocks.
+type transferWatcher struct {
+	rule  config.Rule
+	addrs map[common.Address]struct{}
+	min   *big.Int
+}
+
+func newTransferWatcher(rule config.Rule) (*transferWatcher, error) {
+	w := &transferWatcher{rule: rule, addrs: map[common.Address]struct{}{}, min: big.NewInt(1)}
+	for _, a := range rule.Match.Addresses {
+		w.addrs[common.HexToAddress(a)] = struct{}{}
+	}
+	if rule.Match.MinValue != "" {
+		min, err := config.ParseWei(rule.Match.MinValue)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+		// A zero threshold still skips transactions that move no value.
+		if min.Sign() > 0 {
+			w.min = min
+		}
+	}
+	return w, nil
+}
+
+// matchTx returns a transfer event when tx carries at least min and a
+// watched address sent or received it. Sends take precedence, so a transfer
+// between two watched addresses is reported once, as "out".
+func (w *transferWatcher) matchTx(tx *types.Transaction, from common.Address) (*source.Event, bool) {
+	to := tx.To()
+	if to == nil || tx.Value().Cmp(w.min) < 0 {
+		return nil, false
+	}
+	watched, direction := common.Address{}, ""
+	if _, ok := w.addrs[from]; ok {
+		watched, direction = from, "out"
+	} else if _, ok := w.addrs[*to]; ok {
+		watched, direction = *to, "in"
+	}
+	if direction == "" {
+		return nil, false
+	}
+	return &source.Event{
+		RuleID: w.rule.ID,
+		Name:   NativeTransferEvent,
+		TxHash: tx.Hash().Hex(),
+		Args: map[string]any{
+			"watched":   watched.Hex(),
+			"direction": direction,
+			"from":      from.Hex(),
+			"to":        to.Hex(),
+			"value":     tx.Value(),
+		},
+	}, true
+}
@@ -0,0 +1,163 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Outcomes of a watched transaction.
+const (
+	TxConfirmed = "confirmed"
+	TxDropped   = "dropped"
+	TxReorged   = "reorged"
+)
+
+// DefaultDropAfter is how long a transaction may be unknown to the node
+// before it is reported as dropped.
+const DefaultDropAfter = 2 * time.Minute
+
+// ReceiptClient is implemented by clients that look up single transactions.
+type ReceiptClient interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+	TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error)
+}
+
+// TxStatus is the state of a watched transaction after a check.
+type TxStatus struct {
+	Outcome       string // empty while the transaction is still pending
+	Height        uint64
+	BlockHash     common.Hash
+	Confirmations uint64
+	Reverted      bool
+	GasUsed       uint64
+}
+
+// TxWatcher follows one transaction until it is buried under enough
+// confirmations, drops out of the node's mempool, or is reorged out.
+type TxWatcher struct {
+	client        ReceiptClient
+	hash          common.Hash
+	confirmations uint64
+	dropAfter     time.Duration
+	nowFunc       func() time.Time
+
+	mined        *types.Receipt
+	missingSince time.Time
+}
+
+// NewTxWatcher builds a watcher for hash that confirms it once confirmations
+// blocks sit on top of the one that included it.
+func NewTxWatcher(client ReceiptClient, hash common.Hash, confirmations uint64) *TxWatcher {
+	return &TxWatcher{
+		client:        client,
+		hash:          hash,
+		confirmations: confirmations,
+		dropAfter:     DefaultDropAfter,
+		nowFunc:       time.Now,
+	}
+}
+
+// SetDropAfter changes how long the transaction may be unknown to the node
+// before it counts as dropped.
+func (w *TxWatcher) SetDropAfter(d time.Duration) {
+	w.dropAfter = d
+}
+
+// Wait checks the transaction every interval until it reaches an outcome.
+func (w *TxWatcher) Wait(ctx context.Context, every time.Duration) (TxStatus, error) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		st, err := w.Check(ctx)
+		if err != nil || st.Outcome != "" {
+			return st, err
+		}
+		select {
+		case <-ctx.Done():
+			return st, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check polls the node once. A transaction that was mined and then loses its
+// receipt or moves to another block is reported as reorged.
+func (w *TxWatcher) Check(ctx context.Context) (TxStatus, error) {
+	rcpt, err := w.client.TransactionReceipt(ctx, w.hash)
+	if errors.Is(err, ethereum.NotFound) {
+		if w.mined != nil {
+			return w.status(TxReorged, 0), nil
+		}
+		return w.checkPending(ctx)
+	}
+	if err != nil {
+		return TxStatus{}, fmt.Errorf("receipt %s: %w", w.hash.Hex(), err)
+	}
+	if w.mined != nil && w.mined.BlockHash != rcpt.BlockHash {
+		return w.status(TxReorged, 0), nil
+	}
+	w.mined = rcpt
+	w.missingSince = time.Time{}
+
+	tip, err := w.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return TxStatus{}, fmt.Errorf("latest header: %w", err)
+	}
+	height := rcpt.BlockNumber.Uint64()
+	if tip.Number.Uint64() < height {
+		return w.status("", 0), nil
+	}
+	depth := tip.Number.Uint64() - height
+	if depth < w.confirmations {
+		return w.status("", depth), nil
+	}
+	// Receipts can lag the canonical chain on some nodes; trust the header.
+	h, err := w.client.HeaderByNumber(ctx, rcpt.BlockNumber)
+	if err != nil {
+		return TxStatus{}, fmt.Errorf("header %d: %w", height, err)
+	}
+	if h.Hash() != rcpt.BlockHash {
+		return w.status(TxReorged, depth), nil
+	}
+	return w.status(TxConfirmed, depth), nil
+}
+
+// checkPending reports a not yet mined transaction as dropped once the node
+// has not known it for dropAfter.
+func (w *TxWatcher) checkPending(ctx context.Context) (TxStatus, error) {
+	_, _, err := w.client.TransactionByHash(ctx, w.hash)
+	if err == nil {
+		w.missingSince = time.Time{}
+		return TxStatus{}, nil
+	}
+	if !errors.Is(err, ethereum.NotFound) {
+		return TxStatus{}, fmt.Errorf("transaction %s: %w", w.hash.Hex(), err)
+	}
+	now := w.nowFunc()
+	if w.missingSince.IsZero() {
+		w.missingSince = now
+	}
+	if now.Sub(w.missingSince) >= w.dropAfter {
+		return TxStatus{Outcome: TxDropped}, nil
+	}
+	return TxStatus{}, nil
+}
+
+func (w *TxWatcher) status(outcome string, depth uint64) TxStatus {
+	st := TxStatus{Outcome: outcome, Confirmations: depth}
+	if w.mined != nil {
+		st.Height = w.mined.BlockNumber.Uint64()
+		st.BlockHash = w.mined.BlockHash
+		st.Reverted = w.mined.Status == types.ReceiptStatusFailed
+		st.GasUsed = w.mined.GasUsed
+	}
+	return st
+}
@@ -0,0 +1,93 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type txClient struct {
+	tip     uint64
+	known   bool
+	receipt *types.Receipt
+	headers map[uint64]*types.Header
+}
+
+func (c *txClient) HeaderByNumber(_ context.Context, n *big.Int) (*types.Header, error) {
+	if n == nil {
+		return &types.Header{Number: new(big.Int).SetUint64(c.tip)}, nil
+	}
+	return c.headers[n.Uint64()], nil
+}
+
+func (c *txClient) TransactionByHash(context.Context, common.Hash) (*types.Transaction, bool, error) {
+	if !c.known {
+		return nil, false, ethereum.NotFound
+	}
+	return nil, true, nil
+}
+
+func (c *txClient) TransactionReceipt(context.Context, common.Hash) (*types.Receipt, error) {
+	if c.receipt == nil {
+		return nil, ethereum.NotFound
+	}
+	return c.receipt, nil
+}
+
+func TestTxWatcherConfirms(t *testing.T) {
+	ctx := context.Background()
+	block := &types.Header{Number: big.NewInt(100)}
+	c := &txClient{known: true, tip: 99, headers: map[uint64]*types.Header{100: block}}
+	w := NewTxWatcher(c, common.HexToHash("0x01"), 3)
+
+	if st, err := w.Check(ctx); err != nil || st.Outcome != "" {
+		t.Fatalf("expected pending, got %+v, %v", st, err)
+	}
+	c.receipt = &types.Receipt{BlockNumber: big.NewInt(100), BlockHash: block.Hash(), Status: types.ReceiptStatusSuccessful, GasUsed: 21000}
+	c.tip = 102
+	if st, err := w.Check(ctx); err != nil || st.Outcome != "" || st.Confirmations != 2 {
+		t.Fatalf("expected 2 confirmations, got %+v, %v", st, err)
+	}
+	c.tip = 103
+	st, err := w.Check(ctx)
+	if err != nil || st.Outcome != TxConfirmed || st.Height != 100 || st.Reverted || st.GasUsed != 21000 {
+		t.Fatalf("expected confirmed, got %+v, %v", st, err)
+	}
+}
+
+func TestTxWatcherReorged(t *testing.T) {
+	ctx := context.Background()
+	c := &txClient{tip: 100, receipt: &types.Receipt{BlockNumber: big.NewInt(100), BlockHash: common.HexToHash("0xaa")}}
+	w := NewTxWatcher(c, common.HexToHash("0x01"), 6)
+	if st, err := w.Check(ctx); err != nil || st.Outcome != "" {
+		t.Fatalf("expected pending, got %+v, %v", st, err)
+	}
+	c.receipt = nil
+	st, err := w.Check(ctx)
+	if err != nil || st.Outcome != TxReorged || st.Height != 100 {
+		t.Fatalf("expected reorged, got %+v, %v", st, err)
+	}
+}
+
+func TestTxWatcherDropped(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	c := &txClient{known: true}
+	w := NewTxWatcher(c, common.HexToHash("0x01"), 1)
+	w.nowFunc = func() time.Time { return now }
+	w.SetDropAfter(time.Minute)
+
+	c.known = false
+	if st, _ := w.Check(ctx); st.Outcome != "" {
+		t.Fatalf("expected grace period before drop, got %+v", st)
+	}
+	now = now.Add(time.Minute)
+	if st, err := w.Check(ctx); err != nil || st.Outcome != TxDropped {
+		t.Fatalf("expected dropped, got %+v, %v", st, err)
+	}
+}
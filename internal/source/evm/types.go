@@ -1,25 +1,5 @@
 package evm
 
-import (
-	"errors"
-)
-
-// Chain is the identifier for EVM chains.
+// Chain is the identifier for EVM chains. Its events' Contract is the
+// emitting contract and LogIndex the log's position in the block.
 const Chain = "evm"
-
-// ErrReorgDetected signals that the chain rewound; caller should restart from the updated cursor.
-var ErrReorgDetected = errors.New("reorg detected")
-
-// NormalizedEvent represents a decoded on-chain event in a uniform shape.
-type NormalizedEvent struct {
-	Chain    string
-	SourceID string
-	RuleID   string
-	Height   uint64
-	Hash     string
-	TxHash   string
-	LogIndex *uint
-	Contract string
-	Name     string
-	Args     map[string]any
-}
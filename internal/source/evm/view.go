@@ -0,0 +1,235 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// viewWatcher calls one view function on an interval. Every read is emitted
+// as an event named after the function, carrying the decoded return values,
+// so the rule's where predicates decide whether it alerts.
+type viewWatcher struct {
+	rule     config.Rule
+	contract common.Address
+	method   abi.Method
+	data     []byte // selector and packed inputs
+	every    time.Duration
+	lastRead time.Time
+}
+
+func newViewWatcher(rule config.Rule, abis map[string]*abi.ABI) (*viewWatcher, error) {
+	sig := strings.ReplaceAll(rule.Match.Function, " ", "")
+	selector := crypto.Keccak256([]byte(sig))[:4]
+	if rule.Match.ABI != "" {
+		bound, ok := findABI(abis, rule.Match.ABI)
+		if !ok {
+			return nil, fmt.Errorf("rule %s: abi %s not found in abi_dirs", rule.ID, rule.Match.ABI)
+		}
+		abis = map[string]*abi.ABI{rule.Match.ABI: bound}
+	}
+	method, ok := findMethodByID(abis, selector)
+	switch {
+	case rule.Match.Returns != "":
+		// returns overrides the ABI, if any.
+		synthetic, err := syntheticMethod(sig)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+		returns := strings.ReplaceAll(rule.Match.Returns, " ", "")
+		if strings.HasPrefix(returns, "(") && strings.HasSuffix(returns, ")") {
+			returns = returns[1 : len(returns)-1]
+		}
+		outputs, err := parseArguments(returns, "out")
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: returns: %w", rule.ID, err)
+		}
+		method = abi.NewMethod(synthetic.Name, synthetic.RawName, abi.Function, "view", false, false, synthetic.Inputs, outputs)
+	case !ok && rule.Match.ABI != "":
+		return nil, fmt.Errorf("rule %s: abi %s does not define %s", rule.ID, rule.Match.ABI, sig)
+	case !ok:
+		return nil, fmt.Errorf("rule %s: no abi in abi_dirs defines %s; set match.returns", rule.ID, sig)
+	}
+	if len(method.Outputs) == 0 {
+		return nil, fmt.Errorf("rule %s: %s returns nothing", rule.ID, sig)
+	}
+	method.Outputs = nameOutputs(method.Outputs)
+
+	if len(rule.Match.Inputs) != len(method.Inputs) {
+		return nil, fmt.Errorf("rule %s: %s takes %d inputs, got %d", rule.ID, sig, len(method.Inputs), len(rule.Match.Inputs))
+	}
+	vals := make([]any, len(method.Inputs))
+	for i, in := range method.Inputs {
+		v, err := parseInput(in.Type, rule.Match.Inputs[i])
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: input %d: %w", rule.ID, i, err)
+		}
+		vals[i] = v
+	}
+	packed, err := method.Inputs.Pack(vals...)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: pack inputs: %w", rule.ID, err)
+	}
+	return &viewWatcher{
+		rule:     rule,
+		contract: common.HexToAddress(rule.Match.Contract),
+		method:   method,
+		data:     append(selector, packed...),
+		every:    rule.Match.PollInterval(),
+	}, nil
+}
+
+// nameOutputs names unnamed return values: a single one becomes value, and
+// the rest out0, out1, and so on.
+func nameOutputs(outputs abi.Arguments) abi.Arguments {
+	named := make(abi.Arguments, len(outputs))
+	copy(named, outputs)
+	for i := range named {
+		switch {
+		case len(named) == 1:
+			named[i].Name = "value"
+		case named[i].Name == "":
+			named[i].Name = fmt.Sprintf("out%d", i)
+		}
+	}
+	return named
+}
+
+// parseInput converts a configured call argument to the Go type abi packs
+// for t.
+func parseInput(t abi.Type, s string) (any, error) {
+	switch t.T {
+	case abi.AddressTy:
+		if !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("invalid address %q", s)
+		}
+		return common.HexToAddress(s), nil
+	case abi.BoolTy:
+		return strconv.ParseBool(s)
+	case abi.StringTy:
+		return s, nil
+	case abi.BytesTy:
+		return hexutil.Decode(s)
+	case abi.FixedBytesTy:
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) != t.Size {
+			return nil, fmt.Errorf("invalid bytes%d %q", t.Size, s)
+		}
+		v := reflect.New(t.GetType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v.Interface(), nil
+	case abi.IntTy, abi.UintTy:
+		n, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+		if t.Size > 64 {
+			return n, nil
+		}
+		// Small integer types pack from the matching Go type.
+		v := reflect.New(t.GetType()).Elem()
+		if t.T == abi.IntTy {
+			if !n.IsInt64() || v.OverflowInt(n.Int64()) {
+				return nil, fmt.Errorf("%s out of range for %s", s, t)
+			}
+			v.SetInt(n.Int64())
+		} else {
+			if !n.IsUint64() || v.OverflowUint(n.Uint64()) {
+				return nil, fmt.Errorf("%s out of range for %s", s, t)
+			}
+			v.SetUint(n.Uint64())
+		}
+		return v.Interface(), nil
+	}
+	return nil, fmt.Errorf("unsupported input type %s", t)
+}
+
+// event decodes a read into an event.
+func (w *viewWatcher) event(out []byte) (source.Event, error) {
+	if len(out) == 0 {
+		return source.Event{}, fmt.Errorf("rule %s: %s returned no data; is %s a contract?", w.rule.ID, w.method.Sig, w.contract.Hex())
+	}
+	args := map[string]any{}
+	if err := w.method.Outputs.UnpackIntoMap(args, out); err != nil {
+		return source.Event{}, fmt.Errorf("rule %s: decode %s: %w", w.rule.ID, w.method.Sig, err)
+	}
+	args["function"] = w.method.Sig
+	return source.Event{
+		RuleID:   w.rule.ID,
+		Contract: w.contract.Hex(),
+		Name:     w.method.RawName,
+		Args:     args,
+	}, nil
+}
+
+// checkViews calls the view rules whose interval has passed at height and
+// emits each result. Several due calls share one Multicall3 request.
+func (s *Scanner) checkViews(ctx context.Context, height uint64, emit func(source.Event) error) error {
+	if len(s.views) == 0 {
+		return nil
+	}
+	reader, ok := s.client.(StateReader)
+	if !ok {
+		return errNoState
+	}
+	now := s.nowFunc()
+	var due []*viewWatcher
+	for _, w := range s.views {
+		if now.Sub(w.lastRead) >= w.every {
+			due = append(due, w)
+		}
+	}
+	at := new(big.Int).SetUint64(height)
+	outs := make([][]byte, len(due))
+	if len(due) > 1 && !s.multicallOff {
+		calls := make([]call3, len(due))
+		for i, w := range due {
+			calls[i] = call3{Target: w.contract, AllowFailure: true, CallData: w.data}
+		}
+		results, err := multicall(ctx, reader, s.multicall, calls, at)
+		switch {
+		case errors.Is(err, errNoMulticall):
+			s.multicallOff = true
+		case err != nil:
+			return err
+		default:
+			for i, r := range results {
+				if !r.Success {
+					return fmt.Errorf("rule %s: call %s: reverted", due[i].rule.ID, due[i].method.Sig)
+				}
+				outs[i] = r.ReturnData
+			}
+		}
+	}
+	for i, w := range due {
+		if outs[i] == nil {
+			out, err := reader.CallContract(ctx, ethereum.CallMsg{To: &w.contract, Data: w.data}, at)
+			if err != nil {
+				return fmt.Errorf("rule %s: call %s: %w", w.rule.ID, w.method.Sig, err)
+			}
+			outs[i] = out
+		}
+		ev, err := w.event(outs[i])
+		if err != nil {
+			return err
+		}
+		if err := emit(ev); err != nil {
+			return err
+		}
+		w.lastRead = now
+	}
+	return nil
+}
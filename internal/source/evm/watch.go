@@ -0,0 +1,122 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ActivityEvent is the event name of watch_address matches on every chain.
+const ActivityEvent = "address_activity"
+
+// TxClient is implemented by clients that return full blocks. It lets
+// watch_address rules see plain value transfers, which emit no logs.
+type TxClient interface {
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+}
+
+// addressWatcher matches any log or transaction that touches one of a
+// watch_address rule's EVM addresses. Non-EVM addresses in the list are
+// ignored here and picked up by the other chains' scanners.
+type addressWatcher struct {
+	rule  config.Rule
+	addrs map[common.Address]struct{}
+}
+
+func newAddressWatcher(rule config.Rule) *addressWatcher {
+	w := &addressWatcher{rule: rule, addrs: map[common.Address]struct{}{}}
+	for _, a := range rule.Match.Addresses {
+		if common.IsHexAddress(a) {
+			w.addrs[common.HexToAddress(a)] = struct{}{}
+		}
+	}
+	return w
+}
+
+// mayMatch reports whether the bloom can hold a log emitted by, or indexing,
+// a watched address.
+func (w *addressWatcher) mayMatch(bloom types.Bloom) bool {
+	for a := range w.addrs {
+		if bloom.Test(a.Bytes()) || bloom.Test(common.LeftPadBytes(a.Bytes(), 32)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLog returns an activity event when a watched address emitted the log
+// or appears in one of its indexed topics.
+func (w *addressWatcher) matchLog(log types.Log) (*source.Event, bool) {
+	watched, role := common.Address{}, ""
+	if _, ok := w.addrs[log.Address]; ok {
+		watched, role = log.Address, "emitter"
+	} else {
+		for _, t := range log.Topics[min(1, len(log.Topics)):] {
+			// An address topic is 12 zero bytes then the address.
+			a := common.BytesToAddress(t.Bytes())
+			if common.BytesToHash(a.Bytes()) != t {
+				continue
+			}
+			if _, ok := w.addrs[a]; ok {
+				watched, role = a, "topic"
+				break
+			}
+		}
+	}
+	if role == "" {
+		return nil, false
+	}
+	args := map[string]any{
+		"watched":  watched.Hex(),
+		"role":     role,
+		"kind":     "log",
+		"contract": log.Address.Hex(),
+	}
+	if len(log.Topics) > 0 {
+		args["topic0"] = log.Topics[0].Hex()
+	}
+	idx := uint(log.Index)
+	return &source.Event{
+		RuleID:   w.rule.ID,
+		Contract: log.Address.Hex(),
+		Name:     ActivityEvent,
+		TxHash:   log.TxHash.Hex(),
+		LogIndex: &idx,
+		Args:     args,
+	}, true
+}
+
+// matchTx returns an activity event when a watched address sent or received tx.
+func (w *addressWatcher) matchTx(tx *types.Transaction, from common.Address) (*source.Event, bool) {
+	watched, role := common.Address{}, ""
+	if _, ok := w.addrs[from]; ok {
+		watched, role = from, "sender"
+	} else if to := tx.To(); to != nil {
+		if _, ok := w.addrs[*to]; ok {
+			watched, role = *to, "receiver"
+		}
+	}
+	if role == "" {
+		return nil, false
+	}
+	args := map[string]any{
+		"watched": watched.Hex(),
+		"role":    role,
+		"kind":    "tx",
+		"from":    from.Hex(),
+		"amount":  tx.Value(),
+	}
+	if to := tx.To(); to != nil {
+		args["to"] = to.Hex()
+	}
+	return &source.Event{
+		RuleID: w.rule.ID,
+		Name:   ActivityEvent,
+		TxHash: tx.Hash().Hex(),
+		Args:   args,
+	}, true
+}
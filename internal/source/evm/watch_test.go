@@ -0,0 +1,57 @@
+package evm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestAddressWatcher(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	hot := crypto.PubkeyToAddress(key.PublicKey)
+	token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	w := newAddressWatcher(config.Rule{ID: "hot", Match: config.MatchSpec{
+		Type:      config.MatchWatchAddress,
+		Addresses: []string{hot.Hex(), "SENDER0000000000000000000000000000000000000000000000000000"},
+	}})
+	if len(w.addrs) != 1 {
+		t.Fatalf("expected only the evm address to be kept, got %d", len(w.addrs))
+	}
+
+	transfer := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	ev, ok := w.matchLog(types.Log{Address: token, Topics: []common.Hash{transfer, addrTopic(common.HexToAddress("0x01")), addrTopic(hot)}, Index: 2})
+	if !ok {
+		t.Fatalf("expected log with watched recipient to match")
+	}
+	if ev.Name != ActivityEvent || ev.Args["role"] != "topic" || ev.Args["watched"] != hot.Hex() || ev.Args["contract"] != token.Hex() {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	// A non-address topic that happens to end in the address is not a hit.
+	fake := addrTopic(hot)
+	fake[0] = 1
+	if _, ok := w.matchLog(types.Log{Address: token, Topics: []common.Hash{transfer, fake}}); ok {
+		t.Fatalf("expected non-address topic to be ignored")
+	}
+
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	to := common.HexToAddress("0x02")
+	tx := types.MustSignNewTx(key, signer, &types.LegacyTx{To: &to, Value: big.NewInt(5), Gas: 21000, GasPrice: big.NewInt(1)})
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		t.Fatalf("sender: %v", err)
+	}
+	ev, ok = w.matchTx(tx, from)
+	if !ok || ev.Args["role"] != "sender" || ev.Args["to"] != to.Hex() || ev.Args["amount"].(*big.Int).Int64() != 5 {
+		t.Fatalf("unexpected tx match: %v %+v", ok, ev)
+	}
+	if !w.mayMatch(types.CreateBloom(types.Receipts{{Logs: []*types.Log{{Address: token, Topics: []common.Hash{transfer, addrTopic(hot)}}}}})) {
+		t.Fatalf("expected bloom with watched topic to match")
+	}
+}
@@ -0,0 +1,235 @@
+package ingest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSignatureHeader carries the signature of an ingest request unless
+// the source names another header.
+const DefaultSignatureHeader = "X-Watchtower-Signature"
+
+// DefaultTimestampHeader carries the unix time an ingest request was
+// signed at unless the source names another header.
+const DefaultTimestampHeader = "X-Watchtower-Timestamp"
+
+// MaxClockSkew is how far a request's signed timestamp may be from the
+// time it arrives. Older requests are refused as replays.
+const MaxClockSkew = 5 * time.Minute
+
+// Event is an event as senders post it. Every field is optional but args;
+// a posted object without an "args" field is taken whole as the args of
+// one event, so provider webhooks can be forwarded unchanged.
+type Event struct {
+	ID        string         `json:"id"` // unique per event; repeated deliveries of an id are dropped
+	Name      string         `json:"name"`
+	Chain     string         `json:"chain"`
+	Height    uint64         `json:"height"`
+	Hash      string         `json:"hash"`
+	TxHash    string         `json:"tx_hash"`
+	LogIndex  *uint          `json:"log_index"`
+	Contract  string         `json:"contract"`
+	Timestamp time.Time      `json:"-"`
+	Args      map[string]any `json:"-"`
+}
+
+// Decode reads the body of an ingest request: one event, an array of
+// events, or an object whose "events" field is the array.
+func Decode(body []byte) ([]Event, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decode body: %w", err)
+	}
+	if dec.More() {
+		return nil, errors.New("decode body: trailing data")
+	}
+	var objs []any
+	switch v := v.(type) {
+	case []any:
+		objs = v
+	case map[string]any:
+		if list, ok := v["events"].([]any); ok && len(v) == 1 {
+			objs = list
+		} else {
+			objs = []any{v}
+		}
+	default:
+		return nil, errors.New("body must be a JSON object or array")
+	}
+	events := make([]Event, 0, len(objs))
+	for i, o := range objs {
+		obj, ok := o.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("event %d: not an object", i)
+		}
+		ev, err := decodeEvent(obj)
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %w", i, err)
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func decodeEvent(obj map[string]any) (Event, error) {
+	raw, ok := obj["args"]
+	if !ok {
+		// A provider payload: its id, if it has one, still names it.
+		id, _ := obj["id"].(string)
+		return Event{ID: id, Args: values(obj)}, nil
+	}
+	args, ok := raw.(map[string]any)
+	if !ok {
+		return Event{}, errors.New("args must be an object")
+	}
+	var ev Event
+	for k, v := range obj {
+		var err error
+		switch k {
+		case "id", "name", "chain", "hash", "tx_hash", "contract":
+			s, ok := v.(string)
+			if !ok {
+				return Event{}, fmt.Errorf("%s must be a string", k)
+			}
+			switch k {
+			case "id":
+				ev.ID = s
+			case "name":
+				ev.Name = s
+			case "chain":
+				ev.Chain = s
+			case "hash":
+				ev.Hash = s
+			case "tx_hash":
+				ev.TxHash = s
+			case "contract":
+				ev.Contract = s
+			}
+		case "height":
+			ev.Height, err = uintField(k, v)
+		case "log_index":
+			var n uint64
+			if n, err = uintField(k, v); err == nil {
+				idx := uint(n)
+				ev.LogIndex = &idx
+			}
+		case "timestamp":
+			ev.Timestamp, err = timeField(v)
+		case "args":
+		default:
+			err = fmt.Errorf("unknown field %q", k)
+		}
+		if err != nil {
+			return Event{}, err
+		}
+	}
+	ev.Args = values(args)
+	return ev, nil
+}
+
+func uintField(name string, v any) (uint64, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	u, ok := new(big.Int).SetString(n.String(), 10)
+	if !ok || !u.IsUint64() {
+		return 0, fmt.Errorf("%s must be a whole number, got %s", name, n)
+	}
+	return u.Uint64(), nil
+}
+
+// timeField reads a timestamp given in RFC 3339 or as unix seconds.
+func timeField(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timestamp: %w", err)
+		}
+		return t.UTC(), nil
+	case json.Number:
+		secs, err := v.Int64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timestamp must be unix seconds, got %s", v)
+		}
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	return time.Time{}, errors.New("timestamp must be RFC 3339 or unix seconds")
+}
+
+// values converts decoded JSON numbers for predicates: those that fit an
+// int64 become one, larger integers *big.Int and the rest float64.
+func values(m map[string]any) map[string]any {
+	for k, v := range m {
+		m[k] = value(v)
+	}
+	return m
+}
+
+func value(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if n, ok := new(big.Int).SetString(v.String(), 10); ok {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		return values(v)
+	case []any:
+		for i, e := range v {
+			v[i] = value(e)
+		}
+	}
+	return v
+}
+
+// Sign returns the signature of an ingest request: the hex HMAC-SHA256,
+// keyed with the source's secret, of the timestamp header's value, a dot
+// and the body. An empty timestamp signs the body alone, as providers that
+// send none do.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	if timestamp != "" {
+		mac.Write([]byte(timestamp + "."))
+	}
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether sig, with or without a "sha256=" prefix, is the
+// signature of timestamp and body.
+func Verify(secret []byte, timestamp string, body []byte, sig string) bool {
+	got, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(sig), "sha256="))
+	if err != nil {
+		return false
+	}
+	want, _ := hex.DecodeString(Sign(secret, timestamp, body))
+	return hmac.Equal(got, want)
+}
+
+// Fresh reports whether timestamp, in unix seconds, is within MaxClockSkew
+// of now.
+func Fresh(timestamp string, now time.Time) bool {
+	secs, err := strconv.ParseInt(strings.TrimSpace(timestamp), 10, 64)
+	if err != nil {
+		return false
+	}
+	d := now.Sub(time.Unix(secs, 0))
+	return d <= MaxClockSkew && d >= -MaxClockSkew
+}
@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	events, err := Decode([]byte(`{"events":[{"name":"A","args":{"x":1}},{"name":"B","args":{}}]}`))
+	if err != nil || len(events) != 2 || events[1].Name != "B" {
+		t.Fatalf("wrapped array: %+v, %v", events, err)
+	}
+
+	// A provider payload without args becomes the args of one event.
+	events, err = Decode([]byte(`{"webhookId":"wh_1","id":"whevt_1","type":"ADDRESS_ACTIVITY","event":{"network":"ETH_MAINNET","activity":[{"value":1.5}]}}`))
+	if err != nil || len(events) != 1 {
+		t.Fatalf("provider payload: %+v, %v", events, err)
+	}
+	ev := events[0]
+	if ev.ID != "whevt_1" || ev.Args["type"] != "ADDRESS_ACTIVITY" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if inner, ok := ev.Args["event"].(map[string]any); !ok || inner["network"] != "ETH_MAINNET" {
+		t.Fatalf("expected nested args kept, got %v", ev.Args["event"])
+	}
+
+	for name, body := range map[string]string{
+		"not json":        `{`,
+		"scalar":          `42`,
+		"args not object": `{"args":[1]}`,
+		"unknown field":   `{"args":{},"colour":"red"}`,
+		"bad height":      `{"args":{},"height":-1}`,
+		"bad timestamp":   `{"args":{},"timestamp":"yesterday"}`,
+		"trailing data":   `{"args":{}} {}`,
+	} {
+		if _, err := Decode([]byte(body)); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"args":{}}`)
+	sig := Sign([]byte("k"), "1714564800", body)
+	if !Verify([]byte("k"), "1714564800", body, sig) || !Verify([]byte("k"), "1714564800", body, "sha256="+sig) {
+		t.Fatal("expected the signature to verify")
+	}
+	if Verify([]byte("k"), "1714564800", append(body, ' '), sig) || Verify([]byte("k"), "1714564801", body, sig) || Verify([]byte("k"), "", body, sig) ||
+		Verify([]byte("k"), "1714564800", body, "") || Verify([]byte("k"), "1714564800", body, "zz") {
+		t.Fatal("expected a mismatch")
+	}
+	if !Verify([]byte("k"), "", body, Sign([]byte("k"), "", body)) {
+		t.Fatal("expected a body-only signature to verify")
+	}
+}
+
+func TestFresh(t *testing.T) {
+	now := time.Unix(1714564800, 0)
+	for ts, want := range map[string]bool{
+		"1714564800": true,
+		"1714564500": true,
+		"1714565100": true,
+		"1714564499": false,
+		"1714565101": false,
+		"":           false,
+		"yesterday":  false,
+	} {
+		if got := Fresh(ts, now); got != want {
+			t.Errorf("Fresh(%q) = %v", ts, got)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package ingest
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// RuleMatcher applies one rule to ingested events.
+type RuleMatcher struct {
+	rule config.Rule
+	name string // empty for every event
+}
+
+// NewRuleMatcher builds a matcher for ingest rules.
+func NewRuleMatcher(rule config.Rule) (*RuleMatcher, error) {
+	if mt := strings.ToLower(rule.Match.Type); mt != config.MatchIngest {
+		return nil, fmt.Errorf("rule %s: unsupported match.type %s for ingest", rule.ID, rule.Match.Type)
+	}
+	return &RuleMatcher{rule: rule, name: rule.Match.Event}, nil
+}
+
+// Match returns the event as the rule's match, if its name fits. Each
+// match gets its own copy of the args, as predicates and annotators may
+// add to them. The event's name is added as the "event" arg unless the
+// args have one.
+func (m *RuleMatcher) Match(ev Event) (source.Event, bool) {
+	if m.name != "" && ev.Name != m.name {
+		return source.Event{}, false
+	}
+	args := maps.Clone(ev.Args)
+	if args == nil {
+		args = map[string]any{}
+	}
+	if _, ok := args["event"]; !ok && ev.Name != "" {
+		args["event"] = ev.Name
+	}
+	txHash := ev.TxHash
+	if txHash == "" {
+		txHash = ev.ID
+	}
+	return source.Event{
+		Chain:     ev.Chain,
+		RuleID:    m.rule.ID,
+		Height:    ev.Height,
+		Hash:      ev.Hash,
+		TxHash:    txHash,
+		LogIndex:  ev.LogIndex,
+		Contract:  ev.Contract,
+		Timestamp: ev.Timestamp,
+		Name:      ev.Name,
+		Args:      args,
+	}, true
+}
@@ -0,0 +1,75 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+)
+
+// Path is where an ingest source with the given id accepts events.
+func Path(sourceID string) string {
+	return "/ingest/" + sourceID
+}
+
+// Handler routes requests to the sources by id, at Path.
+func Handler(sources map[string]*Source) http.Handler {
+	mux := http.NewServeMux()
+	for id, s := range sources {
+		mux.Handle("POST "+Path(id), s)
+	}
+	return mux
+}
+
+// Serve listens on addr and serves h in the background. The listener is
+// opened before it returns, so an address in use is reported at startup.
+func Serve(addr string, h http.Handler) (*http.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: 3 * time.Second,
+		ReadTimeout:       30 * time.Second,
+	}
+	go func() { _ = srv.Serve(lis) }()
+	return srv, nil
+}
+
+// ServeAll starts a listener for each address the sources listen on,
+// serving the sources that share it, and returns them by address. sources
+// are the config's; those without an entry in ingests are passed over.
+func ServeAll(sources []config.Source, ingests map[string]*Source) (map[string]*http.Server, error) {
+	byAddr := map[string]map[string]*Source{}
+	for _, src := range sources {
+		s, ok := ingests[src.ID]
+		if !ok {
+			continue
+		}
+		if byAddr[src.Listen] == nil {
+			byAddr[src.Listen] = map[string]*Source{}
+		}
+		byAddr[src.Listen][src.ID] = s
+	}
+	srvs := map[string]*http.Server{}
+	for addr, group := range byAddr {
+		srv, err := Serve(addr, Handler(group))
+		if err != nil {
+			for _, s := range srvs {
+				_ = s.Close()
+			}
+			return nil, fmt.Errorf("ingest listener %s: %w", addr, err)
+		}
+		srvs[addr] = srv
+	}
+	return srvs, nil
+}
+
+// Shutdown gracefully stops a listener started by Serve.
+func Shutdown(ctx context.Context, srv *http.Server) error {
+	return srv.Shutdown(ctx)
+}
@@ -0,0 +1,355 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// DefaultQueueSize is how many accepted events may wait for the runner
+// before a source answers 503, so senders retry later.
+const DefaultQueueSize = 10000
+
+// MaxBodySize bounds an ingest request body.
+const MaxBodySize = 1 << 20
+
+// errQueueFull is returned by enqueue when the events do not fit.
+var errQueueFull = errors.New("queue full")
+
+// Source accepts the events posted to one ingest source and queues them
+// in the store until the runner takes them on its next tick, so events
+// acknowledged to a sender survive a restart. Repeated deliveries of an
+// event id, as webhook providers make when unsure of an earlier one, are
+// dropped while the id is among the last DefaultQueueSize seen.
+type Source struct {
+	store  *storage.Store
+	source config.Source
+	secret []byte
+	header string
+	// tsHeader carries the signed timestamp; empty for sources that take
+	// signatures over the body alone.
+	tsHeader string
+	matchers []*RuleMatcher
+	notify   func()
+	nowFunc  func() time.Time
+
+	mu    sync.Mutex
+	queue []queued
+	seen  map[string]struct{}
+	ids   []string // seen, oldest first
+	// tip is the highest height received, read by the dashboard.
+	tip atomic.Uint64
+}
+
+// queued is an accepted event and its place in the store's queue.
+type queued struct {
+	seq int64
+	ev  Event
+}
+
+// record is how a queued event is stored.
+type record struct {
+	Event
+	Timestamp time.Time      `json:"timestamp"`
+	Args      map[string]any `json:"args"`
+}
+
+// NewSource builds an ingest source and matchers for its rules, and loads
+// the events left queued in store by an earlier run.
+func NewSource(store *storage.Store, src config.Source, rules []config.Rule) (*Source, error) {
+	s := &Source{
+		store:   store,
+		source:  src,
+		secret:  []byte(src.Secret),
+		header:  src.SignatureHeader,
+		nowFunc: time.Now,
+		seen:    map[string]struct{}{},
+	}
+	if s.header == "" {
+		s.header = DefaultSignatureHeader
+	}
+	if !src.BodyOnlySignature {
+		s.tsHeader = src.TimestampHeader
+		if s.tsHeader == "" {
+			s.tsHeader = DefaultTimestampHeader
+		}
+	}
+	commit, err := s.PrepareRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	commit()
+	if err := s.load(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load restores the queue from the store.
+func (s *Source) load(ctx context.Context) error {
+	rows, err := s.store.IngestedEvents(ctx, s.source.ID)
+	if err != nil {
+		return fmt.Errorf("source %s: %w", s.source.ID, err)
+	}
+	for _, row := range rows {
+		dec := json.NewDecoder(bytes.NewReader([]byte(row.EventJSON)))
+		dec.UseNumber()
+		var rec record
+		if err := dec.Decode(&rec); err != nil {
+			return fmt.Errorf("source %s: queued event %d: %w", s.source.ID, row.Seq, err)
+		}
+		ev := rec.Event
+		ev.Timestamp = rec.Timestamp
+		ev.Args = values(rec.Args)
+		s.remember(ev)
+		s.queue = append(s.queue, queued{seq: row.Seq, ev: ev})
+	}
+	return nil
+}
+
+// PrepareRules builds matchers for the source's rules without touching the
+// running source. Calling the returned commit func swaps them in. Rules
+// for every source that need a chain, watch_address and reorg, are
+// skipped.
+func (s *Source) PrepareRules(rules []config.Rule) (commit func(), err error) {
+	matchers := []*RuleMatcher{}
+	for _, r := range rules {
+		if !r.AppliesTo(s.source.ID) {
+			continue
+		}
+		mt := strings.ToLower(r.Match.Type)
+		if r.Source == config.AllSources && (mt == config.MatchWatchAddress || mt == config.MatchReorg) {
+			continue
+		}
+		if mt == config.MatchReorg {
+			continue // ingest sources never rewind
+		}
+		m, err := NewRuleMatcher(r)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return func() {
+		s.matchers = matchers
+	}, nil
+}
+
+// SetNotify makes the source call f after it queues events, such as to
+// start a tick at once.
+func (s *Source) SetNotify(f func()) {
+	s.notify = f
+}
+
+// Tip returns the highest height among the events received, or 0.
+func (s *Source) Tip() uint64 {
+	return s.tip.Load()
+}
+
+// Pending returns how many events wait for the runner.
+func (s *Source) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// ServeHTTP accepts a signed request posting events. It answers 202 once
+// they are stored, 401 for a missing or wrong signature or a timestamp
+// more than MaxClockSkew off, 400 for a body
+// that is not events, and 503 when the queue is full or the events could
+// not be stored.
+func (s *Source) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodySize))
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "read body", http.StatusBadRequest)
+		return
+	}
+	var ts string
+	if s.tsHeader != "" {
+		ts = r.Header.Get(s.tsHeader)
+		if !Fresh(ts, s.nowFunc()) {
+			http.Error(w, "stale or missing timestamp", http.StatusUnauthorized)
+			return
+		}
+	}
+	if !Verify(s.secret, ts, body, r.Header.Get(s.header)) {
+		http.Error(w, "invalid or missing signature", http.StatusUnauthorized)
+		return
+	}
+	events, err := Decode(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.enqueue(r.Context(), events); err != nil {
+		w.Header().Set("Retry-After", "5")
+		if errors.Is(err, errQueueFull) {
+			http.Error(w, "queue full", http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "could not store events", http.StatusServiceUnavailable)
+		return
+	}
+	if s.notify != nil {
+		s.notify()
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// enqueue stores and queues events whose ids have not been seen, all or
+// none. It returns errQueueFull when they do not fit.
+func (s *Source) enqueue(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fresh := events[:0:0]
+	for _, ev := range events {
+		if ev.ID != "" {
+			if _, dup := s.seen[ev.ID]; dup {
+				continue
+			}
+		}
+		fresh = append(fresh, ev)
+	}
+	if len(s.queue)+len(fresh) > DefaultQueueSize {
+		return errQueueFull
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+	now := s.nowFunc().UTC()
+	rows := make([]storage.Ingested, len(fresh))
+	for i := range fresh {
+		if fresh[i].Timestamp.IsZero() {
+			fresh[i].Timestamp = now
+		}
+		ev := fresh[i]
+		b, err := json.Marshal(record{Event: ev, Timestamp: ev.Timestamp, Args: ev.Args})
+		if err != nil {
+			return fmt.Errorf("event %s: %w", eventLabel(ev), err)
+		}
+		rows[i] = storage.Ingested{EventID: ev.ID, EventJSON: string(b)}
+	}
+	seqs, err := s.store.EnqueueIngested(ctx, s.source.ID, rows)
+	if err != nil {
+		return err
+	}
+	for i, ev := range fresh {
+		s.remember(ev)
+		s.queue = append(s.queue, queued{seq: seqs[i], ev: ev})
+	}
+	return nil
+}
+
+// remember marks ev's id seen and raises the tip to its height.
+func (s *Source) remember(ev Event) {
+	if ev.ID != "" {
+		s.seen[ev.ID] = struct{}{}
+		s.ids = append(s.ids, ev.ID)
+		if len(s.ids) > DefaultQueueSize {
+			delete(s.seen, s.ids[0])
+			s.ids = s.ids[1:]
+		}
+	}
+	if ev.Height > s.tip.Load() {
+		s.tip.Store(ev.Height)
+	}
+}
+
+// ProcessNextFunc matches the rules against the queued events and hands
+// each match to emit. Events are taken off the queue, and out of the
+// store, as they are handled, so if emit fails the rest stay queued for
+// the next call.
+func (s *Source) ProcessNextFunc(ctx context.Context, emit func(source.Event) error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		q := s.queue[0]
+		s.mu.Unlock()
+		ev := q.ev
+
+		for _, m := range s.matchers {
+			out, ok := m.Match(ev)
+			if !ok {
+				continue
+			}
+			out.SourceID = s.source.ID
+			if out.Chain == "" {
+				out.Chain = s.Chain()
+			}
+			if err := emit(out); err != nil {
+				return fmt.Errorf("event %s: %w", eventLabel(ev), err)
+			}
+		}
+		if err := s.store.DeleteIngested(ctx, q.seq); err != nil {
+			return fmt.Errorf("event %s: %w", eventLabel(ev), err)
+		}
+
+		s.mu.Lock()
+		s.queue[0] = queued{}
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+	}
+}
+
+// SkipNext drops the oldest queued event without matching it and returns
+// its height.
+func (s *Source) SkipNext(ctx context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return 0, fmt.Errorf("source %s has no queued events", s.source.ID)
+	}
+	q := s.queue[0]
+	if err := s.store.DeleteIngested(ctx, q.seq); err != nil {
+		return 0, err
+	}
+	s.queue[0] = queued{}
+	s.queue = s.queue[1:]
+	return q.ev.Height, nil
+}
+
+// Chain returns the chain events that name none are put on: the source's
+// chain, or Chain.
+func (s *Source) Chain() string {
+	if s.source.Chain != "" {
+		return s.source.Chain
+	}
+	return Chain
+}
+
+func eventLabel(ev Event) string {
+	if ev.ID != "" {
+		return ev.ID
+	}
+	if ev.TxHash != "" {
+		return ev.TxHash
+	}
+	return "without id"
+}
@@ -0,0 +1,247 @@
+package ingest
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+const testSecret = "s3cret"
+
+// testTime is the unix time test requests are signed at, the time
+// newTestSource's clock reads.
+const testTime = "1714564800"
+
+func post(t *testing.T, h http.Handler, path, body, sig string) int {
+	t.Helper()
+	return postAt(t, h, path, testTime, body, sig)
+}
+
+func postAt(t *testing.T, h http.Handler, path, ts, body, sig string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if sig != "" {
+		req.Header.Set(DefaultSignatureHeader, sig)
+	}
+	if ts != "" {
+		req.Header.Set(DefaultTimestampHeader, ts)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func newTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.Open(t.TempDir() + "/db.sqlite")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func newTestSource(t *testing.T, rules ...config.Rule) *Source {
+	t.Helper()
+	return newStoredSource(t, newTestStore(t), rules...)
+}
+
+func newStoredSource(t *testing.T, store *storage.Store, rules ...config.Rule) *Source {
+	t.Helper()
+	s, err := NewSource(store, config.Source{ID: "hooks", Type: "ingest", Listen: ":0", Secret: testSecret, Chain: "arbitrum"}, rules)
+	if err != nil {
+		t.Fatalf("new source: %v", err)
+	}
+	s.nowFunc = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func TestSourceAcceptsSignedEvents(t *testing.T) {
+	rules := []config.Rule{
+		{ID: "all", Source: "hooks", Match: config.MatchSpec{Type: config.MatchIngest}},
+		{ID: "swaps", Source: "hooks", Match: config.MatchSpec{Type: config.MatchIngest, Event: "Swap"}},
+		{ID: "watch", Source: config.AllSources, Match: config.MatchSpec{Type: config.MatchWatchAddress, Addresses: []string{"0x52908400098527886E0F7030069857D2E4169EE7"}}},
+	}
+	s := newTestSource(t, rules...)
+	notified := 0
+	s.SetNotify(func() { notified++ })
+	h := Handler(map[string]*Source{"hooks": s})
+
+	body := `[{"id":"e1","name":"Swap","height":200,"tx_hash":"0xabc","log_index":3,"timestamp":1714564800,"args":{"amount":"5","usd":12.5,"big":123456789012345678901234567890}},
+	          {"id":"e2","name":"Deposit","args":{"amount":7}}]`
+	if code := post(t, h, Path("hooks"), body, ""); code != http.StatusUnauthorized {
+		t.Fatalf("unsigned: got %d", code)
+	}
+	if code := post(t, h, Path("hooks"), body, Sign([]byte("wrong"), testTime, []byte(body))); code != http.StatusUnauthorized {
+		t.Fatalf("wrong key: got %d", code)
+	}
+	if code := post(t, h, Path("hooks"), `{"args":1}`, Sign([]byte(testSecret), testTime, []byte(`{"args":1}`))); code != http.StatusBadRequest {
+		t.Fatalf("bad body: got %d", code)
+	}
+	if code := post(t, h, Path("hooks"), body, "sha256="+Sign([]byte(testSecret), testTime, []byte(body))); code != http.StatusAccepted {
+		t.Fatalf("signed: got %d", code)
+	}
+	// A redelivery is accepted but not queued again.
+	if code := post(t, h, Path("hooks"), body, Sign([]byte(testSecret), testTime, []byte(body))); code != http.StatusAccepted {
+		t.Fatalf("redelivery: got %d", code)
+	}
+	if s.Pending() != 2 || notified != 2 || s.Tip() != 200 {
+		t.Fatalf("expected 2 queued events and 2 notifications, got %d, %d (tip %d)", s.Pending(), notified, s.Tip())
+	}
+
+	var got []source.Event
+	if err := s.ProcessNextFunc(context.Background(), func(ev source.Event) error {
+		got = append(got, ev)
+		return nil
+	}); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(got) != 3 || s.Pending() != 0 {
+		t.Fatalf("expected the swap matched twice and the deposit once, got %d", len(got))
+	}
+	ev := got[0]
+	if ev.RuleID != "all" || ev.Chain != "arbitrum" || ev.SourceID != "hooks" || ev.Height != 200 || ev.TxHash != "0xabc" || *ev.LogIndex != 3 || !ev.Timestamp.Equal(time.Unix(1714564800, 0)) {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if ev.Args["event"] != "Swap" || ev.Args["amount"] != "5" || ev.Args["usd"] != 12.5 {
+		t.Fatalf("unexpected args %v", ev.Args)
+	}
+	if b, ok := ev.Args["big"].(*big.Int); !ok || b.String() != "123456789012345678901234567890" {
+		t.Fatalf("expected a big integer arg, got %T %v", ev.Args["big"], ev.Args["big"])
+	}
+	if got[1].RuleID != "swaps" {
+		t.Fatalf("expected the swaps rule to match the swap, got %+v", got[1])
+	}
+	if d := got[2]; d.RuleID != "all" || d.TxHash != "e2" || d.Args["amount"] != int64(7) || !d.Timestamp.Equal(s.nowFunc()) {
+		t.Fatalf("unexpected deposit %+v", d)
+	}
+}
+
+func TestSourceQueueFull(t *testing.T) {
+	s := newTestSource(t)
+	s.queue = make([]queued, DefaultQueueSize)
+	h := Handler(map[string]*Source{"hooks": s})
+	body := `{"name":"Ping","args":{}}`
+	if code := post(t, h, Path("hooks"), body, Sign([]byte(testSecret), testTime, []byte(body))); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected a full queue to answer 503, got %d", code)
+	}
+	if code := post(t, h, Path("other"), body, Sign([]byte(testSecret), testTime, []byte(body))); code != http.StatusNotFound {
+		t.Fatalf("expected an unknown source to answer 404, got %d", code)
+	}
+}
+
+func TestSourceKeepsQueueAcrossRestarts(t *testing.T) {
+	store := newTestStore(t)
+	rules := []config.Rule{{ID: "all", Source: "hooks", Match: config.MatchSpec{Type: config.MatchIngest}}}
+	s := newStoredSource(t, store, rules...)
+	h := Handler(map[string]*Source{"hooks": s})
+	body := `[{"id":"e1","name":"Swap","height":200,"log_index":3,"args":{"amount":7,"usd":12.5,"big":123456789012345678901234567890}},{"id":"e2","name":"Ping","args":{}}]`
+	if code := post(t, h, Path("hooks"), body, Sign([]byte(testSecret), testTime, []byte(body))); code != http.StatusAccepted {
+		t.Fatalf("signed: got %d", code)
+	}
+
+	// A new source on the same store picks up what was acknowledged.
+	s = newStoredSource(t, store, rules...)
+	if s.Pending() != 2 || s.Tip() != 200 {
+		t.Fatalf("expected 2 events restored at tip 200, got %d at %d", s.Pending(), s.Tip())
+	}
+	h = Handler(map[string]*Source{"hooks": s})
+	if code := post(t, h, Path("hooks"), body, Sign([]byte(testSecret), testTime, []byte(body))); code != http.StatusAccepted || s.Pending() != 2 {
+		t.Fatalf("expected a redelivery of restored events dropped, got %d with %d queued", code, s.Pending())
+	}
+	if _, err := s.SkipNext(context.Background()); err != nil {
+		t.Fatalf("skip: %v", err)
+	}
+	var got []source.Event
+	if err := s.ProcessNextFunc(context.Background(), func(ev source.Event) error {
+		got = append(got, ev)
+		return nil
+	}); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "Ping" || !got[0].Timestamp.Equal(s.nowFunc()) {
+		t.Fatalf("expected the ping stamped at receipt, got %+v", got)
+	}
+	if s = newStoredSource(t, store, rules...); s.Pending() != 0 {
+		t.Fatalf("expected handled events gone from the store, got %d", s.Pending())
+	}
+
+	s = newStoredSource(t, store, rules...)
+	h = Handler(map[string]*Source{"hooks": s})
+	other := `[{"id":"e3","name":"Swap","height":201,"args":{"amount":7,"usd":12.5,"big":123456789012345678901234567890}}]`
+	if code := post(t, h, Path("hooks"), other, Sign([]byte(testSecret), testTime, []byte(other))); code != http.StatusAccepted {
+		t.Fatalf("signed: got %d", code)
+	}
+	s = newStoredSource(t, store, rules...)
+	got = nil
+	if err := s.ProcessNextFunc(context.Background(), func(ev source.Event) error {
+		got = append(got, ev)
+		return nil
+	}); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected the restored swap, got %+v", got)
+	}
+	ev := got[0]
+	if ev.Height != 201 || ev.Args["amount"] != int64(7) || ev.Args["usd"] != 12.5 {
+		t.Fatalf("unexpected restored event %+v", ev)
+	}
+	if b, ok := ev.Args["big"].(*big.Int); !ok || b.String() != "123456789012345678901234567890" {
+		t.Fatalf("expected a big integer arg, got %T %v", ev.Args["big"], ev.Args["big"])
+	}
+}
+
+func TestSourceAnswers503WhenStoreFails(t *testing.T) {
+	store := newTestStore(t)
+	s := newStoredSource(t, store)
+	_ = store.Close()
+	h := Handler(map[string]*Source{"hooks": s})
+	body := `{"name":"Ping","args":{}}`
+	if code := post(t, h, Path("hooks"), body, Sign([]byte(testSecret), testTime, []byte(body))); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected an unstored event to answer 503, got %d", code)
+	}
+	if s.Pending() != 0 {
+		t.Fatalf("expected nothing queued, got %d", s.Pending())
+	}
+}
+
+func TestSourceRejectsReplays(t *testing.T) {
+	s := newTestSource(t)
+	h := Handler(map[string]*Source{"hooks": s})
+	body := `{"name":"Ping","args":{}}`
+	if code := postAt(t, h, Path("hooks"), "", body, Sign([]byte(testSecret), "", []byte(body))); code != http.StatusUnauthorized {
+		t.Fatalf("expected a request without a timestamp refused, got %d", code)
+	}
+	// A request captured earlier is refused once it is older than the window.
+	old := "1714564200"
+	if code := postAt(t, h, Path("hooks"), old, body, Sign([]byte(testSecret), old, []byte(body))); code != http.StatusUnauthorized {
+		t.Fatalf("expected a replayed request refused, got %d", code)
+	}
+	// Moving the timestamp forward breaks the signature.
+	if code := post(t, h, Path("hooks"), body, Sign([]byte(testSecret), old, []byte(body))); code != http.StatusUnauthorized {
+		t.Fatalf("expected a retimed request refused, got %d", code)
+	}
+	if s.Pending() != 0 {
+		t.Fatalf("expected nothing queued, got %d", s.Pending())
+	}
+
+	// A source taking body-only signatures, as some providers send, skips the check.
+	store := newTestStore(t)
+	s, err := NewSource(store, config.Source{ID: "hooks", Type: "ingest", Listen: ":0", Secret: testSecret, BodyOnlySignature: true}, nil)
+	if err != nil {
+		t.Fatalf("new source: %v", err)
+	}
+	h = Handler(map[string]*Source{"hooks": s})
+	if code := postAt(t, h, Path("hooks"), "", body, Sign([]byte(testSecret), "", []byte(body))); code != http.StatusAccepted {
+		t.Fatalf("expected a body-only signature accepted, got %d", code)
+	}
+}
@@ -0,0 +1,6 @@
+package ingest
+
+// Chain is the chain of ingested events when neither they nor their source
+// name one. Their Hash is the block hash, when the sender gave one, and
+// TxHash the tx hash, or the event id when the sender gave none.
+const Chain = "ingest"
@@ -0,0 +1,349 @@
+package near
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/rpclimit"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// ErrBlockNotFound is returned for a height the node has no block at.
+// NEAR skips heights when a block producer misses its slot, and nodes
+// without archival history have none for old heights either.
+var ErrBlockNotFound = errors.New("block not found")
+
+// Client is the slice of the NEAR JSON-RPC API the scanner uses.
+type Client interface {
+	LatestHeight(ctx context.Context) (uint64, error)
+	FinalizedHeight(ctx context.Context) (uint64, error)
+	Block(ctx context.Context, height uint64) (*Block, error)
+	Chunk(ctx context.Context, hash string) (*Chunk, error)
+}
+
+// Block is a block's header and the chunks it includes, one per shard.
+type Block struct {
+	Hash     string
+	PrevHash string
+	Height   uint64
+	Time     time.Time
+	Chunks   []ChunkHeader
+}
+
+// ChunkHeader names a shard's chunk. A shard whose producer missed the
+// block repeats its previous chunk, with HeightIncluded below the block's.
+type ChunkHeader struct {
+	Hash           string `json:"chunk_hash"`
+	ShardID        uint64 `json:"shard_id"`
+	HeightIncluded uint64 `json:"height_included"`
+}
+
+// Chunk holds the transactions a shard took in and the receipts it routed.
+type Chunk struct {
+	Transactions []Transaction `json:"transactions"`
+	Receipts     []Receipt     `json:"receipts"`
+}
+
+// Transaction is a signed transaction. Unless it is sent to the signer's
+// own account, it runs as a receipt that shows up in a later chunk.
+type Transaction struct {
+	Hash       string   `json:"hash"`
+	SignerID   string   `json:"signer_id"`
+	ReceiverID string   `json:"receiver_id"`
+	Actions    []Action `json:"actions"`
+}
+
+// Receipt is a unit of work sent from PredecessorID to ReceiverID. Data
+// receipts, which carry a result back to a waiting call, have no actions.
+type Receipt struct {
+	ID            string
+	PredecessorID string
+	ReceiverID    string
+	SignerID      string // the account that signed the originating transaction
+	Actions       []Action
+}
+
+// UnmarshalJSON reads the RPC's receipt view, flattening action receipts.
+func (r *Receipt) UnmarshalJSON(b []byte) error {
+	var v struct {
+		ID            string `json:"receipt_id"`
+		PredecessorID string `json:"predecessor_id"`
+		ReceiverID    string `json:"receiver_id"`
+		Receipt       struct {
+			Action *struct {
+				SignerID string   `json:"signer_id"`
+				Actions  []Action `json:"actions"`
+			} `json:"Action"`
+		} `json:"receipt"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*r = Receipt{ID: v.ID, PredecessorID: v.PredecessorID, ReceiverID: v.ReceiverID}
+	if a := v.Receipt.Action; a != nil {
+		r.SignerID = a.SignerID
+		r.Actions = a.Actions
+	}
+	return nil
+}
+
+// Action is one step of a receipt or transaction, such as a FunctionCall
+// or a Transfer.
+type Action struct {
+	Kind string
+	// MethodName, Args and Gas are set for FunctionCall actions.
+	MethodName string
+	Args       []byte
+	Gas        uint64
+	// Deposit is the yoctoNEAR attached to a FunctionCall or Transfer.
+	Deposit *big.Int
+	// Params holds the fields of other kinds, such as DeleteAccount's
+	// beneficiary_id, by their RPC names.
+	Params map[string]any
+}
+
+// UnmarshalJSON reads an action, which the RPC sends as its kind alone
+// ("CreateAccount") or as an object keyed by its kind.
+func (a *Action) UnmarshalJSON(b []byte) error {
+	var kind string
+	if json.Unmarshal(b, &kind) == nil {
+		*a = Action{Kind: kind}
+		return nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	if len(obj) != 1 {
+		return fmt.Errorf("action with %d kinds", len(obj))
+	}
+	for kind, body := range obj {
+		*a = Action{Kind: kind}
+		switch kind {
+		case "FunctionCall":
+			var fc struct {
+				MethodName string      `json:"method_name"`
+				Args       []byte      `json:"args"`
+				Gas        json.Number `json:"gas"`
+				Deposit    string      `json:"deposit"`
+			}
+			if err := json.Unmarshal(body, &fc); err != nil {
+				return fmt.Errorf("FunctionCall: %w", err)
+			}
+			a.MethodName, a.Args = fc.MethodName, fc.Args
+			if fc.Gas != "" {
+				gas, err := strconv.ParseUint(fc.Gas.String(), 10, 64)
+				if err != nil {
+					return fmt.Errorf("FunctionCall gas: %w", err)
+				}
+				a.Gas = gas
+			}
+			var err error
+			if a.Deposit, err = parseYocto(fc.Deposit); err != nil {
+				return err
+			}
+		case "Transfer":
+			var t struct {
+				Deposit string `json:"deposit"`
+			}
+			if err := json.Unmarshal(body, &t); err != nil {
+				return fmt.Errorf("Transfer: %w", err)
+			}
+			var err error
+			if a.Deposit, err = parseYocto(t.Deposit); err != nil {
+				return err
+			}
+		default:
+			if err := json.Unmarshal(body, &a.Params); err != nil {
+				return fmt.Errorf("%s: %w", kind, err)
+			}
+			delete(a.Params, "code") // DeployContract's wasm
+		}
+	}
+	return nil
+}
+
+func parseYocto(s string) (*big.Int, error) {
+	if s == "" {
+		return new(big.Int), nil
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("invalid deposit %q", s)
+	}
+	return n, nil
+}
+
+// RPCError is an error answer from the node. Cause names what went wrong,
+// such as UNKNOWN_BLOCK.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Name    string `json:"name"`
+	Cause   struct {
+		Name string `json:"name"`
+	} `json:"cause"`
+	Data any `json:"data"`
+}
+
+func (e *RPCError) Error() string {
+	msg := fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+	if e.Cause.Name != "" {
+		msg += ": " + e.Cause.Name
+	}
+	if s, ok := e.Data.(string); ok && s != "" {
+		msg += ": " + s
+	}
+	return msg
+}
+
+// RPCClient calls a NEAR node's JSON-RPC API over HTTP. With several URLs,
+// a request that fails to connect or gets a 5xx answer is retried on the
+// next.
+type RPCClient struct {
+	urls    []string
+	header  http.Header
+	http    *http.Client
+	limiter *rpclimit.Limiter
+}
+
+// DefaultTimeout bounds each request to the node.
+const DefaultTimeout = 30 * time.Second
+
+// NewRPCClient builds a client for a source's rpc_url, sending header with
+// every request and making at most rps requests per second (0 for no cap).
+// Throttled and transient failures are retried with backoff.
+func NewRPCClient(urls []string, header http.Header, rps float64) (*RPCClient, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no rpc endpoints")
+	}
+	return &RPCClient{urls: urls, header: header, http: &http.Client{Timeout: DefaultTimeout}, limiter: source.NewLimiter(rps)}, nil
+}
+
+type blockView struct {
+	Header struct {
+		Hash      string `json:"hash"`
+		PrevHash  string `json:"prev_hash"`
+		Height    uint64 `json:"height"`
+		Timestamp uint64 `json:"timestamp"` // nanoseconds
+	} `json:"header"`
+	Chunks []ChunkHeader `json:"chunks"`
+}
+
+// LatestHeight implements Client.
+func (c *RPCClient) LatestHeight(ctx context.Context) (uint64, error) {
+	var b blockView
+	if err := c.call(ctx, "block", map[string]any{"finality": "optimistic"}, &b); err != nil {
+		return 0, err
+	}
+	return b.Header.Height, nil
+}
+
+// FinalizedHeight implements Client.
+func (c *RPCClient) FinalizedHeight(ctx context.Context) (uint64, error) {
+	var b blockView
+	if err := c.call(ctx, "block", map[string]any{"finality": "final"}, &b); err != nil {
+		return 0, err
+	}
+	return b.Header.Height, nil
+}
+
+// Block implements Client.
+func (c *RPCClient) Block(ctx context.Context, height uint64) (*Block, error) {
+	var b blockView
+	if err := c.call(ctx, "block", map[string]any{"block_id": height}, &b); err != nil {
+		if unknown(err) {
+			return nil, fmt.Errorf("%w at height %d", ErrBlockNotFound, height)
+		}
+		return nil, err
+	}
+	h := b.Header
+	return &Block{Hash: h.Hash, PrevHash: h.PrevHash, Height: h.Height, Time: time.Unix(0, int64(h.Timestamp)).UTC(), Chunks: b.Chunks}, nil
+}
+
+// Chunk implements Client.
+func (c *RPCClient) Chunk(ctx context.Context, hash string) (*Chunk, error) {
+	var ch Chunk
+	if err := c.call(ctx, "chunk", map[string]any{"chunk_id": hash}, &ch); err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+// unknown reports whether err is the node having no block at the height.
+func unknown(err error) bool {
+	var r *RPCError
+	return errors.As(err, &r) && r.Cause.Name == "UNKNOWN_BLOCK"
+}
+
+func (c *RPCClient) call(ctx context.Context, method string, params map[string]any, out any) error {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", method, err)
+	}
+	return c.limiter.Do(ctx, func() error {
+		var lastErr error
+		for _, url := range c.urls {
+			err := c.post(ctx, url, body, out)
+			if err == nil || !source.FailsOver(err) {
+				return err
+			}
+			lastErr = err
+		}
+		return fmt.Errorf("%s: %w", method, lastErr)
+	})
+}
+
+func (c *RPCClient) post(ctx context.Context, url string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header = c.header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		// Recent nodes answer handler errors, such as an unknown block,
+		// with a 4xx and the error in the body.
+		var rpcResp struct {
+			Error *RPCError `json:"error"`
+		}
+		if resp.StatusCode < 500 && json.Unmarshal(msg, &rpcResp) == nil && rpcResp.Error != nil {
+			return rpcResp.Error
+		}
+		if len(msg) > 512 {
+			msg = msg[:512]
+		}
+		return &source.HTTPError{Status: resp.StatusCode, Body: string(bytes.TrimSpace(msg))}
+	}
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if err := json.Unmarshal(rpcResp.Result, out); err != nil {
+		return fmt.Errorf("decode result: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package near
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testChunk = `{
+  "header": {"chunk_hash": "CH1", "shard_id": 2},
+  "transactions": [
+    {"hash": "TX1", "signer_id": "alice.near", "receiver_id": "alice.near", "actions": [{"AddKey": {"public_key": "ed25519:abc", "access_key": {"nonce": 0, "permission": "FullAccess"}}}]}
+  ],
+  "receipts": [
+    {"predecessor_id": "alice.near", "receiver_id": "usdt.tether-token.near", "receipt_id": "R1",
+     "receipt": {"Action": {"signer_id": "alice.near", "actions": [
+       {"FunctionCall": {"method_name": "ft_transfer", "args": "eyJyZWNlaXZlcl9pZCI6ImJvYi5uZWFyIiwiYW1vdW50IjoiMjUwMDAwMDAwMCJ9", "gas": 30000000000000, "deposit": "1"}},
+       "CreateAccount",
+       {"Transfer": {"deposit": "1000000000000000000000000"}},
+       {"DeployContract": {"code": "AGFzbQ=="}}
+     ]}}},
+    {"predecessor_id": "system", "receiver_id": "alice.near", "receipt_id": "R2",
+     "receipt": {"Action": {"signer_id": "system", "actions": [{"Transfer": {"deposit": "5"}}]}}},
+    {"predecessor_id": "bob.near", "receiver_id": "alice.near", "receipt_id": "R3",
+     "receipt": {"Data": {"data_id": "D1", "data": null}}}
+  ]
+}`
+
+func TestRPCClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string         `json:"method"`
+			Params map[string]any `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case req.Method == "block" && req.Params["finality"] == "final":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"header":{"height":120000000}}}`))
+		case req.Method == "block" && req.Params["finality"] == "optimistic":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"header":{"height":120000002}}}`))
+		case req.Method == "block" && req.Params["block_id"] == 120000001.0:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"name":"HANDLER_ERROR","cause":{"name":"UNKNOWN_BLOCK","info":{}},"code":-32000,"message":"Server error","data":"DB Not Found Error: BLOCK HEIGHT: 120000001"}}`))
+		case req.Method == "block":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"header":{"hash":"H","prev_hash":"P","height":120000000,"timestamp":1714564800123456789},"chunks":[{"chunk_hash":"CH1","shard_id":2,"height_included":120000000}]}}`))
+		case req.Method == "chunk" && req.Params["chunk_id"] != "CH1":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"name":"HANDLER_ERROR","cause":{"name":"UNKNOWN_CHUNK","info":{}},"code":-32000,"message":"Server error"}}`))
+		case req.Method == "chunk":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + testChunk + `}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewRPCClient([]string{srv.URL}, nil, 0)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	ctx := context.Background()
+	if h, err := c.LatestHeight(ctx); err != nil || h != 120000002 {
+		t.Fatalf("latest height: %d, %v", h, err)
+	}
+	if h, err := c.FinalizedHeight(ctx); err != nil || h != 120000000 {
+		t.Fatalf("finalized height: %d, %v", h, err)
+	}
+	if _, err := c.Block(ctx, 120000001); !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf("expected a skipped height, got %v", err)
+	}
+	b, err := c.Block(ctx, 120000000)
+	if err != nil {
+		t.Fatalf("block: %v", err)
+	}
+	if b.Hash != "H" || b.PrevHash != "P" || !b.Time.Equal(time.Unix(0, 1714564800123456789)) || len(b.Chunks) != 1 || b.Chunks[0].HeightIncluded != 120000000 {
+		t.Fatalf("unexpected block %+v", b)
+	}
+	ch, err := c.Chunk(ctx, "CH1")
+	if err != nil {
+		t.Fatalf("chunk: %v", err)
+	}
+	if len(ch.Transactions) != 1 || len(ch.Receipts) != 3 {
+		t.Fatalf("unexpected chunk %+v", ch)
+	}
+	r := ch.Receipts[0]
+	if r.ID != "R1" || r.SignerID != "alice.near" || len(r.Actions) != 4 {
+		t.Fatalf("unexpected receipt %+v", r)
+	}
+	if a := r.Actions[0]; a.Kind != "FunctionCall" || a.MethodName != "ft_transfer" || a.Gas != 30000000000000 || a.Deposit.Int64() != 1 {
+		t.Fatalf("unexpected function call %+v", a)
+	}
+	if a := r.Actions[1]; a.Kind != "CreateAccount" {
+		t.Fatalf("unexpected action %+v", a)
+	}
+	if a := r.Actions[2]; a.Kind != "Transfer" || a.Deposit.String() != "1000000000000000000000000" {
+		t.Fatalf("unexpected transfer %+v", a)
+	}
+	if a := r.Actions[3]; a.Kind != "DeployContract" || a.Params["code"] != nil {
+		t.Fatalf("expected the contract code dropped, got %+v", a)
+	}
+	if r := ch.Receipts[2]; r.ID != "R3" || r.Actions != nil {
+		t.Fatalf("expected a data receipt without actions, got %+v", r)
+	}
+	var rpcErr *RPCError
+	if _, err := c.Chunk(ctx, "missing"); !errors.As(err, &rpcErr) || rpcErr.Cause.Name != "UNKNOWN_CHUNK" {
+		t.Fatalf("expected an unknown chunk error, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package near
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// ActivityEvent is the event name of watch_address matches on every chain.
+const ActivityEvent = "address_activity"
+
+// SystemAccount sends the gas refunds that follow most calls.
+const SystemAccount = "system"
+
+// accountID matches a NEAR account id, named (alice.near) or implicit (64
+// hex characters).
+var accountID = regexp.MustCompile(`^(([a-z\d]+[-_])*[a-z\d]+\.)*([a-z\d]+[-_])*[a-z\d]+$`)
+
+// RuleMatcher applies one rule to the receipts of a block.
+type RuleMatcher struct {
+	rule     config.Rule
+	kind     string
+	receiver string              // receipt
+	methods  map[string]struct{} // receipt: empty for every action
+	accounts map[string]struct{} // watch_address
+}
+
+// NewRuleMatcher builds a matcher for NEAR rules.
+func NewRuleMatcher(rule config.Rule) (*RuleMatcher, error) {
+	mt := strings.ToLower(rule.Match.Type)
+	m := &RuleMatcher{rule: rule, kind: mt, methods: map[string]struct{}{}, accounts: map[string]struct{}{}}
+	switch mt {
+	case config.MatchReceipt:
+		m.receiver = rule.Match.Account
+		for _, name := range rule.Match.Methods {
+			m.methods[name] = struct{}{}
+		}
+	case config.MatchWatchAddress:
+		// The list is shared with other chains, so entries that are not
+		// NEAR account ids, such as checksummed EVM addresses, are skipped.
+		for _, a := range rule.Match.Addresses {
+			if len(a) >= 2 && len(a) <= 64 && accountID.MatchString(a) {
+				m.accounts[a] = struct{}{}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("rule %s: unsupported match.type %s for near", rule.ID, rule.Match.Type)
+	}
+	return m, nil
+}
+
+// MatchReceipt returns the matches in one receipt. first is the position
+// of its first action among the block's, and txHash, when set, names the
+// transaction a local receipt stands for.
+func (m *RuleMatcher) MatchReceipt(r *Receipt, first uint, txHash string) []source.Event {
+	if len(r.Actions) == 0 || r.PredecessorID == SystemAccount {
+		return nil // data receipts and gas refunds
+	}
+	var out []source.Event
+	switch m.kind {
+	case config.MatchReceipt:
+		if r.ReceiverID != m.receiver {
+			return nil
+		}
+		for i, a := range r.Actions {
+			if len(m.methods) > 0 {
+				if _, ok := m.methods[a.MethodName]; !ok || a.Kind != "FunctionCall" {
+					continue
+				}
+			}
+			args := actionArgs(&a)
+			args["action_index"] = i
+			name := a.Kind
+			if a.Kind == "FunctionCall" {
+				name = a.MethodName
+			}
+			out = append(out, m.event(r, first+uint(i), txHash, name, args))
+		}
+	case config.MatchWatchAddress:
+		for _, c := range []struct{ role, account string }{
+			{"receiver", r.ReceiverID},
+			{"predecessor", r.PredecessorID},
+			{"signer", r.SignerID},
+		} {
+			if _, ok := m.accounts[c.account]; !ok {
+				continue
+			}
+			kinds := make([]string, len(r.Actions))
+			for i, a := range r.Actions {
+				kinds[i] = a.Kind
+			}
+			out = append(out, m.event(r, first, txHash, ActivityEvent, map[string]any{
+				"watched": c.account,
+				"role":    c.role,
+				"actions": kinds,
+			}))
+			break
+		}
+	}
+	return out
+}
+
+// event fills in what every match carries: the receipt's accounts and id.
+func (m *RuleMatcher) event(r *Receipt, index uint, txHash, name string, args map[string]any) source.Event {
+	args["receiver"] = r.ReceiverID
+	args["predecessor"] = r.PredecessorID
+	args["signer"] = r.SignerID
+	args["receipt_id"] = r.ID
+	if txHash == "" {
+		txHash = r.ID
+	}
+	return source.Event{RuleID: m.rule.ID, Name: name, Contract: r.ReceiverID, TxHash: txHash, LogIndex: &index, Args: args}
+}
+
+// actionArgs returns the args of one action. A function call's JSON
+// arguments become args by name; arguments that are not a JSON object are
+// kept base64-encoded as args. The action's own fields are added after, so
+// a call argument named like one of them is shadowed.
+func actionArgs(a *Action) map[string]any {
+	args := map[string]any{}
+	switch a.Kind {
+	case "FunctionCall":
+		if fields, ok := decodeJSONArgs(a.Args); ok {
+			for k, v := range fields {
+				args[k] = v
+			}
+		} else if len(a.Args) > 0 {
+			args["args"] = base64.StdEncoding.EncodeToString(a.Args)
+		}
+		args["method"] = a.MethodName
+		args["gas"] = a.Gas
+	default:
+		for k, v := range a.Params {
+			args[k] = v
+		}
+	}
+	args["action"] = a.Kind
+	deposit := a.Deposit
+	if deposit == nil {
+		deposit = new(big.Int)
+	}
+	args["deposit"] = deposit
+	return args
+}
+
+// decodeJSONArgs decodes call arguments that are a JSON object. Numbers
+// stay exact: those that fit an int64 become one and larger ones strings,
+// as amounts in NEAR contracts are usually strings already.
+func decodeJSONArgs(b []byte) (map[string]any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil || fields == nil {
+		return nil, false
+	}
+	for k, v := range fields {
+		fields[k] = jsonValue(v)
+	}
+	return fields, true
+}
+
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil && strings.ContainsAny(string(v), ".eE") {
+			return f
+		}
+		return string(v)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = jsonValue(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = jsonValue(e)
+		}
+	}
+	return v
+}
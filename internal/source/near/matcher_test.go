@@ -0,0 +1,108 @@
+package near
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+)
+
+func testReceipts(t *testing.T) *Chunk {
+	t.Helper()
+	var ch Chunk
+	if err := json.Unmarshal([]byte(testChunk), &ch); err != nil {
+		t.Fatalf("decode chunk: %v", err)
+	}
+	return &ch
+}
+
+func TestMatcher_Receipt(t *testing.T) {
+	ch := testReceipts(t)
+	m, err := NewRuleMatcher(config.Rule{ID: "r1", Match: config.MatchSpec{Type: config.MatchReceipt, Account: "usdt.tether-token.near", Methods: []string{"ft_transfer"}}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	got := m.MatchReceipt(&ch.Receipts[0], 7, "")
+	if len(got) != 1 {
+		t.Fatalf("expected the ft_transfer call, got %d", len(got))
+	}
+	ev := got[0]
+	if ev.Name != "ft_transfer" || ev.TxHash != "R1" || *ev.LogIndex != 7 || ev.Contract != "usdt.tether-token.near" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	want := map[string]any{
+		"receiver_id":  "bob.near",
+		"amount":       "2500000000",
+		"method":       "ft_transfer",
+		"action":       "FunctionCall",
+		"action_index": 0,
+		"gas":          uint64(30000000000000),
+		"receiver":     "usdt.tether-token.near",
+		"predecessor":  "alice.near",
+		"signer":       "alice.near",
+		"receipt_id":   "R1",
+	}
+	for k, v := range want {
+		if ev.Args[k] != v {
+			t.Fatalf("arg %s: got %v (%T), want %v", k, ev.Args[k], ev.Args[k], v)
+		}
+	}
+
+	all, err := NewRuleMatcher(config.Rule{ID: "r2", Match: config.MatchSpec{Type: config.MatchReceipt, Account: "usdt.tether-token.near"}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	got = all.MatchReceipt(&ch.Receipts[0], 7, "")
+	if len(got) != 4 {
+		t.Fatalf("expected every action, got %d", len(got))
+	}
+	if ev := got[2]; ev.Name != "Transfer" || *ev.LogIndex != 9 || ev.Args["deposit"].(interface{ String() string }).String() != "1000000000000000000000000" {
+		t.Fatalf("unexpected transfer %+v", ev)
+	}
+
+	refunds, err := NewRuleMatcher(config.Rule{ID: "r3", Match: config.MatchSpec{Type: config.MatchReceipt, Account: "alice.near"}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	for i := 1; i < 3; i++ {
+		if got := refunds.MatchReceipt(&ch.Receipts[i], 0, ""); len(got) != 0 {
+			t.Fatalf("receipt %d: expected gas refunds and data receipts skipped, got %+v", i, got)
+		}
+	}
+}
+
+func TestMatcher_WatchAddress(t *testing.T) {
+	ch := testReceipts(t)
+	m, err := NewRuleMatcher(config.Rule{ID: "w", Match: config.MatchSpec{
+		Type:      config.MatchWatchAddress,
+		Addresses: []string{"0x56315b90c40730925ec5485cf004d835058518A0", "alice.near"},
+	}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	if len(m.accounts) != 1 {
+		t.Fatalf("expected only the near account kept, got %v", m.accounts)
+	}
+	got := m.MatchReceipt(&ch.Receipts[0], 0, "")
+	if len(got) != 1 {
+		t.Fatalf("expected one activity event for the receipt, got %d", len(got))
+	}
+	if ev := got[0]; ev.Name != ActivityEvent || ev.Args["role"] != "predecessor" || ev.Args["watched"] != "alice.near" || len(ev.Args["actions"].([]string)) != 4 {
+		t.Fatalf("unexpected activity %+v", ev)
+	}
+}
+
+func TestDecodeJSONArgs(t *testing.T) {
+	args, ok := decodeJSONArgs([]byte(`{"n": 5, "big": 123456789012345678901234567890, "f": 1.5, "nested": {"m": 2}}`))
+	if !ok {
+		t.Fatal("expected a JSON object")
+	}
+	if args["n"] != int64(5) || args["big"] != "123456789012345678901234567890" || args["f"] != 1.5 || args["nested"].(map[string]any)["m"] != int64(2) {
+		t.Fatalf("unexpected args %#v", args)
+	}
+	for _, raw := range []string{``, `[1,2]`, `null`, "\x00\x01"} {
+		if _, ok := decodeJSONArgs([]byte(raw)); ok {
+			t.Fatalf("%q: expected no JSON object", raw)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package near
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/source/blocktime"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// Scanner walks a NEAR source block by block and matches its rules against
+// the receipts in each block's chunks.
+type Scanner struct {
+	client        Client
+	store         *storage.Store
+	source        config.Source
+	confirmations uint64
+	finalized     bool // scan up to the final block rather than the latest
+	matchers      []*RuleMatcher
+	tipTTL        time.Duration
+	nowFunc       func() time.Time
+	// maxBlocks is how many blocks one call may scan (source
+	// max_blocks_per_tick); stopAt, when set, is the last block to scan.
+	maxBlocks uint64
+	stopAt    uint64
+	// tip is the latest block seen, read by the dashboard.
+	tip   atomic.Uint64
+	tipAt time.Time
+}
+
+// NewScanner builds a scanner for a NEAR source and its rules.
+func NewScanner(client Client, store *storage.Store, src config.Source, confirmations uint64, rules []config.Rule) (*Scanner, error) {
+	s := &Scanner{
+		client:        client,
+		store:         store,
+		source:        src,
+		confirmations: confirmations,
+		tipTTL:        src.TipCacheTTL(),
+		nowFunc:       time.Now,
+		maxBlocks:     1,
+	}
+	if src.MaxBlocksPerTick > 1 {
+		s.maxBlocks = uint64(src.MaxBlocksPerTick)
+	}
+	commit, err := s.PrepareRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	commit()
+	return s, nil
+}
+
+// PrepareRules builds matchers for the source's rules without touching the
+// running scanner. Calling the returned commit func swaps them in.
+func (s *Scanner) PrepareRules(rules []config.Rule) (commit func(), err error) {
+	matchers := []*RuleMatcher{}
+	for _, r := range rules {
+		if !r.AppliesTo(s.source.ID) {
+			continue
+		}
+		if strings.EqualFold(r.Match.Type, config.MatchReorg) {
+			continue // raised by the engine when the scanner rewinds
+		}
+		m, err := NewRuleMatcher(r)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return func() {
+		s.matchers = matchers
+	}, nil
+}
+
+// SetFinality makes the scanner stay behind the final block when tag is
+// "finalized", on top of the confirmation depth.
+func (s *Scanner) SetFinality(tag string) {
+	s.finalized = tag == config.ConfirmFinalized
+}
+
+// SetStopHeight keeps a call from scanning past height, the end of a
+// bounded replay. Zero means no limit.
+func (s *Scanner) SetStopHeight(height uint64) {
+	s.stopAt = height
+}
+
+// Tip returns the latest block observed by ProcessNext, or 0 before the first poll.
+func (s *Scanner) Tip() uint64 {
+	return s.tip.Load()
+}
+
+// latestHeight returns the chain tip. The cached tip is reused while next is
+// still confirmed below it (catching up) or while it is younger than tipTTL.
+func (s *Scanner) latestHeight(ctx context.Context, next uint64) (uint64, error) {
+	if tip := s.tip.Load(); tip > 0 {
+		if next+s.confirmations <= tip || s.nowFunc().Sub(s.tipAt) < s.tipTTL {
+			return tip, nil
+		}
+	}
+	latest := s.client.LatestHeight
+	if s.finalized {
+		latest = s.client.FinalizedHeight
+	}
+	height, err := latest(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("latest block: %w", err)
+	}
+	if prev := s.tip.Load(); height < prev {
+		// The lower tip is not kept, so the next tick asks again.
+		return 0, &source.TipError{Tip: height, Previous: prev}
+	}
+	s.tip.Store(height)
+	s.tipAt = s.nowFunc()
+	return height, nil
+}
+
+// ProcessNext handles the next eligible block (respecting confirmations) and returns matched events.
+// A source with max_blocks_per_tick that is behind handles up to that many blocks at once.
+// On success advances the cursor. On reorg returns source.ErrReorgDetected after rewinding.
+func (s *Scanner) ProcessNext(ctx context.Context) ([]source.Event, error) {
+	var events []source.Event
+	err := s.ProcessNextFunc(ctx, func(ev source.Event) error {
+		events = append(events, ev)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ProcessNextFunc is ProcessNext with each matched event handed to emit as it
+// is decoded. The cursor moves after each block, so if emit fails it is left
+// at the last block fully handled and the error is returned.
+func (s *Scanner) ProcessNextFunc(ctx context.Context, emit func(source.Event) error) error {
+	curHeight, curHash, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+	if err != nil {
+		return err
+	}
+
+	latest, err := s.latestHeight(ctx, curHeight+1)
+	if err != nil {
+		return err
+	}
+	if hasCursor && latest < curHeight {
+		return &source.TipError{Tip: latest, Previous: curHeight, Cursor: true}
+	}
+	safe := latest
+	if s.confirmations > 0 {
+		if safe < s.confirmations {
+			return nil
+		}
+		safe -= s.confirmations
+	}
+
+	target := curHeight + 1
+	if !hasCursor {
+		start, err := s.resolveStart(ctx, safe)
+		if err != nil {
+			return err
+		}
+		target = start
+	}
+
+	if target > safe {
+		return nil
+	}
+
+	end := min(target+s.maxBlocks-1, safe)
+	if s.stopAt > 0 {
+		end = min(end, max(s.stopAt, target))
+	}
+	for height := target; height <= end; height++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hash, err := s.processBlock(ctx, height, curHeight, curHash, hasCursor, emit)
+		if err != nil {
+			return err
+		}
+		curHeight, curHash, hasCursor = height, hash, true
+	}
+	return nil
+}
+
+// processBlock matches the rules against the receipts of the block at
+// target, whose parent must be the block the cursor is at, and moves the
+// cursor to it. It returns the block's hash, or curHash when no block was
+// produced at target.
+func (s *Scanner) processBlock(ctx context.Context, target, curHeight uint64, curHash string, hasCursor bool, emit func(source.Event) error) (string, error) {
+	block, err := s.client.Block(ctx, target)
+	if errors.Is(err, ErrBlockNotFound) {
+		// A skipped height. The cursor keeps the last block's hash, which
+		// the next block must build on.
+		return curHash, s.store.UpsertCursor(ctx, s.source.ID, target, curHash)
+	}
+	if err != nil {
+		return "", fmt.Errorf("block %d: %w", target, err)
+	}
+	if hasCursor && curHash != "" && block.PrevHash != curHash {
+		// Blocks behind the final one cannot be reverted, so the scanner ran
+		// ahead of finality or the endpoint serves another network than
+		// before. Rescan from the block below the cursor, without a hash to
+		// check it against.
+		rewindTo := uint64(0)
+		if curHeight > 0 {
+			rewindTo = curHeight - 1
+		}
+		if err := s.store.UpsertCursor(ctx, s.source.ID, rewindTo, ""); err != nil {
+			return "", err
+		}
+		return "", &source.ReorgError{Height: target, From: curHeight, To: curHeight, OldHash: curHash, NewHash: block.PrevHash}
+	}
+
+	var index uint
+	match := func(r *Receipt, txHash string) error {
+		first := index
+		index += uint(len(r.Actions))
+		for _, m := range s.matchers {
+			for _, ev := range m.MatchReceipt(r, first, txHash) {
+				ev.Chain = Chain
+				ev.SourceID = s.source.ID
+				ev.Height = target
+				ev.Hash = block.Hash
+				ev.Timestamp = block.Time
+				if err := emit(ev); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, header := range block.Chunks {
+		if header.HeightIncluded != target {
+			continue // the shard's chunk from an earlier block, already scanned
+		}
+		chunk, err := s.client.Chunk(ctx, header.Hash)
+		if err != nil {
+			return "", fmt.Errorf("block %d chunk %s: %w", target, header.Hash, err)
+		}
+		// A transaction an account sends to itself runs at once as a
+		// local receipt, which no chunk lists.
+		for _, tx := range chunk.Transactions {
+			if tx.SignerID != tx.ReceiverID {
+				continue
+			}
+			local := Receipt{PredecessorID: tx.SignerID, ReceiverID: tx.ReceiverID, SignerID: tx.SignerID, Actions: tx.Actions}
+			if err := match(&local, tx.Hash); err != nil {
+				return "", err
+			}
+		}
+		for i := range chunk.Receipts {
+			if err := match(&chunk.Receipts[i], ""); err != nil {
+				return "", err
+			}
+		}
+	}
+	return block.Hash, s.store.UpsertCursor(ctx, s.source.ID, target, block.Hash)
+}
+
+// SkipNext advances the cursor past the next block without matching it.
+// It requires an existing cursor and returns the skipped height.
+func (s *Scanner) SkipNext(ctx context.Context) (uint64, error) {
+	curHeight, curHash, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+	if err != nil {
+		return 0, err
+	}
+	if !hasCursor {
+		return 0, fmt.Errorf("source %s has no cursor yet", s.source.ID)
+	}
+	target := curHeight + 1
+	hash := curHash
+	block, err := s.client.Block(ctx, target)
+	switch {
+	case err == nil:
+		hash = block.Hash
+	case !errors.Is(err, ErrBlockNotFound):
+		return 0, fmt.Errorf("block %d: %w", target, err)
+	}
+	if err := s.store.UpsertCursor(ctx, s.source.ID, target, hash); err != nil {
+		return 0, err
+	}
+	return target, nil
+}
+
+// resolveStart picks the first block for a source without a cursor. A
+// "time:" start_block is found by binary search over block times, taking
+// a skipped height's time from the next block.
+func (s *Scanner) resolveStart(ctx context.Context, safe uint64) (uint64, error) {
+	at, ok, err := blocktime.Parse(s.source.StartBlock)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return resolveStartHeight(s.source.StartBlock, safe)
+	}
+	return blocktime.Search(ctx, safe, at, func(ctx context.Context, height uint64) (time.Time, error) {
+		for h := height; ; h++ {
+			block, err := s.client.Block(ctx, h)
+			if errors.Is(err, ErrBlockNotFound) && h < safe {
+				continue
+			}
+			if err != nil {
+				return time.Time{}, err
+			}
+			return block.Time, nil
+		}
+	})
+}
+
+// resolveStartHeight reads a start_block: a height, or "latest" or
+// "latest-N" relative to the newest confirmed block. Nodes without archival
+// history keep only the last few epochs, so unlike on EVM sources it
+// defaults to the latest block.
+func resolveStartHeight(start string, safe uint64) (uint64, error) {
+	if start == "" || start == "latest" {
+		return safe, nil
+	}
+	if strings.HasPrefix(start, "latest-") {
+		n, err := strconv.ParseUint(strings.TrimPrefix(start, "latest-"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse start_block %q: %w", start, err)
+		}
+		if n > safe {
+			return 0, nil
+		}
+		return safe - n, nil
+	}
+	n, err := strconv.ParseUint(start, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse start_block %q: %w", start, err)
+	}
+	return n, nil
+}
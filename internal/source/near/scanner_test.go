@@ -0,0 +1,191 @@
+package near
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// fakeClient serves a chain of blocks by height, each with one chunk.
+type fakeClient struct {
+	tip       uint64
+	finalized uint64
+	blocks    map[uint64]*Block
+	chunks    map[string]*Chunk
+	fetched   []string // chunk hashes requested
+}
+
+func (f *fakeClient) LatestHeight(ctx context.Context) (uint64, error) {
+	return f.tip, nil
+}
+
+func (f *fakeClient) FinalizedHeight(ctx context.Context) (uint64, error) {
+	return f.finalized, nil
+}
+
+func (f *fakeClient) Block(ctx context.Context, height uint64) (*Block, error) {
+	b, ok := f.blocks[height]
+	if !ok {
+		return nil, fmt.Errorf("%w at height %d", ErrBlockNotFound, height)
+	}
+	return b, nil
+}
+
+func (f *fakeClient) Chunk(ctx context.Context, hash string) (*Chunk, error) {
+	f.fetched = append(f.fetched, hash)
+	return f.chunks[hash], nil
+}
+
+// add puts a block at height on the chain, building on the highest block
+// below it. Without receipts the shard's chunk is repeated from before.
+func (f *fakeClient) add(height uint64, hash string, receipts ...Receipt) {
+	if f.blocks == nil {
+		f.blocks, f.chunks = map[uint64]*Block{}, map[string]*Chunk{}
+	}
+	var prev *Block
+	for h := height - 1; h > 0 && prev == nil; h-- {
+		prev = f.blocks[h]
+	}
+	b := &Block{Hash: hash, Height: height, Time: time.Unix(1714564800+int64(height), 0).UTC()}
+	chunk := ChunkHeader{Hash: hash + "-c", HeightIncluded: height}
+	if prev != nil {
+		b.PrevHash = prev.Hash
+		if receipts == nil {
+			chunk = prev.Chunks[0]
+		}
+	}
+	b.Chunks = []ChunkHeader{chunk}
+	if chunk.HeightIncluded == height {
+		f.chunks[chunk.Hash] = &Chunk{Receipts: receipts}
+	}
+	f.blocks[height] = b
+	f.tip = max(f.tip, height)
+}
+
+func call(id, receiver, method string) Receipt {
+	return Receipt{ID: id, PredecessorID: "alice.near", ReceiverID: receiver, SignerID: "alice.near", Actions: []Action{{Kind: "FunctionCall", MethodName: method, Args: []byte(`{"amount":"10"}`)}}}
+}
+
+func newTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.Open(t.TempDir() + "/db.sqlite")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestScannerMatchesReceipts(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &fakeClient{}
+	client.add(100, "h100", call("R0", "token.near", "ft_transfer"))
+	client.add(101, "h101", call("R1", "token.near", "ft_transfer"), call("R2", "other.near", "ft_transfer"))
+	// 102 is skipped; 103 repeats the shard's chunk from 101.
+	client.add(103, "h103")
+	client.add(104, "h104", call("R4", "token.near", "ft_transfer"))
+	client.add(105, "h105", call("R5", "token.near", "ft_transfer"))
+	client.finalized = 104
+	if err := store.UpsertCursor(ctx, "near", 100, "h100"); err != nil {
+		t.Fatalf("seed cursor: %v", err)
+	}
+	rules := []config.Rule{{ID: "transfers", Source: "near", Match: config.MatchSpec{Type: config.MatchReceipt, Account: "token.near", Methods: []string{"ft_transfer"}}}}
+	sc, err := NewScanner(client, store, config.Source{ID: "near", Type: Chain, MaxBlocksPerTick: 10}, 0, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	sc.SetFinality(config.ConfirmFinalized)
+	events, err := sc.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(events) != 2 || events[0].TxHash != "R1" || events[1].TxHash != "R4" {
+		t.Fatalf("expected R1 and R4, got %+v", events)
+	}
+	ev := events[1]
+	if ev.Chain != Chain || ev.Height != 104 || ev.Hash != "h104" || ev.Args["amount"] != "10" || !ev.Timestamp.Equal(time.Unix(1714564904, 0)) {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if h, hash, _, _ := store.GetCursor(ctx, "near"); h != 104 || hash != "h104" {
+		t.Fatalf("expected cursor at the final block 104, got %d %s", h, hash)
+	}
+	if len(client.fetched) != 2 {
+		t.Fatalf("expected the repeated chunk not fetched again, got %v", client.fetched)
+	}
+}
+
+func TestScannerLocalReceipts(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &fakeClient{}
+	client.add(10, "h10", call("R", "bob.near", "x"))
+	client.chunks["h10-c"].Transactions = []Transaction{
+		{Hash: "TX1", SignerID: "alice.near", ReceiverID: "alice.near", Actions: []Action{{Kind: "DeleteKey", Params: map[string]any{"public_key": "ed25519:abc"}}}},
+		{Hash: "TX2", SignerID: "alice.near", ReceiverID: "bob.near", Actions: []Action{{Kind: "Transfer"}}},
+	}
+	rules := []config.Rule{{ID: "keys", Source: "near", Match: config.MatchSpec{Type: config.MatchReceipt, Account: "alice.near"}}}
+	sc, err := NewScanner(client, store, config.Source{ID: "near", Type: Chain, StartBlock: "10"}, 0, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	events, err := sc.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(events) != 1 || events[0].TxHash != "TX1" || events[0].Name != "DeleteKey" || events[0].Args["public_key"] != "ed25519:abc" || *events[0].LogIndex != 0 {
+		t.Fatalf("expected the local DeleteKey receipt, got %+v", events)
+	}
+}
+
+func TestScannerDetectsReorg(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &fakeClient{}
+	client.add(10, "h10", call("R", "x.near", "m"))
+	client.add(11, "h11", call("R", "x.near", "m"))
+	sc, err := NewScanner(client, store, config.Source{ID: "near", Type: Chain, StartBlock: "10", MaxBlocksPerTick: 10}, 0, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	sc.tipTTL = 0
+	if _, err := sc.ProcessNext(ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	client.blocks[12] = &Block{Hash: "h12", PrevHash: "h11b", Height: 12}
+	client.tip = 12
+	_, err = sc.ProcessNext(ctx)
+	var rg *source.ReorgError
+	if !errors.As(err, &rg) || rg.From != 11 || rg.OldHash != "h11" || rg.NewHash != "h11b" {
+		t.Fatalf("expected a reorg at 11, got %v", err)
+	}
+	if h, hash, _, _ := store.GetCursor(ctx, "near"); h != 10 || hash != "" {
+		t.Fatalf("expected cursor rewound to 10, got %d %q", h, hash)
+	}
+}
+
+func TestResolveStartSkipsMissingHeights(t *testing.T) {
+	client := &fakeClient{}
+	for h := uint64(1); h <= 20; h++ {
+		if h%3 != 0 {
+			client.add(h, fmt.Sprintf("h%d", h), call("R", "x.near", "m"))
+		}
+	}
+	sc, err := NewScanner(client, newTestStore(t), config.Source{ID: "near", Type: Chain, StartBlock: "time:" + time.Unix(1714564809, 0).UTC().Format(time.RFC3339)}, 0, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	got, err := sc.resolveStart(context.Background(), 20)
+	if err != nil {
+		t.Fatalf("resolve start: %v", err)
+	}
+	if got != 9 { // 9 is skipped and takes block 10's time
+		t.Fatalf("expected 9, got %d", got)
+	}
+}
@@ -0,0 +1,6 @@
+package near
+
+// Chain identifier for NEAR Protocol. Its events' TxHash is the receipt id,
+// or the transaction hash of a local receipt, LogIndex the position of the
+// action among the block's, and Contract the account the receipt went to.
+const Chain = "near"
@@ -0,0 +1,262 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/rpclimit"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// Client is the slice of the Solana JSON-RPC API the scanner uses.
+// Commitment is "confirmed" or "finalized".
+type Client interface {
+	GetSlot(ctx context.Context, commitment string) (uint64, error)
+	// GetBlock returns the slot's block with parsed instructions, or an
+	// error matching ErrSlotSkipped when no block was produced in it.
+	GetBlock(ctx context.Context, slot uint64, commitment string) (*Block, error)
+	GetBlockTime(ctx context.Context, slot uint64) (int64, error)
+}
+
+// ErrSlotSkipped is matched by errors for slots without a block: the leader
+// skipped it, or the node no longer stores it.
+var ErrSlotSkipped = errors.New("slot skipped")
+
+// Commitment levels the scanner reads at.
+const (
+	CommitmentConfirmed = "confirmed"
+	CommitmentFinalized = "finalized"
+)
+
+// JSON-RPC error codes of the Solana validator.
+const (
+	codeBlockNotAvailable          = -32004
+	codeSlotSkipped                = -32007
+	codeLongTermStorageSlotSkipped = -32009
+	codeLimitExceeded              = -32005
+)
+
+// Block is a block as getBlock returns it with jsonParsed encoding.
+type Block struct {
+	Blockhash         string        `json:"blockhash"`
+	PreviousBlockhash string        `json:"previousBlockhash"`
+	ParentSlot        uint64        `json:"parentSlot"`
+	BlockTime         *int64        `json:"blockTime"`
+	Transactions      []Transaction `json:"transactions"`
+}
+
+// Transaction is a block transaction and its execution status.
+type Transaction struct {
+	Transaction struct {
+		Signatures []string `json:"signatures"`
+		Message    Message  `json:"message"`
+	} `json:"transaction"`
+	Meta *TransactionMeta `json:"meta"`
+}
+
+// Signature returns the transaction's first signature, which identifies it.
+func (t *Transaction) Signature() string {
+	if len(t.Transaction.Signatures) == 0 {
+		return ""
+	}
+	return t.Transaction.Signatures[0]
+}
+
+// Message holds a transaction's accounts and top-level instructions. With
+// jsonParsed encoding, the accounts include those loaded from lookup tables.
+type Message struct {
+	AccountKeys  []AccountKey  `json:"accountKeys"`
+	Instructions []Instruction `json:"instructions"`
+}
+
+// AccountKey is one account a transaction references.
+type AccountKey struct {
+	Pubkey   string `json:"pubkey"`
+	Signer   bool   `json:"signer"`
+	Writable bool   `json:"writable"`
+}
+
+// Instruction is a program invocation. Instructions of programs the node
+// knows, such as SPL Token, come with Parsed set; others with Accounts and
+// base58 Data.
+type Instruction struct {
+	ProgramID string          `json:"programId"`
+	Program   string          `json:"program"`
+	Parsed    json.RawMessage `json:"parsed"`
+	Accounts  []string        `json:"accounts"`
+	Data      string          `json:"data"`
+}
+
+// InnerInstructions are the instructions the top-level instruction at Index
+// invoked, in order.
+type InnerInstructions struct {
+	Index        int           `json:"index"`
+	Instructions []Instruction `json:"instructions"`
+}
+
+// TransactionMeta is a transaction's execution status.
+type TransactionMeta struct {
+	Err               json.RawMessage     `json:"err"`
+	Fee               uint64              `json:"fee"`
+	LogMessages       []string            `json:"logMessages"`
+	InnerInstructions []InnerInstructions `json:"innerInstructions"`
+	PreTokenBalances  []TokenBalance      `json:"preTokenBalances"`
+	PostTokenBalances []TokenBalance      `json:"postTokenBalances"`
+}
+
+// Failed reports whether the transaction failed; its fee is charged but
+// its instructions had no effect.
+func (m *TransactionMeta) Failed() bool {
+	return len(m.Err) > 0 && string(m.Err) != "null"
+}
+
+// TokenBalance is a token account's balance around a transaction.
+type TokenBalance struct {
+	AccountIndex  int    `json:"accountIndex"`
+	Mint          string `json:"mint"`
+	Owner         string `json:"owner"`
+	UITokenAmount struct {
+		Amount   string `json:"amount"`
+		Decimals int    `json:"decimals"`
+	} `json:"uiTokenAmount"`
+}
+
+// RPCError is an error answer from the node.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// Is makes errors.Is(err, ErrSlotSkipped) hold for skipped slots.
+func (e *RPCError) Is(target error) bool {
+	return target == ErrSlotSkipped && (e.Code == codeSlotSkipped || e.Code == codeLongTermStorageSlotSkipped)
+}
+
+// Throttled reports whether the node is asking for fewer requests.
+func (e *RPCError) Throttled() bool { return e.Code == codeLimitExceeded }
+
+// Transient reports whether the node has not stored the block yet.
+func (e *RPCError) Transient() bool { return e.Code == codeBlockNotAvailable }
+
+// RPCClient calls the Solana JSON-RPC API over HTTP. With several URLs, a
+// request that fails to connect or gets a 5xx answer is retried on the next.
+type RPCClient struct {
+	urls    []string
+	header  http.Header
+	http    *http.Client
+	limiter *rpclimit.Limiter
+}
+
+// DefaultTimeout bounds each request to the node.
+const DefaultTimeout = 30 * time.Second
+
+// NewRPCClient builds a client for a source's rpc_url, sending header with
+// every request and making at most rps requests per second (0 for no cap).
+// Throttled and transient failures are retried with backoff.
+func NewRPCClient(urls []string, header http.Header, rps float64) (*RPCClient, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no rpc endpoints")
+	}
+	return &RPCClient{urls: urls, header: header, http: &http.Client{Timeout: DefaultTimeout}, limiter: source.NewLimiter(rps)}, nil
+}
+
+// GetSlot implements Client.
+func (c *RPCClient) GetSlot(ctx context.Context, commitment string) (uint64, error) {
+	var slot uint64
+	err := c.call(ctx, "getSlot", []any{map[string]any{"commitment": commitment}}, &slot)
+	return slot, err
+}
+
+// GetBlock implements Client.
+func (c *RPCClient) GetBlock(ctx context.Context, slot uint64, commitment string) (*Block, error) {
+	var b *Block
+	err := c.call(ctx, "getBlock", []any{slot, map[string]any{
+		"commitment":                     commitment,
+		"encoding":                       "jsonParsed",
+		"transactionDetails":             "full",
+		"rewards":                        false,
+		"maxSupportedTransactionVersion": 0,
+	}}, &b)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("slot %d: %w", slot, ErrSlotSkipped)
+	}
+	return b, nil
+}
+
+// GetBlockTime implements Client.
+func (c *RPCClient) GetBlockTime(ctx context.Context, slot uint64) (int64, error) {
+	var t *int64
+	if err := c.call(ctx, "getBlockTime", []any{slot}, &t); err != nil {
+		return 0, err
+	}
+	if t == nil {
+		return 0, fmt.Errorf("slot %d has no block time", slot)
+	}
+	return *t, nil
+}
+
+func (c *RPCClient) call(ctx context.Context, method string, params []any, out any) error {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", method, err)
+	}
+	return c.limiter.Do(ctx, func() error {
+		var lastErr error
+		for _, url := range c.urls {
+			err := c.post(ctx, url, body, out)
+			if err == nil || !source.FailsOver(err) {
+				return err
+			}
+			lastErr = err
+		}
+		return fmt.Errorf("%s: %w", method, lastErr)
+	})
+}
+
+func (c *RPCClient) post(ctx context.Context, url string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header = c.header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &source.HTTPError{Status: resp.StatusCode, Body: string(bytes.TrimSpace(msg))}
+	}
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if err := json.Unmarshal(rpcResp.Result, out); err != nil {
+		return fmt.Errorf("decode result: %w", err)
+	}
+	return nil
+}
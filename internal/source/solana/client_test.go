@@ -0,0 +1,65 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRPCClientGetBlock(t *testing.T) {
+	var gotAuth string
+	var gotParams []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		var req struct {
+			Params []any `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		gotParams = req.Params
+		if req.Params[0].(float64) == 11 {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32007,"message":"Slot 11 was skipped, or missing due to ledger jump to recent snapshot"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"blockhash":"hash10","previousBlockhash":"hash9","parentSlot":9,"blockTime":1700000010,"transactions":[` + swapTxJSON + `]}}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewRPCClient([]string{srv.URL}, http.Header{"Authorization": {"Bearer key"}}, 0)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	b, err := c.GetBlock(context.Background(), 10, CommitmentFinalized)
+	if err != nil {
+		t.Fatalf("get block: %v", err)
+	}
+	if b.Blockhash != "hash10" || len(b.Transactions) != 1 || b.Transactions[0].Signature() == "" {
+		t.Fatalf("unexpected block %+v", b)
+	}
+	opts, _ := gotParams[1].(map[string]any)
+	if gotAuth != "Bearer key" || opts["encoding"] != "jsonParsed" || opts["commitment"] != CommitmentFinalized {
+		t.Fatalf("unexpected request: auth %q params %v", gotAuth, gotParams)
+	}
+	if _, err := c.GetBlock(context.Background(), 11, CommitmentFinalized); !errors.Is(err, ErrSlotSkipped) {
+		t.Fatalf("expected ErrSlotSkipped, got %v", err)
+	}
+}
+
+func TestRPCClientFailsOver(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":250000000}`))
+	}))
+	defer up.Close()
+
+	c, _ := NewRPCClient([]string{down.URL, up.URL}, nil, 0)
+	slot, err := c.GetSlot(context.Background(), CommitmentConfirmed)
+	if err != nil || slot != 250000000 {
+		t.Fatalf("expected slot from second endpoint, got %d (%v)", slot, err)
+	}
+}
@@ -0,0 +1,280 @@
+package solana
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// ActivityEvent is the event name of watch_address matches on every chain.
+const ActivityEvent = "address_activity"
+
+// Token programs whose transfers spl_transfer rules match, by the name the
+// node gives their parsed instructions.
+var tokenPrograms = map[string]bool{"spl-token": true, "spl-token-2022": true}
+
+// address matches a base58 public key.
+var address = regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{32,44}$`)
+
+// RuleMatcher applies one rule to the transactions of a block.
+type RuleMatcher struct {
+	rule    config.Rule
+	kind    string
+	program string              // program_log
+	mint    string              // spl_transfer; empty for any
+	addrs   map[string]struct{} // spl_transfer, watch_address
+}
+
+// NewRuleMatcher builds a matcher for Solana rules.
+func NewRuleMatcher(rule config.Rule) (*RuleMatcher, error) {
+	mt := strings.ToLower(rule.Match.Type)
+	switch mt {
+	case config.MatchProgramLog:
+		if rule.Match.Program == "" {
+			return nil, fmt.Errorf("rule %s: match.program required for program_log", rule.ID)
+		}
+		return &RuleMatcher{rule: rule, kind: mt, program: rule.Match.Program}, nil
+	case config.MatchSPLTransfer:
+		m := &RuleMatcher{rule: rule, kind: mt, mint: rule.Match.Mint, addrs: map[string]struct{}{}}
+		for _, a := range rule.Match.Addresses {
+			m.addrs[a] = struct{}{}
+		}
+		return m, nil
+	case config.MatchWatchAddress:
+		// The list is shared with other chains, so non-Solana entries are skipped.
+		m := &RuleMatcher{rule: rule, kind: mt, addrs: map[string]struct{}{}}
+		for _, a := range rule.Match.Addresses {
+			if address.MatchString(a) {
+				m.addrs[a] = struct{}{}
+			}
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("rule %s: unsupported match.type %s for solana", rule.ID, rule.Match.Type)
+	}
+}
+
+// MatchTxn returns the events a transaction matches. Failed transactions
+// had no effect and match nothing. A program_log rule matches once per log
+// line of its program and an spl_transfer rule once per transfer, each with
+// LogIndex set to tell them apart.
+func (m *RuleMatcher) MatchTxn(tx *Transaction) []source.Event {
+	if tx.Meta == nil || tx.Meta.Failed() {
+		return nil
+	}
+	var evs []source.Event
+	switch m.kind {
+	case config.MatchProgramLog:
+		evs = m.matchLogs(tx)
+	case config.MatchSPLTransfer:
+		evs = m.matchTransfers(tx)
+	case config.MatchWatchAddress:
+		if ev, ok := m.matchActivity(tx); ok {
+			evs = []source.Event{ev}
+		}
+	}
+	for i := range evs {
+		evs[i].RuleID = m.rule.ID
+		evs[i].TxHash = tx.Signature()
+		evs[i].Args["fee_payer"] = feePayer(tx)
+	}
+	return evs
+}
+
+// matchLogs follows the invoke and success lines of a transaction's logs to
+// tell which program wrote each "Program log:" and "Program data:" line.
+// Log events have a message arg; data events, which Anchor programs emit,
+// have the base64 payload in data.
+func (m *RuleMatcher) matchLogs(tx *Transaction) []source.Event {
+	var out []source.Event
+	var stack []string
+	for i, line := range tx.Meta.LogMessages {
+		rest, ok := strings.CutPrefix(line, "Program ")
+		if !ok {
+			continue
+		}
+		var kind, text string
+		switch {
+		case strings.HasPrefix(rest, "log: "):
+			kind, text = "log", strings.TrimPrefix(rest, "log: ")
+		case strings.HasPrefix(rest, "data: "):
+			kind, text = "data", strings.TrimPrefix(rest, "data: ")
+		default:
+			fields := strings.Fields(rest)
+			if len(fields) >= 2 && fields[1] == "invoke" {
+				stack = append(stack, fields[0])
+			} else if len(fields) >= 2 && (fields[1] == "success" || strings.HasPrefix(fields[1], "failed")) && len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		if len(stack) == 0 || stack[len(stack)-1] != m.program {
+			continue
+		}
+		args := map[string]any{
+			"program": m.program,
+			"kind":    kind,
+			"depth":   len(stack),
+		}
+		if kind == "log" {
+			args["message"] = text
+		} else {
+			args["data"] = text
+		}
+		idx := uint(i)
+		out = append(out, source.Event{Name: config.MatchProgramLog, Contract: m.program, LogIndex: &idx, Args: args})
+	}
+	return out
+}
+
+// tokenTransfer is the info of a parsed transfer or transferChecked
+// instruction. transfer has no mint, so it is looked up from the
+// transaction's token balances.
+type tokenTransfer struct {
+	Type string `json:"type"`
+	Info struct {
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+		Authority   string `json:"authority"`
+		Mint        string `json:"mint"`
+		Amount      string `json:"amount"`
+		TokenAmount *struct {
+			Amount   string `json:"amount"`
+			Decimals int    `json:"decimals"`
+		} `json:"tokenAmount"`
+	} `json:"info"`
+}
+
+// matchTransfers walks the top-level instructions and, after each, the
+// inner ones it invoked. instruction_index is the position of the transfer:
+// "2" for the third top-level instruction, "2.0" for the first it invoked.
+func (m *RuleMatcher) matchTransfers(tx *Transaction) []source.Event {
+	inner := map[int][]Instruction{}
+	for _, ii := range tx.Meta.InnerInstructions {
+		inner[ii.Index] = ii.Instructions
+	}
+	balances := tokenAccounts(tx)
+	var out []source.Event
+	pos := uint(0)
+	visit := func(ix Instruction, path string) {
+		defer func() { pos++ }()
+		if ev, ok := m.matchTransfer(ix, balances); ok {
+			idx := pos
+			ev.LogIndex = &idx
+			ev.Args["instruction_index"] = path
+			out = append(out, ev)
+		}
+	}
+	for i, ix := range tx.Transaction.Message.Instructions {
+		visit(ix, strconv.Itoa(i))
+		for j, in := range inner[i] {
+			visit(in, fmt.Sprintf("%d.%d", i, j))
+		}
+	}
+	return out
+}
+
+func (m *RuleMatcher) matchTransfer(ix Instruction, balances map[string]TokenBalance) (source.Event, bool) {
+	if !tokenPrograms[ix.Program] || len(ix.Parsed) == 0 {
+		return source.Event{}, false
+	}
+	var t tokenTransfer
+	if err := json.Unmarshal(ix.Parsed, &t); err != nil || (t.Type != "transfer" && t.Type != "transferChecked") {
+		return source.Event{}, false
+	}
+	src, dst := balances[t.Info.Source], balances[t.Info.Destination]
+	mint := t.Info.Mint
+	if mint == "" {
+		mint = src.Mint
+		if mint == "" {
+			mint = dst.Mint
+		}
+	}
+	if m.mint != "" && mint != m.mint {
+		return source.Event{}, false
+	}
+	amountStr, decimals := t.Info.Amount, src.UITokenAmount.Decimals
+	if t.Info.TokenAmount != nil {
+		amountStr, decimals = t.Info.TokenAmount.Amount, t.Info.TokenAmount.Decimals
+	}
+	amount, err := strconv.ParseUint(amountStr, 10, 64)
+	if err != nil || amount < m.rule.Match.MinAmount {
+		return source.Event{}, false
+	}
+	if len(m.addrs) > 0 && !m.watches(t.Info.Source, t.Info.Destination, src.Owner, dst.Owner) {
+		return source.Event{}, false
+	}
+	return source.Event{
+		Name:     config.MatchSPLTransfer,
+		Contract: ix.ProgramID,
+		Args: map[string]any{
+			"mint":              mint,
+			"amount":            amount,
+			"decimals":          decimals,
+			"source":            t.Info.Source,
+			"destination":       t.Info.Destination,
+			"source_owner":      src.Owner,
+			"destination_owner": dst.Owner,
+			"authority":         t.Info.Authority,
+			"instruction":       t.Type,
+		},
+	}, true
+}
+
+// tokenAccounts returns the token balances of a transaction by account, for
+// the mint and owner of the accounts in its transfers.
+func tokenAccounts(tx *Transaction) map[string]TokenBalance {
+	keys := tx.Transaction.Message.AccountKeys
+	out := map[string]TokenBalance{}
+	for _, list := range [][]TokenBalance{tx.Meta.PreTokenBalances, tx.Meta.PostTokenBalances} {
+		for _, b := range list {
+			if b.AccountIndex >= 0 && b.AccountIndex < len(keys) {
+				out[keys[b.AccountIndex].Pubkey] = b
+			}
+		}
+	}
+	return out
+}
+
+// matchActivity matches a transaction referencing a watched account. role
+// is "signer" when the account signed it and "account" otherwise.
+func (m *RuleMatcher) matchActivity(tx *Transaction) (source.Event, bool) {
+	for _, k := range tx.Transaction.Message.AccountKeys {
+		if _, ok := m.addrs[k.Pubkey]; !ok {
+			continue
+		}
+		role := "account"
+		if k.Signer {
+			role = "signer"
+		}
+		return source.Event{Name: ActivityEvent, Args: map[string]any{
+			"kind":    "transaction",
+			"watched": k.Pubkey,
+			"role":    role,
+			"fee":     tx.Meta.Fee,
+		}}, true
+	}
+	return source.Event{}, false
+}
+
+func (m *RuleMatcher) watches(addrs ...string) bool {
+	for _, a := range addrs {
+		if _, ok := m.addrs[a]; ok && a != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// feePayer returns the account that paid for a transaction, its first.
+func feePayer(tx *Transaction) string {
+	if keys := tx.Transaction.Message.AccountKeys; len(keys) > 0 {
+		return keys[0].Pubkey
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package solana
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+)
+
+const (
+	tokenProgram = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	usdcMint     = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	swapProgram  = "JUP6LkbZbjS1jKKwapdHNy74zcZ3tdTJ4Zdq8U7gKpeR"
+	trader       = "8kWHm4LBnBvAGpJZ9cFZkZ6qB2wL9JQyfZyxXJQn4Nn2"
+	traderUSDC   = "3uYx9qWzWbGZ7Rk7Hf7wY2tZ9dcnkzRuPvkSN8RJWJ8A"
+	poolUSDC     = "9wFFyRfZBsuAha4YcuxcXLKwMxJR43S7fPfQLusDBzvT"
+	pool         = "7XawhbbxtsRcQA8KTkHT9f9nc6d69UwqCDh6U5EEbEmX"
+)
+
+// swapTxJSON is a swap as getBlock returns it with jsonParsed encoding: the
+// trader pays USDC into the pool and the pool's program logs the swap.
+const swapTxJSON = `{
+  "transaction": {
+    "signatures": ["5h6xBEauJ3PK6SWCZ1PGjBvj8vDdWG3KpwATGy1ARAXFSDwt8GFXM7W5Ncn16wmqokgpiKRLuS83KUxyZyv2sUYv"],
+    "message": {
+      "accountKeys": [
+        {"pubkey": "` + trader + `", "signer": true, "writable": true},
+        {"pubkey": "` + traderUSDC + `", "signer": false, "writable": true},
+        {"pubkey": "` + poolUSDC + `", "signer": false, "writable": true},
+        {"pubkey": "` + swapProgram + `", "signer": false, "writable": false},
+        {"pubkey": "` + tokenProgram + `", "signer": false, "writable": false}
+      ],
+      "instructions": [
+        {"programId": "` + swapProgram + `", "accounts": ["` + trader + `"], "data": "3Bxs4h24hBtQy9rw"}
+      ]
+    }
+  },
+  "meta": {
+    "err": null,
+    "fee": 5000,
+    "logMessages": [
+      "Program ` + swapProgram + ` invoke [1]",
+      "Program log: Instruction: Swap",
+      "Program ` + tokenProgram + ` invoke [2]",
+      "Program log: Instruction: Transfer",
+      "Program ` + tokenProgram + ` success",
+      "Program data: QMbN6CYIceINAAAAAAAAAA==",
+      "Program ` + swapProgram + ` consumed 30000 of 200000 compute units",
+      "Program ` + swapProgram + ` success"
+    ],
+    "innerInstructions": [
+      {"index": 0, "instructions": [
+        {"program": "spl-token", "programId": "` + tokenProgram + `", "parsed": {"type": "transfer", "info": {
+          "source": "` + traderUSDC + `", "destination": "` + poolUSDC + `", "authority": "` + trader + `", "amount": "2500000"}}}
+      ]}
+    ],
+    "preTokenBalances": [
+      {"accountIndex": 1, "mint": "` + usdcMint + `", "owner": "` + trader + `", "uiTokenAmount": {"amount": "9000000", "decimals": 6}},
+      {"accountIndex": 2, "mint": "` + usdcMint + `", "owner": "` + pool + `", "uiTokenAmount": {"amount": "1000000", "decimals": 6}}
+    ],
+    "postTokenBalances": [
+      {"accountIndex": 1, "mint": "` + usdcMint + `", "owner": "` + trader + `", "uiTokenAmount": {"amount": "6500000", "decimals": 6}},
+      {"accountIndex": 2, "mint": "` + usdcMint + `", "owner": "` + pool + `", "uiTokenAmount": {"amount": "3500000", "decimals": 6}}
+    ]
+  }
+}`
+
+func swapTx(t *testing.T) *Transaction {
+	t.Helper()
+	var tx Transaction
+	if err := json.Unmarshal([]byte(swapTxJSON), &tx); err != nil {
+		t.Fatalf("decode tx: %v", err)
+	}
+	return &tx
+}
+
+func TestMatcher_ProgramLog(t *testing.T) {
+	m, err := NewRuleMatcher(config.Rule{ID: "swaps", Match: config.MatchSpec{Type: config.MatchProgramLog, Program: swapProgram}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	evs := m.MatchTxn(swapTx(t))
+	// The token program's "Instruction: Transfer" line is not the swap program's.
+	if len(evs) != 2 {
+		t.Fatalf("expected 2 events, got %+v", evs)
+	}
+	if evs[0].Args["message"] != "Instruction: Swap" || *evs[0].LogIndex != 1 || evs[0].Args["depth"] != 1 || evs[0].Args["fee_payer"] != trader {
+		t.Fatalf("unexpected log event %+v", evs[0])
+	}
+	if evs[1].Args["kind"] != "data" || evs[1].Args["data"] != "QMbN6CYIceINAAAAAAAAAA==" || *evs[1].LogIndex != 5 {
+		t.Fatalf("unexpected data event %+v", evs[1])
+	}
+	if evs[0].TxHash == "" || evs[0].Contract != swapProgram {
+		t.Fatalf("expected signature and program on event, got %+v", evs[0])
+	}
+
+	failed := swapTx(t)
+	failed.Meta.Err = json.RawMessage(`{"InstructionError":[0,"Custom"]}`)
+	if evs := m.MatchTxn(failed); len(evs) != 0 {
+		t.Fatalf("expected failed transaction to match nothing, got %+v", evs)
+	}
+}
+
+func TestMatcher_SPLTransfer(t *testing.T) {
+	cases := []struct {
+		name string
+		spec config.MatchSpec
+		want bool
+	}{
+		{"any mint", config.MatchSpec{}, true},
+		{"mint", config.MatchSpec{Mint: usdcMint}, true},
+		{"other mint", config.MatchSpec{Mint: tokenProgram}, false},
+		{"min amount", config.MatchSpec{Mint: usdcMint, MinAmount: 2500000}, true},
+		{"below min amount", config.MatchSpec{Mint: usdcMint, MinAmount: 2500001}, false},
+		{"owner", config.MatchSpec{Addresses: []string{pool}}, true},
+		{"token account", config.MatchSpec{Addresses: []string{traderUSDC}}, true},
+		{"unrelated", config.MatchSpec{Addresses: []string{swapProgram}}, false},
+	}
+	for _, c := range cases {
+		c.spec.Type = config.MatchSPLTransfer
+		m, err := NewRuleMatcher(config.Rule{ID: "usdc", Match: c.spec})
+		if err != nil {
+			t.Fatalf("%s: new matcher: %v", c.name, err)
+		}
+		evs := m.MatchTxn(swapTx(t))
+		if got := len(evs) == 1; got != c.want {
+			t.Fatalf("%s: matched %v, want %v: %+v", c.name, got, c.want, evs)
+		}
+		if !c.want {
+			continue
+		}
+		args := evs[0].Args
+		if args["mint"] != usdcMint || args["amount"] != uint64(2500000) || args["decimals"] != 6 ||
+			args["source_owner"] != trader || args["destination_owner"] != pool || args["instruction_index"] != "0.0" {
+			t.Fatalf("%s: unexpected args %+v", c.name, args)
+		}
+	}
+}
+
+func TestMatcher_WatchAddress(t *testing.T) {
+	m, err := NewRuleMatcher(config.Rule{ID: "watch", Match: config.MatchSpec{
+		Type:      config.MatchWatchAddress,
+		Addresses: []string{"0x00000000000000000000000000000000000000aa", trader},
+	}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	evs := m.MatchTxn(swapTx(t))
+	if len(evs) != 1 || evs[0].Name != ActivityEvent || evs[0].Args["role"] != "signer" || evs[0].Args["fee"] != uint64(5000) {
+		t.Fatalf("unexpected events %+v", evs)
+	}
+}
@@ -0,0 +1,321 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/source/blocktime"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+// maxSkippedSlots is how many slots in a row a time-based start looks past
+// for one with a block.
+const maxSkippedSlots = 64
+
+// Scanner walks a Solana source slot by slot and matches its rules against
+// each block's transactions. Slots without a block move the cursor without
+// changing its hash, so the next block's parent is checked against the last
+// one seen.
+type Scanner struct {
+	client        Client
+	store         *storage.Store
+	source        config.Source
+	confirmations uint64
+	commitment    string
+	matchers      []*RuleMatcher
+	tipTTL        time.Duration
+	nowFunc       func() time.Time
+	// maxSlots is how many slots one call may scan (source
+	// max_blocks_per_tick); stopAt, when set, is the last slot to scan.
+	maxSlots uint64
+	stopAt   uint64
+	// tip is the latest slot seen, read by the dashboard.
+	tip   atomic.Uint64
+	tipAt time.Time
+}
+
+// NewScanner builds a scanner for a Solana source and its rules.
+func NewScanner(client Client, store *storage.Store, src config.Source, confirmations uint64, rules []config.Rule) (*Scanner, error) {
+	s := &Scanner{
+		client:        client,
+		store:         store,
+		source:        src,
+		confirmations: confirmations,
+		commitment:    CommitmentConfirmed,
+		tipTTL:        src.TipCacheTTL(),
+		nowFunc:       time.Now,
+		maxSlots:      1,
+	}
+	if src.MaxBlocksPerTick > 1 {
+		s.maxSlots = uint64(src.MaxBlocksPerTick)
+	}
+	commit, err := s.PrepareRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	commit()
+	return s, nil
+}
+
+// PrepareRules builds matchers for the source's rules without touching the
+// running scanner. Calling the returned commit func swaps them in.
+func (s *Scanner) PrepareRules(rules []config.Rule) (commit func(), err error) {
+	matchers := []*RuleMatcher{}
+	for _, r := range rules {
+		if !r.AppliesTo(s.source.ID) {
+			continue
+		}
+		if strings.EqualFold(r.Match.Type, config.MatchReorg) {
+			continue // raised by the engine when the scanner rewinds
+		}
+		m, err := NewRuleMatcher(r)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return func() {
+		s.matchers = matchers
+	}, nil
+}
+
+// SetFinality scans finalized slots for the "finalized" confirmations tag;
+// otherwise the scanner reads confirmed slots.
+func (s *Scanner) SetFinality(tag string) {
+	if tag == config.ConfirmFinalized {
+		s.commitment = CommitmentFinalized
+	} else {
+		s.commitment = CommitmentConfirmed
+	}
+}
+
+// SetStopHeight keeps a call from scanning past slot, the end of a bounded
+// replay. Zero means no limit.
+func (s *Scanner) SetStopHeight(slot uint64) {
+	s.stopAt = slot
+}
+
+// Tip returns the latest slot observed by ProcessNext, or 0 before the first poll.
+func (s *Scanner) Tip() uint64 {
+	return s.tip.Load()
+}
+
+// latestHeight returns the chain tip. The cached tip is reused while next is
+// still confirmed below it (catching up) or while it is younger than tipTTL.
+func (s *Scanner) latestHeight(ctx context.Context, next uint64) (uint64, error) {
+	if tip := s.tip.Load(); tip > 0 {
+		if next+s.confirmations <= tip || s.nowFunc().Sub(s.tipAt) < s.tipTTL {
+			return tip, nil
+		}
+	}
+	slot, err := s.client.GetSlot(ctx, s.commitment)
+	if err != nil {
+		return 0, fmt.Errorf("latest slot: %w", err)
+	}
+	if prev := s.tip.Load(); slot < prev {
+		// The lower tip is not kept, so the next tick asks again.
+		return 0, &source.TipError{Tip: slot, Previous: prev, Unit: "slot"}
+	}
+	s.tip.Store(slot)
+	s.tipAt = s.nowFunc()
+	return slot, nil
+}
+
+// ProcessNext handles the next eligible slot (respecting confirmations) and returns matched events.
+// A source with max_blocks_per_tick that is behind handles up to that many slots at once.
+// On success advances the cursor. On reorg returns source.ErrReorgDetected after rewinding.
+func (s *Scanner) ProcessNext(ctx context.Context) ([]source.Event, error) {
+	var events []source.Event
+	err := s.ProcessNextFunc(ctx, func(ev source.Event) error {
+		events = append(events, ev)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ProcessNextFunc is ProcessNext with each matched event handed to emit as it
+// is decoded. The cursor moves after each slot, so if emit fails it is left
+// at the last slot fully handled and the error is returned.
+func (s *Scanner) ProcessNextFunc(ctx context.Context, emit func(source.Event) error) error {
+	curSlot, curHash, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+	if err != nil {
+		return err
+	}
+
+	latest, err := s.latestHeight(ctx, curSlot+1)
+	if err != nil {
+		return err
+	}
+	if hasCursor && latest < curSlot {
+		return &source.TipError{Tip: latest, Previous: curSlot, Cursor: true, Unit: "slot"}
+	}
+	safe := latest
+	if s.confirmations > 0 {
+		if safe < s.confirmations {
+			return nil
+		}
+		safe -= s.confirmations
+	}
+
+	target := curSlot + 1
+	if !hasCursor {
+		start, err := s.resolveStart(ctx, safe)
+		if err != nil {
+			return err
+		}
+		target = start
+	}
+
+	if target > safe {
+		return nil
+	}
+
+	end := min(target+s.maxSlots-1, safe)
+	if s.stopAt > 0 {
+		end = min(end, max(s.stopAt, target))
+	}
+	for slot := target; slot <= end; slot++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hash, err := s.processSlot(ctx, slot, curSlot, curHash, hasCursor, emit)
+		if err != nil {
+			return err
+		}
+		curSlot, curHash, hasCursor = slot, hash, true
+	}
+	return nil
+}
+
+// processSlot matches the rules against target's block, whose parent must be
+// the last block the cursor saw, and moves the cursor to it. It returns the
+// hash the cursor now holds.
+func (s *Scanner) processSlot(ctx context.Context, target, curSlot uint64, curHash string, hasCursor bool, emit func(source.Event) error) (string, error) {
+	block, err := s.client.GetBlock(ctx, target, s.commitment)
+	if errors.Is(err, ErrSlotSkipped) {
+		return curHash, s.store.UpsertCursor(ctx, s.source.ID, target, curHash)
+	}
+	if err != nil {
+		return "", fmt.Errorf("block %d: %w", target, err)
+	}
+
+	if hasCursor && curHash != "" && block.PreviousBlockhash != curHash {
+		// The parent slot is on the new fork too; rescan from it, without a
+		// hash to check it against.
+		from := min(block.ParentSlot, curSlot)
+		rewindTo := uint64(0)
+		if from > 0 {
+			rewindTo = from - 1
+		}
+		if err := s.store.UpsertCursor(ctx, s.source.ID, rewindTo, ""); err != nil {
+			return "", err
+		}
+		return "", &source.ReorgError{Height: target, From: from, To: curSlot, OldHash: curHash, NewHash: block.PreviousBlockhash, Unit: "slot"}
+	}
+
+	var stamp time.Time
+	if block.BlockTime != nil {
+		stamp = time.Unix(*block.BlockTime, 0).UTC()
+	}
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		for _, m := range s.matchers {
+			for _, ev := range m.MatchTxn(tx) {
+				ev.Chain = Chain
+				ev.SourceID = s.source.ID
+				ev.Height = target
+				ev.Hash = block.Blockhash
+				ev.Timestamp = stamp
+				if err := emit(ev); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	return block.Blockhash, s.store.UpsertCursor(ctx, s.source.ID, target, block.Blockhash)
+}
+
+// SkipNext advances the cursor past the next slot without matching it.
+// It requires an existing cursor and returns the skipped slot.
+func (s *Scanner) SkipNext(ctx context.Context) (uint64, error) {
+	curSlot, curHash, hasCursor, err := s.store.GetCursor(ctx, s.source.ID)
+	if err != nil {
+		return 0, err
+	}
+	if !hasCursor {
+		return 0, fmt.Errorf("source %s has no cursor yet", s.source.ID)
+	}
+	target := curSlot + 1
+	block, err := s.client.GetBlock(ctx, target, s.commitment)
+	switch {
+	case errors.Is(err, ErrSlotSkipped):
+	case err != nil:
+		return 0, fmt.Errorf("block %d: %w", target, err)
+	default:
+		curHash = block.Blockhash
+	}
+	if err := s.store.UpsertCursor(ctx, s.source.ID, target, curHash); err != nil {
+		return 0, err
+	}
+	return target, nil
+}
+
+// resolveStart picks the first slot for a source without a cursor. A
+// "time:" start_slot is found by binary search over block times.
+func (s *Scanner) resolveStart(ctx context.Context, safe uint64) (uint64, error) {
+	at, ok, err := blocktime.Parse(s.source.StartSlot)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return resolveStartSlot(s.source.StartSlot, safe)
+	}
+	return blocktime.Search(ctx, safe, at, func(ctx context.Context, slot uint64) (time.Time, error) {
+		// A skipped slot has no time; the next block's stands in for it.
+		for i := uint64(0); i < maxSkippedSlots && slot+i <= safe; i++ {
+			t, err := s.client.GetBlockTime(ctx, slot+i)
+			if errors.Is(err, ErrSlotSkipped) {
+				continue
+			}
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(t, 0), nil
+		}
+		return time.Time{}, fmt.Errorf("no block within %d slots of %d", maxSkippedSlots, slot)
+	})
+}
+
+// resolveStartSlot reads a start_slot: a slot number, or "latest" or
+// "latest-N" relative to the newest confirmed slot. Nodes keep little
+// history, so unlike start_block it defaults to the latest slot.
+func resolveStartSlot(start string, safe uint64) (uint64, error) {
+	if start == "" || start == "latest" {
+		return safe, nil
+	}
+	if strings.HasPrefix(start, "latest-") {
+		n, err := strconv.ParseUint(strings.TrimPrefix(start, "latest-"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse start_slot %q: %w", start, err)
+		}
+		if n > safe {
+			return 0, nil
+		}
+		return safe - n, nil
+	}
+	n, err := strconv.ParseUint(start, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse start_slot %q: %w", start, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,124 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+type fakeClient struct {
+	slot   uint64
+	blocks map[uint64]*Block // missing slots were skipped
+}
+
+func (f *fakeClient) GetSlot(ctx context.Context, commitment string) (uint64, error) {
+	return f.slot, nil
+}
+
+func (f *fakeClient) GetBlock(ctx context.Context, slot uint64, commitment string) (*Block, error) {
+	b, ok := f.blocks[slot]
+	if !ok {
+		return nil, &RPCError{Code: codeSlotSkipped, Message: fmt.Sprintf("Slot %d was skipped", slot)}
+	}
+	return b, nil
+}
+
+func (f *fakeClient) GetBlockTime(ctx context.Context, slot uint64) (int64, error) {
+	b, ok := f.blocks[slot]
+	if !ok {
+		return 0, &RPCError{Code: codeSlotSkipped}
+	}
+	return *b.BlockTime, nil
+}
+
+func newTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.Open(t.TempDir() + "/db.sqlite")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func block(slot uint64, prev string, txs ...Transaction) *Block {
+	ts := int64(1700000000 + slot)
+	return &Block{
+		Blockhash:         fmt.Sprintf("hash%d", slot),
+		PreviousBlockhash: prev,
+		ParentSlot:        slot - 1,
+		BlockTime:         &ts,
+		Transactions:      txs,
+	}
+}
+
+func TestScannerSkipsEmptySlots(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	swap := *swapTx(t)
+	after := block(12, "hash10", swap)
+	after.ParentSlot = 10
+	client := &fakeClient{slot: 12, blocks: map[uint64]*Block{
+		10: block(10, "hash9"),
+		// 11 was skipped.
+		12: after,
+	}}
+	src := config.Source{ID: "sol", Type: "solana", StartSlot: "10", MaxBlocksPerTick: 5}
+	rule := config.Rule{ID: "usdc", Source: "sol", Match: config.MatchSpec{Type: config.MatchSPLTransfer, Mint: usdcMint}}
+	sc, err := NewScanner(client, store, src, 0, []config.Rule{rule})
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	evs, err := sc.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process next: %v", err)
+	}
+	if len(evs) != 1 || evs[0].Height != 12 || evs[0].Hash != "hash12" || evs[0].Chain != Chain || evs[0].Timestamp.Unix() != 1700000012 {
+		t.Fatalf("unexpected events %+v", evs)
+	}
+	slot, hash, _, err := store.GetCursor(ctx, "sol")
+	if err != nil || slot != 12 || hash != "hash12" {
+		t.Fatalf("expected cursor at 12/hash12, got %d/%s (%v)", slot, hash, err)
+	}
+}
+
+func TestScannerDetectsReorg(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	if err := store.UpsertCursor(ctx, "sol", 12, "hash12"); err != nil {
+		t.Fatalf("seed cursor: %v", err)
+	}
+	// Slot 13 builds on a different block at slot 12.
+	client := &fakeClient{slot: 13, blocks: map[uint64]*Block{13: block(13, "fork12")}}
+	sc, err := NewScanner(client, store, config.Source{ID: "sol", Type: "solana"}, 0, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	_, err = sc.ProcessNext(ctx)
+	var rg *source.ReorgError
+	if !errors.As(err, &rg) || !errors.Is(err, source.ErrReorgDetected) {
+		t.Fatalf("expected reorg error, got %v", err)
+	}
+	if rg.From != 12 || rg.To != 12 || rg.OldHash != "hash12" || rg.NewHash != "fork12" {
+		t.Fatalf("unexpected reorg %+v", rg)
+	}
+	slot, hash, _, _ := store.GetCursor(ctx, "sol")
+	if slot != 11 || hash != "" {
+		t.Fatalf("expected cursor rewound to 11 without a hash, got %d/%q", slot, hash)
+	}
+}
+
+func TestResolveStartSlot(t *testing.T) {
+	cases := map[string]uint64{"": 100, "latest": 100, "latest-10": 90, "latest-500": 0, "42": 42}
+	for start, want := range cases {
+		got, err := resolveStartSlot(start, 100)
+		if err != nil || got != want {
+			t.Fatalf("%q: got %d (%v), want %d", start, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package solana
+
+// Chain identifier for Solana. Its events' Height is the slot, Hash the
+// blockhash, TxHash the transaction's first signature, LogIndex the position
+// among the transaction's matches of the rule, and Contract the program that
+// logged or moved the tokens.
+const Chain = "solana"
@@ -0,0 +1,244 @@
+package substrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/rpclimit"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// eventsKey is the storage key of System.Events: twox128("System") followed
+// by twox128("Events").
+const eventsKey = "0x26aa394eea5630e07c48ae0c9558cef780d41e5e16056765bc8461851072c9d7"
+
+// ErrBlockNotFound is returned for a height the node has no block at.
+var ErrBlockNotFound = errors.New("block not found")
+
+// Client is the slice of the Substrate JSON-RPC API the scanner uses.
+type Client interface {
+	LatestHeight(ctx context.Context) (uint64, error)
+	FinalizedHeight(ctx context.Context) (uint64, error)
+	BlockHash(ctx context.Context, height uint64) (string, error)
+	Block(ctx context.Context, hash string) (*Block, error)
+	// Events returns the SCALE-encoded System.Events of the block.
+	Events(ctx context.Context, hash string) ([]byte, error)
+	// SpecVersion returns the runtime version the block was built with.
+	SpecVersion(ctx context.Context, hash string) (uint32, error)
+	// Metadata returns the SCALE-encoded metadata of the block's runtime.
+	Metadata(ctx context.Context, hash string) ([]byte, error)
+}
+
+// Block is a block's header and SCALE-encoded extrinsics.
+type Block struct {
+	Hash       string
+	ParentHash string
+	Height     uint64
+	Extrinsics [][]byte
+}
+
+// RPCError is an error answer from the node.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// RPCClient calls a Substrate node's JSON-RPC API over HTTP. With several
+// URLs, a request that fails to connect or gets a 5xx answer is retried on
+// the next.
+type RPCClient struct {
+	urls    []string
+	header  http.Header
+	http    *http.Client
+	limiter *rpclimit.Limiter
+}
+
+// DefaultTimeout bounds each request to the node.
+const DefaultTimeout = 30 * time.Second
+
+// NewRPCClient builds a client for a source's rpc_url, sending header with
+// every request and making at most rps requests per second (0 for no cap).
+// Throttled and transient failures are retried with backoff.
+func NewRPCClient(urls []string, header http.Header, rps float64) (*RPCClient, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no rpc endpoints")
+	}
+	return &RPCClient{urls: urls, header: header, http: &http.Client{Timeout: DefaultTimeout}, limiter: source.NewLimiter(rps)}, nil
+}
+
+type header struct {
+	ParentHash string `json:"parentHash"`
+	Number     string `json:"number"`
+}
+
+func (h *header) height() (uint64, error) {
+	return strconv.ParseUint(strings.TrimPrefix(h.Number, "0x"), 16, 64)
+}
+
+// LatestHeight implements Client.
+func (c *RPCClient) LatestHeight(ctx context.Context) (uint64, error) {
+	var h header
+	if err := c.call(ctx, "chain_getHeader", nil, &h); err != nil {
+		return 0, err
+	}
+	return h.height()
+}
+
+// FinalizedHeight implements Client.
+func (c *RPCClient) FinalizedHeight(ctx context.Context) (uint64, error) {
+	var hash string
+	if err := c.call(ctx, "chain_getFinalizedHead", nil, &hash); err != nil {
+		return 0, err
+	}
+	var h header
+	if err := c.call(ctx, "chain_getHeader", []any{hash}, &h); err != nil {
+		return 0, err
+	}
+	return h.height()
+}
+
+// BlockHash implements Client.
+func (c *RPCClient) BlockHash(ctx context.Context, height uint64) (string, error) {
+	var hash *string
+	if err := c.call(ctx, "chain_getBlockHash", []any{height}, &hash); err != nil {
+		return "", err
+	}
+	if hash == nil {
+		return "", fmt.Errorf("%w at height %d", ErrBlockNotFound, height)
+	}
+	return *hash, nil
+}
+
+// Block implements Client.
+func (c *RPCClient) Block(ctx context.Context, hash string) (*Block, error) {
+	var res *struct {
+		Block struct {
+			Header     header   `json:"header"`
+			Extrinsics []string `json:"extrinsics"`
+		} `json:"block"`
+	}
+	if err := c.call(ctx, "chain_getBlock", []any{hash}, &res); err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, fmt.Errorf("%w: %s", ErrBlockNotFound, hash)
+	}
+	height, err := res.Block.Header.height()
+	if err != nil {
+		return nil, fmt.Errorf("block number: %w", err)
+	}
+	b := &Block{Hash: hash, ParentHash: res.Block.Header.ParentHash, Height: height}
+	for _, x := range res.Block.Extrinsics {
+		raw, err := decodeHex(x)
+		if err != nil {
+			return nil, fmt.Errorf("extrinsic: %w", err)
+		}
+		b.Extrinsics = append(b.Extrinsics, raw)
+	}
+	return b, nil
+}
+
+// Events implements Client.
+func (c *RPCClient) Events(ctx context.Context, hash string) ([]byte, error) {
+	var data *string
+	if err := c.call(ctx, "state_getStorage", []any{eventsKey, hash}, &data); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return []byte{0}, nil // no events: an empty sequence
+	}
+	return decodeHex(*data)
+}
+
+// SpecVersion implements Client.
+func (c *RPCClient) SpecVersion(ctx context.Context, hash string) (uint32, error) {
+	var v struct {
+		SpecVersion uint32 `json:"specVersion"`
+	}
+	if err := c.call(ctx, "state_getRuntimeVersion", []any{hash}, &v); err != nil {
+		return 0, err
+	}
+	return v.SpecVersion, nil
+}
+
+// Metadata implements Client.
+func (c *RPCClient) Metadata(ctx context.Context, hash string) ([]byte, error) {
+	var data string
+	if err := c.call(ctx, "state_getMetadata", []any{hash}, &data); err != nil {
+		return nil, err
+	}
+	return decodeHex(data)
+}
+
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+func (c *RPCClient) call(ctx context.Context, method string, params []any, out any) error {
+	if params == nil {
+		params = []any{}
+	}
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", method, err)
+	}
+	return c.limiter.Do(ctx, func() error {
+		var lastErr error
+		for _, url := range c.urls {
+			err := c.post(ctx, url, body, out)
+			if err == nil || !source.FailsOver(err) {
+				return err
+			}
+			lastErr = err
+		}
+		return fmt.Errorf("%s: %w", method, lastErr)
+	})
+}
+
+func (c *RPCClient) post(ctx context.Context, url string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header = c.header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &source.HTTPError{Status: resp.StatusCode, Body: string(bytes.TrimSpace(msg))}
+	}
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if err := json.Unmarshal(rpcResp.Result, out); err != nil {
+		return fmt.Errorf("decode result: %w", err)
+	}
+	return nil
+}
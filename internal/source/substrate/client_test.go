@@ -0,0 +1,82 @@
+package substrate
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRPCClient(t *testing.T) {
+	ext := "0x" + hex.EncodeToString(testExtrinsics(testTime)[0])
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+			Params []any  `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch req.Method {
+		case "chain_getFinalizedHead":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0xf1"}`))
+		case "chain_getHeader":
+			if len(req.Params) == 1 && req.Params[0] != "0xf1" {
+				t.Errorf("unexpected header params %v", req.Params)
+			}
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"parentHash":"0xp","number":"0x1499e2a"}}`))
+		case "chain_getBlockHash":
+			if req.Params[0].(float64) == 1 {
+				_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0xab"}`))
+		case "chain_getBlock":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"block":{"header":{"parentHash":"0xaa","number":"0x10"},"extrinsics":["` + ext + `"]},"justifications":null}}`))
+		case "state_getStorage":
+			if req.Params[0] != eventsKey {
+				t.Errorf("unexpected storage key %v", req.Params[0])
+			}
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+		case "state_getRuntimeVersion":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"specName":"polkadot","specVersion":1002000}}`))
+		default:
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":4003,"message":"Client error: UnknownBlock: State already discarded"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewRPCClient([]string{srv.URL}, nil, 0)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	ctx := context.Background()
+	if h, err := c.LatestHeight(ctx); err != nil || h != 21601834 {
+		t.Fatalf("latest height: %d, %v", h, err)
+	}
+	if h, err := c.FinalizedHeight(ctx); err != nil || h != 21601834 {
+		t.Fatalf("finalized height: %d, %v", h, err)
+	}
+	if _, err := c.BlockHash(ctx, 1); !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf("expected block not found, got %v", err)
+	}
+	b, err := c.Block(ctx, "0xab")
+	if err != nil {
+		t.Fatalf("block: %v", err)
+	}
+	if b.Hash != "0xab" || b.ParentHash != "0xaa" || b.Height != 16 || len(b.Extrinsics) != 1 || "0x"+hex.EncodeToString(b.Extrinsics[0]) != ext {
+		t.Fatalf("unexpected block %+v", b)
+	}
+	if ev, err := c.Events(ctx, "0xab"); err != nil || len(ev) != 1 || ev[0] != 0 {
+		t.Fatalf("expected an empty event list, got %x, %v", ev, err)
+	}
+	if v, err := c.SpecVersion(ctx, "0xab"); err != nil || v != 1002000 {
+		t.Fatalf("spec version: %d, %v", v, err)
+	}
+	_, err = c.Metadata(ctx, "0xab")
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != 4003 {
+		t.Fatalf("expected an rpc error, got %v", err)
+	}
+}
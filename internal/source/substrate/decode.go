@@ -0,0 +1,423 @@
+package substrate
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// maxDepth bounds how deeply values may nest, so a malformed registry
+// cannot recurse forever.
+const maxDepth = 64
+
+// AccountID is a 32-byte account, rendered as an SS58 address in args.
+type AccountID [32]byte
+
+// Event is an event a block deposited, decoded with the runtime metadata.
+type Event struct {
+	Index  uint   // position among the block's events
+	Phase  string // "apply_extrinsic", "finalization" or "initialization"
+	Pallet string
+	Name   string
+	Fields []Field
+	// Extrinsic is the index of the extrinsic that emitted the event, for
+	// the apply_extrinsic phase; -1 otherwise.
+	Extrinsic int
+}
+
+// Field is a named value of an event or call. Unnamed fields are named
+// arg0, arg1 and so on.
+type Field struct {
+	Name  string
+	Value any
+}
+
+// Extrinsic is a decoded extrinsic of a block.
+type Extrinsic struct {
+	Hash   string
+	Signer *AccountID // nil for unsigned extrinsics and inherents
+	Pallet string
+	Call   string
+	Args   []Field
+}
+
+// Value returns the value of the field named name.
+func (e *Event) Value(name string) (any, bool) {
+	for _, f := range e.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// DecodeEvents decodes the System.Events storage value of a block.
+func (m *Metadata) DecodeEvents(b []byte) ([]Event, error) {
+	t := m.types[m.eventsType]
+	if t.kind != kindSequence {
+		return nil, errors.New("events: System.Events is not a sequence")
+	}
+	record, ok := m.types[t.elem]
+	if !ok || record.kind != kindComposite {
+		return nil, errors.New("events: event record is not a composite")
+	}
+	r := newReader(b)
+	n, err := r.compactInt()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, n)
+	for i := range n {
+		ev := Event{Index: uint(i), Extrinsic: -1}
+		for _, f := range record.fields {
+			switch f.name {
+			case "phase":
+				err = m.decodePhase(r, f.typ, &ev)
+			case "event":
+				ev.Pallet, ev.Name, ev.Fields, err = m.decodeNested(r, f.typ)
+			default: // topics
+				_, err = m.decode(r, f.typ, 0)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("event %d: %w", i, err)
+			}
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func (m *Metadata) decodePhase(r *reader, typ uint32, ev *Event) error {
+	name, fields, err := m.decodeVariant(r, typ, 0)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "ApplyExtrinsic":
+		ev.Phase = "apply_extrinsic"
+		if len(fields) == 1 {
+			if i, ok := fields[0].Value.(uint64); ok {
+				ev.Extrinsic = int(i)
+			}
+		}
+	case "Finalization":
+		ev.Phase = "finalization"
+	default:
+		ev.Phase = "initialization"
+	}
+	return nil
+}
+
+// decodeNested decodes a RuntimeEvent or RuntimeCall: a variant per pallet
+// wrapping a variant per event or call.
+func (m *Metadata) decodeNested(r *reader, typ uint32) (pallet, name string, fields []Field, err error) {
+	t, ok := m.types[typ]
+	if !ok || t.kind != kindVariant {
+		return "", "", nil, fmt.Errorf("type %d is not a variant", typ)
+	}
+	index, err := r.byte()
+	if err != nil {
+		return "", "", nil, err
+	}
+	outer, ok := t.variant(index)
+	if !ok || len(outer.fields) != 1 {
+		return "", "", nil, fmt.Errorf("unknown pallet %d", index)
+	}
+	name, fields, err = m.decodeVariant(r, outer.fields[0].typ, 1)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("%s: %w", outer.name, err)
+	}
+	return outer.name, name, fields, nil
+}
+
+// variantValue is a decoded enum value with fields, before it is turned
+// into args.
+type variantValue struct {
+	name   string
+	fields []Field
+}
+
+func (m *Metadata) decodeVariant(r *reader, typ uint32, depth int) (string, []Field, error) {
+	t, ok := m.types[typ]
+	if !ok || t.kind != kindVariant {
+		return "", nil, fmt.Errorf("type %d is not a variant", typ)
+	}
+	index, err := r.byte()
+	if err != nil {
+		return "", nil, err
+	}
+	v, ok := t.variant(index)
+	if !ok {
+		return "", nil, fmt.Errorf("%s: unknown variant %d", t.name(), index)
+	}
+	fields, err := m.decodeFields(r, v.fields, depth+1)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", v.name, err)
+	}
+	return v.name, fields, nil
+}
+
+func (m *Metadata) decodeFields(r *reader, fields []field, depth int) ([]Field, error) {
+	out := make([]Field, len(fields))
+	for i, f := range fields {
+		v, err := m.decode(r, f.typ, depth)
+		if err != nil {
+			return nil, err
+		}
+		name := f.name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		out[i] = Field{Name: name, Value: v}
+	}
+	return out, nil
+}
+
+// decode reads a value of type typ. Integers of up to 64 bits come back as
+// uint64 or int64 and wider ones as *big.Int; byte strings as []byte;
+// accounts as AccountID; enums without fields as their variant's name.
+func (m *Metadata) decode(r *reader, typ uint32, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, errors.New("value nested too deeply")
+	}
+	t, ok := m.types[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %d", typ)
+	}
+	switch t.kind {
+	case kindComposite:
+		if t.name() == "AccountId32" {
+			b, err := r.bytes(32)
+			if err != nil {
+				return nil, err
+			}
+			return AccountID(b), nil
+		}
+		fields, err := m.decodeFields(r, t.fields, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 1 && t.fields[0].name == "" {
+			return fields[0].Value, nil // a newtype, such as H256 or Perbill
+		}
+		return fieldsValue(t.fields, fields), nil
+	case kindVariant:
+		if t.name() == "Option" {
+			some, err := r.optional()
+			if err != nil || !some {
+				return nil, err
+			}
+			v, ok := t.variant(1)
+			if !ok || len(v.fields) != 1 {
+				return nil, errors.New("malformed Option type")
+			}
+			return m.decode(r, v.fields[0].typ, depth+1)
+		}
+		name, fields, err := m.decodeVariant(r, typ, depth)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			return name, nil
+		}
+		if t.name() == "MultiAddress" && name == "Id" {
+			return fields[0].Value, nil
+		}
+		return variantValue{name: name, fields: fields}, nil
+	case kindSequence:
+		n, err := r.compactInt()
+		if err != nil {
+			return nil, err
+		}
+		return m.decodeList(r, t.elem, n, depth)
+	case kindArray:
+		return m.decodeList(r, t.elem, int(t.length), depth)
+	case kindTuple:
+		if len(t.tuple) == 0 {
+			return nil, nil
+		}
+		out := make([]any, len(t.tuple))
+		for i, e := range t.tuple {
+			v, err := m.decode(r, e, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	case kindPrimitive:
+		return decodePrimitive(r, t.prim)
+	case kindCompact:
+		return r.compact()
+	case kindBitSequence:
+		bits, err := r.compactInt()
+		if err != nil {
+			return nil, err
+		}
+		// Bits are packed into words of the store type, u8 to u64.
+		store := 1
+		if s, ok := m.types[t.elem]; ok && s.kind == kindPrimitive && s.prim >= primU8 && s.prim <= primU64 {
+			store = 1 << (s.prim - primU8)
+		}
+		return r.bytes((bits + store*8 - 1) / (store * 8) * store)
+	}
+	return nil, fmt.Errorf("unknown type kind %d", t.kind)
+}
+
+func (m *Metadata) decodeList(r *reader, elem uint32, n, depth int) (any, error) {
+	if e, ok := m.types[elem]; ok && e.kind == kindPrimitive && e.prim == primU8 {
+		return r.bytes(n)
+	}
+	out := make([]any, 0, min(n, 1024))
+	for range n {
+		v, err := m.decode(r, elem, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func fieldsValue(defs []field, fields []Field) any {
+	if len(defs) > 0 && defs[0].name != "" {
+		out := make(map[string]any, len(fields))
+		for _, f := range fields {
+			out[f.Name] = f.Value
+		}
+		return out
+	}
+	out := make([]any, len(fields))
+	for i, f := range fields {
+		out[i] = f.Value
+	}
+	return out
+}
+
+func decodePrimitive(r *reader, prim byte) (any, error) {
+	switch prim {
+	case primBool:
+		return r.bool()
+	case primChar:
+		c, err := r.uint(4)
+		return string(rune(c)), err
+	case primStr:
+		return r.string()
+	case primU8, primU16, primU32, primU64:
+		return r.uint(1 << (prim - primU8))
+	case primU128, primU256:
+		return r.bigUint(16 << (prim - primU128))
+	case primI8, primI16, primI32, primI64:
+		size := 1 << (prim - primI8)
+		v, err := r.bigInt(size)
+		if err != nil {
+			return nil, err
+		}
+		return v.Int64(), nil
+	case primI128, primI256:
+		return r.bigInt(16 << (prim - primI128))
+	}
+	return nil, fmt.Errorf("unknown primitive %d", prim)
+}
+
+// DecodeExtrinsic decodes an extrinsic as chain_getBlock returns it: its
+// SCALE bytes, with their length prefix.
+func (m *Metadata) DecodeExtrinsic(b []byte) (*Extrinsic, error) {
+	x := &Extrinsic{Hash: ExtrinsicHash(b)}
+	r := newReader(b)
+	if _, err := r.compactInt(); err != nil {
+		return nil, err
+	}
+	version, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if version&0x7f != 4 {
+		return nil, fmt.Errorf("unsupported extrinsic version %d", version&0x7f)
+	}
+	if version&0x80 != 0 {
+		addr, err := m.decode(r, m.addressType, 0)
+		if err != nil {
+			return nil, fmt.Errorf("signer: %w", err)
+		}
+		if id, ok := addr.(AccountID); ok {
+			x.Signer = &id
+		}
+		if _, err := m.decode(r, m.signatureType, 0); err != nil {
+			return nil, fmt.Errorf("signature: %w", err)
+		}
+		for _, ext := range m.extensions {
+			if _, err := m.decode(r, ext, 0); err != nil {
+				return nil, fmt.Errorf("signed extensions: %w", err)
+			}
+		}
+	}
+	x.Pallet, x.Call, x.Args, err = m.decodeNested(r, m.callType)
+	if err != nil {
+		return nil, fmt.Errorf("call: %w", err)
+	}
+	return x, nil
+}
+
+// ExtrinsicHash returns the hash explorers know an extrinsic by: the
+// BLAKE2b-256 of its bytes, length prefix included.
+func ExtrinsicHash(b []byte) string {
+	sum := blake2b.Sum256(b)
+	return "0x" + hex.EncodeToString(sum[:])
+}
+
+// Timestamp returns the time the Timestamp.set inherent among exts sets,
+// or the zero time.
+func Timestamp(exts []*Extrinsic) time.Time {
+	for _, x := range exts {
+		if x == nil || x.Pallet != "Timestamp" || x.Call != "set" || len(x.Args) != 1 {
+			continue
+		}
+		if ms, ok := x.Args[0].Value.(uint64); ok {
+			return time.UnixMilli(int64(ms)).UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// argValue turns a decoded value into one predicates and templates can
+// use: accounts become SS58 addresses, byte strings 0x-prefixed hex and
+// enums with fields a map from the variant's name to its fields.
+func argValue(v any, prefix uint16) any {
+	switch x := v.(type) {
+	case AccountID:
+		return EncodeSS58(x, prefix)
+	case []byte:
+		return "0x" + hex.EncodeToString(x)
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = argValue(e, prefix)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			out[k] = argValue(e, prefix)
+		}
+		return out
+	case variantValue:
+		if len(x.fields) == 1 && x.fields[0].Name == "arg0" {
+			return map[string]any{x.name: argValue(x.fields[0].Value, prefix)}
+		}
+		return map[string]any{x.name: fieldArgs(x.fields, prefix)}
+	}
+	return v
+}
+
+// fieldArgs turns decoded fields into args.
+func fieldArgs(fields []Field, prefix uint16) map[string]any {
+	out := make(map[string]any, len(fields))
+	for _, f := range fields {
+		out[f.Name] = argValue(f.Value, prefix)
+	}
+	return out
+}
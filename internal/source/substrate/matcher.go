@@ -0,0 +1,132 @@
+package substrate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// ActivityEvent is the event name of watch_address matches on every chain.
+const ActivityEvent = "address_activity"
+
+// RuleMatcher applies one rule to the events of a block.
+type RuleMatcher struct {
+	rule   config.Rule
+	kind   string
+	pallet string                 // pallet_event
+	event  string                 // pallet_event (optional)
+	addrs  map[AccountID]struct{} // watch_address
+}
+
+// NewRuleMatcher builds a matcher for Substrate rules.
+func NewRuleMatcher(rule config.Rule) (*RuleMatcher, error) {
+	mt := strings.ToLower(rule.Match.Type)
+	m := &RuleMatcher{rule: rule, kind: mt, addrs: map[AccountID]struct{}{}}
+	switch mt {
+	case config.MatchPalletEvent:
+		m.pallet = rule.Match.Pallet
+		m.event = rule.Match.Event
+	case config.MatchWatchAddress:
+		// The list is shared with other chains, so entries that are not
+		// SS58 addresses are skipped. Any network's prefix matches.
+		for _, a := range rule.Match.Addresses {
+			if id, err := DecodeSS58(a); err == nil {
+				m.addrs[id] = struct{}{}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("rule %s: unsupported match.type %s for substrate", rule.ID, rule.Match.Type)
+	}
+	return m, nil
+}
+
+// Match returns the events of a block the rule matches. exts are the
+// block's extrinsics, with only the hash set for one that could not be
+// decoded, and prefix is the chain's SS58 prefix.
+func (m *RuleMatcher) Match(events []Event, exts []*Extrinsic, prefix uint16) []source.Event {
+	var out []source.Event
+	switch m.kind {
+	case config.MatchPalletEvent:
+		for i := range events {
+			ev := &events[i]
+			if !strings.EqualFold(ev.Pallet, m.pallet) || (m.event != "" && !strings.EqualFold(ev.Name, m.event)) {
+				continue
+			}
+			args := fieldArgs(ev.Fields, prefix)
+			out = append(out, m.finish(ev, exts, prefix, ev.Name, args))
+		}
+	case config.MatchWatchAddress:
+		// One activity event per extrinsic, or per phase outside them, at
+		// the first event naming a watched account.
+		seen := map[string]bool{}
+		for i := range events {
+			ev := &events[i]
+			group := fmt.Sprintf("%s/%d", ev.Phase, ev.Extrinsic)
+			if seen[group] {
+				continue
+			}
+			watched, role, ok := m.watchedIn(ev, extrinsicOf(ev, exts))
+			if !ok {
+				continue
+			}
+			seen[group] = true
+			out = append(out, m.finish(ev, exts, prefix, ActivityEvent, map[string]any{
+				"watched": EncodeSS58(watched, prefix),
+				"role":    role,
+			}))
+		}
+	}
+	return out
+}
+
+// watchedIn finds a watched account in an event: the signer of its
+// extrinsic (role "signer"), or a field holding the account, whose name
+// becomes the role.
+func (m *RuleMatcher) watchedIn(ev *Event, x *Extrinsic) (AccountID, string, bool) {
+	if x != nil && x.Signer != nil {
+		if _, ok := m.addrs[*x.Signer]; ok {
+			return *x.Signer, "signer", true
+		}
+	}
+	for _, f := range ev.Fields {
+		if id, ok := f.Value.(AccountID); ok {
+			if _, ok := m.addrs[id]; ok {
+				return id, f.Name, true
+			}
+		}
+	}
+	return AccountID{}, "", false
+}
+
+// finish fills in what every match carries: the event's pallet, name and
+// phase, and for events of an extrinsic its index, call and signer.
+func (m *RuleMatcher) finish(ev *Event, exts []*Extrinsic, prefix uint16, name string, args map[string]any) source.Event {
+	args["pallet"] = ev.Pallet
+	args["event"] = ev.Name
+	args["phase"] = ev.Phase
+	out := source.Event{RuleID: m.rule.ID, Name: name, Args: args}
+	index := ev.Index
+	out.LogIndex = &index
+	if ev.Extrinsic >= 0 {
+		args["extrinsic_index"] = ev.Extrinsic
+	}
+	if x := extrinsicOf(ev, exts); x != nil {
+		out.TxHash = x.Hash
+		if x.Pallet != "" {
+			args["call"] = x.Pallet + "." + x.Call
+		}
+		if x.Signer != nil {
+			args["signer"] = EncodeSS58(*x.Signer, prefix)
+		}
+	}
+	return out
+}
+
+func extrinsicOf(ev *Event, exts []*Extrinsic) *Extrinsic {
+	if ev.Extrinsic < 0 || ev.Extrinsic >= len(exts) {
+		return nil
+	}
+	return exts[ev.Extrinsic]
+}
@@ -0,0 +1,102 @@
+package substrate
+
+import (
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+)
+
+func decodedBlock(t *testing.T) ([]Event, []*Extrinsic) {
+	t.Helper()
+	meta, err := ParseMetadata(testMetadata())
+	if err != nil {
+		t.Fatalf("parse metadata: %v", err)
+	}
+	events, err := meta.DecodeEvents(testEvents())
+	if err != nil {
+		t.Fatalf("decode events: %v", err)
+	}
+	var exts []*Extrinsic
+	for _, b := range testExtrinsics(testTime) {
+		x, err := meta.DecodeExtrinsic(b)
+		if err != nil {
+			t.Fatalf("decode extrinsic: %v", err)
+		}
+		exts = append(exts, x)
+	}
+	return events, exts
+}
+
+func TestMatcher_PalletEvent(t *testing.T) {
+	events, exts := decodedBlock(t)
+	m, err := NewRuleMatcher(config.Rule{ID: "r1", Match: config.MatchSpec{Type: config.MatchPalletEvent, Pallet: "balances", Event: "Transfer"}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	got := m.Match(events, exts, 0)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(got))
+	}
+	ev := got[0]
+	if ev.RuleID != "r1" || *ev.LogIndex != 1 || ev.TxHash != exts[1].Hash {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	want := map[string]any{
+		"from":            EncodeSS58(alice, 0),
+		"to":              EncodeSS58(bob, 0),
+		"pallet":          "Balances",
+		"event":           "Transfer",
+		"phase":           "apply_extrinsic",
+		"extrinsic_index": 1,
+		"call":            "Balances.transfer_keep_alive",
+		"signer":          EncodeSS58(alice, 0),
+	}
+	for k, v := range want {
+		if ev.Args[k] != v {
+			t.Fatalf("arg %s: got %v, want %v", k, ev.Args[k], v)
+		}
+	}
+	if ev.Args["amount"].(interface{ String() string }).String() != "10000000000" {
+		t.Fatalf("unexpected amount %v", ev.Args["amount"])
+	}
+
+	all, err := NewRuleMatcher(config.Rule{ID: "r2", Match: config.MatchSpec{Type: config.MatchPalletEvent, Pallet: "Balances"}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	if got := all.Match(events, exts, 0); len(got) != 2 || got[1].TxHash != "" || got[1].Args["phase"] != "finalization" {
+		t.Fatalf("expected every Balances event, got %+v", got)
+	}
+}
+
+func TestMatcher_WatchAddress(t *testing.T) {
+	events, exts := decodedBlock(t)
+	m, err := NewRuleMatcher(config.Rule{ID: "w", Match: config.MatchSpec{
+		Type:      config.MatchWatchAddress,
+		Addresses: []string{"0x00000000000000000000000000000000000000aa", EncodeSS58(bob, 2)},
+	}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	if len(m.addrs) != 1 {
+		t.Fatalf("expected only the SS58 address kept, got %d", len(m.addrs))
+	}
+	got := m.Match(events, exts, 0)
+	if len(got) != 2 {
+		t.Fatalf("expected the transfer and the deposit, got %d", len(got))
+	}
+	if ev := got[0]; ev.Name != ActivityEvent || ev.Args["role"] != "to" || ev.Args["watched"] != EncodeSS58(bob, 0) || *ev.LogIndex != 1 {
+		t.Fatalf("unexpected transfer activity %+v", ev)
+	}
+	if ev := got[1]; ev.Args["role"] != "who" || ev.Args["phase"] != "finalization" {
+		t.Fatalf("unexpected deposit activity %+v", ev)
+	}
+
+	signer, err := NewRuleMatcher(config.Rule{ID: "s", Match: config.MatchSpec{Type: config.MatchWatchAddress, Addresses: []string{EncodeSS58(alice, 42)}}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	if got := signer.Match(events, exts, 0); len(got) != 1 || got[0].Args["role"] != "signer" || *got[0].LogIndex != 1 {
+		t.Fatalf("expected one signer activity for the extrinsic, got %+v", got)
+	}
+}
@@ -0,0 +1,471 @@
+package substrate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Type definition kinds of the metadata's portable type registry.
+const (
+	kindComposite = iota
+	kindVariant
+	kindSequence
+	kindArray
+	kindTuple
+	kindPrimitive
+	kindCompact
+	kindBitSequence
+)
+
+// Primitive types, in registry order.
+const (
+	primBool = iota
+	primChar
+	primStr
+	primU8
+	primU16
+	primU32
+	primU64
+	primU128
+	primU256
+	primI8
+	primI16
+	primI32
+	primI64
+	primI128
+	primI256
+)
+
+// Metadata is the part of a runtime's metadata (V14 or V15) needed to
+// decode its blocks and events.
+type Metadata struct {
+	types map[uint32]*typeDef
+	// eventsType is the type of the System.Events storage value.
+	eventsType uint32
+	// addressType, callType and signatureType make up signed extrinsics,
+	// with the signed extensions' types in between.
+	addressType   uint32
+	callType      uint32
+	signatureType uint32
+	extensions    []uint32
+	// SS58Prefix is the chain's address prefix, from the System.SS58Prefix
+	// constant; 42 (generic Substrate) when the runtime has none.
+	SS58Prefix uint16
+}
+
+type typeDef struct {
+	path     []string
+	params   map[string]uint32
+	kind     int
+	fields   []field   // composite
+	variants []variant // variant
+	elem     uint32    // sequence, array, compact; bit sequence store
+	length   uint32    // array
+	tuple    []uint32
+	prim     byte
+}
+
+type field struct {
+	name string
+	typ  uint32
+}
+
+type variant struct {
+	name   string
+	index  byte
+	fields []field
+}
+
+// name is the last segment of the type's path, such as "AccountId32".
+func (t *typeDef) name() string {
+	if len(t.path) == 0 {
+		return ""
+	}
+	return t.path[len(t.path)-1]
+}
+
+func (t *typeDef) variant(index byte) (*variant, bool) {
+	for i := range t.variants {
+		if t.variants[i].index == index {
+			return &t.variants[i], true
+		}
+	}
+	return nil, false
+}
+
+// ParseMetadata decodes the SCALE metadata state_getMetadata returns.
+func ParseMetadata(b []byte) (*Metadata, error) {
+	r := newReader(b)
+	magic, err := r.bytes(4)
+	if err != nil {
+		return nil, err
+	}
+	if string(magic) != "meta" {
+		return nil, errors.New("metadata: missing magic number")
+	}
+	version, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if version != 14 && version != 15 {
+		return nil, fmt.Errorf("metadata: unsupported version V%d, need V14 or V15", version)
+	}
+	m := &Metadata{types: map[uint32]*typeDef{}, SS58Prefix: 42}
+	if err := m.readTypes(r); err != nil {
+		return nil, fmt.Errorf("metadata types: %w", err)
+	}
+	if err := m.readPallets(r, version); err != nil {
+		return nil, fmt.Errorf("metadata pallets: %w", err)
+	}
+	if err := m.readExtrinsic(r, version); err != nil {
+		return nil, fmt.Errorf("metadata extrinsic: %w", err)
+	}
+	if _, ok := m.types[m.eventsType]; !ok {
+		return nil, errors.New("metadata: no System.Events storage")
+	}
+	return m, nil
+}
+
+func (m *Metadata) readTypes(r *reader) error {
+	n, err := r.compactInt()
+	if err != nil {
+		return err
+	}
+	for range n {
+		id, err := r.compactU32()
+		if err != nil {
+			return err
+		}
+		t := &typeDef{params: map[string]uint32{}}
+		if t.path, err = r.strings(); err != nil {
+			return err
+		}
+		params, err := r.compactInt()
+		if err != nil {
+			return err
+		}
+		for range params {
+			name, err := r.string()
+			if err != nil {
+				return err
+			}
+			some, err := r.optional()
+			if err != nil {
+				return err
+			}
+			if some {
+				typ, err := r.compactU32()
+				if err != nil {
+					return err
+				}
+				t.params[name] = typ
+			}
+		}
+		if err := readTypeDef(r, t); err != nil {
+			return fmt.Errorf("type %d: %w", id, err)
+		}
+		if _, err := r.strings(); err != nil { // docs
+			return err
+		}
+		m.types[id] = t
+	}
+	return nil
+}
+
+func readTypeDef(r *reader, t *typeDef) error {
+	kind, err := r.byte()
+	if err != nil {
+		return err
+	}
+	t.kind = int(kind)
+	switch t.kind {
+	case kindComposite:
+		t.fields, err = readFields(r)
+		return err
+	case kindVariant:
+		n, err := r.compactInt()
+		if err != nil {
+			return err
+		}
+		for range n {
+			var v variant
+			if v.name, err = r.string(); err != nil {
+				return err
+			}
+			if v.fields, err = readFields(r); err != nil {
+				return err
+			}
+			if v.index, err = r.byte(); err != nil {
+				return err
+			}
+			if _, err := r.strings(); err != nil { // docs
+				return err
+			}
+			t.variants = append(t.variants, v)
+		}
+		return nil
+	case kindSequence, kindCompact:
+		t.elem, err = r.compactU32()
+		return err
+	case kindArray:
+		n, err := r.uint(4)
+		if err != nil {
+			return err
+		}
+		t.length = uint32(n)
+		t.elem, err = r.compactU32()
+		return err
+	case kindTuple:
+		n, err := r.compactInt()
+		if err != nil {
+			return err
+		}
+		for range n {
+			typ, err := r.compactU32()
+			if err != nil {
+				return err
+			}
+			t.tuple = append(t.tuple, typ)
+		}
+		return nil
+	case kindPrimitive:
+		t.prim, err = r.byte()
+		return err
+	case kindBitSequence:
+		if t.elem, err = r.compactU32(); err != nil { // bit store
+			return err
+		}
+		_, err = r.compactU32() // bit order
+		return err
+	}
+	return fmt.Errorf("unknown type kind %d", kind)
+}
+
+func readFields(r *reader) ([]field, error) {
+	n, err := r.compactInt()
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]field, 0, n)
+	for range n {
+		var f field
+		some, err := r.optional()
+		if err != nil {
+			return nil, err
+		}
+		if some {
+			if f.name, err = r.string(); err != nil {
+				return nil, err
+			}
+		}
+		if f.typ, err = r.compactU32(); err != nil {
+			return nil, err
+		}
+		if some, err = r.optional(); err != nil { // type name
+			return nil, err
+		}
+		if some {
+			if _, err := r.string(); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := r.strings(); err != nil { // docs
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func (m *Metadata) readPallets(r *reader, version byte) error {
+	n, err := r.compactInt()
+	if err != nil {
+		return err
+	}
+	for range n {
+		name, err := r.string()
+		if err != nil {
+			return err
+		}
+		if err := m.readStorage(r, name); err != nil {
+			return fmt.Errorf("pallet %s storage: %w", name, err)
+		}
+		if err := skipOptionalType(r); err != nil { // calls
+			return err
+		}
+		if err := skipOptionalType(r); err != nil { // event
+			return err
+		}
+		if err := m.readConstants(r, name); err != nil {
+			return fmt.Errorf("pallet %s constants: %w", name, err)
+		}
+		if err := skipOptionalType(r); err != nil { // error
+			return err
+		}
+		if _, err := r.byte(); err != nil { // index
+			return err
+		}
+		if version >= 15 {
+			if _, err := r.strings(); err != nil { // docs
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func skipOptionalType(r *reader) error {
+	some, err := r.optional()
+	if err != nil || !some {
+		return err
+	}
+	_, err = r.compactU32()
+	return err
+}
+
+// readStorage reads a pallet's storage entries, keeping the type of
+// System.Events.
+func (m *Metadata) readStorage(r *reader, pallet string) error {
+	some, err := r.optional()
+	if err != nil || !some {
+		return err
+	}
+	if _, err := r.string(); err != nil { // prefix
+		return err
+	}
+	n, err := r.compactInt()
+	if err != nil {
+		return err
+	}
+	for range n {
+		name, err := r.string()
+		if err != nil {
+			return err
+		}
+		if _, err := r.byte(); err != nil { // modifier
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case 0: // plain
+			typ, err := r.compactU32()
+			if err != nil {
+				return err
+			}
+			if pallet == "System" && name == "Events" {
+				m.eventsType = typ
+			}
+		case 1: // map
+			if _, err := r.byteVec(); err != nil { // hashers, one byte each
+				return err
+			}
+			if _, err := r.compactU32(); err != nil { // key
+				return err
+			}
+			if _, err := r.compactU32(); err != nil { // value
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown storage entry kind %d", kind)
+		}
+		if _, err := r.byteVec(); err != nil { // default
+			return err
+		}
+		if _, err := r.strings(); err != nil { // docs
+			return err
+		}
+	}
+	return nil
+}
+
+// readConstants reads a pallet's constants, keeping System.SS58Prefix.
+func (m *Metadata) readConstants(r *reader, pallet string) error {
+	n, err := r.compactInt()
+	if err != nil {
+		return err
+	}
+	for range n {
+		name, err := r.string()
+		if err != nil {
+			return err
+		}
+		if _, err := r.compactU32(); err != nil {
+			return err
+		}
+		value, err := r.byteVec()
+		if err != nil {
+			return err
+		}
+		if pallet == "System" && name == "SS58Prefix" && len(value) >= 1 {
+			prefix := uint16(value[0])
+			if len(value) >= 2 {
+				prefix |= uint16(value[1]) << 8
+			}
+			m.SS58Prefix = prefix
+		}
+		if _, err := r.strings(); err != nil { // docs
+			return err
+		}
+	}
+	return nil
+}
+
+// readExtrinsic reads the types making up a signed extrinsic. V14 names
+// them as the parameters of the UncheckedExtrinsic type; V15 lists them.
+func (m *Metadata) readExtrinsic(r *reader, version byte) error {
+	if version == 14 {
+		typ, err := r.compactU32()
+		if err != nil {
+			return err
+		}
+		if _, err := r.byte(); err != nil { // version
+			return err
+		}
+		t, ok := m.types[typ]
+		if !ok {
+			return fmt.Errorf("unknown extrinsic type %d", typ)
+		}
+		var okA, okC, okS bool
+		m.addressType, okA = t.params["Address"]
+		m.callType, okC = t.params["Call"]
+		m.signatureType, okS = t.params["Signature"]
+		if !okA || !okC || !okS {
+			return errors.New("extrinsic type lacks Address, Call or Signature parameters")
+		}
+	} else {
+		if _, err := r.byte(); err != nil { // version
+			return err
+		}
+		for _, dst := range []*uint32{&m.addressType, &m.callType, &m.signatureType} {
+			typ, err := r.compactU32()
+			if err != nil {
+				return err
+			}
+			*dst = typ
+		}
+		if _, err := r.compactU32(); err != nil { // extra
+			return err
+		}
+	}
+	n, err := r.compactInt()
+	if err != nil {
+		return err
+	}
+	for range n {
+		if _, err := r.string(); err != nil { // identifier
+			return err
+		}
+		typ, err := r.compactU32()
+		if err != nil {
+			return err
+		}
+		if _, err := r.compactU32(); err != nil { // additional signed
+			return err
+		}
+		m.extensions = append(m.extensions, typ)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package substrate
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Well-known development accounts.
+var (
+	alice = mustAccount("d43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d")
+	bob   = mustAccount("8eaf04151687736326c9fea17e25fc5287613693c912909cb226aa4794f26a48")
+)
+
+func mustAccount(h string) AccountID {
+	b, err := hex.DecodeString(h)
+	if err != nil || len(b) != 32 {
+		panic("bad account " + h)
+	}
+	return AccountID(b)
+}
+
+// enc writes SCALE for test fixtures.
+type enc []byte
+
+func (e *enc) u8(v ...byte) *enc { *e = append(*e, v...); return e }
+
+func (e *enc) compact(v uint64) *enc {
+	switch {
+	case v < 1<<6:
+		return e.u8(byte(v << 2))
+	case v < 1<<14:
+		return e.u8(byte(v<<2)|1, byte(v>>6))
+	case v < 1<<30:
+		return e.u8(binary.LittleEndian.AppendUint32(nil, uint32(v<<2)|2)...)
+	}
+	b := binary.LittleEndian.AppendUint64(nil, v)
+	for len(b) > 4 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+	return e.u8(byte(len(b)-4)<<2 | 3).u8(b...)
+}
+
+func (e *enc) str(s string) *enc { return e.compact(uint64(len(s))).u8([]byte(s)...) }
+
+func (e *enc) strs(ss ...string) *enc {
+	e.compact(uint64(len(ss)))
+	for _, s := range ss {
+		e.str(s)
+	}
+	return e
+}
+
+// typ starts a registry entry: id, path, type params and the def's kind.
+func (e *enc) typ(id uint64, path []string, params map[string]uint64, kind byte) *enc {
+	e.compact(id).strs(path...).compact(uint64(len(params)))
+	names := make([]string, 0, len(params))
+	for n := range params {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	for _, n := range names {
+		e.str(n).u8(1).compact(params[n])
+	}
+	return e.u8(kind)
+}
+
+type tf struct {
+	name string
+	typ  uint64
+}
+
+func (e *enc) fields(fs ...tf) *enc {
+	e.compact(uint64(len(fs)))
+	for _, f := range fs {
+		if f.name == "" {
+			e.u8(0)
+		} else {
+			e.u8(1).str(f.name)
+		}
+		e.compact(f.typ).u8(0).strs()
+	}
+	return e
+}
+
+type tv struct {
+	name   string
+	index  byte
+	fields []tf
+}
+
+func (e *enc) variants(vs ...tv) *enc {
+	e.compact(uint64(len(vs)))
+	for _, v := range vs {
+		e.str(v.name).fields(v.fields...).u8(v.index).strs()
+	}
+	return e
+}
+
+// testMetadata is a V14 metadata of a tiny runtime with System, Timestamp
+// and Balances pallets and a Polkadot SS58 prefix.
+func testMetadata() []byte {
+	e := &enc{}
+	e.u8([]byte("meta")...).u8(14)
+	e.compact(28)
+	e.typ(0, nil, nil, kindPrimitive).u8(primU8).strs()
+	e.typ(1, nil, nil, kindArray).u8(32, 0, 0, 0).compact(0).strs()
+	e.typ(2, []string{"sp_core", "crypto", "AccountId32"}, nil, kindComposite).fields(tf{"", 1}).strs()
+	e.typ(3, nil, nil, kindPrimitive).u8(primU128).strs()
+	e.typ(4, nil, nil, kindPrimitive).u8(primU32).strs()
+	e.typ(5, []string{"frame_system", "Phase"}, nil, kindVariant).variants(
+		tv{"ApplyExtrinsic", 0, []tf{{"", 4}}}, tv{"Finalization", 1, nil}, tv{"Initialization", 2, nil}).strs()
+	e.typ(6, []string{"pallet_balances", "pallet", "Event"}, nil, kindVariant).variants(
+		tv{"Transfer", 2, []tf{{"from", 2}, {"to", 2}, {"amount", 3}}},
+		tv{"Deposit", 7, []tf{{"who", 2}, {"amount", 3}}}).strs()
+	e.typ(7, []string{"frame_system", "pallet", "Event"}, nil, kindVariant).variants(tv{"ExtrinsicSuccess", 0, nil}).strs()
+	e.typ(8, []string{"runtime", "RuntimeEvent"}, nil, kindVariant).variants(
+		tv{"System", 0, []tf{{"", 7}}}, tv{"Balances", 5, []tf{{"", 6}}}).strs()
+	e.typ(9, []string{"primitive_types", "H256"}, nil, kindComposite).fields(tf{"", 1}).strs()
+	e.typ(10, nil, nil, kindSequence).compact(9).strs()
+	e.typ(11, []string{"frame_system", "EventRecord"}, nil, kindComposite).fields(tf{"phase", 5}, tf{"event", 8}, tf{"topics", 10}).strs()
+	e.typ(12, nil, nil, kindSequence).compact(11).strs()
+	e.typ(13, nil, nil, kindPrimitive).u8(primU64).strs()
+	e.typ(14, []string{"pallet_timestamp", "pallet", "Call"}, nil, kindVariant).variants(tv{"set", 0, []tf{{"now", 15}}}).strs()
+	e.typ(15, nil, nil, kindCompact).compact(13).strs()
+	e.typ(16, []string{"pallet_balances", "pallet", "Call"}, nil, kindVariant).variants(
+		tv{"transfer_keep_alive", 3, []tf{{"dest", 17}, {"value", 18}}}).strs()
+	e.typ(17, []string{"sp_runtime", "multiaddress", "MultiAddress"}, nil, kindVariant).variants(
+		tv{"Id", 0, []tf{{"", 2}}}, tv{"Index", 1, []tf{{"", 23}}}).strs()
+	e.typ(18, nil, nil, kindCompact).compact(3).strs()
+	e.typ(19, []string{"runtime", "RuntimeCall"}, nil, kindVariant).variants(
+		tv{"Timestamp", 3, []tf{{"", 14}}}, tv{"Balances", 5, []tf{{"", 16}}}).strs()
+	e.typ(20, []string{"sp_runtime", "MultiSignature"}, nil, kindVariant).variants(tv{"Sr25519", 1, []tf{{"", 21}}}).strs()
+	e.typ(21, nil, nil, kindArray).u8(64, 0, 0, 0).compact(0).strs()
+	e.typ(22, []string{"frame_system", "extensions", "check_nonce", "CheckNonce"}, nil, kindComposite).fields(tf{"", 23}).strs()
+	e.typ(23, nil, nil, kindCompact).compact(4).strs()
+	e.typ(24, []string{"sp_runtime", "generic", "unchecked_extrinsic", "UncheckedExtrinsic"},
+		map[string]uint64{"Address": 17, "Call": 19, "Signature": 20, "Extra": 27}, kindComposite).fields(tf{"", 25}).strs()
+	e.typ(25, nil, nil, kindSequence).compact(0).strs()
+	e.typ(26, nil, nil, kindPrimitive).u8(primU16).strs()
+	e.typ(27, nil, nil, kindTuple).compact(1).compact(22).strs()
+
+	e.compact(3)
+	// System: Events storage and the SS58Prefix constant.
+	e.str("System").u8(1).str("System").compact(2)
+	e.str("Number").u8(1).u8(0).compact(4).compact(4).u8(0, 0, 0, 0).strs()
+	e.str("Events").u8(1).u8(0).compact(12).compact(1).u8(0).strs("Events deposited for the current block.")
+	e.u8(0).u8(1).compact(7)
+	e.compact(1).str("SS58Prefix").compact(26).compact(2).u8(0, 0).strs()
+	e.u8(0).u8(0)
+	// Timestamp: a map entry, to be skipped.
+	e.str("Timestamp").u8(1).str("Timestamp").compact(1)
+	e.str("Ghost").u8(0).u8(1).compact(1).u8(2).compact(4).compact(13).compact(0).strs()
+	e.u8(1).compact(14).u8(0).compact(0).u8(0).u8(3)
+	// Balances: calls and events.
+	e.str("Balances").u8(0).u8(1).compact(16).u8(1).compact(6).compact(0).u8(0).u8(5)
+
+	e.compact(24).u8(4).compact(1).str("CheckNonce").compact(22).compact(27)
+	e.compact(19) // runtime type
+	return *e
+}
+
+// eventRecord encodes an EventRecord without topics.
+func eventRecord(e *enc, phase []byte, event ...byte) {
+	e.u8(phase...).u8(event...).compact(0)
+}
+
+func u128(v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, v), 0)
+}
+
+// testEvents are the events of a block where extrinsic 1 is Alice sending
+// Bob 1 DOT, and Bob gets a deposit at finalization.
+func testEvents() []byte {
+	e := &enc{}
+	e.compact(4)
+	eventRecord(e, []byte{0, 0, 0, 0, 0}, 0, 0)
+	eventRecord(e, []byte{0, 1, 0, 0, 0}, slices.Concat([]byte{5, 2}, alice[:], bob[:], u128(10_000_000_000))...)
+	eventRecord(e, []byte{0, 1, 0, 0, 0}, 0, 0)
+	eventRecord(e, []byte{1}, slices.Concat([]byte{5, 7}, bob[:], u128(5))...)
+	return *e
+}
+
+// testExtrinsics are a Timestamp.set inherent at now and a transfer signed
+// by Alice.
+func testExtrinsics(now time.Time) [][]byte {
+	set := &enc{}
+	set.u8(4, 3, 0).compact(uint64(now.UnixMilli()))
+	transfer := &enc{}
+	transfer.u8(0x84, 0).u8(alice[:]...).u8(1).u8(make([]byte, 64)...).compact(7)
+	transfer.u8(5, 3, 0).u8(bob[:]...).compact(10_000_000_000)
+	var out [][]byte
+	for _, x := range []*enc{set, transfer} {
+		out = append(out, *(&enc{}).compact(uint64(len(*x))).u8(*x...))
+	}
+	return out
+}
+
+func TestParseMetadataAndDecodeEvents(t *testing.T) {
+	meta, err := ParseMetadata(testMetadata())
+	if err != nil {
+		t.Fatalf("parse metadata: %v", err)
+	}
+	if meta.SS58Prefix != 0 {
+		t.Fatalf("expected the Polkadot prefix, got %d", meta.SS58Prefix)
+	}
+	events, err := meta.DecodeEvents(testEvents())
+	if err != nil {
+		t.Fatalf("decode events: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(events))
+	}
+	tr := events[1]
+	if tr.Pallet != "Balances" || tr.Name != "Transfer" || tr.Phase != "apply_extrinsic" || tr.Extrinsic != 1 || tr.Index != 1 {
+		t.Fatalf("unexpected transfer %+v", tr)
+	}
+	if from, _ := tr.Value("from"); from != alice {
+		t.Fatalf("unexpected from %v", from)
+	}
+	if amount, _ := tr.Value("amount"); amount.(*big.Int).Cmp(big.NewInt(10_000_000_000)) != 0 {
+		t.Fatalf("unexpected amount %v", amount)
+	}
+	if dep := events[3]; dep.Phase != "finalization" || dep.Extrinsic != -1 || dep.Name != "Deposit" {
+		t.Fatalf("unexpected deposit %+v", dep)
+	}
+
+	if _, err := meta.DecodeEvents(testEvents()[:40]); err == nil {
+		t.Fatal("expected an error for truncated events")
+	}
+	if _, err := ParseMetadata([]byte("meta\x0d")); err == nil {
+		t.Fatal("expected V13 metadata to be rejected")
+	}
+}
+
+func TestDecodeExtrinsic(t *testing.T) {
+	meta, err := ParseMetadata(testMetadata())
+	if err != nil {
+		t.Fatalf("parse metadata: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	raw := testExtrinsics(now)
+	var exts []*Extrinsic
+	for _, b := range raw {
+		x, err := meta.DecodeExtrinsic(b)
+		if err != nil {
+			t.Fatalf("decode extrinsic: %v", err)
+		}
+		exts = append(exts, x)
+	}
+	if got := Timestamp(exts); !got.Equal(now) {
+		t.Fatalf("expected timestamp %v, got %v", now, got)
+	}
+	x := exts[1]
+	if x.Signer == nil || *x.Signer != alice || x.Pallet != "Balances" || x.Call != "transfer_keep_alive" {
+		t.Fatalf("unexpected extrinsic %+v", x)
+	}
+	args := fieldArgs(x.Args, meta.SS58Prefix)
+	if args["dest"] != "14E5nqKAp3oAJcmzgZhUD2RcptBeUBScxKHgJKU4HPNcKVf3" || args["value"] != uint64(10_000_000_000) {
+		t.Fatalf("unexpected args %+v", args)
+	}
+	if x.Hash != ExtrinsicHash(raw[1]) || len(x.Hash) != 66 {
+		t.Fatalf("unexpected hash %s", x.Hash)
+	}
+}
+
+func TestSS58(t *testing.T) {
+	for prefix, want := range map[uint16]string{
+		0:  "15oF4uVJwmo4TdGW7VfQxNLavjCXviqxT9S1MgbjMNHr6Sp5",
+		42: "5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY",
+	} {
+		if got := EncodeSS58(alice, prefix); got != want {
+			t.Fatalf("prefix %d: got %s, want %s", prefix, got, want)
+		}
+		id, err := DecodeSS58(want)
+		if err != nil || id != alice {
+			t.Fatalf("decode %s: %x, %v", want, id, err)
+		}
+	}
+	if id, err := DecodeSS58(EncodeSS58(bob, 2007)); err != nil || id != bob {
+		t.Fatalf("two-byte prefix round trip: %x, %v", id, err)
+	}
+	if _, err := DecodeSS58("5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQZ"); err == nil {
+		t.Fatal("expected a checksum error")
+	}
+	if _, err := DecodeSS58("0x8eaf04151687736326c9fea17e25fc5287613693"); err == nil {
+		t.Fatal("expected an EVM address to be rejected")
+	}
+}
@@ -0,0 +1,187 @@
+package substrate
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+// errShort is returned when SCALE data ends before the value it encodes.
+var errShort = errors.New("scale: unexpected end of data")
+
+// reader decodes SCALE, the encoding Substrate uses for blocks, events and
+// metadata.
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func newReader(b []byte) *reader {
+	return &reader{buf: b}
+}
+
+func (r *reader) remaining() int {
+	return len(r.buf) - r.pos
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.remaining() < n {
+		return nil, errShort
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) byte() (byte, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *reader) bool() (bool, error) {
+	b, err := r.byte()
+	if err != nil {
+		return false, err
+	}
+	switch b {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	}
+	return false, fmt.Errorf("scale: invalid bool %d", b)
+}
+
+// uint reads a little-endian unsigned integer of size bytes, up to 8.
+func (r *reader) uint(size int) (uint64, error) {
+	b, err := r.bytes(size)
+	if err != nil {
+		return 0, err
+	}
+	var v [8]byte
+	copy(v[:], b)
+	return binary.LittleEndian.Uint64(v[:]), nil
+}
+
+// bigUint reads a little-endian unsigned integer of size bytes.
+func (r *reader) bigUint(size int) (*big.Int, error) {
+	b, err := r.bytes(size)
+	if err != nil {
+		return nil, err
+	}
+	be := slices.Clone(b)
+	slices.Reverse(be)
+	return new(big.Int).SetBytes(be), nil
+}
+
+// bigInt reads a little-endian two's complement integer of size bytes.
+func (r *reader) bigInt(size int) (*big.Int, error) {
+	v, err := r.bigUint(size)
+	if err != nil {
+		return nil, err
+	}
+	if v.Bit(size*8-1) == 1 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(size*8)))
+	}
+	return v, nil
+}
+
+// compact reads a compact-encoded unsigned integer. Values above 64 bits
+// are returned as a *big.Int, others as a uint64.
+func (r *reader) compact() (any, error) {
+	b, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch b & 3 {
+	case 0:
+		return uint64(b >> 2), nil
+	case 1:
+		hi, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		return uint64(b>>2) | uint64(hi)<<6, nil
+	case 2:
+		rest, err := r.uint(3)
+		if err != nil {
+			return nil, err
+		}
+		return uint64(b>>2) | rest<<6, nil
+	}
+	size := int(b>>2) + 4
+	if size <= 8 {
+		return r.uint(size)
+	}
+	return r.bigUint(size)
+}
+
+// compactInt reads a compact integer that must fit an int, such as the
+// length of a sequence.
+func (r *reader) compactInt() (int, error) {
+	v, err := r.compact()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := v.(uint64)
+	if !ok || n > uint64(r.remaining())+1<<20 {
+		return 0, fmt.Errorf("scale: length %v out of range", v)
+	}
+	return int(n), nil
+}
+
+// compactU32 reads a compact integer used as a type id.
+func (r *reader) compactU32() (uint32, error) {
+	v, err := r.compact()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := v.(uint64)
+	if !ok || n > 1<<32-1 {
+		return 0, fmt.Errorf("scale: type id %v out of range", v)
+	}
+	return uint32(n), nil
+}
+
+// byteVec reads a length-prefixed byte string.
+func (r *reader) byteVec() ([]byte, error) {
+	n, err := r.compactInt()
+	if err != nil {
+		return nil, err
+	}
+	return r.bytes(n)
+}
+
+func (r *reader) string() (string, error) {
+	b, err := r.byteVec()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (r *reader) strings() ([]string, error) {
+	n, err := r.compactInt()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, min(n, 64))
+	for range n {
+		s, err := r.string()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// optional reads the flag of an Option, reporting whether a value follows.
+func (r *reader) optional() (bool, error) {
+	return r.bool()
+}
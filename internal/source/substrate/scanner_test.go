@@ -0,0 +1,173 @@
+package substrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+var testTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeClient serves a chain of blocks by height, all with the test
+// runtime's extrinsics and events.
+type fakeClient struct {
+	tip       uint64
+	finalized uint64
+	chain     map[uint64]string // height -> hash
+	blocks    map[string]*Block
+	metadata  int // Metadata calls
+}
+
+func (f *fakeClient) LatestHeight(ctx context.Context) (uint64, error) {
+	return f.tip, nil
+}
+
+func (f *fakeClient) FinalizedHeight(ctx context.Context) (uint64, error) {
+	return f.finalized, nil
+}
+
+func (f *fakeClient) BlockHash(ctx context.Context, height uint64) (string, error) {
+	hash, ok := f.chain[height]
+	if !ok {
+		return "", fmt.Errorf("%w at height %d", ErrBlockNotFound, height)
+	}
+	return hash, nil
+}
+
+func (f *fakeClient) Block(ctx context.Context, hash string) (*Block, error) {
+	b, ok := f.blocks[hash]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrBlockNotFound, hash)
+	}
+	return b, nil
+}
+
+func (f *fakeClient) Events(ctx context.Context, hash string) ([]byte, error) {
+	return testEvents(), nil
+}
+
+func (f *fakeClient) SpecVersion(ctx context.Context, hash string) (uint32, error) {
+	return 1_002_000, nil
+}
+
+func (f *fakeClient) Metadata(ctx context.Context, hash string) ([]byte, error) {
+	f.metadata++
+	return testMetadata(), nil
+}
+
+// add puts a block at height on the chain, building on whatever is below it.
+func (f *fakeClient) add(height uint64, hash string) {
+	if f.chain == nil {
+		f.chain, f.blocks = map[uint64]string{}, map[string]*Block{}
+	}
+	f.chain[height] = hash
+	f.blocks[hash] = &Block{Hash: hash, ParentHash: f.chain[height-1], Height: height, Extrinsics: testExtrinsics(testTime.Add(time.Duration(height) * 6 * time.Second))}
+	f.tip = max(f.tip, height)
+}
+
+func newTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.Open(t.TempDir() + "/db.sqlite")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestScannerMatchesEvents(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &fakeClient{}
+	for h := uint64(99); h <= 102; h++ {
+		client.add(h, fmt.Sprintf("0x%d", h))
+	}
+	client.finalized = 101
+	if err := store.UpsertCursor(ctx, "dot", 99, "0x99"); err != nil {
+		t.Fatalf("seed cursor: %v", err)
+	}
+	rules := []config.Rule{{ID: "transfers", Source: "dot", Match: config.MatchSpec{Type: config.MatchPalletEvent, Pallet: "Balances", Event: "Transfer"}}}
+	sc, err := NewScanner(client, store, config.Source{ID: "dot", Type: Chain, MaxBlocksPerTick: 10}, 0, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	sc.SetFinality(config.ConfirmFinalized)
+	events, err := sc.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected a transfer in each of blocks 100 and 101, got %d", len(events))
+	}
+	ev := events[1]
+	if ev.Chain != Chain || ev.Height != 101 || ev.Hash != "0x101" || !ev.Timestamp.Equal(testTime.Add(606*time.Second)) {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if h, hash, _, _ := store.GetCursor(ctx, "dot"); h != 101 || hash != "0x101" {
+		t.Fatalf("expected cursor at the finalized block 101, got %d %s", h, hash)
+	}
+	if client.metadata != 1 {
+		t.Fatalf("expected metadata fetched once for the runtime, got %d", client.metadata)
+	}
+}
+
+func TestScannerRewindsToCommonAncestor(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &fakeClient{}
+	for h := uint64(10); h <= 12; h++ {
+		client.add(h, fmt.Sprintf("0x%d", h))
+	}
+	sc, err := NewScanner(client, store, config.Source{ID: "dot", Type: Chain, StartBlock: "10", MaxBlocksPerTick: 10}, 0, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	sc.tipTTL = 0
+	if _, err := sc.ProcessNext(ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	// Blocks 11 and 12 are replaced by a longer fork.
+	for h := uint64(11); h <= 13; h++ {
+		client.add(h, fmt.Sprintf("0x%db", h))
+	}
+	_, err = sc.ProcessNext(ctx)
+	var rg *source.ReorgError
+	if !errors.As(err, &rg) {
+		t.Fatalf("expected a reorg, got %v", err)
+	}
+	if rg.From != 11 || rg.To != 12 || rg.Exceeded {
+		t.Fatalf("unexpected reorg %+v", rg)
+	}
+	if h, hash, _, _ := store.GetCursor(ctx, "dot"); h != 10 || hash != "0x10" {
+		t.Fatalf("expected cursor at the common ancestor 10, got %d %s", h, hash)
+	}
+}
+
+func TestResolveStartHeight(t *testing.T) {
+	cases := []struct {
+		start string
+		want  uint64
+	}{
+		{"", 500},
+		{"latest", 500},
+		{"latest-20", 480},
+		{"latest-900", 0},
+		{"123", 123},
+	}
+	for _, c := range cases {
+		got, err := resolveStartHeight(c.start, 500)
+		if err != nil || got != c.want {
+			t.Fatalf("%q: got %d, %v; want %d", c.start, got, err, c.want)
+		}
+	}
+	if _, err := resolveStartHeight("soon", 500); err == nil {
+		t.Fatal("expected an error for a bad start_block")
+	}
+}
@@ -0,0 +1,91 @@
+package substrate
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var ss58Pre = []byte("SS58PRE")
+
+// EncodeSS58 renders an account as an SS58 address with the network's
+// prefix, such as 0 for Polkadot or 2 for Kusama.
+func EncodeSS58(id AccountID, prefix uint16) string {
+	var b []byte
+	if prefix < 64 {
+		b = []byte{byte(prefix)}
+	} else {
+		b = []byte{byte(prefix&0xfc>>2) | 0x40, byte(prefix>>8) | byte(prefix&0x03)<<6}
+	}
+	b = append(b, id[:]...)
+	sum := ss58Checksum(b)
+	return base58Encode(append(b, sum[:2]...))
+}
+
+// DecodeSS58 reads the account of an SS58 address of any network.
+func DecodeSS58(addr string) (AccountID, error) {
+	b, err := base58Decode(addr)
+	if err != nil {
+		return AccountID{}, err
+	}
+	prefixLen := 1
+	if len(b) > 0 && b[0]&0x40 != 0 {
+		prefixLen = 2
+	}
+	if len(b) != prefixLen+32+2 {
+		return AccountID{}, errors.New("ss58: not a 32-byte account address")
+	}
+	body := b[:prefixLen+32]
+	if sum := ss58Checksum(body); !bytes.Equal(sum[:2], b[len(body):]) {
+		return AccountID{}, errors.New("ss58: bad checksum")
+	}
+	return AccountID(body[prefixLen:]), nil
+}
+
+func ss58Checksum(b []byte) [64]byte {
+	return blake2b.Sum512(append(append([]byte{}, ss58Pre...), b...))
+}
+
+func base58Encode(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	var out []byte
+	mod := new(big.Int)
+	for n.Sign() > 0 {
+		n.DivMod(n, big.NewInt(58), mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	for _, c := range []byte(s) {
+		i := bytes.IndexByte([]byte(base58Alphabet), c)
+		if i < 0 {
+			return nil, errors.New("ss58: invalid base58 character")
+		}
+		n.Mul(n, big.NewInt(58))
+		n.Add(n, big.NewInt(int64(i)))
+	}
+	out := n.Bytes()
+	for _, c := range []byte(s) {
+		if c != base58Alphabet[0] {
+			break
+		}
+		out = append([]byte{0}, out...)
+	}
+	return out, nil
+}
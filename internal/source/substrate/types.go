@@ -0,0 +1,6 @@
+package substrate
+
+// Chain identifier for Substrate chains such as Polkadot and Kusama. Its
+// events' TxHash is the extrinsic hash, empty for events outside an
+// extrinsic, and LogIndex the position of the event among the block's.
+const Chain = "substrate"
@@ -0,0 +1,93 @@
+package tron
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// addressPrefix starts every Tron mainnet and testnet address.
+const addressPrefix = 0x41
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// Address converts an address as the node's API shows it, hex with or
+// without the 41 prefix, to the base58 form wallets and explorers use.
+func Address(h string) (string, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(h, "0x"))
+	if err != nil {
+		return "", fmt.Errorf("address %q: %w", h, err)
+	}
+	switch {
+	case len(b) == 20:
+		b = append([]byte{addressPrefix}, b...)
+	case len(b) == 21 && b[0] == addressPrefix:
+	default:
+		return "", fmt.Errorf("address %q: want 20 bytes", h)
+	}
+	return encodeBase58Check(b), nil
+}
+
+// topicAddress reads the address in a 32-byte log topic.
+func topicAddress(topic string) (string, error) {
+	if len(topic) != 64 {
+		return "", fmt.Errorf("topic %q: want 32 bytes", topic)
+	}
+	return Address(topic[24:])
+}
+
+// DecodeAddress returns the hex form, with the 41 prefix, of a base58
+// address, checking its checksum.
+func DecodeAddress(a string) (string, error) {
+	n := new(big.Int)
+	for _, c := range []byte(a) {
+		i := strings.IndexByte(base58Alphabet, c)
+		if i < 0 {
+			return "", fmt.Errorf("address %q: invalid base58", a)
+		}
+		n.Mul(n, big.NewInt(58))
+		n.Add(n, big.NewInt(int64(i)))
+	}
+	b := n.Bytes()
+	for i := 0; i < len(a) && a[i] == base58Alphabet[0]; i++ {
+		b = append([]byte{0}, b...)
+	}
+	if len(b) != 25 || b[0] != addressPrefix {
+		return "", fmt.Errorf("address %q: not a tron address", a)
+	}
+	if sum := checksum(b[:21]); !bytes.Equal(sum, b[21:]) {
+		return "", errors.New("address " + a + ": bad checksum")
+	}
+	return hex.EncodeToString(b[:21]), nil
+}
+
+func encodeBase58Check(payload []byte) string {
+	b := append(append([]byte{}, payload...), checksum(payload)...)
+	n := new(big.Int).SetBytes(b)
+	var out []byte
+	mod := new(big.Int)
+	for n.Sign() > 0 {
+		n.DivMod(n, big.NewInt(58), mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func checksum(payload []byte) []byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return second[:4]
+}
@@ -0,0 +1,271 @@
+package tron
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/rpclimit"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// ErrBlockNotFound is returned for a height the node has no block at.
+var ErrBlockNotFound = errors.New("block not found")
+
+// Client is the slice of the Tron full node HTTP API (as TronGrid serves
+// it) the scanner uses.
+type Client interface {
+	LatestHeight(ctx context.Context) (uint64, error)
+	// FinalizedHeight returns the solidified block, confirmed by two
+	// thirds of the super representatives.
+	FinalizedHeight(ctx context.Context) (uint64, error)
+	Block(ctx context.Context, height uint64) (*Block, error)
+	// TransactionInfo returns the receipts and logs of a block's
+	// transactions.
+	TransactionInfo(ctx context.Context, height uint64) ([]TxInfo, error)
+}
+
+// Block is a block's header and transactions.
+type Block struct {
+	Hash       string
+	ParentHash string
+	Height     uint64
+	Time       time.Time
+	Txs        []Tx
+}
+
+// Tx is a transaction and the contract it runs: a TransferContract for
+// TRX, a TriggerSmartContract for a call, and so on.
+type Tx struct {
+	ID       string
+	Result   string // SUCCESS, REVERT, OUT_OF_ENERGY...
+	Type     string
+	Owner    string // sender, base58
+	To       string // TransferContract recipient, base58
+	Contract string // TriggerSmartContract contract, base58
+	Amount   int64  // TransferContract sun
+}
+
+// TxInfo is the outcome of a transaction.
+type TxInfo struct {
+	ID     string `json:"id"`
+	Result string `json:"-"` // SUCCESS when the contract call succeeded
+	Logs   []Log  `json:"log"`
+}
+
+// Log is an event a contract emitted. Address is the contract's hex
+// address without the 41 prefix, and topics and data are hex.
+type Log struct {
+	Address string   `json:"address"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
+}
+
+// APIError is an error the node answers with, such as an exception it
+// hit serving the request.
+type APIError struct {
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return "tron api error: " + e.Message
+}
+
+// RPCClient calls a Tron node's HTTP API, or TronGrid's. With several
+// URLs, a request that fails to connect or gets a 5xx answer is retried
+// on the next. TronGrid's API key goes in rpc_headers as TRON-PRO-API-KEY.
+type RPCClient struct {
+	urls    []string
+	header  http.Header
+	http    *http.Client
+	limiter *rpclimit.Limiter
+}
+
+// DefaultTimeout bounds each request to the node.
+const DefaultTimeout = 30 * time.Second
+
+// NewRPCClient builds a client for a source's rpc_url, sending header with
+// every request and making at most rps requests per second (0 for no cap).
+// Throttled and transient failures are retried with backoff.
+func NewRPCClient(urls []string, header http.Header, rps float64) (*RPCClient, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no rpc endpoints")
+	}
+	return &RPCClient{urls: urls, header: header, http: &http.Client{Timeout: DefaultTimeout}, limiter: source.NewLimiter(rps)}, nil
+}
+
+type blockView struct {
+	BlockID     string `json:"blockID"`
+	BlockHeader struct {
+		RawData struct {
+			Number     uint64 `json:"number"`
+			ParentHash string `json:"parentHash"`
+			Timestamp  int64  `json:"timestamp"` // milliseconds
+		} `json:"raw_data"`
+	} `json:"block_header"`
+	Transactions []struct {
+		TxID string `json:"txID"`
+		Ret  []struct {
+			ContractRet string `json:"contractRet"`
+		} `json:"ret"`
+		RawData struct {
+			Contract []struct {
+				Type      string `json:"type"`
+				Parameter struct {
+					Value struct {
+						OwnerAddress    string `json:"owner_address"`
+						ToAddress       string `json:"to_address"`
+						ContractAddress string `json:"contract_address"`
+						Amount          int64  `json:"amount"`
+					} `json:"value"`
+				} `json:"parameter"`
+			} `json:"contract"`
+		} `json:"raw_data"`
+	} `json:"transactions"`
+}
+
+// LatestHeight implements Client.
+func (c *RPCClient) LatestHeight(ctx context.Context) (uint64, error) {
+	var b blockView
+	if err := c.post(ctx, "/wallet/getnowblock", nil, &b); err != nil {
+		return 0, err
+	}
+	return b.BlockHeader.RawData.Number, nil
+}
+
+// FinalizedHeight implements Client.
+func (c *RPCClient) FinalizedHeight(ctx context.Context) (uint64, error) {
+	var b blockView
+	if err := c.post(ctx, "/walletsolidity/getnowblock", nil, &b); err != nil {
+		return 0, err
+	}
+	return b.BlockHeader.RawData.Number, nil
+}
+
+// Block implements Client.
+func (c *RPCClient) Block(ctx context.Context, height uint64) (*Block, error) {
+	var b blockView
+	if err := c.post(ctx, "/wallet/getblockbynum", map[string]any{"num": height}, &b); err != nil {
+		return nil, err
+	}
+	if b.BlockID == "" {
+		return nil, fmt.Errorf("%w at height %d", ErrBlockNotFound, height)
+	}
+	h := b.BlockHeader.RawData
+	out := &Block{Hash: b.BlockID, ParentHash: h.ParentHash, Height: h.Number, Time: time.UnixMilli(h.Timestamp).UTC()}
+	for _, t := range b.Transactions {
+		tx := Tx{ID: t.TxID}
+		if len(t.Ret) > 0 {
+			tx.Result = t.Ret[0].ContractRet
+		}
+		if len(t.RawData.Contract) > 0 {
+			ct := t.RawData.Contract[0]
+			v := ct.Parameter.Value
+			tx.Type, tx.Amount = ct.Type, v.Amount
+			for _, a := range []struct {
+				hex string
+				dst *string
+			}{{v.OwnerAddress, &tx.Owner}, {v.ToAddress, &tx.To}, {v.ContractAddress, &tx.Contract}} {
+				if a.hex == "" {
+					continue
+				}
+				addr, err := Address(a.hex)
+				if err != nil {
+					return nil, fmt.Errorf("tx %s: %w", t.TxID, err)
+				}
+				*a.dst = addr
+			}
+		}
+		out.Txs = append(out.Txs, tx)
+	}
+	return out, nil
+}
+
+// TransactionInfo implements Client.
+func (c *RPCClient) TransactionInfo(ctx context.Context, height uint64) ([]TxInfo, error) {
+	var raw json.RawMessage
+	if err := c.post(ctx, "/wallet/gettransactioninfobyblocknum", map[string]any{"num": height}, &raw); err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		return nil, nil // a block without transactions
+	}
+	var infos []struct {
+		TxInfo
+		Receipt struct {
+			Result string `json:"result"`
+		} `json:"receipt"`
+	}
+	if err := json.Unmarshal(raw, &infos); err != nil {
+		return nil, fmt.Errorf("decode transaction info: %w", err)
+	}
+	out := make([]TxInfo, len(infos))
+	for i, info := range infos {
+		out[i] = info.TxInfo
+		out[i].Result = info.Receipt.Result
+	}
+	return out, nil
+}
+
+func (c *RPCClient) post(ctx context.Context, path string, params map[string]any, out any) error {
+	if params == nil {
+		params = map[string]any{}
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", path, err)
+	}
+	return c.limiter.Do(ctx, func() error {
+		var lastErr error
+		for _, url := range c.urls {
+			err := c.do(ctx, strings.TrimSuffix(url, "/")+path, body, out)
+			if err == nil || !source.FailsOver(err) {
+				return err
+			}
+			lastErr = err
+		}
+		return fmt.Errorf("%s: %w", path, lastErr)
+	})
+}
+
+func (c *RPCClient) do(ctx context.Context, url string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header = c.header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &source.HTTPError{Status: resp.StatusCode, Body: string(bytes.TrimSpace(msg))}
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	// Errors come back with a 200 and an Error field.
+	var apiErr struct {
+		Error string `json:"Error"`
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) && json.Unmarshal(raw, &apiErr) == nil && apiErr.Error != "" {
+		return &APIError{Message: apiErr.Error}
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package tron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testBlockJSON = `{
+  "blockID": "0000000003d0900066f1c2f3",
+  "block_header": {"raw_data": {"number": 64000000, "parentHash": "0000000003d08fff11aa", "timestamp": 1714564800000}},
+  "transactions": [
+    {"ret": [{"contractRet": "SUCCESS"}], "txID": "aa01",
+     "raw_data": {"contract": [{"type": "TransferContract", "parameter": {"type_url": "type.googleapis.com/protocol.TransferContract",
+       "value": {"amount": 7000000, "owner_address": "410000000000000000000000000000000000000001", "to_address": "410000000000000000000000000000000000000002"}}}]}},
+    {"ret": [{"contractRet": "SUCCESS"}], "txID": "aa02",
+     "raw_data": {"contract": [{"type": "TriggerSmartContract", "parameter": {"type_url": "type.googleapis.com/protocol.TriggerSmartContract",
+       "value": {"data": "a9059cbb", "owner_address": "410000000000000000000000000000000000000001", "contract_address": "41a614f803b6fd780986a42c78ec9c7f77e6ded13c"}}}]}}
+  ]
+}`
+
+const testInfoJSON = `[
+  {"id": "aa01", "fee": 1100000, "blockNumber": 64000000, "receipt": {"net_fee": 100000}},
+  {"id": "aa02", "blockNumber": 64000000, "contract_address": "41a614f803b6fd780986a42c78ec9c7f77e6ded13c", "receipt": {"energy_usage_total": 13045, "result": "SUCCESS"},
+   "log": [{"address": "a614f803b6fd780986a42c78ec9c7f77e6ded13c",
+     "topics": ["ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef", "0000000000000000000000000000000000000000000000000000000000000001", "0000000000000000000000000000000000000000000000000000000000000002"],
+     "data": "0000000000000000000000000000000000000000000000000000000000262d8a"}]}
+]`
+
+func TestRPCClient(t *testing.T) {
+	var gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("TRON-PRO-API-KEY")
+		var req struct {
+			Num uint64 `json:"num"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case r.URL.Path == "/wallet/getnowblock":
+			_, _ = w.Write([]byte(`{"blockID":"x","block_header":{"raw_data":{"number":64000020}}}`))
+		case r.URL.Path == "/walletsolidity/getnowblock":
+			_, _ = w.Write([]byte(`{"blockID":"y","block_header":{"raw_data":{"number":64000001}}}`))
+		case r.URL.Path == "/wallet/getblockbynum" && req.Num == 64000000:
+			_, _ = w.Write([]byte(testBlockJSON))
+		case r.URL.Path == "/wallet/getblockbynum":
+			_, _ = w.Write([]byte(`{}`))
+		case r.URL.Path == "/wallet/gettransactioninfobyblocknum" && req.Num == 64000000:
+			_, _ = w.Write([]byte(testInfoJSON))
+		case r.URL.Path == "/wallet/gettransactioninfobyblocknum" && req.Num == 1:
+			_, _ = w.Write([]byte(`{"Error":"class org.tron.core.exception.BadItemException : block not found"}`))
+		case r.URL.Path == "/wallet/gettransactioninfobyblocknum":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewRPCClient([]string{srv.URL}, http.Header{"Tron-Pro-Api-Key": {"k1"}}, 0)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	ctx := context.Background()
+	if h, err := c.LatestHeight(ctx); err != nil || h != 64000020 {
+		t.Fatalf("latest height: %d, %v", h, err)
+	}
+	if gotKey != "k1" {
+		t.Fatalf("expected the API key header sent, got %q", gotKey)
+	}
+	if h, err := c.FinalizedHeight(ctx); err != nil || h != 64000001 {
+		t.Fatalf("finalized height: %d, %v", h, err)
+	}
+	if _, err := c.Block(ctx, 64000099); !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf("expected a missing block, got %v", err)
+	}
+	b, err := c.Block(ctx, 64000000)
+	if err != nil {
+		t.Fatalf("block: %v", err)
+	}
+	if b.Hash != "0000000003d0900066f1c2f3" || b.ParentHash != "0000000003d08fff11aa" || b.Height != 64000000 || !b.Time.Equal(time.UnixMilli(1714564800000)) || len(b.Txs) != 2 {
+		t.Fatalf("unexpected block %+v", b)
+	}
+	if tx := b.Txs[0]; tx.ID != "aa01" || tx.Result != success || tx.Type != TransferContract || tx.Owner != alice || tx.To != bob || tx.Amount != 7000000 {
+		t.Fatalf("unexpected transfer %+v", tx)
+	}
+	if tx := b.Txs[1]; tx.Type != TriggerSmartContract || tx.Contract != usdt || tx.To != "" {
+		t.Fatalf("unexpected call %+v", tx)
+	}
+	infos, err := c.TransactionInfo(ctx, 64000000)
+	if err != nil {
+		t.Fatalf("transaction info: %v", err)
+	}
+	if len(infos) != 2 || infos[0].Result != "" || infos[1].Result != success || len(infos[1].Logs) != 1 {
+		t.Fatalf("unexpected infos %+v", infos)
+	}
+	if ts := Transfers(infos); len(ts) != 1 || ts[0].Amount.Int64() != 2502026 || ts[0].From != alice || ts[0].Contract != usdt {
+		t.Fatalf("unexpected transfers %+v", ts)
+	}
+	if infos, err := c.TransactionInfo(ctx, 64000002); err != nil || len(infos) != 0 {
+		t.Fatalf("expected no infos for an empty block, got %v, %v", infos, err)
+	}
+	var apiErr *APIError
+	if _, err := c.TransactionInfo(ctx, 1); !errors.As(err, &apiErr) {
+		t.Fatalf("expected an api error, got %v", err)
+	}
+}
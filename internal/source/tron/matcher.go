@@ -0,0 +1,210 @@
+package tron
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+)
+
+// ActivityEvent is the event name of watch_address matches on every chain.
+const ActivityEvent = "address_activity"
+
+// TransferTopic is topic0 of the TRC-20 (and ERC-20) Transfer(address,
+// address,uint256) event.
+const TransferTopic = "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+// Contract types of the transactions the matchers look at.
+const (
+	TransferContract     = "TransferContract"
+	TriggerSmartContract = "TriggerSmartContract"
+)
+
+// success is the result of a transaction that went through.
+const success = "SUCCESS"
+
+// address matches a base58 Tron address.
+var address = regexp.MustCompile(`^T[1-9A-HJ-NP-Za-km-z]{33}$`)
+
+// RuleMatcher applies one rule to the transactions of a block.
+type RuleMatcher struct {
+	rule      config.Rule
+	kind      string
+	contract  string              // trc20_transfer: empty for every token
+	minAmount *big.Int            // trc20_transfer
+	minValue  uint64              // trx_transfer
+	addrs     map[string]struct{} // trc20_transfer, trx_transfer (optional), watch_address
+}
+
+// NewRuleMatcher builds a matcher for Tron rules.
+func NewRuleMatcher(rule config.Rule) (*RuleMatcher, error) {
+	mt := strings.ToLower(rule.Match.Type)
+	m := &RuleMatcher{rule: rule, kind: mt, addrs: map[string]struct{}{}}
+	switch mt {
+	case config.MatchTRC20Transfer:
+		m.contract = rule.Match.Contract
+		m.minAmount = new(big.Int).SetUint64(rule.Match.MinAmount)
+		for _, a := range rule.Match.Addresses {
+			m.addrs[a] = struct{}{}
+		}
+	case config.MatchTRXTransfer:
+		min, err := config.ParseSun(rule.Match.MinValue)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+		m.minValue = min
+		for _, a := range rule.Match.Addresses {
+			m.addrs[a] = struct{}{}
+		}
+	case config.MatchWatchAddress:
+		// The list is shared with other chains, so non-Tron entries are skipped.
+		for _, a := range rule.Match.Addresses {
+			if address.MatchString(a) {
+				m.addrs[a] = struct{}{}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("rule %s: unsupported match.type %s for tron", rule.ID, rule.Match.Type)
+	}
+	return m, nil
+}
+
+// Match returns the matches in a block. infos are the outcomes of its
+// transactions, in block order.
+func (m *RuleMatcher) Match(block *Block, infos []TxInfo) []source.Event {
+	var out []source.Event
+	switch m.kind {
+	case config.MatchTRC20Transfer:
+		for _, t := range Transfers(infos) {
+			if m.contract != "" && t.Contract != m.contract {
+				continue
+			}
+			if t.Amount.Cmp(m.minAmount) < 0 {
+				continue
+			}
+			if len(m.addrs) > 0 && !m.watches(t.From) && !m.watches(t.To) {
+				continue
+			}
+			idx := t.LogIndex
+			out = append(out, source.Event{RuleID: m.rule.ID, Name: config.MatchTRC20Transfer, TxHash: t.TxID, LogIndex: &idx, Contract: t.Contract, Args: map[string]any{
+				"from":     t.From,
+				"to":       t.To,
+				"amount":   t.Amount,
+				"contract": t.Contract,
+			}})
+		}
+	case config.MatchTRXTransfer:
+		for i, tx := range block.Txs {
+			if tx.Type != TransferContract || tx.Result != success || tx.Amount < 0 || uint64(tx.Amount) < m.minValue {
+				continue
+			}
+			if len(m.addrs) > 0 && !m.watches(tx.Owner) && !m.watches(tx.To) {
+				continue
+			}
+			idx := uint(i)
+			out = append(out, source.Event{RuleID: m.rule.ID, Name: config.MatchTRXTransfer, TxHash: tx.ID, LogIndex: &idx, Args: map[string]any{
+				"from":   tx.Owner,
+				"to":     tx.To,
+				"amount": uint64(tx.Amount),
+			}})
+		}
+	case config.MatchWatchAddress:
+		out = m.matchActivity(block, infos)
+	}
+	return out
+}
+
+// matchActivity matches transactions a watched address sends, receives TRX
+// in, calls as a contract, or sends or receives TRC-20 tokens in, once per
+// transaction. role is the first of "sender", "receiver", "contract",
+// "token_sender" and "token_receiver" that fits.
+func (m *RuleMatcher) matchActivity(block *Block, infos []TxInfo) []source.Event {
+	tokens := map[string][]Transfer{}
+	for _, t := range Transfers(infos) {
+		tokens[t.TxID] = append(tokens[t.TxID], t)
+	}
+	var out []source.Event
+	for i, tx := range block.Txs {
+		cands := []struct{ role, addr string }{
+			{"sender", tx.Owner},
+			{"receiver", tx.To},
+			{"contract", tx.Contract},
+		}
+		for _, t := range tokens[tx.ID] {
+			cands = append(cands, struct{ role, addr string }{"token_sender", t.From}, struct{ role, addr string }{"token_receiver", t.To})
+		}
+		for _, c := range cands {
+			if c.addr == "" || !m.watches(c.addr) {
+				continue
+			}
+			idx := uint(i)
+			out = append(out, source.Event{RuleID: m.rule.ID, Name: ActivityEvent, TxHash: tx.ID, LogIndex: &idx, Args: map[string]any{
+				"kind":    tx.Type,
+				"watched": c.addr,
+				"role":    c.role,
+				"result":  tx.Result,
+				"amount":  uint64(max(tx.Amount, 0)),
+			}})
+			break
+		}
+	}
+	return out
+}
+
+func (m *RuleMatcher) watches(a string) bool {
+	_, ok := m.addrs[a]
+	return ok
+}
+
+// Transfer is a TRC-20 Transfer event.
+type Transfer struct {
+	TxID     string
+	LogIndex uint // position of the log among the block's
+	Contract string
+	From     string
+	To       string
+	Amount   *big.Int
+}
+
+// Transfers decodes the TRC-20 Transfer events in the outcomes of a
+// block's transactions. Logs of other events, and Transfer events with the
+// token id indexed as TRC-721 emits them, are skipped; so are transactions
+// that failed, whose logs the chain discarded.
+func Transfers(infos []TxInfo) []Transfer {
+	var out []Transfer
+	idx := uint(0)
+	for _, info := range infos {
+		for _, l := range info.Logs {
+			i := idx
+			idx++
+			if info.Result != "" && info.Result != success {
+				continue
+			}
+			if len(l.Topics) != 3 || !strings.EqualFold(strings.TrimPrefix(l.Topics[0], "0x"), TransferTopic) {
+				continue
+			}
+			contract, err := Address(l.Address)
+			if err != nil {
+				continue
+			}
+			from, err := topicAddress(strings.TrimPrefix(l.Topics[1], "0x"))
+			if err != nil {
+				continue
+			}
+			to, err := topicAddress(strings.TrimPrefix(l.Topics[2], "0x"))
+			if err != nil {
+				continue
+			}
+			data, err := hex.DecodeString(strings.TrimPrefix(l.Data, "0x"))
+			if err != nil || len(data) != 32 {
+				continue
+			}
+			out = append(out, Transfer{TxID: info.ID, LogIndex: i, Contract: contract, From: from, To: to, Amount: new(big.Int).SetBytes(data)})
+		}
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package tron
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+)
+
+const (
+	usdt  = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t" // 41a614f803b6fd780986a42c78ec9c7f77e6ded13c
+	alice = "T9yD14Nj9j7xAB4dbGeiX9h8unkKLxmGkn" // 41000...01
+	bob   = "T9yD14Nj9j7xAB4dbGeiX9h8unkKT76qbH" // 41000...02
+)
+
+// transferLog is a TRC-20 Transfer of amount from alice to bob emitted by
+// the USDT contract.
+func transferLog(amount int64) Log {
+	return Log{
+		Address: "a614f803b6fd780986a42c78ec9c7f77e6ded13c",
+		Topics:  []string{TransferTopic, fmt.Sprintf("%064x", 1), fmt.Sprintf("%064x", 2)},
+		Data:    fmt.Sprintf("%064x", amount),
+	}
+}
+
+func testBlock() (*Block, []TxInfo) {
+	block := &Block{Txs: []Tx{
+		{ID: "t0", Result: success, Type: TransferContract, Owner: alice, To: bob, Amount: 7_000_000},
+		{ID: "t1", Result: success, Type: TriggerSmartContract, Owner: alice, Contract: usdt},
+		{ID: "t2", Result: "REVERT", Type: TriggerSmartContract, Owner: bob, Contract: usdt},
+	}}
+	infos := []TxInfo{
+		{ID: "t0"},
+		{ID: "t1", Result: success, Logs: []Log{
+			{Address: "a614f803b6fd780986a42c78ec9c7f77e6ded13c", Topics: []string{"8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"}},
+			transferLog(2_500_000),
+		}},
+		{ID: "t2", Result: "REVERT"},
+	}
+	return block, infos
+}
+
+func TestAddress(t *testing.T) {
+	got, err := Address("41a614f803b6fd780986a42c78ec9c7f77e6ded13c")
+	if err != nil || got != usdt {
+		t.Fatalf("got %s, %v; want %s", got, err, usdt)
+	}
+	if got, _ := Address("a614f803b6fd780986a42c78ec9c7f77e6ded13c"); got != usdt {
+		t.Fatalf("address without prefix: got %s", got)
+	}
+	h, err := DecodeAddress(usdt)
+	if err != nil || h != "41a614f803b6fd780986a42c78ec9c7f77e6ded13c" {
+		t.Fatalf("decode: got %s, %v", h, err)
+	}
+	if _, err := DecodeAddress(usdt[:33] + "u"); err == nil {
+		t.Fatal("expected a checksum error")
+	}
+}
+
+func TestMatcher_TRC20Transfer(t *testing.T) {
+	block, infos := testBlock()
+	m, err := NewRuleMatcher(config.Rule{ID: "r1", Match: config.MatchSpec{Type: config.MatchTRC20Transfer, Contract: usdt, MinAmount: 1_000_000, Addresses: []string{bob}}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	got := m.Match(block, infos)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(got))
+	}
+	ev := got[0]
+	if ev.RuleID != "r1" || ev.Name != config.MatchTRC20Transfer || ev.TxHash != "t1" || *ev.LogIndex != 1 || ev.Contract != usdt {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if ev.Args["from"] != alice || ev.Args["to"] != bob || ev.Args["amount"].(*big.Int).Int64() != 2_500_000 {
+		t.Fatalf("unexpected args %v", ev.Args)
+	}
+
+	m, _ = NewRuleMatcher(config.Rule{ID: "r2", Match: config.MatchSpec{Type: config.MatchTRC20Transfer, Contract: usdt, MinAmount: 3_000_000}})
+	if got := m.Match(block, infos); len(got) != 0 {
+		t.Fatalf("expected a transfer below min_amount to be skipped, got %+v", got)
+	}
+}
+
+func TestMatcher_TRXTransfer(t *testing.T) {
+	block, infos := testBlock()
+	m, err := NewRuleMatcher(config.Rule{ID: "r1", Match: config.MatchSpec{Type: config.MatchTRXTransfer, MinValue: "5 trx"}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	got := m.Match(block, infos)
+	if len(got) != 1 || got[0].TxHash != "t0" || got[0].Args["amount"] != uint64(7_000_000) || got[0].Args["to"] != bob {
+		t.Fatalf("unexpected matches %+v", got)
+	}
+
+	m, _ = NewRuleMatcher(config.Rule{ID: "r2", Match: config.MatchSpec{Type: config.MatchTRXTransfer, MinValue: "8000000"}})
+	if got := m.Match(block, infos); len(got) != 0 {
+		t.Fatalf("expected no match above the amount, got %+v", got)
+	}
+}
+
+func TestMatcher_WatchAddress(t *testing.T) {
+	block, infos := testBlock()
+	m, err := NewRuleMatcher(config.Rule{ID: "r1", Match: config.MatchSpec{Type: config.MatchWatchAddress, Addresses: []string{"0x52908400098527886E0F7030069857D2E4169EE7", bob}}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	got := m.Match(block, infos)
+	if len(got) != 3 {
+		t.Fatalf("expected one match per transaction, got %d", len(got))
+	}
+	for i, role := range []string{"receiver", "token_receiver", "sender"} {
+		if got[i].Args["role"] != role || got[i].Args["watched"] != bob || *got[i].LogIndex != uint(i) {
+			t.Fatalf("match %d: unexpected %+v", i, got[i])
+		}
+	}
+	if got[2].Args["result"] != "REVERT" {
+		t.Fatalf("expected the failed transaction's result, got %v", got[2].Args["result"])
+	}
+}
@@ -0,0 +1,167 @@
+package tron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/source"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+var testTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeClient serves a chain of blocks by height.
+type fakeClient struct {
+	tip       uint64
+	finalized uint64
+	blocks    map[uint64]*Block
+	infos     map[uint64][]TxInfo
+	infoCalls int
+}
+
+func (f *fakeClient) LatestHeight(ctx context.Context) (uint64, error) {
+	return f.tip, nil
+}
+
+func (f *fakeClient) FinalizedHeight(ctx context.Context) (uint64, error) {
+	return f.finalized, nil
+}
+
+func (f *fakeClient) Block(ctx context.Context, height uint64) (*Block, error) {
+	b, ok := f.blocks[height]
+	if !ok {
+		return nil, fmt.Errorf("%w at height %d", ErrBlockNotFound, height)
+	}
+	return b, nil
+}
+
+func (f *fakeClient) TransactionInfo(ctx context.Context, height uint64) ([]TxInfo, error) {
+	f.infoCalls++
+	return f.infos[height], nil
+}
+
+// add puts a block at height on the chain, building on the block below it,
+// with one TRX transfer of amount sun when amount is not zero.
+func (f *fakeClient) add(height uint64, hash string, amount int64) {
+	if f.blocks == nil {
+		f.blocks, f.infos = map[uint64]*Block{}, map[uint64][]TxInfo{}
+	}
+	b := &Block{Hash: hash, Height: height, Time: testTime.Add(time.Duration(height) * 3 * time.Second)}
+	if prev, ok := f.blocks[height-1]; ok {
+		b.ParentHash = prev.Hash
+	}
+	if amount != 0 {
+		id := fmt.Sprintf("tx%s", hash)
+		b.Txs = []Tx{{ID: id, Result: success, Type: TransferContract, Owner: alice, To: bob, Amount: amount}}
+		f.infos[height] = []TxInfo{{ID: id}}
+	}
+	f.blocks[height] = b
+	f.tip = max(f.tip, height)
+}
+
+func newTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.Open(t.TempDir() + "/db.sqlite")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestScannerMatchesTransfers(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &fakeClient{}
+	client.add(99, "b99", 0)
+	client.add(100, "b100", 5_000_000)
+	client.add(101, "b101", 0)
+	client.add(102, "b102", 9_000_000)
+	client.add(103, "b103", 9_000_000)
+	client.finalized = 102
+	if err := store.UpsertCursor(ctx, "trx", 99, "b99"); err != nil {
+		t.Fatalf("seed cursor: %v", err)
+	}
+	rules := []config.Rule{{ID: "big", Source: "trx", Match: config.MatchSpec{Type: config.MatchTRXTransfer, MinValue: "5 trx"}}}
+	sc, err := NewScanner(client, store, config.Source{ID: "trx", Type: Chain, MaxBlocksPerTick: 10}, 0, rules)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	sc.SetFinality(config.ConfirmFinalized)
+	events, err := sc.ProcessNext(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected transfers in blocks 100 and 102, got %d", len(events))
+	}
+	ev := events[1]
+	if ev.Chain != Chain || ev.Height != 102 || ev.Hash != "b102" || ev.TxHash != "txb102" || !ev.Timestamp.Equal(testTime.Add(306*time.Second)) {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if h, hash, _, _ := store.GetCursor(ctx, "trx"); h != 102 || hash != "b102" {
+		t.Fatalf("expected cursor at the solidified block 102, got %d %s", h, hash)
+	}
+	if client.infoCalls != 2 {
+		t.Fatalf("expected transaction info fetched only for blocks with transactions, got %d calls", client.infoCalls)
+	}
+}
+
+func TestScannerRewindsToCommonAncestor(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	client := &fakeClient{}
+	for h := uint64(10); h <= 12; h++ {
+		client.add(h, fmt.Sprintf("b%d", h), 0)
+	}
+	sc, err := NewScanner(client, store, config.Source{ID: "trx", Type: Chain, StartBlock: "10", MaxBlocksPerTick: 10}, 0, nil)
+	if err != nil {
+		t.Fatalf("new scanner: %v", err)
+	}
+	sc.tipTTL = 0
+	if _, err := sc.ProcessNext(ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	// Blocks 11 and 12 are replaced by a longer fork.
+	for h := uint64(11); h <= 13; h++ {
+		client.add(h, fmt.Sprintf("b%d'", h), 0)
+	}
+	_, err = sc.ProcessNext(ctx)
+	var rg *source.ReorgError
+	if !errors.As(err, &rg) {
+		t.Fatalf("expected a reorg, got %v", err)
+	}
+	if rg.From != 11 || rg.To != 12 || rg.Exceeded {
+		t.Fatalf("unexpected reorg %+v", rg)
+	}
+	if h, hash, _, _ := store.GetCursor(ctx, "trx"); h != 10 || hash != "b10" {
+		t.Fatalf("expected cursor at the common ancestor 10, got %d %s", h, hash)
+	}
+}
+
+func TestResolveStartHeight(t *testing.T) {
+	cases := []struct {
+		start string
+		want  uint64
+	}{
+		{"", 500},
+		{"latest", 500},
+		{"latest-20", 480},
+		{"latest-900", 0},
+		{"123", 123},
+	}
+	for _, c := range cases {
+		got, err := resolveStartHeight(c.start, 500)
+		if err != nil || got != c.want {
+			t.Fatalf("%q: got %d, %v; want %d", c.start, got, err, c.want)
+		}
+	}
+	if _, err := resolveStartHeight("soon", 500); err == nil {
+		t.Fatal("expected an error for a bad start_block")
+	}
+}
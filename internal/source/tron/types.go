@@ -0,0 +1,7 @@
+package tron
+
+// Chain identifier for Tron. Its events' TxHash is the transaction id,
+// LogIndex the position of the log among the block's, or of the transaction
+// for TRX transfers and address activity, and Contract the token contract
+// of TRC-20 transfers.
+const Chain = "tron"
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -12,12 +13,68 @@ import (
 
 // Store wraps SQLite-backed persistence for cursors, alerts, sends, and dedupe.
 type Store struct {
-	db *sql.DB
+	db    *sql.DB
+	stmts *statements
+}
+
+// statements are the hot-path queries, prepared once on Open. They run for
+// every block (cursors) and every matched event (dedupe, silences).
+type statements struct {
+	getCursor    *sql.Stmt
+	upsertCursor *sql.Stmt
+	getDedupe    *sql.Stmt
+	deleteDedupe *sql.Stmt
+	markDedupe   *sql.Stmt
+	isSilenced   *sql.Stmt
+}
+
+func prepare(db *sql.DB) (*statements, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	st := &statements{}
+	for _, q := range []struct {
+		dst  **sql.Stmt
+		name string
+		sql  string
+	}{
+		{&st.getCursor, "get cursor", `SELECT height, hash FROM cursors WHERE source_id = ?;`},
+		{&st.upsertCursor, "upsert cursor", `
+INSERT INTO cursors (source_id, height, hash, updated_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(source_id) DO UPDATE SET
+  height=excluded.height,
+  hash=excluded.hash,
+  updated_at=CURRENT_TIMESTAMP;`},
+		{&st.getDedupe, "get dedupe", `SELECT expires_at FROM dedupe WHERE key = ?;`},
+		{&st.deleteDedupe, "delete dedupe", `DELETE FROM dedupe WHERE key = ?;`},
+		{&st.markDedupe, "mark dedupe", `
+INSERT INTO dedupe (key, expires_at)
+VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET expires_at=excluded.expires_at;`},
+		{&st.isSilenced, "is silenced", `SELECT COUNT(1) FROM silences WHERE (rule_id = ? OR rule_id = '*') AND expires_at > ?;`},
+	} {
+		stmt, err := db.PrepareContext(ctx, q.sql)
+		if err != nil {
+			st.close()
+			return nil, fmt.Errorf("prepare %s: %w", q.name, err)
+		}
+		*q.dst = stmt
+	}
+	return st, nil
+}
+
+func (st *statements) close() {
+	for _, stmt := range []*sql.Stmt{st.getCursor, st.upsertCursor, st.getDedupe, st.deleteDedupe, st.markDedupe, st.isSilenced} {
+		if stmt != nil {
+			_ = stmt.Close()
+		}
+	}
 }
 
 // Open initializes a SQLite database and runs minimal schema setup.
 func Open(path string) (*Store, error) {
-	db, err := sql.Open("sqlite", path)
+	db, err := sql.Open("sqlite", dsn(path))
 	if err != nil {
 		return nil, fmt.Errorf("open db: %w", err)
 	}
@@ -29,7 +86,12 @@ func Open(path string) (*Store, error) {
 		db.Close()
 		return nil, err
 	}
-	return &Store{db: db}, nil
+	stmts, err := prepare(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db, stmts: stmts}, nil
 }
 
 // Close releases the underlying database handle.
@@ -37,6 +99,9 @@ func (s *Store) Close() error {
 	if s == nil || s.db == nil {
 		return nil
 	}
+	if s.stmts != nil {
+		s.stmts.close()
+	}
 	return s.db.Close()
 }
 
@@ -48,14 +113,24 @@ func (s *Store) Ping(ctx context.Context) error {
 	return s.db.PingContext(ctx)
 }
 
+// dsn adds the per-connection pragmas to path. They must be set on every
+// pooled connection: one opened without busy_timeout fails at once with
+// SQLITE_BUSY when the scanner and alert handling write concurrently.
+func dsn(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+}
+
+// configure sets database-wide pragmas, which persist in the file.
 func configure(db *sql.DB) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
 	pragmas := []string{
-		"PRAGMA foreign_keys = ON;",
 		"PRAGMA journal_mode = WAL;",
-		"PRAGMA busy_timeout = 5000;",
 	}
 	for _, p := range pragmas {
 		if _, err := db.ExecContext(ctx, p); err != nil {
@@ -83,7 +158,10 @@ CREATE TABLE IF NOT EXISTS alerts (
   fingerprint   TEXT,
   txhash        TEXT,
   payload_json  TEXT,
-  created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+  created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  source_id     TEXT,
+  height        INTEGER,
+  retracts      TEXT
 );
 
 CREATE TABLE IF NOT EXISTS sends (
@@ -95,14 +173,149 @@ CREATE TABLE IF NOT EXISTS sends (
   PRIMARY KEY(alert_id, sink_id)
 );
 
+CREATE TABLE IF NOT EXISTS deliveries (
+  alert_id        TEXT NOT NULL,
+  sink_id         TEXT NOT NULL,
+  attempts        INTEGER NOT NULL DEFAULT 0,
+  next_attempt_ms INTEGER NOT NULL,
+  last_error      TEXT,
+  PRIMARY KEY(alert_id, sink_id)
+);
+
+CREATE TABLE IF NOT EXISTS held (
+  alert_id    TEXT NOT NULL,
+  sink_id     TEXT NOT NULL,
+  held_at     TIMESTAMP NOT NULL,
+  PRIMARY KEY(alert_id, sink_id)
+);
+
 CREATE TABLE IF NOT EXISTS dedupe (
   key         TEXT PRIMARY KEY,
   expires_at  TIMESTAMP NOT NULL
 );
+CREATE INDEX IF NOT EXISTS dedupe_expires_at ON dedupe(expires_at);
+
+CREATE TABLE IF NOT EXISTS rules (
+  id          TEXT PRIMARY KEY,
+  spec_json   TEXT NOT NULL,
+  disabled    INTEGER NOT NULL DEFAULT 0,
+  updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS tokens (
+  source_id   TEXT NOT NULL,
+  address     TEXT NOT NULL,
+  symbol      TEXT NOT NULL,
+  decimals    INTEGER NOT NULL,
+  updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY(source_id, address)
+);
+
+CREATE TABLE IF NOT EXISTS block_hashes (
+  source_id   TEXT NOT NULL,
+  height      INTEGER NOT NULL,
+  hash        TEXT NOT NULL,
+  PRIMARY KEY(source_id, height)
+);
+
+CREATE TABLE IF NOT EXISTS ens_names (
+  source_id   TEXT NOT NULL,
+  address     TEXT NOT NULL,
+  name        TEXT NOT NULL,
+  updated_at  TIMESTAMP NOT NULL,
+  PRIMARY KEY(source_id, address)
+);
+
+CREATE TABLE IF NOT EXISTS state_values (
+  source_id   TEXT NOT NULL,
+  rule_id     TEXT NOT NULL,
+  value       TEXT NOT NULL,
+  height      INTEGER NOT NULL,
+  updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY(source_id, rule_id)
+);
+
+CREATE TABLE IF NOT EXISTS slack_threads (
+  channel     TEXT NOT NULL,
+  group_key   TEXT NOT NULL,
+  ts          TEXT NOT NULL,
+  created_at  TIMESTAMP NOT NULL,
+  PRIMARY KEY(channel, group_key)
+);
+
+CREATE TABLE IF NOT EXISTS audit (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+  rule_id     TEXT NOT NULL,
+  source_id   TEXT,
+  height      INTEGER,
+  txhash      TEXT,
+  alert_id    TEXT,
+  sink_id     TEXT,
+  decision    TEXT NOT NULL,
+  detail      TEXT,
+  created_at  TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_created_at ON audit(created_at);
+
+CREATE TABLE IF NOT EXISTS silences (
+  id          TEXT PRIMARY KEY,
+  rule_id     TEXT NOT NULL,
+  reason      TEXT,
+  expires_at  TIMESTAMP NOT NULL,
+  created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS ingested (
+  seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+  source_id   TEXT NOT NULL,
+  event_id    TEXT,
+  event_json  TEXT NOT NULL,
+  created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS ingested_source_seq ON ingested(source_id, seq);
 `
 	if _, err := db.ExecContext(ctx, schema); err != nil {
 		return fmt.Errorf("apply schema: %w", err)
 	}
+	// Columns added after a table first shipped; CREATE TABLE IF NOT EXISTS
+	// leaves older databases without them.
+	for _, c := range []struct{ table, column, decl string }{
+		{"alerts", "source_id", "TEXT"},
+		{"alerts", "height", "INTEGER"},
+		{"alerts", "retracts", "TEXT"},
+	} {
+		if err := addColumn(ctx, db, c.table, c.column, c.decl); err != nil {
+			return err
+		}
+	}
+	if _, err := db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS alerts_source_height ON alerts(source_id, height);`); err != nil {
+		return fmt.Errorf("apply schema: %w", err)
+	}
+	return nil
+}
+
+// addColumn adds a column to table unless it already exists.
+func addColumn(ctx context.Context, db *sql.DB, table, column, decl string) error {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_info(?);`, table)
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("inspect %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column, decl)); err != nil {
+		return fmt.Errorf("add %s.%s: %w", table, column, err)
+	}
 	return nil
 }
 
@@ -111,14 +324,7 @@ func (s *Store) UpsertCursor(ctx context.Context, sourceID string, height uint64
 	if sourceID == "" {
 		return errors.New("sourceID required")
 	}
-	_, err := s.db.ExecContext(ctx, `
-INSERT INTO cursors (source_id, height, hash, updated_at)
-VALUES (?, ?, ?, CURRENT_TIMESTAMP)
-ON CONFLICT(source_id) DO UPDATE SET
-  height=excluded.height,
-  hash=excluded.hash,
-  updated_at=CURRENT_TIMESTAMP;
-`, sourceID, height, hash)
+	_, err := s.stmts.upsertCursor.ExecContext(ctx, sourceID, height, hash)
 	if err != nil {
 		return fmt.Errorf("upsert cursor: %w", err)
 	}
@@ -127,9 +333,7 @@ ON CONFLICT(source_id) DO UPDATE SET
 
 // GetCursor retrieves the cursor for a source.
 func (s *Store) GetCursor(ctx context.Context, sourceID string) (height uint64, hash string, ok bool, err error) {
-	row := s.db.QueryRowContext(ctx, `
-SELECT height, hash FROM cursors WHERE source_id = ?;
-`, sourceID)
+	row := s.stmts.getCursor.QueryRowContext(ctx, sourceID)
 	switch err = row.Scan(&height, &hash); err {
 	case nil:
 		return height, hash, true, nil
@@ -140,16 +344,109 @@ SELECT height, hash FROM cursors WHERE source_id = ?;
 	}
 }
 
+// RecordBlockHashes keeps the hashes of processed blocks, so a reorg can be
+// traced back to the last block both chains share. Hashes more than keep
+// blocks below the highest one are dropped.
+func (s *Store) RecordBlockHashes(ctx context.Context, sourceID string, hashes map[uint64]string, keep uint64) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("record block hashes: %w", err)
+	}
+	defer tx.Rollback()
+	var top uint64
+	for height, hash := range hashes {
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO block_hashes (source_id, height, hash) VALUES (?, ?, ?)
+ON CONFLICT(source_id, height) DO UPDATE SET hash=excluded.hash;
+`, sourceID, height, hash)
+		if err != nil {
+			return fmt.Errorf("record block hashes: %w", err)
+		}
+		top = max(top, height)
+	}
+	if top > keep {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM block_hashes WHERE source_id = ? AND height <= ?;`, sourceID, top-keep); err != nil {
+			return fmt.Errorf("record block hashes: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("record block hashes: %w", err)
+	}
+	return nil
+}
+
+// BlockHash returns the recorded hash of a processed block.
+func (s *Store) BlockHash(ctx context.Context, sourceID string, height uint64) (string, bool, error) {
+	var hash string
+	row := s.db.QueryRowContext(ctx, `SELECT hash FROM block_hashes WHERE source_id = ? AND height = ?;`, sourceID, height)
+	switch err := row.Scan(&hash); err {
+	case nil:
+		return hash, true, nil
+	case sql.ErrNoRows:
+		return "", false, nil
+	default:
+		return "", false, fmt.Errorf("get block hash: %w", err)
+	}
+}
+
+// RewindCursor moves a source's cursor back to height after a reorg and
+// forgets the hashes of the orphaned blocks above it.
+func (s *Store) RewindCursor(ctx context.Context, sourceID string, height uint64, hash string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("rewind cursor: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.StmtContext(ctx, s.stmts.upsertCursor).ExecContext(ctx, sourceID, height, hash); err != nil {
+		return fmt.Errorf("rewind cursor: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM block_hashes WHERE source_id = ? AND height > ?;`, sourceID, height); err != nil {
+		return fmt.Errorf("rewind cursor: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("rewind cursor: %w", err)
+	}
+	return nil
+}
+
+// Cursor is a persisted source position.
+type Cursor struct {
+	SourceID  string
+	Height    uint64
+	Hash      string
+	UpdatedAt time.Time
+}
+
+// ListCursors returns all cursors ordered by source id.
+func (s *Store) ListCursors(ctx context.Context) ([]Cursor, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT source_id, height, hash, updated_at FROM cursors ORDER BY source_id;
+`)
+	if err != nil {
+		return nil, fmt.Errorf("list cursors: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Cursor
+	for rows.Next() {
+		var c Cursor
+		if err := rows.Scan(&c.SourceID, &c.Height, &c.Hash, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan cursor: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
 // MarkDedupe sets or refreshes a dedupe key until expiresAt.
 func (s *Store) MarkDedupe(ctx context.Context, key string, expiresAt time.Time) error {
 	if key == "" {
 		return errors.New("key required")
 	}
-	_, err := s.db.ExecContext(ctx, `
-INSERT INTO dedupe (key, expires_at)
-VALUES (?, ?)
-ON CONFLICT(key) DO UPDATE SET expires_at=excluded.expires_at;
-`, key, expiresAt.UTC())
+	_, err := s.stmts.markDedupe.ExecContext(ctx, key, expiresAt.UTC())
 	if err != nil {
 		return fmt.Errorf("mark dedupe: %w", err)
 	}
@@ -163,9 +460,7 @@ func (s *Store) IsDuplicate(ctx context.Context, key string, now time.Time) (boo
 	}
 
 	var expires time.Time
-	err := s.db.QueryRowContext(ctx, `
-SELECT expires_at FROM dedupe WHERE key = ?;
-`, key).Scan(&expires)
+	err := s.stmts.getDedupe.QueryRowContext(ctx, key).Scan(&expires)
 	if err == sql.ErrNoRows {
 		return false, nil
 	}
@@ -177,12 +472,22 @@ SELECT expires_at FROM dedupe WHERE key = ?;
 		return true, nil
 	}
 
-	if _, err := s.db.ExecContext(ctx, `DELETE FROM dedupe WHERE key = ?;`, key); err != nil {
+	if _, err := s.stmts.deleteDedupe.ExecContext(ctx, key); err != nil {
 		return false, fmt.Errorf("prune dedupe: %w", err)
 	}
 	return false, nil
 }
 
+// PruneDedupe deletes dedupe keys that expired before now and returns how
+// many were removed. Keys that are never checked again are otherwise kept forever.
+func (s *Store) PruneDedupe(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM dedupe WHERE expires_at <= ?;`, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("prune dedupe: %w", err)
+	}
+	return res.RowsAffected()
+}
+
 // Alert represents an emitted alert record.
 type Alert struct {
 	ID          string
@@ -191,6 +496,11 @@ type Alert struct {
 	TxHash      string
 	PayloadJSON string
 	CreatedAt   time.Time
+	// SourceID and Height locate the event, so alerts from reorged blocks can be found.
+	SourceID string
+	Height   uint64
+	// Retracts is the id of the alert this one withdraws, if any.
+	Retracts string
 }
 
 // InsertAlert stores an alert; primary key enforces exactly-once insertion.
@@ -199,15 +509,111 @@ func (s *Store) InsertAlert(ctx context.Context, a Alert) error {
 		return errors.New("alert id and rule_id required")
 	}
 	_, err := s.db.ExecContext(ctx, `
-INSERT INTO alerts (id, rule_id, fingerprint, txhash, payload_json, created_at)
-VALUES (?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP));
-`, a.ID, a.RuleID, a.Fingerprint, a.TxHash, a.PayloadJSON, nullTime(a.CreatedAt))
+INSERT INTO alerts (id, rule_id, fingerprint, txhash, payload_json, created_at, source_id, height, retracts)
+VALUES (?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP), NULLIF(?, ''), ?, NULLIF(?, ''));
+`, a.ID, a.RuleID, a.Fingerprint, a.TxHash, a.PayloadJSON, nullTime(a.CreatedAt), a.SourceID, a.Height, a.Retracts)
 	if err != nil {
 		return fmt.Errorf("insert alert: %w", err)
 	}
 	return nil
 }
 
+// AlertFilter narrows ListAlerts results. Zero values mean no filter.
+type AlertFilter struct {
+	RuleID string
+	Since  time.Time
+	Limit  int
+}
+
+// ListAlerts returns alerts newest first.
+func (s *Store) ListAlerts(ctx context.Context, f AlertFilter) ([]Alert, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, rule_id, COALESCE(fingerprint, ''), COALESCE(txhash, ''), COALESCE(payload_json, ''), created_at
+FROM alerts
+WHERE (? = '' OR rule_id = ?) AND (? IS NULL OR created_at >= ?)
+ORDER BY created_at DESC, id
+LIMIT ?;
+`, f.RuleID, f.RuleID, nullTime(f.Since), nullTime(f.Since), limit)
+	if err != nil {
+		return nil, fmt.Errorf("list alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Alert
+	for rows.Next() {
+		var a Alert
+		if err := rows.Scan(&a.ID, &a.RuleID, &a.Fingerprint, &a.TxHash, &a.PayloadJSON, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan alert: %w", err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// ReorgedAlerts returns the alerts raised for events in sourceID's blocks
+// from..to that have not been retracted yet. Retractions themselves are skipped.
+func (s *Store) ReorgedAlerts(ctx context.Context, sourceID string, from, to uint64) ([]Alert, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, rule_id, COALESCE(fingerprint, ''), COALESCE(txhash, ''), COALESCE(payload_json, ''), created_at, source_id, height
+FROM alerts
+WHERE source_id = ? AND height BETWEEN ? AND ? AND retracts IS NULL
+  AND id NOT IN (SELECT retracts FROM alerts WHERE retracts IS NOT NULL)
+ORDER BY created_at, id;
+`, sourceID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("reorged alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Alert
+	for rows.Next() {
+		var a Alert
+		if err := rows.Scan(&a.ID, &a.RuleID, &a.Fingerprint, &a.TxHash, &a.PayloadJSON, &a.CreatedAt, &a.SourceID, &a.Height); err != nil {
+			return nil, fmt.Errorf("scan alert: %w", err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// SentSinks returns the sinks an alert was successfully delivered to.
+func (s *Store) SentSinks(ctx context.Context, alertID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT sink_id FROM sends WHERE alert_id = ? AND status = 'sent' ORDER BY sink_id;`, alertID)
+	if err != nil {
+		return nil, fmt.Errorf("sent sinks: %w", err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan send: %w", err)
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// CancelDeliveries drops an alert's queued sends, recording them as cancelled.
+func (s *Store) CancelDeliveries(ctx context.Context, alertID string, now time.Time) error {
+	return s.WithTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+INSERT OR REPLACE INTO sends (alert_id, sink_id, status, created_at)
+SELECT alert_id, sink_id, 'cancelled', ? FROM deliveries WHERE alert_id = ?;
+`, now.UTC(), alertID); err != nil {
+			return fmt.Errorf("cancel deliveries: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM deliveries WHERE alert_id = ?;`, alertID); err != nil {
+			return fmt.Errorf("cancel deliveries: %w", err)
+		}
+		return nil
+	})
+}
+
 // Send represents a sink delivery record.
 type Send struct {
 	AlertID      string
@@ -232,6 +638,640 @@ VALUES (?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP));
 	return nil
 }
 
+// Delivery is a queued sink send. PayloadJSON is read from the alert.
+type Delivery struct {
+	AlertID       string
+	SinkID        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	PayloadJSON   string
+}
+
+// EnqueueDelivery queues a send for a sink; re-enqueueing the same alert/sink is a no-op.
+// Due times are stored as unix milliseconds so the due query compares numbers.
+func (s *Store) EnqueueDelivery(ctx context.Context, d Delivery) error {
+	if d.AlertID == "" || d.SinkID == "" {
+		return errors.New("alert_id and sink_id are required")
+	}
+	_, err := s.db.ExecContext(ctx, `
+INSERT OR IGNORE INTO deliveries (alert_id, sink_id, attempts, next_attempt_ms)
+VALUES (?, ?, ?, ?);
+`, d.AlertID, d.SinkID, d.Attempts, d.NextAttemptAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("enqueue delivery: %w", err)
+	}
+	return nil
+}
+
+// DueDeliveries returns up to limit queued sends whose next attempt is at or before now.
+func (s *Store) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]Delivery, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT d.alert_id, d.sink_id, d.attempts, d.next_attempt_ms, COALESCE(d.last_error, ''), COALESCE(a.payload_json, '')
+FROM deliveries d
+LEFT JOIN alerts a ON a.id = d.alert_id
+WHERE d.next_attempt_ms <= ?
+ORDER BY d.next_attempt_ms, d.alert_id, d.sink_id
+LIMIT ?;
+`, now.UnixMilli(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("due deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Delivery
+	for rows.Next() {
+		var d Delivery
+		var next int64
+		if err := rows.Scan(&d.AlertID, &d.SinkID, &d.Attempts, &next, &d.LastError, &d.PayloadJSON); err != nil {
+			return nil, fmt.Errorf("scan delivery: %w", err)
+		}
+		d.NextAttemptAt = time.UnixMilli(next).UTC()
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// RetryDelivery records a failed attempt and schedules the next one.
+func (s *Store) RetryDelivery(ctx context.Context, d Delivery) error {
+	_, err := s.db.ExecContext(ctx, `
+UPDATE deliveries SET attempts = ?, next_attempt_ms = ?, last_error = ?
+WHERE alert_id = ? AND sink_id = ?;
+`, d.Attempts, d.NextAttemptAt.UnixMilli(), d.LastError, d.AlertID, d.SinkID)
+	if err != nil {
+		return fmt.Errorf("retry delivery: %w", err)
+	}
+	return nil
+}
+
+// CompleteDelivery records the final send outcome and removes the queued delivery atomically.
+func (s *Store) CompleteDelivery(ctx context.Context, srec Send) error {
+	if srec.AlertID == "" || srec.SinkID == "" || srec.Status == "" {
+		return errors.New("alert_id, sink_id, and status are required")
+	}
+	return s.WithTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+INSERT OR REPLACE INTO sends (alert_id, sink_id, status, response_code, created_at)
+VALUES (?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP));
+`, srec.AlertID, srec.SinkID, srec.Status, srec.ResponseCode, nullTime(srec.CreatedAt)); err != nil {
+			return fmt.Errorf("insert send: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM deliveries WHERE alert_id = ? AND sink_id = ?;`, srec.AlertID, srec.SinkID); err != nil {
+			return fmt.Errorf("delete delivery: %w", err)
+		}
+		return nil
+	})
+}
+
+// HoldAlert keeps an alert from a sink in quiet hours until ReleaseHeld.
+func (s *Store) HoldAlert(ctx context.Context, alertID, sinkID string, at time.Time) error {
+	if alertID == "" || sinkID == "" {
+		return errors.New("alert_id and sink_id are required")
+	}
+	_, err := s.db.ExecContext(ctx, `
+INSERT OR IGNORE INTO held (alert_id, sink_id, held_at) VALUES (?, ?, ?);
+`, alertID, sinkID, at.UTC())
+	if err != nil {
+		return fmt.Errorf("hold alert: %w", err)
+	}
+	return nil
+}
+
+// HeldAlerts returns the alerts held for a sink, oldest first.
+func (s *Store) HeldAlerts(ctx context.Context, sinkID string) ([]Alert, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT a.id, a.rule_id, COALESCE(a.fingerprint, ''), COALESCE(a.txhash, ''), COALESCE(a.payload_json, ''), a.created_at
+FROM held h
+JOIN alerts a ON a.id = h.alert_id
+WHERE h.sink_id = ?
+ORDER BY h.held_at, a.id;
+`, sinkID)
+	if err != nil {
+		return nil, fmt.Errorf("held alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Alert
+	for rows.Next() {
+		var a Alert
+		if err := rows.Scan(&a.ID, &a.RuleID, &a.Fingerprint, &a.TxHash, &a.PayloadJSON, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan alert: %w", err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// ReleaseHeld drops held alerts for a sink once they went out in a digest,
+// recording them as digested.
+func (s *Store) ReleaseHeld(ctx context.Context, sinkID string, alertIDs []string, now time.Time) error {
+	return s.WithTx(ctx, func(tx *sql.Tx) error {
+		for _, id := range alertIDs {
+			if _, err := tx.ExecContext(ctx, `
+INSERT OR REPLACE INTO sends (alert_id, sink_id, status, created_at) VALUES (?, ?, 'digested', ?);
+`, id, sinkID, now.UTC()); err != nil {
+				return fmt.Errorf("release held: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, `DELETE FROM held WHERE alert_id = ? AND sink_id = ?;`, id, sinkID); err != nil {
+				return fmt.Errorf("release held: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// Ingested is an event an ingest source accepted and the runner has not
+// handled yet.
+type Ingested struct {
+	Seq       int64
+	SourceID  string
+	EventID   string
+	EventJSON string
+}
+
+// EnqueueIngested queues events accepted by an ingest source, all or none,
+// and returns their sequence numbers.
+func (s *Store) EnqueueIngested(ctx context.Context, sourceID string, events []Ingested) ([]int64, error) {
+	if sourceID == "" {
+		return nil, errors.New("source_id is required")
+	}
+	seqs := make([]int64, 0, len(events))
+	err := s.WithTx(ctx, func(tx *sql.Tx) error {
+		for _, ev := range events {
+			res, err := tx.ExecContext(ctx, `
+INSERT INTO ingested (source_id, event_id, event_json) VALUES (?, ?, ?);
+`, sourceID, ev.EventID, ev.EventJSON)
+			if err != nil {
+				return fmt.Errorf("enqueue ingested: %w", err)
+			}
+			seq, err := res.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("enqueue ingested: %w", err)
+			}
+			seqs = append(seqs, seq)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return seqs, nil
+}
+
+// IngestedEvents returns the events queued for an ingest source, oldest first.
+func (s *Store) IngestedEvents(ctx context.Context, sourceID string) ([]Ingested, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT seq, source_id, COALESCE(event_id, ''), event_json
+FROM ingested
+WHERE source_id = ?
+ORDER BY seq;
+`, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("ingested events: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Ingested
+	for rows.Next() {
+		var ev Ingested
+		if err := rows.Scan(&ev.Seq, &ev.SourceID, &ev.EventID, &ev.EventJSON); err != nil {
+			return nil, fmt.Errorf("scan ingested: %w", err)
+		}
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
+
+// DeleteIngested removes a handled event from the ingest queue.
+func (s *Store) DeleteIngested(ctx context.Context, seq int64) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM ingested WHERE seq = ?;`, seq); err != nil {
+		return fmt.Errorf("delete ingested: %w", err)
+	}
+	return nil
+}
+
+// RuleHits counts alerts recorded for a rule.
+type RuleHits struct {
+	RuleID string
+	Count  int
+	LastAt time.Time
+}
+
+// ListRuleHits returns alert counts per rule since the given time, busiest first.
+func (s *Store) ListRuleHits(ctx context.Context, since time.Time) ([]RuleHits, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT rule_id, COUNT(*), MAX(created_at)
+FROM alerts
+WHERE (? IS NULL OR created_at >= ?)
+GROUP BY rule_id
+ORDER BY COUNT(*) DESC, rule_id;
+`, nullTime(since), nullTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("list rule hits: %w", err)
+	}
+	defer rows.Close()
+
+	var out []RuleHits
+	for rows.Next() {
+		var h RuleHits
+		var last any
+		if err := rows.Scan(&h.RuleID, &h.Count, &last); err != nil {
+			return nil, fmt.Errorf("scan rule hits: %w", err)
+		}
+		h.LastAt = parseTime(last)
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// SinkStats summarizes delivery attempts for a sink.
+type SinkStats struct {
+	SinkID     string
+	Sent       int
+	Failed     int
+	LastStatus string
+	LastAt     time.Time
+}
+
+// ListSinkStats returns delivery counts per sink since the given time.
+func (s *Store) ListSinkStats(ctx context.Context, since time.Time) ([]SinkStats, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT sink_id,
+       SUM(CASE WHEN status = 'sent' THEN 1 ELSE 0 END),
+       SUM(CASE WHEN status = 'sent' THEN 0 ELSE 1 END),
+       (SELECT s2.status FROM sends s2 WHERE s2.sink_id = sends.sink_id ORDER BY s2.created_at DESC LIMIT 1),
+       MAX(created_at)
+FROM sends
+WHERE (? IS NULL OR created_at >= ?)
+GROUP BY sink_id
+ORDER BY sink_id;
+`, nullTime(since), nullTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("list sink stats: %w", err)
+	}
+	defer rows.Close()
+
+	var out []SinkStats
+	for rows.Next() {
+		var st SinkStats
+		var last any
+		if err := rows.Scan(&st.SinkID, &st.Sent, &st.Failed, &st.LastStatus, &last); err != nil {
+			return nil, fmt.Errorf("scan sink stats: %w", err)
+		}
+		st.LastAt = parseTime(last)
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+// RuleRecord is a rule managed at runtime through the API. It overrides a
+// config rule with the same id.
+type RuleRecord struct {
+	ID        string
+	SpecJSON  string
+	Disabled  bool
+	UpdatedAt time.Time
+}
+
+// UpsertRule stores or replaces a runtime-managed rule.
+func (s *Store) UpsertRule(ctx context.Context, rec RuleRecord) error {
+	if rec.ID == "" || rec.SpecJSON == "" {
+		return errors.New("rule id and spec required")
+	}
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO rules (id, spec_json, disabled, updated_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(id) DO UPDATE SET
+  spec_json=excluded.spec_json,
+  disabled=excluded.disabled,
+  updated_at=CURRENT_TIMESTAMP;
+`, rec.ID, rec.SpecJSON, rec.Disabled)
+	if err != nil {
+		return fmt.Errorf("upsert rule: %w", err)
+	}
+	return nil
+}
+
+// ListRules returns all runtime-managed rules ordered by id.
+func (s *Store) ListRules(ctx context.Context) ([]RuleRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, spec_json, disabled, updated_at FROM rules ORDER BY id;
+`)
+	if err != nil {
+		return nil, fmt.Errorf("list rules: %w", err)
+	}
+	defer rows.Close()
+
+	var out []RuleRecord
+	for rows.Next() {
+		var rec RuleRecord
+		if err := rows.Scan(&rec.ID, &rec.SpecJSON, &rec.Disabled, &rec.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan rule: %w", err)
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+// TokenMeta is the cached symbol and decimals of a token contract. An empty
+// Symbol records a contract that is not a token, so it is not asked again.
+type TokenMeta struct {
+	SourceID string
+	Address  string
+	Symbol   string
+	Decimals int
+}
+
+// GetToken returns cached metadata for a token contract on a source.
+func (s *Store) GetToken(ctx context.Context, sourceID, address string) (TokenMeta, bool, error) {
+	meta := TokenMeta{SourceID: sourceID, Address: address}
+	row := s.db.QueryRowContext(ctx, `SELECT symbol, decimals FROM tokens WHERE source_id = ? AND address = ?;`, sourceID, address)
+	switch err := row.Scan(&meta.Symbol, &meta.Decimals); err {
+	case nil:
+		return meta, true, nil
+	case sql.ErrNoRows:
+		return meta, false, nil
+	default:
+		return meta, false, fmt.Errorf("get token: %w", err)
+	}
+}
+
+// UpsertToken caches token metadata.
+func (s *Store) UpsertToken(ctx context.Context, meta TokenMeta) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO tokens (source_id, address, symbol, decimals, updated_at)
+VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(source_id, address) DO UPDATE SET
+  symbol=excluded.symbol,
+  decimals=excluded.decimals,
+  updated_at=CURRENT_TIMESTAMP;
+`, meta.SourceID, meta.Address, meta.Symbol, meta.Decimals)
+	if err != nil {
+		return fmt.Errorf("upsert token: %w", err)
+	}
+	return nil
+}
+
+// ENSName is the cached primary ENS name of an address. An empty Name
+// records an address without one; UpdatedAt says when it was looked up.
+type ENSName struct {
+	SourceID  string
+	Address   string
+	Name      string
+	UpdatedAt time.Time
+}
+
+// GetENSName returns the cached ENS name of an address on a source.
+func (s *Store) GetENSName(ctx context.Context, sourceID, address string) (ENSName, bool, error) {
+	n := ENSName{SourceID: sourceID, Address: address}
+	row := s.db.QueryRowContext(ctx, `SELECT name, updated_at FROM ens_names WHERE source_id = ? AND address = ?;`, sourceID, address)
+	switch err := row.Scan(&n.Name, &n.UpdatedAt); err {
+	case nil:
+		return n, true, nil
+	case sql.ErrNoRows:
+		return n, false, nil
+	default:
+		return n, false, fmt.Errorf("get ens name: %w", err)
+	}
+}
+
+// UpsertENSName caches an ENS lookup.
+func (s *Store) UpsertENSName(ctx context.Context, n ENSName) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO ens_names (source_id, address, name, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(source_id, address) DO UPDATE SET
+  name=excluded.name,
+  updated_at=excluded.updated_at;
+`, n.SourceID, n.Address, n.Name, n.UpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("upsert ens name: %w", err)
+	}
+	return nil
+}
+
+// StateValue is the last value a storage or call rule read from a contract,
+// or the threshold state of a balance rule.
+type StateValue struct {
+	SourceID string
+	RuleID   string
+	Value    string
+	Height   uint64
+}
+
+// GetStateValue returns the last value recorded for a rule on a source.
+func (s *Store) GetStateValue(ctx context.Context, sourceID, ruleID string) (StateValue, bool, error) {
+	v := StateValue{SourceID: sourceID, RuleID: ruleID}
+	row := s.db.QueryRowContext(ctx, `SELECT value, height FROM state_values WHERE source_id = ? AND rule_id = ?;`, sourceID, ruleID)
+	switch err := row.Scan(&v.Value, &v.Height); err {
+	case nil:
+		return v, true, nil
+	case sql.ErrNoRows:
+		return v, false, nil
+	default:
+		return v, false, fmt.Errorf("get state value: %w", err)
+	}
+}
+
+// UpsertStateValue records the latest value read for a rule.
+func (s *Store) UpsertStateValue(ctx context.Context, v StateValue) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO state_values (source_id, rule_id, value, height, updated_at)
+VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(source_id, rule_id) DO UPDATE SET
+  value=excluded.value,
+  height=excluded.height,
+  updated_at=CURRENT_TIMESTAMP;
+`, v.SourceID, v.RuleID, v.Value, v.Height)
+	if err != nil {
+		return fmt.Errorf("upsert state value: %w", err)
+	}
+	return nil
+}
+
+// GetThread returns the ts of the Slack thread started for group in channel
+// after since, or "" when there is none.
+func (s *Store) GetThread(ctx context.Context, channel, group string, since time.Time) (string, error) {
+	var ts string
+	err := s.db.QueryRowContext(ctx, `SELECT ts FROM slack_threads WHERE channel = ? AND group_key = ? AND created_at > ?;`, channel, group, since.UTC()).Scan(&ts)
+	switch err {
+	case nil, sql.ErrNoRows:
+		return ts, nil
+	default:
+		return "", fmt.Errorf("get thread: %w", err)
+	}
+}
+
+// PutThread records ts as the thread for group in channel, replacing an
+// expired one.
+func (s *Store) PutThread(ctx context.Context, channel, group, ts string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO slack_threads (channel, group_key, ts, created_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(channel, group_key) DO UPDATE SET
+  ts=excluded.ts,
+  created_at=excluded.created_at;
+`, channel, group, ts, at.UTC())
+	if err != nil {
+		return fmt.Errorf("put thread: %w", err)
+	}
+	return nil
+}
+
+// AuditRecord is one decision taken on an event: whether it matched its
+// rule, why it was suppressed, and how each delivery went.
+type AuditRecord struct {
+	ID       int64
+	RuleID   string
+	SourceID string
+	Height   uint64
+	TxHash   string
+	AlertID  string
+	SinkID   string
+	Decision string
+	// Detail explains the decision, such as the predicate that failed.
+	Detail    string
+	CreatedAt time.Time
+}
+
+// InsertAudit stores an audit record.
+func (s *Store) InsertAudit(ctx context.Context, a AuditRecord) error {
+	if a.Decision == "" {
+		return errors.New("audit decision required")
+	}
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO audit (rule_id, source_id, height, txhash, alert_id, sink_id, decision, detail, created_at)
+VALUES (?, NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, NULLIF(?, ''), COALESCE(?, CURRENT_TIMESTAMP));
+`, a.RuleID, a.SourceID, a.Height, a.TxHash, a.AlertID, a.SinkID, a.Decision, a.Detail, nullTime(a.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("insert audit: %w", err)
+	}
+	return nil
+}
+
+// AuditFilter narrows ListAudit results. Zero values mean no filter.
+type AuditFilter struct {
+	RuleID string
+	TxHash string
+	Since  time.Time
+	Limit  int
+}
+
+// ListAudit returns audit records newest first.
+func (s *Store) ListAudit(ctx context.Context, f AuditFilter) ([]AuditRecord, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, rule_id, COALESCE(source_id, ''), COALESCE(height, 0), COALESCE(txhash, ''), COALESCE(alert_id, ''),
+  COALESCE(sink_id, ''), decision, COALESCE(detail, ''), created_at
+FROM audit
+WHERE (? = '' OR rule_id = ?) AND (? = '' OR txhash = ?) AND (? IS NULL OR created_at >= ?)
+ORDER BY id DESC
+LIMIT ?;
+`, f.RuleID, f.RuleID, f.TxHash, f.TxHash, nullTime(f.Since), nullTime(f.Since), limit)
+	if err != nil {
+		return nil, fmt.Errorf("list audit: %w", err)
+	}
+	defer rows.Close()
+
+	var out []AuditRecord
+	for rows.Next() {
+		var a AuditRecord
+		if err := rows.Scan(&a.ID, &a.RuleID, &a.SourceID, &a.Height, &a.TxHash, &a.AlertID, &a.SinkID, &a.Decision, &a.Detail, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan audit: %w", err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// PruneAudit deletes audit records created before cutoff and returns how
+// many were removed.
+func (s *Store) PruneAudit(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM audit WHERE created_at < ?;`, cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("prune audit: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Silence mutes alerts for a rule until ExpiresAt. RuleID "*" mutes every rule.
+type Silence struct {
+	ID        string
+	RuleID    string
+	Reason    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// InsertSilence stores a silence window.
+func (s *Store) InsertSilence(ctx context.Context, sil Silence) error {
+	if sil.ID == "" || sil.RuleID == "" {
+		return errors.New("silence id and rule_id required")
+	}
+	if sil.ExpiresAt.IsZero() {
+		return errors.New("silence expires_at required")
+	}
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO silences (id, rule_id, reason, expires_at, created_at)
+VALUES (?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP));
+`, sil.ID, sil.RuleID, sil.Reason, sil.ExpiresAt.UTC(), nullTime(sil.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("insert silence: %w", err)
+	}
+	return nil
+}
+
+// ListSilences returns silences that have not expired at now.
+func (s *Store) ListSilences(ctx context.Context, now time.Time) ([]Silence, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, rule_id, COALESCE(reason, ''), expires_at, created_at
+FROM silences WHERE expires_at > ? ORDER BY expires_at;
+`, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("list silences: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Silence
+	for rows.Next() {
+		var sil Silence
+		if err := rows.Scan(&sil.ID, &sil.RuleID, &sil.Reason, &sil.ExpiresAt, &sil.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan silence: %w", err)
+		}
+		out = append(out, sil)
+	}
+	return out, rows.Err()
+}
+
+// DeleteSilence removes a silence; ok is false when the id is unknown.
+func (s *Store) DeleteSilence(ctx context.Context, id string) (ok bool, err error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM silences WHERE id = ?;`, id)
+	if err != nil {
+		return false, fmt.Errorf("delete silence: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete silence: %w", err)
+	}
+	return n > 0, nil
+}
+
+// IsSilenced reports whether an active silence covers the rule at now.
+func (s *Store) IsSilenced(ctx context.Context, ruleID string, now time.Time) (bool, error) {
+	var n int
+	err := s.stmts.isSilenced.QueryRowContext(ctx, ruleID, now.UTC()).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("check silence: %w", err)
+	}
+	return n > 0, nil
+}
+
 // WithTx executes a callback inside a transaction for callers needing atomicity.
 func (s *Store) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -254,3 +1294,19 @@ func nullTime(t time.Time) any {
 	}
 	return t.UTC()
 }
+
+// parseTime reads an aggregated timestamp column. SQLite drops the declared
+// column type for MAX(), so the driver may hand back a string.
+func parseTime(v any) time.Time {
+	switch t := v.(type) {
+	case time.Time:
+		return t
+	case string:
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999 -0700 MST", "2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano, "2006-01-02 15:04:05"} {
+			if parsed, err := time.Parse(layout, t); err == nil {
+				return parsed
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 	"testing"
 	"time"
@@ -69,6 +70,35 @@ func TestDedupeTTL(t *testing.T) {
 	}
 }
 
+func TestPruneDedupe(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for key, exp := range map[string]time.Time{
+		"old":     now.Add(-time.Hour),
+		"just":    now.Add(-500 * time.Millisecond),
+		"fresh":   now.Add(1500 * time.Millisecond),
+		"pending": now.Add(time.Hour),
+	} {
+		if err := store.MarkDedupe(ctx, key, exp); err != nil {
+			t.Fatalf("mark %s: %v", key, err)
+		}
+	}
+	n, err := store.PruneDedupe(ctx, now)
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 expired keys pruned, got %d", n)
+	}
+	for _, key := range []string{"fresh", "pending"} {
+		if dup, err := store.IsDuplicate(ctx, key, now); err != nil || !dup {
+			t.Fatalf("expected %s kept, got dup=%v err=%v", key, dup, err)
+		}
+	}
+}
+
 func TestExactlyOnceAlert(t *testing.T) {
 	store := newTestStore(t)
 	ctx := context.Background()
@@ -103,3 +133,206 @@ func TestPing(t *testing.T) {
 		t.Fatalf("expected ping to fail after close")
 	}
 }
+
+func TestListAlertsFiltersByRule(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for i, rule := range []string{"r1", "r2", "r1"} {
+		a := Alert{ID: fmt.Sprintf("a%d", i), RuleID: rule, TxHash: "0x1", CreatedAt: now.Add(time.Duration(i) * time.Second)}
+		if err := store.InsertAlert(ctx, a); err != nil {
+			t.Fatalf("insert alert: %v", err)
+		}
+	}
+
+	alerts, err := store.ListAlerts(ctx, AlertFilter{RuleID: "r1"})
+	if err != nil {
+		t.Fatalf("list alerts: %v", err)
+	}
+	if len(alerts) != 2 || alerts[0].ID != "a2" {
+		t.Fatalf("unexpected alerts: %+v", alerts)
+	}
+
+	alerts, err = store.ListAlerts(ctx, AlertFilter{Limit: 1})
+	if err != nil || len(alerts) != 1 {
+		t.Fatalf("limit not applied: %d err=%v", len(alerts), err)
+	}
+}
+
+func TestSilences(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := store.InsertSilence(ctx, Silence{ID: "s1", RuleID: "r1", ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("insert silence: %v", err)
+	}
+	silenced, err := store.IsSilenced(ctx, "r1", now)
+	if err != nil || !silenced {
+		t.Fatalf("expected r1 silenced, got %v err=%v", silenced, err)
+	}
+	if silenced, _ := store.IsSilenced(ctx, "r2", now); silenced {
+		t.Fatalf("expected r2 not silenced")
+	}
+	if silenced, _ := store.IsSilenced(ctx, "r1", now.Add(2*time.Hour)); silenced {
+		t.Fatalf("expected silence to expire")
+	}
+
+	active, err := store.ListSilences(ctx, now)
+	if err != nil || len(active) != 1 {
+		t.Fatalf("list silences: %v err=%v", active, err)
+	}
+	ok, err := store.DeleteSilence(ctx, "s1")
+	if err != nil || !ok {
+		t.Fatalf("delete silence: ok=%v err=%v", ok, err)
+	}
+	if silenced, _ := store.IsSilenced(ctx, "r1", now); silenced {
+		t.Fatalf("expected silence removed")
+	}
+}
+
+func TestRuleRecords(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	if err := store.UpsertRule(ctx, RuleRecord{ID: "r1", SpecJSON: `{"id":"r1"}`}); err != nil {
+		t.Fatalf("upsert rule: %v", err)
+	}
+	if err := store.UpsertRule(ctx, RuleRecord{ID: "r1", SpecJSON: `{"id":"r1"}`, Disabled: true}); err != nil {
+		t.Fatalf("update rule: %v", err)
+	}
+	recs, err := store.ListRules(ctx)
+	if err != nil {
+		t.Fatalf("list rules: %v", err)
+	}
+	if len(recs) != 1 || !recs[0].Disabled {
+		t.Fatalf("unexpected rules: %+v", recs)
+	}
+}
+
+func TestRuleHitsAndSinkStats(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, rule := range []string{"r1", "r1", "r2"} {
+		id := fmt.Sprintf("a%d", i)
+		if err := store.InsertAlert(ctx, Alert{ID: id, RuleID: rule, CreatedAt: now.Add(time.Duration(i) * time.Second)}); err != nil {
+			t.Fatalf("insert alert: %v", err)
+		}
+	}
+	sends := []Send{
+		{AlertID: "a0", SinkID: "slack", Status: "sent", CreatedAt: now},
+		{AlertID: "a1", SinkID: "slack", Status: "failed", CreatedAt: now.Add(time.Second)},
+		{AlertID: "a2", SinkID: "hook", Status: "sent", CreatedAt: now},
+	}
+	for _, s := range sends {
+		if err := store.InsertSend(ctx, s); err != nil {
+			t.Fatalf("insert send: %v", err)
+		}
+	}
+
+	hits, err := store.ListRuleHits(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("rule hits: %v", err)
+	}
+	if len(hits) != 2 || hits[0].RuleID != "r1" || hits[0].Count != 2 || !hits[0].LastAt.Equal(now.Add(time.Second)) {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+
+	stats, err := store.ListSinkStats(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("sink stats: %v", err)
+	}
+	if len(stats) != 2 || stats[1].SinkID != "slack" || stats[1].Sent != 1 || stats[1].Failed != 1 || stats[1].LastStatus != "failed" {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestThreads(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	if ts, err := store.GetThread(ctx, "C1", "g", now.Add(-time.Hour)); err != nil || ts != "" {
+		t.Fatalf("expected no thread, got %q %v", ts, err)
+	}
+	if err := store.PutThread(ctx, "C1", "g", "100.1", now.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("put thread: %v", err)
+	}
+	if ts, _ := store.GetThread(ctx, "C1", "g", now.Add(-time.Hour)); ts != "" {
+		t.Fatalf("expired thread returned: %q", ts)
+	}
+	if err := store.PutThread(ctx, "C1", "g", "100.2", now); err != nil {
+		t.Fatalf("replace thread: %v", err)
+	}
+	if ts, _ := store.GetThread(ctx, "C1", "g", now.Add(-time.Hour)); ts != "100.2" {
+		t.Fatalf("expected 100.2, got %q", ts)
+	}
+	if ts, _ := store.GetThread(ctx, "C2", "g", now.Add(-time.Hour)); ts != "" {
+		t.Fatalf("thread leaked across channels: %q", ts)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for i, rec := range []AuditRecord{
+		{RuleID: "whale", TxHash: "0x1", Decision: "predicate_failed", Detail: "value > 10", CreatedAt: now.Add(-48 * time.Hour)},
+		{RuleID: "whale", TxHash: "0x2", Decision: "matched", AlertID: "a2", CreatedAt: now},
+		{RuleID: "mint", TxHash: "0x2", Decision: "deduped", CreatedAt: now},
+	} {
+		if err := store.InsertAudit(ctx, rec); err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+	if err := store.InsertAudit(ctx, AuditRecord{RuleID: "whale"}); err == nil {
+		t.Fatalf("expected error without a decision")
+	}
+
+	recs, err := store.ListAudit(ctx, AuditFilter{RuleID: "whale"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(recs) != 2 || recs[0].Decision != "matched" || recs[1].Detail != "value > 10" {
+		t.Fatalf("unexpected records: %+v", recs)
+	}
+	if recs, _ := store.ListAudit(ctx, AuditFilter{TxHash: "0x2"}); len(recs) != 2 {
+		t.Fatalf("expected 2 records for 0x2, got %+v", recs)
+	}
+
+	n, err := store.PruneAudit(ctx, now.Add(-time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 pruned, got %d (%v)", n, err)
+	}
+	if recs, _ := store.ListAudit(ctx, AuditFilter{}); len(recs) != 2 {
+		t.Fatalf("expected 2 records left, got %d", len(recs))
+	}
+}
+
+func BenchmarkHotPath(b *testing.B) {
+	store, err := Open(b.TempDir() + "/db.sqlite")
+	if err != nil {
+		b.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	now := time.Now()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("0x%d", i%64)
+		if _, err := store.IsDuplicate(ctx, key, now); err != nil {
+			b.Fatal(err)
+		}
+		if err := store.MarkDedupe(ctx, key, now.Add(time.Hour)); err != nil {
+			b.Fatal(err)
+		}
+		if _, _, _, err := store.GetCursor(ctx, "evm_main"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/devblac/watch-tower/internal/sink"
+)
+
+// Hub fans out matched events to live subscribers (API streams, dashboards).
+// Publishing never blocks: a subscriber that falls behind loses events.
+type Hub struct {
+	mu   sync.Mutex
+	next int
+	subs map[int]chan sink.EventPayload
+}
+
+// NewHub creates an empty hub.
+func NewHub() *Hub {
+	return &Hub{subs: map[int]chan sink.EventPayload{}}
+}
+
+// Subscribe registers a subscriber with the given buffer size.
+// The returned cancel func unregisters it and closes the channel.
+func (h *Hub) Subscribe(buffer int) (<-chan sink.EventPayload, func()) {
+	if buffer <= 0 {
+		buffer = 64
+	}
+	ch := make(chan sink.EventPayload, buffer)
+
+	h.mu.Lock()
+	id := h.next
+	h.next++
+	h.subs[id] = ch
+	h.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, id)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// Publish delivers the payload to every subscriber with buffer room.
+func (h *Hub) Publish(p sink.EventPayload) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ch := range h.subs {
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+// Subscribers returns the number of active subscribers.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
@@ -0,0 +1,38 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/sink"
+)
+
+func TestHubFanOutAndCancel(t *testing.T) {
+	h := NewHub()
+	a, cancelA := h.Subscribe(1)
+	b, cancelB := h.Subscribe(1)
+	defer cancelB()
+
+	h.Publish(sink.EventPayload{RuleID: "r1"})
+	if got := (<-a).RuleID; got != "r1" {
+		t.Fatalf("subscriber a got %q", got)
+	}
+	if got := (<-b).RuleID; got != "r1" {
+		t.Fatalf("subscriber b got %q", got)
+	}
+
+	// Full buffers drop instead of blocking the publisher.
+	h.Publish(sink.EventPayload{RuleID: "r2"})
+	h.Publish(sink.EventPayload{RuleID: "r3"})
+
+	cancelA()
+	cancelA()
+	if h.Subscribers() != 1 {
+		t.Fatalf("expected 1 subscriber, got %d", h.Subscribers())
+	}
+	if _, ok := <-a; !ok {
+		t.Fatalf("expected buffered event before close")
+	}
+	if _, ok := <-a; ok {
+		t.Fatalf("expected channel closed after cancel")
+	}
+}
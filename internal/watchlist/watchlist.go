@@ -0,0 +1,170 @@
+// Package watchlist loads watch_address lists kept outside the config (a file
+// or an HTTP endpoint) and reloads them on each rule's refresh interval.
+package watchlist
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/metrics"
+)
+
+// maxListBytes caps how much of a list is read, so a misconfigured URL
+// cannot exhaust memory.
+const maxListBytes = 16 << 20
+
+// ApplyFunc installs a freshly loaded list for a rule.
+type ApplyFunc func(ruleID string, addrs []string) error
+
+type list struct {
+	ruleID string
+	from   string
+	every  time.Duration
+}
+
+// Reloader keeps the addresses_from lists of watch_address rules current.
+type Reloader struct {
+	lists   []list
+	apply   ApplyFunc
+	client  *http.Client
+	metrics *metrics.Metrics
+	log     *slog.Logger
+	nowFunc func() time.Time
+}
+
+// NewReloader builds a reloader for the rules that set addresses_from.
+// m and log may be nil.
+func NewReloader(rules []config.Rule, apply ApplyFunc, m *metrics.Metrics, log *slog.Logger) *Reloader {
+	r := &Reloader{
+		apply:   apply,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		metrics: m,
+		log:     log,
+		nowFunc: time.Now,
+	}
+	for _, rule := range rules {
+		if rule.Match.Type != config.MatchWatchAddress || rule.Match.AddressesFrom == "" {
+			continue
+		}
+		r.lists = append(r.lists, list{ruleID: rule.ID, from: rule.Match.AddressesFrom, every: rule.Match.RefreshInterval()})
+	}
+	return r
+}
+
+// Len returns how many rules have an external list.
+func (r *Reloader) Len() int {
+	return len(r.lists)
+}
+
+// Refresh loads every list once. It is called before scanning starts, so a
+// list that cannot be loaded fails startup instead of silently watching nothing.
+func (r *Reloader) Refresh(ctx context.Context) error {
+	var errs []error
+	for _, l := range r.lists {
+		if err := r.refresh(ctx, l); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run reloads each list on its interval until ctx is cancelled. A failed
+// reload keeps the previous list and is retried on the next tick.
+func (r *Reloader) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, l := range r.lists {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(l.every)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				if err := r.refresh(ctx, l); err != nil && r.log != nil && ctx.Err() == nil {
+					r.log.Warn("watchlist refresh failed", "rule", l.ruleID, "error", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Reloader) refresh(ctx context.Context, l list) error {
+	addrs, err := r.load(ctx, l.from)
+	if err != nil {
+		return fmt.Errorf("rule %s watchlist: %w", l.ruleID, err)
+	}
+	if err := r.apply(l.ruleID, addrs); err != nil {
+		return fmt.Errorf("rule %s watchlist: %w", l.ruleID, err)
+	}
+	r.metrics.WatchlistRefreshed(l.ruleID, len(addrs), r.nowFunc())
+	return nil
+}
+
+// load reads a list from an http(s) URL or a local file.
+func (r *Reloader) load(ctx context.Context, from string) ([]string, error) {
+	if !strings.HasPrefix(from, "http://") && !strings.HasPrefix(from, "https://") {
+		f, err := os.Open(from)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return Parse(io.LimitReader(f, maxListBytes))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, from, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: status %d", from, resp.StatusCode)
+	}
+	return Parse(io.LimitReader(resp.Body, maxListBytes))
+}
+
+// Parse reads a list as either a JSON array of strings or plain text with
+// one address per line. In text, blank lines and # comments are skipped and
+// only the first comma-separated field is used, so CSV exports work as-is.
+func Parse(rd io.Reader) ([]string, error) {
+	raw, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		var addrs []string
+		if err := json.Unmarshal(trimmed, &addrs); err != nil {
+			return nil, fmt.Errorf("parse json list: %w", err)
+		}
+		return addrs, nil
+	}
+	var addrs []string
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		field, _, _ := strings.Cut(line, ",")
+		if field = strings.TrimSpace(field); field != "" {
+			addrs = append(addrs, field)
+		}
+	}
+	return addrs, sc.Err()
+}
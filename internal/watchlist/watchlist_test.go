@@ -0,0 +1,71 @@
+package watchlist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/devblac/watch-tower/internal/config"
+)
+
+func TestParseFormats(t *testing.T) {
+	text := "# sanctioned\n0xaa, OFAC\n\n  0xbb  # added 2024-05\n"
+	got, err := Parse(strings.NewReader(text))
+	if err != nil || !slices.Equal(got, []string{"0xaa", "0xbb"}) {
+		t.Fatalf("text list: %v, %v", got, err)
+	}
+	got, err = Parse(strings.NewReader(` ["0xaa", "ALGOADDR"] `))
+	if err != nil || !slices.Equal(got, []string{"0xaa", "ALGOADDR"}) {
+		t.Fatalf("json list: %v, %v", got, err)
+	}
+}
+
+func TestReloaderRefresh(t *testing.T) {
+	body := "0xaa\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if body == "" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	file := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(file, []byte("0xcc\n0xdd\n"), 0o600); err != nil {
+		t.Fatalf("write list: %v", err)
+	}
+
+	applied := map[string][]string{}
+	rules := []config.Rule{
+		{ID: "remote", Match: config.MatchSpec{Type: config.MatchWatchAddress, AddressesFrom: srv.URL}},
+		{ID: "local", Match: config.MatchSpec{Type: config.MatchWatchAddress, AddressesFrom: file}},
+		{ID: "static", Match: config.MatchSpec{Type: config.MatchWatchAddress, Addresses: []string{"0x01"}}},
+	}
+	r := NewReloader(rules, func(ruleID string, addrs []string) error {
+		applied[ruleID] = addrs
+		return nil
+	}, nil, nil)
+	if r.Len() != 2 {
+		t.Fatalf("expected 2 external lists, got %d", r.Len())
+	}
+	if err := r.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if !slices.Equal(applied["remote"], []string{"0xaa"}) || len(applied["local"]) != 2 {
+		t.Fatalf("unexpected lists: %v", applied)
+	}
+
+	// A failed fetch reports an error and leaves the applied list alone.
+	body = ""
+	if err := r.Refresh(context.Background()); err == nil {
+		t.Fatalf("expected error for failing endpoint")
+	}
+	if !slices.Equal(applied["remote"], []string{"0xaa"}) {
+		t.Fatalf("expected previous list kept, got %v", applied["remote"])
+	}
+}
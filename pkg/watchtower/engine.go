@@ -0,0 +1,425 @@
+package watchtower
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/engine"
+	"github.com/devblac/watch-tower/internal/price"
+	"github.com/devblac/watch-tower/internal/source/evm"
+	"github.com/devblac/watch-tower/internal/source/ingest"
+	"github.com/devblac/watch-tower/internal/watchlist"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Publisher receives every event that passes a rule's predicates, before
+// suppression, as the live event stream does.
+type Publisher = engine.Publisher
+
+type options struct {
+	store     *Store
+	sinks     map[string]Sender
+	log       *slog.Logger
+	dryRun    bool
+	from, to  uint64
+	publisher Publisher
+	workers   int
+	clients   map[string]any // by source id, of the source's client type
+}
+
+// Option customises an Engine.
+type Option func(*options)
+
+// WithStore uses an already open store instead of opening
+// Config.Global.DBPath. The caller keeps ownership: Close does not close it.
+func WithStore(s *Store) Option {
+	return func(o *options) { o.store = s }
+}
+
+// WithSink registers sender under id. Declare it in the config as a sink of
+// type external so rules can refer to it. It may also replace a built-in
+// sink with the same id.
+func WithSink(id string, sender Sender) Option {
+	return func(o *options) { o.sinks[id] = sender }
+}
+
+// WithLogger logs each alert's progress to l. Nothing is logged by default.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) { o.log = l }
+}
+
+// WithDryRun matches and publishes events without sending alerts or
+// recording dedupe state.
+func WithDryRun() Option {
+	return func(o *options) { o.dryRun = true }
+}
+
+// WithRange scans from height from through to, inclusive. Zero leaves
+// either end at its default: the stored cursor and the chain tip.
+func WithRange(from, to uint64) Option {
+	return func(o *options) { o.from, o.to = from, to }
+}
+
+// WithPublisher sends every matched event to p.
+func WithPublisher(p Publisher) Option {
+	return func(o *options) { o.publisher = p }
+}
+
+// WithSinkWorkers queues alerts and delivers them with n concurrent workers,
+// retrying failed sends with backoff. By default, or with zero, alerts are
+// sent inline during scanning and a failed send fails the tick.
+func WithSinkWorkers(n int) Option {
+	return func(o *options) { o.workers = n }
+}
+
+// WithEVMClient scans EVM source sourceID through c instead of dialing its
+// rpc_url, such as to share an existing client or for tests. Token symbols
+// and decimals are only resolved when c can also make contract calls.
+func WithEVMClient(sourceID string, c EVMClient) Option {
+	return func(o *options) { o.clients[sourceID] = c }
+}
+
+// WithAlgodClient scans Algorand source sourceID through c instead of
+// dialing its algod_url.
+func WithAlgodClient(sourceID string, c AlgodClient) Option {
+	return func(o *options) { o.clients[sourceID] = c }
+}
+
+// WithSolanaClient scans Solana source sourceID through c instead of
+// calling its rpc_url.
+func WithSolanaClient(sourceID string, c SolanaClient) Option {
+	return func(o *options) { o.clients[sourceID] = c }
+}
+
+// WithBitcoinClient scans Bitcoin source sourceID through c instead of
+// calling its rpc_url or esplora_url.
+func WithBitcoinClient(sourceID string, c BitcoinClient) Option {
+	return func(o *options) { o.clients[sourceID] = c }
+}
+
+// WithCosmosClient scans Cosmos source sourceID through c instead of
+// calling its rpc_url.
+func WithCosmosClient(sourceID string, c CosmosClient) Option {
+	return func(o *options) { o.clients[sourceID] = c }
+}
+
+// WithSubstrateClient scans Substrate source sourceID through c instead of
+// calling its rpc_url.
+func WithSubstrateClient(sourceID string, c SubstrateClient) Option {
+	return func(o *options) { o.clients[sourceID] = c }
+}
+
+// WithNearClient scans NEAR source sourceID through c instead of calling
+// its rpc_url.
+func WithNearClient(sourceID string, c NearClient) Option {
+	return func(o *options) { o.clients[sourceID] = c }
+}
+
+// WithTronClient scans Tron source sourceID through c instead of calling
+// its rpc_url.
+func WithTronClient(sourceID string, c TronClient) Option {
+	return func(o *options) { o.clients[sourceID] = c }
+}
+
+// Engine scans the configured sources and delivers alerts for the rules.
+type Engine struct {
+	cfg        *Config
+	store      *Store
+	ownStore   bool
+	runner     *engine.Runner
+	dispatcher *engine.Dispatcher
+	lists      *watchlist.Reloader
+	ingests    map[string]*ingest.Source
+	log        *slog.Logger
+	dryRun     bool
+}
+
+// New builds an engine for cfg, which must already be validated (LoadConfig
+// and ParseConfig do so). Rules changed through a previous run's admin API
+// are read from the store and take precedence, as they do for the CLI.
+func New(ctx context.Context, cfg *Config, opts ...Option) (*Engine, error) {
+	o := options{
+		sinks:   map[string]Sender{},
+		log:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		clients: map[string]any{},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	e := &Engine{cfg: cfg, store: o.store, ingests: map[string]*ingest.Source{}, log: o.log, dryRun: o.dryRun}
+	if e.store == nil {
+		store, err := OpenStore(cfg.Global.DBPath)
+		if err != nil {
+			return nil, fmt.Errorf("open storage: %w", err)
+		}
+		e.store, e.ownStore = store, true
+	}
+	if err := e.init(ctx, o); err != nil {
+		_ = e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Engine) init(ctx context.Context, o options) error {
+	cfg, store := e.cfg, e.store
+	if err := engine.ApplyStoredRules(ctx, store, cfg); err != nil {
+		return fmt.Errorf("load stored rules: %w", err)
+	}
+
+	scanners := map[string]engine.Scanner{}
+	mempools := map[string]*evm.MempoolWatcher{}
+	for _, src := range cfg.Sources {
+		switch src.Type {
+		case "evm":
+			if o.from > 0 {
+				src.StartBlock = fmt.Sprintf("%d", o.from)
+			}
+			confirmations := cfg.Global.Confirmations["evm"]
+			if cli, ok := o.clients[src.ID].(EVMClient); ok {
+				abis := e.loadABIs(ctx, src, cfg.Rules, cli)
+				sc, err := evm.NewScanner(cli, store, src, confirmations.Depth, abis, cfg.Rules)
+				if err != nil {
+					return err
+				}
+				sc.SetFinality(confirmations.Tag)
+				if caller, ok := cli.(ethereum.ContractCaller); ok {
+					sc.SetTokenResolver(evm.NewTokenResolver(caller, store, src.ID))
+				}
+				if mc, ok := cli.(evm.MempoolClient); ok && evm.HasPendingRules(src.ID, cfg.Rules) {
+					mempools[src.ID] = evm.NewMempoolWatcher(mc, src.ID, cfg.Rules)
+				}
+				scanners[src.ID] = engine.NewEVMScanner(sc)
+				continue
+			}
+			rpcCli, err := evm.Dial(src.RPCURL, src.RPCHeaders, src.RPCBasicAuth)
+			if err != nil {
+				return err
+			}
+			cli := evm.NewLimitedClient(rpcCli, src.MaxRPS)
+			abis := e.loadABIs(ctx, src, cfg.Rules, cli)
+			sc, err := evm.NewScanner(cli, store, src, confirmations.Depth, abis, cfg.Rules)
+			if err != nil {
+				return err
+			}
+			sc.SetFinality(confirmations.Tag)
+			sc.SetTokenResolver(evm.NewTokenResolver(cli, store, src.ID))
+			if evm.HasPendingRules(src.ID, cfg.Rules) {
+				mempools[src.ID] = evm.NewMempoolWatcher(cli, src.ID, cfg.Rules)
+			}
+			scanners[src.ID] = engine.NewEVMScanner(sc)
+		case "ingest":
+			s, err := ingest.NewSource(store, src, cfg.Rules)
+			if err != nil {
+				return err
+			}
+			e.ingests[src.ID] = s
+		default:
+			chain, ok := engine.Chains[src.Type]
+			if !ok {
+				continue
+			}
+			if o.from > 0 {
+				chain.SetStart(&src, fmt.Sprintf("%d", o.from))
+			}
+			cli, ok := o.clients[src.ID]
+			if !ok {
+				var err error
+				if cli, err = chain.Dial(src); err != nil {
+					return err
+				}
+			}
+			sc, err := chain.NewScanner(cli, store, src, cfg.Global.Confirmations[src.Type], cfg.Rules)
+			if err != nil {
+				return err
+			}
+			scanners[src.ID] = sc
+		}
+	}
+
+	sinks, err := NewSinks(cfg.Sinks, store)
+	if err != nil {
+		return err
+	}
+	for id, s := range o.sinks {
+		sinks[id] = s
+	}
+	for _, s := range cfg.Sinks {
+		if _, ok := sinks[s.ID]; !ok && s.Type == "external" {
+			return fmt.Errorf("sink %s: external sinks need a sender from WithSink", s.ID)
+		}
+	}
+
+	runner, err := engine.NewRunner(store, cfg, scanners, sinks, o.dryRun, o.from, o.to)
+	if err != nil {
+		return err
+	}
+	if err := runner.LoadDisabledRules(ctx); err != nil {
+		return fmt.Errorf("load stored rules: %w", err)
+	}
+	runner.SetLogger(o.log)
+	for id, s := range e.ingests {
+		runner.SetIngest(id, s)
+	}
+	for id, w := range mempools {
+		runner.SetMempool(id, w)
+	}
+	if a := cfg.Global.Audit; a != nil && a.Table {
+		retention, _ := time.ParseDuration(a.Retention)
+		runner.SetAuditTable(retention)
+	}
+	if o.publisher != nil {
+		runner.SetPublisher(o.publisher)
+	}
+	if cfg.Prices != nil {
+		prices, err := price.New(cfg.Prices)
+		if err != nil {
+			return err
+		}
+		runner.SetAnnotator(prices)
+	}
+	if o.workers > 0 && !o.dryRun {
+		e.dispatcher = engine.NewDispatcher(store, sinks, o.workers)
+		e.dispatcher.SetLogger(o.log)
+		runner.SetDispatcher(e.dispatcher)
+	}
+	e.lists = watchlist.NewReloader(cfg.Rules, runner.UpdateWatchlist, nil, o.log)
+	if e.lists.Len() > 0 {
+		if err := e.lists.Refresh(ctx); err != nil {
+			return err
+		}
+	}
+	e.runner = runner
+	return nil
+}
+
+// loadABIs fetches the source's ABIs when abi_fetch is set, following
+// proxies through cli, and loads them with its abi_dirs. A fetch that fails
+// is logged; the ABIs already on disk are used.
+func (e *Engine) loadABIs(ctx context.Context, src Source, rules []Rule, cli EVMClient) map[string]*abi.ABI {
+	if src.ABIFetch != nil {
+		fetcher := evm.NewABIFetcher(*src.ABIFetch)
+		if reader, ok := cli.(evm.StateReader); ok {
+			fetcher.SetStateReader(reader)
+		}
+		if err := fetcher.Fetch(ctx, evm.RuleContracts(src.ID, rules)); err != nil {
+			e.log.Warn("abi fetch incomplete", "source", src.ID, "error", err)
+		}
+	}
+	abis, _ := evm.LoadABIs(src.ABIPaths())
+	return abis
+}
+
+// Tick advances each source by one block or round, sends the digests of
+// quiet hours that have ended, and waits for the resulting alerts to be
+// delivered.
+func (e *Engine) Tick(ctx context.Context) error {
+	if err := e.runner.RunOnce(ctx); err != nil {
+		return err
+	}
+	if e.runner.HasQuietHours() && !e.dryRun {
+		if err := e.runner.FlushQuietHours(ctx); err != nil {
+			return fmt.Errorf("quiet hours digest: %w", err)
+		}
+	}
+	if e.dispatcher != nil {
+		if err := e.dispatcher.Drain(ctx); err != nil {
+			return fmt.Errorf("deliver alerts: %w", err)
+		}
+	}
+	return nil
+}
+
+// Run scans continuously until ctx is cancelled, which returns nil, or a
+// source exhausts its failure budget. It also runs the background jobs the
+// config asks for: dedupe and audit cleanup, watchlist refreshes,
+// suppression summaries, sink heartbeats, quiet hours digests, mempool
+// watching for pending_tx rules, and the listeners of ingest sources.
+func (e *Engine) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	srvs, err := ingest.ServeAll(e.cfg.Sources, e.ingests)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		shutdownCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+		defer stop()
+		for _, srv := range srvs {
+			_ = ingest.Shutdown(shutdownCtx, srv)
+		}
+	}()
+
+	go e.runner.RunJanitor(ctx, engine.DefaultDedupeSweep, func(err error) {
+		e.log.Warn("janitor sweep failed", "error", err)
+	})
+	if e.lists.Len() > 0 {
+		go e.lists.Run(ctx)
+	}
+	if every, _ := time.ParseDuration(e.cfg.Global.SuppressionSummary); every > 0 {
+		go e.runner.RunSuppressionSummaries(ctx, every, func(err error) {
+			e.log.Warn("suppression summary failed", "error", err)
+		})
+	}
+	for _, s := range e.cfg.Sinks {
+		if every, _ := time.ParseDuration(s.Heartbeat); every > 0 && !e.dryRun {
+			go e.runner.RunHeartbeat(ctx, s.ID, every, nil)
+		}
+	}
+	if e.runner.HasMempools() {
+		go e.runner.RunMempools(ctx, func(err error) {
+			e.log.Warn("mempool watcher failed", "error", err)
+		})
+	}
+	if e.runner.HasQuietHours() && !e.dryRun {
+		go e.runner.RunQuietHours(ctx, engine.DefaultQuietCheck, func(err error) {
+			e.log.Warn("quiet hours digest failed", "error", err)
+		})
+	}
+	if e.dispatcher != nil {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			e.dispatcher.Run(ctx)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+	}
+
+	for {
+		if err := e.runner.RunOnce(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-e.runner.Wake():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Store returns the engine's store, for reading alerts and cursors.
+func (e *Engine) Store() *Store {
+	return e.store
+}
+
+// Close releases the store if the engine opened it. Call it after Run or
+// Tick has returned.
+func (e *Engine) Close() error {
+	if e.ownStore {
+		return e.store.Close()
+	}
+	return nil
+}
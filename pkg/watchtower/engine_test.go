@@ -0,0 +1,88 @@
+package watchtower
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devblac/watch-tower/internal/devgen"
+)
+
+type recordingSender struct {
+	mu  sync.Mutex
+	got []Payload
+}
+
+func (r *recordingSender) Send(_ context.Context, p Payload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.got = append(r.got, p)
+	return nil
+}
+
+func TestEngineEmbeds(t *testing.T) {
+	ctx := context.Background()
+	cfg, err := ParseConfig([]byte(fmt.Sprintf(`
+version: 1
+global:
+  db_path: %q
+sources:
+  - id: dev
+    type: evm
+    rpc_url: http://unused
+    start_block: "1"
+rules:
+  - id: transfers
+    source: dev
+    match:
+      type: log
+      contract: "0x00000000000000000000000000000000000000aa"
+      event: "Transfer(address,address,uint256)"
+    sinks: [mine]
+sinks:
+  - id: mine
+    type: external
+`, filepath.Join(t.TempDir(), "wt.db"))))
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+
+	if _, err := New(ctx, cfg); err == nil {
+		t.Fatalf("expected an error for an external sink without a sender")
+	}
+
+	chain, err := devgen.NewEVMChain(devgen.Options{BlockTime: time.Millisecond, EventsPerBlock: 1, Seed: 1}, "dev", cfg.Rules, nil)
+	if err != nil {
+		t.Fatalf("chain: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	s := &recordingSender{}
+	eng, err := New(ctx, cfg, WithEVMClient("dev", chain), WithSink("mine", s))
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	defer eng.Close()
+	for i := 0; i < 5; i++ {
+		if err := eng.Tick(ctx); err != nil {
+			t.Fatalf("tick %d: %v", i, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.got) == 0 {
+		t.Fatalf("expected alerts from generated blocks")
+	}
+	for _, p := range s.got {
+		if p.RuleID != "transfers" || p.SourceID != "dev" {
+			t.Fatalf("unexpected payload: %+v", p)
+		}
+	}
+	if _, _, ok, err := eng.Store().GetCursor(ctx, "dev"); err != nil || !ok {
+		t.Fatalf("expected a stored cursor, got %v %v", ok, err)
+	}
+}
@@ -0,0 +1,69 @@
+package watchtower
+
+import (
+	"time"
+
+	"github.com/devblac/watch-tower/internal/sink"
+)
+
+// NewSinks builds a sender for each configured sink, keyed by sink id.
+// threads backs Slack threading and may be nil; a *Store satisfies it.
+func NewSinks(cfgs []SinkConfig, threads ThreadStore) (map[string]Sender, error) {
+	sinks := map[string]Sender{}
+	for _, s := range cfgs {
+		switch s.Type {
+		case "slack":
+			var sender Sender
+			var err error
+			if s.Token != "" {
+				ttl, _ := time.ParseDuration(s.ThreadTTL)
+				sender, err = sink.NewSlackAPISender(s.Token, s.Channel, s.Template, threads, ttl)
+			} else {
+				sender, err = sink.NewSlackSender(s.WebhookURL, s.Template)
+			}
+			if err != nil {
+				return nil, err
+			}
+			sinks[s.ID] = sender
+		case "teams":
+			sender, err := sink.NewTeamsSender(s.WebhookURL, s.Template)
+			if err != nil {
+				return nil, err
+			}
+			sinks[s.ID] = sender
+		case "webhook":
+			if s.Encoding != "" && s.Encoding != sink.EncodingText {
+				var registry *sink.SchemaRegistry
+				if r := s.SchemaRegistry; r != nil {
+					subject := r.Subject
+					if subject == "" {
+						subject = s.ID + "-value"
+					}
+					registry = sink.NewSchemaRegistry(r.URL, subject, r.Username, r.Password)
+				}
+				enc, err := sink.NewEncoder(s.Encoding, registry)
+				if err != nil {
+					return nil, err
+				}
+				sender, err := sink.NewEncodedWebhookSender(s.URL, s.Method, enc, s.Compress == "gzip", nil)
+				if err != nil {
+					return nil, err
+				}
+				sinks[s.ID] = sender
+				continue
+			}
+			newSender := sink.NewWebhookSender
+			if s.Compress == "gzip" {
+				newSender = sink.NewGzipWebhookSender
+			}
+			sender, err := newSender(s.URL, s.Method, s.Template, nil)
+			if err != nil {
+				return nil, err
+			}
+			sinks[s.ID] = sender
+		default:
+			continue
+		}
+	}
+	return sinks, nil
+}
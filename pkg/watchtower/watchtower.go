@@ -0,0 +1,85 @@
+// Package watchtower embeds the watch-tower scanning and alerting engine in
+// another Go program. It is the supported, stable entry point to the
+// pipeline that the watch-tower CLI runs: build a Config (from YAML or in
+// code), pass it to New with any Options, then call Run or Tick.
+//
+//	cfg, err := watchtower.LoadConfig("config.yaml")
+//	...
+//	eng, err := watchtower.New(ctx, cfg, watchtower.WithSink("ops", mySender))
+//	...
+//	defer eng.Close()
+//	err = eng.Run(ctx)
+//
+// The types below are aliases of the engine's own, so values built here can
+// be passed anywhere the engine takes them.
+package watchtower
+
+import (
+	"github.com/devblac/watch-tower/internal/config"
+	"github.com/devblac/watch-tower/internal/sink"
+	"github.com/devblac/watch-tower/internal/source/algorand"
+	"github.com/devblac/watch-tower/internal/source/bitcoin"
+	"github.com/devblac/watch-tower/internal/source/cosmos"
+	"github.com/devblac/watch-tower/internal/source/evm"
+	"github.com/devblac/watch-tower/internal/source/near"
+	"github.com/devblac/watch-tower/internal/source/solana"
+	"github.com/devblac/watch-tower/internal/source/substrate"
+	"github.com/devblac/watch-tower/internal/source/tron"
+	"github.com/devblac/watch-tower/internal/storage"
+)
+
+type (
+	// Config is a complete watch-tower configuration.
+	Config = config.Config
+	// Source configures one chain endpoint.
+	Source = config.Source
+	// Rule configures what to match and where to send it.
+	Rule = config.Rule
+	// MatchSpec is the match section of a rule.
+	MatchSpec = config.MatchSpec
+	// SinkConfig configures one built-in sink.
+	SinkConfig = config.Sink
+
+	// Payload is the alert handed to sinks and publishers.
+	Payload = sink.EventPayload
+	// Sender delivers alerts. Implement it to add a sink of your own.
+	Sender = sink.Sender
+	// ThreadStore remembers Slack threads for threaded sinks.
+	ThreadStore = sink.ThreadStore
+
+	// Store is the SQLite database holding cursors, alerts, and dedupe state.
+	Store = storage.Store
+
+	// EVMClient is the RPC surface an EVM source needs.
+	EVMClient = evm.BlockClient
+	// AlgodClient is the algod surface an Algorand source needs.
+	AlgodClient = algorand.AlgodClient
+	// SolanaClient is the RPC surface a Solana source needs.
+	SolanaClient = solana.Client
+	// BitcoinClient is the node or indexer surface a Bitcoin source needs.
+	BitcoinClient = bitcoin.Client
+	// CosmosClient is the CometBFT RPC surface a Cosmos source needs.
+	CosmosClient = cosmos.Client
+	// SubstrateClient is the JSON-RPC surface a Substrate source needs.
+	SubstrateClient = substrate.Client
+	// NearClient is the JSON-RPC surface a NEAR source needs.
+	NearClient = near.Client
+	// TronClient is the HTTP API surface a Tron source needs.
+	TronClient = tron.Client
+)
+
+// LoadConfig reads a YAML config file, interpolates ${ENV} references (and a
+// .env file next to it), and validates it.
+func LoadConfig(path string) (*Config, error) {
+	return config.Load(path)
+}
+
+// ParseConfig parses and validates a YAML config held in memory.
+func ParseConfig(data []byte) (*Config, error) {
+	return config.Parse(data)
+}
+
+// OpenStore opens (or creates) the SQLite database at path.
+func OpenStore(path string) (*Store, error) {
+	return storage.Open(path)
+}